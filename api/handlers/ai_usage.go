@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/uzzalhcse/crawlify/internal/ai"
+	"github.com/uzzalhcse/crawlify/internal/storage"
+)
+
+// AIUsageHandler reports AI calls, spend against the daily budgets and the response cache
+type AIUsageHandler struct {
+	repo  *storage.AIUsageRepository
+	meter *ai.Meter
+}
+
+// NewAIUsageHandler creates a new AI usage handler
+func NewAIUsageHandler(repo *storage.AIUsageRepository, meter *ai.Meter) *AIUsageHandler {
+	return &AIUsageHandler{repo: repo, meter: meter}
+}
+
+// ListUsage lists AI calls, newest first
+// GET /api/v1/ai/usage?workflow_id=&feature=&limit=100&offset=0
+func (h *AIUsageHandler) ListUsage(c *fiber.Ctx) error {
+	limit, _ := strconv.Atoi(c.Query("limit", "100"))
+	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+
+	usages, err := h.repo.List(c.Context(), c.Query("workflow_id"), c.Query("feature"), limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"calls": usages,
+		"count": len(usages),
+	})
+}
+
+// GetSummary totals AI calls over a period
+// GET /api/v1/ai/usage/summary?group_by=day&from=2026-01-01&to=2026-01-31&workflow_id=
+func (h *AIUsageHandler) GetSummary(c *fiber.Ctx) error {
+	to := time.Now().UTC()
+	from := to.AddDate(0, 0, -30).Truncate(24 * time.Hour)
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "from must be a date (YYYY-MM-DD)",
+			})
+		}
+		from = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "to must be a date (YYYY-MM-DD)",
+			})
+		}
+		to = t.AddDate(0, 0, 1) // Inclusive
+	}
+	groupBy := c.Query("group_by", "day")
+
+	summaries, err := h.repo.Summary(c.Context(), from, to, groupBy, c.Query("workflow_id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"from":     from.Format("2006-01-02"),
+		"to":       to.AddDate(0, 0, -1).Format("2006-01-02"),
+		"group_by": groupBy,
+		"groups":   summaries,
+	})
+}
+
+// GetBudget returns today's spend against the global budget, and the workflow's when asked
+// GET /api/v1/ai/budget?workflow_id=
+func (h *AIUsageHandler) GetBudget(c *fiber.Ctx) error {
+	global, err := h.meter.Status(c.Context(), "")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	response := fiber.Map{"global": global}
+
+	if workflowID := c.Query("workflow_id"); workflowID != "" {
+		workflow, err := h.meter.Status(c.Context(), workflowID)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		response["workflow"] = workflow
+	}
+
+	return c.JSON(response)
+}
+
+// ClearCache deletes cached AI answers, or only the expired ones with ?expired=true
+// DELETE /api/v1/ai/cache
+func (h *AIUsageHandler) ClearCache(c *fiber.Ctx) error {
+	deleted, err := h.repo.ClearCache(c.Context(), c.QueryBool("expired"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"deleted": deleted,
+	})
+}
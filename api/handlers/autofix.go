@@ -2,6 +2,9 @@ package handlers
 
 import (
 	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -11,8 +14,12 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/uzzalhcse/crawlify/internal/ai"
+	"github.com/uzzalhcse/crawlify/internal/ai/aiusage"
 	"github.com/uzzalhcse/crawlify/internal/logger"
+	"github.com/uzzalhcse/crawlify/internal/monitoring"
+	"github.com/uzzalhcse/crawlify/internal/plugins"
 	"github.com/uzzalhcse/crawlify/internal/storage"
+	"github.com/uzzalhcse/crawlify/internal/workflow"
 	"github.com/uzzalhcse/crawlify/pkg/models"
 	"go.uber.org/zap"
 )
@@ -25,7 +32,11 @@ type AutoFixHandler struct {
 	versionRepo      *storage.WorkflowVersionRepository
 	monitoringRepo   *storage.MonitoringRepository // NEW
 	autoFixService   *ai.AutoFixService
+	orchestrator     *monitoring.Orchestrator
+	parser           *workflow.Parser
 	snapshotBasePath string
+	// autoApplyConfidence applies verified suggestions at or above it without review; 0 disables
+	autoApplyConfidence float64
 }
 
 // NewAutoFixHandler creates a new autofix handler
@@ -36,16 +47,21 @@ func NewAutoFixHandler(
 	versionRepo *storage.WorkflowVersionRepository,
 	monitoringRepo *storage.MonitoringRepository, // NEW
 	autoFixService *ai.AutoFixService,
+	orchestrator *monitoring.Orchestrator,
 	snapshotBasePath string,
+	autoApplyConfidence float64,
 ) *AutoFixHandler {
 	return &AutoFixHandler{
-		snapshotRepo:     snapshotRepo,
-		suggestionRepo:   suggestionRepo,
-		workflowRepo:     workflowRepo,
-		versionRepo:      versionRepo,
-		monitoringRepo:   monitoringRepo,
-		autoFixService:   autoFixService,
-		snapshotBasePath: snapshotBasePath,
+		snapshotRepo:        snapshotRepo,
+		suggestionRepo:      suggestionRepo,
+		workflowRepo:        workflowRepo,
+		versionRepo:         versionRepo,
+		monitoringRepo:      monitoringRepo,
+		autoFixService:      autoFixService,
+		orchestrator:        orchestrator,
+		parser:              workflow.NewParser(),
+		snapshotBasePath:    snapshotBasePath,
+		autoApplyConfidence: autoApplyConfidence,
 	}
 }
 
@@ -93,34 +109,17 @@ func (h *AutoFixHandler) AnalyzeSnapshot(c *fiber.Ctx) error {
 	}
 
 	// Try to get baseline preview data to help AI understand expected output
-	var baselinePreview string
-	baseline, err := h.monitoringRepo.GetBaseline(c.Context(), report.WorkflowID)
-	if err == nil && baseline != nil {
-		// Get baseline snapshots for the same node
-		baselineSnapshots, err := h.snapshotRepo.GetByReportID(c.Context(), baseline.ID)
-		if err == nil {
-			// Find snapshot for the same node
-			for _, baselineSnap := range baselineSnapshots {
-				if baselineSnap.NodeID == snapshot.NodeID && baselineSnap.ElementsFound > 0 {
-					// This baseline snapshot worked! Extract preview using its selector
-					if baselineSnap.DOMSnapshotPath != nil && baselineSnap.SelectorValue != nil {
-						fullPath := filepath.Join(h.snapshotBasePath, *baselineSnap.DOMSnapshotPath)
-						if domBytes, err := h.readAndExtractPreview(fullPath, *baselineSnap.SelectorValue); err == nil {
-							baselinePreview = domBytes
-							logger.Info("Loaded baseline preview for AI",
-								zap.String("baseline_selector", *baselineSnap.SelectorValue),
-								zap.Int("preview_length", len(baselinePreview)))
-						}
-					}
-					break
-				}
-			}
-		}
-	}
+	baselinePreview := h.baselinePreview(c.Context(), report.WorkflowID, snapshot.NodeID)
 
 	// Analyze with AI
 	logger.Info("Analyzing snapshot with AI", zap.String("snapshot_id", snapshotID))
-	aiSuggestion, err := h.autoFixService.AnalyzeSnapshot(c.Context(), snapshot, screenshotPath, baselinePreview)
+	ctx := aiusage.WithScope(c.Context(), aiusage.Scope{Feature: ai.FeatureAutoFix, WorkflowID: report.WorkflowID})
+	aiSuggestion, err := h.autoFixService.AnalyzeSnapshot(ctx, snapshot, screenshotPath, baselinePreview)
+	if errors.Is(err, aiusage.ErrBudgetExceeded) {
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
 	if err != nil {
 		logger.Error("AI analysis failed", zap.Error(err))
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -132,19 +131,9 @@ func (h *AutoFixHandler) AnalyzeSnapshot(c *fiber.Ctx) error {
 	suggestedNodeConfig := make(map[string]interface{})
 
 	// Find the node
-	var targetNode *models.Node
-	for _, phase := range workflow.Config.Phases {
-		for _, node := range phase.Nodes {
-			if node.ID == snapshot.NodeID {
-				targetNode = &node
-				break
-			}
-		}
-		if targetNode != nil {
-			break
-		}
-	}
+	targetNode := findNode(workflow, snapshot.NodeID)
 
+	targetField := ""
 	if targetNode != nil && snapshot.SelectorValue != nil {
 		failedSelector := *snapshot.SelectorValue
 
@@ -170,6 +159,7 @@ func (h *AutoFixHandler) AnalyzeSnapshot(c *fiber.Ctx) error {
 						}
 						newConfigMap["selector"] = aiSuggestion.SuggestedSelector
 						fieldsCopy[fieldName] = newConfigMap
+						targetField = fieldName
 						updated = true
 						break // Assuming only one field matches for now
 					}
@@ -193,6 +183,20 @@ func (h *AutoFixHandler) AnalyzeSnapshot(c *fiber.Ctx) error {
 		}
 	}
 
+	// Verify by replaying the node with the suggested params
+	var verification *models.VerificationResult
+	if targetNode != nil {
+		verification = h.orchestrator.VerifyFix(c.Context(), &monitoring.FixVerification{
+			Workflow:        workflow,
+			Node:            mergeNodeParams(targetNode, suggestedNodeConfig),
+			Field:           targetField,
+			PageURL:         snapshot.URL,
+			MHTMLPath:       h.snapshotFile(snapshot.MetadataData["mhtml_path"]),
+			DOMPath:         stringValue(snapshot.DOMSnapshotPath),
+			BaselinePreview: splitPreview(baselinePreview),
+		})
+	}
+
 	// Save suggestion to database
 	suggestion := &models.FixSuggestion{
 		ID:                   uuid.New().String(),
@@ -204,9 +208,9 @@ func (h *AutoFixHandler) AnalyzeSnapshot(c *fiber.Ctx) error {
 		SuggestedNodeConfig:  suggestedNodeConfig,
 		FixExplanation:       aiSuggestion.Explanation,
 		ConfidenceScore:      aiSuggestion.Confidence,
-		VerificationResult:   aiSuggestion.VerificationResult, // Include verification results
+		VerificationResult:   verification,
 		Status:               "pending",
-		AIModel:              "gemini-2.0-flash-exp",
+		AIModel:              h.autoFixService.Model(),
 	}
 
 	if err := h.suggestionRepo.Create(c.Context(), suggestion); err != nil {
@@ -220,6 +224,25 @@ func (h *AutoFixHandler) AnalyzeSnapshot(c *fiber.Ctx) error {
 		zap.String("suggestion_id", suggestion.ID),
 		zap.Float64("confidence", suggestion.ConfidenceScore))
 
+	// Confident, verified fixes skip review when auto-apply is enabled
+	if h.autoApplyConfidence > 0 && suggestion.ConfidenceScore >= h.autoApplyConfidence &&
+		verification != nil && verification.IsValid {
+		if err := h.suggestionRepo.UpdateStatus(c.Context(), suggestion.ID, "approved", "auto-fix"); err != nil {
+			logger.Error("Failed to approve suggestion for auto-apply", zap.Error(err))
+		} else if _, err := h.applySuggestion(c.Context(), suggestion); err != nil {
+			logger.Error("Failed to auto-apply suggestion",
+				zap.String("suggestion_id", suggestion.ID),
+				zap.Error(err))
+		} else {
+			logger.Info("Suggestion auto-applied",
+				zap.String("suggestion_id", suggestion.ID),
+				zap.Float64("confidence", suggestion.ConfidenceScore))
+		}
+		if updated, err := h.suggestionRepo.GetByID(c.Context(), suggestion.ID); err == nil {
+			suggestion = updated
+		}
+	}
+
 	return c.JSON(suggestion)
 }
 
@@ -293,57 +316,108 @@ func (h *AutoFixHandler) ApplySuggestion(c *fiber.Ctx) error {
 		})
 	}
 
+	// Replay the fix before it touches the workflow, unless it already was
+	verification := suggestion.VerificationResult
+	if verification == nil || verification.Method != models.VerificationMethodNodeReplay {
+		verification, err = h.verifySuggestion(c.Context(), suggestion)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Failed to verify suggestion: " + err.Error(),
+			})
+		}
+		if err := h.suggestionRepo.UpdateVerification(c.Context(), suggestionID, verification); err != nil {
+			logger.Warn("Failed to save verification", zap.Error(err))
+		}
+		suggestion.VerificationResult = verification
+	}
+	if !verification.IsValid && c.Query("force") != "true" {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error":        "Suggestion failed verification; pass force=true to apply anyway",
+			"verification": verification,
+		})
+	}
+
+	newVersionNum, err := h.applySuggestion(c.Context(), suggestion)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) {
+			status = fiberErr.Code
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	logger.Info("Suggestion applied and new version created",
+		zap.String("suggestion_id", suggestionID),
+		zap.Int("new_version", newVersionNum))
+
+	suggestion, _ = h.suggestionRepo.GetByID(c.Context(), suggestionID)
+	return c.JSON(suggestion)
+}
+
+// applySuggestion merges an approved suggestion into its workflow as a new version
+// and returns the version number
+func (h *AutoFixHandler) applySuggestion(ctx context.Context, suggestion *models.FixSuggestion) (int, error) {
 	// Get workflow
 	logger.Info("Fetching workflow for suggestion",
-		zap.String("suggestion_id", suggestionID),
+		zap.String("suggestion_id", suggestion.ID),
 		zap.String("workflow_id", suggestion.WorkflowID))
 
-	workflow, err := h.workflowRepo.GetByID(c.Context(), suggestion.WorkflowID)
+	workflow, err := h.workflowRepo.GetByID(ctx, suggestion.WorkflowID)
 	if err != nil {
 		logger.Error("Failed to fetch workflow",
 			zap.String("workflow_id", suggestion.WorkflowID),
 			zap.Error(err))
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Workflow not found",
-		})
+		return 0, fiber.NewError(fiber.StatusNotFound, "Workflow not found")
 	}
 
 	// 1. Create a new version (Version N+1) with the APPLIED changes
 	// We need to modify the workflow config first
 
 	// Find the node to update
-	nodeFound := false
+	var fixedNode *models.Node
 	for i, phase := range workflow.Config.Phases {
 		for j, node := range phase.Nodes {
 			if node.ID == suggestion.NodeID {
 				// Update node params with suggested config
 				// We merge the suggested config into existing params
+				if workflow.Config.Phases[i].Nodes[j].Params == nil {
+					workflow.Config.Phases[i].Nodes[j].Params = make(map[string]interface{})
+				}
 				for k, v := range suggestion.SuggestedNodeConfig {
 					workflow.Config.Phases[i].Nodes[j].Params[k] = v
 				}
-				nodeFound = true
+				fixedNode = &workflow.Config.Phases[i].Nodes[j]
 				break
 			}
 		}
-		if nodeFound {
+		if fixedNode != nil {
 			break
 		}
 	}
 
-	if !nodeFound {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Target node not found in workflow",
-		})
+	if fixedNode == nil {
+		return 0, fiber.NewError(fiber.StatusBadRequest, "Target node not found in workflow")
+	}
+
+	// The fixed workflow must pass the same checks as a saved one
+	if err := h.parser.Validate(&workflow.Config); err != nil {
+		return 0, fiber.NewError(fiber.StatusUnprocessableEntity, fmt.Sprintf("Fixed workflow is invalid: %v", err))
+	}
+	if h.orchestrator != nil {
+		if err := h.orchestrator.ValidateNodeParams(fixedNode); err != nil {
+			return 0, fiber.NewError(fiber.StatusUnprocessableEntity, fmt.Sprintf("Fixed node '%s' is invalid: %v", fixedNode.ID, err))
+		}
 	}
 
 	// Get the latest version number from the database to avoid duplicate key constraints
-	latestVersion, err := h.versionRepo.GetLatest(c.Context(), workflow.ID)
+	latestVersion, err := h.versionRepo.GetLatest(ctx, workflow.ID)
 	var newVersionNum int
 	if err != nil {
 		logger.Error("Failed to get latest version", zap.Error(err))
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to get latest version",
-		})
+		return 0, fiber.NewError(fiber.StatusInternalServerError, "Failed to get latest version")
 	}
 
 	if latestVersion != nil {
@@ -357,38 +431,56 @@ func (h *AutoFixHandler) ApplySuggestion(c *fiber.Ctx) error {
 		Version:      newVersionNum,
 		Config:       workflow.Config,
 		ChangeReason: "AI Auto-Fix: " + suggestion.FixExplanation,
+		Plugins:      plugins.ResolveWorkflowPlugins(&workflow.Config),
 	}
 
-	if err := h.versionRepo.Create(c.Context(), newVersion); err != nil {
+	if err := h.versionRepo.Create(ctx, newVersion); err != nil {
 		logger.Error("Failed to create workflow version", zap.Error(err))
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create workflow version",
-		})
+		return 0, fiber.NewError(fiber.StatusInternalServerError, "Failed to create workflow version")
 	}
 
 	// Update workflow
 	workflow.Version = newVersionNum
-	if err := h.workflowRepo.Update(c.Context(), workflow); err != nil {
+	if err := h.workflowRepo.Update(ctx, workflow); err != nil {
 		logger.Error("Failed to update workflow", zap.Error(err))
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to update workflow",
-		})
+		return 0, fiber.NewError(fiber.StatusInternalServerError, "Failed to update workflow")
 	}
 
 	// Mark suggestion as applied
-	if err := h.suggestionRepo.MarkAsApplied(c.Context(), suggestionID); err != nil {
+	if err := h.suggestionRepo.MarkAsApplied(ctx, suggestion.ID); err != nil {
 		logger.Error("Failed to mark suggestion as applied", zap.Error(err))
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to apply suggestion",
-		})
+		return 0, fiber.NewError(fiber.StatusInternalServerError, "Failed to apply suggestion")
 	}
 
-	logger.Info("Suggestion applied and new version created",
-		zap.String("suggestion_id", suggestionID),
-		zap.Int("new_version", newVersionNum))
+	return newVersionNum, nil
+}
 
-	suggestion, _ = h.suggestionRepo.GetByID(c.Context(), suggestionID)
-	return c.JSON(suggestion)
+// verifySuggestion replays a stored suggestion against its snapshot's page
+func (h *AutoFixHandler) verifySuggestion(ctx context.Context, suggestion *models.FixSuggestion) (*models.VerificationResult, error) {
+	snapshot, err := h.snapshotRepo.GetByID(ctx, suggestion.SnapshotID)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot not found")
+	}
+
+	workflow, err := h.workflowRepo.GetByID(ctx, suggestion.WorkflowID)
+	if err != nil {
+		return nil, fmt.Errorf("workflow not found")
+	}
+
+	targetNode := findNode(workflow, suggestion.NodeID)
+	if targetNode == nil {
+		return nil, fmt.Errorf("target node not found in workflow")
+	}
+
+	return h.orchestrator.VerifyFix(ctx, &monitoring.FixVerification{
+		Workflow:        workflow,
+		Node:            mergeNodeParams(targetNode, suggestion.SuggestedNodeConfig),
+		Field:           suggestedField(targetNode, suggestion.SuggestedNodeConfig),
+		PageURL:         snapshot.URL,
+		MHTMLPath:       h.snapshotFile(snapshot.MetadataData["mhtml_path"]),
+		DOMPath:         h.snapshotFile(stringValue(snapshot.DOMSnapshotPath)),
+		BaselinePreview: splitPreview(h.baselinePreview(ctx, workflow.ID, suggestion.NodeID)),
+	}), nil
 }
 
 // RevertSuggestion reverts an applied suggestion
@@ -475,6 +567,7 @@ func (h *AutoFixHandler) RevertSuggestion(c *fiber.Ctx) error {
 		Version:      newVersionNum,
 		Config:       prevVersion.Config,
 		ChangeReason: "Revert: " + suggestion.FixExplanation,
+		Plugins:      plugins.ResolveWorkflowPlugins(&prevVersion.Config),
 	}
 
 	if err := h.versionRepo.Create(c.Context(), newVersion); err != nil {
@@ -550,3 +643,105 @@ func (h *AutoFixHandler) readAndExtractPreview(domPath string, selector string)
 
 	return strings.Join(previews, "\n"), nil
 }
+
+// baselinePreview extracts what a node produced in the workflow's baseline report, "" if unknown
+func (h *AutoFixHandler) baselinePreview(ctx context.Context, workflowID, nodeID string) string {
+	baseline, err := h.monitoringRepo.GetBaseline(ctx, workflowID)
+	if err != nil || baseline == nil {
+		return ""
+	}
+
+	// Get baseline snapshots for the same node
+	baselineSnapshots, err := h.snapshotRepo.GetByReportID(ctx, baseline.ID)
+	if err != nil {
+		return ""
+	}
+
+	// Find snapshot for the same node
+	for _, baselineSnap := range baselineSnapshots {
+		if baselineSnap.NodeID == nodeID && baselineSnap.ElementsFound > 0 {
+			// This baseline snapshot worked! Extract preview using its selector
+			if baselineSnap.DOMSnapshotPath != nil && baselineSnap.SelectorValue != nil {
+				fullPath := filepath.Join(h.snapshotBasePath, *baselineSnap.DOMSnapshotPath)
+				if preview, err := h.readAndExtractPreview(fullPath, *baselineSnap.SelectorValue); err == nil {
+					logger.Info("Loaded baseline preview for AI",
+						zap.String("baseline_selector", *baselineSnap.SelectorValue),
+						zap.Int("preview_length", len(preview)))
+					return preview
+				}
+			}
+			break
+		}
+	}
+	return ""
+}
+
+// snapshotFile resolves a stored snapshot path under the snapshot directory, "" if there is none
+func (h *AutoFixHandler) snapshotFile(path interface{}) string {
+	p, _ := path.(string)
+	if p == "" {
+		return ""
+	}
+	return filepath.Join(h.snapshotBasePath, p)
+}
+
+// findNode finds a node in any phase of a workflow
+func findNode(workflow *models.Workflow, nodeID string) *models.Node {
+	for i := range workflow.Config.Phases {
+		for j := range workflow.Config.Phases[i].Nodes {
+			if workflow.Config.Phases[i].Nodes[j].ID == nodeID {
+				return &workflow.Config.Phases[i].Nodes[j]
+			}
+		}
+	}
+	return nil
+}
+
+// mergeNodeParams returns a copy of the node with the suggested config merged into its params
+func mergeNodeParams(node *models.Node, suggested map[string]interface{}) *models.Node {
+	merged := *node
+	merged.Params = make(map[string]interface{}, len(node.Params)+len(suggested))
+	for k, v := range node.Params {
+		merged.Params[k] = v
+	}
+	for k, v := range suggested {
+		merged.Params[k] = v
+	}
+	return &merged
+}
+
+// suggestedField finds the extract field whose selector a suggestion changes, "" if none
+func suggestedField(node *models.Node, suggested map[string]interface{}) string {
+	newFields, ok := suggested["fields"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	oldFields, _ := node.Params["fields"].(map[string]interface{})
+	for name, config := range newFields {
+		newConfig, _ := config.(map[string]interface{})
+		oldConfig, _ := oldFields[name].(map[string]interface{})
+		if newConfig != nil && (oldConfig == nil || newConfig["selector"] != oldConfig["selector"]) {
+			return name
+		}
+	}
+	return ""
+}
+
+// splitPreview splits a newline-joined preview into its values
+func splitPreview(preview string) []string {
+	var values []string
+	for _, line := range strings.Split(preview, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			values = append(values, line)
+		}
+	}
+	return values
+}
+
+// stringValue dereferences an optional string
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
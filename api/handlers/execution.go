@@ -6,8 +6,11 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/uzzalhcse/crawlify/internal/assets"
 	"github.com/uzzalhcse/crawlify/internal/browser"
+	"github.com/uzzalhcse/crawlify/internal/config"
 	"github.com/uzzalhcse/crawlify/internal/logger"
+	"github.com/uzzalhcse/crawlify/internal/plugins"
 	"github.com/uzzalhcse/crawlify/internal/queue"
 	"github.com/uzzalhcse/crawlify/internal/storage"
 	"github.com/uzzalhcse/crawlify/internal/workflow"
@@ -37,6 +40,8 @@ func NewExecutionHandler(
 	urlQueue *queue.URLQueue,
 	errorRecoverySystem interface{},
 	recoveryHistoryRepo *storage.ErrorRecoveryHistoryRepository,
+	crawlerConfig *config.CrawlerConfig,
+	assetDownloader *assets.Downloader,
 ) *ExecutionHandler {
 	return &ExecutionHandler{
 		workflowRepo:        workflowRepo,
@@ -45,11 +50,21 @@ func NewExecutionHandler(
 		nodeExecRepo:        nodeExecRepo,
 		browserPool:         browserPool,
 		urlQueue:            urlQueue,
-		executor:            workflow.NewExecutor(browserPool, urlQueue, extractedItemsRepo, nodeExecRepo, executionRepo, errorRecoverySystem, recoveryHistoryRepo),
+		executor:            workflow.NewExecutor(browserPool, urlQueue, extractedItemsRepo, nodeExecRepo, executionRepo, errorRecoverySystem, recoveryHistoryRepo, crawlerConfig, assetDownloader),
 		errorRecoverySystem: errorRecoverySystem,
 	}
 }
 
+// GetPluginLoader returns the loader the executor resolves plugin nodes with
+func (h *ExecutionHandler) GetPluginLoader() *plugins.PluginLoader {
+	return h.executor.GetNodeRegistry().PluginLoader()
+}
+
+// SetPluginKVRepository stores the key-value stores plugins get during executions
+func (h *ExecutionHandler) SetPluginKVRepository(repo *storage.PluginKVRepository) {
+	h.executor.SetPluginKV(repo)
+}
+
 // GetEventBroadcaster returns the event broadcaster from the underlying executor
 func (h *ExecutionHandler) GetEventBroadcaster() *workflow.EventBroadcaster {
 	return h.executor.GetEventBroadcaster()
@@ -267,9 +282,10 @@ func (h *ExecutionHandler) ResumeExecution(c *fiber.Ctx) error {
 		})
 	}
 
-	if execution.Status != models.ExecutionStatusPaused && execution.Status != models.ExecutionStatusRunning {
+	if execution.Status != models.ExecutionStatusPaused && execution.Status != models.ExecutionStatusRunning &&
+		execution.Status != models.ExecutionStatusStopped {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Execution cannot be resumed (must be paused or running after restart)",
+			"error": "Execution cannot be resumed (must be paused, stopped by a budget or running after restart)",
 		})
 	}
 
@@ -0,0 +1,731 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/uzzalhcse/crawlify/internal/config"
+	"github.com/uzzalhcse/crawlify/internal/plugin"
+	"github.com/uzzalhcse/crawlify/internal/plugins"
+	"github.com/uzzalhcse/crawlify/internal/storage"
+	"github.com/uzzalhcse/crawlify/pkg/models"
+	"go.uber.org/zap"
+)
+
+// PluginBundleHandler handles exporting and importing signed plugin bundles and the plugin registry
+type PluginBundleHandler struct {
+	pluginRepo *storage.PluginRepository
+	sources    *plugin.SourceManager // Imported sources are recorded as source revisions
+	cfg        config.PluginsConfig
+	keyring    *plugins.Keyring
+	signingKey ed25519.PrivateKey // nil when bundles are exported unsigned
+	logger     *zap.Logger
+}
+
+// NewPluginBundleHandler creates a new plugin bundle handler
+func NewPluginBundleHandler(pluginRepo *storage.PluginRepository, sources *plugin.SourceManager, cfg config.PluginsConfig, logger *zap.Logger) (*PluginBundleHandler, error) {
+	keyring, err := plugins.NewKeyring(cfg.TrustedKeys)
+	if err != nil {
+		return nil, err
+	}
+	h := &PluginBundleHandler{
+		pluginRepo: pluginRepo,
+		sources:    sources,
+		cfg:        cfg,
+		keyring:    keyring,
+		logger:     logger,
+	}
+	if cfg.SigningKey != "" {
+		if h.signingKey, err = plugins.ParseSigningKey(cfg.SigningKey); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// ExportBundle handles GET /api/v1/plugins/:id/versions/:version/bundle
+// :version is a version ID or a version number. The bundle holds the plugin's current source and
+// the version's binary for this server's platform, signed with plugins.signing_key when it is set.
+func (h *PluginBundleHandler) ExportBundle(c *fiber.Ctx) error {
+	plugin, version, err := h.findVersion(c.Context(), c.Params("id"), c.Params("version"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	src, err := h.bundleSource(plugin, version)
+	if err != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	tmp, err := os.CreateTemp("", "crawlify-bundle-*.tar.gz")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create bundle",
+		})
+	}
+	// The open file keeps its content until the response has been sent
+	os.Remove(tmp.Name())
+	manifest, err := plugins.WriteBundle(tmp, src)
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		tmp.Close()
+		h.logger.Error("Failed to export plugin bundle", zap.String("plugin", plugin.Slug), zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create bundle: " + err.Error(),
+		})
+	}
+	info, err := tmp.Stat()
+	if err != nil {
+		tmp.Close()
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create bundle",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "application/gzip")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, plugins.BundleFileName(manifest)))
+	return c.SendStream(tmp, int(info.Size()))
+}
+
+// PublishToRegistry handles POST /api/v1/plugins/:id/versions/:version/registry
+// It writes the version's bundle into plugins.registry_dir and regenerates the registry index
+func (h *PluginBundleHandler) PublishToRegistry(c *fiber.Ctx) error {
+	if h.cfg.RegistryDir == "" {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "plugins.registry_dir is not configured",
+		})
+	}
+	plugin, version, err := h.findVersion(c.Context(), c.Params("id"), c.Params("version"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	src, err := h.bundleSource(plugin, version)
+	if err != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := os.MkdirAll(h.cfg.RegistryDir, 0755); err != nil {
+		h.logger.Error("Failed to create registry directory", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create registry directory",
+		})
+	}
+	fileName := plugins.BundleFileName(&src.Manifest)
+	if err := writeBundleFile(filepath.Join(h.cfg.RegistryDir, fileName), src); err != nil {
+		h.logger.Error("Failed to publish plugin bundle", zap.String("plugin", plugin.Slug), zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to write bundle: " + err.Error(),
+		})
+	}
+	index, err := plugins.WriteRegistryIndex(h.cfg.RegistryDir)
+	if err != nil {
+		h.logger.Error("Failed to write registry index", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to write registry index",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"bundle":  fileName,
+		"signed":  h.signingKey != nil,
+		"plugins": len(index.Plugins),
+	})
+}
+
+// ImportBundle handles POST /api/v1/plugins/import
+// The bundle is sent as the multipart file "bundle" or as the raw request body.
+// ?overwrite_source=true replaces an existing source tree of the plugin with the bundled one.
+func (h *PluginBundleHandler) ImportBundle(c *fiber.Ctx) error {
+	tmp, err := os.CreateTemp("", "crawlify-import-*.tar.gz")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to store bundle",
+		})
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	err = readBundleUpload(c, tmp, h.cfg.MaxBundleSize)
+	switch {
+	case errors.Is(err, errBundleTooLarge):
+		return c.Status(fiber.StatusRequestEntityTooLarge).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, errNoBundle):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Send the bundle as the multipart file 'bundle' or as the request body",
+		})
+	case err != nil:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to read uploaded bundle: " + err.Error(),
+		})
+	}
+
+	return h.importBundle(c, tmp, c.QueryBool("overwrite_source"))
+}
+
+// ListRegistry handles GET /api/v1/plugins/registry?url=<index.json>
+// It returns the index of the registry at url, which must be plugins.registry_url or one of
+// plugins.registries, or of plugins.registry_url
+func (h *PluginBundleHandler) ListRegistry(c *fiber.Ctx) error {
+	client, err := h.registryClient(c.Query("url"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	index, err := client.FetchIndex(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(index)
+}
+
+// ImportFromRegistry handles POST /api/v1/plugins/registry/import
+// Body: {"slug": "my-plugin", "version": "^1.2", "registry_url": "...", "overwrite_source": false}.
+// The newest stable version for this platform is imported when version is empty.
+func (h *PluginBundleHandler) ImportFromRegistry(c *fiber.Ctx) error {
+	var req struct {
+		Slug            string `json:"slug"`
+		Version         string `json:"version"`
+		RegistryURL     string `json:"registry_url"`
+		OverwriteSource bool   `json:"overwrite_source"`
+	}
+	if err := c.BodyParser(&req); err != nil || req.Slug == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "slug is required",
+		})
+	}
+	client, err := h.registryClient(req.RegistryURL)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	index, err := client.FetchIndex(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	_, entry, err := index.Find(req.Slug, req.Version)
+	switch {
+	case errors.Is(err, plugins.ErrRegistryPluginNotFound), errors.Is(err, plugins.ErrNoMatchingVersion):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case err != nil:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	tmp, err := os.CreateTemp("", "crawlify-import-*.tar.gz")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to store bundle",
+		})
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if err := client.FetchBundle(c.Context(), entry, tmp); err != nil {
+		return c.Status(fiber.StatusBadGateway).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return h.importBundle(c, tmp, req.OverwriteSource)
+}
+
+// VerifyPlugin handles POST /api/v1/plugins/:id/verify
+// It checks the signature of every version against the trusted keys, e.g. after a key was
+// added or removed, and updates is_verified
+func (h *PluginBundleHandler) VerifyPlugin(c *fiber.Ctx) error {
+	plugin, err := h.pluginRepo.GetPluginByID(c.Context(), c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Plugin not found",
+		})
+	}
+	verified, results, err := h.refreshVerification(c.Context(), plugin.ID)
+	if err != nil {
+		h.logger.Error("Failed to verify plugin", zap.String("plugin", plugin.Slug), zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to verify plugin",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"plugin_id":    plugin.ID,
+		"slug":         plugin.Slug,
+		"is_verified":  verified,
+		"versions":     results,
+		"trusted_keys": h.keyring.KeyIDs(),
+	})
+}
+
+// versionVerification is the signature check of one plugin version
+type versionVerification struct {
+	VersionID    string `json:"version_id"`
+	Version      string `json:"version"`
+	Verified     bool   `json:"verified"`
+	SigningKeyID string `json:"signing_key_id,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// refreshVerification sets is_verified of a plugin: true when it has versions and all of them
+// carry a signature by a trusted key over a manifest matching their binary
+func (h *PluginBundleHandler) refreshVerification(ctx context.Context, pluginID string) (bool, []versionVerification, error) {
+	versions, err := h.pluginRepo.ListVersions(ctx, pluginID)
+	if err != nil {
+		return false, nil, err
+	}
+	verified := len(versions) > 0
+	results := make([]versionVerification, 0, len(versions))
+	for _, version := range versions {
+		result := versionVerification{VersionID: version.ID, Version: version.Version}
+		if keyID, err := h.keyring.VerifyVersion(version); err != nil {
+			result.Error = err.Error()
+			verified = false
+		} else {
+			result.Verified, result.SigningKeyID = true, keyID
+		}
+		results = append(results, result)
+	}
+	if err := h.pluginRepo.SetVerified(ctx, pluginID, verified); err != nil {
+		return false, nil, err
+	}
+	return verified, results, nil
+}
+
+// importBundle checks a bundle file, stores its binary as a plugin version and its source, and
+// creates the plugin when this instance does not have it yet
+func (h *PluginBundleHandler) importBundle(c *fiber.Ctx, bundleFile *os.File, overwriteSource bool) error {
+	ctx := c.Context()
+	if _, err := bundleFile.Seek(0, io.SeekStart); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to read bundle",
+		})
+	}
+	dir, err := os.MkdirTemp("", "crawlify-bundle-*")
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to extract bundle",
+		})
+	}
+	defer os.RemoveAll(dir)
+
+	bundle, err := plugins.ReadBundle(bundleFile, dir, h.cfg.MaxBundleSize)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	m := bundle.Manifest
+
+	// A signature that does not match is always rejected; a missing or unknown one only when
+	// unsigned imports are not allowed
+	keyID, verifyErr := h.keyring.Verify(bundle.RawManifest, bundle.Signature)
+	if errors.Is(verifyErr, plugins.ErrBadSignature) || verifyErr != nil && !h.cfg.AllowUnsigned {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": verifyErr.Error(),
+		})
+	}
+	if m.Runtime != models.PluginRuntimeWASM && (m.OS != runtime.GOOS || m.Arch != runtime.GOARCH) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": fmt.Sprintf("bundle is built for %s/%s, this server runs %s/%s", m.OS, m.Arch, runtime.GOOS, runtime.GOARCH),
+		})
+	}
+	pluginVersion, err := plugins.CanonicalVersion(m.Version)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// What the import created is removed again when a later step fails
+	var undo []func()
+	imported := false
+	defer func() {
+		if imported {
+			return
+		}
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+	}()
+
+	plugin, err := h.pluginRepo.GetPluginBySlug(ctx, m.Plugin.Slug)
+	created := false
+	if err != nil {
+		plugin = &models.Plugin{
+			ID:               uuid.New().String(),
+			Name:             m.Plugin.Name,
+			Slug:             m.Plugin.Slug,
+			Description:      m.Plugin.Description,
+			AuthorName:       m.Plugin.AuthorName,
+			AuthorEmail:      m.Plugin.AuthorEmail,
+			RepositoryURL:    m.Plugin.RepositoryURL,
+			DocumentationURL: m.Plugin.DocumentationURL,
+			PhaseType:        m.Plugin.PhaseType,
+			PluginType:       m.Plugin.PluginType,
+			Category:         m.Plugin.Category,
+			Tags:             models.JSONArray(m.Plugin.Tags),
+		}
+		if plugin.PluginType == "" {
+			plugin.PluginType = models.PluginTypeCommunity
+		}
+		if err := h.pluginRepo.CreatePlugin(ctx, plugin); err != nil {
+			h.logger.Error("Failed to create imported plugin", zap.String("plugin", m.Plugin.Slug), zap.Error(err))
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to create plugin",
+			})
+		}
+		created = true
+		undo = append(undo, func() {
+			if err := h.pluginRepo.DeletePlugin(ctx, plugin.ID); err != nil {
+				h.logger.Error("Failed to remove plugin of failed import", zap.String("plugin", plugin.Slug), zap.Error(err))
+			}
+		})
+	}
+
+	versions, err := h.pluginRepo.ListVersions(ctx, plugin.ID)
+	if err != nil {
+		h.logger.Error("Failed to list versions", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to list plugin versions",
+		})
+	}
+	for _, existing := range versions {
+		if canonical, _ := plugins.CanonicalVersion(existing.Version); canonical == pluginVersion {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error":      fmt.Sprintf("plugin %s version %s already exists; publish a new version", plugin.Slug, pluginVersion),
+				"version_id": existing.ID,
+			})
+		}
+	}
+
+	_, statErr := os.Stat(plugins.ArtifactPath(plugin.Slug, pluginVersion, m.Runtime))
+	artifactPath, err := plugins.InstallArtifact(plugin.Slug, pluginVersion, m.Runtime, bundle.ArtifactPath(), m.Artifact.SHA256)
+	if err != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if os.IsNotExist(statErr) {
+		undo = append(undo, func() {
+			if err := os.Remove(artifactPath); err != nil && !os.IsNotExist(err) {
+				h.logger.Error("Failed to remove artifact of failed import", zap.String("path", artifactPath), zap.Error(err))
+			}
+		})
+	}
+
+	version := &models.PluginVersion{
+		ID:                 uuid.New().String(),
+		PluginID:           plugin.ID,
+		Version:            pluginVersion,
+		Changelog:          m.Changelog,
+		IsStable:           m.IsStable,
+		MinCrawlifyVersion: m.MinCrawlifyVersion,
+		BinaryHash:         m.Artifact.SHA256,
+		BinarySizeBytes:    m.Artifact.Size,
+		SBOM:               m.SBOM,
+		ConfigSchema:       m.ConfigSchema,
+		Runtime:            m.Runtime,
+		Capabilities:       m.Capabilities,
+		Limits:             m.Limits,
+		PublishedAt:        time.Now(),
+	}
+	if m.Runtime == models.PluginRuntimeWASM {
+		// One module runs on every platform
+		for _, platform := range [][2]string{{"linux", "amd64"}, {"linux", "arm64"}, {"darwin", "amd64"}, {"darwin", "arm64"}} {
+			version.SetBinaryPath(platform[0], platform[1], artifactPath)
+		}
+	} else if !version.SetBinaryPath(m.OS, m.Arch, artifactPath) {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": fmt.Sprintf("plugin versions have no binary for %s/%s", m.OS, m.Arch),
+		})
+	}
+	if bundle.Signature != nil {
+		version.Manifest = string(bundle.RawManifest)
+		version.Signature = bundle.Signature.Signature
+		version.SigningKeyID = bundle.Signature.KeyID
+	}
+	if err := h.pluginRepo.PublishVersion(ctx, version); err != nil {
+		h.logger.Error("Failed to publish imported version", zap.String("plugin", plugin.Slug), zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to publish version",
+		})
+	}
+	if !created {
+		// Removing a created plugin removes its versions too
+		undo = append(undo, func() {
+			if err := h.pluginRepo.DeleteVersion(ctx, version.ID); err != nil {
+				h.logger.Error("Failed to remove version of failed import", zap.String("plugin", plugin.Slug), zap.Error(err))
+			}
+		})
+	}
+
+	// The source is kept next to the other plugin sources so it can be edited and rebuilt
+	source := "none"
+	var skippedSource []string
+	if len(m.Source) > 0 {
+		sourceDir := filepath.Join(h.sources.BasePath, plugin.Slug)
+		_, statErr := os.Stat(sourceDir)
+		switch {
+		case os.IsNotExist(statErr) || overwriteSource:
+			// Keep the source being replaced in the history so the import can be rolled back
+			if statErr == nil {
+				if _, err := h.sources.RecordRevision(ctx, plugin.Slug, "", "Source before import"); err != nil {
+					h.logger.Error("Failed to record plugin source", zap.String("plugin", plugin.Slug), zap.Error(err))
+					return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+						"error": "Failed to record plugin source",
+					})
+				}
+			}
+			files, err := bundle.SourceFiles()
+			if err != nil {
+				h.logger.Error("Failed to read imported plugin source", zap.String("plugin", plugin.Slug), zap.Error(err))
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to read plugin source",
+				})
+			}
+			message := fmt.Sprintf("Imported from bundle %s %s", m.Plugin.Slug, m.Version)
+			_, skipped, err := h.sources.ImportSource(ctx, plugin.Slug, files, "", message)
+			if err != nil {
+				h.logger.Error("Failed to write imported plugin source", zap.String("plugin", plugin.Slug), zap.Error(err))
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to write plugin source: " + err.Error(),
+				})
+			}
+			skippedSource = skipped
+			source = "written"
+		default:
+			source = "kept"
+		}
+	}
+
+	imported = true
+
+	verified, _, err := h.refreshVerification(ctx, plugin.ID)
+	if err != nil {
+		h.logger.Error("Failed to verify plugin", zap.String("plugin", plugin.Slug), zap.Error(err))
+	}
+	h.logger.Info("Imported plugin bundle",
+		zap.String("plugin", plugin.Slug),
+		zap.String("version", pluginVersion),
+		zap.String("signing_key_id", keyID),
+		zap.Bool("verified", verified))
+
+	result := fiber.Map{
+		"plugin_id":      plugin.ID,
+		"slug":           plugin.Slug,
+		"plugin_created": created,
+		"version":        version,
+		"signed":         bundle.Signature != nil,
+		"signing_key_id": keyID,
+		"is_verified":    verified,
+		"source":         source,
+	}
+	if verifyErr != nil {
+		result["signature_error"] = verifyErr.Error()
+	}
+	if len(skippedSource) > 0 {
+		result["skipped_source_files"] = skippedSource
+	}
+	return c.Status(fiber.StatusCreated).JSON(result)
+}
+
+// findVersion looks up a plugin and one of its versions by version ID or version number
+func (h *PluginBundleHandler) findVersion(ctx context.Context, pluginID, ref string) (*models.Plugin, *models.PluginVersion, error) {
+	plugin, err := h.pluginRepo.GetPluginByID(ctx, pluginID)
+	if err != nil {
+		return nil, nil, errors.New("Plugin not found")
+	}
+	versions, err := h.pluginRepo.ListVersions(ctx, pluginID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list plugin versions: %w", err)
+	}
+	canonical, _ := plugins.CanonicalVersion(ref)
+	for _, version := range versions {
+		if version.ID == ref {
+			return plugin, version, nil
+		}
+		if v, _ := plugins.CanonicalVersion(version.Version); canonical != "" && v == canonical {
+			return plugin, version, nil
+		}
+	}
+	return nil, nil, errors.New("Version not found")
+}
+
+// bundleSource collects the manifest, source and binary of a plugin version for this platform
+func (h *PluginBundleHandler) bundleSource(plugin *models.Plugin, version *models.PluginVersion) (plugins.BundleSource, error) {
+	pluginRuntime := version.Runtime
+	if pluginRuntime == "" {
+		pluginRuntime = models.PluginRuntimeNative
+	}
+	pluginVersion, err := plugins.CanonicalVersion(version.Version)
+	if err != nil {
+		return plugins.BundleSource{}, err
+	}
+
+	// The published binary, or else the one installed into the versioned store
+	artifact := version.BinaryPath(runtime.GOOS, runtime.GOARCH)
+	if _, err := os.Stat(artifact); artifact == "" || err != nil {
+		artifact = plugins.ArtifactPath(plugin.Slug, pluginVersion, pluginRuntime)
+		if _, err := os.Stat(artifact); err != nil {
+			return plugins.BundleSource{}, fmt.Errorf("plugin %s version %s has no binary for %s/%s; build or install it first", plugin.Slug, pluginVersion, runtime.GOOS, runtime.GOARCH)
+		}
+	}
+
+	src := plugins.BundleSource{
+		Manifest: plugins.BundleManifest{
+			Plugin: plugins.BundlePlugin{
+				Name:             plugin.Name,
+				Slug:             plugin.Slug,
+				Description:      plugin.Description,
+				AuthorName:       plugin.AuthorName,
+				AuthorEmail:      plugin.AuthorEmail,
+				RepositoryURL:    plugin.RepositoryURL,
+				DocumentationURL: plugin.DocumentationURL,
+				PhaseType:        plugin.PhaseType,
+				PluginType:       plugin.PluginType,
+				Category:         plugin.Category,
+				Tags:             plugin.Tags,
+			},
+			Version:            pluginVersion,
+			Changelog:          version.Changelog,
+			IsStable:           version.IsStable,
+			MinCrawlifyVersion: version.MinCrawlifyVersion,
+			Runtime:            pluginRuntime,
+			ConfigSchema:       version.ConfigSchema,
+			Capabilities:       version.Capabilities,
+			Limits:             version.Limits,
+			SBOM:               version.SBOM,
+		},
+		Artifact: artifact,
+		SignWith: h.signingKey,
+	}
+	if pluginRuntime != models.PluginRuntimeWASM {
+		src.Manifest.OS, src.Manifest.Arch = runtime.GOOS, runtime.GOARCH
+	}
+	if sourceDir := filepath.Join(h.sources.BasePath, plugin.Slug); dirExists(sourceDir) {
+		src.SourceDir = sourceDir
+	}
+	return src, nil
+}
+
+// registryClient returns a client for the registry at indexURL, or at plugins.registry_url
+// Only configured registries are fetched, so requests cannot point the server at other URLs.
+func (h *PluginBundleHandler) registryClient(indexURL string) (*plugins.RegistryClient, error) {
+	if indexURL == "" {
+		indexURL = h.cfg.RegistryURL
+	}
+	if indexURL == "" {
+		return nil, errors.New("no registry URL given and plugins.registry_url is not configured")
+	}
+	if indexURL != h.cfg.RegistryURL && !slices.Contains(h.cfg.Registries, indexURL) {
+		return nil, fmt.Errorf("registry %s is not configured; add it to plugins.registries", indexURL)
+	}
+	return plugins.NewRegistryClient(indexURL, h.cfg.MaxBundleSize), nil
+}
+
+// Errors of readBundleUpload
+var (
+	errBundleTooLarge = errors.New("plugin bundle upload is larger than plugins.max_bundle_size")
+	errNoBundle       = errors.New("no plugin bundle in the request")
+)
+
+// readBundleUpload copies the bundle of an import request into w: the multipart file "bundle",
+// or else the raw body. At most maxBytes of the body are read, plus room for multipart headers.
+func readBundleUpload(c *fiber.Ctx, w io.Writer, maxBytes int64) error {
+	var body io.Reader = c.Request().BodyStream()
+	if body == nil {
+		body = bytes.NewReader(c.Body())
+	}
+	limited := &io.LimitedReader{R: body, N: maxBytes + 64<<10 + 1}
+
+	var n int64
+	var err error
+	mediaType, params, _ := mime.ParseMediaType(c.Get(fiber.HeaderContentType))
+	if mediaType == fiber.MIMEMultipartForm {
+		reader := multipart.NewReader(limited, params["boundary"])
+		for {
+			part, partErr := reader.NextPart()
+			if partErr == io.EOF {
+				return errNoBundle
+			}
+			if partErr != nil {
+				if limited.N <= 0 {
+					return errBundleTooLarge
+				}
+				return partErr
+			}
+			if part.FormName() == "bundle" {
+				n, err = io.Copy(w, part)
+				break
+			}
+		}
+	} else {
+		n, err = io.Copy(w, limited)
+	}
+	if limited.N <= 0 || n > maxBytes {
+		return errBundleTooLarge
+	}
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return errNoBundle
+	}
+	return nil
+}
+
+// writeBundleFile writes a bundle to path, replacing it only once the bundle is complete
+func writeBundleFile(path string, src plugins.BundleSource) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".bundle-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := plugins.WriteBundle(tmp, src); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
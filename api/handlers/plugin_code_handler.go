@@ -1,14 +1,22 @@
 package handlers
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/uzzalhcse/crawlify/internal/logger"
 	"github.com/uzzalhcse/crawlify/internal/plugin"
 	"github.com/uzzalhcse/crawlify/internal/storage"
+	"github.com/uzzalhcse/crawlify/pkg/models"
+	"github.com/valyala/fasthttp"
 	"go.uber.org/zap"
 )
 
@@ -59,12 +67,17 @@ func (h *PluginCodeHandler) GetPluginSource(c *fiber.Ctx) error {
 }
 
 // UpdatePluginSource handles PUT /api/v1/plugins/:id/code
+// The body {"files": {...}, "delete": [...], "author": "...", "message": "..."} is saved as a new
+// source revision; files not listed are kept.
 func (h *PluginCodeHandler) UpdatePluginSource(c *fiber.Ctx) error {
 	pluginID := c.Params("id")
 	ctx := c.Context()
 
 	var req struct {
-		Files map[string]string `json:"files"`
+		Files   map[string]string `json:"files"`
+		Delete  []string          `json:"delete"`
+		Author  string            `json:"author"`
+		Message string            `json:"message"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -81,7 +94,18 @@ func (h *PluginCodeHandler) UpdatePluginSource(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := h.sourceManager.UpdateSource(p.Slug, req.Files); err != nil {
+	revision, err := h.sourceManager.UpdateSource(ctx, p.Slug, plugin.SourceUpdate{
+		Files:   req.Files,
+		Delete:  req.Delete,
+		Author:  req.Author,
+		Message: req.Message,
+	})
+	if errors.Is(err, plugin.ErrInvalidSourcePath) || errors.Is(err, plugin.ErrSourceTooLarge) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
 		logger.Error("Failed to update plugin source", zap.String("plugin_id", pluginID), zap.Error(err))
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": fmt.Sprintf("Failed to update plugin source: %v", err),
@@ -89,15 +113,194 @@ func (h *PluginCodeHandler) UpdatePluginSource(c *fiber.Ctx) error {
 	}
 
 	return c.JSON(fiber.Map{
-		"message": "Source updated successfully",
+		"message":  "Source updated successfully",
+		"revision": revision.Revision,
+		"changes":  revision.Changes,
+	})
+}
+
+// ListSourceRevisions handles GET /api/v1/plugins/:id/code/revisions
+func (h *PluginCodeHandler) ListSourceRevisions(c *fiber.Ctx) error {
+	pluginID := c.Params("id")
+
+	p, err := h.pluginRepo.GetPluginByID(c.Context(), pluginID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Plugin not found",
+		})
+	}
+
+	revisions, err := h.sourceManager.ListRevisions(c.Context(), p.Slug, c.QueryInt("limit", 50), c.QueryInt("offset", 0))
+	if err != nil {
+		logger.Error("Failed to list source revisions", zap.String("plugin_id", pluginID), zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to list source revisions",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"revisions": revisions,
+	})
+}
+
+// GetSourceRevision handles GET /api/v1/plugins/:id/code/revisions/:revision
+// It returns the revision's files and its diff against the previous revision
+func (h *PluginCodeHandler) GetSourceRevision(c *fiber.Ctx) error {
+	p, revision, ok := h.revisionParams(c)
+	if !ok {
+		return nil
+	}
+
+	rev, err := h.sourceManager.GetRevision(c.Context(), p.Slug, revision)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Source revision not found",
+		})
+	}
+
+	return c.JSON(rev)
+}
+
+// RollbackSource handles POST /api/v1/plugins/:id/code/revisions/:revision/rollback
+// The source is restored to the revision, which is recorded as a new revision; an optional
+// body {"author": "..."} names who rolled back
+func (h *PluginCodeHandler) RollbackSource(c *fiber.Ctx) error {
+	p, revision, ok := h.revisionParams(c)
+	if !ok {
+		return nil
+	}
+
+	var req struct {
+		Author string `json:"author"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
+	if _, err := h.sourceManager.GetRevision(c.Context(), p.Slug, revision); err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Source revision not found",
+		})
+	}
+
+	rev, err := h.sourceManager.Rollback(c.Context(), p.Slug, revision, req.Author)
+	if err != nil {
+		logger.Error("Failed to roll back plugin source",
+			zap.String("plugin_id", p.ID), zap.Int("revision", revision), zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": fmt.Sprintf("Failed to roll back plugin source: %v", err),
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":  fmt.Sprintf("Source rolled back to revision %d", revision),
+		"revision": rev.Revision,
+		"changes":  rev.Changes,
 	})
 }
 
+// DiffSource handles GET /api/v1/plugins/:id/code/diff?from=3&to=5
+// Revision 0, the default for to, is the working tree
+func (h *PluginCodeHandler) DiffSource(c *fiber.Ctx) error {
+	pluginID := c.Params("id")
+
+	p, err := h.pluginRepo.GetPluginByID(c.Context(), pluginID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Plugin not found",
+		})
+	}
+
+	from, to := c.QueryInt("from", -1), c.QueryInt("to", 0)
+	if from < 0 || to < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "from must be a revision number",
+		})
+	}
+	for _, revision := range []int{from, to} {
+		if revision == 0 {
+			continue
+		}
+		if _, err := h.sourceManager.GetRevision(c.Context(), p.Slug, revision); err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": fmt.Sprintf("Source revision %d not found", revision),
+			})
+		}
+	}
+
+	diff, changes, err := h.sourceManager.Diff(c.Context(), p.Slug, from, to)
+	if err != nil {
+		logger.Error("Failed to diff plugin source", zap.String("plugin_id", pluginID), zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to diff plugin source",
+		})
+	}
+
+	if c.Query("format") == "patch" {
+		c.Set(fiber.HeaderContentType, "text/x-diff; charset=utf-8")
+		return c.SendString(diff)
+	}
+	return c.JSON(fiber.Map{
+		"from":    from,
+		"to":      to,
+		"changes": changes,
+		"diff":    diff,
+	})
+}
+
+// revisionParams reads the plugin and revision number of a revision route
+// When either is invalid it writes the error response and returns false.
+func (h *PluginCodeHandler) revisionParams(c *fiber.Ctx) (*models.Plugin, int, bool) {
+	p, err := h.pluginRepo.GetPluginByID(c.Context(), c.Params("id"))
+	if err != nil {
+		c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Plugin not found",
+		})
+		return nil, 0, false
+	}
+	revision, err := c.ParamsInt("revision")
+	if err != nil || revision <= 0 {
+		c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid revision number",
+		})
+		return nil, 0, false
+	}
+	return p, revision, true
+}
+
 // BuildPlugin handles POST /api/v1/plugins/:id/build
+// An optional body {"version": "1.2.0"} records the artifact on that published version, and
+// {"revision": 4} builds that source revision instead of the working tree
 func (h *PluginCodeHandler) BuildPlugin(c *fiber.Ctx) error {
+	return h.queueBuild(c, false)
+}
+
+// TestPlugin handles POST /api/v1/plugins/:id/test
+// It runs the plugin's bundled tests without building it; poll the job via the build status endpoint
+func (h *PluginCodeHandler) TestPlugin(c *fiber.Ctx) error {
+	return h.queueBuild(c, true)
+}
+
+func (h *PluginCodeHandler) queueBuild(c *fiber.Ctx, testsOnly bool) error {
 	pluginID := c.Params("id")
 	ctx := c.Context()
 
+	var req struct {
+		Version  string `json:"version"`
+		Revision int    `json:"revision"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
 	// Get plugin to find slug
 	p, err := h.pluginRepo.GetPluginByID(ctx, pluginID)
 	if err != nil {
@@ -106,7 +309,45 @@ func (h *PluginCodeHandler) BuildPlugin(c *fiber.Ctx) error {
 		})
 	}
 
-	jobID, err := h.builder.Build(p.Slug)
+	buildReq := plugin.BuildRequest{PluginID: p.ID, PluginSlug: p.Slug, Revision: req.Revision, TestsOnly: testsOnly}
+	if req.Revision < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid revision number",
+		})
+	}
+	if req.Revision > 0 {
+		if _, err := h.sourceManager.GetRevision(ctx, p.Slug, req.Revision); err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": fmt.Sprintf("Source revision %d not found", req.Revision),
+			})
+		}
+	}
+	if req.Version != "" && !testsOnly {
+		versions, err := h.pluginRepo.ListVersions(ctx, pluginID)
+		if err != nil {
+			logger.Error("Failed to list plugin versions", zap.String("plugin_id", pluginID), zap.Error(err))
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to list plugin versions",
+			})
+		}
+		for _, v := range versions {
+			if v.Version == req.Version {
+				buildReq.PluginVersionID = v.ID
+			}
+		}
+		if buildReq.PluginVersionID == "" {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": fmt.Sprintf("Plugin version %s not found; publish it before building", req.Version),
+			})
+		}
+	}
+
+	build, err := h.builder.Build(ctx, buildReq)
+	if errors.Is(err, plugin.ErrQueueFull) {
+		return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+			"error": "Build queue is full, try again later",
+		})
+	}
 	if err != nil {
 		logger.Error("Failed to trigger build", zap.String("plugin_id", pluginID), zap.Error(err))
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -114,24 +355,116 @@ func (h *PluginCodeHandler) BuildPlugin(c *fiber.Ctx) error {
 		})
 	}
 
+	message := "Build triggered successfully"
+	if testsOnly {
+		message = "Plugin tests triggered successfully"
+	}
 	return c.JSON(fiber.Map{
-		"build_id": jobID,
-		"message":  "Build triggered successfully",
+		"build_id": build.ID,
+		"status":   build.Status,
+		"message":  message,
+	})
+}
+
+// ListPluginBuilds handles GET /api/v1/plugins/:id/builds
+func (h *PluginCodeHandler) ListPluginBuilds(c *fiber.Ctx) error {
+	pluginID := c.Params("id")
+
+	p, err := h.pluginRepo.GetPluginByID(c.Context(), pluginID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Plugin not found",
+		})
+	}
+
+	builds, err := h.builder.ListBuilds(c.Context(), p.Slug, c.QueryInt("limit", 20))
+	if err != nil {
+		logger.Error("Failed to list builds", zap.String("plugin_id", pluginID), zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to list builds",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"builds": builds,
 	})
 }
 
 // GetBuildStatus handles GET /api/v1/builds/:build_id/status
+// With ?stream=true or Accept: text/event-stream the log is streamed as server-sent events
+// ("log" with new output, "status" on changes) until a final "done" event with the build.
 func (h *PluginCodeHandler) GetBuildStatus(c *fiber.Ctx) error {
 	buildID := c.Params("build_id")
 
-	job, err := h.builder.GetBuildJob(buildID)
+	job, err := h.builder.GetBuildJob(c.Context(), buildID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Build job not found",
 		})
 	}
 
-	return c.JSON(job)
+	if c.Query("stream") != "true" && !strings.Contains(c.Get(fiber.HeaderAccept), "text/event-stream") {
+		return c.JSON(job)
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("Transfer-Encoding", "chunked")
+
+	ctx := c.Context()
+	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		sent := 0
+		status := models.BuildStatus("")
+		send := func(build *models.PluginBuild) error {
+			if len(build.Log) > sent {
+				data, _ := json.Marshal(build.Log[sent:])
+				sent = len(build.Log)
+				fmt.Fprintf(w, "event: log\ndata: %s\n\n", data)
+			}
+			if build.Status != status {
+				status = build.Status
+				fmt.Fprintf(w, "event: status\ndata: %q\n\n", status)
+			}
+			return w.Flush()
+		}
+		done := func(build *models.PluginBuild) {
+			data, _ := json.Marshal(build)
+			fmt.Fprintf(w, "event: done\ndata: %s\n\n", data)
+			w.Flush()
+		}
+
+		for {
+			build, changed, active := h.builder.Follow(buildID)
+			if !active {
+				// Finished: the stored build has the complete log
+				final, err := h.builder.GetBuildJob(context.Background(), buildID)
+				if err != nil {
+					final = job
+				}
+				send(final)
+				done(final)
+				return
+			}
+			if err := send(&build); err != nil {
+				logger.Debug("Client disconnected from build log", zap.String("build_id", buildID), zap.Error(err))
+				return
+			}
+
+			select {
+			case <-changed:
+			case <-ctx.Done():
+				return
+			case <-time.After(30 * time.Second):
+				fmt.Fprintf(w, ": keepalive\n\n")
+				if err := w.Flush(); err != nil {
+					return
+				}
+			}
+		}
+	}))
+
+	return nil
 }
 
 // ScaffoldPlugin handles POST /api/v1/plugins/scaffold
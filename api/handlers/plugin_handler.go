@@ -1,11 +1,15 @@
 package handlers
 
 import (
+	"errors"
+	"os"
+	"runtime"
 	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/uzzalhcse/crawlify/internal/plugins"
 	"github.com/uzzalhcse/crawlify/internal/storage"
 	"github.com/uzzalhcse/crawlify/pkg/models"
 	"go.uber.org/zap"
@@ -13,15 +17,19 @@ import (
 
 // PluginHandler handles plugin marketplace API requests
 type PluginHandler struct {
-	pluginRepo *storage.PluginRepository
-	logger     *zap.Logger
+	pluginRepo   *storage.PluginRepository
+	workflowRepo *storage.WorkflowRepository
+	pluginLoader *plugins.PluginLoader // Reports runtime metrics and health; may be nil
+	logger       *zap.Logger
 }
 
 // NewPluginHandler creates a new plugin handler
-func NewPluginHandler(pluginRepo *storage.PluginRepository, logger *zap.Logger) *PluginHandler {
+func NewPluginHandler(pluginRepo *storage.PluginRepository, workflowRepo *storage.WorkflowRepository, pluginLoader *plugins.PluginLoader, logger *zap.Logger) *PluginHandler {
 	return &PluginHandler{
-		pluginRepo: pluginRepo,
-		logger:     logger,
+		pluginRepo:   pluginRepo,
+		workflowRepo: workflowRepo,
+		pluginLoader: pluginLoader,
+		logger:       logger,
 	}
 }
 
@@ -37,6 +45,8 @@ func (h *PluginHandler) CreatePlugin(c *fiber.Ctx) error {
 	if plugin.ID == "" {
 		plugin.ID = uuid.New().String()
 	}
+	// Plugins are only verified by signed bundles (see PluginBundleHandler)
+	plugin.IsVerified = false
 
 	if plugin.Name == "" || plugin.Slug == "" || plugin.PhaseType == "" {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
@@ -97,6 +107,39 @@ func (h *PluginHandler) GetPlugin(c *fiber.Ctx) error {
 	return c.JSON(plugin)
 }
 
+// ListPluginRuntimes handles GET /api/v1/plugins/runtime
+// It returns the metrics and health of every plugin version executed since the server started
+func (h *PluginHandler) ListPluginRuntimes(c *fiber.Ctx) error {
+	statuses := []plugins.PluginStatus{}
+	if h.pluginLoader != nil {
+		statuses = h.pluginLoader.PluginStatuses()
+	}
+	return c.JSON(fiber.Map{
+		"plugins": statuses,
+		"total":   len(statuses),
+	})
+}
+
+// GetPluginRuntime handles GET /api/v1/plugins/:slug/runtime
+func (h *PluginHandler) GetPluginRuntime(c *fiber.Ctx) error {
+	slug := c.Params("slug")
+
+	var statuses []plugins.PluginStatus
+	if h.pluginLoader != nil {
+		statuses = h.pluginLoader.PluginStatus(slug)
+	}
+	if len(statuses) == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Plugin has not been executed since the server started",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"slug":     slug,
+		"versions": statuses,
+	})
+}
+
 // UpdatePlugin handles PUT /api/v1/plugins/:id
 func (h *PluginHandler) UpdatePlugin(c *fiber.Ctx) error {
 	pluginID := c.Params("id")
@@ -148,12 +191,41 @@ func (h *PluginHandler) PublishVersion(c *fiber.Ctx) error {
 	version.ID = uuid.New().String()
 	version.PluginID = pluginID
 
+	if _, err := plugins.CanonicalVersion(version.Version); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if version.MinCrawlifyVersion != "" {
+		if _, err := plugins.CanonicalVersion(version.MinCrawlifyVersion); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "min_crawlify_version: " + err.Error(),
+			})
+		}
+	}
+
+	switch version.Runtime {
+	case "":
+		version.Runtime = models.PluginRuntimeNative
+	case models.PluginRuntimeNative, models.PluginRuntimeRPC, models.PluginRuntimeWASM:
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "runtime must be native, rpc or wasm",
+		})
+	}
+
+	// A version published here carries no signature
+	version.Manifest, version.Signature, version.SigningKeyID = "", "", ""
+
 	if err := h.pluginRepo.PublishVersion(c.Context(), &version); err != nil {
 		h.logger.Error("Failed to publish version", zap.Error(err))
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to publish version",
 		})
 	}
+	if err := h.pluginRepo.SetVerified(c.Context(), pluginID, false); err != nil {
+		h.logger.Warn("Failed to clear plugin verification", zap.String("plugin_id", pluginID), zap.Error(err))
+	}
 
 	return c.Status(fiber.StatusCreated).JSON(version)
 }
@@ -192,19 +264,57 @@ func (h *PluginHandler) InstallPlugin(c *fiber.Ctx) error {
 	pluginID := c.Params("id")
 
 	// Verify plugin exists
-	_, err := h.pluginRepo.GetPluginByID(c.Context(), pluginID)
+	plugin, err := h.pluginRepo.GetPluginByID(c.Context(), pluginID)
 	if err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Plugin not found",
 		})
 	}
 
+	// Version constraint to install (latest stable by default) and the capabilities and limits
+	// the admin approves for a WASM plugin (optional body)
+	var req struct {
+		Version              string                    `json:"version"`
+		ApprovedCapabilities models.PluginCapabilities `json:"approved_capabilities"`
+		ApprovedLimits       models.PluginLimits       `json:"approved_limits"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid request body",
+			})
+		}
+	}
+
 	workspaceID := c.Get("X-Workspace-ID", "default")
 
-	// Get or create a version for this plugin
-	version, err := h.pluginRepo.GetLatestVersion(c.Context(), pluginID)
+	versions, err := h.pluginRepo.ListVersions(c.Context(), pluginID)
 	if err != nil {
-		// No version exists, create a default one
+		h.logger.Error("Failed to list versions", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to list plugin versions",
+		})
+	}
+
+	// Pick the newest matching version this Crawlify can run, or create a default one
+	var version *models.PluginVersion
+	if len(versions) > 0 || req.Version != "" {
+		version, err = plugins.SelectRelease(versions, req.Version)
+		switch {
+		case errors.Is(err, plugins.ErrIncompatibleVersion):
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case errors.Is(err, plugins.ErrNoMatchingVersion):
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case err != nil:
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	} else {
 		version = &models.PluginVersion{
 			ID:          uuid.New().String(),
 			PluginID:    pluginID,
@@ -228,6 +338,67 @@ func (h *PluginHandler) InstallPlugin(c *fiber.Ctx) error {
 		PluginVersionID: version.ID,
 		WorkspaceID:     workspaceID,
 		InstalledAt:     time.Now(),
+		Version:         version.Version,
+	}
+
+	// Store the version's binary side by side with other versions so later builds cannot change it.
+	// The published binary for this platform is used, or else the current development build.
+	pluginVersion, err := plugins.CanonicalVersion(version.Version)
+	if err != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	pluginRuntime := version.Runtime
+	if pluginRuntime == "" {
+		pluginRuntime = models.PluginRuntimeNative
+	}
+	source := version.BinaryPath(runtime.GOOS, runtime.GOARCH)
+	if source == "" {
+		source = plugins.DevBuildPath(plugin.Slug, pluginRuntime)
+	}
+	if _, err := os.Stat(source); err == nil {
+		installation.ArtifactPath, err = plugins.InstallArtifact(plugin.Slug, pluginVersion, pluginRuntime, source, version.BinaryHash)
+		if err != nil {
+			h.logger.Error("Failed to store plugin binary", zap.String("plugin", plugin.Slug), zap.Error(err))
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+	} else if version.BinaryPath(runtime.GOOS, runtime.GOARCH) != "" {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Published binary not found: " + source,
+		})
+	}
+
+	// A WASM plugin only gets the capabilities it asks for once an admin approves all of them
+	if version.Runtime == models.PluginRuntimeWASM {
+		if missing := req.ApprovedCapabilities.Missing(version.Capabilities); len(missing) > 0 {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":                  "Plugin requests capabilities that were not approved",
+				"missing":                missing,
+				"requested_capabilities": version.Capabilities,
+				"requested_limits":       version.Limits,
+			})
+		}
+		installation.ApprovedCapabilities = version.Capabilities.Intersect(req.ApprovedCapabilities)
+		installation.ApprovedLimits = req.ApprovedLimits
+		if installation.ApprovedLimits == (models.PluginLimits{}) {
+			installation.ApprovedLimits = version.Limits
+		}
+		installation.ApprovedLimits = installation.ApprovedLimits.Clamp(plugins.WASMMaxLimits)
+
+		modulePath := installation.ArtifactPath
+		if modulePath == "" {
+			modulePath = plugins.DevBuildPath(plugin.Slug, models.PluginRuntimeWASM)
+		}
+		grant := plugins.PluginGrant{Capabilities: installation.ApprovedCapabilities, Limits: installation.ApprovedLimits}
+		if err := plugins.WriteGrant(modulePath, grant); err != nil {
+			h.logger.Error("Failed to write plugin grant", zap.String("plugin", plugin.Slug), zap.Error(err))
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to store approved capabilities",
+			})
+		}
 	}
 
 	if err := h.pluginRepo.InstallPlugin(c.Context(), installation); err != nil {
@@ -237,15 +408,128 @@ func (h *PluginHandler) InstallPlugin(c *fiber.Ctx) error {
 		})
 	}
 
+	// Plugin nodes without a plugin_version constraint now use this version
+	if installation.ArtifactPath != "" {
+		if err := plugins.LockVersion(plugin.Slug, pluginVersion); err != nil {
+			h.logger.Error("Failed to lock plugin version", zap.String("plugin", plugin.Slug), zap.Error(err))
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to lock plugin version",
+			})
+		}
+	}
+	if h.pluginLoader != nil {
+		h.pluginLoader.RetireSlug(plugin.Slug)
+	}
+
 	return c.JSON(installation)
 }
 
+// GetUpgradeImpact handles GET /api/v1/plugins/:id/upgrade-impact?version=<constraint>
+// It reports which workflows would run a different version of the plugin if the matching version
+// (latest stable by default) were installed
+func (h *PluginHandler) GetUpgradeImpact(c *fiber.Ctx) error {
+	pluginID := c.Params("id")
+
+	plugin, err := h.pluginRepo.GetPluginByID(c.Context(), pluginID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Plugin not found",
+		})
+	}
+
+	versions, err := h.pluginRepo.ListVersions(c.Context(), pluginID)
+	if err != nil {
+		h.logger.Error("Failed to list versions", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to list plugin versions",
+		})
+	}
+	target, err := plugins.SelectRelease(versions, c.Query("version"))
+	switch {
+	case errors.Is(err, plugins.ErrIncompatibleVersion):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case errors.Is(err, plugins.ErrNoMatchingVersion):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	case err != nil:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	targetVersion, err := plugins.CanonicalVersion(target.Version)
+	if err != nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	workflows, err := h.workflowRepo.List(c.Context(), "", 0, 0)
+	if err != nil {
+		h.logger.Error("Failed to list workflows", zap.Error(err))
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to list workflows",
+		})
+	}
+
+	type workflowImpact struct {
+		WorkflowID string                `json:"workflow_id"`
+		Name       string                `json:"name"`
+		Status     models.WorkflowStatus `json:"status"`
+		Affected   bool                  `json:"affected"`
+		Nodes      []plugins.NodeUpgrade `json:"nodes"`
+	}
+	impacts := []workflowImpact{}
+	affected := 0
+	for _, workflow := range workflows {
+		nodes, err := plugins.UpgradeImpact(plugin.Slug, targetVersion, workflow.Config.PluginRefs())
+		if err != nil {
+			h.logger.Error("Failed to resolve plugin versions", zap.String("plugin", plugin.Slug), zap.Error(err))
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to resolve installed plugin versions",
+			})
+		}
+		if len(nodes) == 0 {
+			continue
+		}
+		impact := workflowImpact{WorkflowID: workflow.ID, Name: workflow.Name, Status: workflow.Status, Nodes: nodes}
+		for _, node := range nodes {
+			impact.Affected = impact.Affected || node.Affected
+		}
+		if impact.Affected {
+			affected++
+		}
+		impacts = append(impacts, impact)
+	}
+
+	currentVersion, _ := plugins.LockedVersion(plugin.Slug)
+	return c.JSON(fiber.Map{
+		"plugin_id":            pluginID,
+		"slug":                 plugin.Slug,
+		"current_version":      currentVersion,
+		"target_version":       targetVersion,
+		"target_version_id":    target.ID,
+		"min_crawlify_version": target.MinCrawlifyVersion,
+		"workflows":            impacts,
+		"affected_workflows":   affected,
+	})
+}
+
 // UninstallPlugin handles DELETE /api/v1/plugins/:id/uninstall
 func (h *PluginHandler) UninstallPlugin(c *fiber.Ctx) error {
 	pluginID := c.Params("id")
 
 	workspaceID := c.Get("X-Workspace-ID", "default")
 
+	plugin, err := h.pluginRepo.GetPluginByID(c.Context(), pluginID)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Plugin not found",
+		})
+	}
+
 	if err := h.pluginRepo.UninstallPlugin(c.Context(), pluginID, workspaceID); err != nil {
 		h.logger.Error("Failed to uninstall plugin", zap.Error(err))
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -253,6 +537,14 @@ func (h *PluginHandler) UninstallPlugin(c *fiber.Ctx) error {
 		})
 	}
 
+	// Stored versions stay so nodes pinned with plugin_version keep working
+	if err := plugins.UnlockVersion(plugin.Slug); err != nil {
+		h.logger.Warn("Failed to unlock plugin version", zap.String("plugin", plugin.Slug), zap.Error(err))
+	}
+	if h.pluginLoader != nil {
+		h.pluginLoader.RetireSlug(plugin.Slug)
+	}
+
 	return c.SendStatus(fiber.StatusNoContent)
 }
 
@@ -7,6 +7,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
 	"github.com/uzzalhcse/crawlify/internal/logger"
+	"github.com/uzzalhcse/crawlify/internal/schema"
 	"github.com/uzzalhcse/crawlify/internal/storage"
 	"github.com/uzzalhcse/crawlify/internal/workflow"
 	"github.com/uzzalhcse/crawlify/pkg/models"
@@ -87,6 +88,42 @@ func (h *WorkflowHandler) GetWorkflow(c *fiber.Ctx) error {
 	return c.JSON(workflow)
 }
 
+// GetWorkflowSchema exports the workflow's output schemas as JSON Schema, keyed by phase ID
+func (h *WorkflowHandler) GetWorkflowSchema(c *fiber.Ctx) error {
+	id := c.Params("id")
+
+	workflow, err := h.repo.GetByID(context.Background(), id)
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Workflow not found",
+		})
+	}
+
+	schemas := make(map[string]interface{})
+	for i := range workflow.Config.Phases {
+		phase := &workflow.Config.Phases[i]
+		if itemSchema := schema.ForPhase(&workflow.Config, phase); itemSchema != nil {
+			schemas[phase.ID] = schema.ToJSONSchema(itemSchema)
+		}
+	}
+
+	// ?phase= returns the bare JSON Schema document for one phase
+	if phaseID := c.Query("phase"); phaseID != "" {
+		doc, ok := schemas[phaseID]
+		if !ok {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "No schema defined for phase",
+			})
+		}
+		return c.JSON(doc)
+	}
+
+	return c.JSON(fiber.Map{
+		"workflow_id": workflow.ID,
+		"schemas":     schemas,
+	})
+}
+
 // ListWorkflows lists all workflows
 func (h *WorkflowHandler) ListWorkflows(c *fiber.Ctx) error {
 	status := models.WorkflowStatus(c.Query("status", ""))
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/uzzalhcse/crawlify/internal/ai"
+	"github.com/uzzalhcse/crawlify/internal/ai/aiusage"
+	"github.com/uzzalhcse/crawlify/internal/logger"
+	"go.uber.org/zap"
+)
+
+// WorkflowGeneratorHandler proposes workflows from a start URL and a goal
+type WorkflowGeneratorHandler struct {
+	generator *ai.WorkflowGenerator
+}
+
+// NewWorkflowGeneratorHandler creates a new workflow generator handler
+func NewWorkflowGeneratorHandler(generator *ai.WorkflowGenerator) *WorkflowGeneratorHandler {
+	return &WorkflowGeneratorHandler{generator: generator}
+}
+
+// GenerateWorkflow proposes a workflow and previews it on sample pages
+// The proposal is not saved; create it with POST /api/v1/workflows once reviewed.
+// POST /api/v1/workflows/generate
+func (h *WorkflowGeneratorHandler) GenerateWorkflow(c *fiber.Ctx) error {
+	var req ai.GenerateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if req.URL == "" || strings.TrimSpace(req.Goal) == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "url and goal are required",
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	generated, err := h.generator.Generate(ctx, &req)
+	if errors.Is(err, aiusage.ErrBudgetExceeded) {
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if err != nil {
+		logger.Error("Workflow generation failed", zap.String("url", req.URL), zap.Error(err))
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+			"error": "Workflow generation failed: " + err.Error(),
+		})
+	}
+
+	return c.JSON(generated)
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/uzzalhcse/crawlify/internal/logger"
+	"github.com/uzzalhcse/crawlify/internal/plugins"
 	"github.com/uzzalhcse/crawlify/internal/storage"
 	"github.com/uzzalhcse/crawlify/pkg/models"
 	"go.uber.org/zap"
@@ -83,6 +84,7 @@ func (h *WorkflowVersionHandler) RollbackVersion(c *fiber.Ctx) error {
 		Version:      newVersionNum,
 		Config:       targetVersion.Config, // Restore config from target version
 		ChangeReason: "Rollback to version " + versionStr,
+		Plugins:      plugins.ResolveWorkflowPlugins(&targetVersion.Config),
 	}
 
 	if err := h.versionRepo.Create(c.Context(), newVersion); err != nil {
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
 	"syscall"
@@ -14,6 +15,7 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/requestid"
 	"github.com/uzzalhcse/crawlify/api/handlers"
 	"github.com/uzzalhcse/crawlify/internal/ai"
+	"github.com/uzzalhcse/crawlify/internal/assets"
 	"github.com/uzzalhcse/crawlify/internal/browser"
 	"github.com/uzzalhcse/crawlify/internal/config"
 	"github.com/uzzalhcse/crawlify/internal/error_recovery"
@@ -23,6 +25,7 @@ import (
 	"github.com/uzzalhcse/crawlify/internal/queue"
 	"github.com/uzzalhcse/crawlify/internal/storage"
 	"github.com/uzzalhcse/crawlify/internal/workflow"
+	"github.com/uzzalhcse/crawlify/pkg/version"
 	"go.uber.org/zap"
 )
 
@@ -68,6 +71,14 @@ func main() {
 	}
 	defer browserPool.Close()
 
+	// Initialize asset downloads (images and files referenced by extracted items)
+	assetDownloader, err := assets.NewDownloaderFromConfig(&cfg.Assets)
+	if err != nil {
+		logger.Warn("Asset downloads disabled", zap.Error(err))
+	} else {
+		logger.Info("Asset downloads enabled", zap.String("storage", cfg.Assets.Storage))
+	}
+
 	// Initialize Fiber app
 	app := fiber.New(fiber.Config{
 		AppName:               "Crawlify API",
@@ -75,11 +86,17 @@ func main() {
 		ErrorHandler:          errorHandler,
 		ReadTimeout:           time.Duration(cfg.Server.ReadTimeout) * time.Second,
 		WriteTimeout:          time.Duration(cfg.Server.WriteTimeout) * time.Second,
+		BodyLimit:             fiber.DefaultBodyLimit,
+		// Bodies are read on demand so the bundle import can take up to plugins.max_bundle_size;
+		// limitBody holds every other route to BodyLimit
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
 	})
 
 	// Middleware
 	app.Use(recover.New())
 	app.Use(requestid.New())
+	app.Use(limitBody(fiber.DefaultBodyLimit, "/api/v1/plugins/import"))
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "*",
 		AllowMethods: "GET,POST,PUT,DELETE,PATCH",
@@ -141,33 +158,35 @@ func main() {
 	// Initialize AI services for auto-fix with key rotation
 	zapLogger, _ := zap.NewProduction()
 
-	// Initialize key repository and manager
+	// Each AI feature uses its configured provider; remote providers rotate through stored keys
 	aiKeyRepo := storage.NewAIKeyRepository(db, zapLogger)
-	keyManager := ai.NewAIKeyManager(aiKeyRepo, zapLogger)
+	aiClients := ai.NewClientSet(cfg.AI, aiKeyRepo, zapLogger)
+	defer aiClients.Close()
 
-	// Initialize AI client based on configured provider
-	var aiClient interface {
-		GenerateText(context.Context, string) (string, error)
-		GenerateWithImage(context.Context, string, []byte, string) (string, error)
-		Close() error
-	}
+	// Every AI call is logged, counted against the daily budgets and answered from cache when it can be
+	aiUsageRepo := storage.NewAIUsageRepository(db)
+	aiMeter := ai.NewMeter(aiUsageRepo, cfg.AI, zapLogger)
+	aiClients.SetMeter(aiMeter)
+	aiUsageHandler := handlers.NewAIUsageHandler(aiUsageRepo, aiMeter)
 
-	if cfg.AI.Provider == "openrouter" {
-		aiClient, err = ai.NewOpenRouterClient(keyManager, cfg.AI.OpenRouterModel, zapLogger)
-		if err != nil {
-			logger.Fatal("Failed to initialize OpenRouter client", zap.Error(err))
-		}
-		logger.Info("Using OpenRouter AI provider", zap.String("model", cfg.AI.OpenRouterModel))
-	} else {
-		aiClient, err = ai.NewGeminiClient(keyManager, cfg.AI.GeminiModel, zapLogger)
-		if err != nil {
-			logger.Fatal("Failed to initialize Gemini client", zap.Error(err))
-		}
-		logger.Info("Using Gemini AI provider", zap.String("model", cfg.AI.GeminiModel))
+	autoFixClient, err := aiClients.ForFeature(ai.FeatureAutoFix)
+	if err != nil {
+		logger.Fatal("Failed to initialize AI client", zap.Error(err))
+	}
+	recoveryClient, err := aiClients.ForFeature(ai.FeatureRecovery)
+	if err != nil {
+		logger.Fatal("Failed to initialize AI client", zap.Error(err))
 	}
-	defer aiClient.Close()
+	generationClient, err := aiClients.ForFeature(ai.FeatureGeneration)
+	if err != nil {
+		logger.Fatal("Failed to initialize AI client", zap.Error(err))
+	}
+	logger.Info("AI providers configured",
+		zap.String("autofix", aiClients.Provider(ai.FeatureAutoFix)),
+		zap.String("recovery", aiClients.Provider(ai.FeatureRecovery)),
+		zap.String("generation", aiClients.Provider(ai.FeatureGeneration)))
 
-	// Initialize Error Recovery System (after aiClient)
+	// Initialize Error Recovery System (after the AI clients)
 	errorRecoveryRepo := storage.NewErrorRecoveryRepository(db)
 	ctx := context.Background()
 	rules, err := errorRecoveryRepo.ListRules(ctx)
@@ -200,7 +219,7 @@ func main() {
 			AIEnabled:      true,
 		},
 		rules,
-		aiClient,
+		recoveryClient,
 	)
 	logger.Info("Error recovery system initialized", zap.Int("rules", len(rules)))
 
@@ -211,9 +230,10 @@ func main() {
 	errorRecoveryHandler := handlers.NewErrorRecoveryHandler(errorRecoveryRepo)
 
 	// Create ExecutionHandler with errorRecoverySystem
-	executionHandler := handlers.NewExecutionHandler(workflowRepo, executionRepo, extractedItemsRepo, nodeExecRepo, browserPool, urlQueue, errorRecoverySystem, recoveryHistoryRepo)
+	executionHandler := handlers.NewExecutionHandler(workflowRepo, executionRepo, extractedItemsRepo, nodeExecRepo, browserPool, urlQueue, errorRecoverySystem, recoveryHistoryRepo, &cfg.Crawler, assetDownloader)
+	executionHandler.SetPluginKVRepository(storage.NewPluginKVRepository(db))
 
-	autoFixService := ai.NewAutoFixService(aiClient, zapLogger)
+	autoFixService := ai.NewAutoFixService(autoFixClient, aiClients.Model(ai.FeatureAutoFix), zapLogger)
 	fixSuggestionRepo := storage.NewFixSuggestionRepository(db)
 	autoFixHandler := handlers.NewAutoFixHandler(
 		snapshotRepo,
@@ -222,27 +242,46 @@ func main() {
 		workflowVersionRepo,
 		monitoringRepo,
 		autoFixService,
+		monitoringOrchestrator,
 		snapshotStoragePath,
+		cfg.AI.AutoFix.AutoApplyConfidence,
 	)
 
 	logger.Info("AI auto-fix service initialized with key rotation")
 
+	// Initialize workflow generator (proposes workflows from a URL and a goal)
+	workflowGenerator := ai.NewWorkflowGenerator(generationClient, browserPool, nodeRegistry, zapLogger)
+	workflowGeneratorHandler := handlers.NewWorkflowGeneratorHandler(workflowGenerator)
+
 	// Initialize schedule handler
 	scheduleHandler := handlers.NewScheduleHandler(scheduleRepo, schedulerService)
 
 	// Initialize plugin handler
-	pluginHandler := handlers.NewPluginHandler(pluginRepo, zapLogger)
+	pluginHandler := handlers.NewPluginHandler(pluginRepo, workflowRepo, executionHandler.GetPluginLoader(), zapLogger)
 
 	// Initialize plugin code handler (for editing/building plugins)
-	sourceManager := plugin.NewSourceManager("./examples/plugins")
-	builder := plugin.NewBuilder("./examples/plugins", "./plugins")
+	sourceManager := plugin.NewSourceManager("./examples/plugins", storage.NewPluginSourceRepository(db))
+	builder := plugin.NewBuilder(sourceManager, "./plugins", storage.NewPluginBuildRepository(db), cfg.Builds)
+	if err := builder.Start(ctx); err != nil {
+		logger.Error("Failed to recover plugin builds", zap.Error(err))
+	}
 	pluginCodeHandler := handlers.NewPluginCodeHandler(sourceManager, builder, pluginRepo)
 
+	// Initialize plugin bundle handler (signed export/import and the plugin registry)
+	pluginBundleHandler, err := handlers.NewPluginBundleHandler(pluginRepo, sourceManager, cfg.Plugins, zapLogger)
+	if err != nil {
+		logger.Fatal("Invalid plugin signing configuration", zap.Error(err))
+	}
+	if cfg.Plugins.RegistryDir != "" {
+		// Bundles published by this instance; other instances set plugins.registry_url to /registry/index.json
+		app.Static("/registry", cfg.Plugins.RegistryDir)
+	}
+
 	// Initialize browser profile handler
 	browserProfileHandler := handlers.NewBrowserProfileHandler(browserProfileRepo, browserPool.GetLauncher())
 
 	// Routes
-	setupRoutes(app, workflowHandler, workflowVersionHandler, executionHandler, analyticsHandler, selectorHandler, monitoringHandler, scheduleHandler, snapshotHandler, autoFixHandler, pluginHandler, pluginCodeHandler, browserProfileHandler, errorRecoveryHandler, recoveryHistoryHandler)
+	setupRoutes(app, workflowHandler, workflowVersionHandler, executionHandler, analyticsHandler, selectorHandler, monitoringHandler, scheduleHandler, snapshotHandler, autoFixHandler, pluginHandler, pluginCodeHandler, pluginBundleHandler, browserProfileHandler, errorRecoveryHandler, recoveryHistoryHandler, aiUsageHandler, workflowGeneratorHandler)
 
 	// Monitoring
 	app.Get("/health", func(c *fiber.Ctx) error {
@@ -258,7 +297,7 @@ func main() {
 
 		return c.JSON(fiber.Map{
 			"status":  "healthy",
-			"version": "1.0.0",
+			"version": version.Version,
 			"time":    time.Now().UTC(),
 		})
 	})
@@ -285,6 +324,13 @@ func main() {
 		// Stop scheduler
 		schedulerService.Stop()
 		logger.Info("Monitoring scheduler stopped")
+
+		// Stop plugin builds; queued builds resume on the next start
+		builder.Stop()
+
+		// Stop RPC plugin processes and release plugin instances
+		executionHandler.GetPluginLoader().CloseAll()
+		nodeRegistry.PluginLoader().CloseAll()
 	}()
 
 	if err := app.Listen(addr); err != nil {
@@ -292,17 +338,19 @@ func main() {
 	}
 }
 
-func setupRoutes(app *fiber.App, workflowHandler *handlers.WorkflowHandler, workflowVersionHandler *handlers.WorkflowVersionHandler, executionHandler *handlers.ExecutionHandler, analyticsHandler *handlers.AnalyticsHandler, selectorHandler *handlers.SelectorHandler, monitoringHandler *handlers.MonitoringHandler, scheduleHandler *handlers.ScheduleHandler, snapshotHandler *handlers.SnapshotHandler, autoFixHandler *handlers.AutoFixHandler, pluginHandler *handlers.PluginHandler, pluginCodeHandler *handlers.PluginCodeHandler, browserProfileHandler *handlers.BrowserProfileHandler, errorRecoveryHandler *handlers.ErrorRecoveryHandler, recoveryHistoryHandler *handlers.ErrorRecoveryHistoryHandler) {
+func setupRoutes(app *fiber.App, workflowHandler *handlers.WorkflowHandler, workflowVersionHandler *handlers.WorkflowVersionHandler, executionHandler *handlers.ExecutionHandler, analyticsHandler *handlers.AnalyticsHandler, selectorHandler *handlers.SelectorHandler, monitoringHandler *handlers.MonitoringHandler, scheduleHandler *handlers.ScheduleHandler, snapshotHandler *handlers.SnapshotHandler, autoFixHandler *handlers.AutoFixHandler, pluginHandler *handlers.PluginHandler, pluginCodeHandler *handlers.PluginCodeHandler, pluginBundleHandler *handlers.PluginBundleHandler, browserProfileHandler *handlers.BrowserProfileHandler, errorRecoveryHandler *handlers.ErrorRecoveryHandler, recoveryHistoryHandler *handlers.ErrorRecoveryHistoryHandler, aiUsageHandler *handlers.AIUsageHandler, workflowGeneratorHandler *handlers.WorkflowGeneratorHandler) {
 	api := app.Group("/api/v1")
 
 	// Workflow routes
 	workflows := api.Group("/workflows")
 	workflows.Post("/", workflowHandler.CreateWorkflow)
 	workflows.Get("/", workflowHandler.ListWorkflows)
+	workflows.Post("/generate", workflowGeneratorHandler.GenerateWorkflow)
 	workflows.Get("/:id", workflowHandler.GetWorkflow)
 	workflows.Put("/:id", workflowHandler.UpdateWorkflow)
 	workflows.Delete("/:id", workflowHandler.DeleteWorkflow)
 	workflows.Patch("/:id/status", workflowHandler.UpdateWorkflowStatus)
+	workflows.Get("/:id/schema", workflowHandler.GetWorkflowSchema)
 
 	// Workflow Version routes
 	workflows.Get("/:id/versions", workflowVersionHandler.ListVersions)
@@ -379,15 +427,24 @@ func setupRoutes(app *fiber.App, workflowHandler *handlers.WorkflowHandler, work
 	plugins.Get("/search", pluginHandler.SearchPlugins)
 	plugins.Get("/popular", pluginHandler.GetPopularPlugins)
 	plugins.Get("/installed", pluginHandler.ListInstalledPlugins)
+	plugins.Get("/runtime", pluginHandler.ListPluginRuntimes)
+	plugins.Get("/registry", pluginBundleHandler.ListRegistry)
+	plugins.Post("/registry/import", pluginBundleHandler.ImportFromRegistry)
+	plugins.Post("/import", pluginBundleHandler.ImportBundle)
 	plugins.Post("/", pluginHandler.CreatePlugin)
 	plugins.Get("/", pluginHandler.ListPlugins)
 	plugins.Get("/:slug", pluginHandler.GetPlugin)
+	plugins.Get("/:slug/runtime", pluginHandler.GetPluginRuntime)
 	plugins.Put("/:id", pluginHandler.UpdatePlugin)
 	plugins.Delete("/:id", pluginHandler.DeletePlugin)
 	plugins.Post("/:id/versions", pluginHandler.PublishVersion)
 	plugins.Get("/:id/versions", pluginHandler.ListVersions)
 	plugins.Get("/:id/versions/:version", pluginHandler.GetVersion)
+	plugins.Get("/:id/versions/:version/bundle", pluginBundleHandler.ExportBundle)
+	plugins.Post("/:id/versions/:version/registry", pluginBundleHandler.PublishToRegistry)
+	plugins.Post("/:id/verify", pluginBundleHandler.VerifyPlugin)
 	plugins.Post("/:id/install", pluginHandler.InstallPlugin)
+	plugins.Get("/:id/upgrade-impact", pluginHandler.GetUpgradeImpact)
 	plugins.Post("/:id/uninstall", pluginHandler.UninstallPlugin)
 	plugins.Post("/:id/reviews", pluginHandler.CreateReview)
 	plugins.Get("/:id/reviews", pluginHandler.ListReviews)
@@ -395,7 +452,13 @@ func setupRoutes(app *fiber.App, workflowHandler *handlers.WorkflowHandler, work
 	// Plugin code management routes
 	plugins.Get("/:id/code", pluginCodeHandler.GetPluginSource)
 	plugins.Put("/:id/code", pluginCodeHandler.UpdatePluginSource)
+	plugins.Get("/:id/code/revisions", pluginCodeHandler.ListSourceRevisions)
+	plugins.Get("/:id/code/revisions/:revision", pluginCodeHandler.GetSourceRevision)
+	plugins.Post("/:id/code/revisions/:revision/rollback", pluginCodeHandler.RollbackSource)
+	plugins.Get("/:id/code/diff", pluginCodeHandler.DiffSource)
 	plugins.Post("/:id/build", pluginCodeHandler.BuildPlugin)
+	plugins.Post("/:id/test", pluginCodeHandler.TestPlugin)
+	plugins.Get("/:id/builds", pluginCodeHandler.ListPluginBuilds)
 	plugins.Post("/scaffold", pluginCodeHandler.ScaffoldPlugin)
 	plugins.Get("/:id/readme", pluginCodeHandler.GetPluginReadme)
 
@@ -438,6 +501,41 @@ func setupRoutes(app *fiber.App, workflowHandler *handlers.WorkflowHandler, work
 
 	// Workflow-specific recovery history
 	api.Get("/workflows/:id/recovery-history", recoveryHistoryHandler.GetWorkflowHistory)
+
+	// AI usage accounting routes
+	aiRoutes := api.Group("/ai")
+	aiRoutes.Get("/usage", aiUsageHandler.ListUsage)
+	aiRoutes.Get("/usage/summary", aiUsageHandler.GetSummary)
+	aiRoutes.Get("/budget", aiUsageHandler.GetBudget)
+	aiRoutes.Delete("/cache", aiUsageHandler.ClearCache)
+}
+
+// limitBody rejects request bodies over limit except on the given paths, which read
+// and limit their body themselves
+func limitBody(limit int, except ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		for _, path := range except {
+			if c.Path() == path {
+				return c.Next()
+			}
+		}
+		req := c.Request()
+		if req.Header.ContentLength() > limit {
+			return fiber.ErrRequestEntityTooLarge
+		}
+		if req.Header.ContentLength() < 0 && req.IsBodyStream() {
+			// A chunked body has no declared length
+			body, err := io.ReadAll(io.LimitReader(req.BodyStream(), int64(limit)+1))
+			if err != nil {
+				return fiber.ErrBadRequest
+			}
+			if len(body) > limit {
+				return fiber.ErrRequestEntityTooLarge
+			}
+			req.SetBody(body)
+		}
+		return c.Next()
+	}
 }
 
 func errorHandler(c *fiber.Ctx, err error) error {
@@ -0,0 +1,226 @@
+// Package aitest is a local stand-in for model servers, for testing AI clients and the features
+// built on them without a GPU or network access
+//
+// A Server answers OpenAI-compatible chat completions (POST .../chat/completions) and Ollama
+// chat (POST /api/chat) requests by replaying canned replies in order, and records every
+// request it gets.
+//
+//	srv := aitest.NewServer(t, aitest.Reply{Content: `{"suggested_selector": ".price"}`})
+//	client, _ := ai.NewOllamaClient(config.OllamaConfig{BaseURL: srv.URL, Model: "llama3.1"}, zap.NewNop())
+//	text, _ := client.GenerateText(ctx, prompt)
+//	srv.Requests()[0].Prompt // What the client sent
+package aitest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Reply is a canned answer
+type Reply struct {
+	Content          string `json:"content"`
+	Status           int    `json:"status,omitempty"` // HTTP status; 0 is 200
+	Error            string `json:"error,omitempty"`  // Error message sent with a non-200 status
+	PromptTokens     int    `json:"prompt_tokens,omitempty"`
+	CompletionTokens int    `json:"completion_tokens,omitempty"`
+}
+
+// Request is a request the server got, reduced to what a test checks
+type Request struct {
+	API    string   // "openai" or "ollama"
+	Model  string   // Model the client asked for
+	Prompt string   // Text of the messages, joined by newlines
+	Images []string // Base64 images; data: URLs keep their prefix
+	Header http.Header
+	Body   []byte
+}
+
+// Server replays canned replies to AI clients
+type Server struct {
+	*httptest.Server // URL is the base URL; OpenAI-compatible clients use URL + "/v1"
+
+	mu       sync.Mutex
+	replies  []Reply
+	fallback *Reply
+	requests []Request
+}
+
+// NewServer starts a server that answers with replies in order
+// Once they are used up, the last reply is repeated. The server is closed when the test ends.
+func NewServer(t testing.TB, replies ...Reply) *Server {
+	t.Helper()
+	s := &Server{replies: replies}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// LoadReplies reads canned replies from a JSON file holding an array of replies
+func LoadReplies(t testing.TB, path string) []Reply {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("aitest: %v", err)
+	}
+	var replies []Reply
+	if err := json.Unmarshal(data, &replies); err != nil {
+		t.Fatalf("aitest: invalid replies in %s: %v", path, err)
+	}
+	return replies
+}
+
+// Enqueue adds replies to send after the current ones
+func (s *Server) Enqueue(replies ...Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies = append(s.replies, replies...)
+}
+
+// Requests returns the requests received so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// next pops the next reply
+func (s *Server) next() Reply {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.replies) == 0 {
+		if s.fallback != nil {
+			return *s.fallback
+		}
+		return Reply{Status: http.StatusInternalServerError, Error: "aitest: no canned reply left"}
+	}
+	reply := s.replies[0]
+	s.replies = s.replies[1:]
+	s.fallback = &reply
+	return reply
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	req := Request{Header: r.Header.Clone(), Body: body}
+
+	switch {
+	case r.Method == http.MethodPost && strings.HasSuffix(r.URL.Path, "/chat/completions"):
+		req.API = "openai"
+		parseOpenAI(body, &req)
+	case r.Method == http.MethodPost && r.URL.Path == "/api/chat":
+		req.API = "ollama"
+		parseOllama(body, &req)
+	default:
+		http.NotFound(w, r)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	s.mu.Unlock()
+
+	reply := s.next()
+	w.Header().Set("Content-Type", "application/json")
+	if reply.Status != 0 && reply.Status != http.StatusOK {
+		w.WriteHeader(reply.Status)
+		if req.API == "ollama" {
+			json.NewEncoder(w).Encode(map[string]string{"error": reply.Error})
+		} else {
+			json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]string{"message": reply.Error}})
+		}
+		return
+	}
+
+	if req.API == "ollama" {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"model":             req.Model,
+			"message":           map[string]string{"role": "assistant", "content": reply.Content},
+			"done":              true,
+			"prompt_eval_count": reply.PromptTokens,
+			"eval_count":        reply.CompletionTokens,
+		})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"id":     fmt.Sprintf("aitest-%d", len(s.Requests())),
+		"object": "chat.completion",
+		"model":  req.Model,
+		"choices": []map[string]interface{}{{
+			"index":         0,
+			"message":       map[string]string{"role": "assistant", "content": reply.Content},
+			"finish_reason": "stop",
+		}},
+		"usage": map[string]int{
+			"prompt_tokens":     reply.PromptTokens,
+			"completion_tokens": reply.CompletionTokens,
+			"total_tokens":      reply.PromptTokens + reply.CompletionTokens,
+		},
+	})
+}
+
+func parseOpenAI(body []byte, req *Request) {
+	var payload struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Content json.RawMessage `json:"content"`
+		} `json:"messages"`
+	}
+	if json.Unmarshal(body, &payload) != nil {
+		return
+	}
+	req.Model = payload.Model
+	var texts []string
+	for _, m := range payload.Messages {
+		var text string
+		if json.Unmarshal(m.Content, &text) == nil {
+			texts = append(texts, text)
+			continue
+		}
+		var parts []struct {
+			Type     string `json:"type"`
+			Text     string `json:"text"`
+			ImageURL struct {
+				URL string `json:"url"`
+			} `json:"image_url"`
+		}
+		if json.Unmarshal(m.Content, &parts) != nil {
+			continue
+		}
+		for _, p := range parts {
+			switch p.Type {
+			case "text":
+				texts = append(texts, p.Text)
+			case "image_url":
+				req.Images = append(req.Images, p.ImageURL.URL)
+			}
+		}
+	}
+	req.Prompt = strings.Join(texts, "\n")
+}
+
+func parseOllama(body []byte, req *Request) {
+	var payload struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Content string   `json:"content"`
+			Images  []string `json:"images"`
+		} `json:"messages"`
+	}
+	if json.Unmarshal(body, &payload) != nil {
+		return
+	}
+	req.Model = payload.Model
+	var texts []string
+	for _, m := range payload.Messages {
+		texts = append(texts, m.Content)
+		req.Images = append(req.Images, m.Images...)
+	}
+	req.Prompt = strings.Join(texts, "\n")
+}
@@ -0,0 +1,54 @@
+// Package aiusage carries who an AI call is made for through a context, and the error AI
+// clients return when a budget is spent
+//
+// It has no dependencies so that any package making AI calls can use it.
+package aiusage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrBudgetExceeded is returned instead of making an AI call when the global or the workflow's
+// daily budget is spent; callers continue without AI (rules-only)
+var ErrBudgetExceeded = errors.New("AI budget exceeded")
+
+// ErrWorkflowBudgetExceeded is the ErrBudgetExceeded returned when only the workflow's own
+// budget is spent, so other workflows may still call
+var ErrWorkflowBudgetExceeded = fmt.Errorf("%w for the workflow", ErrBudgetExceeded)
+
+// Scope attributes an AI call to a feature and, when it has one, a workflow run
+type Scope struct {
+	Feature     string
+	WorkflowID  string
+	ExecutionID string
+
+	// CacheKey replaces the prompt in the response cache key. Callers set it when prompts
+	// differ in details that do not change the answer, such as the URL of a failing page.
+	CacheKey string
+}
+
+type scopeKey struct{}
+
+// WithScope returns a context whose AI calls are attributed to scope
+// Empty fields of scope, except CacheKey, keep the values of a scope already in ctx.
+func WithScope(ctx context.Context, scope Scope) context.Context {
+	parent := FromContext(ctx)
+	if scope.Feature == "" {
+		scope.Feature = parent.Feature
+	}
+	if scope.WorkflowID == "" {
+		scope.WorkflowID = parent.WorkflowID
+	}
+	if scope.ExecutionID == "" {
+		scope.ExecutionID = parent.ExecutionID
+	}
+	return context.WithValue(ctx, scopeKey{}, scope)
+}
+
+// FromContext returns the scope of ctx; the zero Scope when it has none
+func FromContext(ctx context.Context) Scope {
+	scope, _ := ctx.Value(scopeKey{}).(Scope)
+	return scope
+}
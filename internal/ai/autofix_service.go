@@ -9,7 +9,6 @@ import (
 	"os"
 	"strings"
 
-	"github.com/PuerkitoBio/goquery"
 	"github.com/uzzalhcse/crawlify/pkg/models"
 	"go.uber.org/zap"
 )
@@ -24,27 +23,33 @@ type AIClient interface {
 // AutoFixService analyzes monitoring failures and suggests fixes
 type AutoFixService struct {
 	aiClient AIClient
+	model    string // Model answering for the client, recorded with suggestions
 	logger   *zap.Logger
 }
 
 // FixSuggestion represents an AI-generated fix suggestion
 type FixSuggestion struct {
-	SuggestedSelector    string                     `json:"suggested_selector"`
-	AlternativeSelectors []string                   `json:"alternative_selectors,omitempty"`
-	Confidence           float64                    `json:"confidence"`
-	Explanation          string                     `json:"explanation"`
-	SuggestedNodeConfig  map[string]interface{}     `json:"suggested_node_config,omitempty"`
-	VerificationResult   *models.VerificationResult `json:"verification_result,omitempty"`
+	SuggestedSelector    string                 `json:"suggested_selector"`
+	AlternativeSelectors []string               `json:"alternative_selectors,omitempty"`
+	Confidence           float64                `json:"confidence"`
+	Explanation          string                 `json:"explanation"`
+	SuggestedNodeConfig  map[string]interface{} `json:"suggested_node_config,omitempty"`
 }
 
 // NewAutoFixService creates a new autofix service
-func NewAutoFixService(aiClient AIClient, logger *zap.Logger) *AutoFixService {
+func NewAutoFixService(aiClient AIClient, model string, logger *zap.Logger) *AutoFixService {
 	return &AutoFixService{
 		aiClient: aiClient,
+		model:    model,
 		logger:   logger,
 	}
 }
 
+// Model returns the model the service's suggestions come from
+func (s *AutoFixService) Model() string {
+	return s.model
+}
+
 // AnalyzeSnapshot analyzes a monitoring snapshot and suggests fixes
 func (s *AutoFixService) AnalyzeSnapshot(ctx context.Context, snapshot *models.MonitoringSnapshot, screenshotPath string, baselinePreview string) (*FixSuggestion, error) {
 	s.logger.Info("Analyzing snapshot with AI",
@@ -98,31 +103,6 @@ func (s *AutoFixService) AnalyzeSnapshot(ctx context.Context, snapshot *models.M
 		return nil, fmt.Errorf("failed to parse AI response: %w", err)
 	}
 
-	// Verify suggestion against DOM snapshot if available
-	s.logger.Info("Checking for DOM snapshot for verification",
-		zap.Bool("has_dom_path", snapshot.DOMSnapshotPath != nil),
-		zap.String("dom_path", func() string {
-			if snapshot.DOMSnapshotPath != nil {
-				return *snapshot.DOMSnapshotPath
-			}
-			return "nil"
-		}()))
-
-	if snapshot.DOMSnapshotPath != nil && fileExists(*snapshot.DOMSnapshotPath) {
-		s.logger.Info("Running verification", zap.String("selector", suggestion.SuggestedSelector))
-		verificationResult := s.verifySuggestion(suggestion.SuggestedSelector, *snapshot.DOMSnapshotPath)
-		suggestion.VerificationResult = verificationResult
-
-		s.logger.Info("Suggestion verified",
-			zap.Bool("is_valid", verificationResult.IsValid),
-			zap.Int("elements_found", verificationResult.ElementsFound),
-			zap.Strings("data_preview", verificationResult.DataPreview))
-	} else {
-		s.logger.Warn("DOM snapshot not available for verification",
-			zap.Bool("path_is_nil", snapshot.DOMSnapshotPath == nil),
-			zap.Bool("file_exists", snapshot.DOMSnapshotPath != nil && fileExists(*snapshot.DOMSnapshotPath)))
-	}
-
 	s.logger.Info("AI analysis complete",
 		zap.String("suggested_selector", suggestion.SuggestedSelector),
 		zap.Float64("confidence", suggestion.Confidence))
@@ -130,72 +110,6 @@ func (s *AutoFixService) AnalyzeSnapshot(ctx context.Context, snapshot *models.M
 	return suggestion, nil
 }
 
-// verifySuggestion verifies a selector against a DOM snapshot
-func (s *AutoFixService) verifySuggestion(selector string, domPath string) *models.VerificationResult {
-	result := &models.VerificationResult{
-		IsValid:       false,
-		ElementsFound: 0,
-		DataPreview:   []string{},
-	}
-
-	// Open DOM file
-	f, err := os.Open(domPath)
-	if err != nil {
-		result.ErrorMessage = fmt.Sprintf("Failed to open DOM file: %v", err)
-		return result
-	}
-	defer f.Close()
-
-	// Parse HTML
-	var doc *goquery.Document
-	if strings.HasSuffix(domPath, ".gz") {
-		// Handle gzipped file if needed (though current implementation might not gzip yet)
-		// For now assuming plain HTML or handling decompression if we add it
-		// If we strictly use .html.gz, we need gzip reader.
-		// Let's check if we are using gzip in snapshot service.
-		// SnapshotService uses gzip.NewWriter. So we need gzip reader.
-
-		gz, err := gzip.NewReader(f)
-		if err != nil {
-			result.ErrorMessage = fmt.Sprintf("Failed to create gzip reader: %v", err)
-			return result
-		}
-		defer gz.Close()
-		doc, err = goquery.NewDocumentFromReader(gz)
-	} else {
-		doc, err = goquery.NewDocumentFromReader(f)
-	}
-
-	if err != nil {
-		result.ErrorMessage = fmt.Sprintf("Failed to parse HTML: %v", err)
-		return result
-	}
-
-	// Find elements
-	selection := doc.Find(selector)
-	result.ElementsFound = selection.Length()
-
-	if result.ElementsFound > 0 {
-		result.IsValid = true
-		// Extract preview data (limit to 5 items)
-		selection.EachWithBreak(func(i int, s *goquery.Selection) bool {
-			text := strings.TrimSpace(s.Text())
-			if text != "" {
-				// Truncate long text
-				if len(text) > 100 {
-					text = text[:97] + "..."
-				}
-				result.DataPreview = append(result.DataPreview, text)
-			}
-			return i < 4 // Stop after 5 items (0-4)
-		})
-	} else {
-		result.ErrorMessage = "Selector found no elements in the DOM snapshot"
-	}
-
-	return result
-}
-
 // buildPrompt constructs the AI prompt from snapshot data
 func (s *AutoFixService) buildPrompt(snapshot *models.MonitoringSnapshot, domContent string, baselinePreview string) string {
 	var prompt strings.Builder
@@ -0,0 +1,71 @@
+package ai
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"golang.org/x/net/html"
+)
+
+// keptAttributes are the attributes useful for writing selectors; the rest are dropped
+var keptAttributes = map[string]bool{
+	"id": true, "class": true, "href": true, "src": true, "alt": true, "title": true,
+	"name": true, "type": true, "role": true, "aria-label": true, "content": true,
+	"itemprop": true, "itemtype": true, "itemscope": true, "property": true, "datetime": true,
+}
+
+var whitespaceRun = regexp.MustCompile(`\s+`)
+
+// trimDOM shrinks a page for a prompt: it drops scripts, styles, SVG, comments and
+// presentational attributes, shortens long text and cuts the result at maxBytes
+func trimDOM(page string, maxBytes int) (string, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(page))
+	if err != nil {
+		return "", err
+	}
+	doc.Find("script, style, noscript, svg, iframe, link, template, canvas").Remove()
+
+	body := doc.Find("body")
+	if body.Length() == 0 {
+		body = doc.Selection
+	}
+	for _, n := range body.Nodes {
+		trimNode(n)
+	}
+
+	out, err := goquery.OuterHtml(body)
+	if err != nil {
+		return "", err
+	}
+	out = whitespaceRun.ReplaceAllString(out, " ")
+	if len(out) > maxBytes {
+		out = strings.ToValidUTF8(out[:maxBytes], "") + "\n<!-- truncated -->"
+	}
+	return out, nil
+}
+
+// trimNode strips attributes and comments below n and shortens its text
+func trimNode(n *html.Node) {
+	for c := n.FirstChild; c != nil; {
+		next := c.NextSibling
+		switch c.Type {
+		case html.CommentNode:
+			n.RemoveChild(c)
+		case html.TextNode:
+			if text := []rune(strings.TrimSpace(c.Data)); len(text) > 200 {
+				c.Data = string(text[:200]) + "…"
+			}
+		case html.ElementNode:
+			attrs := c.Attr[:0]
+			for _, a := range c.Attr {
+				if keptAttributes[a.Key] || strings.HasPrefix(a.Key, "data-") && len(a.Val) <= 100 {
+					attrs = append(attrs, a)
+				}
+			}
+			c.Attr = attrs
+			trimNode(c)
+		}
+		c = next
+	}
+}
@@ -35,6 +35,23 @@ func NewGeminiClient(keyManager *AIKeyManager, model string, logger *zap.Logger)
 
 // GenerateText sends a text prompt to Gemini and returns the response
 func (g *GeminiClient) GenerateText(ctx context.Context, prompt string) (string, error) {
+	c, err := g.completeText(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return c.Text, nil
+}
+
+// GenerateWithImage sends a prompt with an image to Gemini
+func (g *GeminiClient) GenerateWithImage(ctx context.Context, prompt string, imageData []byte, mimeType string) (string, error) {
+	c, err := g.completeWithImage(ctx, prompt, imageData, mimeType)
+	if err != nil {
+		return "", err
+	}
+	return c.Text, nil
+}
+
+func (g *GeminiClient) completeText(ctx context.Context, prompt string) (*completion, error) {
 	var lastErr error
 
 	// Try with all available keys (up to 100 attempts to avoid infinite loops)
@@ -45,9 +62,9 @@ func (g *GeminiClient) GenerateText(ctx context.Context, prompt string) (string,
 		if err != nil {
 			// No more available keys
 			if attempt == 0 {
-				return "", fmt.Errorf("no API keys available: %w", err)
+				return nil, fmt.Errorf("no API keys available: %w", err)
 			}
-			return "", fmt.Errorf("failed after %d attempts, no more keys available: %w", attempt, lastErr)
+			return nil, fmt.Errorf("failed after %d attempts, no more keys available: %w", attempt, lastErr)
 		}
 
 		// Create client with current key using new SDK
@@ -55,7 +72,7 @@ func (g *GeminiClient) GenerateText(ctx context.Context, prompt string) (string,
 			APIKey: apiKey,
 		})
 		if err != nil {
-			return "", fmt.Errorf("failed to create Gemini client: %w", err)
+			return nil, fmt.Errorf("failed to create Gemini client: %w", err)
 		}
 
 		g.logger.Debug("Sending prompt to Gemini",
@@ -112,14 +129,13 @@ func (g *GeminiClient) GenerateText(ctx context.Context, prompt string) (string,
 			zap.String("key_id", g.keyManager.GetCurrentKeyID()),
 			zap.Int("attempts_used", attempt+1))
 
-		return result, nil
+		return geminiCompletion(result, g.model, resp), nil
 	}
 
-	return "", fmt.Errorf("failed after %d attempts: %w", maxAttempts, lastErr)
+	return nil, fmt.Errorf("failed after %d attempts: %w", maxAttempts, lastErr)
 }
 
-// GenerateWithImage sends a prompt with an image to Gemini
-func (g *GeminiClient) GenerateWithImage(ctx context.Context, prompt string, imageData []byte, mimeType string) (string, error) {
+func (g *GeminiClient) completeWithImage(ctx context.Context, prompt string, imageData []byte, mimeType string) (*completion, error) {
 	var lastErr error
 
 	// Try with all available keys (round-robin through all keys once)
@@ -130,9 +146,9 @@ func (g *GeminiClient) GenerateWithImage(ctx context.Context, prompt string, ima
 		apiKey, err := g.keyManager.GetAPIKey(ctx, "gemini")
 		if err != nil {
 			if attempt == 0 {
-				return "", fmt.Errorf("no API keys available: %w", err)
+				return nil, fmt.Errorf("no API keys available: %w", err)
 			}
-			return "", fmt.Errorf("failed after %d attempts, no more keys available: %w", attempt, lastErr)
+			return nil, fmt.Errorf("failed after %d attempts, no more keys available: %w", attempt, lastErr)
 		}
 
 		// Create client with current key using new SDK
@@ -149,7 +165,7 @@ func (g *GeminiClient) GenerateWithImage(ctx context.Context, prompt string, ima
 			APIKey: apiKey,
 		})
 		if err != nil {
-			return "", fmt.Errorf("failed to create Gemini client: %w", err)
+			return nil, fmt.Errorf("failed to create Gemini client: %w", err)
 		}
 
 		g.logger.Debug("Sending multimodal prompt to Gemini",
@@ -220,10 +236,10 @@ func (g *GeminiClient) GenerateWithImage(ctx context.Context, prompt string, ima
 			zap.String("key_id", g.keyManager.GetCurrentKeyID()),
 			zap.Int("attempts_used", attempt+1))
 
-		return result, nil
+		return geminiCompletion(result, g.model, resp), nil
 	}
 
-	return "", fmt.Errorf("failed after %d attempts: %w", maxAttempts, lastErr)
+	return nil, fmt.Errorf("failed after %d attempts: %w", maxAttempts, lastErr)
 }
 
 // GenerateWithBase64Image sends a prompt with a base64-encoded image
@@ -237,6 +253,19 @@ func (g *GeminiClient) GenerateWithBase64Image(ctx context.Context, prompt strin
 	return g.GenerateWithImage(ctx, prompt, imageData, mimeType)
 }
 
+// geminiCompletion adds the token counts of a response to its text; thinking is billed as output
+func geminiCompletion(text, model string, resp *genai.GenerateContentResponse) *completion {
+	c := &completion{Text: text, Model: model}
+	if resp.ModelVersion != "" {
+		c.Model = resp.ModelVersion
+	}
+	if u := resp.UsageMetadata; u != nil {
+		c.PromptTokens = int(u.PromptTokenCount)
+		c.CompletionTokens = int(u.CandidatesTokenCount + u.ThoughtsTokenCount)
+	}
+	return c
+}
+
 // Close is a no-op
 func (g *GeminiClient) Close() error {
 	return nil
@@ -0,0 +1,109 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// maxResponseBytes caps the response body read from a model server
+const maxResponseBytes = 32 << 20
+
+// completion is the answer of a model server with its token counts
+type completion struct {
+	Text             string
+	Model            string
+	PromptTokens     int
+	CompletionTokens int
+}
+
+// httpStatusError is a non-200 response of a model server
+type httpStatusError struct {
+	Provider string
+	Status   int
+	Body     string
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("%s API error (status %d): %s", e.Provider, e.Status, e.Body)
+}
+
+// retryable reports whether the request may succeed when sent again
+func (e *httpStatusError) retryable() bool {
+	return e.Status == http.StatusTooManyRequests || e.Status >= 500
+}
+
+// postJSON sends body as JSON to url and decodes the response into out
+// Transport errors, 429 and 5xx responses are retried up to retries times with a growing delay.
+func postJSON(ctx context.Context, httpClient *http.Client, logger *zap.Logger, provider, url string, headers map[string]string, body, out interface{}, retries int) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= retries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(attempt) * time.Second):
+			}
+		}
+
+		lastErr = postOnce(ctx, httpClient, provider, url, headers, data, out)
+		if lastErr == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return lastErr
+		}
+		if statusErr, ok := lastErr.(*httpStatusError); ok && !statusErr.retryable() {
+			return lastErr
+		}
+		logger.Warn("Model server request failed",
+			zap.String("provider", provider),
+			zap.Int("attempt", attempt+1),
+			zap.Error(lastErr))
+	}
+	return fmt.Errorf("failed after %d attempts: %w", retries+1, lastErr)
+}
+
+func postOnce(ctx context.Context, httpClient *http.Client, provider, url string, headers map[string]string, data []byte, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", provider, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return fmt.Errorf("failed to read %s response: %w", provider, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		text := string(respBody)
+		if len(text) > 500 {
+			text = text[:500] + "..."
+		}
+		return &httpStatusError{Provider: provider, Status: resp.StatusCode, Body: text}
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to parse %s response: %w", provider, err)
+	}
+	return nil
+}
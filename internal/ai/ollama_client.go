@@ -0,0 +1,159 @@
+package ai
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/uzzalhcse/crawlify/internal/config"
+	"go.uber.org/zap"
+)
+
+// OllamaClient talks to an Ollama server through its native chat API
+type OllamaClient struct {
+	baseURL     string
+	model       string
+	visionModel string
+	numCtx      int
+	keepAlive   string
+	retries     int
+	logger      *zap.Logger
+	httpClient  *http.Client
+}
+
+// NewOllamaClient creates a client for the Ollama server at cfg.BaseURL
+func NewOllamaClient(cfg config.OllamaConfig, logger *zap.Logger) (*OllamaClient, error) {
+	if cfg.BaseURL == "" {
+		return nil, errors.New("ai.ollama.base_url is required")
+	}
+	if cfg.Model == "" {
+		return nil, errors.New("ai.ollama.model is required")
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 300
+	}
+
+	logger.Info("Ollama client initialized",
+		zap.String("base_url", cfg.BaseURL),
+		zap.String("model", cfg.Model))
+
+	return &OllamaClient{
+		baseURL:     strings.TrimRight(cfg.BaseURL, "/"),
+		model:       cfg.Model,
+		visionModel: cfg.VisionModel,
+		numCtx:      cfg.NumCtx,
+		keepAlive:   cfg.KeepAlive,
+		retries:     cfg.MaxRetries,
+		logger:      logger,
+		httpClient:  &http.Client{Timeout: time.Duration(timeout) * time.Second},
+	}, nil
+}
+
+// ollamaChatRequest is the body of POST /api/chat
+type ollamaChatRequest struct {
+	Model     string                 `json:"model"`
+	Messages  []ollamaMessage        `json:"messages"`
+	Stream    bool                   `json:"stream"`
+	KeepAlive string                 `json:"keep_alive,omitempty"`
+	Options   map[string]interface{} `json:"options,omitempty"`
+}
+
+type ollamaMessage struct {
+	Role    string   `json:"role"`
+	Content string   `json:"content"`
+	Images  []string `json:"images,omitempty"` // Base64, without a data: prefix
+}
+
+// ollamaChatResponse is the response of POST /api/chat with streaming off
+type ollamaChatResponse struct {
+	Model           string        `json:"model"`
+	Message         ollamaMessage `json:"message"`
+	Done            bool          `json:"done"`
+	PromptEvalCount int           `json:"prompt_eval_count"`
+	EvalCount       int           `json:"eval_count"`
+	Error           string        `json:"error,omitempty"`
+}
+
+// GenerateText sends a text prompt to Ollama
+func (o *OllamaClient) GenerateText(ctx context.Context, prompt string) (string, error) {
+	c, err := o.completeText(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return c.Text, nil
+}
+
+// GenerateWithImage sends a prompt with an image to Ollama's vision model
+func (o *OllamaClient) GenerateWithImage(ctx context.Context, prompt string, imageData []byte, mimeType string) (string, error) {
+	c, err := o.completeWithImage(ctx, prompt, imageData, mimeType)
+	if err != nil {
+		return "", err
+	}
+	return c.Text, nil
+}
+
+func (o *OllamaClient) completeText(ctx context.Context, prompt string) (*completion, error) {
+	return o.complete(ctx, o.model, ollamaMessage{Role: "user", Content: prompt})
+}
+
+func (o *OllamaClient) completeWithImage(ctx context.Context, prompt string, imageData []byte, mimeType string) (*completion, error) {
+	model := o.visionModel
+	if model == "" {
+		model = o.model
+	}
+	return o.complete(ctx, model, ollamaMessage{
+		Role:    "user",
+		Content: prompt,
+		Images:  []string{base64.StdEncoding.EncodeToString(imageData)},
+	})
+}
+
+func (o *OllamaClient) complete(ctx context.Context, model string, messages ...ollamaMessage) (*completion, error) {
+	request := ollamaChatRequest{Model: model, Messages: messages, KeepAlive: o.keepAlive}
+	if o.numCtx > 0 {
+		request.Options = map[string]interface{}{"num_ctx": o.numCtx}
+	}
+
+	o.logger.Debug("Sending prompt to Ollama",
+		zap.String("base_url", o.baseURL),
+		zap.String("model", model))
+
+	start := time.Now()
+	var resp ollamaChatResponse
+	if err := postJSON(ctx, o.httpClient, o.logger, "ollama", o.baseURL+"/api/chat", nil, request, &resp, o.retries); err != nil {
+		return nil, err
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("ollama API error: %s", resp.Error)
+	}
+	if !resp.Done && resp.Message.Content == "" {
+		return nil, errors.New("no response from Ollama")
+	}
+
+	result := &completion{
+		Text:             strings.TrimSpace(resp.Message.Content),
+		Model:            model,
+		PromptTokens:     resp.PromptEvalCount,
+		CompletionTokens: resp.EvalCount,
+	}
+	if resp.Model != "" {
+		result.Model = resp.Model
+	}
+
+	o.logger.Info("Ollama request successful",
+		zap.String("model", result.Model),
+		zap.Int("response_length", len(result.Text)),
+		zap.Duration("latency", time.Since(start)))
+
+	return result, nil
+}
+
+// Close is a no-op
+func (o *OllamaClient) Close() error {
+	return nil
+}
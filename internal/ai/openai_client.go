@@ -0,0 +1,183 @@
+package ai
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/uzzalhcse/crawlify/internal/config"
+	"go.uber.org/zap"
+)
+
+// OpenAIClient talks to any server with an OpenAI-compatible chat completions API
+// Pointed at a self-hosted server (vLLM, llama.cpp, LM Studio, LocalAI), no page content leaves
+// the network.
+type OpenAIClient struct {
+	baseURL     string
+	apiKey      string
+	model       string
+	visionModel string
+	maxTokens   int
+	retries     int
+	logger      *zap.Logger
+	httpClient  *http.Client
+}
+
+// NewOpenAIClient creates a client for the chat completions server at cfg.BaseURL
+func NewOpenAIClient(cfg config.OpenAICompatibleConfig, logger *zap.Logger) (*OpenAIClient, error) {
+	if cfg.BaseURL == "" {
+		return nil, errors.New("ai.openai.base_url is required")
+	}
+	if cfg.Model == "" {
+		return nil, errors.New("ai.openai.model is required")
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 300
+	}
+
+	logger.Info("OpenAI-compatible client initialized",
+		zap.String("base_url", cfg.BaseURL),
+		zap.String("model", cfg.Model))
+
+	return &OpenAIClient{
+		baseURL:     strings.TrimRight(cfg.BaseURL, "/"),
+		apiKey:      cfg.APIKey,
+		model:       cfg.Model,
+		visionModel: cfg.VisionModel,
+		maxTokens:   cfg.MaxTokens,
+		retries:     cfg.MaxRetries,
+		logger:      logger,
+		httpClient:  &http.Client{Timeout: time.Duration(timeout) * time.Second},
+	}, nil
+}
+
+// chatCompletionRequest is the body of POST /chat/completions
+type chatCompletionRequest struct {
+	Model     string        `json:"model"`
+	Messages  []chatMessage `json:"messages"`
+	MaxTokens int           `json:"max_tokens,omitempty"`
+	Stream    bool          `json:"stream"`
+}
+
+// chatMessage carries either a string or a list of parts as its content
+type chatMessage struct {
+	Role    string      `json:"role"`
+	Content interface{} `json:"content"`
+}
+
+type chatContentPart struct {
+	Type     string        `json:"type"` // "text" or "image_url"
+	Text     string        `json:"text,omitempty"`
+	ImageURL *chatImageURL `json:"image_url,omitempty"`
+}
+
+type chatImageURL struct {
+	URL string `json:"url"` // A data: URL with the base64 image
+}
+
+// chatCompletionResponse is the response of POST /chat/completions
+type chatCompletionResponse struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Message struct {
+			Content *string `json:"content"`
+		} `json:"message"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// GenerateText sends a text prompt to the server
+func (o *OpenAIClient) GenerateText(ctx context.Context, prompt string) (string, error) {
+	c, err := o.completeText(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return c.Text, nil
+}
+
+// GenerateWithImage sends a prompt with an image to the server's vision model
+func (o *OpenAIClient) GenerateWithImage(ctx context.Context, prompt string, imageData []byte, mimeType string) (string, error) {
+	c, err := o.completeWithImage(ctx, prompt, imageData, mimeType)
+	if err != nil {
+		return "", err
+	}
+	return c.Text, nil
+}
+
+func (o *OpenAIClient) completeText(ctx context.Context, prompt string) (*completion, error) {
+	return o.complete(ctx, o.model, chatMessage{Role: "user", Content: prompt})
+}
+
+func (o *OpenAIClient) completeWithImage(ctx context.Context, prompt string, imageData []byte, mimeType string) (*completion, error) {
+	model := o.visionModel
+	if model == "" {
+		model = o.model
+	}
+	return o.complete(ctx, model, chatMessage{
+		Role: "user",
+		Content: []chatContentPart{
+			{Type: "text", Text: prompt},
+			{Type: "image_url", ImageURL: &chatImageURL{
+				URL: "data:" + mimeType + ";base64," + base64.StdEncoding.EncodeToString(imageData),
+			}},
+		},
+	})
+}
+
+func (o *OpenAIClient) complete(ctx context.Context, model string, messages ...chatMessage) (*completion, error) {
+	headers := map[string]string{}
+	if o.apiKey != "" {
+		headers["Authorization"] = "Bearer " + o.apiKey
+	}
+	request := chatCompletionRequest{Model: model, Messages: messages, MaxTokens: o.maxTokens}
+
+	o.logger.Debug("Sending prompt to OpenAI-compatible server",
+		zap.String("base_url", o.baseURL),
+		zap.String("model", model))
+
+	start := time.Now()
+	var resp chatCompletionResponse
+	if err := postJSON(ctx, o.httpClient, o.logger, "openai", o.baseURL+"/chat/completions", headers, request, &resp, o.retries); err != nil {
+		return nil, err
+	}
+	if resp.Error != nil {
+		return nil, fmt.Errorf("openai API error: %s", resp.Error.Message)
+	}
+	if len(resp.Choices) == 0 || resp.Choices[0].Message.Content == nil {
+		return nil, errors.New("no response from OpenAI-compatible server")
+	}
+
+	result := &completion{
+		Text:             strings.TrimSpace(*resp.Choices[0].Message.Content),
+		Model:            model,
+		PromptTokens:     resp.Usage.PromptTokens,
+		CompletionTokens: resp.Usage.CompletionTokens,
+	}
+	if resp.Model != "" {
+		result.Model = resp.Model
+	}
+
+	o.logger.Info("OpenAI-compatible request successful",
+		zap.String("model", result.Model),
+		zap.Int("response_length", len(result.Text)),
+		zap.Duration("latency", time.Since(start)))
+
+	return result, nil
+}
+
+// Close is a no-op
+func (o *OpenAIClient) Close() error {
+	return nil
+}
@@ -55,6 +55,10 @@ type OpenRouterResponse struct {
 			Content string `json:"content"`
 		} `json:"message"`
 	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
 	Error *struct {
 		Message string `json:"message"`
 		Code    int    `json:"code"`
@@ -63,6 +67,14 @@ type OpenRouterResponse struct {
 
 // GenerateText sends a text prompt to OpenRouter
 func (o *OpenRouterClient) GenerateText(ctx context.Context, prompt string) (string, error) {
+	c, err := o.completeText(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return c.Text, nil
+}
+
+func (o *OpenRouterClient) completeText(ctx context.Context, prompt string) (*completion, error) {
 	var lastErr error
 
 	maxAttempts := 100
@@ -71,9 +83,9 @@ func (o *OpenRouterClient) GenerateText(ctx context.Context, prompt string) (str
 		apiKey, err := o.keyManager.GetAPIKey(ctx, "openrouter")
 		if err != nil {
 			if attempt == 0 {
-				return "", fmt.Errorf("no OpenRouter API keys available: %w", err)
+				return nil, fmt.Errorf("no OpenRouter API keys available: %w", err)
 			}
-			return "", fmt.Errorf("failed after %d attempts, no more keys available: %w", attempt, lastErr)
+			return nil, fmt.Errorf("failed after %d attempts, no more keys available: %w", attempt, lastErr)
 		}
 
 		request := OpenRouterRequest{
@@ -85,12 +97,12 @@ func (o *OpenRouterClient) GenerateText(ctx context.Context, prompt string) (str
 
 		jsonData, err := json.Marshal(request)
 		if err != nil {
-			return "", fmt.Errorf("failed to marshal request: %w", err)
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
 		}
 
 		req, err := http.NewRequestWithContext(ctx, "POST", "https://openrouter.ai/api/v1/chat/completions", bytes.NewBuffer(jsonData))
 		if err != nil {
-			return "", fmt.Errorf("failed to create request: %w", err)
+			return nil, fmt.Errorf("failed to create request: %w", err)
 		}
 
 		req.Header.Set("Authorization", "Bearer "+apiKey)
@@ -183,10 +195,15 @@ func (o *OpenRouterClient) GenerateText(ctx context.Context, prompt string) (str
 			zap.String("key_id", o.keyManager.GetCurrentKeyID()),
 			zap.Int("attempts_used", attempt+1))
 
-		return result, nil
+		return &completion{
+			Text:             result,
+			Model:            o.model,
+			PromptTokens:     apiResp.Usage.PromptTokens,
+			CompletionTokens: apiResp.Usage.CompletionTokens,
+		}, nil
 	}
 
-	return "", fmt.Errorf("failed after %d attempts: %w", maxAttempts, lastErr)
+	return nil, fmt.Errorf("failed after %d attempts: %w", maxAttempts, lastErr)
 }
 
 // Close is a no-op
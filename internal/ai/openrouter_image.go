@@ -8,3 +8,8 @@ func (o *OpenRouterClient) GenerateWithImage(ctx context.Context, prompt string,
 	o.logger.Warn("Image analysis requested but not supported by OpenRouter free models, using text-only")
 	return o.GenerateText(ctx, prompt)
 }
+
+func (o *OpenRouterClient) completeWithImage(ctx context.Context, prompt string, imageData []byte, mimeType string) (*completion, error) {
+	o.logger.Warn("Image analysis requested but not supported by OpenRouter free models, using text-only")
+	return o.completeText(ctx, prompt)
+}
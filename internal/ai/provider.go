@@ -0,0 +1,149 @@
+package ai
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/uzzalhcse/crawlify/internal/config"
+	"github.com/uzzalhcse/crawlify/internal/storage"
+	"go.uber.org/zap"
+)
+
+// AI providers
+const (
+	ProviderGemini     = "gemini"
+	ProviderOpenRouter = "openrouter"
+	ProviderOpenAI     = "openai" // Any OpenAI-compatible server
+	ProviderOllama     = "ollama"
+)
+
+// Features that use AI; each can run on its own provider
+const (
+	FeatureAutoFix    = "autofix"    // Monitoring auto-fix suggestions
+	FeatureRecovery   = "recovery"   // Error recovery reasoning
+	FeatureGeneration = "generation" // Workflow generation
+)
+
+// ClientSet creates the AI client of each feature from config
+// Features on the same provider share one client. With a meter, each feature's calls are
+// logged, budgeted and cached under its name.
+type ClientSet struct {
+	cfg     config.AIConfig
+	keyRepo *storage.AIKeyRepository
+	meter   *Meter
+	logger  *zap.Logger
+
+	mu      sync.Mutex
+	clients map[string]AIClient // By provider
+}
+
+// NewClientSet creates a client set; clients are created when first asked for
+func NewClientSet(cfg config.AIConfig, keyRepo *storage.AIKeyRepository, logger *zap.Logger) *ClientSet {
+	return &ClientSet{
+		cfg:     cfg,
+		keyRepo: keyRepo,
+		logger:  logger,
+		clients: make(map[string]AIClient),
+	}
+}
+
+// SetMeter meters the clients returned by ForFeature from now on
+func (s *ClientSet) SetMeter(meter *Meter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.meter = meter
+}
+
+// Provider returns the provider configured for a feature
+func (s *ClientSet) Provider(feature string) string {
+	var provider string
+	switch feature {
+	case FeatureAutoFix:
+		provider = s.cfg.Features.AutoFix
+	case FeatureRecovery:
+		provider = s.cfg.Features.Recovery
+	case FeatureGeneration:
+		provider = s.cfg.Features.Generation
+	}
+	if provider == "" {
+		provider = s.cfg.Provider
+	}
+	if provider == "" {
+		provider = ProviderGemini
+	}
+	return provider
+}
+
+// ForFeature returns the client of a feature's provider
+func (s *ClientSet) ForFeature(feature string) (AIClient, error) {
+	provider := s.Provider(feature)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	client, ok := s.clients[provider]
+	if !ok {
+		var err error
+		client, err = s.newClient(provider)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s client for %s: %w", provider, feature, err)
+		}
+		s.clients[provider] = client
+	}
+	if s.meter != nil {
+		return s.meter.Client(client, provider, s.model(provider), feature), nil
+	}
+	return client, nil
+}
+
+// Model returns the model of a feature's provider
+func (s *ClientSet) Model(feature string) string {
+	provider := s.Provider(feature)
+	if model := s.model(provider); model != "" {
+		return model
+	}
+	return provider
+}
+
+// model returns the configured model of a provider
+func (s *ClientSet) model(provider string) string {
+	switch provider {
+	case ProviderGemini:
+		return s.cfg.GeminiModel
+	case ProviderOpenRouter:
+		return s.cfg.OpenRouterModel
+	case ProviderOpenAI:
+		return s.cfg.OpenAI.Model
+	case ProviderOllama:
+		return s.cfg.Ollama.Model
+	}
+	return ""
+}
+
+// Close closes every client created so far
+func (s *ClientSet) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var firstErr error
+	for _, client := range s.clients {
+		if err := client.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (s *ClientSet) newClient(provider string) (AIClient, error) {
+	switch provider {
+	case ProviderGemini:
+		// Each remote provider rotates through its own keys
+		return NewGeminiClient(NewAIKeyManager(s.keyRepo, s.logger), s.cfg.GeminiModel, s.logger)
+	case ProviderOpenRouter:
+		return NewOpenRouterClient(NewAIKeyManager(s.keyRepo, s.logger), s.cfg.OpenRouterModel, s.logger)
+	case ProviderOpenAI:
+		return NewOpenAIClient(s.cfg.OpenAI, s.logger)
+	case ProviderOllama:
+		return NewOllamaClient(s.cfg.Ollama, s.logger)
+	default:
+		return nil, fmt.Errorf("unknown AI provider '%s'", provider)
+	}
+}
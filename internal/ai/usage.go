@@ -0,0 +1,436 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uzzalhcse/crawlify/internal/ai/aiusage"
+	"github.com/uzzalhcse/crawlify/internal/config"
+	"github.com/uzzalhcse/crawlify/internal/storage"
+	"github.com/uzzalhcse/crawlify/pkg/models"
+	"go.uber.org/zap"
+)
+
+// defaultPrices are list prices in USD per million tokens; ai.pricing adds to and overrides them
+var defaultPrices = []config.AIModelPrice{
+	{Model: "gemini-2.5-pro", InputPerMillion: 1.25, OutputPerMillion: 10.00},
+	{Model: "gemini-2.5-flash", InputPerMillion: 0.30, OutputPerMillion: 2.50},
+	{Model: "gemini-2.5-flash-lite", InputPerMillion: 0.10, OutputPerMillion: 0.40},
+	{Model: "gemini-2.0-flash", InputPerMillion: 0.10, OutputPerMillion: 0.40},
+	{Model: "gemini-2.0-flash-lite", InputPerMillion: 0.075, OutputPerMillion: 0.30},
+	{Model: "gpt-4o", InputPerMillion: 2.50, OutputPerMillion: 10.00},
+	{Model: "gpt-4o-mini", InputPerMillion: 0.15, OutputPerMillion: 0.60},
+	{Model: "gpt-4.1", InputPerMillion: 2.00, OutputPerMillion: 8.00},
+	{Model: "gpt-4.1-mini", InputPerMillion: 0.40, OutputPerMillion: 1.60},
+	{Model: "gpt-4.1-nano", InputPerMillion: 0.10, OutputPerMillion: 0.40},
+}
+
+// completer is implemented by clients that report the token counts of their answers
+type completer interface {
+	completeText(ctx context.Context, prompt string) (*completion, error)
+	completeWithImage(ctx context.Context, prompt string, imageData []byte, mimeType string) (*completion, error)
+}
+
+// Meter logs every AI call, enforces the daily budgets and caches answers by prompt hash
+// Identical prompts in flight at the same time share one call.
+type Meter struct {
+	repo   *storage.AIUsageRepository
+	cfg    config.AIUsageConfig
+	prices map[string]config.AIModelPrice
+	logger *zap.Logger
+
+	mu       sync.Mutex
+	inflight map[string]*inflightCall
+
+	// budgetMu makes checking the budget and reserving a call's estimated cost one step;
+	// reserved holds the reservations of calls still running, by workflow ID ("" is global)
+	budgetMu sync.Mutex
+	reserved map[string]*reservation
+}
+
+// reservation is the estimated cost and count of running calls that are not recorded yet
+type reservation struct {
+	costUSD float64
+	calls   int
+}
+
+type inflightCall struct {
+	done   chan struct{}
+	result *completion
+	err    error
+}
+
+// NewMeter creates a meter
+func NewMeter(repo *storage.AIUsageRepository, cfg config.AIConfig, logger *zap.Logger) *Meter {
+	prices := make(map[string]config.AIModelPrice)
+	for _, p := range append(append([]config.AIModelPrice(nil), defaultPrices...), cfg.Pricing...) {
+		prices[strings.ToLower(p.Model)] = p
+	}
+	return &Meter{
+		repo:     repo,
+		cfg:      cfg.Usage,
+		prices:   prices,
+		logger:   logger,
+		inflight: make(map[string]*inflightCall),
+		reserved: make(map[string]*reservation),
+	}
+}
+
+// Client wraps a client so that its calls for feature are metered
+func (m *Meter) Client(client AIClient, provider, model, feature string) *MeteredClient {
+	return &MeteredClient{meter: m, client: client, provider: provider, model: model, feature: feature}
+}
+
+// Status returns today's spend against the global limits, or against the per-workflow limits
+// when workflowID is set
+func (m *Meter) Status(ctx context.Context, workflowID string) (*models.AIBudgetStatus, error) {
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+	cost, calls, err := m.repo.Spend(ctx, day, workflowID)
+	if err != nil {
+		return nil, err
+	}
+	status := &models.AIBudgetStatus{
+		Day:          day.Format("2006-01-02"),
+		WorkflowID:   workflowID,
+		CostUSD:      cost,
+		Calls:        calls,
+		CostLimitUSD: m.cfg.DailyBudgetUSD,
+		CallLimit:    m.cfg.DailyCallLimit,
+	}
+	if workflowID != "" {
+		status.CostLimitUSD = m.cfg.WorkflowDailyBudgetUSD
+		status.CallLimit = m.cfg.WorkflowDailyCallLimit
+	}
+	status.Exceeded = status.CostLimitUSD > 0 && cost >= status.CostLimitUSD ||
+		status.CallLimit > 0 && calls >= status.CallLimit
+	return status, nil
+}
+
+// reserveBudget returns ErrBudgetExceeded when the global budget or the workflow's is spent,
+// counting calls that are still running; otherwise it reserves costUSD and one call against
+// both until the returned release is called, after the call is recorded
+func (m *Meter) reserveBudget(ctx context.Context, workflowID string, costUSD float64) (release func(), err error) {
+	m.budgetMu.Lock()
+	defer m.budgetMu.Unlock()
+
+	keys := []string{""}
+	if m.cfg.DailyBudgetUSD > 0 || m.cfg.DailyCallLimit > 0 {
+		status, err := m.Status(ctx, "")
+		if err != nil {
+			return nil, err
+		}
+		if m.exceeded(status) {
+			return nil, fmt.Errorf("%w: global daily budget spent ($%.4f, %d calls)", aiusage.ErrBudgetExceeded, status.CostUSD, status.Calls)
+		}
+	}
+	if workflowID != "" {
+		keys = append(keys, workflowID)
+		if m.cfg.WorkflowDailyBudgetUSD > 0 || m.cfg.WorkflowDailyCallLimit > 0 {
+			status, err := m.Status(ctx, workflowID)
+			if err != nil {
+				return nil, err
+			}
+			if m.exceeded(status) {
+				return nil, fmt.Errorf("%w: daily budget of workflow %s spent ($%.4f, %d calls)", aiusage.ErrWorkflowBudgetExceeded, workflowID, status.CostUSD, status.Calls)
+			}
+		}
+	}
+
+	for _, key := range keys {
+		r := m.reserved[key]
+		if r == nil {
+			r = &reservation{}
+			m.reserved[key] = r
+		}
+		r.costUSD += costUSD
+		r.calls++
+	}
+	return func() {
+		m.budgetMu.Lock()
+		defer m.budgetMu.Unlock()
+		for _, key := range keys {
+			r := m.reserved[key]
+			r.costUSD -= costUSD
+			r.calls--
+			if r.calls == 0 {
+				delete(m.reserved, key)
+			}
+		}
+	}, nil
+}
+
+// exceeded reports whether a budget is spent once the running calls' reservations are added;
+// it is called with budgetMu held
+func (m *Meter) exceeded(status *models.AIBudgetStatus) bool {
+	cost, calls := status.CostUSD, status.Calls
+	if r := m.reserved[status.WorkflowID]; r != nil {
+		cost += r.costUSD
+		calls += r.calls
+	}
+	return status.CostLimitUSD > 0 && cost >= status.CostLimitUSD ||
+		status.CallLimit > 0 && calls >= status.CallLimit
+}
+
+// Cost estimates the cost of a call in USD; models without a price cost nothing
+func (m *Meter) Cost(provider, model string, promptTokens, responseTokens int) float64 {
+	if strings.HasSuffix(model, ":free") {
+		return 0
+	}
+	name := strings.ToLower(model)
+	price, ok := m.lookupPrice(provider + "/" + name)
+	if !ok && provider != ProviderOllama {
+		// Self-hosted models are free unless ai.pricing names them with the ollama/ prefix
+		if i := strings.LastIndex(name, "/"); i >= 0 {
+			name = name[i+1:] // OpenRouter's vendor/model
+		}
+		price, ok = m.lookupPrice(name)
+	}
+	if !ok {
+		return 0
+	}
+	return (float64(promptTokens)*price.InputPerMillion + float64(responseTokens)*price.OutputPerMillion) / 1e6
+}
+
+// lookupPrice finds the price of the longest model name that name starts with
+func (m *Meter) lookupPrice(name string) (config.AIModelPrice, bool) {
+	keys := make([]string, 0, len(m.prices))
+	for k := range m.prices {
+		if strings.HasPrefix(name, k) {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return config.AIModelPrice{}, false
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+	return m.prices[keys[0]], true
+}
+
+// record logs a call; a failure to log never fails the call
+func (m *Meter) record(usage *models.AIUsage) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := m.repo.Record(ctx, usage); err != nil {
+		m.logger.Warn("Failed to record AI usage", zap.Error(err))
+	}
+}
+
+// share runs fn for a prompt hash unless a call for the same hash is already running, in which
+// case it waits for that call's answer; shared reports the latter
+func (m *Meter) share(ctx context.Context, hash string, fn func() (*completion, error)) (result *completion, shared bool, err error) {
+	m.mu.Lock()
+	if call, ok := m.inflight[hash]; ok {
+		m.mu.Unlock()
+		select {
+		case <-call.done:
+			return call.result, true, call.err
+		case <-ctx.Done():
+			return nil, true, ctx.Err()
+		}
+	}
+	call := &inflightCall{done: make(chan struct{})}
+	m.inflight[hash] = call
+	m.mu.Unlock()
+
+	call.result, call.err = fn()
+	m.mu.Lock()
+	delete(m.inflight, hash)
+	m.mu.Unlock()
+	close(call.done)
+	return call.result, false, call.err
+}
+
+// MeteredClient is an AI client whose calls are logged, budgeted and cached
+type MeteredClient struct {
+	meter    *Meter
+	client   AIClient
+	provider string
+	model    string
+	feature  string
+}
+
+// GenerateText sends a text prompt, or answers it from the cache
+func (c *MeteredClient) GenerateText(ctx context.Context, prompt string) (string, error) {
+	return c.call(ctx, prompt, nil, "")
+}
+
+// GenerateWithImage sends a prompt with an image, or answers it from the cache
+func (c *MeteredClient) GenerateWithImage(ctx context.Context, prompt string, imageData []byte, mimeType string) (string, error) {
+	return c.call(ctx, prompt, imageData, mimeType)
+}
+
+// Close is a no-op; the wrapped client is shared and closed by its ClientSet
+func (c *MeteredClient) Close() error {
+	return nil
+}
+
+func (c *MeteredClient) call(ctx context.Context, prompt string, imageData []byte, mimeType string) (string, error) {
+	m := c.meter
+	scope := aiusage.FromContext(ctx)
+	if scope.Feature == "" {
+		scope.Feature = c.feature
+	}
+	key := scope.CacheKey
+	if key == "" {
+		key = prompt
+	}
+	hash := promptHash(c.provider, c.model, key, imageData)
+	usage := &models.AIUsage{
+		Provider:    c.provider,
+		Model:       c.model,
+		Feature:     scope.Feature,
+		WorkflowID:  scope.WorkflowID,
+		ExecutionID: scope.ExecutionID,
+		PromptHash:  hash,
+	}
+
+	if m.cfg.CacheTTL > 0 {
+		cached, err := m.repo.GetCachedResponse(ctx, hash)
+		if err != nil {
+			m.logger.Warn("AI response cache unavailable", zap.Error(err))
+		}
+		if cached != nil {
+			usage.Status = models.AIUsageCached
+			usage.Model = cached.Model
+			usage.PromptTokens, usage.ResponseTokens = cached.PromptTokens, cached.ResponseTokens
+			m.record(usage)
+			m.logger.Debug("AI response served from cache", zap.String("feature", scope.Feature), zap.String("prompt_hash", hash))
+			return cached.Response, nil
+		}
+	}
+
+	estimate := m.Cost(c.provider, c.model, estimateTokens(prompt)+imageTokens(imageData), reservedResponseTokens)
+	release, err := m.reserveBudget(ctx, scope.WorkflowID, estimate)
+	if err != nil {
+		if errors.Is(err, aiusage.ErrBudgetExceeded) {
+			usage.Status = models.AIUsageBudget
+			usage.Error = err.Error()
+			m.record(usage)
+			m.logger.Warn("AI call blocked by budget", zap.String("feature", scope.Feature), zap.Error(err))
+		}
+		return "", err
+	}
+	// Calls are recorded before they return, so the reservation ends once the spend is logged
+	defer release()
+
+	start := time.Now()
+	result, shared, err := m.share(ctx, hash, func() (*completion, error) {
+		return c.complete(ctx, prompt, imageData, mimeType)
+	})
+	usage.LatencyMs = time.Since(start).Milliseconds()
+
+	if shared {
+		// Another caller paid for this answer
+		if err != nil {
+			return "", err
+		}
+		usage.Status = models.AIUsageCached
+		usage.Model = result.Model
+		usage.PromptTokens, usage.ResponseTokens = result.PromptTokens, result.CompletionTokens
+		m.record(usage)
+		return result.Text, nil
+	}
+
+	if err != nil {
+		usage.Status = models.AIUsageError
+		usage.Error = err.Error()
+		m.record(usage)
+		return "", err
+	}
+
+	usage.Status = models.AIUsageSuccess
+	usage.Model = result.Model
+	usage.PromptTokens, usage.ResponseTokens = result.PromptTokens, result.CompletionTokens
+	if usage.PromptTokens == 0 && usage.ResponseTokens == 0 {
+		usage.PromptTokens, usage.ResponseTokens = estimateTokens(prompt)+imageTokens(imageData), estimateTokens(result.Text)
+		usage.TokensEstimated = true
+	}
+	usage.CostUSD = m.Cost(c.provider, usage.Model, usage.PromptTokens, usage.ResponseTokens)
+	m.record(usage)
+
+	if m.cfg.CacheTTL > 0 && result.Text != "" {
+		expires := time.Now().Add(time.Duration(m.cfg.CacheTTL) * time.Second)
+		err := m.repo.PutCachedResponse(context.Background(), &models.AICachedResponse{
+			PromptHash:     hash,
+			Provider:       c.provider,
+			Model:          usage.Model,
+			Response:       result.Text,
+			PromptTokens:   usage.PromptTokens,
+			ResponseTokens: usage.ResponseTokens,
+			ExpiresAt:      &expires,
+		})
+		if err != nil {
+			m.logger.Warn("Failed to cache AI response", zap.Error(err))
+		}
+	}
+
+	m.logger.Info("AI call",
+		zap.String("provider", c.provider),
+		zap.String("model", usage.Model),
+		zap.String("feature", scope.Feature),
+		zap.Int("prompt_tokens", usage.PromptTokens),
+		zap.Int("response_tokens", usage.ResponseTokens),
+		zap.Int64("latency_ms", usage.LatencyMs),
+		zap.Float64("cost_usd", usage.CostUSD))
+
+	return result.Text, nil
+}
+
+// complete calls the wrapped client, with token counts when it reports them
+func (c *MeteredClient) complete(ctx context.Context, prompt string, imageData []byte, mimeType string) (*completion, error) {
+	if inner, ok := c.client.(completer); ok {
+		if imageData != nil {
+			return inner.completeWithImage(ctx, prompt, imageData, mimeType)
+		}
+		return inner.completeText(ctx, prompt)
+	}
+
+	var text string
+	var err error
+	if imageData != nil {
+		text, err = c.client.GenerateWithImage(ctx, prompt, imageData, mimeType)
+	} else {
+		text, err = c.client.GenerateText(ctx, prompt)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &completion{Text: text, Model: c.model}, nil
+}
+
+// promptHash is the cache key of a call: SHA-256 over provider, model, prompt and image
+func promptHash(provider, model, prompt string, imageData []byte) string {
+	h := sha256.New()
+	for _, part := range []string{provider, model, prompt} {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	if imageData != nil {
+		image := sha256.Sum256(imageData)
+		h.Write(image[:])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// reservedResponseTokens is the answer length reserved against the budget before a call
+const reservedResponseTokens = 1000
+
+// estimateTokens approximates the token count of text, at about four bytes per token
+func estimateTokens(text string) int {
+	return (len(text) + 3) / 4
+}
+
+// imageTokens approximates the tokens of an image; providers bill a few hundred to a thousand
+func imageTokens(imageData []byte) int {
+	if imageData == nil {
+		return 0
+	}
+	return 1000
+}
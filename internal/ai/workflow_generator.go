@@ -0,0 +1,664 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+	"github.com/uzzalhcse/crawlify/internal/ai/aiusage"
+	"github.com/uzzalhcse/crawlify/internal/browser"
+	"github.com/uzzalhcse/crawlify/internal/extraction"
+	"github.com/uzzalhcse/crawlify/internal/workflow"
+	"github.com/uzzalhcse/crawlify/internal/workflow/nodes"
+	"github.com/uzzalhcse/crawlify/pkg/models"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultGenerateSamples = 2
+	maxGenerateSamples     = 5
+	generateAttempts       = 2     // Proposals tried before giving up on one that fails validation
+	maxPromptDOMBytes      = 60000 // Trimmed DOM sent per page
+)
+
+// Generated workflow phase IDs and link marker
+const (
+	generatedDiscoveryPhase  = "discover"
+	generatedExtractionPhase = "extract"
+	generatedMarker          = "detail"
+)
+
+// WorkflowGenerator proposes a workflow for a site from a start URL and a plain-language goal
+// It loads sample pages, asks the AI for link and field selectors, builds a phase-based
+// config from them and dry-runs it on the samples.
+type WorkflowGenerator struct {
+	aiClient    AIClient
+	browserPool *browser.BrowserPool
+	registry    *workflow.NodeRegistry
+	parser      *workflow.Parser
+	logger      *zap.Logger
+}
+
+// GenerateRequest asks for a workflow
+type GenerateRequest struct {
+	URL        string `json:"url"`
+	Goal       string `json:"goal"`                  // e.g. "all products with name, price, SKU, images"
+	MaxSamples int    `json:"max_samples,omitempty"` // Detail pages loaded and dry-run (default 2, at most 5)
+}
+
+// GeneratedWorkflow is a proposed workflow with a preview of what it extracts
+type GeneratedWorkflow struct {
+	Name        string                `json:"name"`
+	Description string                `json:"description"`
+	Config      models.WorkflowConfig `json:"config"`
+	Explanation string                `json:"explanation,omitempty"`
+	Samples     []GenerateSample      `json:"samples"`
+	Preview     []GeneratePreview     `json:"preview"`
+	Warnings    []string              `json:"warnings,omitempty"`
+}
+
+// GenerateSample is a page loaded to build the proposal
+type GenerateSample struct {
+	URL  string `json:"url"`
+	Kind string `json:"kind"` // listing, detail
+}
+
+// GeneratePreview is the dry-run result of one node on one sample page
+type GeneratePreview struct {
+	URL    string      `json:"url"`
+	Phase  string      `json:"phase"`
+	NodeID string      `json:"node_id"`
+	Result interface{} `json:"result,omitempty"`
+	Empty  []string    `json:"empty_fields,omitempty"` // Fields that extracted nothing
+	Error  string      `json:"error,omitempty"`
+}
+
+// discoveryProposal is the AI's reading of the start page
+type discoveryProposal struct {
+	PageKind         string `json:"page_kind"` // listing, detail
+	LinkSelector     string `json:"link_selector"`
+	NextPageSelector string `json:"next_page_selector"`
+	Explanation      string `json:"explanation"`
+}
+
+// extractionProposal is the AI's field selectors for the detail pages
+type extractionProposal struct {
+	Name        string                   `json:"name"`
+	WaitFor     string                   `json:"wait_for"`
+	Fields      map[string]proposedField `json:"fields"`
+	Explanation string                   `json:"explanation"`
+}
+
+// proposedField is one field of an extract node
+type proposedField struct {
+	Selector  string `json:"selector"`
+	Type      string `json:"type"` // text, attr, html, href, src
+	Attribute string `json:"attribute,omitempty"`
+	Multiple  bool   `json:"multiple,omitempty"`
+	Transform string `json:"transform,omitempty"`
+}
+
+// pageSample is a loaded page as sent to the AI
+type pageSample struct {
+	url        string
+	dom        string
+	screenshot []byte
+}
+
+// NewWorkflowGenerator creates a workflow generator
+func NewWorkflowGenerator(aiClient AIClient, browserPool *browser.BrowserPool, registry *workflow.NodeRegistry, logger *zap.Logger) *WorkflowGenerator {
+	return &WorkflowGenerator{
+		aiClient:    aiClient,
+		browserPool: browserPool,
+		registry:    registry,
+		parser:      workflow.NewParser(),
+		logger:      logger,
+	}
+}
+
+// Generate proposes a workflow; the config it returns has passed Parser.Validate
+func (g *WorkflowGenerator) Generate(ctx context.Context, req *GenerateRequest) (*GeneratedWorkflow, error) {
+	if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("url must be an http(s) URL")
+	}
+	if strings.TrimSpace(req.Goal) == "" {
+		return nil, fmt.Errorf("goal is required")
+	}
+	maxSamples := req.MaxSamples
+	if maxSamples <= 0 {
+		maxSamples = defaultGenerateSamples
+	}
+	maxSamples = min(maxSamples, maxGenerateSamples)
+
+	ctx = aiusage.WithScope(ctx, aiusage.Scope{Feature: FeatureGeneration})
+
+	browserCtx, err := g.browserPool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire browser: %w", err)
+	}
+	defer g.browserPool.Release(browserCtx)
+
+	start, err := g.loadSample(browserCtx, req.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	discovery, err := g.proposeDiscovery(ctx, req.Goal, start)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &GeneratedWorkflow{Explanation: discovery.Explanation}
+	var detailURLs []string
+	if discovery.PageKind == "detail" || discovery.LinkSelector == "" {
+		detailURLs = []string{req.URL}
+		result.Samples = append(result.Samples, GenerateSample{URL: req.URL, Kind: "detail"})
+	} else {
+		result.Samples = append(result.Samples, GenerateSample{URL: req.URL, Kind: "listing"})
+		links, err := g.runNode(ctx, browserCtx, models.NodeTypeExtractLinks, map[string]interface{}{"selector": discovery.LinkSelector})
+		if err != nil {
+			return nil, fmt.Errorf("proposed link selector '%s' failed: %w", discovery.LinkSelector, err)
+		}
+		detailURLs = sampleLinks(req.URL, links.DiscoveredURLs, maxSamples)
+		if len(detailURLs) == 0 {
+			return nil, fmt.Errorf("proposed link selector '%s' found no links on %s", discovery.LinkSelector, req.URL)
+		}
+		for _, u := range detailURLs {
+			result.Samples = append(result.Samples, GenerateSample{URL: u, Kind: "detail"})
+		}
+	}
+
+	var details []*pageSample
+	for _, u := range detailURLs {
+		sample, err := g.loadSample(browserCtx, u)
+		if err != nil {
+			g.logger.Warn("Failed to load sample page", zap.String("url", u), zap.Error(err))
+			continue
+		}
+		details = append(details, sample)
+	}
+	if len(details) == 0 {
+		return nil, fmt.Errorf("none of the sample pages could be loaded")
+	}
+
+	// Proposals that fail validation are sent back with the error
+	var proposal *extractionProposal
+	var config *models.WorkflowConfig
+	var rejection error
+	for attempt := 0; attempt < generateAttempts; attempt++ {
+		proposal, err = g.proposeExtraction(ctx, req.Goal, details, rejection)
+		if err != nil {
+			return nil, err
+		}
+		config = buildGeneratedConfig(req.URL, discovery, proposal)
+		if rejection = g.validate(config); rejection == nil {
+			break
+		}
+		g.logger.Warn("Generated workflow failed validation", zap.Int("attempt", attempt+1), zap.Error(rejection))
+	}
+	if rejection != nil {
+		return nil, fmt.Errorf("generated workflow is invalid: %w", rejection)
+	}
+
+	result.Config = *config
+	result.Name = proposal.Name
+	if result.Name == "" {
+		result.Name = "Generated workflow for " + hostOf(req.URL)
+	}
+	result.Description = req.Goal
+	if proposal.Explanation != "" {
+		result.Explanation = strings.TrimSpace(result.Explanation + " " + proposal.Explanation)
+	}
+
+	g.dryRun(ctx, browserCtx, req.URL, config, detailURLs, result)
+
+	g.logger.Info("Workflow generated",
+		zap.String("url", req.URL),
+		zap.Int("samples", len(result.Samples)),
+		zap.Int("fields", len(proposal.Fields)),
+		zap.Int("warnings", len(result.Warnings)))
+
+	return result, nil
+}
+
+// loadSample navigates to a page and captures its trimmed DOM and a screenshot
+func (g *WorkflowGenerator) loadSample(browserCtx *browser.BrowserContext, pageURL string) (*pageSample, error) {
+	if err := g.navigate(browserCtx, pageURL); err != nil {
+		return nil, err
+	}
+
+	html, err := browserCtx.Content()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", pageURL, err)
+	}
+	dom, err := trimDOM(html, maxPromptDOMBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", pageURL, err)
+	}
+
+	screenshot, err := browserCtx.Screenshot(playwright.PageScreenshotOptions{
+		Type:    playwright.ScreenshotTypeJpeg,
+		Quality: playwright.Int(60),
+	})
+	if err != nil {
+		g.logger.Warn("Failed to capture screenshot, continuing with DOM only", zap.String("url", pageURL), zap.Error(err))
+	}
+
+	return &pageSample{url: pageURL, dom: dom, screenshot: screenshot}, nil
+}
+
+// navigate loads a page and waits for it to settle
+func (g *WorkflowGenerator) navigate(browserCtx *browser.BrowserContext, pageURL string) error {
+	if _, err := browserCtx.Navigate(pageURL); err != nil {
+		return fmt.Errorf("failed to navigate to %s: %w", pageURL, err)
+	}
+	// Listings often render their items after load; a busy page still gets sampled
+	if err := browserCtx.Page.WaitForLoadState(playwright.PageWaitForLoadStateOptions{
+		State:   playwright.LoadStateNetworkidle,
+		Timeout: playwright.Float(10000),
+	}); err != nil {
+		g.logger.Debug("Page did not reach network idle", zap.String("url", pageURL))
+	}
+	return nil
+}
+
+// proposeDiscovery asks whether the start page lists the target pages and how to find them
+func (g *WorkflowGenerator) proposeDiscovery(ctx context.Context, goal string, start *pageSample) (*discoveryProposal, error) {
+	var prompt strings.Builder
+	prompt.WriteString("You are a web scraping expert designing a crawler.\n\n")
+	fmt.Fprintf(&prompt, "**Goal:** %s\n", goal)
+	fmt.Fprintf(&prompt, "**Start URL:** %s\n\n", start.url)
+	prompt.WriteString("Decide whether the start page is a LISTING that links to the pages holding the data, or a DETAIL page that holds the data itself.\n")
+	prompt.WriteString("For a listing, give a CSS selector matching the <a> elements that link to the detail pages (not navigation, filters or ads), and the CSS selector of the next-page link if the listing is paginated.\n\n")
+	writeSample(&prompt, "Start page", start)
+	prompt.WriteString("Respond with a JSON object in this exact format:\n")
+	prompt.WriteString("```json\n")
+	prompt.WriteString(`{
+  "page_kind": "listing",
+  "link_selector": ".product-card a.title",
+  "next_page_selector": "a.next",
+  "explanation": "brief explanation"
+}`)
+	prompt.WriteString("\n```\n")
+	prompt.WriteString("Use \"\" for selectors that do not apply.\n")
+	prompt.WriteString("**Respond ONLY with the JSON object. No additional text.**")
+
+	response, err := g.generate(ctx, prompt.String(), start.screenshot)
+	if err != nil {
+		return nil, fmt.Errorf("AI discovery proposal failed: %w", err)
+	}
+
+	var proposal discoveryProposal
+	if err := json.Unmarshal([]byte(extractJSON(response)), &proposal); err != nil {
+		g.logger.Error("Failed to parse AI discovery proposal", zap.String("response", response), zap.Error(err))
+		return nil, fmt.Errorf("invalid JSON response from AI: %w", err)
+	}
+	proposal.PageKind = strings.ToLower(strings.TrimSpace(proposal.PageKind))
+	return &proposal, nil
+}
+
+// proposeExtraction asks for the fields of the goal on the detail samples
+// rejection is the validation error of the previous proposal, if any.
+func (g *WorkflowGenerator) proposeExtraction(ctx context.Context, goal string, details []*pageSample, rejection error) (*extractionProposal, error) {
+	var prompt strings.Builder
+	prompt.WriteString("You are a web scraping expert designing a crawler.\n\n")
+	fmt.Fprintf(&prompt, "**Goal:** %s\n\n", goal)
+	prompt.WriteString("The pages below are samples of the same page template. Propose the fields to extract for the goal, with CSS selectors that work on every sample.\n")
+	prompt.WriteString("Prefer stable selectors: ids, itemprop, data-* attributes and semantic class names over positions and generated class names.\n\n")
+	prompt.WriteString("Field types: text, attr (set attribute), html, href, src. Set multiple for lists such as image galleries.\n")
+	transforms := extraction.ListTransforms()
+	names := make([]string, 0, len(transforms))
+	for _, t := range transforms {
+		if len(t.Params) == 0 {
+			names = append(names, t.Name)
+		}
+	}
+	fmt.Fprintf(&prompt, "Optional transform, one of: %s.\n\n", strings.Join(names, ", "))
+	if rejection != nil {
+		fmt.Fprintf(&prompt, "**Your previous answer was rejected:** %s\nFix it in this answer.\n\n", rejection.Error())
+	}
+	for i, sample := range details {
+		writeSample(&prompt, fmt.Sprintf("Sample %d", i+1), sample)
+	}
+	prompt.WriteString("Respond with a JSON object in this exact format:\n")
+	prompt.WriteString("```json\n")
+	prompt.WriteString(`{
+  "name": "short workflow name",
+  "wait_for": "CSS selector that appears once the data has rendered, or empty",
+  "fields": {
+    "name": {"selector": "h1.product-title", "type": "text", "transform": "trim"},
+    "price": {"selector": "[itemprop=price]", "type": "attr", "attribute": "content", "transform": "extract_price"},
+    "images": {"selector": ".gallery img", "type": "src", "multiple": true}
+  },
+  "explanation": "brief explanation"
+}`)
+	prompt.WriteString("\n```\n")
+	prompt.WriteString("**Respond ONLY with the JSON object. No additional text.**")
+
+	// The first sample's screenshot shows the layout; the others are sent as DOM only
+	response, err := g.generate(ctx, prompt.String(), details[0].screenshot)
+	if err != nil {
+		return nil, fmt.Errorf("AI extraction proposal failed: %w", err)
+	}
+
+	var proposal extractionProposal
+	if err := json.Unmarshal([]byte(extractJSON(response)), &proposal); err != nil {
+		g.logger.Error("Failed to parse AI extraction proposal", zap.String("response", response), zap.Error(err))
+		return nil, fmt.Errorf("invalid JSON response from AI: %w", err)
+	}
+	if len(proposal.Fields) == 0 {
+		return nil, fmt.Errorf("AI did not propose any fields")
+	}
+	return &proposal, nil
+}
+
+// generate sends a prompt with a screenshot when there is one
+func (g *WorkflowGenerator) generate(ctx context.Context, prompt string, screenshot []byte) (string, error) {
+	if len(screenshot) > 0 {
+		return g.aiClient.GenerateWithImage(ctx, prompt, screenshot, "image/jpeg")
+	}
+	return g.aiClient.GenerateText(ctx, prompt)
+}
+
+// writeSample adds a page's URL and trimmed DOM to a prompt
+func writeSample(prompt *strings.Builder, title string, sample *pageSample) {
+	fmt.Fprintf(prompt, "**%s:** %s\n", title, sample.url)
+	prompt.WriteString("```html\n")
+	prompt.WriteString(sample.dom)
+	prompt.WriteString("\n```\n\n")
+}
+
+// buildGeneratedConfig builds a phase-based workflow from the proposals
+// A listing start page gets a discovery phase that marks the detail links; the extraction
+// phase takes the marked URLs, or the start URL itself when it is the detail page.
+func buildGeneratedConfig(startURL string, discovery *discoveryProposal, extraction *extractionProposal) *models.WorkflowConfig {
+	config := &models.WorkflowConfig{
+		StartURLs:      []string{startURL},
+		RateLimitDelay: 1000,
+		Scope:          &models.ScopeConfig{SameDomainOnly: true},
+	}
+
+	fieldNames := make([]string, 0, len(extraction.Fields))
+	for name := range extraction.Fields {
+		fieldNames = append(fieldNames, name)
+	}
+	sort.Strings(fieldNames)
+	fields := make(map[string]interface{}, len(extraction.Fields))
+	for _, name := range fieldNames {
+		fields[name] = extraction.Fields[name].params()
+	}
+
+	var extractNodes []models.Node
+	var dependencies []string
+	if extraction.WaitFor != "" {
+		extractNodes = append(extractNodes, models.Node{
+			ID:       "wait_for_content",
+			Type:     models.NodeTypeWait,
+			Name:     "Wait For Content",
+			Params:   map[string]interface{}{"selector": extraction.WaitFor, "timeout": 10000},
+			Optional: true,
+		})
+		dependencies = []string{"wait_for_content"}
+	}
+	extractNodes = append(extractNodes, models.Node{
+		ID:           "extract_fields",
+		Type:         models.NodeTypeExtract,
+		Name:         "Extract Fields",
+		Params:       map[string]interface{}{"fields": fields},
+		Dependencies: dependencies,
+	})
+
+	extractPhase := models.WorkflowPhase{
+		ID:    generatedExtractionPhase,
+		Type:  models.PhaseTypeExtraction,
+		Name:  "Extract Details",
+		Nodes: extractNodes,
+	}
+
+	if discovery.PageKind == "detail" || discovery.LinkSelector == "" {
+		depth := 0
+		extractPhase.URLFilter = &models.URLFilter{Depth: &depth}
+		config.Phases = []models.WorkflowPhase{extractPhase}
+		return config
+	}
+
+	var discoveryNode models.Node
+	if discovery.NextPageSelector != "" {
+		discoveryNode = models.Node{
+			ID:   "paginate_listing",
+			Type: models.NodeTypePaginate,
+			Name: "Paginate Listing",
+			Params: map[string]interface{}{
+				"selector":      discovery.NextPageSelector,
+				"link_selector": discovery.LinkSelector,
+				"max_pages":     10,
+				"marker":        generatedMarker,
+			},
+		}
+	} else {
+		discoveryNode = models.Node{
+			ID:   "extract_detail_links",
+			Type: models.NodeTypeExtractLinks,
+			Name: "Extract Detail Links",
+			Params: map[string]interface{}{
+				"selector": discovery.LinkSelector,
+				"marker":   generatedMarker,
+			},
+		}
+	}
+
+	depth := 0
+	config.MaxDepth = 1
+	extractPhase.URLFilter = &models.URLFilter{Markers: []string{generatedMarker}}
+	config.Phases = []models.WorkflowPhase{
+		{
+			ID:         generatedDiscoveryPhase,
+			Type:       models.PhaseTypeDiscovery,
+			Name:       "Discover Detail Pages",
+			Nodes:      []models.Node{discoveryNode},
+			URLFilter:  &models.URLFilter{Depth: &depth},
+			Transition: &models.PhaseTransition{Condition: "all_nodes_complete", NextPhase: generatedExtractionPhase},
+		},
+		extractPhase,
+	}
+	return config
+}
+
+// params returns the extract node config of a field
+func (f proposedField) params() map[string]interface{} {
+	params := map[string]interface{}{
+		"selector": f.Selector,
+		"type":     f.Type,
+	}
+	if f.Type == "" {
+		params["type"] = "text"
+	}
+	if f.Attribute != "" {
+		params["attribute"] = f.Attribute
+	}
+	if f.Multiple {
+		params["multiple"] = true
+	}
+	if f.Transform != "" {
+		params["transform"] = f.Transform
+	}
+	return params
+}
+
+// validate checks a generated config with the parser and each node's executor
+func (g *WorkflowGenerator) validate(config *models.WorkflowConfig) error {
+	if err := g.parser.Validate(config); err != nil {
+		return err
+	}
+	for _, phase := range config.Phases {
+		for _, node := range phase.Nodes {
+			executor, err := g.registry.Get(node.Type)
+			if err != nil {
+				return err
+			}
+			if err := executor.Validate(node.Params); err != nil {
+				return fmt.Errorf("node '%s': %w", node.ID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// dryRun runs the generated nodes on the sample pages and records what they return
+// Nothing is enqueued or saved; pagination only reads the first page.
+func (g *WorkflowGenerator) dryRun(ctx context.Context, browserCtx *browser.BrowserContext, startURL string, config *models.WorkflowConfig, detailURLs []string, result *GeneratedWorkflow) {
+	for _, phase := range config.Phases {
+		urls := detailURLs
+		if phase.ID == generatedDiscoveryPhase {
+			urls = []string{startURL}
+		}
+
+		for _, pageURL := range urls {
+			if err := g.navigate(browserCtx, pageURL); err != nil {
+				result.Preview = append(result.Preview, GeneratePreview{URL: pageURL, Phase: phase.ID, Error: err.Error()})
+				continue
+			}
+			execCtx := models.NewExecutionContext()
+			for _, node := range phase.Nodes {
+				params := node.Params
+				if node.Type == models.NodeTypePaginate {
+					// Read the listing's first page without following the next link
+					params = map[string]interface{}{"selector": params["link_selector"]}
+					node.Type = models.NodeTypeExtractLinks
+				}
+
+				preview := GeneratePreview{URL: pageURL, Phase: phase.ID, NodeID: node.ID}
+				output, err := g.runNodeWith(ctx, browserCtx, &execCtx, node.Type, params)
+				if err != nil {
+					preview.Error = err.Error()
+					if !node.Optional {
+						result.Warnings = append(result.Warnings, fmt.Sprintf("node '%s' failed on %s: %s", node.ID, pageURL, err.Error()))
+					}
+				} else if node.Type == models.NodeTypeExtractLinks {
+					preview.Result = map[string]interface{}{
+						"count":  len(output.DiscoveredURLs),
+						"sample": output.DiscoveredURLs[:min(len(output.DiscoveredURLs), 5)],
+					}
+				} else if node.Type == models.NodeTypeExtract {
+					preview.Result = output.Result
+					preview.Empty = emptyFields(output.Result)
+				} else {
+					continue
+				}
+				result.Preview = append(result.Preview, preview)
+			}
+		}
+	}
+
+	// A field empty on every sample is most likely a wrong selector
+	emptyCount := make(map[string]int)
+	samples := 0
+	for _, preview := range result.Preview {
+		if preview.Phase != generatedExtractionPhase || preview.Error != "" || preview.NodeID != "extract_fields" {
+			continue
+		}
+		samples++
+		for _, field := range preview.Empty {
+			emptyCount[field]++
+		}
+	}
+	for field, count := range emptyCount {
+		if count == samples {
+			result.Warnings = append(result.Warnings, fmt.Sprintf("field '%s' was empty on every sample page", field))
+		}
+	}
+	sort.Strings(result.Warnings)
+}
+
+// runNode runs a node with a fresh execution context on the current page
+func (g *WorkflowGenerator) runNode(ctx context.Context, browserCtx *browser.BrowserContext, nodeType models.NodeType, params map[string]interface{}) (*nodes.ExecutionOutput, error) {
+	execCtx := models.NewExecutionContext()
+	return g.runNodeWith(ctx, browserCtx, &execCtx, nodeType, params)
+}
+
+func (g *WorkflowGenerator) runNodeWith(ctx context.Context, browserCtx *browser.BrowserContext, execCtx *models.ExecutionContext, nodeType models.NodeType, params map[string]interface{}) (*nodes.ExecutionOutput, error) {
+	executor, err := g.registry.Get(nodeType)
+	if err != nil {
+		return nil, err
+	}
+	nodeCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	return executor.Execute(nodeCtx, &nodes.ExecutionInput{
+		BrowserContext:   browserCtx,
+		ExecutionContext: execCtx,
+		Params:           params,
+		URLItem:          &models.URLQueueItem{URL: browserCtx.Page.URL()},
+	})
+}
+
+// sampleLinks picks up to max distinct same-host links other than the start page, resolved
+// against it
+func sampleLinks(startURL string, links []string, max int) []string {
+	base, err := url.Parse(startURL)
+	if err != nil {
+		return nil
+	}
+	seen := map[string]bool{startURL: true}
+	var samples []string
+	for _, link := range links {
+		if len(samples) >= max {
+			break
+		}
+		u, err := url.Parse(link)
+		if err != nil {
+			continue
+		}
+		u = base.ResolveReference(u)
+		u.Fragment = ""
+		abs := u.String()
+		if u.Host != base.Host || seen[abs] {
+			continue
+		}
+		seen[abs] = true
+		samples = append(samples, abs)
+	}
+	return samples
+}
+
+// emptyFields lists the fields of an extract result that hold nothing
+func emptyFields(result interface{}) []string {
+	fields, ok := result.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	var empty []string
+	for name, value := range fields {
+		switch v := value.(type) {
+		case nil:
+			empty = append(empty, name)
+		case string:
+			if strings.TrimSpace(v) == "" {
+				empty = append(empty, name)
+			}
+		case []interface{}:
+			if len(v) == 0 {
+				empty = append(empty, name)
+			}
+		case []string:
+			if len(v) == 0 {
+				empty = append(empty, name)
+			}
+		}
+	}
+	sort.Strings(empty)
+	return empty
+}
+
+// hostOf returns the host of a URL, or "" when it does not parse
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
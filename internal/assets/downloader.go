@@ -0,0 +1,292 @@
+package assets
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/uzzalhcse/crawlify/internal/browser"
+	"github.com/uzzalhcse/crawlify/internal/config"
+	_ "golang.org/x/image/webp"
+)
+
+// Asset describes a downloaded file; it is attached to extracted items
+type Asset struct {
+	URL         string `json:"url"`                 // URL the file was requested from
+	Path        string `json:"path"`                // Content-addressed key in the store
+	Location    string `json:"location"`            // File path, s3:// URI or public URL of the stored file
+	Hash        string `json:"hash"`                // SHA-256 of the content
+	Size        int64  `json:"size"`                // Bytes
+	ContentType string `json:"content_type"`        // MIME type
+	Width       int    `json:"width,omitempty"`     // Images only
+	Height      int    `json:"height,omitempty"`    // Images only
+	Duplicate   bool   `json:"duplicate,omitempty"` // Content was already stored
+}
+
+// Map converts the asset to the plain map stored with extracted items
+func (a *Asset) Map() map[string]interface{} {
+	m := map[string]interface{}{
+		"url":          a.URL,
+		"path":         a.Path,
+		"location":     a.Location,
+		"hash":         a.Hash,
+		"size":         a.Size,
+		"content_type": a.ContentType,
+	}
+	if a.Width > 0 || a.Height > 0 {
+		m["width"] = a.Width
+		m["height"] = a.Height
+	}
+	if a.Duplicate {
+		m["duplicate"] = true
+	}
+	return m
+}
+
+// Options narrow the configured limits for a single download; they can never widen them
+type Options struct {
+	MaxBytes     int64
+	AllowedTypes []string
+	Timeout      time.Duration
+}
+
+// ErrTypeNotAllowed is returned when a file's MIME type is not allowed
+type ErrTypeNotAllowed struct {
+	URL         string
+	ContentType string
+}
+
+func (e *ErrTypeNotAllowed) Error() string {
+	return fmt.Sprintf("file %s has type %s, which is not allowed", e.URL, e.ContentType)
+}
+
+// Downloader fetches referenced files through a browser context and stores them by content hash
+type Downloader struct {
+	store        Store
+	maxBytes     int64
+	allowedTypes []string
+	timeout      time.Duration
+}
+
+// NewDownloader creates a downloader; an empty allowedTypes list allows every type
+func NewDownloader(store Store, maxBytes int64, allowedTypes []string, timeout time.Duration) *Downloader {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &Downloader{
+		store:        store,
+		maxBytes:     maxBytes,
+		allowedTypes: allowedTypes,
+		timeout:      timeout,
+	}
+}
+
+// NewDownloaderFromConfig creates a downloader and its store from the assets config
+func NewDownloaderFromConfig(cfg *config.AssetsConfig) (*Downloader, error) {
+	store, err := NewStore(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewDownloader(store, cfg.MaxBytes, cfg.AllowedTypes, time.Duration(cfg.Timeout)*time.Millisecond), nil
+}
+
+// Download fetches rawURL with the context's cookies and proxy and stores it
+func (d *Downloader) Download(ctx context.Context, bc *browser.BrowserContext, rawURL string, opts Options) (*Asset, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("cannot download '%s': not an http(s) URL", rawURL)
+	}
+
+	file, err := bc.FetchFile(rawURL, d.limit(opts), d.timeoutFor(opts))
+	if err != nil {
+		return nil, err
+	}
+	return d.save(ctx, rawURL, file, opts)
+}
+
+// DownloadByClick clicks selector, waits for the browser download it starts and stores the file
+func (d *Downloader) DownloadByClick(ctx context.Context, bc *browser.BrowserContext, selector string, opts Options) (*Asset, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	file, err := bc.DownloadByClick(selector, d.limit(opts), d.timeoutFor(opts))
+	if err != nil {
+		return nil, err
+	}
+	return d.save(ctx, file.URL, file, opts)
+}
+
+// DownloadValue downloads the URL or list of URLs in value (an extracted field)
+// A single URL yields an asset map, a list yields the assets that downloaded; failures are returned as errors
+func (d *Downloader) DownloadValue(ctx context.Context, bc *browser.BrowserContext, value interface{}, opts Options) (interface{}, []error) {
+	switch v := value.(type) {
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return nil, nil
+		}
+		asset, err := d.Download(ctx, bc, strings.TrimSpace(v), opts)
+		if err != nil {
+			return nil, []error{err}
+		}
+		return asset.Map(), nil
+	case []string:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = item
+		}
+		return d.DownloadValue(ctx, bc, list, opts)
+	case []interface{}:
+		var errs []error
+		downloaded := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			asset, itemErrs := d.DownloadValue(ctx, bc, item, opts)
+			errs = append(errs, itemErrs...)
+			if asset != nil {
+				downloaded = append(downloaded, asset)
+			}
+		}
+		return downloaded, errs
+	case nil:
+		return nil, nil
+	default:
+		return nil, []error{fmt.Errorf("cannot download value of type %T", value)}
+	}
+}
+
+// save checks the type, hashes the content and stores it unless the same content is already stored
+func (d *Downloader) save(ctx context.Context, sourceURL string, file *browser.FetchedFile, opts Options) (*Asset, error) {
+	contentType := detectContentType(file.ContentType, file.Body)
+	if !matchesType(d.allowedTypes, contentType) || !matchesType(opts.AllowedTypes, contentType) {
+		return nil, &ErrTypeNotAllowed{URL: sourceURL, ContentType: contentType}
+	}
+
+	sum := sha256.Sum256(file.Body)
+	hash := hex.EncodeToString(sum[:])
+	key := hash[:2] + "/" + hash[2:4] + "/" + hash + extensionFor(contentType, file.URL)
+
+	asset := &Asset{
+		URL:         sourceURL,
+		Path:        key,
+		Location:    d.store.Location(key),
+		Hash:        hash,
+		Size:        int64(len(file.Body)),
+		ContentType: contentType,
+	}
+	if strings.HasPrefix(contentType, "image/") {
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(file.Body)); err == nil {
+			asset.Width = cfg.Width
+			asset.Height = cfg.Height
+		}
+	}
+
+	exists, err := d.store.Exists(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		asset.Duplicate = true
+		return asset, nil
+	}
+	if err := d.store.Put(ctx, key, contentType, file.Body); err != nil {
+		return nil, err
+	}
+	return asset, nil
+}
+
+// limit returns the effective size limit: the smaller of the configured and requested limits
+func (d *Downloader) limit(opts Options) int64 {
+	if opts.MaxBytes > 0 && (d.maxBytes <= 0 || opts.MaxBytes < d.maxBytes) {
+		return opts.MaxBytes
+	}
+	return d.maxBytes
+}
+
+func (d *Downloader) timeoutFor(opts Options) time.Duration {
+	if opts.Timeout > 0 {
+		return opts.Timeout
+	}
+	return d.timeout
+}
+
+// detectContentType trusts the server's type unless it is missing or generic, then sniffs the content
+func detectContentType(header string, body []byte) string {
+	if mediaType, _, err := mime.ParseMediaType(header); err == nil {
+		switch mediaType {
+		case "application/octet-stream", "binary/octet-stream", "application/force-download":
+		default:
+			return mediaType
+		}
+	}
+	mediaType, _, _ := mime.ParseMediaType(http.DetectContentType(body))
+	return mediaType
+}
+
+// matchesType reports whether contentType matches one of the patterns ("image/*", "application/pdf")
+// An empty pattern list matches everything
+func matchesType(patterns []string, contentType string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "*" || pattern == "*/*" || pattern == contentType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok && strings.HasPrefix(contentType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// commonExtensions avoids the platform-dependent choices of mime.ExtensionsByType for frequent types
+var commonExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"image/avif":      ".avif",
+	"image/svg+xml":   ".svg",
+	"application/pdf": ".pdf",
+	"application/zip": ".zip",
+	"text/csv":        ".csv",
+	"text/plain":      ".txt",
+}
+
+// extensionFor picks a file extension for the content type, preferring the URL's own extension when it fits
+func extensionFor(contentType, rawURL string) string {
+	if ext, ok := commonExtensions[contentType]; ok {
+		return ext
+	}
+	urlExt := ""
+	if u, err := url.Parse(rawURL); err == nil {
+		if ext := strings.ToLower(path.Ext(u.Path)); len(ext) > 1 && len(ext) <= 6 {
+			urlExt = ext
+		}
+	}
+	exts, _ := mime.ExtensionsByType(contentType)
+	for _, ext := range exts {
+		if ext == urlExt {
+			return ext
+		}
+	}
+	if len(exts) > 0 {
+		return exts[0]
+	}
+	return urlExt
+}
@@ -0,0 +1,176 @@
+package assets
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/uzzalhcse/crawlify/internal/config"
+)
+
+// Store persists downloaded files by key
+type Store interface {
+	// Exists reports whether a file with this key is already stored
+	Exists(ctx context.Context, key string) (bool, error)
+	// Put stores data under key
+	Put(ctx context.Context, key, contentType string, data []byte) error
+	// Location returns where a stored key can be found (file path, s3:// URI or public URL)
+	Location(key string) string
+}
+
+// NewStore creates the store selected by cfg.Storage
+func NewStore(cfg *config.AssetsConfig) (Store, error) {
+	switch cfg.Storage {
+	case "", "local":
+		return NewLocalStore(cfg.LocalPath, cfg.PublicURL)
+	case "s3":
+		return NewS3Store(&cfg.S3, cfg.PublicURL)
+	default:
+		return nil, fmt.Errorf("unsupported asset storage: %s", cfg.Storage)
+	}
+}
+
+// LocalStore stores files under a directory on the local filesystem
+type LocalStore struct {
+	root      string
+	publicURL string
+}
+
+// NewLocalStore creates a local store rooted at root
+func NewLocalStore(root, publicURL string) (*LocalStore, error) {
+	if root == "" {
+		return nil, fmt.Errorf("local asset storage requires local_path")
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create asset directory: %w", err)
+	}
+	return &LocalStore{root: root, publicURL: publicURL}, nil
+}
+
+// Exists reports whether key is stored
+func (s *LocalStore) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := os.Stat(s.path(key))
+	if err == nil {
+		return true, nil
+	}
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	return false, err
+}
+
+// Put writes data atomically so a crash never leaves a truncated file under a content hash
+func (s *LocalStore) Put(ctx context.Context, key, contentType string, data []byte) error {
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create asset directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".download-*")
+	if err != nil {
+		return fmt.Errorf("failed to create asset file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write asset file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write asset file: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Location returns the public URL when configured, otherwise the file path
+func (s *LocalStore) Location(key string) string {
+	if s.publicURL != "" {
+		return joinURL(s.publicURL, key)
+	}
+	return s.path(key)
+}
+
+func (s *LocalStore) path(key string) string {
+	return filepath.Join(s.root, filepath.FromSlash(key))
+}
+
+// S3Store stores files in an S3-compatible bucket
+type S3Store struct {
+	client    *minio.Client
+	bucket    string
+	prefix    string
+	publicURL string
+}
+
+// NewS3Store creates a store for an S3-compatible endpoint
+func NewS3Store(cfg *config.S3Config, publicURL string) (*S3Store, error) {
+	if cfg.Bucket == "" {
+		return nil, fmt.Errorf("s3 asset storage requires a bucket")
+	}
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "s3.amazonaws.com"
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create s3 client: %w", err)
+	}
+
+	return &S3Store{
+		client:    client,
+		bucket:    cfg.Bucket,
+		prefix:    cfg.Prefix,
+		publicURL: publicURL,
+	}, nil
+}
+
+// Exists reports whether key is stored
+func (s *S3Store) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := s.client.StatObject(ctx, s.bucket, s.prefix+key, minio.StatObjectOptions{})
+	if err == nil {
+		return true, nil
+	}
+	if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return false, nil
+	}
+	return false, fmt.Errorf("failed to check s3 object: %w", err)
+}
+
+// Put uploads data under key
+func (s *S3Store) Put(ctx context.Context, key, contentType string, data []byte) error {
+	_, err := s.client.PutObject(ctx, s.bucket, s.prefix+key, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{
+		ContentType: contentType,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload to s3: %w", err)
+	}
+	return nil
+}
+
+// Location returns the public URL when configured, otherwise an s3:// URI
+func (s *S3Store) Location(key string) string {
+	if s.publicURL != "" {
+		return joinURL(s.publicURL, s.prefix+key)
+	}
+	return fmt.Sprintf("s3://%s/%s%s", s.bucket, s.prefix, key)
+}
+
+// joinURL appends a key to a base URL, escaping each path segment
+func joinURL(base, key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.TrimRight(base, "/") + "/" + strings.Join(segments, "/")
+}
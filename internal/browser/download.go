@@ -0,0 +1,108 @@
+package browser
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// FetchedFile is a file fetched through the browser context
+type FetchedFile struct {
+	URL         string // Final URL after redirects
+	ContentType string
+	Body        []byte
+}
+
+// ErrFileTooLarge is returned when a file exceeds the caller's size limit
+type ErrFileTooLarge struct {
+	URL   string
+	Size  int64
+	Limit int64
+}
+
+func (e *ErrFileTooLarge) Error() string {
+	return fmt.Sprintf("file %s is %d bytes, larger than the %d byte limit", e.URL, e.Size, e.Limit)
+}
+
+// FetchFile downloads a URL with the context's request API, so the page's cookies,
+// extra headers and proxy are used. The page URL is sent as Referer, since many CDNs require it
+func (bc *BrowserContext) FetchFile(rawURL string, maxBytes int64, timeout time.Duration) (*FetchedFile, error) {
+	headers := map[string]string{}
+	if referer := bc.Page.URL(); referer != "" && referer != "about:blank" {
+		headers["Referer"] = referer
+	}
+
+	resp, err := bc.Context.Request().Get(rawURL, playwright.APIRequestContextGetOptions{
+		Headers:      headers,
+		Timeout:      playwright.Float(float64(timeout.Milliseconds())),
+		MaxRedirects: playwright.Int(5),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+	defer resp.Dispose()
+
+	if !resp.Ok() {
+		return nil, fmt.Errorf("failed to fetch %s: HTTP %d", rawURL, resp.Status())
+	}
+
+	respHeaders := resp.Headers()
+	// Reject oversized files before reading the body when the server declares a length
+	if length, err := strconv.ParseInt(respHeaders["content-length"], 10, 64); err == nil && maxBytes > 0 && length > maxBytes {
+		return nil, &ErrFileTooLarge{URL: rawURL, Size: length, Limit: maxBytes}
+	}
+
+	body, err := resp.Body()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", rawURL, err)
+	}
+	if maxBytes > 0 && int64(len(body)) > maxBytes {
+		return nil, &ErrFileTooLarge{URL: rawURL, Size: int64(len(body)), Limit: maxBytes}
+	}
+
+	return &FetchedFile{
+		URL:         resp.URL(),
+		ContentType: respHeaders["content-type"],
+		Body:        body,
+	}, nil
+}
+
+// DownloadByClick clicks an element that triggers a browser download (e.g. a "Download PDF" button)
+// and returns the downloaded file
+func (bc *BrowserContext) DownloadByClick(selector string, maxBytes int64, timeout time.Duration) (*FetchedFile, error) {
+	download, err := bc.Page.ExpectDownload(func() error {
+		return bc.Page.Locator(selector).First().Click()
+	}, playwright.PageExpectDownloadOptions{
+		Timeout: playwright.Float(float64(timeout.Milliseconds())),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("no download started by clicking '%s': %w", selector, err)
+	}
+	defer download.Delete()
+
+	path, err := download.Path()
+	if err != nil {
+		return nil, fmt.Errorf("download from %s failed: %w", download.URL(), err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("download from %s failed: %w", download.URL(), err)
+	}
+	if maxBytes > 0 && info.Size() > maxBytes {
+		return nil, &ErrFileTooLarge{URL: download.URL(), Size: info.Size(), Limit: maxBytes}
+	}
+
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read download from %s: %w", download.URL(), err)
+	}
+
+	// The browser does not expose the response headers of a download; the type is sniffed from the content
+	return &FetchedFile{
+		URL:  download.URL(),
+		Body: body,
+	}, nil
+}
@@ -1,6 +1,7 @@
 package browser
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/uzzalhcse/crawlify/internal/logger"
@@ -33,6 +34,22 @@ func (bc *BrowserContext) GetResponseHeaders() (map[string]string, error) {
 	return headers, nil
 }
 
+// GetResponseJSON parses the body of the last navigation as JSON (for API endpoints opened as pages)
+func (bc *BrowserContext) GetResponseJSON() (interface{}, error) {
+	if bc.lastResponse == nil {
+		return nil, fmt.Errorf("no response available")
+	}
+	body, err := bc.lastResponse.Body()
+	if err != nil {
+		return nil, err
+	}
+	var data interface{}
+	if err := json.Unmarshal(body, &data); err != nil {
+		return nil, fmt.Errorf("response is not JSON: %w", err)
+	}
+	return data, nil
+}
+
 // GetPageBody returns the current page content
 func (bc *BrowserContext) GetPageBody() (string, error) {
 	return bc.Page.Content()
@@ -0,0 +1,430 @@
+package browser
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+	"github.com/uzzalhcse/crawlify/internal/logger"
+	"go.uber.org/zap"
+)
+
+// defaultMaxBodyBytes caps captured response bodies (5MB)
+const defaultMaxBodyBytes = 5 * 1024 * 1024
+
+// defaultTrackerDomains are analytics and ad domains blocked by block_trackers
+var defaultTrackerDomains = []string{
+	"google-analytics.com",
+	"googletagmanager.com",
+	"googleadservices.com",
+	"googlesyndication.com",
+	"doubleclick.net",
+	"facebook.net",
+	"connect.facebook.com",
+	"hotjar.com",
+	"segment.io",
+	"segment.com",
+	"mixpanel.com",
+	"amplitude.com",
+	"newrelic.com",
+	"nr-data.net",
+	"clarity.ms",
+	"criteo.com",
+	"taboola.com",
+	"outbrain.com",
+	"adsrvr.org",
+	"scorecardresearch.com",
+}
+
+// InterceptRule matches network responses to capture
+type InterceptRule struct {
+	Name          string   `json:"name"`
+	URLPattern    string   `json:"url_pattern"`    // Glob ("*/api/products*"), substring, or "re:<regex>"
+	Methods       []string `json:"methods"`        // HTTP methods (empty = any)
+	ResourceTypes []string `json:"resource_types"` // Playwright resource types, e.g. xhr, fetch (empty = any)
+	MaxCaptures   int      `json:"max_captures"`   // 0 = unlimited
+}
+
+// InterceptConfig configures response capture and request blocking for a page
+type InterceptConfig struct {
+	Rules              []InterceptRule
+	BlockResourceTypes []string // e.g. image, font, media, stylesheet
+	BlockDomains       []string
+	BlockTrackers      bool
+	MaxBodyBytes       int
+}
+
+// CapturedResponse is a network response captured by an intercept rule
+type CapturedResponse struct {
+	Rule         string            `json:"rule"`
+	URL          string            `json:"url"`
+	Method       string            `json:"method"`
+	ResourceType string            `json:"resource_type"`
+	Status       int               `json:"status"`
+	Headers      map[string]string `json:"headers"`
+	ContentType  string            `json:"content_type"`
+	Body         interface{}       `json:"body"` // Parsed JSON when the body is JSON, otherwise a string
+	Truncated    bool              `json:"truncated,omitempty"`
+	CapturedAt   time.Time         `json:"captured_at"`
+}
+
+// networkInterceptor holds the compiled rules and captured responses for a page
+type networkInterceptor struct {
+	rules        []compiledInterceptRule
+	maxBodyBytes int
+
+	mu       sync.Mutex
+	pending  sync.WaitGroup
+	captures map[string][]CapturedResponse
+	blocked  int
+}
+
+type compiledInterceptRule struct {
+	InterceptRule
+	pattern *regexp.Regexp
+	methods map[string]bool
+	types   map[string]bool
+}
+
+// StartIntercept registers response capture and request blocking on the page
+// It must be called before navigation to see the page's initial API calls
+func (bc *BrowserContext) StartIntercept(cfg InterceptConfig) error {
+	if bc.interceptor != nil {
+		return fmt.Errorf("network interception already active for this page")
+	}
+
+	interceptor := &networkInterceptor{
+		maxBodyBytes: cfg.MaxBodyBytes,
+		captures:     make(map[string][]CapturedResponse),
+	}
+	if interceptor.maxBodyBytes <= 0 {
+		interceptor.maxBodyBytes = defaultMaxBodyBytes
+	}
+
+	for i, rule := range cfg.Rules {
+		compiled, err := compileInterceptRule(rule)
+		if err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+		interceptor.rules = append(interceptor.rules, compiled)
+	}
+
+	if len(interceptor.rules) > 0 {
+		bc.Page.OnResponse(interceptor.onResponse)
+	}
+
+	blockTypes := toSet(cfg.BlockResourceTypes)
+	blockDomains := cfg.BlockDomains
+	if cfg.BlockTrackers {
+		blockDomains = append(append([]string{}, blockDomains...), defaultTrackerDomains...)
+	}
+	if len(blockTypes) > 0 || len(blockDomains) > 0 {
+		err := bc.Page.Route("**/*", func(route playwright.Route) {
+			request := route.Request()
+			if blockTypes[request.ResourceType()] || hostMatchesAny(request.URL(), blockDomains) {
+				interceptor.mu.Lock()
+				interceptor.blocked++
+				interceptor.mu.Unlock()
+				route.Abort("blockedbyclient")
+				return
+			}
+			route.Fallback()
+		})
+		if err != nil {
+			return fmt.Errorf("failed to register request blocking: %w", err)
+		}
+	}
+
+	bc.interceptor = interceptor
+	return nil
+}
+
+// IsIntercepting checks if network interception is active for the page
+func (bc *BrowserContext) IsIntercepting() bool {
+	return bc.interceptor != nil
+}
+
+// Captures returns captured responses for a rule ("" = all rules)
+// It waits up to timeout for in-flight response bodies to be read
+func (bc *BrowserContext) Captures(rule string, timeout time.Duration) []CapturedResponse {
+	if bc.interceptor == nil {
+		return nil
+	}
+	bc.interceptor.waitPending(timeout)
+
+	bc.interceptor.mu.Lock()
+	defer bc.interceptor.mu.Unlock()
+
+	var result []CapturedResponse
+	if rule != "" {
+		result = append(result, bc.interceptor.captures[rule]...)
+		return result
+	}
+	for _, r := range bc.interceptor.rules {
+		result = append(result, bc.interceptor.captures[r.Name]...)
+	}
+	return result
+}
+
+// WaitForCapture waits until a rule ("" = any rule) has captured at least one response
+func (bc *BrowserContext) WaitForCapture(rule string, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		if len(bc.Captures(rule, 0)) > 0 {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// BlockedRequests returns the number of requests aborted by blocking rules
+func (bc *BrowserContext) BlockedRequests() int {
+	if bc.interceptor == nil {
+		return 0
+	}
+	bc.interceptor.mu.Lock()
+	defer bc.interceptor.mu.Unlock()
+	return bc.interceptor.blocked
+}
+
+// ResponseCapture records the page's responses matching a rule until it is stopped
+// Unlike StartIntercept, any number of captures can run on a page, alongside the workflow's interception
+type ResponseCapture struct {
+	interceptor *networkInterceptor
+	rule        string
+	stopped     atomic.Bool
+}
+
+// CaptureResponses starts capturing the responses matching rule; maxBodyBytes <= 0 uses the default cap
+func (bc *BrowserContext) CaptureResponses(rule InterceptRule, maxBodyBytes int) (*ResponseCapture, error) {
+	compiled, err := compileInterceptRule(rule)
+	if err != nil {
+		return nil, err
+	}
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = defaultMaxBodyBytes
+	}
+	capture := &ResponseCapture{
+		interceptor: &networkInterceptor{
+			rules:        []compiledInterceptRule{compiled},
+			maxBodyBytes: maxBodyBytes,
+			captures:     make(map[string][]CapturedResponse),
+		},
+		rule: compiled.Name,
+	}
+	// Playwright removes listeners by function pointer, which would also drop other captures' listeners,
+	// so a stopped capture stays registered and ignores responses
+	bc.Page.OnResponse(func(resp playwright.Response) {
+		if !capture.stopped.Load() {
+			capture.interceptor.onResponse(resp)
+		}
+	})
+	return capture, nil
+}
+
+// Responses returns the captured responses, waiting up to timeout for in-flight body reads
+func (c *ResponseCapture) Responses(timeout time.Duration) []CapturedResponse {
+	c.interceptor.waitPending(timeout)
+	c.interceptor.mu.Lock()
+	defer c.interceptor.mu.Unlock()
+	return append([]CapturedResponse(nil), c.interceptor.captures[c.rule]...)
+}
+
+// Wait waits until count responses were captured or timeout passes, and returns the captures
+func (c *ResponseCapture) Wait(count int, timeout time.Duration) []CapturedResponse {
+	deadline := time.Now().Add(timeout)
+	for {
+		captured := c.Responses(0)
+		if len(captured) >= count || time.Now().After(deadline) {
+			return c.Responses(time.Until(deadline))
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// Stop ends the capture; responses captured so far stay available
+func (c *ResponseCapture) Stop() {
+	c.stopped.Store(true)
+}
+
+// onResponse matches a response against the rules and reads its body asynchronously
+// Body reads go through the Playwright connection, so they must not block the event handler
+func (ni *networkInterceptor) onResponse(resp playwright.Response) {
+	request := resp.Request()
+	for _, rule := range ni.rules {
+		if !rule.matches(resp.URL(), request.Method(), request.ResourceType()) {
+			continue
+		}
+
+		ni.mu.Lock()
+		if rule.MaxCaptures > 0 && len(ni.captures[rule.Name]) >= rule.MaxCaptures {
+			ni.mu.Unlock()
+			continue
+		}
+		ni.mu.Unlock()
+
+		ni.pending.Add(1)
+		go func(rule compiledInterceptRule) {
+			defer ni.pending.Done()
+			captured := ni.readResponse(rule.Name, resp)
+
+			ni.mu.Lock()
+			defer ni.mu.Unlock()
+			if rule.MaxCaptures > 0 && len(ni.captures[rule.Name]) >= rule.MaxCaptures {
+				return
+			}
+			ni.captures[rule.Name] = append(ni.captures[rule.Name], captured)
+		}(rule)
+	}
+}
+
+// readResponse converts a Playwright response into a CapturedResponse
+func (ni *networkInterceptor) readResponse(rule string, resp playwright.Response) CapturedResponse {
+	request := resp.Request()
+	captured := CapturedResponse{
+		Rule:         rule,
+		URL:          resp.URL(),
+		Method:       request.Method(),
+		ResourceType: request.ResourceType(),
+		Status:       resp.Status(),
+		CapturedAt:   time.Now(),
+	}
+
+	headers, err := resp.AllHeaders()
+	if err != nil {
+		headers = resp.Headers()
+	}
+	captured.Headers = headers
+	captured.ContentType = headers["content-type"]
+
+	body, err := resp.Body()
+	if err != nil {
+		logger.Debug("Failed to read intercepted response body",
+			zap.String("url", captured.URL),
+			zap.Error(err))
+		return captured
+	}
+	if len(body) > ni.maxBodyBytes {
+		body = body[:ni.maxBodyBytes]
+		captured.Truncated = true
+	}
+
+	var parsed interface{}
+	if !captured.Truncated && json.Unmarshal(body, &parsed) == nil {
+		captured.Body = parsed
+	} else {
+		captured.Body = string(body)
+	}
+	return captured
+}
+
+// waitPending waits up to timeout for in-flight body reads
+func (ni *networkInterceptor) waitPending(timeout time.Duration) {
+	if timeout <= 0 {
+		return
+	}
+	done := make(chan struct{})
+	go func() {
+		ni.pending.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+// compileInterceptRule validates a rule and compiles its URL pattern
+func compileInterceptRule(rule InterceptRule) (compiledInterceptRule, error) {
+	compiled := compiledInterceptRule{
+		InterceptRule: rule,
+		methods:       make(map[string]bool),
+		types:         toSet(rule.ResourceTypes),
+	}
+	if compiled.Name == "" {
+		compiled.Name = "default"
+	}
+	for _, method := range rule.Methods {
+		compiled.methods[strings.ToUpper(method)] = true
+	}
+
+	pattern, err := CompileURLPattern(rule.URLPattern)
+	if err != nil {
+		return compiled, err
+	}
+	compiled.pattern = pattern
+	return compiled, nil
+}
+
+// CompileURLPattern compiles a URL pattern: "re:<regex>", a glob with "*", or a substring
+func CompileURLPattern(pattern string) (*regexp.Regexp, error) {
+	switch {
+	case pattern == "":
+		return nil, nil
+	case strings.HasPrefix(pattern, "re:"):
+		re, err := regexp.Compile(strings.TrimPrefix(pattern, "re:"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid url_pattern regex '%s': %w", pattern, err)
+		}
+		return re, nil
+	case strings.Contains(pattern, "*"):
+		parts := strings.Split(pattern, "*")
+		for i, part := range parts {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+		return regexp.MustCompile("^" + strings.Join(parts, ".*") + "$"), nil
+	default:
+		return regexp.MustCompile(regexp.QuoteMeta(pattern)), nil
+	}
+}
+
+// matches checks a response against the rule
+func (r compiledInterceptRule) matches(rawURL, method, resourceType string) bool {
+	if r.pattern != nil && !r.pattern.MatchString(rawURL) {
+		return false
+	}
+	if len(r.methods) > 0 && !r.methods[strings.ToUpper(method)] {
+		return false
+	}
+	if len(r.types) > 0 && !r.types[resourceType] {
+		return false
+	}
+	return true
+}
+
+// hostMatchesAny checks if a URL's host is one of the domains or a subdomain of one
+func hostMatchesAny(rawURL string, domains []string) bool {
+	if len(domains) == 0 {
+		return false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range domains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		if domain != "" && (host == domain || strings.HasSuffix(host, "."+domain)) {
+			return true
+		}
+	}
+	return false
+}
+
+// toSet converts a string slice into a lookup set
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[strings.ToLower(strings.TrimSpace(v))] = true
+	}
+	return set
+}
@@ -188,7 +188,7 @@ func (bl *BrowserLauncher) connectViaCDP(endpoint string) (playwright.Browser, e
 func (bl *BrowserLauncher) CreateContextWithFingerprint(browser playwright.Browser, profile *models.BrowserProfile) (playwright.BrowserContext, error) {
 	options := playwright.BrowserNewContextOptions{
 		JavaScriptEnabled: playwright.Bool(true),
-		AcceptDownloads:   playwright.Bool(false),
+		AcceptDownloads:   playwright.Bool(true),
 		IgnoreHttpsErrors: playwright.Bool(true),
 	}
 
@@ -0,0 +1,81 @@
+package browser
+
+import (
+	"sync"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+)
+
+// longLivedResourceTypes never finish while the page is open and are not counted as activity
+var longLivedResourceTypes = map[string]bool{
+	"websocket":   true,
+	"eventsource": true,
+	"media":       true,
+}
+
+// NetworkMonitor tracks a page's in-flight requests to detect when the network goes idle
+// Playwright's networkidle load state only applies to the initial page load, not to later XHR activity
+type NetworkMonitor struct {
+	page         playwright.Page
+	mu           sync.Mutex
+	inflight     map[playwright.Request]bool
+	lastActivity time.Time
+	onStart      func(playwright.Request)
+	onDone       func(playwright.Request)
+}
+
+// MonitorNetwork starts tracking requests on the page; call Stop when done
+func (bc *BrowserContext) MonitorNetwork() *NetworkMonitor {
+	m := &NetworkMonitor{
+		page:         bc.Page,
+		inflight:     make(map[playwright.Request]bool),
+		lastActivity: time.Now(),
+	}
+	m.onStart = func(req playwright.Request) {
+		if longLivedResourceTypes[req.ResourceType()] {
+			return
+		}
+		m.mu.Lock()
+		m.inflight[req] = true
+		m.lastActivity = time.Now()
+		m.mu.Unlock()
+	}
+	m.onDone = func(req playwright.Request) {
+		m.mu.Lock()
+		if m.inflight[req] {
+			delete(m.inflight, req)
+			m.lastActivity = time.Now()
+		}
+		m.mu.Unlock()
+	}
+
+	bc.Page.OnRequest(m.onStart)
+	bc.Page.OnRequestFinished(m.onDone)
+	bc.Page.OnRequestFailed(m.onDone)
+	return m
+}
+
+// WaitForIdle waits until no request has been in flight for quiet, reporting false on timeout
+func (m *NetworkMonitor) WaitForIdle(quiet, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for {
+		m.mu.Lock()
+		idle := len(m.inflight) == 0 && time.Since(m.lastActivity) >= quiet
+		m.mu.Unlock()
+		if idle {
+			return true
+		}
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// Stop removes the page listeners
+func (m *NetworkMonitor) Stop() {
+	m.page.RemoveListener("request", m.onStart)
+	m.page.RemoveListener("requestfinished", m.onDone)
+	m.page.RemoveListener("requestfailed", m.onDone)
+}
@@ -32,6 +32,7 @@ type BrowserContext struct {
 	headedBrowser playwright.Browser // For headed sessions
 	isHeaded      bool
 	lastResponse  playwright.Response // Track last navigation response
+	interceptor   *networkInterceptor // Network capture/blocking, set by StartIntercept
 }
 
 func NewBrowserPool(cfg *config.BrowserConfig, profileRepo *storage.BrowserProfileRepository) (*BrowserPool, error) {
@@ -90,7 +91,7 @@ func NewBrowserPool(cfg *config.BrowserConfig, profileRepo *storage.BrowserProfi
 func (p *BrowserPool) createContext() (playwright.BrowserContext, error) {
 	options := playwright.BrowserNewContextOptions{
 		UserAgent:         playwright.String("Crawlify/1.0"),
-		AcceptDownloads:   playwright.Bool(false),
+		AcceptDownloads:   playwright.Bool(true),
 		IgnoreHttpsErrors: playwright.Bool(true),
 		JavaScriptEnabled: playwright.Bool(true),
 		Viewport: &playwright.Size{
@@ -390,6 +391,19 @@ func (bc *BrowserContext) Content() (string, error) {
 	return bc.Page.Content()
 }
 
+// CanonicalURL returns the absolute href of the page's <link rel=canonical>, or "" if absent
+func (bc *BrowserContext) CanonicalURL() (string, error) {
+	result, err := bc.Page.Evaluate(`() => {
+		const link = document.querySelector('link[rel="canonical"]');
+		return link && link.href ? link.href : '';
+	}`)
+	if err != nil {
+		return "", fmt.Errorf("failed to read canonical link: %w", err)
+	}
+	href, _ := result.(string)
+	return href, nil
+}
+
 // Screenshot takes a screenshot of the page
 func (bc *BrowserContext) Screenshot(options playwright.PageScreenshotOptions) ([]byte, error) {
 	return bc.Page.Screenshot(options)
@@ -13,6 +13,9 @@ type Config struct {
 	Browser  BrowserConfig  `mapstructure:"browser"`
 	Crawler  CrawlerConfig  `mapstructure:"crawler"`
 	AI       AIConfig       `mapstructure:"ai"`
+	Assets   AssetsConfig   `mapstructure:"assets"`
+	Builds   BuildConfig    `mapstructure:"builds"`
+	Plugins  PluginsConfig  `mapstructure:"plugins"`
 }
 
 type ServerConfig struct {
@@ -69,6 +72,48 @@ type CrawlerConfig struct {
 	QueueCheckInterval int    `mapstructure:"queue_check_interval"`
 }
 
+// AssetsConfig configures where downloaded images and files are stored and what may be downloaded
+type AssetsConfig struct {
+	Storage      string   `mapstructure:"storage"`       // "local" or "s3"
+	LocalPath    string   `mapstructure:"local_path"`    // Root directory for local storage
+	PublicURL    string   `mapstructure:"public_url"`    // Optional base URL the stored files are served from
+	MaxBytes     int64    `mapstructure:"max_bytes"`     // Largest file that will be downloaded
+	AllowedTypes []string `mapstructure:"allowed_types"` // MIME types, e.g. "image/*", "application/pdf"
+	Timeout      int      `mapstructure:"timeout"`       // Per-file download timeout (ms)
+	S3           S3Config `mapstructure:"s3"`
+}
+
+// S3Config configures an S3-compatible blob store (AWS S3, MinIO, R2, ...)
+type S3Config struct {
+	Endpoint  string `mapstructure:"endpoint"`
+	Region    string `mapstructure:"region"`
+	Bucket    string `mapstructure:"bucket"`
+	AccessKey string `mapstructure:"access_key"`
+	SecretKey string `mapstructure:"secret_key"`
+	Prefix    string `mapstructure:"prefix"`
+	UseSSL    bool   `mapstructure:"use_ssl"`
+}
+
+// BuildConfig configures the plugin build service
+type BuildConfig struct {
+	Concurrency int    `mapstructure:"concurrency"` // Builds that run at the same time; more wait in the queue
+	QueueSize   int    `mapstructure:"queue_size"`  // Builds that may wait; further requests are rejected
+	Timeout     int    `mapstructure:"timeout"`     // Per-build timeout (s), tests included
+	ModCache    string `mapstructure:"mod_cache"`   // GOMODCACHE shared by all builds
+	WorkDir     string `mapstructure:"work_dir"`    // Where builds copy plugin sources; empty for the system temp dir
+}
+
+// PluginsConfig configures sharing plugins between instances as signed bundles
+type PluginsConfig struct {
+	SigningKey    string   `mapstructure:"signing_key"`     // Base64 ed25519 private key exported bundles are signed with; empty to export unsigned
+	TrustedKeys   []string `mapstructure:"trusted_keys"`    // Base64 ed25519 public keys whose signatures make plugins verified
+	AllowUnsigned bool     `mapstructure:"allow_unsigned"`  // Import unsigned bundles, or bundles signed by an unknown key, as unverified
+	RegistryURL   string   `mapstructure:"registry_url"`    // index.json of the registry plugins are imported from
+	Registries    []string `mapstructure:"registries"`      // Other index.json URLs a request may name; no others are fetched
+	RegistryDir   string   `mapstructure:"registry_dir"`    // Where this instance publishes bundles; served at /registry
+	MaxBundleSize int64    `mapstructure:"max_bundle_size"` // Largest bundle that is imported (bytes, uncompressed)
+}
+
 func (c *DatabaseConfig) DSN() string {
 	return fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		c.Host, c.Port, c.User, c.Password, c.Database, c.SSLMode)
@@ -142,18 +187,118 @@ func setDefaults() {
 	viper.SetDefault("crawler.concurrent_workers", 5)
 	viper.SetDefault("crawler.queue_check_interval", 1000)
 
+	// Asset download defaults
+	viper.SetDefault("assets.storage", "local")
+	viper.SetDefault("assets.local_path", "./data/assets")
+	viper.SetDefault("assets.max_bytes", 20*1024*1024)
+	viper.SetDefault("assets.allowed_types", []string{"image/*", "application/pdf"})
+	viper.SetDefault("assets.timeout", 30000)
+	viper.SetDefault("assets.s3.use_ssl", true)
+
+	// Plugin build defaults
+	viper.SetDefault("builds.concurrency", 2)
+	viper.SetDefault("builds.queue_size", 50)
+	viper.SetDefault("builds.timeout", 900)
+	viper.SetDefault("builds.mod_cache", "./data/gomodcache")
+	viper.SetDefault("builds.work_dir", "")
+
+	// Plugin bundle defaults
+	viper.SetDefault("plugins.allow_unsigned", false)
+	viper.SetDefault("plugins.registry_dir", "./data/registry")
+	viper.SetDefault("plugins.max_bundle_size", 200*1024*1024)
+
 	// AI defaults
 	viper.SetDefault("ai.enabled", true)
 	viper.SetDefault("ai.gemini_model", "gemini-2.5-flash")
 	viper.SetDefault("ai.openrouter_model", "google/gemini-2.0-flash-exp:free")
 	viper.SetDefault("ai.provider", "gemini")
+	viper.SetDefault("ai.features.autofix", "")
+	viper.SetDefault("ai.features.recovery", "")
+	viper.SetDefault("ai.features.generation", "")
+	viper.SetDefault("ai.openai.base_url", "http://localhost:8000/v1")
+	viper.SetDefault("ai.openai.max_tokens", 4096)
+	viper.SetDefault("ai.openai.timeout", 300)
+	viper.SetDefault("ai.openai.max_retries", 2)
+	viper.SetDefault("ai.ollama.base_url", "http://localhost:11434")
+	viper.SetDefault("ai.ollama.model", "llama3.1")
+	viper.SetDefault("ai.ollama.num_ctx", 32768)
+	viper.SetDefault("ai.ollama.keep_alive", "10m")
+	viper.SetDefault("ai.ollama.timeout", 300)
+	viper.SetDefault("ai.ollama.max_retries", 2)
+	viper.SetDefault("ai.usage.daily_budget_usd", 0)
+	viper.SetDefault("ai.usage.daily_call_limit", 0)
+	viper.SetDefault("ai.usage.workflow_daily_budget_usd", 0)
+	viper.SetDefault("ai.usage.workflow_daily_call_limit", 0)
+	viper.SetDefault("ai.usage.cache_ttl", 86400)
+	viper.SetDefault("ai.autofix.auto_apply_confidence", 0)
 }
 
 // AIConfig holds AI service configuration
 type AIConfig struct {
-	Enabled         bool   `mapstructure:"enabled"`
-	GeminiAPIKey    string `mapstructure:"gemini_api_key"`
-	GeminiModel     string `mapstructure:"gemini_model"`
-	OpenRouterModel string `mapstructure:"openrouter_model"`
-	Provider        string `mapstructure:"provider"` // "gemini" or "openrouter"
+	Enabled         bool                   `mapstructure:"enabled"`
+	GeminiAPIKey    string                 `mapstructure:"gemini_api_key"`
+	GeminiModel     string                 `mapstructure:"gemini_model"`
+	OpenRouterModel string                 `mapstructure:"openrouter_model"`
+	Provider        string                 `mapstructure:"provider"` // "gemini", "openrouter", "openai" or "ollama"
+	Features        AIFeatureProviders     `mapstructure:"features"`
+	OpenAI          OpenAICompatibleConfig `mapstructure:"openai"`
+	Ollama          OllamaConfig           `mapstructure:"ollama"`
+	Usage           AIUsageConfig          `mapstructure:"usage"`
+	AutoFix         AutoFixConfig          `mapstructure:"autofix"`
+	Pricing         []AIModelPrice         `mapstructure:"pricing"` // Added to, and overriding, the built-in prices
+}
+
+// AIUsageConfig holds the daily AI budgets and the response cache settings
+// Budgets are per UTC day; 0 is unlimited. When one is spent, AI callers work rules-only.
+type AIUsageConfig struct {
+	DailyBudgetUSD         float64 `mapstructure:"daily_budget_usd"`
+	DailyCallLimit         int     `mapstructure:"daily_call_limit"`
+	WorkflowDailyBudgetUSD float64 `mapstructure:"workflow_daily_budget_usd"` // Applies to each workflow
+	WorkflowDailyCallLimit int     `mapstructure:"workflow_daily_call_limit"`
+	CacheTTL               int     `mapstructure:"cache_ttl"` // Seconds an answer is reused; 0 disables the cache
+}
+
+// AutoFixConfig controls how auto-fix suggestions reach workflows
+type AutoFixConfig struct {
+	// Suggestions at or above this confidence are applied without review when their node
+	// replay verification passes; 0 keeps every suggestion for review
+	AutoApplyConfidence float64 `mapstructure:"auto_apply_confidence"`
+}
+
+// AIModelPrice is the price of a model in USD per million tokens
+// Model matches a model name exactly or as its longest prefix.
+type AIModelPrice struct {
+	Model            string  `mapstructure:"model"`
+	InputPerMillion  float64 `mapstructure:"input_per_million"`
+	OutputPerMillion float64 `mapstructure:"output_per_million"`
+}
+
+// AIFeatureProviders picks the provider of each AI feature; "" uses ai.provider
+type AIFeatureProviders struct {
+	AutoFix    string `mapstructure:"autofix"`
+	Recovery   string `mapstructure:"recovery"`
+	Generation string `mapstructure:"generation"`
+}
+
+// OpenAICompatibleConfig configures a chat-completions server: OpenAI itself, or a self-hosted
+// one such as vLLM, llama.cpp server, LM Studio or LocalAI
+type OpenAICompatibleConfig struct {
+	BaseURL     string `mapstructure:"base_url"` // Up to and including /v1
+	APIKey      string `mapstructure:"api_key"`  // Sent as a bearer token when set
+	Model       string `mapstructure:"model"`
+	VisionModel string `mapstructure:"vision_model"` // Used for prompts with an image; "" uses model
+	MaxTokens   int    `mapstructure:"max_tokens"`
+	Timeout     int    `mapstructure:"timeout"` // Seconds per request
+	MaxRetries  int    `mapstructure:"max_retries"`
+}
+
+// OllamaConfig configures an Ollama server
+type OllamaConfig struct {
+	BaseURL     string `mapstructure:"base_url"`
+	Model       string `mapstructure:"model"`
+	VisionModel string `mapstructure:"vision_model"` // Used for prompts with an image; "" uses model
+	NumCtx      int    `mapstructure:"num_ctx"`      // Context window; Ollama's default truncates page-sized prompts
+	KeepAlive   string `mapstructure:"keep_alive"`   // How long the model stays loaded, e.g. "5m"
+	Timeout     int    `mapstructure:"timeout"`      // Seconds per request
+	MaxRetries  int    `mapstructure:"max_retries"`
 }
@@ -3,8 +3,13 @@ package error_recovery
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/uzzalhcse/crawlify/internal/ai/aiusage"
 	"github.com/uzzalhcse/crawlify/internal/logger"
 	"go.uber.org/zap"
 )
@@ -15,11 +20,19 @@ type AIClient interface {
 	Close() error
 }
 
+// budgetPause is how long the engine stays rules-only after the AI budget is found spent
+const budgetPause = time.Minute
+
 // AIReasoningEngine uses AI to solve complex problems when rules fail
 type AIReasoningEngine struct {
 	client         AIClient
 	learningEngine *LearningEngine
 	enabled        bool
+
+	// Rules-only until then because the AI budget is spent, by workflow ID; "" is the global
+	// budget, which pauses every workflow
+	mu          sync.Mutex
+	pausedUntil map[string]time.Time
 }
 
 // NewAIReasoningEngine creates a new AI reasoning engine
@@ -28,6 +41,7 @@ func NewAIReasoningEngine(client AIClient, learningEngine *LearningEngine, enabl
 		client:         client,
 		learningEngine: learningEngine,
 		enabled:        enabled,
+		pausedUntil:    make(map[string]time.Time),
 	}
 }
 
@@ -36,6 +50,9 @@ func (a *AIReasoningEngine) ReasonAndSolve(ctx context.Context, execCtx *Executi
 	if !a.enabled || a.client == nil {
 		return nil, fmt.Errorf("AI reasoning is disabled or no client available")
 	}
+	if a.paused(execCtx.WorkflowID) {
+		return nil, fmt.Errorf("AI reasoning paused, continuing rules-only: %w", aiusage.ErrBudgetExceeded)
+	}
 
 	logger.Warn("🤖 Activating AI Reasoning (expensive)",
 		zap.String("url", execCtx.URL),
@@ -44,8 +61,26 @@ func (a *AIReasoningEngine) ReasonAndSolve(ctx context.Context, execCtx *Executi
 	// Build reasoning prompt
 	prompt := a.buildReasoningPrompt(execCtx, err)
 
+	// Failures of the same kind on one domain share an answer, so a storm of them costs one call
+	ctx = aiusage.WithScope(ctx, aiusage.Scope{
+		Feature:     "recovery",
+		WorkflowID:  execCtx.WorkflowID,
+		ExecutionID: execCtx.ExecutionID,
+		CacheKey:    a.buildCacheKey(execCtx, err),
+	})
+
 	// Call AI
 	response, aiErr := a.client.GenerateText(ctx, prompt)
+	if errors.Is(aiErr, aiusage.ErrBudgetExceeded) {
+		// A workflow's own budget only pauses that workflow
+		if errors.Is(aiErr, aiusage.ErrWorkflowBudgetExceeded) {
+			a.pause(execCtx.WorkflowID)
+		} else {
+			a.pause("")
+		}
+		logger.Warn("AI budget spent, error recovery continues rules-only", zap.Error(aiErr))
+		return nil, aiErr
+	}
 	if aiErr != nil {
 		logger.Error("AI reasoning failed", zap.Error(aiErr))
 		return nil, fmt.Errorf("AI reasoning failed: %w", aiErr)
@@ -71,6 +106,39 @@ func (a *AIReasoningEngine) ReasonAndSolve(ctx context.Context, execCtx *Executi
 	return solution, nil
 }
 
+// paused reports whether the engine is rules-only for the workflow because the global budget
+// or the workflow's is spent
+func (a *AIReasoningEngine) paused(workflowID string) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	return now.Before(a.pausedUntil[""]) || workflowID != "" && now.Before(a.pausedUntil[workflowID])
+}
+
+// pause makes the engine rules-only for budgetPause, for one workflow or, with "", for all
+func (a *AIReasoningEngine) pause(workflowID string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	for id, until := range a.pausedUntil {
+		if now.After(until) {
+			delete(a.pausedUntil, id)
+		}
+	}
+	a.pausedUntil[workflowID] = now.Add(budgetPause)
+}
+
+// buildCacheKey identifies a failure by everything in the prompt except the URL
+func (a *AIReasoningEngine) buildCacheKey(ctx *ExecutionContext, err error) string {
+	return fmt.Sprintf("recovery\n%s\n%s\n%d\n%s\n%v",
+		ctx.Domain,
+		strings.ReplaceAll(err.Error(), ctx.URL, "<url>"),
+		ctx.Response.StatusCode,
+		truncate(ctx.Response.Body, 500),
+		ctx.FailedRules,
+	)
+}
+
 // buildReasoningPrompt builds the prompt for AI reasoning
 func (a *AIReasoningEngine) buildReasoningPrompt(ctx *ExecutionContext, err error) string {
 	return fmt.Sprintf(`You are a web scraping expert analyzing a failure.
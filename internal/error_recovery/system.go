@@ -2,8 +2,10 @@ package error_recovery
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/uzzalhcse/crawlify/internal/ai/aiusage"
 	"github.com/uzzalhcse/crawlify/internal/logger"
 	"go.uber.org/zap"
 )
@@ -97,7 +99,11 @@ func (s *ErrorRecoverySystem) HandleError(ctx context.Context, err error, execCt
 				zap.String("solution_type", "ai"))
 			return aiSolution, nil
 		}
-		logger.Warn("❌ AI reasoning failed", zap.Error(aiErr))
+		if errors.Is(aiErr, aiusage.ErrBudgetExceeded) {
+			logger.Debug("⏭️  AI budget spent - rules-only", zap.Error(aiErr))
+		} else {
+			logger.Warn("❌ AI reasoning failed", zap.Error(aiErr))
+		}
 	} else {
 		logger.Debug("⚠️  AI reasoning disabled, no fallback available")
 	}
@@ -0,0 +1,136 @@
+package expression
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+
+	"github.com/uzzalhcse/crawlify/pkg/models"
+)
+
+// Env holds the global names visible to an expression
+type Env map[string]interface{}
+
+// NewEnv builds the standard environment:
+//   - item: the current item, whose fields are also exposed as top-level names (price, discount)
+//   - vars: ExecutionContext data and string variables
+//   - url: metadata of the URL being processed (url, host, path, query, depth, marker, phase_id, metadata)
+//
+// Values are copied so expressions cannot modify the crawler's state
+func NewEnv(item map[string]interface{}, execCtx *models.ExecutionContext, urlItem *models.URLQueueItem) Env {
+	env := Env{}
+	for name, value := range item {
+		if isIdentifier(name) {
+			env[name] = copyValue(value)
+		}
+	}
+
+	vars := map[string]interface{}{}
+	if execCtx != nil {
+		for key, value := range execCtx.Variables {
+			vars[key] = value
+		}
+		for key, value := range execCtx.Data {
+			if !strings.HasPrefix(key, "__") {
+				vars[key] = copyValue(value)
+			}
+		}
+	}
+
+	if item == nil {
+		item = map[string]interface{}{}
+	}
+	env["item"] = copyValue(item)
+	env["vars"] = vars
+	env["url"] = URLInfo(urlItem)
+	return env
+}
+
+// With returns a copy of env with an extra binding
+func (env Env) With(name string, value interface{}) Env {
+	result := make(Env, len(env)+1)
+	for k, v := range env {
+		result[k] = v
+	}
+	result[name] = copyValue(value)
+	return result
+}
+
+// URLInfo describes a queue item for expressions
+func URLInfo(urlItem *models.URLQueueItem) map[string]interface{} {
+	info := map[string]interface{}{}
+	if urlItem == nil {
+		return info
+	}
+	info["url"] = urlItem.URL
+	info["depth"] = urlItem.Depth
+	info["marker"] = urlItem.Marker
+	info["phase_id"] = urlItem.PhaseID
+	info["retry_count"] = urlItem.RetryCount
+	if urlItem.Metadata != "" {
+		var metadata map[string]interface{}
+		if err := json.Unmarshal([]byte(urlItem.Metadata), &metadata); err == nil {
+			info["metadata"] = metadata
+		}
+	}
+	if u, err := url.Parse(urlItem.URL); err == nil {
+		info["host"] = u.Hostname()
+		info["path"] = u.Path
+		query := map[string]interface{}{}
+		for key, values := range u.Query() {
+			if len(values) > 0 {
+				query[key] = values[0]
+			}
+		}
+		info["query"] = query
+	}
+	return info
+}
+
+// copyValue deep-copies maps and slices of decoded JSON values
+func copyValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = copyValue(item)
+		}
+		return m
+	case []interface{}:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = copyValue(item)
+		}
+		return list
+	case []string:
+		list := make([]interface{}, len(v))
+		for i, item := range v {
+			list[i] = item
+		}
+		return list
+	}
+	return value
+}
+
+// reservedNames are bindings item fields must not shadow
+var reservedNames = map[string]bool{
+	"item": true, "vars": true, "url": true, "value": true,
+	"num": true, "empty": true, "coalesce": true,
+}
+
+// isIdentifier reports whether name can be used as a top-level JavaScript name
+func isIdentifier(name string) bool {
+	if name == "" || reservedNames[name] {
+		return false
+	}
+	for i, r := range name {
+		switch {
+		case r == '_' || r == '$':
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,303 @@
+package expression
+
+import (
+	"container/list"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dop251/goja"
+	"github.com/uzzalhcse/crawlify/internal/schema"
+)
+
+// DefaultTimeout bounds the wall-clock time of a single evaluation
+const DefaultTimeout = 50 * time.Millisecond
+
+// maxCallStackSize bounds recursion inside expressions
+const maxCallStackSize = 256
+
+// maxAllocLength bounds the strings and arrays built-in functions may produce or walk in one call
+// The time limit alone does not stop a single native call such as "x".repeat(1e9) from allocating;
+// repeated concatenation runs in the interpreter and is left to the time limit.
+const maxAllocLength = 1 << 20
+
+// ErrTimeout is returned when an expression exceeds its time limit
+var ErrTimeout = errors.New("expression timed out")
+
+// sandboxPrelude caps the built-ins that turn a small input into a large allocation and removes
+// the binary-data globals expressions have no use for; violations throw a RangeError
+var sandboxPrelude = goja.MustCompile("sandbox", fmt.Sprintf(`(function(max) {
+	var apply = Reflect.apply;
+	function check(n) {
+		if (Number(n) > max) {
+			throw new RangeError("allocation of " + n + " exceeds the limit of " + max);
+		}
+	}
+	function guard(target, name, size) {
+		var original = target[name];
+		if (typeof original !== "function") {
+			return;
+		}
+		Object.defineProperty(target, name, {
+			value: function() {
+				check(size(this, arguments));
+				return apply(original, this, arguments);
+			},
+			writable: true,
+			configurable: true
+		});
+	}
+	function thisLength(self) {
+		return self == null ? 0 : self.length;
+	}
+
+	guard(String.prototype, "repeat", function(self, args) { return String(self).length * args[0]; });
+	guard(String.prototype, "padStart", function(self, args) { return args[0]; });
+	guard(String.prototype, "padEnd", function(self, args) { return args[0]; });
+
+	["copyWithin", "every", "fill", "filter", "find", "findIndex", "findLast", "findLastIndex", "flat",
+	 "flatMap", "forEach", "includes", "indexOf", "join", "lastIndexOf", "map", "reduce", "reduceRight",
+	 "reverse", "slice", "some", "sort", "splice", "keys", "values", "entries", Symbol.iterator].forEach(function(name) {
+		guard(Array.prototype, name, thisLength);
+	});
+	guard(Array.prototype, "concat", function(self, args) {
+		var n = thisLength(self);
+		for (var i = 0; i < args.length; i++) {
+			n += Array.isArray(args[i]) ? args[i].length : 1;
+		}
+		return n;
+	});
+	guard(Array, "from", function(self, args) { return args[0] == null ? 0 : args[0].length; });
+	guard(Function.prototype, "apply", function(self, args) { return args[1] == null ? 0 : args[1].length; });
+	guard(Reflect, "apply", function(self, args) { return args[2] == null ? 0 : args[2].length; });
+
+	var stringify = JSON.stringify;
+	JSON.stringify = function(value, replacer, space) {
+		var allowed = Array.isArray(replacer) ? replacer.map(String) : null;
+		return stringify(value, function(key, v) {
+			if (allowed && key !== "" && !Array.isArray(this) && allowed.indexOf(key) < 0) {
+				return undefined;
+			}
+			if (Array.isArray(v)) {
+				check(v.length);
+			}
+			return typeof replacer === "function" ? apply(replacer, this, [key, v]) : v;
+		}, space);
+	};
+
+	["ArrayBuffer", "SharedArrayBuffer", "DataView", "Int8Array", "Uint8Array", "Uint8ClampedArray",
+	 "Int16Array", "Uint16Array", "Int32Array", "Uint32Array", "Float32Array", "Float64Array",
+	 "BigInt64Array", "BigUint64Array"].forEach(function(name) {
+		delete globalThis[name];
+	});
+})(%d)`, maxAllocLength), true)
+
+// Program is a compiled expression
+type Program struct {
+	source  string
+	program *goja.Program
+}
+
+// maxCachedPrograms bounds the compiled programs kept; the least recently used are evicted
+const maxCachedPrograms = 1024
+
+// programCache caches compiled programs by source
+var programCache = newProgramLRU(maxCachedPrograms)
+
+// programLRU is a fixed-size cache of compiled programs
+type programLRU struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List               // Most recently used first
+	entries map[string]*list.Element // source -> element holding *Program
+}
+
+func newProgramLRU(size int) *programLRU {
+	return &programLRU{size: size, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+// get returns the cached program for source and marks it recently used
+func (c *programLRU) get(source string) (*Program, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[source]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return element.Value.(*Program), true
+}
+
+// add caches a program, evicting the least recently used beyond the size
+func (c *programLRU) add(p *Program) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if element, ok := c.entries[p.source]; ok {
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[p.source] = c.order.PushFront(p)
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*Program).source)
+	}
+}
+
+// Compile compiles a JavaScript expression, e.g. `price * (1 - discount / 100)`
+// Sources containing a return statement are compiled as a function body
+func Compile(source string) (*Program, error) {
+	if cached, ok := programCache.get(source); ok {
+		return cached, nil
+	}
+	if strings.TrimSpace(source) == "" {
+		return nil, fmt.Errorf("expression is empty")
+	}
+
+	program, err := goja.Compile("expression", "(\n"+source+"\n)", true)
+	if err != nil && strings.Contains(source, "return") {
+		program, err = goja.Compile("expression", "(function() {\n"+source+"\n})()", true)
+	}
+	if err != nil {
+		// Report the error against the source as written rather than the wrapper
+		if _, rawErr := goja.Compile("expression", source, true); rawErr != nil {
+			err = rawErr
+		} else {
+			err = errors.New("multiple statements need an explicit return")
+		}
+		return nil, fmt.Errorf("invalid expression '%s': %w", source, err)
+	}
+
+	p := &Program{source: source, program: program}
+	programCache.add(p)
+	return p, nil
+}
+
+// Validate checks that an expression compiles
+func Validate(source string) error {
+	_, err := Compile(source)
+	return err
+}
+
+// Eval compiles and evaluates an expression against env
+func Eval(source string, env Env) (interface{}, error) {
+	p, err := Compile(source)
+	if err != nil {
+		return nil, err
+	}
+	return p.Eval(env)
+}
+
+// Eval evaluates the program in a fresh sandbox with the default time limit
+func (p *Program) Eval(env Env) (interface{}, error) {
+	value, err := p.run(env, DefaultTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return export(value), nil
+}
+
+// EvalBool evaluates the program and converts the result using JavaScript truthiness
+func (p *Program) EvalBool(env Env) (bool, error) {
+	value, err := p.run(env, DefaultTimeout)
+	if err != nil {
+		return false, err
+	}
+	return value.ToBoolean(), nil
+}
+
+// run executes the program in a new runtime that only sees env and the helper functions
+// Runtimes have no I/O: there is no require, network, filesystem or timer access, and built-ins
+// that allocate are capped at maxAllocLength
+func (p *Program) run(env Env, timeout time.Duration) (goja.Value, error) {
+	vm := goja.New()
+	vm.SetMaxCallStackSize(maxCallStackSize)
+	vm.SetFieldNameMapper(goja.TagFieldNameMapper("json", true))
+
+	for name, value := range env {
+		if err := vm.Set(name, value); err != nil {
+			return nil, fmt.Errorf("failed to bind '%s': %w", name, err)
+		}
+	}
+	registerHelpers(vm)
+	if _, err := vm.RunProgram(sandboxPrelude); err != nil {
+		return nil, fmt.Errorf("failed to prepare the sandbox: %w", err)
+	}
+
+	timer := time.AfterFunc(timeout, func() {
+		vm.Interrupt(ErrTimeout)
+	})
+	defer timer.Stop()
+
+	value, err := vm.RunProgram(p.program)
+	if err != nil {
+		var interrupted *goja.InterruptedError
+		if errors.As(err, &interrupted) {
+			return nil, fmt.Errorf("expression '%s': %w", p.source, ErrTimeout)
+		}
+		var exception *goja.Exception
+		if errors.As(err, &exception) {
+			return nil, fmt.Errorf("expression '%s' failed: %s", p.source, exception.Value().String())
+		}
+		return nil, fmt.Errorf("expression '%s' failed: %w", p.source, err)
+	}
+	return value, nil
+}
+
+// registerHelpers adds helper functions available to every expression
+func registerHelpers(vm *goja.Runtime) {
+	// num("1.234,56 €") parses locale-formatted numbers; null when there is none
+	vm.Set("num", func(value goja.Value) interface{} {
+		if goja.IsUndefined(value) || goja.IsNull(value) {
+			return nil
+		}
+		switch v := value.Export().(type) {
+		case int64:
+			return v
+		case float64:
+			return v
+		}
+		f, err := schema.ParseNumber(value.String())
+		if err != nil {
+			return nil
+		}
+		return f
+	})
+	// empty(x) is true for null, undefined, blank strings and empty arrays
+	vm.Set("empty", func(value goja.Value) bool {
+		return isEmpty(value)
+	})
+	// coalesce(a, b, ...) returns the first non-empty argument
+	vm.Set("coalesce", func(call goja.FunctionCall) goja.Value {
+		for _, arg := range call.Arguments {
+			if !isEmpty(arg) {
+				return arg
+			}
+		}
+		return goja.Null()
+	})
+}
+
+// isEmpty reports JavaScript values treated as missing
+func isEmpty(value goja.Value) bool {
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return true
+	}
+	switch v := value.Export().(type) {
+	case string:
+		return strings.TrimSpace(v) == ""
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// export converts a JavaScript result to plain Go values; NaN and undefined become nil
+func export(value goja.Value) interface{} {
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) || goja.IsNaN(value) {
+		return nil
+	}
+	return value.Export()
+}
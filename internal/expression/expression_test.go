@@ -0,0 +1,23 @@
+package expression
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestProgramCacheIsBounded(t *testing.T) {
+	for i := 0; i < maxCachedPrograms+100; i++ {
+		if _, err := Compile(fmt.Sprintf("%d + 1", i)); err != nil {
+			t.Fatalf("Compile failed: %v", err)
+		}
+	}
+	if n := programCache.order.Len(); n != maxCachedPrograms {
+		t.Errorf("cache holds %d programs, want %d", n, maxCachedPrograms)
+	}
+	if _, ok := programCache.get("0 + 1"); ok {
+		t.Error("least recently used program was not evicted")
+	}
+	if _, ok := programCache.get(fmt.Sprintf("%d + 1", maxCachedPrograms+99)); !ok {
+		t.Error("most recent program was evicted")
+	}
+}
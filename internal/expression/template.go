@@ -0,0 +1,185 @@
+package expression
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// templatePattern matches {{ expression }} placeholders
+var templatePattern = regexp.MustCompile(`\{\{\s*(.+?)\s*\}\}`)
+
+// HasTemplate reports whether s contains a {{ }} placeholder
+func HasTemplate(s string) bool {
+	return strings.Contains(s, "{{") && templatePattern.MatchString(s)
+}
+
+// ExpandTemplate evaluates {{ expression }} placeholders in s
+// A string that is exactly one placeholder yields the raw value; otherwise results are interpolated
+func ExpandTemplate(s string, env Env) (interface{}, error) {
+	if !HasTemplate(s) {
+		return s, nil
+	}
+
+	if loc := templatePattern.FindStringSubmatchIndex(s); loc[0] == 0 && loc[1] == len(s) {
+		return Eval(s[loc[2]:loc[3]], env)
+	}
+
+	var evalErr error
+	result := templatePattern.ReplaceAllStringFunc(s, func(placeholder string) string {
+		source := templatePattern.FindStringSubmatch(placeholder)[1]
+		value, err := Eval(source, env)
+		if err != nil {
+			if evalErr == nil {
+				evalErr = err
+			}
+			return placeholder
+		}
+		if value == nil {
+			return ""
+		}
+		return fmt.Sprint(value)
+	})
+	if evalErr != nil {
+		return nil, evalErr
+	}
+	return result, nil
+}
+
+// ValidateTemplate checks that every placeholder in s compiles
+func ValidateTemplate(s string) error {
+	for _, match := range templatePattern.FindAllStringSubmatch(s, -1) {
+		if err := Validate(match[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// legacyPlaceholders are the exact-match placeholders every string param may be, e.g. "{{current_url}}"
+var legacyPlaceholders = map[string]string{
+	"{{current_url}}": "current_url",
+	"{{start_url}}":   "start_url",
+}
+
+// urlParams are params holding URLs; like expression params they may contain {{ }} templates
+var urlParams = map[string]bool{
+	"url":          true,
+	"urls":         true,
+	"url_template": true,
+}
+
+// isTemplateKey reports whether the strings under a param are expanded as templates
+func isTemplateKey(key string, expressionKeys map[string]bool) bool {
+	return expressionKeys[key] || urlParams[key] || strings.HasSuffix(key, "_url") || strings.HasSuffix(key, "_urls")
+}
+
+// keySet builds a lookup set of param names
+func keySet(keys []string) map[string]bool {
+	set := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		set[key] = true
+	}
+	return set
+}
+
+// ExpandParams returns a copy of params with {{ }} templates expanded against env
+// Templates are only expanded in the params named in expressionKeys and in URL params ("url",
+// "urls", "url_template", "*_url", "*_urls"), at any depth. Other strings are kept as written,
+// except the exact placeholders "{{current_url}}" and "{{start_url}}".
+func ExpandParams(params map[string]interface{}, env Env, expressionKeys ...string) (map[string]interface{}, error) {
+	if params == nil {
+		return nil, nil
+	}
+	expanded, err := expandValue(params, "", false, env, keySet(expressionKeys))
+	if err != nil {
+		return nil, err
+	}
+	return expanded.(map[string]interface{}), nil
+}
+
+// expandValue copies value, expanding templates in strings under a template key
+func expandValue(value interface{}, path string, template bool, env Env, expressionKeys map[string]bool) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if template {
+			result, err := ExpandTemplate(v, env)
+			if err != nil {
+				return nil, fmt.Errorf("param '%s': %w", strings.TrimPrefix(path, "."), err)
+			}
+			return result, nil
+		}
+		if name, ok := legacyPlaceholders[v]; ok {
+			if s, _ := env[name].(string); s != "" {
+				return s, nil
+			}
+		}
+		return v, nil
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			expanded, err := expandValue(item, path+"."+key, template || isTemplateKey(key, expressionKeys), env, expressionKeys)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = expanded
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			expanded, err := expandValue(item, fmt.Sprintf("%s[%d]", path, i), template, env, expressionKeys)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = expanded
+		}
+		return result, nil
+	}
+	return value, nil
+}
+
+// ValidateParams compiles the params named in expressionKeys as expressions and the {{ }}
+// templates ExpandParams would expand, so syntax errors are reported when a workflow is saved
+// rather than while it runs. Params that are neither are never compiled.
+func ValidateParams(params map[string]interface{}, expressionKeys ...string) error {
+	return validateValue(params, "", false, keySet(expressionKeys))
+}
+
+// validateValue walks nested params, reporting the path of the first invalid expression
+func validateValue(value interface{}, path string, template bool, expressionKeys map[string]bool) error {
+	switch v := value.(type) {
+	case string:
+		if !template {
+			return nil
+		}
+		if err := ValidateTemplate(v); err != nil {
+			return fmt.Errorf("%s: %w", strings.TrimPrefix(path, "."), err)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if source, ok := v[key].(string); ok && expressionKeys[key] && !HasTemplate(source) {
+				if err := Validate(source); err != nil {
+					return fmt.Errorf("%s: %w", strings.TrimPrefix(path+"."+key, "."), err)
+				}
+				continue
+			}
+			if err := validateValue(v[key], path+"."+key, template || isTemplateKey(key, expressionKeys), expressionKeys); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			if err := validateValue(item, fmt.Sprintf("%s[%d]", path, i), template, expressionKeys); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package expression
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandParams(t *testing.T) {
+	env := Env{"current_url": "https://example.com/a", "start_url": "https://example.com/"}
+	params := map[string]interface{}{
+		"url":    "{{ start_url + 'page/' + (1 + 1) }}",
+		"script": "const t = `{{ not a template }}`; return t",
+		"text":   "{{ price }}",
+		"target": "{{current_url}}",
+		"steps": []interface{}{
+			map[string]interface{}{"next_url": "{{start_url}}", "selector": "a[title='{{x}}']"},
+		},
+	}
+
+	got, err := ExpandParams(params, env)
+	if err != nil {
+		t.Fatalf("ExpandParams failed: %v", err)
+	}
+	want := map[string]interface{}{
+		"url":    "https://example.com/page/2",
+		"script": "const t = `{{ not a template }}`; return t",
+		"text":   "{{ price }}",
+		"target": "https://example.com/a",
+		"steps": []interface{}{
+			map[string]interface{}{"next_url": "https://example.com/", "selector": "a[title='{{x}}']"},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpandParams = %#v, want %#v", got, want)
+	}
+}
+
+func TestValidateParamsIgnoresBracesOutsideTemplateParams(t *testing.T) {
+	params := map[string]interface{}{
+		"script":   "return '{{ ;; }}'",
+		"selector": "div:has-text('{{')",
+	}
+	if err := ValidateParams(params); err != nil {
+		t.Errorf("ValidateParams rejected non-template braces: %v", err)
+	}
+	if err := ValidateParams(map[string]interface{}{"url": "{{ ;; }}"}); err == nil {
+		t.Error("ValidateParams accepted an invalid URL template")
+	}
+	if err := ValidateParams(map[string]interface{}{"filter": "price >"}, "filter"); err == nil {
+		t.Error("ValidateParams accepted an invalid expression param")
+	}
+}
@@ -3,8 +3,6 @@ package extraction
 import (
 	"encoding/json"
 	"fmt"
-	"regexp"
-	"strconv"
 	"strings"
 
 	"github.com/PuerkitoBio/goquery"
@@ -17,7 +15,8 @@ type ExtractionEngine struct {
 
 type ExtractConfig struct {
 	Selector     string                 `json:"selector" yaml:"selector"`
-	Type         string                 `json:"type" yaml:"type"` // text, attr, html, href, src
+	SelectorType string                 `json:"selector_type,omitempty" yaml:"selector_type,omitempty"` // css (default), xpath, text, role, shadow
+	Type         string                 `json:"type" yaml:"type"`                                       // text, attr, html, href, src
 	Attribute    string                 `json:"attribute,omitempty" yaml:"attribute,omitempty"`
 	Multiple     bool                   `json:"multiple,omitempty" yaml:"multiple,omitempty"`
 	Limit        int                    `json:"limit,omitempty" yaml:"limit,omitempty"`         // Limit for multiple extraction (0 = no limit)
@@ -31,8 +30,9 @@ type ExtractConfig struct {
 type ExtractionPair struct {
 	KeySelector    string      `json:"key_selector" yaml:"key_selector"`
 	ValueSelector  string      `json:"value_selector" yaml:"value_selector"`
-	KeyType        string      `json:"key_type" yaml:"key_type"`     // text, attr, html, href, src
-	ValueType      string      `json:"value_type" yaml:"value_type"` // text, attr, html, href, src
+	SelectorType   string      `json:"selector_type,omitempty" yaml:"selector_type,omitempty"` // Applies to both key and value selectors
+	KeyType        string      `json:"key_type" yaml:"key_type"`                               // text, attr, html, href, src
+	ValueType      string      `json:"value_type" yaml:"value_type"`                           // text, attr, html, href, src
 	KeyAttribute   string      `json:"key_attribute,omitempty" yaml:"key_attribute,omitempty"`
 	ValueAttribute string      `json:"value_attribute,omitempty" yaml:"value_attribute,omitempty"`
 	Transform      interface{} `json:"transform,omitempty" yaml:"transform,omitempty"`
@@ -40,7 +40,7 @@ type ExtractionPair struct {
 }
 
 type TransformConfig struct {
-	Type   string                 `json:"type" yaml:"type"` // trim, lowercase, uppercase, regex, replace, split, join, parse_int, parse_float
+	Type   string                 `json:"type" yaml:"type"` // Name of a registered transform (see ListTransforms)
 	Params map[string]interface{} `json:"params,omitempty" yaml:"params,omitempty"`
 }
 
@@ -65,7 +65,7 @@ func (ee *ExtractionEngine) Extract(config ExtractConfig) (interface{}, error) {
 
 // extractSingle extracts a single value
 func (ee *ExtractionEngine) extractSingle(config ExtractConfig) (interface{}, error) {
-	locator := ee.page.Locator(config.Selector)
+	locator := ee.page.Locator(PlaywrightSelector(config.Selector, config.SelectorType))
 
 	count, err := locator.Count()
 	if err != nil || count == 0 {
@@ -117,14 +117,11 @@ func (ee *ExtractionEngine) extractSingle(config ExtractConfig) (interface{}, er
 
 	// Apply transformations
 	if config.Transform != nil {
-		transforms := ee.parseTransforms(config.Transform)
-		if len(transforms) > 0 {
-			transformed, err := ee.applyTransformations(value, transforms)
-			if err != nil {
-				return config.DefaultValue, err
-			}
-			return transformed, nil
+		transformed, err := ee.transform(value, config.Transform)
+		if err != nil {
+			return config.DefaultValue, err
 		}
+		return transformed, nil
 	}
 
 	return value, nil
@@ -132,7 +129,7 @@ func (ee *ExtractionEngine) extractSingle(config ExtractConfig) (interface{}, er
 
 // extractMultiple extracts multiple values
 func (ee *ExtractionEngine) extractMultiple(config ExtractConfig) (interface{}, error) {
-	locator := ee.page.Locator(config.Selector)
+	locator := ee.page.Locator(PlaywrightSelector(config.Selector, config.SelectorType))
 
 	count, err := locator.Count()
 	if err != nil || count == 0 {
@@ -207,14 +204,9 @@ func (ee *ExtractionEngine) extractMultiple(config ExtractConfig) (interface{},
 
 			// Apply transformations
 			if config.Transform != nil {
-				transforms := ee.parseTransforms(config.Transform)
-				if len(transforms) > 0 {
-					transformed, err := ee.applyTransformations(value, transforms)
-					if err == nil {
-						results = append(results, transformed)
-					}
-				} else {
-					results = append(results, value)
+				transformed, err := ee.transform(value, config.Transform)
+				if err == nil {
+					results = append(results, transformed)
 				}
 			} else {
 				results = append(results, value)
@@ -251,7 +243,7 @@ func (ee *ExtractionEngine) extractFieldValue(locator playwright.Locator, config
 func (ee *ExtractionEngine) extractFieldValueSingle(locator playwright.Locator, config ExtractConfig) (interface{}, error) {
 	var subLocator playwright.Locator
 	if config.Selector != "" {
-		subLocator = locator.Locator(config.Selector)
+		subLocator = locator.Locator(PlaywrightSelector(config.Selector, config.SelectorType))
 	} else {
 		subLocator = locator
 	}
@@ -307,10 +299,7 @@ func (ee *ExtractionEngine) extractFieldValueSingle(locator playwright.Locator,
 
 	// Apply transformations
 	if config.Transform != nil {
-		transforms := ee.parseTransforms(config.Transform)
-		if len(transforms) > 0 {
-			return ee.applyTransformations(value, transforms)
-		}
+		return ee.transform(value, config.Transform)
 	}
 
 	return value, nil
@@ -321,7 +310,7 @@ func (ee *ExtractionEngine) extractFieldValueSingle(locator playwright.Locator,
 func (ee *ExtractionEngine) extractFieldValueSimpleArray(locator playwright.Locator, config ExtractConfig) (interface{}, error) {
 	var subLocator playwright.Locator
 	if config.Selector != "" {
-		subLocator = locator.Locator(config.Selector)
+		subLocator = locator.Locator(PlaywrightSelector(config.Selector, config.SelectorType))
 	} else {
 		return nil, fmt.Errorf("selector required for multiple field extraction")
 	}
@@ -379,13 +368,10 @@ func (ee *ExtractionEngine) extractFieldValueSimpleArray(locator playwright.Loca
 
 		// Apply transformations
 		if config.Transform != nil {
-			transforms := ee.parseTransforms(config.Transform)
-			if len(transforms) > 0 {
-				transformed, err := ee.applyTransformations(value, transforms)
-				if err == nil {
-					results = append(results, transformed)
-					continue
-				}
+			transformed, err := ee.transform(value, config.Transform)
+			if err == nil {
+				results = append(results, transformed)
+				continue
 			}
 		}
 
@@ -400,7 +386,7 @@ func (ee *ExtractionEngine) extractFieldValueSimpleArray(locator playwright.Loca
 func (ee *ExtractionEngine) extractFieldValueNestedArray(locator playwright.Locator, config ExtractConfig) (interface{}, error) {
 	var subLocator playwright.Locator
 	if config.Selector != "" {
-		subLocator = locator.Locator(config.Selector)
+		subLocator = locator.Locator(PlaywrightSelector(config.Selector, config.SelectorType))
 	} else {
 		return nil, fmt.Errorf("selector required for multiple field extraction")
 	}
@@ -467,9 +453,9 @@ func (ee *ExtractionEngine) extractFieldValueIndependentArrays(locator playwrigh
 		// Extract keys - use page directly if no parent locator
 		var keyLocator playwright.Locator
 		if locator != nil {
-			keyLocator = locator.Locator(extraction.KeySelector)
+			keyLocator = locator.Locator(PlaywrightSelector(extraction.KeySelector, extraction.SelectorType))
 		} else {
-			keyLocator = baseLocator.Locator(extraction.KeySelector)
+			keyLocator = baseLocator.Locator(PlaywrightSelector(extraction.KeySelector, extraction.SelectorType))
 		}
 
 		keyCount, err := keyLocator.Count()
@@ -480,9 +466,9 @@ func (ee *ExtractionEngine) extractFieldValueIndependentArrays(locator playwrigh
 		// Extract values
 		var valueLocator playwright.Locator
 		if locator != nil {
-			valueLocator = locator.Locator(extraction.ValueSelector)
+			valueLocator = locator.Locator(PlaywrightSelector(extraction.ValueSelector, extraction.SelectorType))
 		} else {
-			valueLocator = baseLocator.Locator(extraction.ValueSelector)
+			valueLocator = baseLocator.Locator(PlaywrightSelector(extraction.ValueSelector, extraction.SelectorType))
 		}
 
 		valueCount, err := valueLocator.Count()
@@ -568,17 +554,14 @@ func (ee *ExtractionEngine) extractFieldValueIndependentArrays(locator playwrigh
 
 			// Apply transformations if specified
 			if extraction.Transform != nil {
-				transforms := ee.parseTransforms(extraction.Transform)
-				if len(transforms) > 0 {
-					if transformedKey, err := ee.applyTransformations(key, transforms); err == nil {
-						if str, ok := transformedKey.(string); ok {
-							key = str
-						}
+				if transformedKey, err := ee.transform(key, extraction.Transform); err == nil {
+					if str, ok := transformedKey.(string); ok {
+						key = str
 					}
-					if transformedValue, err := ee.applyTransformations(value, transforms); err == nil {
-						if str, ok := transformedValue.(string); ok {
-							value = str
-						}
+				}
+				if transformedValue, err := ee.transform(value, extraction.Transform); err == nil {
+					if str, ok := transformedValue.(string); ok {
+						value = str
 					}
 				}
 			}
@@ -595,134 +578,57 @@ func (ee *ExtractionEngine) extractFieldValueIndependentArrays(locator playwrigh
 	return allResults, nil
 }
 
-// parseTransforms converts various transform formats to []TransformConfig
-func (ee *ExtractionEngine) parseTransforms(transform interface{}) []TransformConfig {
-	switch t := transform.(type) {
-	case string:
-		// Handle single string transform like "trim", "clean_html", "extract_price"
-		return []TransformConfig{{Type: t}}
-	case []interface{}:
-		// Handle array of transform configs
-		var transforms []TransformConfig
-		for _, item := range t {
-			if transformMap, ok := item.(map[string]interface{}); ok {
-				var tc TransformConfig
-				if transformType, ok := transformMap["type"].(string); ok {
-					tc.Type = transformType
-					if params, ok := transformMap["params"].(map[string]interface{}); ok {
-						tc.Params = params
-					}
-					transforms = append(transforms, tc)
-				}
-			}
-		}
-		return transforms
-	case []TransformConfig:
-		// Already in correct format
-		return t
-	default:
-		return []TransformConfig{}
+// transform applies a field's transform chain using the page URL as the base for relative links
+func (ee *ExtractionEngine) transform(value string, transform interface{}) (interface{}, error) {
+	transforms, err := ParseTransforms(transform)
+	if err != nil {
+		return nil, err
 	}
+	if len(transforms) == 0 {
+		return value, nil
+	}
+	return ApplyTransforms(value, transforms, &TransformContext{BaseURL: ee.page.URL()})
 }
 
-// applyTransformations applies a chain of transformations to a value
-func (ee *ExtractionEngine) applyTransformations(value string, transforms []TransformConfig) (interface{}, error) {
-	var result interface{} = value
+// ExtractLinks extracts all links from the page with optional limit
+func (ee *ExtractionEngine) ExtractLinks(selector string, limit int) ([]string, error) {
+	return ee.ExtractLinksWithType(selector, SelectorTypeCSS, limit)
+}
 
-	for _, transform := range transforms {
-		switch transform.Type {
-		case "trim":
-			if str, ok := result.(string); ok {
-				result = strings.TrimSpace(str)
-			}
-		case "lowercase":
-			if str, ok := result.(string); ok {
-				result = strings.ToLower(str)
-			}
-		case "uppercase":
-			if str, ok := result.(string); ok {
-				result = strings.ToUpper(str)
-			}
-		case "regex":
-			if str, ok := result.(string); ok {
-				pattern, _ := transform.Params["pattern"].(string)
-				replacement, _ := transform.Params["replacement"].(string)
-				re := regexp.MustCompile(pattern)
-				result = re.ReplaceAllString(str, replacement)
-			}
-		case "replace":
-			if str, ok := result.(string); ok {
-				old, _ := transform.Params["old"].(string)
-				new, _ := transform.Params["new"].(string)
-				result = strings.ReplaceAll(str, old, new)
-			}
-		case "split":
-			if str, ok := result.(string); ok {
-				delimiter, _ := transform.Params["delimiter"].(string)
-				result = strings.Split(str, delimiter)
-			}
-		case "join":
-			if arr, ok := result.([]string); ok {
-				delimiter, _ := transform.Params["delimiter"].(string)
-				result = strings.Join(arr, delimiter)
-			}
-		case "parse_int":
-			if str, ok := result.(string); ok {
-				i, err := strconv.ParseInt(strings.TrimSpace(str), 10, 64)
-				if err == nil {
-					result = i
-				}
-			}
-		case "parse_float":
-			if str, ok := result.(string); ok {
-				f, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
-				if err == nil {
-					result = f
-				}
-			}
-		case "clean_html":
-			if str, ok := result.(string); ok {
-				// Remove HTML tags and clean up text
-				re := regexp.MustCompile(`<[^>]*>`)
-				cleaned := re.ReplaceAllString(str, " ")
-				// Clean up multiple spaces and newlines
-				re = regexp.MustCompile(`\s+`)
-				cleaned = re.ReplaceAllString(cleaned, " ")
-				result = strings.TrimSpace(cleaned)
-			}
-		case "extract_price":
-			if str, ok := result.(string); ok {
-				// Extract price from strings like "¥123,456" or "$99.99"
-				re := regexp.MustCompile(`[\d,]+\.?\d*`)
-				matches := re.FindString(str)
-				if matches != "" {
-					// Remove commas and parse as float
-					priceStr := strings.ReplaceAll(matches, ",", "")
-					if f, err := strconv.ParseFloat(priceStr, 64); err == nil {
-						result = f
-					}
-				}
-			}
-		}
+// ExtractLinksWithType extracts links using a typed selector (css, xpath, text, role, shadow)
+func (ee *ExtractionEngine) ExtractLinksWithType(selector, selectorType string, limit int) ([]string, error) {
+	selType, err := NormalizeSelectorType(selectorType)
+	if err != nil {
+		return nil, err
 	}
 
-	return result, nil
-}
+	// Shadow roots are not serialized by page.Content(), so query the live DOM instead
+	if selType == SelectorTypeShadow {
+		return ee.extractLinksFromLocator(selector, selType, limit)
+	}
 
-// ExtractLinks extracts all links from the page with optional limit
-func (ee *ExtractionEngine) ExtractLinks(selector string, limit int) ([]string, error) {
 	content, err := ee.page.Content()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get page content: %w", err)
 	}
 
+	return ExtractLinksFromHTML(content, selector, selType, limit)
+}
+
+// ExtractLinksFromHTML extracts href values from static HTML using a typed selector
+func ExtractLinksFromHTML(content, selector, selectorType string, limit int) ([]string, error) {
 	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse HTML: %w", err)
 	}
 
+	selection, err := FindInDocument(doc.Selection, selector, selectorType)
+	if err != nil {
+		return nil, err
+	}
+
 	var links []string
-	doc.Find(selector).Each(func(i int, s *goquery.Selection) {
+	selection.Each(func(i int, s *goquery.Selection) {
 		// If limit is set and we've reached it, stop extracting
 		if limit > 0 && len(links) >= limit {
 			return
@@ -737,6 +643,28 @@ func (ee *ExtractionEngine) ExtractLinks(selector string, limit int) ([]string,
 	return links, nil
 }
 
+// extractLinksFromLocator extracts href values through Playwright locators
+func (ee *ExtractionEngine) extractLinksFromLocator(selector, selectorType string, limit int) ([]string, error) {
+	result, err := ee.page.Locator(PlaywrightSelector(selector, selectorType)).EvaluateAll(
+		`els => els.map(el => el.getAttribute('href') || '')`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate link selector: %w", err)
+	}
+
+	var links []string
+	hrefs, _ := result.([]interface{})
+	for _, h := range hrefs {
+		if limit > 0 && len(links) >= limit {
+			break
+		}
+		if href, ok := h.(string); ok && href != "" {
+			links = append(links, href)
+		}
+	}
+
+	return links, nil
+}
+
 // ExtractJSON extracts JSON data from a script tag or embedded JSON
 func (ee *ExtractionEngine) ExtractJSON(selector string) (map[string]interface{}, error) {
 	content, err := ee.page.Locator(selector).First().InnerHTML()
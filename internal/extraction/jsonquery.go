@@ -0,0 +1,84 @@
+package extraction
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmespath/go-jmespath"
+	"github.com/ohler55/ojg/jp"
+)
+
+// JSON query languages supported by QueryJSON
+const (
+	QueryLangJSONPath = "jsonpath"
+	QueryLangJMESPath = "jmespath"
+)
+
+// NormalizeQueryLang returns the query language for an expression
+// An empty lang selects jsonpath for "$"-rooted expressions and jmespath otherwise
+func NormalizeQueryLang(lang, expr string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(lang)) {
+	case "":
+		if strings.HasPrefix(strings.TrimSpace(expr), "$") {
+			return QueryLangJSONPath, nil
+		}
+		return QueryLangJMESPath, nil
+	case QueryLangJSONPath, "json_path":
+		return QueryLangJSONPath, nil
+	case QueryLangJMESPath, "jmes_path":
+		return QueryLangJMESPath, nil
+	default:
+		return "", fmt.Errorf("unsupported query language '%s' (use jsonpath or jmespath)", lang)
+	}
+}
+
+// ValidateJSONQuery checks that a query expression compiles
+func ValidateJSONQuery(expr, lang string) error {
+	queryLang, err := NormalizeQueryLang(lang, expr)
+	if err != nil {
+		return err
+	}
+	switch queryLang {
+	case QueryLangJSONPath:
+		if _, err := jp.ParseString(expr); err != nil {
+			return fmt.Errorf("invalid jsonpath '%s': %w", expr, err)
+		}
+	case QueryLangJMESPath:
+		if _, err := jmespath.Compile(expr); err != nil {
+			return fmt.Errorf("invalid jmespath '%s': %w", expr, err)
+		}
+	}
+	return nil
+}
+
+// QueryJSON evaluates a JSONPath or JMESPath expression against decoded JSON
+// JSONPath results with a single match are unwrapped; multiple matches return a slice
+func QueryJSON(data interface{}, expr, lang string) (interface{}, error) {
+	queryLang, err := NormalizeQueryLang(lang, expr)
+	if err != nil {
+		return nil, err
+	}
+
+	switch queryLang {
+	case QueryLangJSONPath:
+		path, err := jp.ParseString(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid jsonpath '%s': %w", expr, err)
+		}
+		results := path.Get(data)
+		switch len(results) {
+		case 0:
+			return nil, nil
+		case 1:
+			return results[0], nil
+		default:
+			return results, nil
+		}
+	default:
+		result, err := jmespath.Search(expr, data)
+		if err != nil {
+			return nil, fmt.Errorf("jmespath '%s' failed: %w", expr, err)
+		}
+		return result, nil
+	}
+}
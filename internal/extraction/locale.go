@@ -0,0 +1,182 @@
+package extraction
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// commaDecimalLanguages write decimals with "," ("1.234,56")
+var commaDecimalLanguages = map[string]bool{
+	"de": true, "fr": true, "es": true, "it": true, "pt": true, "nl": true, "ru": true, "pl": true,
+	"tr": true, "sv": true, "da": true, "nb": true, "no": true, "fi": true, "cs": true, "sk": true,
+	"hu": true, "ro": true, "bg": true, "uk": true, "el": true, "id": true, "vi": true, "hr": true,
+	"sl": true, "lt": true, "lv": true, "et": true,
+}
+
+// dotDecimalLanguages write decimals with "." ("1,234.56")
+var dotDecimalLanguages = map[string]bool{
+	"en": true, "ja": true, "zh": true, "ko": true, "th": true, "he": true, "hi": true, "ms": true,
+	"tl": true, "bn": true, "ar": true,
+}
+
+// dotDecimalRegions are regional exceptions to their language's separator
+var dotDecimalRegions = map[string]bool{
+	"de-ch": true, "fr-ch": true, "it-ch": true, "es-mx": true, "es-us": true,
+}
+
+// kronaByLanguage is the krona "kr" means in each language
+var kronaByLanguage = map[string]string{
+	"sv": "SEK", "nb": "NOK", "nn": "NOK", "no": "NOK", "da": "DKK", "is": "ISK",
+}
+
+// localeLanguage splits a locale like "de-DE" or "pt_BR" into language and normalised locale
+func localeLanguage(locale string) (string, string) {
+	normalised := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+	language := normalised
+	if i := strings.Index(normalised, "-"); i >= 0 {
+		language = normalised[:i]
+	}
+	return language, normalised
+}
+
+// ValidateLocale checks that a locale is one the transforms know how to parse
+func ValidateLocale(locale string) error {
+	if locale == "" {
+		return nil
+	}
+	language, _ := localeLanguage(locale)
+	if !commaDecimalLanguages[language] && !dotDecimalLanguages[language] {
+		return fmt.Errorf("unsupported locale '%s'", locale)
+	}
+	return nil
+}
+
+// decimalSeparator returns the decimal separator for a locale, or "" to infer it
+func decimalSeparator(locale string) string {
+	if locale == "" {
+		return ""
+	}
+	language, normalised := localeLanguage(locale)
+	if dotDecimalRegions[normalised] {
+		return "."
+	}
+	if commaDecimalLanguages[language] {
+		return ","
+	}
+	if dotDecimalLanguages[language] {
+		return "."
+	}
+	return ""
+}
+
+// localMonthNames maps month names per language to English, including common abbreviations
+var localMonthNames = map[string]map[string]string{
+	"de": {
+		"januar": "January", "jänner": "January", "februar": "February", "märz": "March", "april": "April",
+		"mai": "May", "juni": "June", "juli": "July", "august": "August", "september": "September",
+		"oktober": "October", "november": "November", "dezember": "December",
+		"jan": "Jan", "feb": "Feb", "mär": "Mar", "apr": "Apr", "jun": "Jun", "jul": "Jul",
+		"aug": "Aug", "sep": "Sep", "okt": "Oct", "nov": "Nov", "dez": "Dec",
+	},
+	"fr": {
+		"janvier": "January", "février": "February", "mars": "March", "avril": "April", "mai": "May",
+		"juin": "June", "juillet": "July", "août": "August", "septembre": "September",
+		"octobre": "October", "novembre": "November", "décembre": "December",
+		"janv": "Jan", "févr": "Feb", "avr": "Apr", "juil": "Jul", "sept": "Sep", "oct": "Oct", "déc": "Dec",
+	},
+	"es": {
+		"enero": "January", "febrero": "February", "marzo": "March", "abril": "April", "mayo": "May",
+		"junio": "June", "julio": "July", "agosto": "August", "septiembre": "September", "setiembre": "September",
+		"octubre": "October", "noviembre": "November", "diciembre": "December",
+		"ene": "Jan", "abr": "Apr", "ago": "Aug", "dic": "Dec",
+	},
+	"it": {
+		"gennaio": "January", "febbraio": "February", "marzo": "March", "aprile": "April", "maggio": "May",
+		"giugno": "June", "luglio": "July", "agosto": "August", "settembre": "September",
+		"ottobre": "October", "novembre": "November", "dicembre": "December",
+	},
+	"pt": {
+		"janeiro": "January", "fevereiro": "February", "março": "March", "abril": "April", "maio": "May",
+		"junho": "June", "julho": "July", "agosto": "August", "setembro": "September",
+		"outubro": "October", "novembro": "November", "dezembro": "December",
+	},
+	"nl": {
+		"januari": "January", "februari": "February", "maart": "March", "april": "April", "mei": "May",
+		"juni": "June", "juli": "July", "augustus": "August", "september": "September",
+		"oktober": "October", "november": "November", "december": "December",
+	},
+}
+
+// dateFillerWords are connective words removed before parsing ("5 de marzo de 2024")
+var dateFillerWords = regexp.MustCompile(`(?i)\s+(?:de|del|di)\s+`)
+
+// wordPattern matches letter runs, including accented letters
+var wordPattern = regexp.MustCompile(`\p{L}+`)
+
+// localiseDate rewrites localized month names to English so Go layouts can parse them
+func localiseDate(raw, locale string) string {
+	language, _ := localeLanguage(locale)
+	names, ok := localMonthNames[language]
+	if !ok {
+		return raw
+	}
+	raw = dateFillerWords.ReplaceAllString(raw, " ")
+	return wordPattern.ReplaceAllStringFunc(raw, func(word string) string {
+		if english, ok := names[strings.ToLower(word)]; ok {
+			return english
+		}
+		return word
+	})
+}
+
+// dayFirstLayouts are numeric and named layouts used by day-first locales
+var dayFirstLayouts = []string{
+	"02/01/2006",
+	"2/1/2006",
+	"02.01.2006",
+	"2.1.2006",
+	"02-01-2006",
+	"2 January 2006",
+	"2. January 2006",
+	"2 Jan 2006",
+	"2. Jan 2006",
+	"2 January 2006 15:04",
+	"02.01.2006 15:04",
+}
+
+// monthFirstLayouts are layouts used by month-first locales such as en-US
+var monthFirstLayouts = []string{
+	"01/02/2006",
+	"1/2/2006",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"January 2 2006",
+}
+
+// yearFirstLayouts are layouts used by CJK locales
+var yearFirstLayouts = []string{
+	"2006年1月2日",
+	"2006年01月02日",
+	"2006/01/02",
+	"2006/1/2",
+	"2006.01.02",
+	"2006년 1월 2일",
+}
+
+// dateLayoutsForLocale orders candidate layouts for a locale before the ISO defaults
+func dateLayoutsForLocale(locale string) []string {
+	language, normalised := localeLanguage(locale)
+	var layouts []string
+	switch {
+	case locale == "":
+		return nil
+	case language == "ja" || language == "zh" || language == "ko":
+		layouts = append(layouts, yearFirstLayouts...)
+	case normalised == "en-us" || normalised == "en":
+		layouts = append(layouts, monthFirstLayouts...)
+	default:
+		layouts = append(layouts, dayFirstLayouts...)
+	}
+	return append(layouts, "2006-01-02T15:04:05Z07:00", "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02")
+}
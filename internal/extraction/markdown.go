@@ -0,0 +1,243 @@
+package extraction
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// blankLines collapses runs of blank lines in generated markdown
+var blankLines = regexp.MustCompile(`\n{3,}`)
+
+// HTMLToMarkdown converts an HTML fragment to markdown; relative links resolve against baseURL
+func HTMLToMarkdown(fragment, baseURL string) (string, error) {
+	context := &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body}
+	nodes, err := html.ParseFragment(strings.NewReader(fragment), context)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse html: %w", err)
+	}
+
+	md := &markdownWriter{baseURL: baseURL}
+	for _, n := range nodes {
+		md.node(n)
+	}
+	out := blankLines.ReplaceAllString(md.sb.String(), "\n\n")
+	return strings.TrimSpace(out), nil
+}
+
+// markdownWriter renders HTML nodes as markdown
+type markdownWriter struct {
+	sb      strings.Builder
+	baseURL string
+	lists   []listState
+	pre     bool
+}
+
+// listState tracks nesting and numbering of lists
+type listState struct {
+	ordered bool
+	index   int
+}
+
+// block starts a new paragraph-level block
+func (md *markdownWriter) block() {
+	md.sb.WriteString("\n\n")
+}
+
+// children renders all child nodes
+func (md *markdownWriter) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		md.node(c)
+	}
+}
+
+// node renders a single node
+func (md *markdownWriter) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		if md.pre {
+			md.sb.WriteString(n.Data)
+			return
+		}
+		text := strings.Join(strings.Fields(n.Data), " ")
+		if text == "" {
+			if n.Data != "" {
+				md.sb.WriteString(" ")
+			}
+			return
+		}
+		if strings.TrimLeft(n.Data, " \t\r\n") != n.Data {
+			text = " " + text
+		}
+		if strings.TrimRight(n.Data, " \t\r\n") != n.Data {
+			text += " "
+		}
+		md.sb.WriteString(text)
+		return
+	case html.ElementNode:
+	default:
+		md.children(n)
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.Script, atom.Style, atom.Noscript, atom.Template:
+		return
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		md.block()
+		md.sb.WriteString(strings.Repeat("#", int(n.Data[1]-'0')) + " ")
+		md.inline(n)
+		md.block()
+	case atom.P, atom.Div, atom.Section, atom.Article, atom.Header, atom.Footer, atom.Table:
+		md.block()
+		md.children(n)
+		md.block()
+	case atom.Tr:
+		md.children(n)
+		md.sb.WriteString("\n")
+	case atom.Td, atom.Th:
+		md.inline(n)
+		md.sb.WriteString(" ")
+	case atom.Br:
+		md.sb.WriteString("  \n")
+	case atom.Hr:
+		md.block()
+		md.sb.WriteString("---")
+		md.block()
+	case atom.Strong, atom.B:
+		md.wrap(n, "**")
+	case atom.Em, atom.I:
+		md.wrap(n, "_")
+	case atom.Del, atom.S:
+		md.wrap(n, "~~")
+	case atom.Code:
+		if md.pre {
+			md.children(n)
+		} else {
+			md.wrap(n, "`")
+		}
+	case atom.Pre:
+		md.block()
+		md.sb.WriteString("```\n")
+		md.pre = true
+		md.children(n)
+		md.pre = false
+		md.sb.WriteString("\n```")
+		md.block()
+	case atom.Blockquote:
+		inner := &markdownWriter{baseURL: md.baseURL}
+		inner.children(n)
+		md.block()
+		for _, line := range strings.Split(strings.TrimSpace(blankLines.ReplaceAllString(inner.sb.String(), "\n\n")), "\n") {
+			md.sb.WriteString("> " + strings.TrimSpace(line) + "\n")
+		}
+		md.block()
+	case atom.Ul, atom.Ol:
+		if len(md.lists) == 0 {
+			md.block()
+		}
+		md.lists = append(md.lists, listState{ordered: n.DataAtom == atom.Ol})
+		md.children(n)
+		md.lists = md.lists[:len(md.lists)-1]
+		if len(md.lists) == 0 {
+			md.block()
+		}
+	case atom.Li:
+		md.listItem(n)
+	case atom.A:
+		href := md.resolve(attr(n, "href"))
+		text := strings.TrimSpace(md.render(n))
+		if href == "" || strings.HasPrefix(href, "javascript:") {
+			md.sb.WriteString(text)
+			return
+		}
+		if text == "" {
+			text = href
+		}
+		fmt.Fprintf(&md.sb, "[%s](%s)", text, href)
+	case atom.Img:
+		src := md.resolve(attr(n, "src"))
+		if src != "" {
+			fmt.Fprintf(&md.sb, "![%s](%s)", attr(n, "alt"), src)
+		}
+	default:
+		md.children(n)
+	}
+}
+
+// listItem renders a list item with indentation for nested lists
+func (md *markdownWriter) listItem(n *html.Node) {
+	depth := len(md.lists)
+	marker := "- "
+	if depth > 0 {
+		state := &md.lists[depth-1]
+		state.index++
+		if state.ordered {
+			marker = fmt.Sprintf("%d. ", state.index)
+		}
+	} else {
+		depth = 1
+	}
+	md.sb.WriteString("\n" + strings.Repeat("  ", depth-1) + marker)
+	md.children(n)
+}
+
+// inline renders a node's children on a single line
+func (md *markdownWriter) inline(n *html.Node) {
+	md.sb.WriteString(strings.Join(strings.Fields(md.render(n)), " "))
+}
+
+// wrap renders a node's children between markers, keeping surrounding spaces outside them
+func (md *markdownWriter) wrap(n *html.Node, marker string) {
+	text := md.render(n)
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		md.sb.WriteString(text)
+		return
+	}
+	if strings.HasPrefix(text, " ") {
+		md.sb.WriteString(" ")
+	}
+	md.sb.WriteString(marker + trimmed + marker)
+	if strings.HasSuffix(text, " ") {
+		md.sb.WriteString(" ")
+	}
+}
+
+// render renders a node's children to a string
+func (md *markdownWriter) render(n *html.Node) string {
+	inner := &markdownWriter{baseURL: md.baseURL, pre: md.pre}
+	inner.children(n)
+	return inner.sb.String()
+}
+
+// resolve makes a link absolute against the base URL
+func (md *markdownWriter) resolve(href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" || md.baseURL == "" {
+		return href
+	}
+	base, err := url.Parse(md.baseURL)
+	if err != nil {
+		return href
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// attr returns an attribute value of an element
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
@@ -0,0 +1,537 @@
+package extraction
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xpath"
+	"golang.org/x/net/html"
+)
+
+// Selector dialects supported by selector_type params
+const (
+	SelectorTypeCSS    = "css"    // CSS selector (default)
+	SelectorTypeXPath  = "xpath"  // XPath 1.0 expression
+	SelectorTypeText   = "text"   // Text match; quoted text ("Price") matches exactly
+	SelectorTypeRole   = "role"   // ARIA role with optional name/level, e.g. button[name="Next"]
+	SelectorTypeShadow = "shadow" // CSS that pierces shadow roots; ">>>" marks host boundaries
+)
+
+// roleSelectorPattern parses role selectors like: button[name="Next"][level=2]
+var roleSelectorPattern = regexp.MustCompile(`^\s*([a-zA-Z]+)\s*((?:\[[^\]]*\]\s*)*)$`)
+
+// roleAttrPattern parses a single role selector attribute: name="Next" s, level=2
+var roleAttrPattern = regexp.MustCompile(`\[\s*([a-zA-Z-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s\]]+))\s*([is])?\s*\]`)
+
+// NormalizeSelectorType returns the canonical selector type ("" becomes css)
+func NormalizeSelectorType(selectorType string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(selectorType)) {
+	case "", SelectorTypeCSS:
+		return SelectorTypeCSS, nil
+	case SelectorTypeXPath:
+		return SelectorTypeXPath, nil
+	case SelectorTypeText:
+		return SelectorTypeText, nil
+	case SelectorTypeRole:
+		return SelectorTypeRole, nil
+	case SelectorTypeShadow, "shadow-piercing", "shadow_piercing":
+		return SelectorTypeShadow, nil
+	default:
+		return "", fmt.Errorf("unsupported selector_type '%s' (use css, xpath, text, role or shadow)", selectorType)
+	}
+}
+
+// ValidateSelector checks that a selector is syntactically valid for its type as Playwright
+// evaluates it on a live page
+// CSS may use Playwright's extensions such as :has-text(), :visible, :nth-match() and ">>"
+// chaining; selectors evaluated on static HTML must pass ValidateStaticSelector instead.
+func ValidateSelector(selector, selectorType string) error {
+	return validateSelector(selector, selectorType, false)
+}
+
+// ValidateStaticSelector checks a selector that is evaluated on static HTML by FindInDocument,
+// where CSS must be standard CSS
+func ValidateStaticSelector(selector, selectorType string) error {
+	return validateSelector(selector, selectorType, true)
+}
+
+func validateSelector(selector, selectorType string, static bool) error {
+	selType, err := NormalizeSelectorType(selectorType)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(selector) == "" {
+		return fmt.Errorf("selector is empty")
+	}
+
+	switch selType {
+	case SelectorTypeXPath:
+		if _, err := xpath.Compile(selector); err != nil {
+			return fmt.Errorf("invalid xpath '%s': %w", selector, err)
+		}
+	case SelectorTypeRole:
+		if _, err := parseRoleSelector(selector); err != nil {
+			return err
+		}
+	case SelectorTypeShadow:
+		if _, err := cascadia.ParseGroup(shadowToCSS(selector)); err != nil {
+			return fmt.Errorf("invalid css selector '%s': %w", selector, err)
+		}
+	case SelectorTypeCSS:
+		if static {
+			if _, err := cascadia.ParseGroup(selector); err != nil {
+				return fmt.Errorf("invalid css selector '%s' (selectors read from the page HTML must be standard CSS): %w", selector, err)
+			}
+			return nil
+		}
+		if err := validatePlaywrightCSS(selector); err != nil {
+			return fmt.Errorf("invalid css selector '%s': %w", selector, err)
+		}
+	}
+	return nil
+}
+
+// playwrightPseudoClasses are the CSS extensions of Playwright's css engine
+// nth-match takes a selector and an index; the others are removed before parsing.
+var playwrightPseudoClasses = []string{
+	":has-text(", ":text-is(", ":text-matches(", ":text(", ":visible", ":nth-match(",
+	":left-of(", ":right-of(", ":above(", ":below(", ":near(",
+}
+
+// playwrightEngines are the selector engines a ">>" chain part may name with "engine="
+var playwrightEngines = map[string]bool{
+	"css": true, "xpath": true, "text": true, "id": true, "data-testid": true, "data-test-id": true,
+	"data-test": true, "role": true, "nth": true, "visible": true, "internal": true,
+}
+
+// validatePlaywrightCSS checks CSS in Playwright's dialect: each part of a ">>" chain is parsed
+// as standard CSS once the Playwright pseudo-classes are taken out
+func validatePlaywrightCSS(selector string) error {
+	for _, part := range splitChain(selector) {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			return fmt.Errorf("empty part in '>>' chain")
+		}
+		if engine, body, ok := chainEngine(part); ok {
+			if !playwrightEngines[engine] && !strings.HasPrefix(engine, "internal:") {
+				return fmt.Errorf("unknown selector engine '%s'", engine)
+			}
+			if engine == "css" {
+				if err := validatePlaywrightCSS(body); err != nil {
+					return err
+				}
+			}
+			if engine == "xpath" {
+				if _, err := xpath.Compile(body); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		// Playwright reads unprefixed parts starting with // or .. as xpath, quoted ones as text
+		if strings.HasPrefix(part, "//") || strings.HasPrefix(part, "..") {
+			if _, err := xpath.Compile(part); err != nil {
+				return err
+			}
+			continue
+		}
+		if part[0] == '"' || part[0] == '\'' {
+			continue
+		}
+		css, err := stripPlaywrightPseudoClasses(part)
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(css) == "" {
+			continue
+		}
+		if _, err := cascadia.ParseGroup(css); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// splitChain splits a selector on ">>" outside quotes and parentheses, leaving ">>>" alone
+func splitChain(selector string) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(selector); i++ {
+		c := selector[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case depth == 0 && strings.HasPrefix(selector[i:], ">>>"):
+			i += 2
+		case depth == 0 && strings.HasPrefix(selector[i:], ">>"):
+			parts = append(parts, selector[start:i])
+			start = i + 2
+			i++
+		}
+	}
+	return append(parts, selector[start:])
+}
+
+// chainEngine splits "engine=body" when part names a selector engine
+func chainEngine(part string) (string, string, bool) {
+	i := strings.Index(part, "=")
+	if i <= 0 {
+		return "", "", false
+	}
+	engine := strings.TrimSpace(part[:i])
+	for _, r := range engine {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == ':') {
+			return "", "", false
+		}
+	}
+	return strings.ToLower(engine), part[i+1:], true
+}
+
+// stripPlaywrightPseudoClasses removes Playwright's pseudo-classes, keeping the inner selector
+// of :nth-match() so it is still checked
+func stripPlaywrightPseudoClasses(css string) (string, error) {
+	var out strings.Builder
+	for i := 0; i < len(css); {
+		matched := ""
+		for _, pc := range playwrightPseudoClasses {
+			if strings.HasPrefix(css[i:], pc) {
+				matched = pc
+				break
+			}
+		}
+		if matched == "" {
+			out.WriteByte(css[i])
+			i++
+			continue
+		}
+		i += len(matched)
+		if !strings.HasSuffix(matched, "(") {
+			continue
+		}
+		end := closingParen(css, i)
+		if end < 0 {
+			return "", fmt.Errorf("unclosed %s", matched)
+		}
+		if matched == ":nth-match(" {
+			args := css[i:end]
+			comma := strings.LastIndex(args, ",")
+			if comma < 0 {
+				return "", fmt.Errorf(":nth-match() needs a selector and an index")
+			}
+			if _, err := strconv.Atoi(strings.TrimSpace(args[comma+1:])); err != nil {
+				return "", fmt.Errorf(":nth-match() index must be a number")
+			}
+			if err := validatePlaywrightCSS(args[:comma]); err != nil {
+				return "", err
+			}
+			// The whole pseudo-class is a selector of its own; stand in a universal selector
+			out.WriteString("*")
+		}
+		i = end + 1
+	}
+	return out.String(), nil
+}
+
+// closingParen returns the index of the parenthesis closing the one opened before start
+func closingParen(s string, start int) int {
+	depth := 1
+	var quote byte
+	for i := start; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// PlaywrightSelector converts a typed selector into a Playwright selector string
+func PlaywrightSelector(selector, selectorType string) string {
+	selType, err := NormalizeSelectorType(selectorType)
+	if err != nil {
+		return selector
+	}
+
+	switch selType {
+	case SelectorTypeXPath:
+		return "xpath=" + selector
+	case SelectorTypeText:
+		return "text=" + selector
+	case SelectorTypeRole:
+		return "role=" + strings.TrimSpace(selector)
+	case SelectorTypeShadow:
+		// Playwright's css engine pierces open shadow roots
+		return "css=" + shadowToCSS(selector)
+	default:
+		return selector
+	}
+}
+
+// FindInDocument evaluates a typed selector against a goquery selection
+// It mirrors the Playwright engines so static HTML and live pages select the same elements
+func FindInDocument(sel *goquery.Selection, selector, selectorType string) (*goquery.Selection, error) {
+	selType, err := NormalizeSelectorType(selectorType)
+	if err != nil {
+		return nil, err
+	}
+
+	switch selType {
+	case SelectorTypeXPath:
+		expr, err := xpath.Compile(selector)
+		if err != nil {
+			return nil, fmt.Errorf("invalid xpath '%s': %w", selector, err)
+		}
+		var found []*html.Node
+		seen := make(map[*html.Node]bool)
+		for _, root := range sel.Nodes {
+			for _, node := range htmlquery.QuerySelectorAll(root, expr) {
+				if !seen[node] {
+					seen[node] = true
+					found = append(found, node)
+				}
+			}
+		}
+		return sel.FindNodes(found...), nil
+	case SelectorTypeText:
+		return findByText(sel, selector), nil
+	case SelectorTypeRole:
+		role, err := parseRoleSelector(selector)
+		if err != nil {
+			return nil, err
+		}
+		return findByRole(sel, role), nil
+	case SelectorTypeShadow:
+		return sel.Find(shadowToCSS(selector)), nil
+	default:
+		return sel.Find(selector), nil
+	}
+}
+
+// shadowToCSS turns "host >>> inner" into a descendant selector
+func shadowToCSS(selector string) string {
+	return strings.Join(strings.Fields(strings.ReplaceAll(selector, ">>>", " ")), " ")
+}
+
+// findByText mirrors Playwright's text engine: the smallest elements whose text matches
+func findByText(sel *goquery.Selection, selector string) *goquery.Selection {
+	exact := false
+	needle := strings.TrimSpace(selector)
+	if len(needle) >= 2 && (needle[0] == '"' || needle[0] == '\'') && needle[len(needle)-1] == needle[0] {
+		exact = true
+		needle = needle[1 : len(needle)-1]
+	}
+	needle = normalizeWhitespace(needle)
+
+	matches := func(s *goquery.Selection) bool {
+		text := normalizeWhitespace(s.Text())
+		if exact {
+			return text == needle
+		}
+		return strings.Contains(strings.ToLower(text), strings.ToLower(needle))
+	}
+
+	return sel.Find("*").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		if isNonContentElement(s) || !matches(s) {
+			return false
+		}
+		// Keep only the innermost match
+		childMatch := false
+		s.Children().EachWithBreak(func(_ int, child *goquery.Selection) bool {
+			if !isNonContentElement(child) && matches(child) {
+				childMatch = true
+				return false
+			}
+			return true
+		})
+		return !childMatch
+	})
+}
+
+// roleSelector is a parsed role=... selector
+type roleSelector struct {
+	role      string
+	name      string
+	nameSet   bool
+	nameExact bool
+	level     int
+}
+
+// parseRoleSelector parses selectors like: button[name="Next"], heading[level=2]
+func parseRoleSelector(selector string) (*roleSelector, error) {
+	m := roleSelectorPattern.FindStringSubmatch(selector)
+	if m == nil {
+		return nil, fmt.Errorf("invalid role selector '%s'", selector)
+	}
+
+	rs := &roleSelector{role: strings.ToLower(m[1])}
+	for _, attr := range roleAttrPattern.FindAllStringSubmatch(m[2], -1) {
+		value := attr[2] + attr[3] + attr[4]
+		switch strings.ToLower(attr[1]) {
+		case "name":
+			rs.name = value
+			rs.nameSet = true
+			rs.nameExact = attr[5] == "s"
+		case "level":
+			level, err := strconv.Atoi(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid level in role selector '%s'", selector)
+			}
+			rs.level = level
+		default:
+			return nil, fmt.Errorf("unsupported role selector attribute '%s'", attr[1])
+		}
+	}
+	return rs, nil
+}
+
+// findByRole selects elements whose explicit or implicit ARIA role matches
+func findByRole(sel *goquery.Selection, rs *roleSelector) *goquery.Selection {
+	return sel.Find("*").FilterFunction(func(_ int, s *goquery.Selection) bool {
+		if implicitRole(s) != rs.role {
+			return false
+		}
+		if rs.level > 0 {
+			level, _ := strconv.Atoi(strings.TrimPrefix(goquery.NodeName(s), "h"))
+			if ariaLevel, ok := s.Attr("aria-level"); ok {
+				level, _ = strconv.Atoi(ariaLevel)
+			}
+			if level != rs.level {
+				return false
+			}
+		}
+		if rs.nameSet {
+			name := accessibleName(s)
+			if rs.nameExact {
+				return name == rs.name
+			}
+			return strings.Contains(strings.ToLower(name), strings.ToLower(rs.name))
+		}
+		return true
+	})
+}
+
+// implicitRole returns the explicit role attribute or the element's implicit ARIA role
+func implicitRole(s *goquery.Selection) string {
+	if role, ok := s.Attr("role"); ok && strings.TrimSpace(role) != "" {
+		return strings.ToLower(strings.Fields(role)[0])
+	}
+
+	switch goquery.NodeName(s) {
+	case "a", "area":
+		if _, ok := s.Attr("href"); ok {
+			return "link"
+		}
+	case "button":
+		return "button"
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		return "heading"
+	case "img":
+		if alt, ok := s.Attr("alt"); ok && alt == "" {
+			return "presentation"
+		}
+		return "img"
+	case "input":
+		switch strings.ToLower(s.AttrOr("type", "text")) {
+		case "button", "submit", "reset", "image":
+			return "button"
+		case "checkbox":
+			return "checkbox"
+		case "radio":
+			return "radio"
+		case "range":
+			return "slider"
+		case "number":
+			return "spinbutton"
+		case "search":
+			return "searchbox"
+		case "text", "email", "tel", "url", "":
+			return "textbox"
+		}
+	case "textarea":
+		return "textbox"
+	case "select":
+		return "combobox"
+	case "option":
+		return "option"
+	case "ul", "ol":
+		return "list"
+	case "li":
+		return "listitem"
+	case "nav":
+		return "navigation"
+	case "main":
+		return "main"
+	case "table":
+		return "table"
+	case "tr":
+		return "row"
+	case "td":
+		return "cell"
+	case "th":
+		return "columnheader"
+	case "dialog":
+		return "dialog"
+	case "form":
+		return "form"
+	}
+	return ""
+}
+
+// accessibleName approximates the accessible name of an element
+func accessibleName(s *goquery.Selection) string {
+	for _, attr := range []string{"aria-label", "alt", "title"} {
+		if v, ok := s.Attr(attr); ok && strings.TrimSpace(v) != "" {
+			return normalizeWhitespace(v)
+		}
+	}
+	if goquery.NodeName(s) == "input" {
+		if v, ok := s.Attr("value"); ok {
+			return normalizeWhitespace(v)
+		}
+	}
+	return normalizeWhitespace(s.Text())
+}
+
+// isNonContentElement reports elements whose text is never rendered
+func isNonContentElement(s *goquery.Selection) bool {
+	switch goquery.NodeName(s) {
+	case "script", "style", "noscript", "template", "head", "title", "meta", "link":
+		return true
+	}
+	return false
+}
+
+// normalizeWhitespace collapses runs of whitespace and trims the result
+func normalizeWhitespace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,651 @@
+package extraction
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Structured data sources collected by ParseStructuredData
+const (
+	StructuredSourceJSONLD    = "jsonld"
+	StructuredSourceMicrodata = "microdata"
+	StructuredSourceRDFa      = "rdfa"
+	StructuredSourceOpenGraph = "opengraph"
+	StructuredSourceTwitter   = "twitter"
+	StructuredSourceEmbedded  = "embedded"
+)
+
+// AllStructuredSources lists every structured data source
+var AllStructuredSources = []string{
+	StructuredSourceJSONLD,
+	StructuredSourceMicrodata,
+	StructuredSourceRDFa,
+	StructuredSourceOpenGraph,
+	StructuredSourceTwitter,
+	StructuredSourceEmbedded,
+}
+
+// defaultEmbeddedGlobals are window globals commonly used for hydration state
+var defaultEmbeddedGlobals = []string{
+	"__INITIAL_STATE__",
+	"__PRELOADED_STATE__",
+	"__APOLLO_STATE__",
+	"__NUXT__",
+	"__INITIAL_DATA__",
+}
+
+// schemaTypePrefixes are stripped from @type values so "http://schema.org/Product" matches "Product"
+var schemaTypePrefixes = []string{
+	"https://schema.org/",
+	"http://schema.org/",
+	"schema:",
+}
+
+// StructuredData holds every structured data block found in a document
+// Items from JSON-LD, microdata and RDFa share one shape: maps with "@type" plus properties
+type StructuredData struct {
+	JSONLD    []map[string]interface{} `json:"jsonld"`
+	Microdata []map[string]interface{} `json:"microdata"`
+	RDFa      []map[string]interface{} `json:"rdfa"`
+	OpenGraph map[string]interface{}   `json:"opengraph"`
+	Twitter   map[string]interface{}   `json:"twitter"`
+	Embedded  map[string]interface{}   `json:"embedded"`
+}
+
+// StructuredOptions controls which sources are parsed
+type StructuredOptions struct {
+	Sources         []string // Sources to collect (empty = all)
+	EmbeddedGlobals []string // Extra window globals to parse as embedded state
+}
+
+// ExtractStructured parses structured data from the current page
+// Embedded state is read from the page's window globals, so state built by scripts is found
+// too; globals the page does not expose fall back to the assignments in the HTML.
+func (ee *ExtractionEngine) ExtractStructured(opts StructuredOptions) (*StructuredData, error) {
+	content, err := ee.page.Content()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get page content: %w", err)
+	}
+	data, err := ParseStructuredData(content, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if structuredSourceEnabled(opts, StructuredSourceEmbedded) {
+		for name, value := range ee.evaluateGlobals(embeddedGlobals(opts)) {
+			data.Embedded[name] = value
+		}
+	}
+	return data, nil
+}
+
+// evaluateGlobalsScript returns the JSON of each named window global that is set
+const evaluateGlobalsScript = `names => {
+	const found = {};
+	for (const name of names) {
+		try {
+			const value = window[name];
+			if (value === undefined || value === null || typeof value === 'function') continue;
+			const json = JSON.stringify(value);
+			if (json !== undefined) found[name] = json;
+		} catch (e) {
+			// Cyclic or otherwise unserialisable state is left to the HTML fallback
+		}
+	}
+	return found;
+}`
+
+// evaluateGlobals reads window globals from the page; unreadable ones are left out
+func (ee *ExtractionEngine) evaluateGlobals(names []string) map[string]interface{} {
+	globals := make(map[string]interface{})
+	result, err := ee.page.Evaluate(evaluateGlobalsScript, names)
+	if err != nil {
+		return globals
+	}
+	encoded, _ := result.(map[string]interface{})
+	for name, raw := range encoded {
+		text, ok := raw.(string)
+		if !ok {
+			continue
+		}
+		var value interface{}
+		if err := json.Unmarshal([]byte(text), &value); err == nil {
+			globals[name] = value
+		}
+	}
+	return globals
+}
+
+// structuredSourceEnabled checks if opts collect a source
+func structuredSourceEnabled(opts StructuredOptions, source string) bool {
+	if len(opts.Sources) == 0 {
+		return true
+	}
+	for _, s := range opts.Sources {
+		if strings.EqualFold(s, source) {
+			return true
+		}
+	}
+	return false
+}
+
+// embeddedGlobals lists the window globals parsed as embedded state
+func embeddedGlobals(opts StructuredOptions) []string {
+	return append(append([]string{}, defaultEmbeddedGlobals...), opts.EmbeddedGlobals...)
+}
+
+// ParseStructuredData parses structured data from static HTML
+func ParseStructuredData(content string, opts StructuredOptions) (*StructuredData, error) {
+	doc, err := goquery.NewDocumentFromReader(strings.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	enabled := make(map[string]bool)
+	sources := opts.Sources
+	if len(sources) == 0 {
+		sources = AllStructuredSources
+	}
+	for _, source := range sources {
+		enabled[strings.ToLower(source)] = true
+	}
+
+	data := &StructuredData{
+		JSONLD:    []map[string]interface{}{},
+		Microdata: []map[string]interface{}{},
+		RDFa:      []map[string]interface{}{},
+		OpenGraph: map[string]interface{}{},
+		Twitter:   map[string]interface{}{},
+		Embedded:  map[string]interface{}{},
+	}
+
+	if enabled[StructuredSourceJSONLD] {
+		data.JSONLD = parseJSONLD(doc)
+	}
+	if enabled[StructuredSourceMicrodata] {
+		data.Microdata = parseMicrodata(doc)
+	}
+	if enabled[StructuredSourceRDFa] {
+		data.RDFa = parseRDFa(doc)
+	}
+	if enabled[StructuredSourceOpenGraph] {
+		data.OpenGraph = parseMetaTags(doc, func(key string) (string, bool) {
+			for _, prefix := range []string{"og:", "product:", "article:", "book:", "profile:", "music:", "video:"} {
+				if strings.HasPrefix(key, prefix) {
+					return strings.TrimPrefix(key, "og:"), true
+				}
+			}
+			return "", false
+		})
+	}
+	if enabled[StructuredSourceTwitter] {
+		data.Twitter = parseMetaTags(doc, func(key string) (string, bool) {
+			if strings.HasPrefix(key, "twitter:") {
+				return strings.TrimPrefix(key, "twitter:"), true
+			}
+			return "", false
+		})
+	}
+	if enabled[StructuredSourceEmbedded] {
+		data.Embedded = parseEmbeddedState(doc, embeddedGlobals(opts))
+	}
+
+	return data, nil
+}
+
+// Items returns all JSON-LD, microdata and RDFa items
+func (sd *StructuredData) Items() []map[string]interface{} {
+	items := make([]map[string]interface{}, 0, len(sd.JSONLD)+len(sd.Microdata)+len(sd.RDFa))
+	items = append(items, sd.JSONLD...)
+	items = append(items, sd.Microdata...)
+	items = append(items, sd.RDFa...)
+	return items
+}
+
+// FindByType returns items (including nested ones) whose @type matches one of the given types
+// An empty type list returns the top-level items
+func (sd *StructuredData) FindByType(types ...string) []map[string]interface{} {
+	if len(types) == 0 {
+		return sd.Items()
+	}
+
+	var found []map[string]interface{}
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if TypeMatches(v, types...) {
+				found = append(found, v)
+			}
+			for key, child := range v {
+				if key != "@type" && key != "@context" {
+					walk(child)
+				}
+			}
+		case []interface{}:
+			for _, child := range v {
+				walk(child)
+			}
+		}
+	}
+	for _, item := range sd.Items() {
+		walk(item)
+	}
+	return found
+}
+
+// ToMap converts structured data to a generic map for node results
+func (sd *StructuredData) ToMap() map[string]interface{} {
+	return map[string]interface{}{
+		StructuredSourceJSONLD:    toInterfaceSlice(sd.JSONLD),
+		StructuredSourceMicrodata: toInterfaceSlice(sd.Microdata),
+		StructuredSourceRDFa:      toInterfaceSlice(sd.RDFa),
+		StructuredSourceOpenGraph: sd.OpenGraph,
+		StructuredSourceTwitter:   sd.Twitter,
+		StructuredSourceEmbedded:  sd.Embedded,
+	}
+}
+
+// TypeMatches checks if an item's @type matches one of the given schema.org types
+func TypeMatches(item map[string]interface{}, types ...string) bool {
+	var itemTypes []string
+	switch t := item["@type"].(type) {
+	case string:
+		itemTypes = []string{t}
+	case []interface{}:
+		for _, v := range t {
+			if s, ok := v.(string); ok {
+				itemTypes = append(itemTypes, s)
+			}
+		}
+	}
+
+	for _, itemType := range itemTypes {
+		itemType = shortSchemaType(itemType)
+		for _, want := range types {
+			if strings.EqualFold(itemType, shortSchemaType(want)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// LookupPath resolves a dotted path such as "offers.price" or "offers[0].price"
+// Arrays without an index are traversed, so every match is returned
+func LookupPath(value interface{}, path string) []interface{} {
+	current := []interface{}{value}
+	if strings.TrimSpace(path) == "" {
+		return current
+	}
+
+	for _, segment := range splitPath(path) {
+		var next []interface{}
+		for _, v := range current {
+			next = append(next, lookupSegment(v, segment)...)
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		current = next
+	}
+	return current
+}
+
+// pathIndexPattern matches "[n]" array indexes in a path
+var pathIndexPattern = regexp.MustCompile(`\[(\d+)\]`)
+
+// splitPath splits "offers[0].price" into ["offers", "0", "price"]
+func splitPath(path string) []string {
+	path = pathIndexPattern.ReplaceAllString(path, ".$1")
+	var segments []string
+	for _, segment := range strings.Split(path, ".") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return segments
+}
+
+// lookupSegment applies one path segment, flattening arrays that have no explicit index
+func lookupSegment(value interface{}, segment string) []interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		child, ok := v[segment]
+		if !ok {
+			return nil
+		}
+		return []interface{}{child}
+	case []interface{}:
+		if index, err := strconv.Atoi(segment); err == nil {
+			if index >= 0 && index < len(v) {
+				return []interface{}{v[index]}
+			}
+			return nil
+		}
+		var results []interface{}
+		for _, item := range v {
+			results = append(results, lookupSegment(item, segment)...)
+		}
+		return results
+	}
+	return nil
+}
+
+// parseJSONLD collects all application/ld+json blocks, flattening @graph containers
+func parseJSONLD(doc *goquery.Document) []map[string]interface{} {
+	items := []map[string]interface{}{}
+
+	doc.Find(`script[type="application/ld+json"]`).Each(func(_ int, s *goquery.Selection) {
+		raw := strings.TrimSpace(s.Text())
+		if raw == "" {
+			return
+		}
+
+		var parsed interface{}
+		if err := json.Unmarshal([]byte(raw), &parsed); err != nil {
+			// Some sites wrap JSON-LD in HTML comments or CDATA
+			raw = strings.TrimSuffix(strings.TrimPrefix(raw, "<!--"), "-->")
+			raw = strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(raw), "//<![CDATA["), "//]]>")
+			if err := json.Unmarshal([]byte(strings.TrimSpace(raw)), &parsed); err != nil {
+				return
+			}
+		}
+
+		var collect func(value interface{}, context interface{})
+		collect = func(value interface{}, context interface{}) {
+			switch v := value.(type) {
+			case []interface{}:
+				for _, child := range v {
+					collect(child, context)
+				}
+			case map[string]interface{}:
+				if ctx, ok := v["@context"]; ok {
+					context = ctx
+				}
+				if graph, ok := v["@graph"]; ok {
+					collect(graph, context)
+					return
+				}
+				if _, ok := v["@context"]; !ok && context != nil {
+					v["@context"] = context
+				}
+				items = append(items, v)
+			}
+		}
+		collect(parsed, nil)
+	})
+
+	return items
+}
+
+// parseMicrodata collects top-level itemscope items
+func parseMicrodata(doc *goquery.Document) []map[string]interface{} {
+	items := []map[string]interface{}{}
+	doc.Find("[itemscope]").Each(func(_ int, s *goquery.Selection) {
+		if _, isProp := s.Attr("itemprop"); isProp {
+			return
+		}
+		items = append(items, parseMicrodataItem(s))
+	})
+	return items
+}
+
+// parseMicrodataItem builds an item from an itemscope element
+func parseMicrodataItem(s *goquery.Selection) map[string]interface{} {
+	item := map[string]interface{}{}
+	if itemType := strings.Fields(s.AttrOr("itemtype", "")); len(itemType) > 0 {
+		types := make([]interface{}, 0, len(itemType))
+		for _, t := range itemType {
+			types = append(types, shortSchemaType(t))
+		}
+		if len(types) == 1 {
+			item["@type"] = types[0]
+		} else {
+			item["@type"] = types
+		}
+	}
+	if id, ok := s.Attr("itemid"); ok {
+		item["@id"] = id
+	}
+
+	var walk func(parent *goquery.Selection)
+	walk = func(parent *goquery.Selection) {
+		parent.Children().Each(func(_ int, child *goquery.Selection) {
+			_, hasScope := child.Attr("itemscope")
+			props, hasProp := child.Attr("itemprop")
+
+			if hasProp {
+				var value interface{}
+				if hasScope {
+					value = parseMicrodataItem(child)
+				} else {
+					value = microdataValue(child)
+				}
+				for _, name := range strings.Fields(props) {
+					addProperty(item, name, value)
+				}
+			}
+			// Nested items own their descendants
+			if !hasScope {
+				walk(child)
+			}
+		})
+	}
+	walk(s)
+
+	return item
+}
+
+// microdataValue returns the property value of a microdata element
+func microdataValue(s *goquery.Selection) interface{} {
+	switch goquery.NodeName(s) {
+	case "meta":
+		return s.AttrOr("content", "")
+	case "audio", "embed", "iframe", "img", "source", "track", "video":
+		return s.AttrOr("src", "")
+	case "a", "area", "link":
+		return s.AttrOr("href", "")
+	case "object":
+		return s.AttrOr("data", "")
+	case "data", "meter":
+		return s.AttrOr("value", "")
+	case "time":
+		if datetime, ok := s.Attr("datetime"); ok {
+			return datetime
+		}
+	}
+	if content, ok := s.Attr("content"); ok {
+		return content
+	}
+	return normalizeWhitespace(s.Text())
+}
+
+// parseRDFa collects top-level typeof items
+func parseRDFa(doc *goquery.Document) []map[string]interface{} {
+	items := []map[string]interface{}{}
+	doc.Find("[typeof]").Each(func(_ int, s *goquery.Selection) {
+		if _, isProp := s.Attr("property"); isProp {
+			return
+		}
+		items = append(items, parseRDFaItem(s))
+	})
+	return items
+}
+
+// parseRDFaItem builds an item from a typeof element
+func parseRDFaItem(s *goquery.Selection) map[string]interface{} {
+	item := map[string]interface{}{}
+	if types := strings.Fields(s.AttrOr("typeof", "")); len(types) > 0 {
+		if len(types) == 1 {
+			item["@type"] = shortSchemaType(types[0])
+		} else {
+			list := make([]interface{}, 0, len(types))
+			for _, t := range types {
+				list = append(list, shortSchemaType(t))
+			}
+			item["@type"] = list
+		}
+	}
+	if resource, ok := s.Attr("resource"); ok {
+		item["@id"] = resource
+	} else if about, ok := s.Attr("about"); ok {
+		item["@id"] = about
+	}
+
+	var walk func(parent *goquery.Selection)
+	walk = func(parent *goquery.Selection) {
+		parent.Children().Each(func(_ int, child *goquery.Selection) {
+			_, hasType := child.Attr("typeof")
+			props, hasProp := child.Attr("property")
+
+			if hasProp {
+				var value interface{}
+				if hasType {
+					value = parseRDFaItem(child)
+				} else {
+					value = rdfaValue(child)
+				}
+				for _, name := range strings.Fields(props) {
+					addProperty(item, shortSchemaType(name), value)
+				}
+			}
+			if !hasType {
+				walk(child)
+			}
+		})
+	}
+	walk(s)
+
+	return item
+}
+
+// rdfaValue returns the property value of an RDFa element
+func rdfaValue(s *goquery.Selection) interface{} {
+	for _, attr := range []string{"content", "resource", "href", "src", "datetime"} {
+		if v, ok := s.Attr(attr); ok {
+			return v
+		}
+	}
+	return normalizeWhitespace(s.Text())
+}
+
+// parseMetaTags collects meta tags whose property or name is accepted by keyFn
+// Repeated keys (e.g. several og:image tags) become arrays
+func parseMetaTags(doc *goquery.Document, keyFn func(key string) (string, bool)) map[string]interface{} {
+	tags := map[string]interface{}{}
+	doc.Find("meta").Each(func(_ int, s *goquery.Selection) {
+		key := s.AttrOr("property", "")
+		if key == "" {
+			key = s.AttrOr("name", "")
+		}
+		name, ok := keyFn(strings.ToLower(strings.TrimSpace(key)))
+		if !ok {
+			return
+		}
+		content, ok := s.Attr("content")
+		if !ok {
+			return
+		}
+		addProperty(tags, name, strings.TrimSpace(content))
+	})
+	return tags
+}
+
+// parseEmbeddedState collects framework hydration state from script tags
+func parseEmbeddedState(doc *goquery.Document, globals []string) map[string]interface{} {
+	state := map[string]interface{}{}
+
+	// Next.js and Nuxt ship their state as a JSON script
+	for _, id := range []string{"__NEXT_DATA__", "__NUXT_DATA__"} {
+		doc.Find(`script#` + id).Each(func(_ int, s *goquery.Selection) {
+			var value interface{}
+			if err := json.Unmarshal([]byte(strings.TrimSpace(s.Text())), &value); err == nil {
+				state[id] = value
+			}
+		})
+	}
+
+	scripts := doc.Find("script:not([src])")
+	for _, name := range globals {
+		if _, exists := state[name]; exists {
+			continue
+		}
+		pattern := regexp.MustCompile(`(?:window|self|globalThis)\s*(?:\.\s*` + regexp.QuoteMeta(name) + `|\[\s*["']` + regexp.QuoteMeta(name) + `["']\s*\])\s*=\s*`)
+		scripts.EachWithBreak(func(_ int, s *goquery.Selection) bool {
+			text := s.Text()
+			loc := pattern.FindStringIndex(text)
+			if loc == nil {
+				return true
+			}
+			if value, ok := decodeAssignedJSON(text[loc[1]:]); ok {
+				state[name] = value
+				return false
+			}
+			return true
+		})
+	}
+
+	return state
+}
+
+// decodeAssignedJSON decodes the JSON value at the start of a script assignment
+// Supports plain object literals and JSON.parse("...") wrappers
+func decodeAssignedJSON(text string) (interface{}, bool) {
+	text = strings.TrimSpace(text)
+
+	if strings.HasPrefix(text, "JSON.parse(") {
+		var encoded string
+		decoder := json.NewDecoder(strings.NewReader(strings.TrimSpace(strings.TrimPrefix(text, "JSON.parse("))))
+		if err := decoder.Decode(&encoded); err != nil {
+			// Single-quoted strings are not valid JSON
+			return nil, false
+		}
+		text = encoded
+	}
+
+	var value interface{}
+	decoder := json.NewDecoder(strings.NewReader(text))
+	if err := decoder.Decode(&value); err != nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// addProperty sets a property, turning repeated properties into arrays
+func addProperty(item map[string]interface{}, name string, value interface{}) {
+	existing, ok := item[name]
+	if !ok {
+		item[name] = value
+		return
+	}
+	if list, ok := existing.([]interface{}); ok {
+		item[name] = append(list, value)
+		return
+	}
+	item[name] = []interface{}{existing, value}
+}
+
+// shortSchemaType strips schema.org prefixes from a type or property name
+func shortSchemaType(value string) string {
+	for _, prefix := range schemaTypePrefixes {
+		if strings.HasPrefix(value, prefix) {
+			return strings.TrimPrefix(value, prefix)
+		}
+	}
+	return value
+}
+
+// toInterfaceSlice converts typed items to a generic slice
+func toInterfaceSlice(items []map[string]interface{}) []interface{} {
+	result := make([]interface{}, len(items))
+	for i, item := range items {
+		result[i] = item
+	}
+	return result
+}
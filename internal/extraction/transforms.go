@@ -0,0 +1,303 @@
+package extraction
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TransformParam types checked by ValidateTransforms
+const (
+	TransformParamString     = "string"
+	TransformParamNumber     = "number"
+	TransformParamBool       = "bool"
+	TransformParamStringList = "string_list" // A string or a list of strings
+	TransformParamAny        = "any"
+)
+
+// TransformContext carries page information available to transforms
+type TransformContext struct {
+	BaseURL string // URL of the page the value was extracted from
+}
+
+// TransformFunc applies a transform to a value
+type TransformFunc func(value interface{}, params map[string]interface{}, tc *TransformContext) (interface{}, error)
+
+// TransformParam declares a parameter accepted by a transform
+type TransformParam struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Required    bool   `json:"required,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Transform is a named value transformation usable in field "transform" chains
+type Transform struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Params      []TransformParam `json:"params,omitempty"`
+	// Arrays passes arrays to Apply as a whole instead of applying the transform to each element
+	Arrays bool `json:"arrays,omitempty"`
+	// Validate performs checks beyond parameter types, such as compiling patterns
+	Validate func(params map[string]interface{}) error `json:"-"`
+	Apply    TransformFunc                             `json:"-"`
+
+	builtin bool
+}
+
+// transformRegistry holds built-in and plugin-provided transforms
+var transformRegistry = struct {
+	sync.RWMutex
+	transforms map[string]Transform
+}{transforms: builtinTransforms()}
+
+// RegisterTransform adds a custom transform; names must be unique
+func RegisterTransform(t Transform) error {
+	if t.Name == "" {
+		return fmt.Errorf("transform name is required")
+	}
+	if t.Apply == nil {
+		return fmt.Errorf("transform '%s' has no Apply function", t.Name)
+	}
+
+	transformRegistry.Lock()
+	defer transformRegistry.Unlock()
+	if _, exists := transformRegistry.transforms[t.Name]; exists {
+		return fmt.Errorf("transform '%s' is already registered", t.Name)
+	}
+	transformRegistry.transforms[t.Name] = t
+	return nil
+}
+
+// UnregisterTransform removes a custom transform; built-in transforms cannot be removed
+func UnregisterTransform(name string) {
+	transformRegistry.Lock()
+	defer transformRegistry.Unlock()
+	if t, ok := transformRegistry.transforms[name]; ok && !t.builtin {
+		delete(transformRegistry.transforms, name)
+	}
+}
+
+// LookupTransform returns a registered transform by name
+func LookupTransform(name string) (Transform, bool) {
+	transformRegistry.RLock()
+	defer transformRegistry.RUnlock()
+	t, ok := transformRegistry.transforms[name]
+	return t, ok
+}
+
+// ListTransforms returns all registered transforms sorted by name
+func ListTransforms() []Transform {
+	transformRegistry.RLock()
+	defer transformRegistry.RUnlock()
+	list := make([]Transform, 0, len(transformRegistry.transforms))
+	for _, t := range transformRegistry.transforms {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// ParseTransforms converts a transform definition to []TransformConfig
+// Accepts "trim", ["trim", {"type": "regex", "params": {...}}] or []TransformConfig
+func ParseTransforms(transform interface{}) ([]TransformConfig, error) {
+	switch t := transform.(type) {
+	case nil:
+		return nil, nil
+	case string:
+		return []TransformConfig{{Type: t}}, nil
+	case []TransformConfig:
+		return t, nil
+	case []interface{}:
+		transforms := make([]TransformConfig, 0, len(t))
+		for i, item := range t {
+			switch cfg := item.(type) {
+			case string:
+				transforms = append(transforms, TransformConfig{Type: cfg})
+			case map[string]interface{}:
+				transformType, _ := cfg["type"].(string)
+				if transformType == "" {
+					return nil, fmt.Errorf("transform[%d] requires a type", i)
+				}
+				tc := TransformConfig{Type: transformType}
+				if params, ok := cfg["params"].(map[string]interface{}); ok {
+					tc.Params = params
+				}
+				transforms = append(transforms, tc)
+			default:
+				return nil, fmt.Errorf("transform[%d] must be a name or an object", i)
+			}
+		}
+		return transforms, nil
+	}
+	return nil, fmt.Errorf("transform must be a name or a list of transforms, got %T", transform)
+}
+
+// ValidateTransforms checks that every transform exists and its parameters are valid
+func ValidateTransforms(transform interface{}) error {
+	transforms, err := ParseTransforms(transform)
+	if err != nil {
+		return err
+	}
+	for i, tc := range transforms {
+		t, ok := LookupTransform(tc.Type)
+		if !ok {
+			return fmt.Errorf("transform[%d]: unknown transform '%s'", i, tc.Type)
+		}
+		if err := validateTransformParams(t, tc.Params); err != nil {
+			return fmt.Errorf("transform[%d] '%s': %w", i, tc.Type, err)
+		}
+	}
+	return nil
+}
+
+// validateTransformParams checks declared parameter types, required and unknown parameters
+func validateTransformParams(t Transform, params map[string]interface{}) error {
+	declared := make(map[string]TransformParam, len(t.Params))
+	for _, p := range t.Params {
+		declared[p.Name] = p
+		value, present := params[p.Name]
+		if !present {
+			if p.Required {
+				return fmt.Errorf("missing required param '%s'", p.Name)
+			}
+			continue
+		}
+		if !paramTypeMatches(p.Type, value) {
+			return fmt.Errorf("param '%s' must be a %s", p.Name, strings.ReplaceAll(p.Type, "_", " "))
+		}
+	}
+	for name := range params {
+		if _, ok := declared[name]; !ok {
+			return fmt.Errorf("unknown param '%s'", name)
+		}
+	}
+	if t.Validate != nil {
+		return t.Validate(params)
+	}
+	return nil
+}
+
+// paramTypeMatches reports whether a decoded JSON value has the declared type
+func paramTypeMatches(paramType string, value interface{}) bool {
+	switch paramType {
+	case TransformParamString:
+		_, ok := value.(string)
+		return ok
+	case TransformParamNumber:
+		switch value.(type) {
+		case float64, int, int64:
+			return true
+		}
+		return false
+	case TransformParamBool:
+		_, ok := value.(bool)
+		return ok
+	case TransformParamStringList:
+		switch v := value.(type) {
+		case string, []string:
+			return true
+		case []interface{}:
+			for _, item := range v {
+				if _, ok := item.(string); !ok {
+					return false
+				}
+			}
+			return true
+		}
+		return false
+	}
+	return true
+}
+
+// ApplyTransforms runs a transform chain; transforms without Arrays are applied to each array element
+func ApplyTransforms(value interface{}, transforms []TransformConfig, tc *TransformContext) (interface{}, error) {
+	if tc == nil {
+		tc = &TransformContext{}
+	}
+	result := value
+	for _, cfg := range transforms {
+		t, ok := LookupTransform(cfg.Type)
+		if !ok {
+			return nil, fmt.Errorf("unknown transform '%s'", cfg.Type)
+		}
+		params := cfg.Params
+		if params == nil {
+			params = map[string]interface{}{}
+		}
+
+		var err error
+		if list, isList := asList(result); isList && !t.Arrays {
+			mapped := make([]interface{}, len(list))
+			for i, item := range list {
+				if mapped[i], err = t.Apply(item, params, tc); err != nil {
+					return nil, fmt.Errorf("transform '%s' failed on item %d: %w", cfg.Type, i, err)
+				}
+			}
+			result = mapped
+			continue
+		}
+		if result, err = t.Apply(result, params, tc); err != nil {
+			return nil, fmt.Errorf("transform '%s' failed: %w", cfg.Type, err)
+		}
+	}
+	return result, nil
+}
+
+// asList normalises string and interface slices
+func asList(value interface{}) ([]interface{}, bool) {
+	switch v := value.(type) {
+	case []interface{}:
+		return v, true
+	case []string:
+		list := make([]interface{}, len(v))
+		for i, s := range v {
+			list[i] = s
+		}
+		return list, true
+	}
+	return nil, false
+}
+
+// regexCache caches compiled transform patterns
+var regexCache sync.Map // map[string]*regexp.Regexp
+
+// compileTransformRegex compiles a transform pattern once and caches it
+func compileTransformRegex(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := regexCache.Load(pattern); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+	}
+	regexCache.Store(pattern, re)
+	return re, nil
+}
+
+// paramString reads a string transform parameter
+func paramString(params map[string]interface{}, key string) string {
+	s, _ := params[key].(string)
+	return s
+}
+
+// paramStringList reads a string or list-of-strings transform parameter
+func paramStringList(params map[string]interface{}, key string) []string {
+	switch v := params[key].(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		list := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
@@ -0,0 +1,417 @@
+package extraction
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uzzalhcse/crawlify/internal/expression"
+	"github.com/uzzalhcse/crawlify/internal/schema"
+)
+
+var (
+	htmlTagPattern    = regexp.MustCompile(`<[^>]*>`)
+	whitespacePattern = regexp.MustCompile(`\s+`)
+)
+
+// localeParam is shared by the locale-aware transforms
+var localeParam = TransformParam{Name: "locale", Type: TransformParamString, Description: "Locale such as de-DE or ja-JP; inferred when empty"}
+
+// builtinTransforms returns the transforms available to every workflow
+func builtinTransforms() map[string]Transform {
+	list := []Transform{
+		{
+			Name:        "trim",
+			Description: "Remove leading and trailing whitespace",
+			Apply:       stringTransform(func(s string) (interface{}, error) { return strings.TrimSpace(s), nil }),
+		},
+		{
+			Name:        "lowercase",
+			Description: "Convert text to lower case",
+			Apply:       stringTransform(func(s string) (interface{}, error) { return strings.ToLower(s), nil }),
+		},
+		{
+			Name:        "uppercase",
+			Description: "Convert text to upper case",
+			Apply:       stringTransform(func(s string) (interface{}, error) { return strings.ToUpper(s), nil }),
+		},
+		{
+			Name:        "regex",
+			Description: "Replace matches of a regular expression",
+			Params: []TransformParam{
+				{Name: "pattern", Type: TransformParamString, Required: true},
+				{Name: "replacement", Type: TransformParamString, Description: "Supports $1-style group references"},
+			},
+			Validate: func(params map[string]interface{}) error {
+				_, err := compileTransformRegex(paramString(params, "pattern"))
+				return err
+			},
+			Apply: func(value interface{}, params map[string]interface{}, tc *TransformContext) (interface{}, error) {
+				s, ok := value.(string)
+				if !ok {
+					return value, nil
+				}
+				re, err := compileTransformRegex(paramString(params, "pattern"))
+				if err != nil {
+					return nil, err
+				}
+				return re.ReplaceAllString(s, paramString(params, "replacement")), nil
+			},
+		},
+		{
+			Name:        "replace",
+			Description: "Replace all occurrences of a substring",
+			Params: []TransformParam{
+				{Name: "old", Type: TransformParamString, Required: true},
+				{Name: "new", Type: TransformParamString},
+			},
+			Apply: func(value interface{}, params map[string]interface{}, tc *TransformContext) (interface{}, error) {
+				s, ok := value.(string)
+				if !ok {
+					return value, nil
+				}
+				return strings.ReplaceAll(s, paramString(params, "old"), paramString(params, "new")), nil
+			},
+		},
+		{
+			Name:        "split",
+			Description: "Split text into an array; later transforms apply to each element",
+			Params:      []TransformParam{{Name: "delimiter", Type: TransformParamString}},
+			Apply: func(value interface{}, params map[string]interface{}, tc *TransformContext) (interface{}, error) {
+				s, ok := value.(string)
+				if !ok {
+					return value, nil
+				}
+				parts := strings.Split(s, paramString(params, "delimiter"))
+				result := make([]interface{}, len(parts))
+				for i, part := range parts {
+					result[i] = part
+				}
+				return result, nil
+			},
+		},
+		{
+			Name:        "join",
+			Description: "Join an array into text",
+			Params:      []TransformParam{{Name: "delimiter", Type: TransformParamString}},
+			Arrays:      true,
+			Apply: func(value interface{}, params map[string]interface{}, tc *TransformContext) (interface{}, error) {
+				list, ok := asList(value)
+				if !ok {
+					return value, nil
+				}
+				parts := make([]string, len(list))
+				for i, item := range list {
+					parts[i] = fmt.Sprint(item)
+				}
+				return strings.Join(parts, paramString(params, "delimiter")), nil
+			},
+		},
+		{
+			Name:        "parse_int",
+			Description: "Parse a plain integer; the value is kept when it is not a number",
+			Apply: stringTransform(func(s string) (interface{}, error) {
+				if i, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+					return i, nil
+				}
+				return s, nil
+			}),
+		},
+		{
+			Name:        "parse_float",
+			Description: "Parse a plain decimal number; the value is kept when it is not a number",
+			Apply: stringTransform(func(s string) (interface{}, error) {
+				if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+					return f, nil
+				}
+				return s, nil
+			}),
+		},
+		{
+			Name:        "clean_html",
+			Description: "Strip HTML tags and collapse whitespace",
+			Apply: stringTransform(func(s string) (interface{}, error) {
+				cleaned := htmlTagPattern.ReplaceAllString(s, " ")
+				return strings.TrimSpace(whitespacePattern.ReplaceAllString(cleaned, " ")), nil
+			}),
+		},
+		{
+			Name:        "extract_price",
+			Description: "Extract a price amount such as ¥123,456, or 1.234,56 € with a locale; the value is kept when no number is found",
+			Params:      []TransformParam{localeParam},
+			Validate:    validateLocaleParam,
+			Apply: func(value interface{}, params map[string]interface{}, tc *TransformContext) (interface{}, error) {
+				s, ok := value.(string)
+				if !ok {
+					return value, nil
+				}
+				// Without a locale commas are grouping, as extract_price always treated them
+				decimal := "."
+				if locale := paramString(params, "locale"); locale != "" {
+					decimal = decimalSeparator(locale)
+				}
+				if f, err := schema.ParseLocaleNumber(s, decimal); err == nil {
+					return f, nil
+				}
+				return s, nil
+			},
+		},
+		{
+			Name:        "parse_number",
+			Description: "Parse a locale-formatted number such as 1.234,56 or 1,234.56",
+			Params: []TransformParam{
+				localeParam,
+				{Name: "decimal", Type: TransformParamString, Description: "Decimal separator, \".\" or \",\"; overrides locale"},
+			},
+			Validate: func(params map[string]interface{}) error {
+				if d := paramString(params, "decimal"); d != "" && d != "." && d != "," {
+					return fmt.Errorf("decimal must be \".\" or \",\"")
+				}
+				return validateLocaleParam(params)
+			},
+			Apply: func(value interface{}, params map[string]interface{}, tc *TransformContext) (interface{}, error) {
+				switch v := value.(type) {
+				case float64, int, int64:
+					return v, nil
+				case string:
+					decimal := paramString(params, "decimal")
+					if decimal == "" {
+						decimal = decimalSeparator(paramString(params, "locale"))
+					}
+					return schema.ParseLocaleNumber(v, decimal)
+				}
+				return nil, fmt.Errorf("cannot parse %T as a number", value)
+			},
+		},
+		{
+			Name:        "parse_currency",
+			Description: "Parse an amount and its currency into {amount, currency}",
+			Params: []TransformParam{
+				localeParam,
+				{Name: "currency", Type: TransformParamString, Description: "ISO code used when none is detected"},
+			},
+			Validate: func(params map[string]interface{}) error {
+				if c := paramString(params, "currency"); c != "" && len(c) != 3 {
+					return fmt.Errorf("currency must be a 3-letter ISO code")
+				}
+				return validateLocaleParam(params)
+			},
+			Apply: stringParamTransform(parseCurrency),
+		},
+		{
+			Name:        "parse_date",
+			Description: "Parse a localized date such as 5. März 2024 or 2024年3月5日",
+			Params: []TransformParam{
+				localeParam,
+				{Name: "layout", Type: TransformParamStringList, Description: "Go layouts to try before the locale defaults"},
+				{Name: "output", Type: TransformParamString, Description: "\"date\", \"datetime\" (default) or a Go layout"},
+			},
+			Validate: validateLocaleParam,
+			Apply:    stringParamTransform(parseDate),
+		},
+		{
+			Name:        "absolute_url",
+			Description: "Resolve a relative URL against the page URL",
+			Params:      []TransformParam{{Name: "base", Type: TransformParamString, Description: "Base URL; defaults to the page URL"}},
+			Validate: func(params map[string]interface{}) error {
+				if base := paramString(params, "base"); base != "" {
+					if u, err := url.Parse(base); err != nil || !u.IsAbs() {
+						return fmt.Errorf("base must be an absolute URL")
+					}
+				}
+				return nil
+			},
+			Apply: stringParamTransform(func(s string, params map[string]interface{}, tc *TransformContext) (interface{}, error) {
+				return absoluteURL(s, paramString(params, "base"), tc.BaseURL)
+			}),
+		},
+		{
+			Name:        "normalize_unit",
+			Description: "Convert a quantity such as 1,5 kg or 12 in to a number in the target unit",
+			Params: []TransformParam{
+				{Name: "to", Type: TransformParamString, Required: true, Description: "Target unit, e.g. g, kg, cm, ml"},
+				{Name: "from", Type: TransformParamString, Description: "Unit assumed when the value has none"},
+				localeParam,
+			},
+			Validate: func(params map[string]interface{}) error {
+				to, ok := lookupUnit(paramString(params, "to"))
+				if !ok {
+					return fmt.Errorf("unknown unit '%s'", paramString(params, "to"))
+				}
+				if fromName := paramString(params, "from"); fromName != "" {
+					from, ok := lookupUnit(fromName)
+					if !ok {
+						return fmt.Errorf("unknown unit '%s'", fromName)
+					}
+					if from.dimension != to.dimension {
+						return fmt.Errorf("cannot convert %s to %s", from.canonical, to.canonical)
+					}
+				}
+				return validateLocaleParam(params)
+			},
+			Apply: stringParamTransform(func(s string, params map[string]interface{}, tc *TransformContext) (interface{}, error) {
+				return normalizeUnit(s, paramString(params, "to"), paramString(params, "from"), paramString(params, "locale"))
+			}),
+		},
+		{
+			Name:        "html_to_markdown",
+			Description: "Convert HTML to markdown, resolving links against the page URL",
+			Apply: stringParamTransform(func(s string, params map[string]interface{}, tc *TransformContext) (interface{}, error) {
+				return HTMLToMarkdown(s, tc.BaseURL)
+			}),
+		},
+		{
+			Name:        "expression",
+			Description: "Compute a value with a JavaScript expression over value and url, e.g. value * 1.1",
+			Params:      []TransformParam{{Name: "expression", Type: TransformParamString, Required: true}},
+			Validate: func(params map[string]interface{}) error {
+				return expression.Validate(paramString(params, "expression"))
+			},
+			Apply: func(value interface{}, params map[string]interface{}, tc *TransformContext) (interface{}, error) {
+				env := expression.Env{"url": map[string]interface{}{"url": tc.BaseURL}}.With("value", value)
+				return expression.Eval(paramString(params, "expression"), env)
+			},
+		},
+		{
+			Name:        "default_if_empty",
+			Description: "Replace empty text, null or an empty array with a default value",
+			Params:      []TransformParam{{Name: "value", Type: TransformParamAny, Required: true}},
+			Arrays:      true,
+			Apply: func(value interface{}, params map[string]interface{}, tc *TransformContext) (interface{}, error) {
+				if isEmptyValue(value) {
+					return params["value"], nil
+				}
+				return value, nil
+			},
+		},
+	}
+
+	transforms := make(map[string]Transform, len(list))
+	for _, t := range list {
+		t.builtin = true
+		transforms[t.Name] = t
+	}
+	return transforms
+}
+
+// stringTransform adapts a parameterless string function; non-string values pass through
+func stringTransform(fn func(s string) (interface{}, error)) TransformFunc {
+	return func(value interface{}, params map[string]interface{}, tc *TransformContext) (interface{}, error) {
+		if s, ok := value.(string); ok {
+			return fn(s)
+		}
+		return value, nil
+	}
+}
+
+// stringParamTransform adapts a string function that fails on non-string input
+func stringParamTransform(fn func(s string, params map[string]interface{}, tc *TransformContext) (interface{}, error)) TransformFunc {
+	return func(value interface{}, params map[string]interface{}, tc *TransformContext) (interface{}, error) {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected text, got %T", value)
+		}
+		return fn(s, params, tc)
+	}
+}
+
+// validateLocaleParam checks the optional locale parameter
+func validateLocaleParam(params map[string]interface{}) error {
+	return ValidateLocale(paramString(params, "locale"))
+}
+
+// parseCurrency parses an amount and detects its currency
+func parseCurrency(s string, params map[string]interface{}, tc *TransformContext) (interface{}, error) {
+	locale := paramString(params, "locale")
+	amount, err := schema.ParseLocaleNumber(s, decimalSeparator(locale))
+	if err != nil {
+		return nil, err
+	}
+
+	// The default currency, or else the locale, tells which krona "kr" is
+	hint := paramString(params, "currency")
+	if hint == "" {
+		language, _ := localeLanguage(locale)
+		hint = kronaByLanguage[language]
+	}
+	currency := schema.DetectCurrencyWithHint(s, hint)
+	if currency == "" {
+		currency = strings.ToUpper(paramString(params, "currency"))
+	}
+	// "¥" is shared by the yen and the yuan
+	if language, _ := localeLanguage(locale); currency == "JPY" && language == "zh" && !strings.Contains(s, "円") {
+		currency = "CNY"
+	}
+
+	result := map[string]interface{}{"amount": amount}
+	if currency != "" {
+		result["currency"] = currency
+	}
+	return result, nil
+}
+
+// parseDate parses a localized date and formats it as RFC 3339, a date or a custom layout
+func parseDate(s string, params map[string]interface{}, tc *TransformContext) (interface{}, error) {
+	locale := paramString(params, "locale")
+	raw := localiseDate(strings.TrimSpace(s), locale)
+
+	layouts := paramStringList(params, "layout")
+	layouts = append(layouts, dateLayoutsForLocale(locale)...)
+
+	t, err := schema.ParseDate(raw, layouts...)
+	if err != nil && len(layouts) > 0 {
+		t, err = schema.ParseDate(raw)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unrecognised date '%s'", s)
+	}
+
+	switch output := paramString(params, "output"); output {
+	case "", "datetime", "date-time":
+		return t.Format(time.RFC3339), nil
+	case "date":
+		return t.Format("2006-01-02"), nil
+	default:
+		return t.Format(output), nil
+	}
+}
+
+// absoluteURL resolves href against an explicit base or the page URL
+func absoluteURL(href, base, pageURL string) (interface{}, error) {
+	href = strings.TrimSpace(href)
+	if href == "" {
+		return href, nil
+	}
+	if base == "" {
+		base = pageURL
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL '%s': %w", href, err)
+	}
+	if ref.IsAbs() || base == "" {
+		return ref.String(), nil
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL '%s': %w", base, err)
+	}
+	return baseURL.ResolveReference(ref).String(), nil
+}
+
+// isEmptyValue reports nil, blank strings and empty arrays
+func isEmptyValue(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	if s, ok := value.(string); ok {
+		return strings.TrimSpace(s) == ""
+	}
+	if list, ok := asList(value); ok {
+		return len(list) == 0
+	}
+	return false
+}
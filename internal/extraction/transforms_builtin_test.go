@@ -0,0 +1,31 @@
+package extraction
+
+import "testing"
+
+func TestExtractPrice(t *testing.T) {
+	tests := []struct {
+		input  string
+		locale string
+		want   interface{}
+	}{
+		{"¥123,456", "", 123456.0},
+		{"$99.99", "", 99.99},
+		{"$1,299.00", "", 1299.0},
+		{"1.234,56 €", "de-DE", 1234.56},
+		{"no price", "", "no price"},
+	}
+	for _, tt := range tests {
+		params := map[string]interface{}{}
+		if tt.locale != "" {
+			params["locale"] = tt.locale
+		}
+		got, err := ApplyTransforms(tt.input, []TransformConfig{{Type: "extract_price", Params: params}}, nil)
+		if err != nil {
+			t.Errorf("extract_price(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("extract_price(%q, locale %q) = %v, want %v", tt.input, tt.locale, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package extraction
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/uzzalhcse/crawlify/internal/schema"
+)
+
+// unitDef describes a unit by dimension and factor relative to the dimension's base unit
+type unitDef struct {
+	canonical string
+	dimension string
+	factor    float64
+}
+
+// units maps unit spellings to definitions; base units are g, m, l and m2
+var units = buildUnitTable()
+
+// buildUnitTable expands unit definitions into a spelling lookup table
+func buildUnitTable() map[string]unitDef {
+	defs := []struct {
+		def     unitDef
+		aliases []string
+	}{
+		{unitDef{"mg", "mass", 0.001}, []string{"mg", "milligram", "milligrams"}},
+		{unitDef{"g", "mass", 1}, []string{"g", "gr", "gram", "grams", "gramm", "grammes", "グラム"}},
+		{unitDef{"kg", "mass", 1000}, []string{"kg", "kgs", "kilo", "kilos", "kilogram", "kilograms", "キロ"}},
+		{unitDef{"t", "mass", 1e6}, []string{"t", "tonne", "tonnes"}},
+		{unitDef{"oz", "mass", 28.349523125}, []string{"oz", "ounce", "ounces"}},
+		{unitDef{"lb", "mass", 453.59237}, []string{"lb", "lbs", "pound", "pounds"}},
+
+		{unitDef{"mm", "length", 0.001}, []string{"mm", "millimeter", "millimeters", "millimetre", "millimetres"}},
+		{unitDef{"cm", "length", 0.01}, []string{"cm", "centimeter", "centimeters", "centimetre", "centimetres"}},
+		{unitDef{"m", "length", 1}, []string{"m", "meter", "meters", "metre", "metres"}},
+		{unitDef{"km", "length", 1000}, []string{"km", "kilometer", "kilometers", "kilometre", "kilometres"}},
+		{unitDef{"in", "length", 0.0254}, []string{"in", "inch", "inches", "\"", "″"}},
+		{unitDef{"ft", "length", 0.3048}, []string{"ft", "foot", "feet", "'", "′"}},
+		{unitDef{"yd", "length", 0.9144}, []string{"yd", "yard", "yards"}},
+		{unitDef{"mi", "length", 1609.344}, []string{"mi", "mile", "miles"}},
+
+		{unitDef{"ml", "volume", 0.001}, []string{"ml", "milliliter", "milliliters", "millilitre", "millilitres"}},
+		{unitDef{"cl", "volume", 0.01}, []string{"cl", "centiliter", "centilitre"}},
+		{unitDef{"dl", "volume", 0.1}, []string{"dl", "deciliter", "decilitre"}},
+		{unitDef{"l", "volume", 1}, []string{"l", "liter", "liters", "litre", "litres"}},
+		{unitDef{"fl_oz", "volume", 0.0295735295625}, []string{"fl oz", "fl. oz", "fl_oz", "fluid ounce", "fluid ounces"}},
+		{unitDef{"gal", "volume", 3.785411784}, []string{"gal", "gallon", "gallons"}},
+
+		{unitDef{"cm2", "area", 0.0001}, []string{"cm2", "cm²", "sq cm"}},
+		{unitDef{"m2", "area", 1}, []string{"m2", "m²", "sqm", "sq m", "㎡"}},
+		{unitDef{"ft2", "area", 0.09290304}, []string{"ft2", "ft²", "sq ft", "sqft"}},
+	}
+	table := make(map[string]unitDef)
+	for _, d := range defs {
+		for _, alias := range d.aliases {
+			table[alias] = d.def
+		}
+	}
+	return table
+}
+
+// unitPattern captures the unit text that follows a number
+var unitPattern = regexp.MustCompile(`^\s*((?:fl\.?\s*oz|fluid ounces?|sq\.?\s*(?:ft|m|cm))|[\p{L}²㎡"'″′]+\d?)`)
+
+// lookupUnit resolves a unit spelling case-insensitively
+func lookupUnit(name string) (unitDef, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if def, ok := units[name]; ok {
+		return def, true
+	}
+	// Tolerate abbreviation dots and extra spacing ("fl. oz", "sq.  ft")
+	def, ok := units[strings.Join(strings.Fields(strings.ReplaceAll(name, ".", " ")), " ")]
+	return def, ok
+}
+
+// normalizeUnit parses a quantity like "1,5 kg" and converts it to the target unit
+func normalizeUnit(raw, to, from, locale string) (float64, error) {
+	target, ok := lookupUnit(to)
+	if !ok {
+		return 0, fmt.Errorf("unknown unit '%s'", to)
+	}
+
+	amount, err := schema.ParseLocaleNumber(raw, decimalSeparator(locale))
+	if err != nil {
+		return 0, err
+	}
+
+	source, found := unitAfterNumber(raw)
+	if !found {
+		if from == "" {
+			return 0, fmt.Errorf("no unit in '%s'", raw)
+		}
+		source, _ = lookupUnit(from)
+	}
+	if source.dimension != target.dimension {
+		return 0, fmt.Errorf("cannot convert %s to %s", source.canonical, target.canonical)
+	}
+	// Round away floating point noise from the conversion factors
+	return math.Round(amount*source.factor/target.factor*1e9) / 1e9, nil
+}
+
+// numberPrefix matches the number preceding a unit
+var numberPrefix = regexp.MustCompile(`-?\d[\d., \x{00A0}]*`)
+
+// unitAfterNumber finds the unit written directly after the first number
+func unitAfterNumber(raw string) (unitDef, bool) {
+	loc := numberPrefix.FindStringIndex(raw)
+	if loc == nil {
+		return unitDef{}, false
+	}
+	match := unitPattern.FindStringSubmatch(raw[loc[1]:])
+	if match == nil {
+		return unitDef{}, false
+	}
+	return lookupUnit(match[1])
+}
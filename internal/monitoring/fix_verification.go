@@ -0,0 +1,393 @@
+package monitoring
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+	"github.com/uzzalhcse/crawlify/internal/browser"
+	"github.com/uzzalhcse/crawlify/internal/logger"
+	"github.com/uzzalhcse/crawlify/internal/schema"
+	"github.com/uzzalhcse/crawlify/internal/workflow/nodes"
+	"github.com/uzzalhcse/crawlify/pkg/models"
+	"go.uber.org/zap"
+)
+
+// Fix verification page sources
+const (
+	FixSourceAuto  = "auto" // live, then mhtml, then dom
+	FixSourceLive  = "live"
+	FixSourceMHTML = "mhtml"
+	FixSourceDOM   = "dom"
+)
+
+// minBaselineSimilarity is the similarity to the baseline preview a replayed value needs to pass
+const minBaselineSimilarity = 0.5
+
+// FixVerification describes a suggested fix to replay
+type FixVerification struct {
+	Workflow        *models.Workflow
+	Node            *models.Node // Params already merged with the suggested config
+	Field           string       // Extract field the fix targets; "" checks the whole node output
+	PageURL         string       // Page the failure was captured on, replayed live
+	MHTMLPath       string       // Full path of the MHTML snapshot, if one was captured
+	DOMPath         string       // Full path of the DOM snapshot, the last resort
+	BaselinePreview []string     // What the node extracted when it last worked
+	Source          string       // FixSource*; "" is auto
+}
+
+// VerifyFix replays the node with the suggested params in a browser and checks what it extracts
+// against the expected type and the baseline. The page is loaded live first, then from the MHTML
+// snapshot, then from the DOM snapshot, so JavaScript-rendered content is tested where it can be.
+func (o *Orchestrator) VerifyFix(ctx context.Context, req *FixVerification) *models.VerificationResult {
+	now := time.Now()
+	result := &models.VerificationResult{
+		Method:      models.VerificationMethodNodeReplay,
+		Field:       req.Field,
+		DataPreview: []string{},
+		VerifiedAt:  &now,
+	}
+
+	if err := o.ValidateNodeParams(req.Node); err != nil {
+		result.ErrorMessage = fmt.Sprintf("suggested params are invalid: %v", err)
+		return result
+	}
+	executor, err := o.registry.Get(req.Node.Type)
+	if err != nil {
+		result.ErrorMessage = err.Error()
+		return result
+	}
+
+	browserCtx, err := o.browserPool.Acquire(ctx)
+	if err != nil {
+		result.ErrorMessage = fmt.Sprintf("failed to acquire browser: %v", err)
+		return result
+	}
+	defer o.browserPool.Release(browserCtx)
+
+	source, pageURL, err := o.loadFixPage(browserCtx, req)
+	if err != nil {
+		result.ErrorMessage = err.Error()
+		return result
+	}
+	result.Source = source
+
+	startURLs := []string{}
+	if req.Workflow != nil {
+		startURLs = req.Workflow.Config.StartURLs
+	}
+	execCtx := models.NewExecutionContext()
+	params, err := o.resolveTemplateVariables(req.Node, pageURL, &execCtx, startURLs)
+	if err != nil {
+		result.ErrorMessage = err.Error()
+		return result
+	}
+
+	output, err := executor.Execute(ctx, &nodes.ExecutionInput{
+		BrowserContext:   browserCtx,
+		ExecutionContext: &execCtx,
+		Params:           params,
+		URLItem:          &models.URLQueueItem{URL: pageURL},
+		NodeID:           req.Node.ID,
+	})
+	if err != nil {
+		result.ErrorMessage = fmt.Sprintf("node failed: %v", err)
+		return result
+	}
+
+	value := fixOutputValue(output, req.Field)
+	values := flattenValues(value)
+	result.ExtractedValue = value
+	result.ElementsFound = len(values)
+	for i, v := range values {
+		if i >= 5 {
+			break
+		}
+		if len(v) > 100 {
+			v = v[:97] + "..."
+		}
+		result.DataPreview = append(result.DataPreview, v)
+	}
+
+	if result.ElementsFound == 0 {
+		result.Issues = append(result.Issues, "node extracted nothing")
+	}
+
+	// Type check against the declared schema, or what the baseline looked like
+	field := expectedField(req)
+	if field == nil {
+		field = inferField(req.BaselinePreview)
+	}
+	result.ExpectedType = string(field.Type)
+	result.TypeMatches = true
+	if result.ElementsFound > 0 {
+		check := &models.ItemSchema{Fields: map[string]*models.SchemaField{"value": field}}
+		_, errs := schema.Apply(check, map[string]interface{}{"value": value}, pageURL)
+		for _, fieldErr := range errs {
+			result.TypeMatches = false
+			result.Issues = append(result.Issues, fmt.Sprintf("expected %s: %s", field.Type, fieldErr.Message))
+		}
+	}
+
+	if len(req.BaselinePreview) > 0 && len(values) > 0 {
+		similarity := baselineSimilarity(values, req.BaselinePreview)
+		result.BaselineSimilarity = &similarity
+		if similarity < minBaselineSimilarity {
+			result.Issues = append(result.Issues, fmt.Sprintf("value differs from the baseline (similarity %.2f)", similarity))
+		}
+	}
+
+	result.IsValid = result.ElementsFound > 0 && result.TypeMatches &&
+		(result.BaselineSimilarity == nil || *result.BaselineSimilarity >= minBaselineSimilarity)
+	if !result.IsValid && result.ErrorMessage == "" {
+		result.ErrorMessage = strings.Join(result.Issues, "; ")
+	}
+
+	logger.Info("Fix verified by node replay",
+		zap.String("node_id", req.Node.ID),
+		zap.String("source", result.Source),
+		zap.Bool("is_valid", result.IsValid),
+		zap.Int("elements_found", result.ElementsFound))
+
+	return result
+}
+
+// ValidateNodeParams checks a node's params with its executor's Validate
+func (o *Orchestrator) ValidateNodeParams(node *models.Node) error {
+	executor, err := o.registry.Get(node.Type)
+	if err != nil {
+		return err
+	}
+	return executor.Validate(node.Params)
+}
+
+// loadFixPage loads the page to replay on and returns the source used and the page's URL
+func (o *Orchestrator) loadFixPage(browserCtx *browser.BrowserContext, req *FixVerification) (string, string, error) {
+	source := req.Source
+	if source == "" {
+		source = FixSourceAuto
+	}
+
+	var failures []string
+	try := func(name string, load func() error) bool {
+		if source != FixSourceAuto && source != name {
+			return false
+		}
+		if err := load(); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", name, err))
+			return false
+		}
+		return true
+	}
+
+	if req.PageURL != "" && try(FixSourceLive, func() error {
+		resp, err := browserCtx.Navigate(req.PageURL)
+		if err != nil {
+			return err
+		}
+		if resp != nil && resp.Status() >= 400 {
+			return fmt.Errorf("HTTP %d", resp.Status())
+		}
+		// Give client-side rendering a chance; a page that never idles is still usable
+		_ = browserCtx.Page.WaitForLoadState(playwright.PageWaitForLoadStateOptions{
+			State:   playwright.LoadStateNetworkidle,
+			Timeout: playwright.Float(10000),
+		})
+		return nil
+	}) {
+		return FixSourceLive, req.PageURL, nil
+	}
+
+	if req.MHTMLPath != "" && try(FixSourceMHTML, func() error {
+		path, err := filepath.Abs(req.MHTMLPath)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Stat(path); err != nil {
+			return err
+		}
+		_, err = browserCtx.Navigate("file://" + filepath.ToSlash(path))
+		return err
+	}) {
+		return FixSourceMHTML, req.PageURL, nil
+	}
+
+	if req.DOMPath != "" && try(FixSourceDOM, func() error {
+		html, err := readDOMFile(req.DOMPath)
+		if err != nil {
+			return err
+		}
+		return browserCtx.Page.SetContent(html)
+	}) {
+		return FixSourceDOM, req.PageURL, nil
+	}
+
+	if len(failures) == 0 {
+		return "", "", fmt.Errorf("no page available to replay on (source %s)", source)
+	}
+	return "", "", fmt.Errorf("could not load the page: %s", strings.Join(failures, "; "))
+}
+
+// readDOMFile reads a DOM snapshot, gzipped or plain
+func readDOMFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var reader io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return "", err
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// fixOutputValue picks the part of a node's output the fix is about
+func fixOutputValue(output *nodes.ExecutionOutput, field string) interface{} {
+	if output == nil {
+		return nil
+	}
+	if field != "" {
+		if fields, ok := output.Result.(map[string]interface{}); ok {
+			return fields[field]
+		}
+	}
+	if output.Result == nil && len(output.DiscoveredURLs) > 0 {
+		return output.DiscoveredURLs
+	}
+	return output.Result
+}
+
+// flattenValues lists the non-empty scalar values in an extracted value
+func flattenValues(value interface{}) []string {
+	var values []string
+	switch v := value.(type) {
+	case nil:
+	case string:
+		if s := strings.TrimSpace(v); s != "" {
+			values = append(values, s)
+		}
+	case []string:
+		for _, item := range v {
+			values = append(values, flattenValues(item)...)
+		}
+	case []interface{}:
+		for _, item := range v {
+			values = append(values, flattenValues(item)...)
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			values = append(values, flattenValues(item)...)
+		}
+	default:
+		values = append(values, fmt.Sprint(v))
+	}
+	return values
+}
+
+// expectedField finds the output schema field the fix targets
+func expectedField(req *FixVerification) *models.SchemaField {
+	if req.Field == "" || req.Workflow == nil {
+		return nil
+	}
+	for i := range req.Workflow.Config.Phases {
+		phase := &req.Workflow.Config.Phases[i]
+		for _, node := range phase.Nodes {
+			if node.ID != req.Node.ID {
+				continue
+			}
+			if s := schema.ForPhase(&req.Workflow.Config, phase); s != nil {
+				return s.Fields[req.Field]
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// inferField guesses the expected type from the baseline values; without them any text passes
+func inferField(baseline []string) *models.SchemaField {
+	if len(baseline) == 0 {
+		return &models.SchemaField{Type: models.FieldTypeString}
+	}
+	numbers, urls := 0, 0
+	for _, v := range baseline {
+		if _, err := schema.ParseNumber(v); err == nil {
+			numbers++
+		}
+		if strings.HasPrefix(v, "http://") || strings.HasPrefix(v, "https://") || strings.HasPrefix(v, "/") {
+			urls++
+		}
+	}
+	switch {
+	case numbers == len(baseline):
+		return &models.SchemaField{Type: models.FieldTypeDecimal}
+	case urls == len(baseline):
+		return &models.SchemaField{Type: models.FieldTypeURL}
+	}
+	return &models.SchemaField{Type: models.FieldTypeString}
+}
+
+// baselineSimilarity scores how much the replayed values look like the baseline, from 0 to 1
+// A fix on a changed page rarely extracts the same text, so values of the same shape score too.
+func baselineSimilarity(values, baseline []string) float64 {
+	total := 0.0
+	for _, want := range baseline {
+		best := 0.0
+		for _, got := range values {
+			if score := valueSimilarity(got, want); score > best {
+				best = score
+			}
+		}
+		total += best
+	}
+	return total / float64(len(baseline))
+}
+
+// valueSimilarity scores one extracted value against one baseline value
+func valueSimilarity(got, want string) float64 {
+	got, want = strings.ToLower(strings.TrimSpace(got)), strings.ToLower(strings.TrimSpace(want))
+	want = strings.TrimSuffix(want, "...")
+	switch {
+	case got == want:
+		return 1
+	case got != "" && want != "" && (strings.Contains(got, want) || strings.Contains(want, got)):
+		return 0.8
+	}
+
+	_, gotErr := schema.ParseNumber(got)
+	_, wantErr := schema.ParseNumber(want)
+	if gotErr == nil && wantErr == nil {
+		return 0.6
+	}
+	if gotErr == nil || wantErr == nil {
+		return 0
+	}
+
+	// Both text: comparable lengths suggest the same kind of content
+	shorter, longer := len(got), len(want)
+	if shorter > longer {
+		shorter, longer = longer, shorter
+	}
+	if longer > 0 && float64(shorter)/float64(longer) >= 0.3 {
+		return 0.5
+	}
+	return 0
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/uzzalhcse/crawlify/internal/browser"
+	"github.com/uzzalhcse/crawlify/internal/expression"
 	"github.com/uzzalhcse/crawlify/internal/logger"
 	"github.com/uzzalhcse/crawlify/internal/workflow"
 	"github.com/uzzalhcse/crawlify/internal/workflow/nodes"
@@ -276,8 +277,22 @@ func (o *Orchestrator) validateNode(ctx context.Context, wf *models.Workflow, no
 		validator = NewGenericValidator(node.Type)
 	}
 
-	// Resolve template variables in params before validation
-	resolvedParams := o.resolveTemplateVariables(node.Params, testURL, wf.Config.StartURLs)
+	// Resolve template expressions in params before validation
+	resolvedParams, err := o.resolveTemplateVariables(node, testURL, execCtx, wf.Config.StartURLs)
+	if err != nil {
+		return models.NodeValidationResult{
+			NodeID:   node.ID,
+			NodeName: node.Name,
+			NodeType: string(node.Type),
+			Status:   models.ValidationStatusFail,
+			Metrics:  make(map[string]interface{}),
+			Issues: []models.ValidationIssue{{
+				Severity: "critical",
+				Code:     "TEMPLATE_INVALID",
+				Message:  err.Error(),
+			}},
+		}
+	}
 
 	// Run validation
 	input := &nodes.ValidationInput{
@@ -354,53 +369,18 @@ func (o *Orchestrator) determineOverallStatus(summary *models.MonitoringSummary)
 	return models.MonitoringStatusHealthy
 }
 
-// resolveTemplateVariables resolves template placeholders in node params
-// Supports {{start_url}} and {{current_url}} placeholders
-func (o *Orchestrator) resolveTemplateVariables(params map[string]interface{}, testURL string, startURLs []string) map[string]interface{} {
-	if params == nil {
-		return nil
+// resolveTemplateVariables expands {{ expression }} templates in the node's expression and URL params
+// Templates are sandboxed expressions over url, vars, current_url and start_url; other params only
+// resolve the exact placeholders "{{current_url}}" and "{{start_url}}"
+func (o *Orchestrator) resolveTemplateVariables(node *models.Node, testURL string, execCtx *models.ExecutionContext, startURLs []string) (map[string]interface{}, error) {
+	startURL := ""
+	if len(startURLs) > 0 {
+		startURL = startURLs[0]
 	}
+	env := expression.NewEnv(nil, execCtx, &models.URLQueueItem{URL: testURL}).
+		With("current_url", testURL).
+		With("start_url", startURL)
 
-	// Deep copy params to avoid mutating original configuration
-	resolvedParams := make(map[string]interface{})
-	for key, value := range params {
-		resolvedParams[key] = o.resolveValue(value, testURL, startURLs)
-	}
-
-	return resolvedParams
-}
-
-// resolveValue recursively resolves template variables in any value type
-func (o *Orchestrator) resolveValue(value interface{}, testURL string, startURLs []string) interface{} {
-	switch v := value.(type) {
-	case string:
-		// Replace template variables
-		if v == "{{current_url}}" {
-			return testURL
-		}
-		if v == "{{start_url}}" && len(startURLs) > 0 {
-			return startURLs[0]
-		}
-		return v
-
-	case map[string]interface{}:
-		// Recursively resolve nested maps
-		resolvedMap := make(map[string]interface{})
-		for k, val := range v {
-			resolvedMap[k] = o.resolveValue(val, testURL, startURLs)
-		}
-		return resolvedMap
-
-	case []interface{}:
-		// Recursively resolve arrays
-		resolvedArray := make([]interface{}, len(v))
-		for i, val := range v {
-			resolvedArray[i] = o.resolveValue(val, testURL, startURLs)
-		}
-		return resolvedArray
-
-	default:
-		// Return other types as-is (numbers, bools, etc.)
-		return v
-	}
+	// ExpandParams copies params, so the original configuration is never mutated
+	return expression.ExpandParams(node.Params, env, workflow.ExpressionParams(node.Type)...)
 }
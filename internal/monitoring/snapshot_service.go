@@ -96,6 +96,14 @@ func (s *SnapshotService) CaptureSnapshot(
 		snapshot.DOMSnapshotPath = &domPath
 	}
 
+	// Capture MHTML so a fix can be replayed later against the page as it was, scripts' output included
+	mhtmlPath, err := s.captureMHTMLSnapshot(browserCtx, snapshotDir, nodeID)
+	if err != nil {
+		s.logger.Warn("Failed to capture MHTML", zap.Error(err))
+	} else {
+		snapshot.MetadataData["mhtml_path"] = mhtmlPath
+	}
+
 	// Capture console logs
 	consoleLogs := s.captureConsoleLogs(browserCtx)
 	snapshot.ConsoleLogsData = consoleLogs
@@ -249,6 +257,35 @@ func (s *SnapshotService) captureDOMSnapshot(browserCtx *browser.BrowserContext,
 	return relativePath, nil
 }
 
+// captureMHTMLSnapshot saves the rendered page with its resources as one MHTML file
+// Chromium loads it back from a file:// URL, which is how fix verification replays it.
+func (s *SnapshotService) captureMHTMLSnapshot(browserCtx *browser.BrowserContext, dir, nodeID string) (string, error) {
+	session, err := browserCtx.Context.NewCDPSession(browserCtx.Page)
+	if err != nil {
+		return "", fmt.Errorf("failed to open CDP session: %w", err)
+	}
+	defer session.Detach()
+
+	result, err := session.Send("Page.captureSnapshot", map[string]interface{}{"format": "mhtml"})
+	if err != nil {
+		return "", fmt.Errorf("failed to capture MHTML: %w", err)
+	}
+	data, _ := result.(map[string]interface{})["data"].(string)
+	if data == "" {
+		return "", fmt.Errorf("browser returned an empty MHTML snapshot")
+	}
+
+	filename := fmt.Sprintf("%s_page_%d.mhtml", nodeID, time.Now().Unix())
+	fullPath := filepath.Join(dir, filename)
+	if err := os.WriteFile(fullPath, []byte(data), 0644); err != nil {
+		return "", fmt.Errorf("failed to write MHTML: %w", err)
+	}
+
+	// Return relative path
+	relativePath := filepath.Join(filepath.Base(filepath.Dir(dir)), filepath.Base(dir), filename)
+	return relativePath, nil
+}
+
 // captureConsoleLogs captures browser console logs
 // Note: Playwright requires console event listeners to be set up before page load
 // This is a simplified version - for production, you'd need to set up listeners earlier
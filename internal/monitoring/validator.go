@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/uzzalhcse/crawlify/internal/browser"
+	"github.com/uzzalhcse/crawlify/internal/extraction"
 	"github.com/uzzalhcse/crawlify/internal/workflow/nodes"
 	"github.com/uzzalhcse/crawlify/pkg/models"
 )
@@ -32,7 +33,7 @@ func (v *GenericValidator) ValidateForMonitoring(ctx context.Context, input *nod
 	// Extract and validate all selector parameters
 	selectors := v.extractSelectorParams(input.Params)
 	for paramName, selector := range selectors {
-		issue := v.validateSelector(input.BrowserContext, paramName, selector)
+		issue := v.validateSelector(input.BrowserContext, paramName, selector.selector, selector.selectorType)
 		if issue != nil {
 			result.Issues = append(result.Issues, *issue)
 		}
@@ -47,15 +48,27 @@ func (v *GenericValidator) ValidateForMonitoring(ctx context.Context, input *nod
 	return result, nil
 }
 
+// typedSelector is a selector param paired with its selector type
+type typedSelector struct {
+	selector     string
+	selectorType string
+}
+
 // extractSelectorParams finds all selector-like parameters recursively
-func (v *GenericValidator) extractSelectorParams(params map[string]interface{}) map[string]string {
-	selectors := make(map[string]string)
+func (v *GenericValidator) extractSelectorParams(params map[string]interface{}) map[string]typedSelector {
+	selectors := make(map[string]typedSelector)
 
 	for key, value := range params {
-		// Check if parameter name contains "selector"
-		if strings.Contains(strings.ToLower(key), "selector") {
+		// Check if parameter name contains "selector" (selector_type params describe a selector)
+		lowerKey := strings.ToLower(key)
+		if strings.Contains(lowerKey, "selector") && !strings.HasSuffix(lowerKey, "_type") {
 			if str, ok := value.(string); ok && str != "" {
-				selectors[key] = str
+				// Prefer a dedicated <key>_type param, fall back to the sibling selector_type
+				selectorType := nodes.GetStringParam(params, key+"_type")
+				if selectorType == "" {
+					selectorType = nodes.GetStringParam(params, "selector_type")
+				}
+				selectors[key] = typedSelector{selector: str, selectorType: selectorType}
 			}
 		}
 
@@ -70,10 +83,20 @@ func (v *GenericValidator) extractSelectorParams(params map[string]interface{})
 	return selectors
 }
 
-// validateSelector validates a single CSS selector
-func (v *GenericValidator) validateSelector(browserCtx *browser.BrowserContext, paramName, selector string) *models.ValidationIssue {
+// validateSelector validates a single selector of the given selector type
+func (v *GenericValidator) validateSelector(browserCtx *browser.BrowserContext, paramName, selector, selectorType string) *models.ValidationIssue {
+	if err := extraction.ValidateSelector(selector, selectorType); err != nil {
+		return &models.ValidationIssue{
+			Severity:   "critical",
+			Code:       "SELECTOR_INVALID",
+			Message:    fmt.Sprintf("Parameter '%s': %v", paramName, err),
+			Selector:   selector,
+			Suggestion: "Check selector syntax and selector_type",
+		}
+	}
+
 	page := browserCtx.Page
-	locator := page.Locator(selector)
+	locator := page.Locator(extraction.PlaywrightSelector(selector, selectorType))
 
 	count, err := locator.Count()
 	if err != nil {
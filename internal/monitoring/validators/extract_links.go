@@ -38,7 +38,7 @@ func (v *ExtractLinksValidator) ValidateForMonitoring(ctx context.Context, input
 	}
 
 	page := input.BrowserContext.Page
-	locator := page.Locator(selector)
+	locator := page.Locator(nodes.ResolveSelector(input.Params, "selector", "selector_type"))
 	count, _ := locator.Count()
 
 	logger.Debug("extract_links validation",
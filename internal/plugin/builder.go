@@ -2,150 +2,644 @@ package plugin
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"debug/buildinfo"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/uzzalhcse/crawlify/internal/config"
+	"github.com/uzzalhcse/crawlify/internal/logger"
+	"github.com/uzzalhcse/crawlify/internal/storage"
+	"github.com/uzzalhcse/crawlify/pkg/models"
+	"go.uber.org/zap"
 )
 
-// BuildStatus represents the status of a build
-type BuildStatus string
+// ErrQueueFull is returned by Build when the build queue has no room
+var ErrQueueFull = errors.New("build queue is full")
 
-const (
-	BuildStatusQueued   BuildStatus = "queued"
-	BuildStatusBuilding BuildStatus = "building"
-	BuildStatusSuccess  BuildStatus = "success"
-	BuildStatusFailed   BuildStatus = "failed"
-)
+// maxLogBytes caps the stored log of a build; later output is dropped
+const maxLogBytes = 1 << 20
 
-// BuildJob represents a build job
-type BuildJob struct {
-	ID          string      `json:"id"`
-	PluginSlug  string      `json:"plugin_slug"`
-	Status      BuildStatus `json:"status"`
-	Log         string      `json:"log"`
-	Artifact    string      `json:"artifact"`
-	CreatedAt   time.Time   `json:"created_at"`
-	CompletedAt *time.Time  `json:"completed_at"`
+// BuildRequest describes a build to queue
+type BuildRequest struct {
+	PluginID        string
+	PluginSlug      string
+	PluginVersionID string // Records the artifact, hash and SBOM on this version when the build succeeds
+	Revision        int    // Source revision to build; 0 builds the working tree
+	TestsOnly       bool   // Only run the plugin's bundled tests
 }
 
 // Builder handles plugin building
+// Builds are persisted, queued with a concurrency cap and a timeout, and run in a copy of the
+// source with a shared module cache; builds of the same plugin never run at the same time.
 type Builder struct {
 	SourcePath string
 	OutputPath string
-	jobs       map[string]*BuildJob
-	mu         sync.RWMutex
+	sources    *SourceManager
+	repo       *storage.PluginBuildRepository
+	config     config.BuildConfig
+	queue      chan *activeBuild
+
+	mu      sync.RWMutex
+	active  map[string]*activeBuild // Queued and running builds
+	slugs   map[string]*sync.Mutex  // One build per plugin source at a time
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
 }
 
 // NewBuilder creates a new Builder
-func NewBuilder(sourcePath, outputPath string) *Builder {
+func NewBuilder(sources *SourceManager, outputPath string, repo *storage.PluginBuildRepository, cfg config.BuildConfig) *Builder {
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 50
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 900
+	}
 	return &Builder{
-		SourcePath: sourcePath,
+		SourcePath: sources.BasePath,
 		OutputPath: outputPath,
-		jobs:       make(map[string]*BuildJob),
+		sources:    sources,
+		repo:       repo,
+		config:     cfg,
+		queue:      make(chan *activeBuild, cfg.QueueSize),
+		active:     make(map[string]*activeBuild),
+		slugs:      make(map[string]*sync.Mutex),
+	}
+}
+
+// Start recovers builds interrupted by a restart and starts the build workers
+// Queued builds are queued again; builds that were running are marked failed.
+func (b *Builder) Start(ctx context.Context) error {
+	workerCtx, cancel := context.WithCancel(context.Background())
+	b.cancel = cancel
+	for i := 0; i < b.config.Concurrency; i++ {
+		b.workers.Add(1)
+		go b.worker(workerCtx)
+	}
+
+	unfinished, err := b.repo.ListUnfinished(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load unfinished builds: %w", err)
+	}
+
+	for _, build := range unfinished {
+		a := newActiveBuild(build)
+		if build.Status == models.BuildStatusBuilding {
+			b.finish(a, models.BuildStatusFailed, "\nBuild interrupted by a server restart")
+			continue
+		}
+		if err := b.enqueue(a); err != nil {
+			b.finish(a, models.BuildStatusFailed, "\nBuild dropped after a server restart: "+err.Error())
+		}
+	}
+	if len(unfinished) > 0 {
+		logger.Info("Recovered plugin builds", zap.Int("count", len(unfinished)))
+	}
+	return nil
+}
+
+// Stop cancels running builds, which are marked failed, and stops the workers
+func (b *Builder) Stop() {
+	if b.cancel == nil {
+		return
 	}
+	b.cancel()
+	b.workers.Wait()
 }
 
-// Build triggers a build for a plugin
-func (b *Builder) Build(pluginSlug string) (string, error) {
-	jobID := uuid.New().String()
-	job := &BuildJob{
-		ID:         jobID,
-		PluginSlug: pluginSlug,
-		Status:     BuildStatusQueued,
-		CreatedAt:  time.Now(),
+// Build queues a build for a plugin
+// The plugin's bundled tests run first; the build fails if any of them fails
+func (b *Builder) Build(ctx context.Context, req BuildRequest) (*models.PluginBuild, error) {
+	build := &models.PluginBuild{
+		ID:              uuid.New().String(),
+		PluginID:        req.PluginID,
+		PluginSlug:      req.PluginSlug,
+		PluginVersionID: req.PluginVersionID,
+		SourceRevision:  req.Revision,
+		Status:          models.BuildStatusQueued,
+		TestsOnly:       req.TestsOnly,
+		CreatedAt:       time.Now(),
+	}
+	if err := b.repo.Create(ctx, build); err != nil {
+		return nil, err
 	}
 
+	a := newActiveBuild(build)
+	if err := b.enqueue(a); err != nil {
+		b.finish(a, models.BuildStatusFailed, "Build rejected: "+err.Error())
+		return nil, err
+	}
+	snapshot := a.snapshot()
+	return &snapshot, nil
+}
+
+func (b *Builder) enqueue(a *activeBuild) error {
 	b.mu.Lock()
-	b.jobs[jobID] = job
+	b.active[a.build.ID] = a
 	b.mu.Unlock()
 
-	go b.runBuild(job)
+	select {
+	case b.queue <- a:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// GetBuildJob returns a build by ID
+func (b *Builder) GetBuildJob(ctx context.Context, buildID string) (*models.PluginBuild, error) {
+	if a := b.activeBuild(buildID); a != nil {
+		snapshot := a.snapshot()
+		return &snapshot, nil
+	}
+	return b.repo.GetByID(ctx, buildID)
+}
+
+// ListBuilds lists the builds of a plugin, newest first
+func (b *Builder) ListBuilds(ctx context.Context, pluginSlug string, limit int) ([]*models.PluginBuild, error) {
+	builds, err := b.repo.ListByPlugin(ctx, pluginSlug, limit)
+	if err != nil {
+		return nil, err
+	}
+	for i, build := range builds {
+		if a := b.activeBuild(build.ID); a != nil {
+			snapshot := a.snapshot()
+			builds[i] = &snapshot
+		}
+	}
+	return builds, nil
+}
 
-	return jobID, nil
+// Follow returns the current state of a queued or running build and a channel that is closed
+// on its next change. ok is false when the build is not active; it is finished or unknown.
+func (b *Builder) Follow(buildID string) (build models.PluginBuild, changed <-chan struct{}, ok bool) {
+	a := b.activeBuild(buildID)
+	if a == nil {
+		return models.PluginBuild{}, nil, false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.build, a.changed, true
 }
 
-// GetBuildJob returns a build job by ID
-func (b *Builder) GetBuildJob(jobID string) (*BuildJob, error) {
+func (b *Builder) activeBuild(buildID string) *activeBuild {
 	b.mu.RLock()
 	defer b.mu.RUnlock()
+	return b.active[buildID]
+}
 
-	job, ok := b.jobs[jobID]
-	if !ok {
-		return nil, fmt.Errorf("build job not found: %s", jobID)
+func (b *Builder) worker(ctx context.Context) {
+	defer b.workers.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			b.drain()
+			return
+		case a := <-b.queue:
+			b.run(ctx, a)
+		}
 	}
-	return job, nil
 }
 
-func (b *Builder) runBuild(job *BuildJob) {
-	// Update status to building
-	b.mu.Lock()
-	job.Status = BuildStatusBuilding
-	b.mu.Unlock()
+// drain stops tracking builds still queued at shutdown
+// They stay queued in the database and are queued again by the next Start.
+func (b *Builder) drain() {
+	for {
+		select {
+		case a := <-b.queue:
+			b.mu.Lock()
+			delete(b.active, a.build.ID)
+			b.mu.Unlock()
+		default:
+			return
+		}
+	}
+}
 
-	pluginDir := filepath.Join(b.SourcePath, job.PluginSlug)
+func (b *Builder) run(ctx context.Context, a *activeBuild) {
+	slugLock := b.slugLock(a.build.PluginSlug)
+	slugLock.Lock()
+	defer slugLock.Unlock()
 
-	// Ensure output directory exists
-	if err := exec.Command("mkdir", "-p", b.OutputPath).Run(); err != nil {
-		b.updateJobStatus(job, BuildStatusFailed, fmt.Sprintf("Failed to create output directory: %v", err))
-		return
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(b.config.Timeout)*time.Second)
+	defer cancel()
+
+	a.update(func(build *models.PluginBuild) {
+		now := time.Now()
+		build.Status = models.BuildStatusBuilding
+		build.StartedAt = &now
+	})
+	b.save(a)
+
+	status, message := b.execute(ctx, a)
+	if status == models.BuildStatusFailed && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		message = fmt.Sprintf("\nBuild timed out after %ds", b.config.Timeout)
+	} else if status == models.BuildStatusFailed && errors.Is(ctx.Err(), context.Canceled) {
+		message = "\nBuild interrupted by server shutdown"
 	}
+	b.finish(a, status, message)
+}
 
-	// Run go mod tidy first to ensure dependencies are downloaded
-	tidyCmd := exec.Command("go", "mod", "tidy")
-	tidyCmd.Dir = pluginDir
+// execute runs the steps of a build and returns its outcome and a closing log line
+func (b *Builder) execute(ctx context.Context, a *activeBuild) (models.BuildStatus, string) {
+	slug := a.build.PluginSlug
+	pluginDir, err := b.prepareSource(ctx, slug, a.build.SourceRevision)
+	if err != nil {
+		return models.BuildStatusFailed, fmt.Sprintf("Failed to prepare sources: %v", err)
+	}
+	defer os.RemoveAll(filepath.Dir(pluginDir))
+	if a.build.SourceRevision > 0 {
+		fmt.Fprintf(a, "Building source revision %d\n", a.build.SourceRevision)
+	}
 
-	var tidyStdout, tidyStderr bytes.Buffer
-	tidyCmd.Stdout = &tidyStdout
-	tidyCmd.Stderr = &tidyStderr
+	// The first tidy may download modules into the build module cache; every later step is read-only
+	fmt.Fprintf(a, "$ go mod tidy\n")
+	if err := b.command(ctx, pluginDir, a, false, "go", "mod", "tidy"); err != nil {
+		return models.BuildStatusFailed, fmt.Sprintf("\nFailed to run go mod tidy: %v", err)
+	}
 
-	if err := tidyCmd.Run(); err != nil {
-		errorMsg := fmt.Sprintf("Failed to run go mod tidy: %v\nStdout: %s\nStderr: %s",
-			err, tidyStdout.String(), tidyStderr.String())
-		b.updateJobStatus(job, BuildStatusFailed, errorMsg)
-		return
+	// Run the plugin's bundled tests; a regression fails the build before anything is replaced
+	if hasTests(pluginDir) {
+		fmt.Fprintf(a, "$ go test ./...\n")
+		tests := &testLog{build: a}
+		err := b.command(ctx, pluginDir, tests, true, "go", "test", "-count=1", "./...")
+		a.update(func(build *models.PluginBuild) {
+			build.Tests = models.TestStatusPassed
+			if err != nil {
+				build.Tests = models.TestStatusFailed
+			}
+		})
+		b.save(a)
+		if err != nil {
+			return models.BuildStatusFailed, fmt.Sprintf("\nPlugin tests failed: %v", err)
+		}
+	} else {
+		a.update(func(build *models.PluginBuild) { build.Tests = models.TestStatusSkipped })
+	}
+	if a.snapshot().TestsOnly {
+		return models.BuildStatusSuccess, "\nTests passed"
 	}
 
-	outputFile, err := filepath.Abs(filepath.Join(b.OutputPath, job.PluginSlug+".so"))
+	// Each build keeps its artifact; versions refer to it after the development build changes
+	artifact, err := filepath.Abs(filepath.Join(b.OutputPath, ".builds", a.build.ID, slug+".so"))
 	if err != nil {
-		b.updateJobStatus(job, BuildStatusFailed, fmt.Sprintf("Failed to resolve output path: %v", err))
-		return
+		return models.BuildStatusFailed, fmt.Sprintf("\nFailed to resolve output path: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(artifact), 0755); err != nil {
+		return models.BuildStatusFailed, fmt.Sprintf("\nFailed to create output directory: %v", err)
 	}
 
-	// Construct command: go build -buildmode=plugin -o <output> .
-	cmd := exec.Command("go", "build", "-buildmode=plugin", "-o", outputFile, ".")
-	cmd.Dir = pluginDir
+	// No -trimpath: packages shared with the crawler must compile exactly as they do in its build
+	fmt.Fprintf(a, "$ go build -buildmode=plugin -buildvcs=false -o %s .\n", artifact)
+	if err := b.command(ctx, pluginDir, a, true, "go", "build", "-buildmode=plugin", "-buildvcs=false", "-o", artifact, "."); err != nil {
+		return models.BuildStatusFailed, fmt.Sprintf("\nBuild failed: %v", err)
+	}
 
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+	hash, size, err := fileHashAndSize(artifact)
+	if err != nil {
+		return models.BuildStatusFailed, fmt.Sprintf("\nFailed to hash artifact: %v", err)
+	}
+	sbom, err := readSBOM(artifact)
+	if err != nil {
+		fmt.Fprintf(a, "Warning: no build info in artifact, SBOM is empty: %v\n", err)
+	}
+	a.update(func(build *models.PluginBuild) {
+		build.Artifact = artifact
+		build.ArtifactHash = hash
+		build.ArtifactSize = size
+		build.SBOM = sbom
+	})
 
-	err = cmd.Run()
+	if err := b.installDevBuild(artifact, slug); err != nil {
+		return models.BuildStatusFailed, fmt.Sprintf("\nFailed to install development build: %v", err)
+	}
+	return models.BuildStatusSuccess, fmt.Sprintf("\nBuilt %s (sha256 %s, %d bytes)", artifact, hash, size)
+}
 
-	logOutput := stdout.String() + "\n" + stderr.String()
+// prepareSource copies a plugin's source into the work directory and returns the copy
+// The copy always lives at the same path so builds of the same source are reproducible.
+// A revision other than 0 replaces the editable files of the copy with the revision's.
+// Relative replace directives are rewritten to point at the original tree.
+func (b *Builder) prepareSource(ctx context.Context, slug string, revision int) (string, error) {
+	src, err := filepath.Abs(filepath.Join(b.SourcePath, slug))
+	if err != nil {
+		return "", err
+	}
+	if info, err := os.Stat(src); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("plugin source directory not found: %s", slug)
+	}
+
+	workDir := b.config.WorkDir
+	if workDir == "" {
+		workDir = os.TempDir()
+	}
+	root := filepath.Join(workDir, "crawlify-builds", slug)
+	if err := os.RemoveAll(root); err != nil {
+		return "", err
+	}
+	dst := filepath.Join(root, slug)
+	if err := copyTree(src, dst); err != nil {
+		return "", err
+	}
+	if revision > 0 {
+		if err := b.sources.Materialize(ctx, slug, revision, dst); err != nil {
+			return "", fmt.Errorf("failed to check out source revision %d: %w", revision, err)
+		}
+	}
+
+	var mod struct {
+		Replace []struct {
+			Old struct{ Path, Version string }
+			New struct{ Path, Version string }
+		}
+	}
+	var out bytes.Buffer
+	if err := b.command(ctx, dst, &out, false, "go", "mod", "edit", "-json"); err != nil {
+		return "", fmt.Errorf("failed to read go.mod: %v\n%s", err, out.String())
+	}
+	if err := json.Unmarshal(out.Bytes(), &mod); err != nil {
+		return "", fmt.Errorf("failed to read go.mod: %w", err)
+	}
+	for _, r := range mod.Replace {
+		if r.New.Version != "" || filepath.IsAbs(r.New.Path) {
+			continue
+		}
+		old := r.Old.Path
+		if r.Old.Version != "" {
+			old += "@" + r.Old.Version
+		}
+		out.Reset()
+		if err := b.command(ctx, dst, &out, false, "go", "mod", "edit", "-replace", old+"="+filepath.Join(src, r.New.Path)); err != nil {
+			return "", fmt.Errorf("failed to rewrite replace %s: %v\n%s", old, err, out.String())
+		}
+	}
+	return dst, nil
+}
 
+// command runs a go command with the build environment, writing its output to w
+func (b *Builder) command(ctx context.Context, dir string, w io.Writer, readonly bool, name string, args ...string) error {
+	modCache, err := filepath.Abs(b.config.ModCache)
 	if err != nil {
-		b.updateJobStatus(job, BuildStatusFailed, logOutput+fmt.Sprintf("\nBuild failed: %v", err))
-	} else {
-		// Update job with success and artifact path
-		b.mu.Lock()
+		return err
+	}
+	goflags := ""
+	if readonly {
+		goflags = "-mod=readonly"
+	}
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(),
+		"GOMODCACHE="+modCache,
+		"GOFLAGS="+goflags,
+		"GOTOOLCHAIN=local", // Plugins must be built by the crawler's toolchain
+		"GOWORK=off",
+		"CGO_ENABLED=1",
+	)
+	cmd.Stdout = w
+	cmd.Stderr = w
+	// Compiler subprocesses may outlive a killed go command; stop waiting for their output
+	cmd.WaitDelay = 10 * time.Second
+	return cmd.Run()
+}
+
+// installDevBuild replaces the development build with a copy of the artifact
+func (b *Builder) installDevBuild(artifact, slug string) error {
+	dst := filepath.Join(b.OutputPath, slug+".so")
+	tmp := dst + ".tmp"
+	if err := copyFile(artifact, tmp, 0755); err != nil {
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// finish records the outcome of a build and stops tracking it
+func (b *Builder) finish(a *activeBuild, status models.BuildStatus, message string) {
+	a.update(func(build *models.PluginBuild) {
 		now := time.Now()
-		job.Status = BuildStatusSuccess
-		job.CompletedAt = &now
-		job.Log = logOutput
-		job.Artifact = outputFile
-		b.mu.Unlock()
+		build.Status = status
+		build.CompletedAt = &now
+		appendLog(&build.Log, message)
+	})
+	build := a.snapshot()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := b.repo.Finish(ctx, &build, runtime.GOOS, runtime.GOARCH); err != nil {
+		logger.Error("Failed to save plugin build", zap.String("build_id", build.ID), zap.Error(err))
+	}
+
+	b.mu.Lock()
+	delete(b.active, build.ID)
+	b.mu.Unlock()
+	a.close()
+}
+
+// save persists the progress of a build
+func (b *Builder) save(a *activeBuild) {
+	build := a.snapshot()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := b.repo.Update(ctx, &build); err != nil {
+		logger.Error("Failed to save plugin build", zap.String("build_id", build.ID), zap.Error(err))
 	}
 }
 
-func (b *Builder) updateJobStatus(job *BuildJob, status BuildStatus, log string) {
+func (b *Builder) slugLock(slug string) *sync.Mutex {
 	b.mu.Lock()
 	defer b.mu.Unlock()
-	now := time.Now()
-	job.Status = status
-	job.CompletedAt = &now
-	job.Log = log
+	lock, ok := b.slugs[slug]
+	if !ok {
+		lock = &sync.Mutex{}
+		b.slugs[slug] = lock
+	}
+	return lock
+}
+
+// activeBuild is a queued or running build with its live log
+// Writes append to the log; every change closes changed and replaces it.
+type activeBuild struct {
+	mu      sync.Mutex
+	build   models.PluginBuild
+	changed chan struct{}
+	closed  bool
+}
+
+func newActiveBuild(build *models.PluginBuild) *activeBuild {
+	return &activeBuild{build: *build, changed: make(chan struct{})}
+}
+
+func (a *activeBuild) Write(p []byte) (int, error) {
+	a.update(func(build *models.PluginBuild) { appendLog(&build.Log, string(p)) })
+	return len(p), nil
+}
+
+func (a *activeBuild) update(fn func(*models.PluginBuild)) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	fn(&a.build)
+	if !a.closed {
+		close(a.changed)
+		a.changed = make(chan struct{})
+	}
+}
+
+func (a *activeBuild) snapshot() models.PluginBuild {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.build
+}
+
+func (a *activeBuild) close() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if !a.closed {
+		a.closed = true
+		close(a.changed)
+	}
+}
+
+// testLog writes test output to both the build log and the test log
+type testLog struct {
+	build *activeBuild
+}
+
+func (t *testLog) Write(p []byte) (int, error) {
+	t.build.update(func(build *models.PluginBuild) {
+		appendLog(&build.Log, string(p))
+		appendLog(&build.TestLog, string(p))
+	})
+	return len(p), nil
+}
+
+func appendLog(log *string, text string) {
+	if len(*log) >= maxLogBytes {
+		return
+	}
+	if len(*log)+len(text) > maxLogBytes {
+		text = text[:maxLogBytes-len(*log)] + "\n[log truncated]\n"
+	}
+	*log += text
+}
+
+// readSBOM lists the Go version, modules and build settings embedded in a binary
+func readSBOM(path string) (models.PluginSBOM, error) {
+	info, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return models.PluginSBOM{}, err
+	}
+	sbom := models.PluginSBOM{GoVersion: info.GoVersion, Settings: make(map[string]string)}
+	if info.Main.Path != "" {
+		main := sbomModule(&info.Main)
+		sbom.Module = &main
+	}
+	for _, dep := range info.Deps {
+		sbom.Deps = append(sbom.Deps, sbomModule(dep))
+	}
+	for _, setting := range info.Settings {
+		sbom.Settings[setting.Key] = setting.Value
+	}
+	return sbom, nil
+}
+
+func sbomModule(m *debug.Module) models.SBOMModule {
+	module := models.SBOMModule{Path: m.Path, Version: m.Version, Sum: m.Sum}
+	if m.Replace != nil {
+		replace := sbomModule(m.Replace)
+		module.Replace = &replace
+	}
+	return module
+}
+
+func hasTests(pluginDir string) bool {
+	found := false
+	filepath.WalkDir(pluginDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && d.Name() == "testdata" {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && strings.HasSuffix(d.Name(), "_test.go") {
+			found = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found
+}
+
+// copyTree copies a source directory, skipping version control metadata
+func copyTree(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return os.MkdirAll(target, 0755)
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func fileHashAndSize(path string) (string, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
 }
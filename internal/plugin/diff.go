@@ -0,0 +1,247 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/uzzalhcse/crawlify/pkg/models"
+)
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 3
+
+// maxDiffEdits bounds the work of the line diff; files that differ more are shown as replaced
+const maxDiffEdits = 2000
+
+// DiffFiles compares two source trees and returns their unified diff and a summary per changed file
+func DiffFiles(from, to map[string]string) (string, []models.SourceFileChange) {
+	paths := make([]string, 0, len(from)+len(to))
+	for p := range from {
+		paths = append(paths, p)
+	}
+	for p := range to {
+		if _, ok := from[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	var out strings.Builder
+	changes := []models.SourceFileChange{}
+	for _, p := range paths {
+		oldText, inFrom := from[p]
+		newText, inTo := to[p]
+		if inFrom && inTo && oldText == newText {
+			continue
+		}
+
+		change := models.SourceFileChange{Path: p, Status: models.SourceFileModified}
+		oldName, newName := "a/"+p, "b/"+p
+		switch {
+		case !inFrom:
+			change.Status = models.SourceFileAdded
+			oldName = "/dev/null"
+		case !inTo:
+			change.Status = models.SourceFileDeleted
+			newName = "/dev/null"
+		}
+
+		ops := diffLines(splitLines(oldText), splitLines(newText))
+		for _, op := range ops {
+			switch op.kind {
+			case '+':
+				change.Additions++
+			case '-':
+				change.Deletions++
+			}
+		}
+		changes = append(changes, change)
+
+		fmt.Fprintf(&out, "diff --git a/%s b/%s\n--- %s\n+++ %s\n", p, p, oldName, newName)
+		writeHunks(&out, ops)
+	}
+	return out.String(), changes
+}
+
+// diffOp is one line of an edit script: ' ' kept, '-' removed, '+' added
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// splitLines splits text into lines without their line breaks
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// diffLines returns a shortest edit script turning a into b (Myers' algorithm)
+func diffLines(a, b []string) []diffOp {
+	// Common prefix and suffix are kept as they are, which keeps the search small for typical edits
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	ops := make([]diffOp, 0, len(a)+len(b))
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = append(ops, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	return ops
+}
+
+func myers(a, b []string) []diffOp {
+	n, m := len(a), len(b)
+	limit := n + m
+	if limit > maxDiffEdits {
+		limit = maxDiffEdits
+	}
+
+	// v[offset+k] is the furthest x reached on diagonal k; trace[d] holds diagonals -d..d before step d
+	offset := limit + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d:offset+d+1]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || k != d && v[offset+k-1] < v[offset+k+1] {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace)
+			}
+		}
+	}
+
+	// Too many differences to search: replace the whole range
+	ops := make([]diffOp, 0, n+m)
+	for _, line := range a {
+		ops = append(ops, diffOp{'-', line})
+	}
+	for _, line := range b {
+		ops = append(ops, diffOp{'+', line})
+	}
+	return ops
+}
+
+// backtrack walks the search trace from the end back to the start and returns the edit script
+func backtrack(a, b []string, trace [][]int) []diffOp {
+	var ops []diffOp
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d > 0; d-- {
+		v := trace[d]
+		at := func(k int) int { return v[k+d] }
+		k := x - y
+		var prevK int
+		if k == -d || k != d && at(k-1) < at(k+1) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			ops = append(ops, diffOp{' ', a[x-1]})
+			x--
+			y--
+		}
+		if x == prevX {
+			ops = append(ops, diffOp{'+', b[y-1]})
+			y--
+		} else {
+			ops = append(ops, diffOp{'-', a[x-1]})
+			x--
+		}
+	}
+	for x > 0 && y > 0 {
+		ops = append(ops, diffOp{' ', a[x-1]})
+		x--
+		y--
+	}
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// writeHunks writes the changes of an edit script as unified diff hunks
+func writeHunks(out *strings.Builder, ops []diffOp) {
+	// Ranges of ops to print: each change with its context, merged when they touch
+	type span struct{ start, end int }
+	var spans []span
+	for i, op := range ops {
+		if op.kind == ' ' {
+			continue
+		}
+		start, end := i-diffContext, i+diffContext+1
+		if start < 0 {
+			start = 0
+		}
+		if end > len(ops) {
+			end = len(ops)
+		}
+		if len(spans) > 0 && start <= spans[len(spans)-1].end {
+			spans[len(spans)-1].end = end
+		} else {
+			spans = append(spans, span{start, end})
+		}
+	}
+
+	// Line numbers before each op
+	aLine, bLine := make([]int, len(ops)), make([]int, len(ops))
+	x, y := 0, 0
+	for i, op := range ops {
+		aLine[i], bLine[i] = x, y
+		if op.kind != '+' {
+			x++
+		}
+		if op.kind != '-' {
+			y++
+		}
+	}
+
+	for _, s := range spans {
+		aLen, bLen := 0, 0
+		for _, op := range ops[s.start:s.end] {
+			if op.kind != '+' {
+				aLen++
+			}
+			if op.kind != '-' {
+				bLen++
+			}
+		}
+		aStart, bStart := aLine[s.start], bLine[s.start]
+		if aLen > 0 {
+			aStart++
+		}
+		if bLen > 0 {
+			bStart++
+		}
+		fmt.Fprintf(out, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+		for _, op := range ops[s.start:s.end] {
+			out.WriteByte(op.kind)
+			out.WriteString(op.line)
+			out.WriteByte('\n')
+		}
+	}
+}
@@ -1,106 +1,549 @@
 package plugin
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+
+	"github.com/uzzalhcse/crawlify/internal/storage"
+	"github.com/uzzalhcse/crawlify/pkg/models"
+)
+
+// Limits on what the editor may write into a plugin's source tree
+const (
+	MaxSourceFileSize  = 1 << 20  // Per file
+	MaxSourceTreeSize  = 16 << 20 // All editable files of a plugin together
+	MaxSourceFileCount = 500
+)
+
+// sourceExtensions are the file types the editor reads and writes; anything else in the tree,
+// including binaries, is left alone and never recorded in a revision
+var sourceExtensions = map[string]bool{
+	".go": true, ".mod": true, ".sum": true, ".md": true, ".txt": true,
+	".json": true, ".yaml": true, ".yml": true, ".html": true, ".htm": true, ".har": true,
+}
+
+// ErrInvalidSourcePath is returned for file names outside the plugin directory or of a type the
+// editor does not handle; ErrSourceTooLarge when a file or the tree exceeds the size limits
+var (
+	ErrInvalidSourcePath = errors.New("invalid source file path")
+	ErrSourceTooLarge    = errors.New("plugin source too large")
 )
 
+// SourceUpdate is a save from the editor
+type SourceUpdate struct {
+	Files   map[string]string // Files to create or replace, by slash-separated path
+	Delete  []string          // Files to remove
+	Author  string
+	Message string
+}
+
 // SourceManager handles reading and writing plugin source code
+// Writes are confined to the plugin's directory and every save is recorded as a revision.
 type SourceManager struct {
 	BasePath string
+	repo     *storage.PluginSourceRepository
+
+	mu    sync.Mutex
+	slugs map[string]*sync.Mutex // One save per plugin at a time
 }
 
 // NewSourceManager creates a new SourceManager
-func NewSourceManager(basePath string) *SourceManager {
-	return &SourceManager{BasePath: basePath}
+func NewSourceManager(basePath string, repo *storage.PluginSourceRepository) *SourceManager {
+	return &SourceManager{BasePath: basePath, repo: repo, slugs: make(map[string]*sync.Mutex)}
 }
 
 // GetSource returns a map of filenames to their content for a given plugin
 func (m *SourceManager) GetSource(pluginSlug string) (map[string]string, error) {
-	pluginDir := filepath.Join(m.BasePath, pluginSlug)
-
-	// Check if directory exists
-	if _, err := os.Stat(pluginDir); os.IsNotExist(err) {
-		return nil, fmt.Errorf("plugin source directory not found: %s", pluginSlug)
+	root, err := m.pluginDir(pluginSlug)
+	if err != nil {
+		return nil, err
 	}
+	return readSourceTree(root)
+}
 
-	files := make(map[string]string)
+// UpdateSource writes a save from the editor and records it as a new revision
+// The save is checked as a whole before anything is written. A save that changes nothing
+// returns the latest revision. The first save of a plugin also records the tree as it was
+// before, so it can be rolled back to.
+func (m *SourceManager) UpdateSource(ctx context.Context, pluginSlug string, update SourceUpdate) (*models.PluginSourceRevision, error) {
+	lock := m.slugLock(pluginSlug)
+	lock.Lock()
+	defer lock.Unlock()
 
-	// Walk through the directory
-	err := filepath.Walk(pluginDir, func(path string, info os.FileInfo, err error) error {
+	root, err := m.pluginDir(pluginSlug)
+	if err != nil {
+		return nil, err
+	}
+	current, err := readSourceTree(root)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := m.baseline(ctx, pluginSlug, current)
+	if err != nil {
+		return nil, err
+	}
+
+	next := make(map[string]string, len(current)+len(update.Files))
+	for name, content := range current {
+		next[name] = content
+	}
+	for _, name := range update.Delete {
+		clean, err := cleanSourcePath(name)
 		if err != nil {
-			return err
+			return nil, err
 		}
+		delete(next, clean)
+	}
+	for name, content := range update.Files {
+		clean, err := cleanSourcePath(name)
+		if err != nil {
+			return nil, err
+		}
+		next[clean] = content
+	}
+	if err := checkSourceSize(next); err != nil {
+		return nil, err
+	}
 
-		// Skip directories
-		if info.IsDir() {
-			return nil
+	if _, changes := DiffFiles(previous, next); len(changes) == 0 {
+		if err := writeSourceTree(root, current, next); err != nil {
+			return nil, err
 		}
+		return m.repo.Latest(ctx, pluginSlug)
+	}
+	return m.writeAndRecord(ctx, pluginSlug, root, current, previous, next, update.Author, update.Message, nil)
+}
 
-		// Only include .go, .mod, .sum, .md files
-		ext := strings.ToLower(filepath.Ext(path))
-		if ext != ".go" && ext != ".mod" && ext != ".sum" && ext != ".md" {
-			return nil
+// ImportSource writes a source tree from outside the editor, such as a plugin bundle, and
+// records it as a new revision
+// The plugin directory is created when needed. Files go through the same path checks and
+// confinement as editor saves; files of a type the editor does not handle are skipped and returned.
+func (m *SourceManager) ImportSource(ctx context.Context, pluginSlug string, files map[string]string, author, message string) (*models.PluginSourceRevision, []string, error) {
+	if _, err := m.pluginDir(pluginSlug); err != nil {
+		if err := m.createPluginDir(pluginSlug); err != nil {
+			return nil, nil, err
 		}
+	}
 
-		// Get relative path
-		relPath, err := filepath.Rel(pluginDir, path)
-		if err != nil {
-			return err
+	editable := make(map[string]string, len(files))
+	var skipped []string
+	for name, content := range files {
+		if !sourceExtensions[strings.ToLower(path.Ext(name))] {
+			skipped = append(skipped, name)
+			continue
 		}
+		editable[name] = content
+	}
+	sort.Strings(skipped)
 
-		// Read file content
-		content, err := ioutil.ReadFile(path)
-		if err != nil {
-			return err
+	rev, err := m.UpdateSource(ctx, pluginSlug, SourceUpdate{Files: editable, Author: author, Message: message})
+	return rev, skipped, err
+}
+
+// Rollback restores the plugin's source to a revision and records that as a new revision
+func (m *SourceManager) Rollback(ctx context.Context, pluginSlug string, revision int, author string) (*models.PluginSourceRevision, error) {
+	lock := m.slugLock(pluginSlug)
+	lock.Lock()
+	defer lock.Unlock()
+
+	target, err := m.repo.Get(ctx, pluginSlug, revision)
+	if err != nil {
+		return nil, err
+	}
+	root, err := m.pluginDir(pluginSlug)
+	if err != nil {
+		return nil, err
+	}
+	current, err := readSourceTree(root)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := m.baseline(ctx, pluginSlug, current)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSourceTree(target.Files); err != nil {
+		return nil, err
+	}
+
+	return m.writeAndRecord(ctx, pluginSlug, root, current, previous, target.Files, author, fmt.Sprintf("Roll back to revision %d", revision), &revision)
+}
+
+// RecordRevision records the plugin's source tree as it is on disk, after it was written by
+// something other than the editor, such as a bundle import
+// When the tree matches the latest revision, that revision is returned instead.
+func (m *SourceManager) RecordRevision(ctx context.Context, pluginSlug, author, message string) (*models.PluginSourceRevision, error) {
+	lock := m.slugLock(pluginSlug)
+	lock.Lock()
+	defer lock.Unlock()
+
+	root, err := m.pluginDir(pluginSlug)
+	if err != nil {
+		return nil, err
+	}
+	current, err := readSourceTree(root)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := m.repo.Latest(ctx, pluginSlug)
+	if err != nil {
+		return nil, err
+	}
+	previous := map[string]string{}
+	if latest != nil {
+		previous = latest.Files
+		if _, changes := DiffFiles(previous, current); len(changes) == 0 {
+			return latest, nil
 		}
+	}
+	return m.record(ctx, pluginSlug, previous, current, author, message, nil)
+}
 
-		files[relPath] = string(content)
-		return nil
-	})
+// ListRevisions lists the revisions of a plugin's source, newest first
+func (m *SourceManager) ListRevisions(ctx context.Context, pluginSlug string, limit, offset int) ([]*models.PluginSourceRevision, error) {
+	return m.repo.List(ctx, pluginSlug, limit, offset)
+}
+
+// GetRevision returns a revision with its files and its diff against the previous revision
+func (m *SourceManager) GetRevision(ctx context.Context, pluginSlug string, revision int) (*models.PluginSourceRevision, error) {
+	return m.repo.Get(ctx, pluginSlug, revision)
+}
 
+// Diff compares two revisions of a plugin's source; revision 0 is the working tree
+func (m *SourceManager) Diff(ctx context.Context, pluginSlug string, from, to int) (string, []models.SourceFileChange, error) {
+	fromFiles, err := m.revisionFiles(ctx, pluginSlug, from)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read plugin files: %w", err)
+		return "", nil, err
 	}
+	toFiles, err := m.revisionFiles(ctx, pluginSlug, to)
+	if err != nil {
+		return "", nil, err
+	}
+	diff, changes := DiffFiles(fromFiles, toFiles)
+	return diff, changes, nil
+}
 
-	return files, nil
+// Materialize lays a revision out in dst, a copy of the plugin's source tree
+// Editable files are replaced by the revision's and those it does not have are removed;
+// other files of the copy are kept.
+func (m *SourceManager) Materialize(ctx context.Context, pluginSlug string, revision int, dst string) error {
+	rev, err := m.repo.Get(ctx, pluginSlug, revision)
+	if err != nil {
+		return err
+	}
+	if dst, err = filepath.EvalSymlinks(dst); err != nil {
+		return err
+	}
+	current, err := readSourceTree(dst)
+	if err != nil {
+		return err
+	}
+	return writeSourceTree(dst, current, rev.Files)
+}
+
+func (m *SourceManager) revisionFiles(ctx context.Context, pluginSlug string, revision int) (map[string]string, error) {
+	if revision == 0 {
+		return m.GetSource(pluginSlug)
+	}
+	rev, err := m.repo.Get(ctx, pluginSlug, revision)
+	if err != nil {
+		return nil, err
+	}
+	return rev.Files, nil
+}
+
+// baseline returns the files of the latest revision, first recording the tree on disk as the
+// initial revision when the plugin has no history yet
+func (m *SourceManager) baseline(ctx context.Context, pluginSlug string, current map[string]string) (map[string]string, error) {
+	latest, err := m.repo.Latest(ctx, pluginSlug)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil {
+		return latest.Files, nil
+	}
+	initial, err := m.record(ctx, pluginSlug, map[string]string{}, current, "", "Initial source", nil)
+	if err != nil {
+		return nil, err
+	}
+	return initial.Files, nil
+}
+
+// writeAndRecord turns the tree on disk from current into files and records that as a revision
+// When the revision cannot be recorded the tree is put back, so the disk never holds a save
+// that has no revision.
+func (m *SourceManager) writeAndRecord(ctx context.Context, pluginSlug, root string, current, previous, files map[string]string, author, message string, restoredFrom *int) (*models.PluginSourceRevision, error) {
+	if err := writeSourceTree(root, current, files); err != nil {
+		// Undo whatever part of the save was written before the failure
+		if restoreErr := writeSourceTree(root, files, current); restoreErr != nil {
+			return nil, fmt.Errorf("%w (restoring the previous source also failed: %v)", err, restoreErr)
+		}
+		return nil, err
+	}
+	rev, err := m.record(ctx, pluginSlug, previous, files, author, message, restoredFrom)
+	if err != nil {
+		if restoreErr := writeSourceTree(root, files, current); restoreErr != nil {
+			return nil, fmt.Errorf("failed to record revision: %w (restoring the previous source also failed: %v)", err, restoreErr)
+		}
+		return nil, fmt.Errorf("failed to record revision: %w", err)
+	}
+	return rev, nil
 }
 
-// UpdateSource updates the source code for a plugin
-func (m *SourceManager) UpdateSource(pluginSlug string, files map[string]string) error {
-	pluginDir := filepath.Join(m.BasePath, pluginSlug)
+// record saves files as a new revision with its diff against previous
+func (m *SourceManager) record(ctx context.Context, pluginSlug string, previous, files map[string]string, author, message string, restoredFrom *int) (*models.PluginSourceRevision, error) {
+	diff, changes := DiffFiles(previous, files)
+	rev := &models.PluginSourceRevision{
+		PluginSlug:   pluginSlug,
+		Author:       author,
+		Message:      message,
+		Files:        files,
+		Changes:      changes,
+		Diff:         diff,
+		RestoredFrom: restoredFrom,
+	}
+	if err := m.repo.Create(ctx, rev); err != nil {
+		return nil, err
+	}
+	return rev, nil
+}
 
-	// Check if directory exists
-	if _, err := os.Stat(pluginDir); os.IsNotExist(err) {
-		return fmt.Errorf("plugin source directory not found: %s", pluginSlug)
+// pluginDir returns the resolved source directory of a plugin, which must lie inside BasePath
+func (m *SourceManager) pluginDir(pluginSlug string) (string, error) {
+	if pluginSlug == "" || strings.ContainsAny(pluginSlug, `/\`) || strings.HasPrefix(pluginSlug, ".") {
+		return "", fmt.Errorf("invalid plugin slug: %s", pluginSlug)
+	}
+	base, err := filepath.Abs(m.BasePath)
+	if err != nil {
+		return "", err
+	}
+	if base, err = filepath.EvalSymlinks(base); err != nil {
+		return "", fmt.Errorf("plugin source directory not found: %w", err)
 	}
+	root, err := filepath.EvalSymlinks(filepath.Join(base, pluginSlug))
+	if err != nil {
+		return "", fmt.Errorf("plugin source directory not found: %s", pluginSlug)
+	}
+	if !within(base, root) || root == base {
+		return "", fmt.Errorf("plugin source directory of %s is outside %s", pluginSlug, m.BasePath)
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("plugin source directory not found: %s", pluginSlug)
+	}
+	return root, nil
+}
+
+// createPluginDir creates the source directory of a plugin inside BasePath
+func (m *SourceManager) createPluginDir(pluginSlug string) error {
+	if pluginSlug == "" || strings.ContainsAny(pluginSlug, `/\`) || strings.HasPrefix(pluginSlug, ".") {
+		return fmt.Errorf("invalid plugin slug: %s", pluginSlug)
+	}
+	if err := os.MkdirAll(filepath.Join(m.BasePath, pluginSlug), 0755); err != nil {
+		return fmt.Errorf("failed to create plugin source directory: %w", err)
+	}
+	_, err := m.pluginDir(pluginSlug)
+	return err
+}
+
+func (m *SourceManager) slugLock(slug string) *sync.Mutex {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	lock, ok := m.slugs[slug]
+	if !ok {
+		lock = &sync.Mutex{}
+		m.slugs[slug] = lock
+	}
+	return lock
+}
 
-	for filename, content := range files {
-		// Validate filename to prevent directory traversal
-		if strings.Contains(filename, "..") || strings.HasPrefix(filename, "/") {
-			return fmt.Errorf("invalid filename: %s", filename)
+// cleanSourcePath validates a file name from the editor and returns it in canonical form
+// Names are relative, slash-separated, without hidden components, and of an editable type.
+func cleanSourcePath(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, "\\\x00") || path.IsAbs(name) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("%w: %q", ErrInvalidSourcePath, name)
+	}
+	clean := path.Clean(name)
+	if clean != name {
+		return "", fmt.Errorf("%w: %q is not in canonical form", ErrInvalidSourcePath, name)
+	}
+	for _, part := range strings.Split(clean, "/") {
+		if part == ".." || strings.HasPrefix(part, ".") {
+			return "", fmt.Errorf("%w: %q", ErrInvalidSourcePath, name)
 		}
+	}
+	if !sourceExtensions[strings.ToLower(path.Ext(clean))] {
+		return "", fmt.Errorf("%w: %q is not an editable file type", ErrInvalidSourcePath, name)
+	}
+	return clean, nil
+}
 
-		fullPath := filepath.Join(pluginDir, filename)
+// checkSourceTree validates the names and sizes of a whole tree
+func checkSourceTree(files map[string]string) error {
+	for name := range files {
+		if _, err := cleanSourcePath(name); err != nil {
+			return err
+		}
+	}
+	return checkSourceSize(files)
+}
 
-		// Ensure the file belongs to the plugin directory
-		if !strings.HasPrefix(fullPath, pluginDir) {
-			return fmt.Errorf("invalid file path: %s", fullPath)
+func checkSourceSize(files map[string]string) error {
+	if len(files) > MaxSourceFileCount {
+		return fmt.Errorf("%w: %d files, at most %d are allowed", ErrSourceTooLarge, len(files), MaxSourceFileCount)
+	}
+	total := 0
+	for name, content := range files {
+		if len(content) > MaxSourceFileSize {
+			return fmt.Errorf("%w: %s is %d bytes, at most %d are allowed", ErrSourceTooLarge, name, len(content), MaxSourceFileSize)
 		}
+		total += len(content)
+	}
+	if total > MaxSourceTreeSize {
+		return fmt.Errorf("%w: %d bytes in total, at most %d are allowed", ErrSourceTooLarge, total, MaxSourceTreeSize)
+	}
+	return nil
+}
 
-		// Create parent directories if needed
-		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
-			return fmt.Errorf("failed to create directory for %s: %w", filename, err)
+// readSourceTree reads the editable files under root
+// Hidden files and directories, symlinks and files over the size limit are skipped.
+func readSourceTree(root string) (map[string]string, error) {
+	files := make(map[string]string)
+	err := filepath.WalkDir(root, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
 		}
+		if p != root && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !d.Type().IsRegular() || !sourceExtensions[strings.ToLower(filepath.Ext(p))] {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Size() > MaxSourceFileSize {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = string(content)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin files: %w", err)
+	}
+	return files, nil
+}
 
-		// Write file content
-		if err := ioutil.WriteFile(fullPath, []byte(content), 0644); err != nil {
-			return fmt.Errorf("failed to write file %s: %w", filename, err)
+// writeSourceTree turns the editable files under root from current into next
+// Every target is confined to root before anything is written; files are replaced atomically.
+func writeSourceTree(root string, current, next map[string]string) error {
+	var writes, deletes []string
+	for name, content := range next {
+		if old, ok := current[name]; !ok || old != content {
+			writes = append(writes, name)
+		}
+	}
+	for name := range current {
+		if _, ok := next[name]; !ok {
+			deletes = append(deletes, name)
+		}
+	}
+	sort.Strings(writes)
+	for _, name := range append(writes, deletes...) {
+		if _, err := confine(root, name); err != nil {
+			return err
 		}
 	}
 
+	for _, name := range writes {
+		target := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create directory for %s: %w", name, err)
+		}
+		// MkdirAll may have followed a directory swapped for a symlink since the check
+		if _, err := confine(root, name); err != nil {
+			return err
+		}
+		if err := writeFileAtomic(target, []byte(next[name])); err != nil {
+			return fmt.Errorf("failed to write file %s: %w", name, err)
+		}
+	}
+	for _, name := range deletes {
+		target := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.Remove(target); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to delete file %s: %w", name, err)
+		}
+	}
 	return nil
 }
+
+// confine returns the path of name under root, failing if any existing part of it resolves
+// outside root or if the file itself is a symlink
+func confine(root, name string) (string, error) {
+	target := filepath.Join(root, filepath.FromSlash(name))
+	if !within(root, target) {
+		return "", fmt.Errorf("%w: %q", ErrInvalidSourcePath, name)
+	}
+	if info, err := os.Lstat(target); err == nil && info.Mode()&os.ModeSymlink != 0 {
+		return "", fmt.Errorf("%w: %q is a symlink", ErrInvalidSourcePath, name)
+	}
+
+	// Resolve the deepest directory that exists; what is below it will be created inside it
+	dir := filepath.Dir(target)
+	for {
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			if !within(root, resolved) {
+				return "", fmt.Errorf("%w: %q resolves outside the plugin directory", ErrInvalidSourcePath, name)
+			}
+			return target, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// within reports whether p is root or inside it
+func within(root, p string) bool {
+	rel, err := filepath.Rel(root, p)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) && !filepath.IsAbs(rel)
+}
+
+func writeFileAtomic(target string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".tmp-"+filepath.Base(target)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), target)
+}
@@ -0,0 +1,575 @@
+package plugins
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/uzzalhcse/crawlify/pkg/models"
+)
+
+// A plugin bundle is a gzipped tar holding one plugin version:
+//
+//	manifest.json   the plugin's metadata and the SHA-256 of every other file
+//	manifest.sig    optional ed25519 signature of manifest.json
+//	source/...      the plugin's source tree
+//	artifact/<name> the built binary
+//
+// Signing the manifest covers the whole bundle, since reading a bundle rejects files
+// whose hash differs from the manifest and files the manifest does not list.
+const (
+	BundleFormatVersion = 1
+
+	bundleManifestName  = "manifest.json"
+	bundleSignatureName = "manifest.sig"
+	bundleSourceDir     = "source"
+	bundleArtifactDir   = "artifact"
+)
+
+// Errors of Keyring.Verify
+var (
+	ErrUnsigned     = errors.New("plugin bundle is not signed")
+	ErrUntrustedKey = errors.New("plugin bundle is signed with a key that is not trusted")
+	ErrBadSignature = errors.New("plugin bundle signature does not match its manifest")
+)
+
+// BundleManifest describes the plugin version in a bundle
+type BundleManifest struct {
+	FormatVersion      int                       `json:"format_version"`
+	Plugin             BundlePlugin              `json:"plugin"`
+	Version            string                    `json:"version"`
+	Changelog          string                    `json:"changelog,omitempty"`
+	IsStable           bool                      `json:"is_stable"`
+	MinCrawlifyVersion string                    `json:"min_crawlify_version,omitempty"`
+	Runtime            models.PluginRuntime      `json:"runtime"`
+	OS                 string                    `json:"os,omitempty"` // Platform of the artifact; empty for WASM
+	Arch               string                    `json:"arch,omitempty"`
+	ConfigSchema       models.JSONObject         `json:"config_schema,omitempty"`
+	Capabilities       models.PluginCapabilities `json:"capabilities"`
+	Limits             models.PluginLimits       `json:"limits"`
+	SBOM               models.PluginSBOM         `json:"sbom"`
+	Artifact           BundleFile                `json:"artifact"`
+	Source             []BundleFile              `json:"source"`
+	CreatedAt          time.Time                 `json:"created_at"`
+}
+
+// BundlePlugin is the marketplace entry of a bundled plugin
+type BundlePlugin struct {
+	Name             string            `json:"name"`
+	Slug             string            `json:"slug"`
+	Description      string            `json:"description,omitempty"`
+	AuthorName       string            `json:"author_name,omitempty"`
+	AuthorEmail      string            `json:"author_email,omitempty"`
+	RepositoryURL    string            `json:"repository_url,omitempty"`
+	DocumentationURL string            `json:"documentation_url,omitempty"`
+	PhaseType        models.PhaseType  `json:"phase_type"`
+	PluginType       models.PluginType `json:"plugin_type"`
+	Category         string            `json:"category,omitempty"`
+	Tags             []string          `json:"tags,omitempty"`
+}
+
+// BundleFile is a file in a bundle; Path is relative to its source or artifact directory
+type BundleFile struct {
+	Path   string `json:"path"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// BundleSignature is the content of manifest.sig
+type BundleSignature struct {
+	KeyID     string `json:"key_id"`
+	Signature string `json:"signature"` // Base64 ed25519 signature of manifest.json
+}
+
+// Bundle is a bundle read and checked by ReadBundle, extracted into Dir
+type Bundle struct {
+	Manifest    BundleManifest
+	RawManifest []byte           // manifest.json as stored in the bundle, which is what is signed
+	Signature   *BundleSignature // nil when the bundle is not signed
+	Dir         string
+}
+
+// SourceDir returns the directory the bundle's source tree was extracted to
+func (b *Bundle) SourceDir() string {
+	return filepath.Join(b.Dir, bundleSourceDir)
+}
+
+// ArtifactPath returns the extracted binary of the bundle
+func (b *Bundle) ArtifactPath() string {
+	return filepath.Join(b.Dir, bundleArtifactDir, filepath.FromSlash(b.Manifest.Artifact.Path))
+}
+
+// SourceFiles reads the bundle's extracted source tree, by slash-separated path
+func (b *Bundle) SourceFiles() (map[string]string, error) {
+	files := make(map[string]string, len(b.Manifest.Source))
+	for _, file := range b.Manifest.Source {
+		data, err := os.ReadFile(filepath.Join(b.SourceDir(), filepath.FromSlash(file.Path)))
+		if err != nil {
+			return nil, err
+		}
+		files[file.Path] = string(data)
+	}
+	return files, nil
+}
+
+// BundleSource is what WriteBundle packs
+type BundleSource struct {
+	Manifest  BundleManifest     // Artifact, Source and FormatVersion are filled in by WriteBundle
+	SourceDir string             // Plugin source tree; "" to bundle no source
+	Artifact  string             // Built binary
+	SignWith  ed25519.PrivateKey // nil for an unsigned bundle
+}
+
+// WriteBundle writes a bundle of a plugin version to w and returns its manifest
+// Hidden files and compiled binaries in the source tree are left out.
+func WriteBundle(w io.Writer, src BundleSource) (*BundleManifest, error) {
+	manifest := src.Manifest
+	manifest.FormatVersion = BundleFormatVersion
+	if manifest.CreatedAt.IsZero() {
+		manifest.CreatedAt = time.Now().UTC()
+	}
+
+	hash, size, err := fileHashAndSize(src.Artifact)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin binary: %w", err)
+	}
+	manifest.Artifact = BundleFile{Path: filepath.Base(src.Artifact), SHA256: hash, Size: size}
+
+	sourceFiles, err := bundleSourceFiles(src.SourceDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin source: %w", err)
+	}
+	manifest.Source = sourceFiles
+
+	rawManifest, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	if err := writeTarBytes(tw, bundleManifestName, rawManifest); err != nil {
+		return nil, err
+	}
+	if src.SignWith != nil {
+		sig := BundleSignature{
+			KeyID:     KeyID(src.SignWith.Public().(ed25519.PublicKey)),
+			Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(src.SignWith, rawManifest)),
+		}
+		sigJSON, err := json.MarshalIndent(sig, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if err := writeTarBytes(tw, bundleSignatureName, sigJSON); err != nil {
+			return nil, err
+		}
+	}
+	for _, file := range sourceFiles {
+		if err := writeTarFile(tw, path.Join(bundleSourceDir, file.Path), filepath.Join(src.SourceDir, filepath.FromSlash(file.Path)), 0644); err != nil {
+			return nil, err
+		}
+	}
+	if err := writeTarFile(tw, path.Join(bundleArtifactDir, manifest.Artifact.Path), src.Artifact, 0755); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return &manifest, nil
+}
+
+// bundleSourceFiles lists the files of a source tree that go into a bundle, sorted by path
+func bundleSourceFiles(dir string) ([]BundleFile, error) {
+	files := []BundleFile{}
+	if dir == "" {
+		return files, nil
+	}
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() || !d.Type().IsRegular() {
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(p)) {
+		case ".so", ".wasm", ".exe":
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		hash, size, err := fileHashAndSize(p)
+		if err != nil {
+			return err
+		}
+		files = append(files, BundleFile{Path: filepath.ToSlash(rel), SHA256: hash, Size: size})
+		return nil
+	})
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, err
+}
+
+// ReadBundle extracts a bundle into dir, reading at most maxBytes of uncompressed content
+// Every file must be listed in the manifest with a matching SHA-256, and paths must stay inside dir.
+// The signature is read but not verified; see Keyring.Verify.
+func ReadBundle(r io.Reader, dir string, maxBytes int64) (*Bundle, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("plugin bundle is not gzip: %w", err)
+	}
+	defer gz.Close()
+	limited := &io.LimitedReader{R: gz, N: maxBytes + 1}
+	tr := tar.NewReader(limited)
+
+	bundle := &Bundle{Dir: dir}
+	hashes := make(map[string]string)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if limited.N <= 0 {
+			return nil, fmt.Errorf("plugin bundle is larger than %d bytes", maxBytes)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid plugin bundle: %w", err)
+		}
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+		if header.Typeflag != tar.TypeReg {
+			return nil, fmt.Errorf("plugin bundle entry %s is not a regular file", header.Name)
+		}
+		name, err := bundleEntryName(header.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		switch name {
+		case bundleManifestName:
+			if bundle.RawManifest, err = io.ReadAll(tr); err != nil {
+				return nil, err
+			}
+			continue
+		case bundleSignatureName:
+			data, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			var sig BundleSignature
+			if err := json.Unmarshal(data, &sig); err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", bundleSignatureName, err)
+			}
+			bundle.Signature = &sig
+			continue
+		}
+		if !strings.HasPrefix(name, bundleSourceDir+"/") && !strings.HasPrefix(name, bundleArtifactDir+"/") {
+			return nil, fmt.Errorf("unexpected plugin bundle entry %s", name)
+		}
+
+		target := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, err
+		}
+		out, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", name, err)
+		}
+		h := sha256.New()
+		_, err = io.Copy(io.MultiWriter(out, h), tr)
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+		if limited.N <= 0 {
+			return nil, fmt.Errorf("plugin bundle is larger than %d bytes", maxBytes)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to extract %s: %w", name, err)
+		}
+		hashes[name] = hex.EncodeToString(h.Sum(nil))
+	}
+
+	if bundle.RawManifest == nil {
+		return nil, fmt.Errorf("plugin bundle has no %s", bundleManifestName)
+	}
+	if err := json.Unmarshal(bundle.RawManifest, &bundle.Manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", bundleManifestName, err)
+	}
+	if err := bundle.check(hashes); err != nil {
+		return nil, err
+	}
+	return bundle, nil
+}
+
+// ReadBundleManifest reads only the manifest and signature of a bundle, extracting nothing
+func ReadBundleManifest(r io.Reader) (*BundleManifest, []byte, *BundleSignature, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("plugin bundle is not gzip: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	var rawManifest []byte
+	var sig *BundleSignature
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("invalid plugin bundle: %w", err)
+		}
+		switch path.Clean(strings.TrimPrefix(header.Name, "./")) {
+		case bundleManifestName:
+			if rawManifest, err = io.ReadAll(io.LimitReader(tr, 16<<20)); err != nil {
+				return nil, nil, nil, err
+			}
+		case bundleSignatureName:
+			sig = &BundleSignature{}
+			if err := json.NewDecoder(tr).Decode(sig); err != nil {
+				return nil, nil, nil, fmt.Errorf("invalid %s: %w", bundleSignatureName, err)
+			}
+		}
+	}
+	if rawManifest == nil {
+		return nil, nil, nil, fmt.Errorf("plugin bundle has no %s", bundleManifestName)
+	}
+	var manifest BundleManifest
+	if err := json.Unmarshal(rawManifest, &manifest); err != nil {
+		return nil, nil, nil, fmt.Errorf("invalid %s: %w", bundleManifestName, err)
+	}
+	return &manifest, rawManifest, sig, nil
+}
+
+// BundleFileName returns the conventional file name of a bundle, e.g. my-plugin-1.2.0-linux-amd64.tar.gz
+func BundleFileName(m *BundleManifest) string {
+	name := m.Plugin.Slug + "-" + m.Version
+	if m.OS != "" {
+		name += "-" + m.OS + "-" + m.Arch
+	}
+	return name + ".tar.gz"
+}
+
+// check validates the manifest and compares the extracted files with it
+func (b *Bundle) check(hashes map[string]string) error {
+	m := &b.Manifest
+	if m.FormatVersion != BundleFormatVersion {
+		return fmt.Errorf("unsupported plugin bundle format %d", m.FormatVersion)
+	}
+	if m.Plugin.Slug == "" || m.Plugin.Name == "" || m.Plugin.PhaseType == "" {
+		return fmt.Errorf("plugin bundle manifest needs plugin name, slug and phase_type")
+	}
+	if strings.ContainsAny(m.Plugin.Slug, `/\.`) {
+		return fmt.Errorf("invalid plugin slug '%s'", m.Plugin.Slug)
+	}
+	if _, err := CanonicalVersion(m.Version); err != nil {
+		return err
+	}
+	switch m.Runtime {
+	case models.PluginRuntimeNative, models.PluginRuntimeRPC, models.PluginRuntimeWASM:
+	default:
+		return fmt.Errorf("unknown plugin runtime '%s'", m.Runtime)
+	}
+
+	expected := make(map[string]string, len(m.Source)+1)
+	for _, file := range m.Source {
+		if err := checkBundlePath(file.Path); err != nil {
+			return err
+		}
+		expected[path.Join(bundleSourceDir, file.Path)] = file.SHA256
+	}
+	if m.Artifact.Path == "" || strings.Contains(m.Artifact.Path, "/") {
+		return fmt.Errorf("invalid artifact path '%s'", m.Artifact.Path)
+	}
+	expected[path.Join(bundleArtifactDir, m.Artifact.Path)] = m.Artifact.SHA256
+
+	for name, hash := range hashes {
+		want, ok := expected[name]
+		if !ok {
+			return fmt.Errorf("plugin bundle entry %s is not in the manifest", name)
+		}
+		if !strings.EqualFold(want, hash) {
+			return fmt.Errorf("plugin bundle entry %s has SHA-256 %s, manifest says %s", name, hash, want)
+		}
+	}
+	for name := range expected {
+		if _, ok := hashes[name]; !ok {
+			return fmt.Errorf("plugin bundle is missing %s", name)
+		}
+	}
+	return nil
+}
+
+// checkBundlePath rejects manifest paths that are not relative, slash-separated and canonical,
+// since they name where source files are written on import
+func checkBundlePath(p string) error {
+	if p == "" || path.IsAbs(p) || strings.ContainsAny(p, "\\\x00") || path.Clean(p) != p {
+		return fmt.Errorf("invalid source path '%s' in plugin bundle manifest", p)
+	}
+	for _, part := range strings.Split(p, "/") {
+		if part == ".." {
+			return fmt.Errorf("invalid source path '%s' in plugin bundle manifest", p)
+		}
+	}
+	return nil
+}
+
+// bundleEntryName cleans a tar entry name and rejects names that would leave the bundle directory
+func bundleEntryName(name string) (string, error) {
+	cleaned := path.Clean(strings.TrimPrefix(name, "./"))
+	if path.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, "../") || strings.Contains(name, `\`) {
+		return "", fmt.Errorf("plugin bundle entry %s leaves the bundle", name)
+	}
+	return cleaned, nil
+}
+
+func writeTarBytes(tw *tar.Writer, name string, data []byte) error {
+	header := &tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), ModTime: time.Now(), Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+func writeTarFile(tw *tar.Writer, name, src string, mode int64) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	header := &tar.Header{Name: name, Mode: mode, Size: info.Size(), ModTime: info.ModTime(), Typeflag: tar.TypeReg}
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+func fileHashAndSize(p string) (string, int64, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// Keyring holds the public keys whose signatures mark a plugin as verified
+type Keyring struct {
+	keys map[string]ed25519.PublicKey
+}
+
+// NewKeyring parses base64 ed25519 public keys
+func NewKeyring(encoded []string) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string]ed25519.PublicKey, len(encoded))}
+	for _, value := range encoded {
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid trusted plugin key '%s': want a base64 ed25519 public key", value)
+		}
+		pub := ed25519.PublicKey(raw)
+		k.keys[KeyID(pub)] = pub
+	}
+	return k, nil
+}
+
+// KeyIDs returns the IDs of the trusted keys
+func (k *Keyring) KeyIDs() []string {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Verify checks an ed25519 signature of manifest and returns the ID of the trusted key that made it
+func (k *Keyring) Verify(manifest []byte, sig *BundleSignature) (string, error) {
+	if sig == nil || sig.Signature == "" {
+		return "", ErrUnsigned
+	}
+	pub, ok := k.keys[sig.KeyID]
+	if !ok {
+		return "", fmt.Errorf("%w (key %s)", ErrUntrustedKey, sig.KeyID)
+	}
+	raw, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil || !ed25519.Verify(pub, manifest, raw) {
+		return "", ErrBadSignature
+	}
+	return sig.KeyID, nil
+}
+
+// VerifyVersion checks the signature a plugin version was imported with, and that the
+// signed manifest describes the version's binary
+func (k *Keyring) VerifyVersion(version *models.PluginVersion) (string, error) {
+	if version.Manifest == "" {
+		return "", ErrUnsigned
+	}
+	keyID, err := k.Verify([]byte(version.Manifest), &BundleSignature{KeyID: version.SigningKeyID, Signature: version.Signature})
+	if err != nil {
+		return "", err
+	}
+	var manifest BundleManifest
+	if err := json.Unmarshal([]byte(version.Manifest), &manifest); err != nil {
+		return "", fmt.Errorf("invalid stored manifest: %w", err)
+	}
+	if !strings.EqualFold(manifest.Artifact.SHA256, version.BinaryHash) {
+		return "", fmt.Errorf("%w: binary hash %s is not the signed %s", ErrBadSignature, version.BinaryHash, manifest.Artifact.SHA256)
+	}
+	return keyID, nil
+}
+
+// KeyID identifies a public key by the first 8 bytes of its SHA-256, in hex
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// ParseSigningKey decodes a base64 ed25519 private key, given as a 32-byte seed or a 64-byte key
+func ParseSigningKey(encoded string) (ed25519.PrivateKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("invalid plugin signing key: %w", err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	}
+	return nil, fmt.Errorf("invalid plugin signing key: want a %d-byte seed or %d-byte key, got %d bytes", ed25519.SeedSize, ed25519.PrivateKeySize, len(raw))
+}
@@ -17,34 +17,24 @@ type PluginExecutor struct {
 	pluginInfo plugins.PluginInfo
 	plugin     interface{} // DiscoveryPlugin or ExtractionPlugin
 	logger     *zap.Logger
-	metrics    *plugins.PluginMetrics
-	health     *plugins.PluginHealth
+	stats      *pluginStats
 }
 
 // NewPluginExecutor creates a new plugin executor wrapper
 func NewPluginExecutor(loaded *LoadedPlugin, logger *zap.Logger) *PluginExecutor {
+	logger = logger.With(zap.String("plugin_id", loaded.Info.ID))
 	return &PluginExecutor{
 		pluginID:   loaded.Info.ID,
 		pluginInfo: loaded.Info,
 		plugin:     loaded.Instance,
-		logger:     logger.With(zap.String("plugin_id", loaded.Info.ID)),
-		metrics: &plugins.PluginMetrics{
-			ExecutionCount: 0,
-			TotalDuration:  0,
-			SuccessCount:   0,
-			FailureCount:   0,
-		},
-		health: &plugins.PluginHealth{
-			IsHealthy:        true,
-			ConsecutiveFails: 0,
-		},
+		logger:     logger,
+		stats:      newPluginStats(loaded.Info, logger),
 	}
 }
 
 // Execute executes the plugin
-func (pe *PluginExecutor) Execute(ctx context.Context, input *nodes.ExecutionInput) (*nodes.ExecutionOutput, error) {
+func (pe *PluginExecutor) Execute(ctx context.Context, input *nodes.ExecutionInput) (output *nodes.ExecutionOutput, err error) {
 	startTime := time.Now()
-	pe.metrics.ExecutionCount++
 
 	// Recover from panics
 	defer func() {
@@ -52,13 +42,11 @@ func (pe *PluginExecutor) Execute(ctx context.Context, input *nodes.ExecutionInp
 			pe.logger.Error("Plugin panic recovered",
 				zap.Any("panic", r),
 				zap.String("plugin", pe.pluginInfo.Name))
-			pe.recordFailure(fmt.Errorf("plugin panic: %v", r))
+			output, err = nil, fmt.Errorf("plugin panic: %v", r)
+			pe.stats.record(time.Since(startTime), 0, 0, err)
 		}
 	}()
 
-	var output *nodes.ExecutionOutput
-	var err error
-
 	// Execute based on plugin type
 	switch p := pe.plugin.(type) {
 	case plugins.DiscoveryPlugin:
@@ -70,17 +58,18 @@ func (pe *PluginExecutor) Execute(ctx context.Context, input *nodes.ExecutionInp
 	}
 
 	// Update metrics
-	duration := time.Since(startTime)
-	pe.metrics.TotalDuration += duration
-	pe.metrics.AverageDuration = pe.metrics.TotalDuration / time.Duration(pe.metrics.ExecutionCount)
-	pe.metrics.LastExecutedAt = time.Now()
-
+	discovered, extracted := 0, 0
+	if err == nil {
+		if _, ok := pe.plugin.(plugins.DiscoveryPlugin); ok {
+			discovered = len(output.DiscoveredURLs)
+		} else {
+			extracted = 1
+		}
+	}
+	pe.stats.record(time.Since(startTime), discovered, extracted, err)
 	if err != nil {
-		pe.recordFailure(err)
 		return nil, err
 	}
-
-	pe.recordSuccess()
 	return output, nil
 }
 
@@ -89,12 +78,15 @@ func (pe *PluginExecutor) executeDiscovery(ctx context.Context, plugin plugins.D
 	// Convert ExecutionInput to DiscoveryInput
 	discoveryInput := &plugins.DiscoveryInput{
 		BrowserContext:   input.BrowserContext,
+		Browser:          plugins.NewLocalBrowser(input.BrowserContext),
 		URL:              input.URLItem.URL,
 		URLItem:          input.URLItem,
 		ExecutionContext: input.ExecutionContext,
 		Config:           input.Params,
 		ExecutionID:      input.ExecutionID,
+		HTTP:             input.HTTP,
 	}
+	discoveryInput.WorkflowStore, discoveryInput.ExecutionStore = input.PluginStores(plugin.Info().ID)
 
 	// Execute plugin
 	result, err := plugin.Discover(ctx, discoveryInput)
@@ -102,9 +94,6 @@ func (pe *PluginExecutor) executeDiscovery(ctx context.Context, plugin plugins.D
 		return nil, fmt.Errorf("discovery plugin error: %w", err)
 	}
 
-	// Update metrics
-	pe.metrics.URLsDiscovered += int64(len(result.DiscoveredURLs))
-
 	// Convert DiscoveryOutput to ExecutionOutput
 	return &nodes.ExecutionOutput{
 		Result:         result,
@@ -118,12 +107,15 @@ func (pe *PluginExecutor) executeExtraction(ctx context.Context, plugin plugins.
 	// Convert ExecutionInput to ExtractionInput
 	extractionInput := &plugins.ExtractionInput{
 		BrowserContext:   input.BrowserContext,
+		Browser:          plugins.NewLocalBrowser(input.BrowserContext),
 		URL:              input.URLItem.URL,
 		URLItem:          input.URLItem,
 		ExecutionContext: input.ExecutionContext,
 		Config:           input.Params,
 		ExecutionID:      input.ExecutionID,
+		HTTP:             input.HTTP,
 	}
+	extractionInput.WorkflowStore, extractionInput.ExecutionStore = input.PluginStores(plugin.Info().ID)
 
 	// Execute plugin
 	result, err := plugin.Extract(ctx, extractionInput)
@@ -131,9 +123,6 @@ func (pe *PluginExecutor) executeExtraction(ctx context.Context, plugin plugins.
 		return nil, fmt.Errorf("extraction plugin error: %w", err)
 	}
 
-	// Update metrics
-	pe.metrics.ItemsExtracted++
-
 	// Convert ExtractionOutput to ExecutionOutput
 	return &nodes.ExecutionOutput{
 		Result:         result.Data,
@@ -165,35 +154,14 @@ func (pe *PluginExecutor) GetPluginInfo() plugins.PluginInfo {
 	return pe.pluginInfo
 }
 
-// GetMetrics returns plugin execution metrics
+// GetMetrics returns a snapshot of the plugin execution metrics
 func (pe *PluginExecutor) GetMetrics() *plugins.PluginMetrics {
-	return pe.metrics
+	metrics, _ := pe.stats.snapshot()
+	return &metrics
 }
 
-// GetHealth returns plugin health status
+// GetHealth returns a snapshot of the plugin health status
 func (pe *PluginExecutor) GetHealth() *plugins.PluginHealth {
-	return pe.health
-}
-
-// recordSuccess updates metrics and health for successful execution
-func (pe *PluginExecutor) recordSuccess() {
-	pe.metrics.SuccessCount++
-	pe.health.IsHealthy = true
-	pe.health.ConsecutiveFails = 0
-}
-
-// recordFailure updates metrics and health for failed execution
-func (pe *PluginExecutor) recordFailure(err error) {
-	pe.metrics.FailureCount++
-	pe.health.ConsecutiveFails++
-	pe.health.LastError = err.Error()
-	pe.health.LastErrorAt = time.Now()
-
-	// Mark unhealthy after 3 consecutive failures
-	if pe.health.ConsecutiveFails >= 3 {
-		pe.health.IsHealthy = false
-		pe.logger.Warn("Plugin marked unhealthy",
-			zap.String("plugin", pe.pluginInfo.Name),
-			zap.Int("consecutive_fails", pe.health.ConsecutiveFails))
-	}
+	_, health := pe.stats.snapshot()
+	return &health
 }
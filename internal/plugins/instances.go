@@ -0,0 +1,562 @@
+package plugins
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"plugin"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/uzzalhcse/crawlify/internal/extraction"
+	"github.com/uzzalhcse/crawlify/internal/workflow/nodes"
+	"github.com/uzzalhcse/crawlify/pkg/models"
+	"github.com/uzzalhcse/crawlify/pkg/plugins"
+	"go.uber.org/zap"
+)
+
+// executionEndTimeout bounds OnExecutionEnd so a slow plugin cannot hold up the end of an execution
+const executionEndTimeout = 30 * time.Second
+
+// pluginInstance is a plugin constructed for one node config
+// Instances are shared by every execution using the same plugin binary and config; an execution keeps the
+// instance it first resolved until it ends, even if the plugin file is replaced meanwhile
+type pluginInstance struct {
+	key     string // path#config hash
+	slug    string
+	path    string
+	modTime time.Time
+	runtime models.PluginRuntime
+	info    plugins.PluginInfo
+	value   interface{} // DiscoveryPlugin or ExtractionPlugin
+	stats   *pluginStats
+
+	ready chan struct{} // Closed once construction finished; err is set on failure
+	err   error
+
+	pins    int  // Executions holding the instance
+	retired bool // Replaced or unloaded; closed once no execution holds it
+
+	transforms           []string // Custom transforms registered from the instance
+	transformsRegistered bool
+}
+
+// executionPin is an instance held by an execution
+type executionPin struct {
+	instance *pluginInstance
+	once     sync.Once
+	err      error // OnExecutionStart failure
+	started  bool  // OnExecutionStart ran and succeeded
+}
+
+// pluginRun implements nodes.PluginRun
+type pluginRun struct {
+	instance *pluginInstance
+}
+
+func (r *pluginRun) Instance() interface{} {
+	return r.instance.value
+}
+
+func (r *pluginRun) Record(start time.Time, discovered, extracted int, err error) {
+	r.instance.stats.record(time.Since(start), discovered, extracted, err)
+}
+
+// PluginStatus reports the runtime state of a plugin version
+type PluginStatus struct {
+	Slug             string                `json:"slug"`
+	PluginID         string                `json:"plugin_id"`
+	Name             string                `json:"name"`
+	Version          string                `json:"version"`
+	Runtime          models.PluginRuntime  `json:"runtime"`
+	Instances        int                   `json:"instances"` // One per distinct node config
+	ActiveExecutions int                   `json:"active_executions"`
+	Metrics          plugins.PluginMetrics `json:"metrics"`
+	Health           plugins.PluginHealth  `json:"health"`
+}
+
+// ResolvePlugin returns the plugin installed under slug: the newest stored version satisfying
+// constraint, the locked version when constraint is empty, or else the development build
+// (see store.go). Native (.so), WASM (.wasm) and RPC (executable) binaries are tried in that order.
+// The instance is created and initialised with config on first use and reused afterwards; within an
+// execution the same instance is returned on every call, and OnExecutionStart runs before the first.
+// An empty executionID resolves without pinning or execution hooks.
+func (pl *PluginLoader) ResolvePlugin(ctx context.Context, executionID, slug, constraint string, config map[string]interface{}) (nodes.PluginRun, error) {
+	configHash, err := hashConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// An execution keeps what it resolved first, even if another version is installed meanwhile
+	pinKey := slug + "@" + constraint + "#" + configHash
+	pl.instMu.Lock()
+	if pin := pl.pins[executionID][pinKey]; pin != nil {
+		pl.instMu.Unlock()
+		return pl.startExecution(ctx, executionID, pin)
+	}
+	pl.instMu.Unlock()
+
+	path, modTime, runtime, err := findPlugin(slug, constraint)
+	if err != nil {
+		return nil, err
+	}
+	key := path + "#" + configHash
+
+	pl.instMu.Lock()
+	instance := pl.instances[key]
+	if instance != nil && !instance.modTime.Equal(modTime) {
+		pl.logger.Info("Plugin file changed; replacing instance", zap.String("slug", slug), zap.String("path", path))
+		pl.retire(instance)
+		instance = nil
+	}
+	creating := instance == nil
+	if creating {
+		instance = &pluginInstance{key: key, slug: slug, path: path, modTime: modTime, runtime: runtime, ready: make(chan struct{})}
+		pl.instances[key] = instance
+	}
+	pl.instMu.Unlock()
+
+	if creating {
+		pl.construct(instance, config)
+	}
+	select {
+	case <-instance.ready:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if instance.err != nil {
+		return nil, instance.err
+	}
+	pl.registerInstanceTransforms(instance)
+	if executionID == "" {
+		return &pluginRun{instance: instance}, nil
+	}
+
+	pl.instMu.Lock()
+	pin := pl.pins[executionID][pinKey]
+	if pin == nil {
+		if instance.retired {
+			// Replaced while it was being constructed; resolve the new one
+			pl.instMu.Unlock()
+			return pl.ResolvePlugin(ctx, executionID, slug, constraint, config)
+		}
+		pin = &executionPin{instance: instance}
+		if pl.pins[executionID] == nil {
+			pl.pins[executionID] = make(map[string]*executionPin)
+		}
+		pl.pins[executionID][pinKey] = pin
+		instance.pins++
+	}
+	pl.instMu.Unlock()
+	return pl.startExecution(ctx, executionID, pin)
+}
+
+// startExecution runs OnExecutionStart once per execution and instance
+func (pl *PluginLoader) startExecution(ctx context.Context, executionID string, pin *executionPin) (nodes.PluginRun, error) {
+	pin.once.Do(func() {
+		hooks, ok := pin.instance.value.(plugins.ExecutionHooks)
+		if !ok {
+			return
+		}
+		if err := hooks.OnExecutionStart(ctx, executionID); err != nil {
+			pin.err = fmt.Errorf("plugin %s OnExecutionStart failed: %w", pin.instance.slug, err)
+			return
+		}
+		pin.started = true
+	})
+	if pin.err != nil {
+		return nil, pin.err
+	}
+	return &pluginRun{instance: pin.instance}, nil
+}
+
+// construct loads the plugin file, creates the instance and calls Init
+// A failed instance is dropped so the next call tries again
+func (pl *PluginLoader) construct(instance *pluginInstance, config map[string]interface{}) {
+	defer close(instance.ready)
+
+	var err error
+	switch instance.runtime {
+	case models.PluginRuntimeNative:
+		instance.value, err = pl.newNativeInstance(instance.path)
+	case models.PluginRuntimeWASM:
+		var wasmPlugin *WASMPlugin
+		if wasmPlugin, err = LoadWASMPlugin(instance.path, pl.logger); err == nil {
+			instance.value = wasmPlugin.Instance()
+		}
+	default:
+		var rpcPlugin *RPCPlugin
+		if rpcPlugin, err = StartRPCPlugin(instance.path, pl.logger); err == nil {
+			instance.value = rpcPlugin.Instance()
+		}
+	}
+	if err == nil {
+		switch p := instance.value.(type) {
+		case plugins.DiscoveryPlugin:
+			instance.info = p.Info()
+		case plugins.ExtractionPlugin:
+			instance.info = p.Info()
+		default:
+			err = fmt.Errorf("plugin %s implements neither DiscoveryPlugin nor ExtractionPlugin", instance.slug)
+		}
+	}
+	if initializer, ok := instance.value.(plugins.Initializer); ok && err == nil {
+		if err = initializer.Init(config); err != nil {
+			err = fmt.Errorf("plugin %s Init failed: %w", instance.slug, err)
+			closeInstance(instance, pl.logger)
+		}
+	}
+
+	pl.instMu.Lock()
+	defer pl.instMu.Unlock()
+	if err != nil {
+		instance.err = err
+		if pl.instances[instance.key] == instance {
+			delete(pl.instances, instance.key)
+		}
+		return
+	}
+	instance.stats = pl.statsFor(instance.slug, instance.info, instance.runtime)
+	pl.logger.Info("Created plugin instance",
+		zap.String("slug", instance.slug),
+		zap.String("id", instance.info.ID),
+		zap.String("version", instance.info.Version),
+		zap.String("runtime", string(instance.runtime)))
+}
+
+// newNativeInstance opens a Go plugin and calls its constructor
+// Both the logger-taking constructors and the plain func() forms are accepted
+func (pl *PluginLoader) newNativeInstance(path string) (interface{}, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load plugin: %w", err)
+	}
+
+	if symbol, err := p.Lookup("NewExtractionPlugin"); err == nil {
+		switch constructor := symbol.(type) {
+		case func(*zap.Logger) (plugins.ExtractionPlugin, error):
+			instance, err := constructor(pl.logger)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create extraction plugin: %w", err)
+			}
+			return instance, nil
+		case func() plugins.ExtractionPlugin:
+			return constructor(), nil
+		}
+		return nil, fmt.Errorf("NewExtractionPlugin has invalid signature")
+	}
+
+	if symbol, err := p.Lookup("NewDiscoveryPlugin"); err == nil {
+		switch constructor := symbol.(type) {
+		case func(*zap.Logger) (plugins.DiscoveryPlugin, error):
+			instance, err := constructor(pl.logger)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create discovery plugin: %w", err)
+			}
+			return instance, nil
+		case func() plugins.DiscoveryPlugin:
+			return constructor(), nil
+		}
+		return nil, fmt.Errorf("NewDiscoveryPlugin has invalid signature")
+	}
+
+	return nil, fmt.Errorf("plugin does not export NewDiscoveryPlugin or NewExtractionPlugin function")
+}
+
+// EndExecution calls OnExecutionEnd on the instances the execution used and releases them
+func (pl *PluginLoader) EndExecution(executionID string) {
+	pl.instMu.Lock()
+	pins := pl.pins[executionID]
+	delete(pl.pins, executionID)
+	var release []*pluginInstance
+	for _, pin := range pins {
+		pin.instance.pins--
+		if pin.instance.retired && pin.instance.pins == 0 {
+			release = append(release, pin.instance)
+		}
+	}
+	pl.instMu.Unlock()
+
+	for _, pin := range pins {
+		if !pin.started {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), executionEndTimeout)
+		if err := pin.instance.value.(plugins.ExecutionHooks).OnExecutionEnd(ctx, executionID); err != nil {
+			pl.logger.Warn("Plugin OnExecutionEnd failed",
+				zap.String("slug", pin.instance.slug),
+				zap.String("execution_id", executionID),
+				zap.Error(err))
+		}
+		cancel()
+	}
+	for _, instance := range release {
+		closeInstance(instance, pl.logger)
+	}
+}
+
+// retireInstances retires every instance of a plugin ID, e.g. when it is unloaded
+func (pl *PluginLoader) retireInstances(pluginID string) {
+	pl.instMu.Lock()
+	defer pl.instMu.Unlock()
+	for _, instance := range pl.instances {
+		if instance.info.ID == pluginID {
+			pl.retire(instance)
+		}
+	}
+}
+
+// RetireSlug retires every instance of a plugin slug so the next resolution picks up a newly
+// installed or uninstalled version; running executions keep the instances they hold
+func (pl *PluginLoader) RetireSlug(slug string) {
+	pl.instMu.Lock()
+	defer pl.instMu.Unlock()
+	for _, instance := range pl.instances {
+		if instance.slug == slug {
+			pl.retire(instance)
+		}
+	}
+}
+
+// retire stops handing out an instance and closes it once no execution holds it
+// Must be called with instMu held
+func (pl *PluginLoader) retire(instance *pluginInstance) {
+	if pl.instances[instance.key] == instance {
+		delete(pl.instances, instance.key)
+	}
+	instance.retired = true
+	// The next lookup registers them again from the version that replaces it
+	for _, name := range instance.transforms {
+		extraction.UnregisterTransform(name)
+	}
+	instance.transforms = nil
+	if instance.pins == 0 {
+		go func() {
+			<-instance.ready
+			if instance.err == nil {
+				closeInstance(instance, pl.logger)
+			}
+		}()
+	}
+}
+
+// CloseAll closes every plugin instance; used at shutdown
+func (pl *PluginLoader) CloseAll() {
+	pl.instMu.Lock()
+	seen := make(map[*pluginInstance]bool)
+	for _, instance := range pl.instances {
+		seen[instance] = true
+	}
+	for _, pins := range pl.pins {
+		for _, pin := range pins {
+			seen[pin.instance] = true
+		}
+	}
+	pl.instances = make(map[string]*pluginInstance)
+	pl.pins = make(map[string]map[string]*executionPin)
+	pl.instMu.Unlock()
+
+	for instance := range seen {
+		<-instance.ready
+		if instance.err == nil {
+			closeInstance(instance, pl.logger)
+		}
+	}
+}
+
+// closeInstance calls Close on plugins implementing plugins.Closer
+// RPC and WASM instances always do, stopping their process or runtime
+func closeInstance(instance *pluginInstance, logger *zap.Logger) {
+	closer, ok := instance.value.(plugins.Closer)
+	if !ok {
+		return
+	}
+	if err := closer.Close(); err != nil {
+		logger.Warn("Failed to close plugin instance", zap.String("slug", instance.slug), zap.Error(err))
+	}
+}
+
+// PluginStatuses returns the metrics and health of every plugin version used since startup
+func (pl *PluginLoader) PluginStatuses() []PluginStatus {
+	return pl.statuses("")
+}
+
+// PluginStatus returns the metrics and health of the versions of one plugin used since startup
+func (pl *PluginLoader) PluginStatus(slug string) []PluginStatus {
+	return pl.statuses(slug)
+}
+
+func (pl *PluginLoader) statuses(slug string) []PluginStatus {
+	pl.instMu.Lock()
+	defer pl.instMu.Unlock()
+
+	statuses := make([]PluginStatus, 0, len(pl.stats))
+	for _, stats := range pl.stats {
+		if slug != "" && stats.slug != slug {
+			continue
+		}
+		status := PluginStatus{
+			Slug:     stats.slug,
+			PluginID: stats.info.ID,
+			Name:     stats.info.Name,
+			Version:  stats.info.Version,
+			Runtime:  stats.runtime,
+		}
+		for _, instance := range pl.instances {
+			if instance.stats == stats {
+				status.Instances++
+			}
+		}
+		for _, pins := range pl.pins {
+			for _, pin := range pins {
+				if pin.instance.stats == stats {
+					status.ActiveExecutions++
+					break
+				}
+			}
+		}
+		status.Metrics, status.Health = stats.snapshot()
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Slug != statuses[j].Slug {
+			return statuses[i].Slug < statuses[j].Slug
+		}
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses
+}
+
+// statsFor returns the shared stats of a plugin version
+// Must be called with instMu held
+func (pl *PluginLoader) statsFor(slug string, info plugins.PluginInfo, runtime models.PluginRuntime) *pluginStats {
+	key := slug + "@" + info.Version
+	stats, ok := pl.stats[key]
+	if !ok {
+		stats = newPluginStats(info, pl.logger)
+		stats.slug = slug
+		stats.runtime = runtime
+		pl.stats[key] = stats
+	}
+	return stats
+}
+
+// registerInstanceTransforms registers the custom transforms of an instance once and returns them
+func (pl *PluginLoader) registerInstanceTransforms(instance *pluginInstance) []string {
+	pl.instMu.Lock()
+	defer pl.instMu.Unlock()
+	if instance.transformsRegistered || instance.retired {
+		return instance.transforms
+	}
+	instance.transformsRegistered = true
+
+	provider, ok := instance.value.(plugins.TransformProvider)
+	if !ok {
+		return nil
+	}
+	for _, t := range provider.Transforms() {
+		if err := extraction.RegisterTransform(t); err != nil {
+			pl.logger.Warn("Skipping plugin transform",
+				zap.String("slug", instance.slug),
+				zap.String("transform", t.Name),
+				zap.Error(err))
+			continue
+		}
+		instance.transforms = append(instance.transforms, t.Name)
+	}
+	return instance.transforms
+}
+
+// findPlugin locates the binary of the plugin version a node resolves to
+func findPlugin(slug, constraint string) (string, time.Time, models.PluginRuntime, error) {
+	pluginVersion, err := ResolveVersion(slug, constraint)
+	if err != nil {
+		return "", time.Time{}, "", fmt.Errorf("plugin %s: %w", slug, err)
+	}
+	for _, runtime := range []models.PluginRuntime{models.PluginRuntimeNative, models.PluginRuntimeWASM, models.PluginRuntimeRPC} {
+		path := DevBuildPath(slug, runtime)
+		if pluginVersion != "" {
+			path = ArtifactPath(slug, pluginVersion, runtime)
+		}
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, info.ModTime(), runtime, nil
+		}
+	}
+	if pluginVersion != "" {
+		return "", time.Time{}, "", fmt.Errorf("plugin %s version %s has no binary in %s", slug, pluginVersion, filepath.Join(PluginDir, slug, pluginVersion))
+	}
+	return "", time.Time{}, "", fmt.Errorf("plugin %s is not installed", slug)
+}
+
+// hashConfig identifies a node config
+func hashConfig(config map[string]interface{}) (string, error) {
+	data, err := json.Marshal(config)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode plugin config: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+// pluginStats accumulates the metrics and health of a plugin, safely across concurrent calls
+type pluginStats struct {
+	slug    string
+	info    plugins.PluginInfo
+	runtime models.PluginRuntime
+	logger  *zap.Logger
+
+	mu      sync.Mutex
+	metrics plugins.PluginMetrics
+	health  plugins.PluginHealth
+}
+
+func newPluginStats(info plugins.PluginInfo, logger *zap.Logger) *pluginStats {
+	return &pluginStats{
+		info:   info,
+		logger: logger,
+		health: plugins.PluginHealth{IsHealthy: true},
+	}
+}
+
+// record adds one call; a plugin is marked unhealthy after 3 consecutive failures
+func (s *pluginStats) record(duration time.Duration, discovered, extracted int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.metrics.ExecutionCount++
+	s.metrics.TotalDuration += duration
+	s.metrics.AverageDuration = s.metrics.TotalDuration / time.Duration(s.metrics.ExecutionCount)
+	s.metrics.LastExecutedAt = time.Now()
+
+	if err == nil {
+		s.metrics.SuccessCount++
+		s.metrics.URLsDiscovered += int64(discovered)
+		s.metrics.ItemsExtracted += int64(extracted)
+		s.health.IsHealthy = true
+		s.health.ConsecutiveFails = 0
+		return
+	}
+
+	s.metrics.FailureCount++
+	s.health.ConsecutiveFails++
+	s.health.LastError = err.Error()
+	s.health.LastErrorAt = time.Now()
+	if s.health.ConsecutiveFails >= 3 && s.health.IsHealthy {
+		s.health.IsHealthy = false
+		s.logger.Warn("Plugin marked unhealthy",
+			zap.String("plugin", s.info.Name),
+			zap.Int("consecutive_fails", s.health.ConsecutiveFails))
+	}
+}
+
+// snapshot returns copies of the metrics and health
+func (s *pluginStats) snapshot() (plugins.PluginMetrics, plugins.PluginHealth) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.metrics, s.health
+}
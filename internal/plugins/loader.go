@@ -2,19 +2,32 @@ package plugins
 
 import (
 	"fmt"
+	"path/filepath"
 	"plugin"
 	"sync"
 
+	"github.com/uzzalhcse/crawlify/internal/extraction"
+	"github.com/uzzalhcse/crawlify/pkg/models"
 	"github.com/uzzalhcse/crawlify/pkg/plugins"
 	"go.uber.org/zap"
 )
 
+// PluginDir is where compiled plugins live: <slug>.so for Go plugins, <slug>.wasm for WASM plugins
+// and <slug> for RPC plugin executables. Installed versions live under <slug>/<version>/ (see store.go)
+const PluginDir = "./plugins"
+
 // LoadedPlugin represents a loaded plugin with its instance
 type LoadedPlugin struct {
 	Info     plugins.PluginInfo
 	Instance interface{} // DiscoveryPlugin or ExtractionPlugin
 	Plugin   *plugin.Plugin
 	Path     string
+	Runtime  models.PluginRuntime
+	RPC      *RPCPlugin  // Set for RPC plugins
+	WASM     *WASMPlugin // Set for WASM plugins
+
+	// Transforms lists custom transforms registered by the plugin
+	Transforms []string
 }
 
 // PluginLoader manages loading and unloading of compiled plugins
@@ -22,6 +35,12 @@ type PluginLoader struct {
 	loadedPlugins map[string]*LoadedPlugin
 	mu            sync.RWMutex
 	logger        *zap.Logger
+
+	// Instances resolved by slug for plugin nodes, see instances.go
+	instMu    sync.Mutex
+	instances map[string]*pluginInstance          // slug#config hash -> current instance
+	pins      map[string]map[string]*executionPin // execution ID -> slug#config hash -> instance it uses
+	stats     map[string]*pluginStats             // slug@version -> metrics and health
 }
 
 // NewPluginLoader creates a new plugin loader
@@ -29,11 +48,22 @@ func NewPluginLoader(logger *zap.Logger) *PluginLoader {
 	return &PluginLoader{
 		loadedPlugins: make(map[string]*LoadedPlugin),
 		logger:        logger,
+		instances:     make(map[string]*pluginInstance),
+		pins:          make(map[string]map[string]*executionPin),
+		stats:         make(map[string]*pluginStats),
 	}
 }
 
 // LoadPlugin loads a compiled plugin from file
+// A .so file is opened as a Go plugin, a .wasm file runs in the WASM sandbox and any other
+// file is started as an RPC plugin executable
 func (pl *PluginLoader) LoadPlugin(path string) (*LoadedPlugin, error) {
+	if ext := filepath.Ext(path); ext == ".wasm" {
+		return pl.loadWASMPlugin(path)
+	} else if ext != ".so" {
+		return pl.loadRPCPlugin(path)
+	}
+
 	pl.mu.Lock()
 	defer pl.mu.Unlock()
 
@@ -62,8 +92,10 @@ func (pl *PluginLoader) LoadPlugin(path string) (*LoadedPlugin, error) {
 			Instance: instance,
 			Plugin:   p,
 			Path:     path,
+			Runtime:  models.PluginRuntimeNative,
 		}
 
+		pl.registerTransforms(loaded)
 		pl.loadedPlugins[info.ID] = loaded
 		pl.logger.Info("Loaded discovery plugin",
 			zap.String("id", info.ID),
@@ -90,8 +122,10 @@ func (pl *PluginLoader) LoadPlugin(path string) (*LoadedPlugin, error) {
 			Instance: instance,
 			Plugin:   p,
 			Path:     path,
+			Runtime:  models.PluginRuntimeNative,
 		}
 
+		pl.registerTransforms(loaded)
 		pl.loadedPlugins[info.ID] = loaded
 		pl.logger.Info("Loaded extraction plugin",
 			zap.String("id", info.ID),
@@ -104,6 +138,98 @@ func (pl *PluginLoader) LoadPlugin(path string) (*LoadedPlugin, error) {
 	return nil, fmt.Errorf("plugin must export either NewDiscoveryPlugin or NewExtractionPlugin function")
 }
 
+// loadRPCPlugin starts an RPC plugin executable
+func (pl *PluginLoader) loadRPCPlugin(path string) (*LoadedPlugin, error) {
+	pl.logger.Info("Starting RPC plugin", zap.String("path", path))
+
+	rpcPlugin, err := StartRPCPlugin(path, pl.logger)
+	if err != nil {
+		return nil, err
+	}
+	info := rpcPlugin.Info()
+	loaded := &LoadedPlugin{
+		Info:     info,
+		Instance: rpcPlugin.Instance(),
+		Path:     path,
+		Runtime:  models.PluginRuntimeRPC,
+		RPC:      rpcPlugin,
+	}
+
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	if previous, exists := pl.loadedPlugins[info.ID]; exists {
+		previous.close(pl.logger)
+	}
+	pl.loadedPlugins[info.ID] = loaded
+	pl.logger.Info("Loaded RPC plugin",
+		zap.String("id", info.ID),
+		zap.String("name", info.Name),
+		zap.String("version", info.Version),
+		zap.String("kind", rpcPlugin.Kind()))
+
+	return loaded, nil
+}
+
+// loadWASMPlugin compiles a WASM plugin with the capabilities in its grant
+func (pl *PluginLoader) loadWASMPlugin(path string) (*LoadedPlugin, error) {
+	pl.logger.Info("Loading WASM plugin", zap.String("path", path))
+
+	wasmPlugin, err := LoadWASMPlugin(path, pl.logger)
+	if err != nil {
+		return nil, err
+	}
+	info := wasmPlugin.Info()
+	loaded := &LoadedPlugin{
+		Info:     info,
+		Instance: wasmPlugin.Instance(),
+		Path:     path,
+		Runtime:  models.PluginRuntimeWASM,
+		WASM:     wasmPlugin,
+	}
+
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	if previous, exists := pl.loadedPlugins[info.ID]; exists {
+		previous.close(pl.logger)
+	}
+	pl.loadedPlugins[info.ID] = loaded
+	return loaded, nil
+}
+
+// close stops an RPC plugin or releases a WASM plugin
+// Go's plugin package can't unload, so native plugins stay in memory
+func (lp *LoadedPlugin) close(logger *zap.Logger) {
+	var err error
+	switch {
+	case lp.RPC != nil:
+		err = lp.RPC.Close()
+	case lp.WASM != nil:
+		err = lp.WASM.Close()
+	}
+	if err != nil {
+		logger.Warn("Failed to close plugin", zap.String("id", lp.Info.ID), zap.Error(err))
+	}
+}
+
+// registerTransforms registers custom transforms provided by a plugin instance
+// Transforms whose names are already taken are skipped with a warning
+func (pl *PluginLoader) registerTransforms(loaded *LoadedPlugin) {
+	provider, ok := loaded.Instance.(plugins.TransformProvider)
+	if !ok {
+		return
+	}
+	for _, t := range provider.Transforms() {
+		if err := extraction.RegisterTransform(t); err != nil {
+			pl.logger.Warn("Skipping plugin transform",
+				zap.String("plugin", loaded.Info.ID),
+				zap.String("transform", t.Name),
+				zap.Error(err))
+			continue
+		}
+		loaded.Transforms = append(loaded.Transforms, t.Name)
+	}
+}
+
 // GetPlugin retrieves a loaded plugin by ID
 func (pl *PluginLoader) GetPlugin(pluginID string) (*LoadedPlugin, error) {
 	pl.mu.RLock()
@@ -128,13 +254,17 @@ func (pl *PluginLoader) UnloadPlugin(pluginID string) error {
 	}
 
 	delete(pl.loadedPlugins, pluginID)
+	pl.retireInstances(pluginID)
+	for _, name := range loaded.Transforms {
+		extraction.UnregisterTransform(name)
+	}
+
+	loaded.close(pl.logger)
 
 	pl.logger.Info("Unloaded plugin",
 		zap.String("id", pluginID),
 		zap.String("name", loaded.Info.Name))
 
-	// Note: Go's plugin package doesn't support actual unloading
-	// The plugin stays in memory, we just remove our reference
 	return nil
 }
 
@@ -176,6 +306,21 @@ func (pl *PluginLoader) ReloadPlugin(pluginID string, newPath string) error {
 
 // ValidatePlugin checks if a plugin file is valid before loading
 func (pl *PluginLoader) ValidatePlugin(path string) error {
+	if filepath.Ext(path) == ".wasm" {
+		wasmPlugin, err := LoadWASMPlugin(path, pl.logger)
+		if err != nil {
+			return fmt.Errorf("invalid WASM plugin: %w", err)
+		}
+		return wasmPlugin.Close()
+	}
+	if filepath.Ext(path) != ".so" {
+		rpcPlugin, err := StartRPCPlugin(path, pl.logger)
+		if err != nil {
+			return fmt.Errorf("invalid plugin executable: %w", err)
+		}
+		return rpcPlugin.Close()
+	}
+
 	// Try to open the plugin without loading it into our map
 	p, err := plugin.Open(path)
 	if err != nil {
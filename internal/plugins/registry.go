@@ -0,0 +1,279 @@
+package plugins
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// A plugin registry is a directory of bundles with an index.json listing them. Any file server can
+// serve it: the index links each bundle by a URL relative to the index, and carries the bundle's
+// SHA-256 so a tampered or truncated download is rejected before it is read.
+const RegistryIndexName = "index.json"
+
+// RegistryIndex is the index.json of a registry
+type RegistryIndex struct {
+	FormatVersion int              `json:"format_version"`
+	GeneratedAt   time.Time        `json:"generated_at"`
+	Plugins       []RegistryPlugin `json:"plugins"`
+}
+
+// RegistryPlugin is a plugin in a registry with its published bundles
+type RegistryPlugin struct {
+	BundlePlugin
+	Versions []RegistryVersion `json:"versions"`
+}
+
+// RegistryVersion is one bundle in a registry
+type RegistryVersion struct {
+	Version            string `json:"version"`
+	IsStable           bool   `json:"is_stable"`
+	MinCrawlifyVersion string `json:"min_crawlify_version,omitempty"`
+	Runtime            string `json:"runtime"`
+	OS                 string `json:"os,omitempty"`
+	Arch               string `json:"arch,omitempty"`
+	Bundle             string `json:"bundle"` // URL of the bundle, relative to the index
+	SHA256             string `json:"sha256"` // Of the bundle file
+	Size               int64  `json:"size"`
+	KeyID              string `json:"key_id,omitempty"` // Key the bundle is signed with; "" when unsigned
+}
+
+// ErrRegistryPluginNotFound is returned by RegistryIndex.Find for a plugin the registry does not list
+var ErrRegistryPluginNotFound = errors.New("plugin not found in registry")
+
+// Find picks the bundle to import: the newest version of slug satisfying constraint (only stable
+// versions when it is empty) that runs on this platform and this Crawlify version
+func (idx *RegistryIndex) Find(slug, constraint string) (*RegistryPlugin, *RegistryVersion, error) {
+	var c *semver.Constraints
+	if constraint != "" {
+		var err error
+		if c, err = semver.NewConstraint(constraint); err != nil {
+			return nil, nil, fmt.Errorf("invalid version constraint '%s': %w", constraint, err)
+		}
+	}
+	for i := range idx.Plugins {
+		p := &idx.Plugins[i]
+		if p.Slug != slug {
+			continue
+		}
+		var best *RegistryVersion
+		var bestVersion *semver.Version
+		for j := range p.Versions {
+			candidate := &p.Versions[j]
+			v, err := semver.NewVersion(candidate.Version)
+			if err != nil || c == nil && !candidate.IsStable || c != nil && !c.Check(v) {
+				continue
+			}
+			if candidate.OS != "" && (candidate.OS != runtime.GOOS || candidate.Arch != runtime.GOARCH) {
+				continue
+			}
+			if CheckCrawlifyVersion(candidate.MinCrawlifyVersion) != nil {
+				continue
+			}
+			if bestVersion == nil || v.GreaterThan(bestVersion) {
+				best, bestVersion = candidate, v
+			}
+		}
+		if best == nil {
+			return nil, nil, fmt.Errorf("%w: plugin %s has no version for %s/%s matching '%s'", ErrNoMatchingVersion, slug, runtime.GOOS, runtime.GOARCH, constraint)
+		}
+		return p, best, nil
+	}
+	return nil, nil, fmt.Errorf("%w: %s", ErrRegistryPluginNotFound, slug)
+}
+
+// RegistryClient reads a registry over HTTP
+type RegistryClient struct {
+	IndexURL string
+	MaxBytes int64 // Largest bundle that will be downloaded
+	HTTP     *http.Client
+}
+
+// NewRegistryClient creates a client for the registry whose index is at indexURL
+func NewRegistryClient(indexURL string, maxBytes int64) *RegistryClient {
+	return &RegistryClient{
+		IndexURL: indexURL,
+		MaxBytes: maxBytes,
+		HTTP:     &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+// FetchIndex downloads the registry's index
+func (c *RegistryClient) FetchIndex(ctx context.Context) (*RegistryIndex, error) {
+	body, err := c.get(ctx, c.IndexURL)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	var idx RegistryIndex
+	if err := json.NewDecoder(io.LimitReader(body, 32<<20)).Decode(&idx); err != nil {
+		return nil, fmt.Errorf("invalid registry index: %w", err)
+	}
+	return &idx, nil
+}
+
+// FetchBundle downloads a bundle of the registry into w, checking its size and SHA-256
+func (c *RegistryClient) FetchBundle(ctx context.Context, v *RegistryVersion, w io.Writer) error {
+	bundleURL, err := c.resolve(v.Bundle)
+	if err != nil {
+		return err
+	}
+	body, err := c.get(ctx, bundleURL)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(w, h), io.LimitReader(body, c.MaxBytes+1))
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %w", bundleURL, err)
+	}
+	if n > c.MaxBytes {
+		return fmt.Errorf("bundle %s is larger than %d bytes", bundleURL, c.MaxBytes)
+	}
+	if sum := hex.EncodeToString(h.Sum(nil)); !strings.EqualFold(sum, v.SHA256) {
+		return fmt.Errorf("bundle %s has SHA-256 %s, registry says %s", bundleURL, sum, v.SHA256)
+	}
+	return nil
+}
+
+// resolve returns the absolute URL of a link in the index
+func (c *RegistryClient) resolve(ref string) (string, error) {
+	base, err := url.Parse(c.IndexURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid registry URL '%s': %w", c.IndexURL, err)
+	}
+	target, err := url.Parse(ref)
+	if err != nil {
+		return "", fmt.Errorf("invalid bundle URL '%s': %w", ref, err)
+	}
+	return base.ResolveReference(target).String(), nil
+}
+
+func (c *RegistryClient) get(ctx context.Context, target string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %w", target, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to fetch %s: %s", target, resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// WriteRegistryIndex scans a directory of bundles and writes its index.json
+// Files that are not readable bundles are skipped.
+func WriteRegistryIndex(dir string) (*RegistryIndex, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	byslug := make(map[string]*RegistryPlugin)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".tar.gz") {
+			continue
+		}
+		version, plugin, err := indexBundle(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		p, ok := byslug[plugin.Slug]
+		if !ok {
+			p = &RegistryPlugin{}
+			byslug[plugin.Slug] = p
+		}
+		// The newest version's metadata describes the plugin
+		if len(p.Versions) == 0 || semverGreater(version.Version, newestVersion(p.Versions)) {
+			p.BundlePlugin = plugin
+		}
+		p.Versions = append(p.Versions, version)
+	}
+
+	idx := &RegistryIndex{FormatVersion: BundleFormatVersion, GeneratedAt: time.Now().UTC(), Plugins: []RegistryPlugin{}}
+	for _, p := range byslug {
+		sort.Slice(p.Versions, func(i, j int) bool { return semverGreater(p.Versions[i].Version, p.Versions[j].Version) })
+		idx.Plugins = append(idx.Plugins, *p)
+	}
+	sort.Slice(idx.Plugins, func(i, j int) bool { return idx.Plugins[i].Slug < idx.Plugins[j].Slug })
+
+	data, err := json.MarshalIndent(idx, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	tmp := filepath.Join(dir, RegistryIndexName+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return nil, err
+	}
+	return idx, os.Rename(tmp, filepath.Join(dir, RegistryIndexName))
+}
+
+// indexBundle reads the index entry of a bundle file
+func indexBundle(bundlePath string) (RegistryVersion, BundlePlugin, error) {
+	f, err := os.Open(bundlePath)
+	if err != nil {
+		return RegistryVersion{}, BundlePlugin{}, err
+	}
+	defer f.Close()
+	manifest, _, sig, err := ReadBundleManifest(f)
+	if err != nil {
+		return RegistryVersion{}, BundlePlugin{}, err
+	}
+	hash, size, err := fileHashAndSize(bundlePath)
+	if err != nil {
+		return RegistryVersion{}, BundlePlugin{}, err
+	}
+	version := RegistryVersion{
+		Version:            manifest.Version,
+		IsStable:           manifest.IsStable,
+		MinCrawlifyVersion: manifest.MinCrawlifyVersion,
+		Runtime:            string(manifest.Runtime),
+		OS:                 manifest.OS,
+		Arch:               manifest.Arch,
+		Bundle:             url.PathEscape(filepath.Base(bundlePath)),
+		SHA256:             hash,
+		Size:               size,
+	}
+	if sig != nil {
+		version.KeyID = sig.KeyID
+	}
+	return version, manifest.Plugin, nil
+}
+
+func newestVersion(versions []RegistryVersion) string {
+	newest := versions[0].Version
+	for _, v := range versions[1:] {
+		if semverGreater(v.Version, newest) {
+			newest = v.Version
+		}
+	}
+	return newest
+}
+
+func semverGreater(a, b string) bool {
+	va, errA := semver.NewVersion(a)
+	vb, errB := semver.NewVersion(b)
+	if errA != nil || errB != nil {
+		return a > b
+	}
+	return va.GreaterThan(vb)
+}
@@ -0,0 +1,516 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/uzzalhcse/crawlify/pkg/models"
+	"github.com/uzzalhcse/crawlify/pkg/plugins"
+	"go.uber.org/zap"
+)
+
+const (
+	rpcHandshakeTimeout = 10 * time.Second
+	rpcShutdownTimeout  = 5 * time.Second
+	rpcLifecycleTimeout = time.Minute // Init may open connections or warm caches
+	rpcMaxRestarts      = 5           // Restarts allowed within rpcRestartWindow before the plugin is given up on
+	rpcRestartWindow    = time.Minute
+)
+
+// RPCPlugin runs a plugin executable as a subprocess speaking the RPC plugin protocol over stdio
+// A crash only fails the calls in flight; the next call restarts the process
+type RPCPlugin struct {
+	path   string
+	logger *zap.Logger
+
+	mu        sync.Mutex
+	proc      *rpcProcess
+	handshake plugins.HandshakeResult
+	restarts  []time.Time
+	closed    bool
+	config    map[string]interface{} // Set by Init; sent again to a restarted process
+	inited    bool
+	active    map[string]bool // Executions started on the plugin; started again on a restarted process
+
+	sessions    sync.Map // session ID -> *rpcSession of the call
+	nextSession atomic.Uint64
+}
+
+// rpcProcess is one run of the plugin executable
+type rpcProcess struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	conn   *plugins.Conn
+	exited chan struct{}
+}
+
+// StartRPCPlugin starts the plugin executable and performs the handshake
+func StartRPCPlugin(path string, logger *zap.Logger) (*RPCPlugin, error) {
+	p := &RPCPlugin{
+		path:   path,
+		logger: logger.With(zap.String("plugin_path", path)),
+	}
+	if _, err := p.process(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Kind returns discovery or extraction
+func (p *RPCPlugin) Kind() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.handshake.Kind
+}
+
+// Info returns the plugin metadata from the handshake
+func (p *RPCPlugin) Info() plugins.PluginInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.handshake.Info
+}
+
+// ConfigSchema returns the configuration schema from the handshake
+func (p *RPCPlugin) ConfigSchema() map[string]interface{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.handshake.ConfigSchema
+}
+
+// Validate asks the plugin to validate a configuration
+func (p *RPCPlugin) Validate(config map[string]interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), rpcHandshakeTimeout)
+	defer cancel()
+	return p.call(ctx, plugins.MethodValidate, plugins.ValidateParams{Config: config}, nil)
+}
+
+// Init forwards plugins.Initializer; the config is sent again whenever the process restarts
+func (p *RPCPlugin) Init(config map[string]interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), rpcLifecycleTimeout)
+	defer cancel()
+	if err := p.call(ctx, plugins.MethodInit, plugins.InitParams{Config: config}, nil); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.config, p.inited = config, true
+	p.mu.Unlock()
+	return nil
+}
+
+// OnExecutionStart forwards plugins.ExecutionHooks
+func (p *RPCPlugin) OnExecutionStart(ctx context.Context, executionID string) error {
+	if err := p.call(ctx, plugins.MethodExecutionStart, plugins.ExecutionParams{ExecutionID: executionID}, nil); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	if p.active == nil {
+		p.active = make(map[string]bool)
+	}
+	p.active[executionID] = true
+	p.mu.Unlock()
+	return nil
+}
+
+// OnExecutionEnd forwards plugins.ExecutionHooks
+func (p *RPCPlugin) OnExecutionEnd(ctx context.Context, executionID string) error {
+	p.mu.Lock()
+	delete(p.active, executionID)
+	p.mu.Unlock()
+	return p.call(ctx, plugins.MethodExecutionEnd, plugins.ExecutionParams{ExecutionID: executionID}, nil)
+}
+
+// Instance returns the plugin as a DiscoveryPlugin or ExtractionPlugin, according to its handshake
+func (p *RPCPlugin) Instance() interface{} {
+	if p.Kind() == plugins.PluginKindDiscovery {
+		return &rpcDiscoveryPlugin{p}
+	}
+	return &rpcExtractionPlugin{p}
+}
+
+// Close asks the plugin to shut down and stops the process
+func (p *RPCPlugin) Close() error {
+	p.mu.Lock()
+	p.closed = true
+	proc := p.proc
+	p.proc = nil
+	p.mu.Unlock()
+
+	if proc == nil {
+		return nil
+	}
+	return p.stop(proc)
+}
+
+// stop shuts a process down gracefully, killing it when it does not exit in time
+func (p *RPCPlugin) stop(proc *rpcProcess) error {
+	ctx, cancel := context.WithTimeout(context.Background(), rpcShutdownTimeout)
+	defer cancel()
+	proc.conn.Call(ctx, plugins.MethodShutdown, struct{}{}, nil)
+
+	proc.stdin.Close()
+	select {
+	case <-proc.exited:
+		return nil
+	case <-ctx.Done():
+		return proc.cmd.Process.Kill()
+	}
+}
+
+// process returns the running process, restarting it if it exited
+func (p *RPCPlugin) process() (*rpcProcess, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, fmt.Errorf("plugin %s is closed", p.path)
+	}
+	if p.proc != nil {
+		select {
+		case <-p.proc.exited:
+		case <-p.proc.conn.Done(): // Closed as soon as the pipe breaks, before the exit is reaped
+		default:
+			return p.proc, nil
+		}
+
+		// The process crashed: restart it unless it keeps crashing
+		now := time.Now()
+		recent := p.restarts[:0]
+		for _, t := range p.restarts {
+			if now.Sub(t) < rpcRestartWindow {
+				recent = append(recent, t)
+			}
+		}
+		p.restarts = recent
+		if len(p.restarts) >= rpcMaxRestarts {
+			return nil, fmt.Errorf("plugin %s crashed %d times in %s; not restarting", p.handshake.Info.Name, len(p.restarts), rpcRestartWindow)
+		}
+		p.restarts = append(p.restarts, now)
+		p.logger.Warn("Restarting crashed RPC plugin", zap.Int("restarts", len(p.restarts)))
+	}
+
+	proc, handshake, err := p.start()
+	if err != nil {
+		return nil, err
+	}
+	if p.inited {
+		// A restarted process has lost the state Init set up
+		ctx, cancel := context.WithTimeout(context.Background(), rpcLifecycleTimeout)
+		err := proc.conn.Call(ctx, plugins.MethodInit, plugins.InitParams{Config: p.config}, nil)
+		cancel()
+		if err != nil {
+			p.stop(proc)
+			return nil, fmt.Errorf("plugin %s failed to initialise after restart: %w", p.path, err)
+		}
+	}
+	for executionID := range p.active {
+		ctx, cancel := context.WithTimeout(context.Background(), rpcLifecycleTimeout)
+		err := proc.conn.Call(ctx, plugins.MethodExecutionStart, plugins.ExecutionParams{ExecutionID: executionID}, nil)
+		cancel()
+		if err != nil {
+			p.stop(proc)
+			return nil, fmt.Errorf("plugin %s failed to restart execution %s: %w", p.path, executionID, err)
+		}
+	}
+	if p.handshake.Info.ID != "" && handshake.Info.ID != p.handshake.Info.ID {
+		p.logger.Warn("RPC plugin identity changed on restart",
+			zap.String("was", p.handshake.Info.ID),
+			zap.String("now", handshake.Info.ID))
+	}
+	p.proc = proc
+	p.handshake = *handshake
+	return proc, nil
+}
+
+// start launches the executable and performs the handshake
+func (p *RPCPlugin) start() (*rpcProcess, *plugins.HandshakeResult, error) {
+	cmd := exec.Command(p.path)
+	cmd.Stderr = &logWriter{logger: p.logger}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, nil, fmt.Errorf("failed to start plugin %s: %w", p.path, err)
+	}
+
+	proc := &rpcProcess{
+		cmd:    cmd,
+		stdin:  stdin,
+		conn:   plugins.NewConn(stdout, stdin, p.handleCallback),
+		exited: make(chan struct{}),
+	}
+	go func() {
+		connErr := proc.conn.Serve()
+		waitErr := cmd.Wait()
+		if connErr != plugins.ErrConnClosed || waitErr != nil {
+			p.logger.Warn("RPC plugin exited", zap.NamedError("connection", connErr), zap.NamedError("process", waitErr))
+		}
+		close(proc.exited)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), rpcHandshakeTimeout)
+	defer cancel()
+	var handshake plugins.HandshakeResult
+	err = proc.conn.Call(ctx, plugins.MethodHandshake, plugins.HandshakeParams{ProtocolVersion: plugins.ProtocolVersion}, &handshake)
+	if err == nil && handshake.ProtocolVersion != plugins.ProtocolVersion {
+		err = fmt.Errorf("plugin speaks protocol version %d, host speaks %d", handshake.ProtocolVersion, plugins.ProtocolVersion)
+	}
+	if err == nil && handshake.Kind != plugins.PluginKindDiscovery && handshake.Kind != plugins.PluginKindExtraction {
+		err = fmt.Errorf("unknown plugin kind '%s'", handshake.Kind)
+	}
+	if err != nil {
+		cmd.Process.Kill()
+		return nil, nil, fmt.Errorf("plugin %s handshake failed: %w", p.path, err)
+	}
+
+	p.logger.Info("Started RPC plugin",
+		zap.String("id", handshake.Info.ID),
+		zap.String("name", handshake.Info.Name),
+		zap.String("version", handshake.Info.Version),
+		zap.Int("pid", cmd.Process.Pid))
+	return proc, &handshake, nil
+}
+
+// call sends a request to the plugin, starting it if needed
+func (p *RPCPlugin) call(ctx context.Context, method string, params, result interface{}) error {
+	proc, err := p.process()
+	if err != nil {
+		return err
+	}
+	return p.callProcess(ctx, proc, method, params, result)
+}
+
+// callProcess sends a request to a running plugin process
+func (p *RPCPlugin) callProcess(ctx context.Context, proc *rpcProcess, method string, params, result interface{}) error {
+	err := proc.conn.Call(ctx, method, params, result)
+	if errors.Is(err, plugins.ErrConnClosed) {
+		return fmt.Errorf("plugin %s crashed during %s: %w", p.Info().Name, method, err)
+	}
+	var rpcErr *plugins.RPCError
+	if errors.As(err, &rpcErr) && rpcErr.Code == plugins.RPCCodePluginError {
+		return errors.New(rpcErr.Message)
+	}
+	return err
+}
+
+// rpcSession is what a running call's requests to the host can reach
+type rpcSession struct {
+	browser        plugins.Browser
+	http           *plugins.HTTPClient
+	workflowStore  plugins.KVStore
+	executionStore plugins.KVStore
+}
+
+// session registers the page and services of a call so the plugin's requests can reach them
+func (p *RPCPlugin) session(s *rpcSession) (string, func()) {
+	id := strconv.FormatUint(p.nextSession.Add(1), 10)
+	p.sessions.Store(id, s)
+	return id, func() { p.sessions.Delete(id) }
+}
+
+// handleCallback serves the plugin's browser, http.fetch and kv.* requests
+func (p *RPCPlugin) handleCallback(ctx context.Context, method string, raw json.RawMessage) (interface{}, error) {
+	var ref struct {
+		Session string `json:"session"`
+	}
+	if err := json.Unmarshal(raw, &ref); err != nil {
+		return nil, &plugins.RPCError{Code: plugins.RPCCodeInvalidParams, Message: err.Error()}
+	}
+	value, ok := p.sessions.Load(ref.Session)
+	if !ok {
+		return nil, fmt.Errorf("unknown or finished session '%s'", ref.Session)
+	}
+	s := value.(*rpcSession)
+
+	switch method {
+	case plugins.MethodHTTPFetch:
+		var params plugins.HTTPFetchParams
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return nil, &plugins.RPCError{Code: plugins.RPCCodeInvalidParams, Message: err.Error()}
+		}
+		return plugins.ServeHTTPFetch(ctx, s.http, params)
+	case plugins.MethodKVGet, plugins.MethodKVSet, plugins.MethodKVDelete, plugins.MethodKVKeys:
+		var params plugins.KVParams
+		if err := json.Unmarshal(raw, &params); err != nil {
+			return nil, &plugins.RPCError{Code: plugins.RPCCodeInvalidParams, Message: err.Error()}
+		}
+		return plugins.ServeKV(ctx, s.workflowStore, s.executionStore, method, params)
+	}
+
+	var params plugins.BrowserParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, &plugins.RPCError{Code: plugins.RPCCodeInvalidParams, Message: err.Error()}
+	}
+	return plugins.ServeBrowser(s.browser, method, params)
+}
+
+// callSession sends a plugin.discover or plugin.extract call for a session, telling the plugin
+// to cancel it when ctx ends first
+func (p *RPCPlugin) callSession(ctx context.Context, method string, params *plugins.CallParams, result interface{}) error {
+	proc, err := p.process()
+	if err != nil {
+		return err
+	}
+	err = p.callProcess(ctx, proc, method, params, result)
+	if ctx.Err() != nil {
+		proc.conn.Notify(plugins.MethodCancel, plugins.CancelParams{Session: params.Session})
+	}
+	return err
+}
+
+func (p *RPCPlugin) discover(ctx context.Context, input *plugins.DiscoveryInput) (*plugins.DiscoveryOutput, error) {
+	session, done := p.session(&rpcSession{
+		browser:        inputBrowser(input.Browser, input),
+		http:           input.HTTP,
+		workflowStore:  input.WorkflowStore,
+		executionStore: input.ExecutionStore,
+	})
+	defer done()
+
+	var result plugins.DiscoverResult
+	err := p.callSession(ctx, plugins.MethodDiscover, &plugins.CallParams{
+		Session:          session,
+		URL:              input.URL,
+		URLItem:          input.URLItem,
+		ExecutionContext: input.ExecutionContext,
+		Config:           input.Config,
+		ExecutionID:      input.ExecutionID,
+		HTTP:             input.HTTP != nil,
+		Stores:           input.WorkflowStore != nil,
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+	mergeContext(input.ExecutionContext, result.ExecutionContext)
+	if result.Output == nil {
+		result.Output = &plugins.DiscoveryOutput{}
+	}
+	return result.Output, nil
+}
+
+func (p *RPCPlugin) extract(ctx context.Context, input *plugins.ExtractionInput) (*plugins.ExtractionOutput, error) {
+	session, done := p.session(&rpcSession{
+		browser:        inputBrowser(input.Browser, input),
+		http:           input.HTTP,
+		workflowStore:  input.WorkflowStore,
+		executionStore: input.ExecutionStore,
+	})
+	defer done()
+
+	var result plugins.ExtractResult
+	err := p.callSession(ctx, plugins.MethodExtract, &plugins.CallParams{
+		Session:          session,
+		URL:              input.URL,
+		URLItem:          input.URLItem,
+		ExecutionContext: input.ExecutionContext,
+		Config:           input.Config,
+		ExecutionID:      input.ExecutionID,
+		HTTP:             input.HTTP != nil,
+		Stores:           input.WorkflowStore != nil,
+	}, &result)
+	if err != nil {
+		return nil, err
+	}
+	mergeContext(input.ExecutionContext, result.ExecutionContext)
+	if result.Output == nil {
+		result.Output = &plugins.ExtractionOutput{}
+	}
+	return result.Output, nil
+}
+
+// inputBrowser returns the call's Browser, wrapping its browser context when the caller did not set one
+func inputBrowser(browser plugins.Browser, input interface{}) plugins.Browser {
+	if browser != nil {
+		return browser
+	}
+	switch in := input.(type) {
+	case *plugins.DiscoveryInput:
+		return plugins.NewLocalBrowser(in.BrowserContext)
+	case *plugins.ExtractionInput:
+		return plugins.NewLocalBrowser(in.BrowserContext)
+	}
+	return nil
+}
+
+// mergeContext applies the values a plugin changed in its copy of the execution context
+func mergeContext(dst, src *models.ExecutionContext) {
+	if dst == nil || src == nil {
+		return
+	}
+	for key, value := range src.Data {
+		if current, ok := dst.Data[key]; ok && jsonEqual(current, value) {
+			continue
+		}
+		dst.Set(key, value)
+	}
+	for key, value := range src.Variables {
+		dst.SetVariable(key, value)
+	}
+}
+
+func jsonEqual(a, b interface{}) bool {
+	left, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	right, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(left, right)
+}
+
+// rpcDiscoveryPlugin exposes a discovery RPC plugin as a DiscoveryPlugin
+type rpcDiscoveryPlugin struct {
+	*RPCPlugin
+}
+
+func (p *rpcDiscoveryPlugin) Discover(ctx context.Context, input *plugins.DiscoveryInput) (*plugins.DiscoveryOutput, error) {
+	return p.discover(ctx, input)
+}
+
+// rpcExtractionPlugin exposes an extraction RPC plugin as an ExtractionPlugin
+type rpcExtractionPlugin struct {
+	*RPCPlugin
+}
+
+func (p *rpcExtractionPlugin) Extract(ctx context.Context, input *plugins.ExtractionInput) (*plugins.ExtractionOutput, error) {
+	return p.extract(ctx, input)
+}
+
+// logWriter forwards a plugin's stderr to the logger, one entry per line
+type logWriter struct {
+	logger *zap.Logger
+	mu     sync.Mutex
+	buf    []byte
+}
+
+func (w *logWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf = append(w.buf, data...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		if line := bytes.TrimSpace(w.buf[:i]); len(line) > 0 {
+			w.logger.Info("plugin: " + string(line))
+		}
+		w.buf = w.buf[i+1:]
+	}
+	return len(data), nil
+}
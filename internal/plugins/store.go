@@ -0,0 +1,349 @@
+package plugins
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/uzzalhcse/crawlify/pkg/models"
+	"github.com/uzzalhcse/crawlify/pkg/version"
+)
+
+// Installed plugin versions are stored side by side as <PluginDir>/<slug>/<version>/<slug>[.so|.wasm]
+// and never change once written. <PluginDir>/<slug>/installed holds the version the installation
+// locked; plugin nodes without a plugin_version constraint use it. Files directly in PluginDir
+// (<slug>.so, <slug>.wasm, <slug>) are development builds, used when no version is installed.
+const lockFileName = "installed"
+
+// ArtifactPath returns where a plugin version's binary is stored; pluginVersion must be canonical
+func ArtifactPath(slug, pluginVersion string, runtime models.PluginRuntime) string {
+	return filepath.Join(PluginDir, slug, pluginVersion, slug+runtimeExt(runtime))
+}
+
+// DevBuildPath returns the unversioned development build of a plugin
+func DevBuildPath(slug string, runtime models.PluginRuntime) string {
+	return filepath.Join(PluginDir, slug+runtimeExt(runtime))
+}
+
+func runtimeExt(runtime models.PluginRuntime) string {
+	switch runtime {
+	case models.PluginRuntimeWASM:
+		return ".wasm"
+	case models.PluginRuntimeRPC:
+		return ""
+	}
+	return ".so"
+}
+
+// CanonicalVersion normalises a plugin version for use in the store, e.g. "v1.2" becomes "1.2.0"
+func CanonicalVersion(pluginVersion string) (string, error) {
+	v, err := semver.NewVersion(pluginVersion)
+	if err != nil {
+		return "", fmt.Errorf("invalid plugin version '%s': %w", pluginVersion, err)
+	}
+	return v.String(), nil
+}
+
+// InstallArtifact copies a plugin binary into the store as the given version and returns its path
+// A non-empty hash must match the SHA-256 of the binary. An artifact already stored for the version
+// is kept as is when its content matches, and is an error otherwise: versions are immutable.
+func InstallArtifact(slug, pluginVersion string, runtime models.PluginRuntime, src, hash string) (string, error) {
+	pluginVersion, err := CanonicalVersion(pluginVersion)
+	if err != nil {
+		return "", err
+	}
+	srcHash, err := fileHash(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to read plugin binary: %w", err)
+	}
+	if hash != "" && !strings.EqualFold(hash, srcHash) {
+		return "", fmt.Errorf("plugin binary %s has SHA-256 %s, expected %s", src, srcHash, hash)
+	}
+
+	dst := ArtifactPath(slug, pluginVersion, runtime)
+	if existing, err := fileHash(dst); err == nil {
+		if existing != srcHash {
+			return "", fmt.Errorf("plugin %s version %s is already installed with different content; publish a new version", slug, pluginVersion)
+		}
+		return dst, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return "", err
+	}
+	defer in.Close()
+	tmp, err := os.CreateTemp(filepath.Dir(dst), ".install-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, in); err != nil {
+		tmp.Close()
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", err
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), dst); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
+
+// InstalledVersions returns the versions of a plugin in the store, newest first
+func InstalledVersions(slug string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(PluginDir, slug))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var versions []*semver.Version
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if v, err := semver.StrictNewVersion(entry.Name()); err == nil {
+			versions = append(versions, v)
+		}
+	}
+	sort.Sort(sort.Reverse(semver.Collection(versions)))
+	result := make([]string, len(versions))
+	for i, v := range versions {
+		result[i] = v.Original()
+	}
+	return result, nil
+}
+
+// LockedVersion returns the version the installation of a plugin locked, or "" when it is not installed
+func LockedVersion(slug string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(PluginDir, slug, lockFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// LockVersion makes a stored version the installed version of a plugin
+func LockVersion(slug, pluginVersion string) error {
+	path := filepath.Join(PluginDir, slug, lockFileName)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(pluginVersion+"\n"), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// UnlockVersion removes the installed version of a plugin; its stored versions are kept
+func UnlockVersion(slug string) error {
+	err := os.Remove(filepath.Join(PluginDir, slug, lockFileName))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// ResolveVersion picks the version a plugin node uses
+// Without a constraint that is the locked version; with one, the newest stored version satisfying it.
+// "" with no error means no version is installed and the development build should be used.
+func ResolveVersion(slug, constraint string) (string, error) {
+	installed, err := InstalledVersions(slug)
+	if err != nil {
+		return "", err
+	}
+	locked, err := LockedVersion(slug)
+	if err != nil {
+		return "", err
+	}
+	return SelectVersion(installed, locked, constraint)
+}
+
+// SelectVersion applies the rules of ResolveVersion to a given set of stored versions
+func SelectVersion(installed []string, locked, constraint string) (string, error) {
+	if constraint == "" {
+		return locked, nil
+	}
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return "", fmt.Errorf("invalid plugin_version constraint '%s': %w", constraint, err)
+	}
+	best := ""
+	var bestVersion *semver.Version
+	for _, candidate := range installed {
+		v, err := semver.NewVersion(candidate)
+		if err != nil || !c.Check(v) {
+			continue
+		}
+		if bestVersion == nil || v.GreaterThan(bestVersion) {
+			best, bestVersion = candidate, v
+		}
+	}
+	if best == "" {
+		return "", fmt.Errorf("no installed version satisfies '%s' (installed: %s)", constraint, strings.Join(installed, ", "))
+	}
+	return best, nil
+}
+
+// Errors of SelectRelease
+var (
+	ErrNoMatchingVersion   = errors.New("no published version matches")
+	ErrIncompatibleVersion = errors.New("no matching version supports this Crawlify version")
+)
+
+// SelectRelease picks the published version to install: the newest one satisfying constraint
+// (only stable versions when constraint is empty) whose min_crawlify_version this Crawlify meets
+func SelectRelease(versions []*models.PluginVersion, constraint string) (*models.PluginVersion, error) {
+	var c *semver.Constraints
+	if constraint != "" {
+		var err error
+		if c, err = semver.NewConstraint(constraint); err != nil {
+			return nil, fmt.Errorf("invalid version constraint '%s': %w", constraint, err)
+		}
+	}
+
+	var best *models.PluginVersion
+	var bestVersion *semver.Version
+	var incompatible []string
+	for _, candidate := range versions {
+		v, err := semver.NewVersion(candidate.Version)
+		if err != nil {
+			continue
+		}
+		if c == nil && !candidate.IsStable || c != nil && !c.Check(v) {
+			continue
+		}
+		if err := CheckCrawlifyVersion(candidate.MinCrawlifyVersion); err != nil {
+			incompatible = append(incompatible, candidate.Version+" "+err.Error())
+			continue
+		}
+		if bestVersion == nil || v.GreaterThan(bestVersion) {
+			best, bestVersion = candidate, v
+		}
+	}
+	switch {
+	case best != nil:
+		return best, nil
+	case len(incompatible) > 0:
+		return nil, fmt.Errorf("%w: %s", ErrIncompatibleVersion, strings.Join(incompatible, "; "))
+	case constraint != "":
+		return nil, fmt.Errorf("%w '%s'", ErrNoMatchingVersion, constraint)
+	}
+	return nil, ErrNoMatchingVersion
+}
+
+// CheckCrawlifyVersion returns an error when this Crawlify is older than a plugin version requires
+func CheckCrawlifyVersion(minVersion string) error {
+	if minVersion == "" {
+		return nil
+	}
+	required, err := semver.NewVersion(minVersion)
+	if err != nil {
+		return fmt.Errorf("invalid min_crawlify_version '%s': %w", minVersion, err)
+	}
+	current, err := semver.NewVersion(version.Version)
+	if err != nil {
+		return fmt.Errorf("invalid Crawlify version '%s': %w", version.Version, err)
+	}
+	if current.LessThan(required) {
+		return fmt.Errorf("requires Crawlify %s or newer, this is %s", minVersion, version.Version)
+	}
+	return nil
+}
+
+// ResolveWorkflowPlugins records the version each plugin node of a workflow resolves to now
+func ResolveWorkflowPlugins(config *models.WorkflowConfig) []models.ResolvedPlugin {
+	refs := config.PluginRefs()
+	resolved := make([]models.ResolvedPlugin, 0, len(refs))
+	for _, ref := range refs {
+		pluginVersion, _ := ResolveVersion(ref.Slug, ref.Constraint)
+		resolved = append(resolved, models.ResolvedPlugin{PluginRef: ref, Version: pluginVersion})
+	}
+	return resolved
+}
+
+// NodeUpgrade describes how installing a new version of a plugin changes one plugin node
+type NodeUpgrade struct {
+	models.PluginRef
+	CurrentVersion string `json:"current_version"` // "" when the node uses the development build
+	NewVersion     string `json:"new_version"`
+	Affected       bool   `json:"affected"`
+	Error          string `json:"error,omitempty"` // Why the node cannot resolve a version today
+}
+
+// UpgradeImpact reports how the nodes in refs using slug would resolve once targetVersion is
+// installed and locked. Nodes whose plugin_version excludes targetVersion are not affected.
+func UpgradeImpact(slug, targetVersion string, refs []models.PluginRef) ([]NodeUpgrade, error) {
+	installed, err := InstalledVersions(slug)
+	if err != nil {
+		return nil, err
+	}
+	locked, err := LockedVersion(slug)
+	if err != nil {
+		return nil, err
+	}
+	after := installed
+	found := false
+	for _, v := range installed {
+		found = found || v == targetVersion
+	}
+	if !found {
+		after = append([]string{targetVersion}, installed...)
+	}
+
+	var upgrades []NodeUpgrade
+	for _, ref := range refs {
+		if ref.Slug != slug {
+			continue
+		}
+		upgrade := NodeUpgrade{PluginRef: ref}
+		if upgrade.CurrentVersion, err = SelectVersion(installed, locked, ref.Constraint); err != nil {
+			upgrade.Error = err.Error()
+		}
+		upgrade.NewVersion, _ = SelectVersion(after, targetVersion, ref.Constraint)
+		upgrade.Affected = upgrade.NewVersion != upgrade.CurrentVersion
+		upgrades = append(upgrades, upgrade)
+	}
+	return upgrades, nil
+}
+
+// storeRoot returns PluginDir for both stored versions and development builds of a plugin binary
+func storeRoot(path, slug string) string {
+	versionDir := filepath.Dir(path)
+	if filepath.Base(filepath.Dir(versionDir)) == slug {
+		if _, err := semver.StrictNewVersion(filepath.Base(versionDir)); err == nil {
+			return filepath.Dir(filepath.Dir(versionDir))
+		}
+	}
+	return versionDir
+}
+
+func fileHash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
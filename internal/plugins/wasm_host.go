@@ -0,0 +1,548 @@
+package plugins
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/tetratelabs/wazero"
+	"github.com/tetratelabs/wazero/api"
+	"github.com/tetratelabs/wazero/imports/wasi_snapshot_preview1"
+	"github.com/uzzalhcse/crawlify/pkg/models"
+	"github.com/uzzalhcse/crawlify/pkg/plugins"
+	"go.uber.org/zap"
+)
+
+const (
+	wasmPageSize      = 64 * 1024
+	wasmMaxFetchBytes = 10 * 1024 * 1024
+)
+
+// Limits applied when the grant sets none, and the most a grant may allow
+var (
+	WASMDefaultLimits = models.PluginLimits{MemoryMB: 128, TimeoutMs: 30000}
+	WASMMaxLimits     = models.PluginLimits{MemoryMB: 1024, TimeoutMs: 300000}
+)
+
+// PluginGrant is what an admin approved for a WASM plugin at install time
+// It is stored next to the module as <slug>.grant.json; without one the plugin may only read the page
+type PluginGrant struct {
+	Capabilities models.PluginCapabilities `json:"capabilities"`
+	Limits       models.PluginLimits       `json:"limits"`
+}
+
+// GrantPath returns the grant file of a WASM module
+func GrantPath(modulePath string) string {
+	return strings.TrimSuffix(modulePath, ".wasm") + ".grant.json"
+}
+
+// ReadGrant reads the grant of a WASM module; a missing grant grants nothing
+func ReadGrant(modulePath string) (PluginGrant, error) {
+	var grant PluginGrant
+	data, err := os.ReadFile(GrantPath(modulePath))
+	if os.IsNotExist(err) {
+		return grant, nil
+	}
+	if err != nil {
+		return grant, err
+	}
+	if err := json.Unmarshal(data, &grant); err != nil {
+		return grant, fmt.Errorf("invalid grant %s: %w", GrantPath(modulePath), err)
+	}
+	return grant, nil
+}
+
+// WriteGrant stores the grant of a WASM module
+func WriteGrant(modulePath string, grant PluginGrant) error {
+	data, err := json.MarshalIndent(grant, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(modulePath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(GrantPath(modulePath), data, 0644)
+}
+
+// WASMPlugin runs a plugin compiled to WebAssembly in an embedded sandbox
+// The module has no filesystem, network or page access except through host functions,
+// which only allow the capabilities both requested in its handshake and approved in its grant.
+// Every call runs in a fresh instance bounded by the granted memory and time limits
+type WASMPlugin struct {
+	path    string
+	slug    string
+	dataDir string
+	logger  *zap.Logger
+
+	runtime   wazero.Runtime
+	compiled  wazero.CompiledModule
+	handshake plugins.HandshakeResult
+
+	capabilities models.PluginCapabilities // Requested and approved
+	limits       models.PluginLimits
+	httpClient   *http.Client
+}
+
+// LoadWASMPlugin compiles a WASM plugin module and performs the handshake
+func LoadWASMPlugin(path string, logger *zap.Logger) (*WASMPlugin, error) {
+	code, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin %s: %w", path, err)
+	}
+	grant, err := ReadGrant(path)
+	if err != nil {
+		return nil, err
+	}
+
+	limits := grant.Limits.Clamp(WASMMaxLimits)
+	if limits.MemoryMB == 0 {
+		limits.MemoryMB = WASMDefaultLimits.MemoryMB
+	}
+	if limits.TimeoutMs == 0 {
+		limits.TimeoutMs = WASMDefaultLimits.TimeoutMs
+	}
+
+	slug := strings.TrimSuffix(filepath.Base(path), ".wasm")
+	p := &WASMPlugin{
+		path:    path,
+		slug:    slug,
+		dataDir: filepath.Join(storeRoot(path, slug), "data", slug), // Shared by all versions of the plugin
+		logger:  logger.With(zap.String("plugin_path", path)),
+		limits:  limits,
+	}
+
+	ctx := context.Background()
+	runtimeConfig := wazero.NewRuntimeConfig().
+		WithMemoryLimitPages(uint32(limits.MemoryMB * 1024 * 1024 / wasmPageSize)).
+		WithCloseOnContextDone(true)
+	// Compiling a module takes seconds, so the machine code is kept next to the plugins
+	if cache, err := wazero.NewCompilationCacheWithDir(filepath.Join(storeRoot(path, slug), ".wasm-cache")); err == nil {
+		runtimeConfig = runtimeConfig.WithCompilationCache(cache)
+	}
+	p.runtime = wazero.NewRuntimeWithConfig(ctx, runtimeConfig)
+
+	if err := p.instantiateHost(ctx); err != nil {
+		p.runtime.Close(ctx)
+		return nil, err
+	}
+	if p.compiled, err = p.runtime.CompileModule(ctx, code); err != nil {
+		p.runtime.Close(ctx)
+		return nil, fmt.Errorf("failed to compile plugin %s: %w", path, err)
+	}
+	exports := p.compiled.ExportedFunctions()
+	for _, name := range []string{plugins.WASMExportMalloc, plugins.WASMExportCall} {
+		if _, ok := exports[name]; !ok {
+			p.runtime.Close(ctx)
+			return nil, fmt.Errorf("plugin %s does not export %s; build it as a reactor with pkg/plugins/wasmguest", path, name)
+		}
+	}
+
+	// The handshake runs before any capability is granted
+	var handshake plugins.HandshakeResult
+	err = p.call(ctx, plugins.MethodHandshake, plugins.HandshakeParams{ProtocolVersion: plugins.ProtocolVersion}, &handshake, nil)
+	if err == nil && handshake.ProtocolVersion != plugins.ProtocolVersion {
+		err = fmt.Errorf("plugin speaks protocol version %d, host speaks %d", handshake.ProtocolVersion, plugins.ProtocolVersion)
+	}
+	if err == nil && handshake.Kind != plugins.PluginKindDiscovery && handshake.Kind != plugins.PluginKindExtraction {
+		err = fmt.Errorf("unknown plugin kind '%s'", handshake.Kind)
+	}
+	if err != nil {
+		p.runtime.Close(ctx)
+		return nil, fmt.Errorf("plugin %s handshake failed: %w", path, err)
+	}
+	p.handshake = handshake
+
+	if handshake.Capabilities != nil {
+		p.capabilities = handshake.Capabilities.Intersect(grant.Capabilities)
+		if missing := grant.Capabilities.Missing(*handshake.Capabilities); len(missing) > 0 {
+			p.logger.Warn("WASM plugin requests capabilities that were not approved",
+				zap.Strings("missing", missing))
+		}
+	}
+	p.httpClient = &http.Client{
+		Timeout: time.Duration(limits.TimeoutMs) * time.Millisecond,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if !p.capabilities.AllowsHost(req.URL.Hostname()) {
+				return fmt.Errorf("redirect to %s is not an approved network host", req.URL.Hostname())
+			}
+			return nil
+		},
+	}
+
+	p.logger.Info("Loaded WASM plugin",
+		zap.String("id", handshake.Info.ID),
+		zap.String("name", handshake.Info.Name),
+		zap.String("version", handshake.Info.Version),
+		zap.Int("memory_mb", limits.MemoryMB),
+		zap.Int("timeout_ms", limits.TimeoutMs),
+		zap.Strings("network_hosts", p.capabilities.NetworkHosts),
+		zap.Bool("browser_evaluate", p.capabilities.BrowserEvaluate),
+		zap.Bool("file_access", p.capabilities.FileAccess))
+	return p, nil
+}
+
+// Kind returns discovery or extraction
+func (p *WASMPlugin) Kind() string {
+	return p.handshake.Kind
+}
+
+// Info returns the plugin metadata from the handshake
+func (p *WASMPlugin) Info() plugins.PluginInfo {
+	return p.handshake.Info
+}
+
+// ConfigSchema returns the configuration schema from the handshake
+func (p *WASMPlugin) ConfigSchema() map[string]interface{} {
+	return p.handshake.ConfigSchema
+}
+
+// Capabilities returns the capabilities the plugin runs with
+func (p *WASMPlugin) Capabilities() models.PluginCapabilities {
+	return p.capabilities
+}
+
+// Limits returns the per-call limits the plugin runs with
+func (p *WASMPlugin) Limits() models.PluginLimits {
+	return p.limits
+}
+
+// Validate asks the plugin to validate a configuration
+func (p *WASMPlugin) Validate(config map[string]interface{}) error {
+	return p.call(context.Background(), plugins.MethodValidate, plugins.ValidateParams{Config: config}, nil, nil)
+}
+
+// Instance returns the plugin as a DiscoveryPlugin or ExtractionPlugin, according to its handshake
+func (p *WASMPlugin) Instance() interface{} {
+	if p.Kind() == plugins.PluginKindDiscovery {
+		return &wasmDiscoveryPlugin{p}
+	}
+	return &wasmExtractionPlugin{p}
+}
+
+// Close releases the runtime
+func (p *WASMPlugin) Close() error {
+	return p.runtime.Close(context.Background())
+}
+
+// wasmCall is the state of one call, reachable from host functions through the context
+type wasmCall struct {
+	browser        plugins.Browser
+	workflowStore  plugins.KVStore
+	executionStore plugins.KVStore
+	pending        []byte // Response of the last host_call, fetched by host_result
+}
+
+type wasmCallKey struct{}
+
+// call runs one request in a fresh instance of the module; state (which may be nil) holds what
+// the plugin's host requests can reach
+func (p *WASMPlugin) call(ctx context.Context, method string, params, result interface{}, state *wasmCall) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(p.limits.TimeoutMs)*time.Millisecond)
+	defer cancel()
+	if state == nil {
+		state = &wasmCall{}
+	}
+	ctx = context.WithValue(ctx, wasmCallKey{}, state)
+
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s params: %w", method, err)
+	}
+	request, err := json.Marshal(plugins.WASMRequest{Method: method, Params: rawParams})
+	if err != nil {
+		return err
+	}
+
+	output := &logWriter{logger: p.logger}
+	config := wazero.NewModuleConfig().
+		WithName("").
+		WithArgs(p.slug).
+		WithStartFunctions(plugins.WASMStartFunction).
+		WithStdout(output).
+		WithStderr(output).
+		WithSysWalltime().
+		WithSysNanotime().
+		WithRandSource(rand.Reader)
+	if p.capabilities.FileAccess {
+		if err := os.MkdirAll(p.dataDir, 0755); err != nil {
+			return fmt.Errorf("failed to create plugin data directory: %w", err)
+		}
+		config = config.WithFSConfig(wazero.NewFSConfig().WithDirMount(p.dataDir, plugins.WASMDataMountPoint))
+	}
+
+	mod, err := p.runtime.InstantiateModule(ctx, p.compiled, config)
+	if err != nil {
+		return p.callError(ctx, method, err)
+	}
+	defer mod.Close(context.Background())
+
+	results, err := mod.ExportedFunction(plugins.WASMExportMalloc).Call(ctx, uint64(len(request)))
+	if err != nil {
+		return p.callError(ctx, method, err)
+	}
+	if !mod.Memory().Write(uint32(results[0]), request) {
+		return fmt.Errorf("plugin %s returned an invalid buffer for %s", p.path, method)
+	}
+	results, err = mod.ExportedFunction(plugins.WASMExportCall).Call(ctx, results[0], uint64(len(request)))
+	if err != nil {
+		return p.callError(ctx, method, err)
+	}
+	data, ok := mod.Memory().Read(uint32(results[0]>>32), uint32(results[0]))
+	if !ok {
+		return fmt.Errorf("plugin %s returned an invalid response for %s", p.path, method)
+	}
+
+	var response plugins.WASMResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", method, err)
+	}
+	if response.Error != nil {
+		if response.Error.Code == plugins.RPCCodePluginError {
+			return errors.New(response.Error.Message)
+		}
+		return response.Error
+	}
+	if result == nil || len(response.Result) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(response.Result, result); err != nil {
+		return fmt.Errorf("failed to decode %s result: %w", method, err)
+	}
+	return nil
+}
+
+// callError describes a trap or a limit hit during a call
+func (p *WASMPlugin) callError(ctx context.Context, method string, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("plugin %s exceeded its %dms limit during %s", p.handshake.Info.Name, p.limits.TimeoutMs, method)
+	}
+	return fmt.Errorf("plugin %s failed during %s (memory limit %dMB): %w", p.handshake.Info.Name, method, p.limits.MemoryMB, err)
+}
+
+// instantiateHost provides WASI and the crawlify host module to the runtime
+func (p *WASMPlugin) instantiateHost(ctx context.Context) error {
+	if _, err := wasi_snapshot_preview1.Instantiate(ctx, p.runtime); err != nil {
+		return fmt.Errorf("failed to instantiate WASI: %w", err)
+	}
+	_, err := p.runtime.NewHostModuleBuilder(plugins.WASMHostModule).
+		NewFunctionBuilder().WithFunc(p.hostCall).Export(plugins.WASMHostCall).
+		NewFunctionBuilder().WithFunc(p.hostResult).Export(plugins.WASMHostResult).
+		NewFunctionBuilder().WithFunc(p.hostLog).Export(plugins.WASMHostLog).
+		Instantiate(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to instantiate host module: %w", err)
+	}
+	return nil
+}
+
+func (p *WASMPlugin) hostCall(ctx context.Context, mod api.Module, ptr, size uint32) uint32 {
+	state, _ := ctx.Value(wasmCallKey{}).(*wasmCall)
+	if state == nil {
+		return 0
+	}
+
+	response := plugins.WASMResponse{}
+	data, ok := mod.Memory().Read(ptr, size)
+	var request plugins.WASMRequest
+	if !ok {
+		response.Error = &plugins.RPCError{Code: plugins.RPCCodeInvalidParams, Message: "request is outside memory"}
+	} else if err := json.Unmarshal(data, &request); err != nil {
+		response.Error = &plugins.RPCError{Code: plugins.RPCCodeInvalidParams, Message: err.Error()}
+	} else if result, err := p.serveHost(ctx, state, request); err != nil {
+		var rpcErr *plugins.RPCError
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &plugins.RPCError{Code: plugins.RPCCodeInternal, Message: err.Error()}
+		}
+		response.Error = rpcErr
+	} else if response.Result, err = json.Marshal(result); err != nil {
+		response.Result = nil
+		response.Error = &plugins.RPCError{Code: plugins.RPCCodeInternal, Message: err.Error()}
+	}
+
+	state.pending, _ = json.Marshal(response)
+	return uint32(len(state.pending))
+}
+
+func (p *WASMPlugin) hostResult(ctx context.Context, mod api.Module, ptr uint32) {
+	if state, _ := ctx.Value(wasmCallKey{}).(*wasmCall); state != nil {
+		mod.Memory().Write(ptr, state.pending)
+		state.pending = nil
+	}
+}
+
+func (p *WASMPlugin) hostLog(ctx context.Context, mod api.Module, ptr, size uint32) {
+	if data, ok := mod.Memory().Read(ptr, size); ok {
+		p.logger.Info("plugin: " + string(data))
+	}
+}
+
+// serveHost runs a plugin's host request if its capabilities allow it
+func (p *WASMPlugin) serveHost(ctx context.Context, state *wasmCall, request plugins.WASMRequest) (interface{}, error) {
+	if request.Method == plugins.MethodHTTPFetch {
+		var params plugins.HTTPFetchParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return nil, &plugins.RPCError{Code: plugins.RPCCodeInvalidParams, Message: err.Error()}
+		}
+		return p.fetch(ctx, params)
+	}
+	switch request.Method {
+	case plugins.MethodKVGet, plugins.MethodKVSet, plugins.MethodKVDelete, plugins.MethodKVKeys:
+		var params plugins.KVParams
+		if err := json.Unmarshal(request.Params, &params); err != nil {
+			return nil, &plugins.RPCError{Code: plugins.RPCCodeInvalidParams, Message: err.Error()}
+		}
+		return plugins.ServeKV(ctx, state.workflowStore, state.executionStore, request.Method, params)
+	}
+
+	var params plugins.BrowserParams
+	if err := json.Unmarshal(request.Params, &params); err != nil {
+		return nil, &plugins.RPCError{Code: plugins.RPCCodeInvalidParams, Message: err.Error()}
+	}
+	switch request.Method {
+	case plugins.MethodBrowserEvaluate:
+		if !p.capabilities.BrowserEvaluate {
+			return nil, denied("browser_evaluate")
+		}
+	case plugins.MethodBrowserNavigate:
+		if !p.capabilities.AllowsHost(params.URL) && !sameHost(state.browser, params.URL) {
+			return nil, denied("network:" + params.URL)
+		}
+	}
+	return plugins.ServeBrowser(state.browser, request.Method, params)
+}
+
+// fetch performs http.fetch for an approved host
+func (p *WASMPlugin) fetch(ctx context.Context, params plugins.HTTPFetchParams) (*plugins.HTTPFetchResult, error) {
+	target, err := url.Parse(params.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") {
+		return nil, &plugins.RPCError{Code: plugins.RPCCodeInvalidParams, Message: fmt.Sprintf("invalid URL '%s'", params.URL)}
+	}
+	if !p.capabilities.AllowsHost(target.Hostname()) {
+		return nil, denied("network:" + target.Hostname())
+	}
+
+	method := params.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequestWithContext(ctx, method, params.URL, strings.NewReader(params.Body))
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range params.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, wasmMaxFetchBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > wasmMaxFetchBytes {
+		return nil, fmt.Errorf("response exceeds %d bytes", wasmMaxFetchBytes)
+	}
+	headers := make(map[string]string, len(resp.Header))
+	for key := range resp.Header {
+		headers[key] = resp.Header.Get(key)
+	}
+	return &plugins.HTTPFetchResult{Status: resp.StatusCode, Headers: headers, Body: string(body)}, nil
+}
+
+// sameHost reports whether rawURL is on the host of the page the plugin was given
+func sameHost(browser plugins.Browser, rawURL string) bool {
+	if browser == nil {
+		return false
+	}
+	current, err := browser.URL()
+	if err != nil {
+		return false
+	}
+	from, err1 := url.Parse(current)
+	to, err2 := url.Parse(rawURL)
+	return err1 == nil && err2 == nil && to.Host != "" && strings.EqualFold(from.Hostname(), to.Hostname())
+}
+
+func denied(capability string) error {
+	return &plugins.RPCError{Code: plugins.RPCCodeDenied, Message: "capability not approved: " + capability}
+}
+
+func (p *WASMPlugin) discover(ctx context.Context, input *plugins.DiscoveryInput) (*plugins.DiscoveryOutput, error) {
+	var result plugins.DiscoverResult
+	err := p.call(ctx, plugins.MethodDiscover, &plugins.CallParams{
+		URL:              input.URL,
+		URLItem:          input.URLItem,
+		ExecutionContext: input.ExecutionContext,
+		Config:           input.Config,
+		ExecutionID:      input.ExecutionID,
+		HTTP:             true, // Served by fetch, within the approved hosts
+		Stores:           input.WorkflowStore != nil,
+	}, &result, &wasmCall{
+		browser:        inputBrowser(input.Browser, input),
+		workflowStore:  input.WorkflowStore,
+		executionStore: input.ExecutionStore,
+	})
+	if err != nil {
+		return nil, err
+	}
+	mergeContext(input.ExecutionContext, result.ExecutionContext)
+	if result.Output == nil {
+		result.Output = &plugins.DiscoveryOutput{}
+	}
+	return result.Output, nil
+}
+
+func (p *WASMPlugin) extract(ctx context.Context, input *plugins.ExtractionInput) (*plugins.ExtractionOutput, error) {
+	var result plugins.ExtractResult
+	err := p.call(ctx, plugins.MethodExtract, &plugins.CallParams{
+		URL:              input.URL,
+		URLItem:          input.URLItem,
+		ExecutionContext: input.ExecutionContext,
+		Config:           input.Config,
+		ExecutionID:      input.ExecutionID,
+		HTTP:             true, // Served by fetch, within the approved hosts
+		Stores:           input.WorkflowStore != nil,
+	}, &result, &wasmCall{
+		browser:        inputBrowser(input.Browser, input),
+		workflowStore:  input.WorkflowStore,
+		executionStore: input.ExecutionStore,
+	})
+	if err != nil {
+		return nil, err
+	}
+	mergeContext(input.ExecutionContext, result.ExecutionContext)
+	if result.Output == nil {
+		result.Output = &plugins.ExtractionOutput{}
+	}
+	return result.Output, nil
+}
+
+// wasmDiscoveryPlugin exposes a discovery WASM plugin as a DiscoveryPlugin
+type wasmDiscoveryPlugin struct {
+	*WASMPlugin
+}
+
+func (p *wasmDiscoveryPlugin) Discover(ctx context.Context, input *plugins.DiscoveryInput) (*plugins.DiscoveryOutput, error) {
+	return p.discover(ctx, input)
+}
+
+// wasmExtractionPlugin exposes an extraction WASM plugin as an ExtractionPlugin
+type wasmExtractionPlugin struct {
+	*WASMPlugin
+}
+
+func (p *wasmExtractionPlugin) Extract(ctx context.Context, input *plugins.ExtractionInput) (*plugins.ExtractionOutput, error) {
+	return p.extract(ctx, input)
+}
@@ -0,0 +1,152 @@
+package queue
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/uzzalhcse/crawlify/pkg/models"
+)
+
+// defaultStripParams are the campaign parameters stripped unless keep_default_params is set
+// Click IDs and session parameters are site specific; workflows list them in strip_params.
+var defaultStripParams = []string{
+	"utm_*",
+}
+
+// Canonicalizer normalises URLs so that equivalent addresses hash to the same value
+type Canonicalizer struct {
+	config       models.DedupConfig
+	exactParams  map[string]bool
+	prefixParams []string
+}
+
+// NewCanonicalizer creates a canonicalizer from a workflow dedup config
+// Canonicalisation is opt-in: a nil config or an empty strategy compares raw URLs.
+func NewCanonicalizer(cfg *models.DedupConfig) *Canonicalizer {
+	c := &Canonicalizer{
+		exactParams: make(map[string]bool),
+	}
+	if cfg != nil {
+		c.config = *cfg
+	}
+	if c.config.Strategy == "" {
+		c.config.Strategy = models.DedupStrategyExact
+	}
+
+	params := c.config.StripParams
+	if !c.config.KeepDefaultParams {
+		params = append(append([]string{}, defaultStripParams...), params...)
+	}
+	for _, p := range params {
+		p = strings.ToLower(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if strings.HasSuffix(p, "*") {
+			c.prefixParams = append(c.prefixParams, strings.TrimSuffix(p, "*"))
+		} else {
+			c.exactParams[p] = true
+		}
+	}
+
+	return c
+}
+
+// Config returns the effective dedup configuration
+func (c *Canonicalizer) Config() models.DedupConfig {
+	return c.config
+}
+
+// Canonicalize returns the canonical form of a URL
+// Unparseable URLs are returned trimmed but otherwise unchanged
+func (c *Canonicalizer) Canonicalize(rawURL string) string {
+	rawURL = strings.TrimSpace(rawURL)
+	if c.config.Strategy == models.DedupStrategyExact {
+		return rawURL
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return rawURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+
+	// Drop default ports
+	if (u.Scheme == "http" && strings.HasSuffix(u.Host, ":80")) ||
+		(u.Scheme == "https" && strings.HasSuffix(u.Host, ":443")) {
+		u.Host = u.Host[:strings.LastIndex(u.Host, ":")]
+	}
+
+	if !c.config.KeepFragment {
+		u.Fragment = ""
+		u.RawFragment = ""
+	}
+
+	// Strip path-embedded session IDs such as /cart;jsessionid=ABC when jsessionid is stripped
+	if idx := strings.Index(strings.ToLower(u.Path), ";jsessionid="); idx >= 0 && c.shouldStrip("jsessionid") {
+		u.Path = u.Path[:idx]
+		u.RawPath = ""
+	}
+
+	if u.Path == "" {
+		u.Path = "/"
+	}
+	if c.config.StripTrailingSlash && len(u.Path) > 1 {
+		u.Path = strings.TrimRight(u.Path, "/")
+		u.RawPath = ""
+		if u.Path == "" {
+			u.Path = "/"
+		}
+	}
+
+	u.RawQuery = c.canonicalQuery(u.RawQuery)
+
+	return u.String()
+}
+
+// Hash returns the SHA-256 hash of the canonical URL
+func (c *Canonicalizer) Hash(rawURL string) string {
+	hash := sha256.Sum256([]byte(c.Canonicalize(rawURL)))
+	return fmt.Sprintf("%x", hash)
+}
+
+// canonicalQuery removes stripped params and sorts the remaining ones by key
+func (c *Canonicalizer) canonicalQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		// Keep malformed queries as-is rather than dropping data
+		return rawQuery
+	}
+
+	for key := range values {
+		if c.shouldStrip(key) {
+			values.Del(key)
+		}
+	}
+
+	// url.Values.Encode sorts by key and keeps each key's values in their original order,
+	// since sites often read repeated params (filters, paths) in order
+	return values.Encode()
+}
+
+// shouldStrip checks if a query parameter should be dropped
+func (c *Canonicalizer) shouldStrip(key string) bool {
+	key = strings.ToLower(key)
+	if c.exactParams[key] {
+		return true
+	}
+	for _, prefix := range c.prefixParams {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
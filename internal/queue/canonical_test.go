@@ -0,0 +1,27 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/uzzalhcse/crawlify/pkg/models"
+)
+
+func TestCanonicalizeQuery(t *testing.T) {
+	c := NewCanonicalizer(&models.DedupConfig{Strategy: models.DedupStrategyCanonical})
+
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"https://example.com/p?b=2&a=1", "https://example.com/p?a=1&b=2", true},
+		{"https://example.com/p?a=1&utm_source=x", "https://example.com/p?a=1", true},
+		{"https://example.com/p?tag=b&tag=a", "https://example.com/p?tag=a&tag=b", false},
+		{"https://example.com/p?tag=b&x=1&tag=a", "https://example.com/p?x=1&tag=b&tag=a", true},
+	}
+	for _, tt := range tests {
+		if same := c.Hash(tt.a) == c.Hash(tt.b); same != tt.same {
+			t.Errorf("Hash(%q) == Hash(%q) is %v, want %v (%s vs %s)",
+				tt.a, tt.b, same, tt.same, c.Canonicalize(tt.a), c.Canonicalize(tt.b))
+		}
+	}
+}
@@ -2,36 +2,80 @@ package queue
 
 import (
 	"context"
-	"crypto/sha256"
+	"errors"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/uzzalhcse/crawlify/internal/storage"
 	"github.com/uzzalhcse/crawlify/pkg/models"
 )
 
 type URLQueue struct {
-	db       *storage.PostgresDB
-	workerID string
+	db               *storage.PostgresDB
+	workerID         string
+	defaultCanonical *Canonicalizer
+	executions       sync.Map // executionID -> *executionDedup
+}
+
+// executionDedup holds the dedup settings of a running execution
+type executionDedup struct {
+	workflowID    string
+	canonicalizer *Canonicalizer
 }
 
 func NewURLQueue(db *storage.PostgresDB) *URLQueue {
 	return &URLQueue{
-		db:       db,
-		workerID: uuid.New().String(),
+		db:               db,
+		workerID:         uuid.New().String(),
+		defaultCanonical: NewCanonicalizer(nil),
 	}
 }
 
+// RegisterExecution applies a workflow's dedup configuration to an execution
+// Executions that are not registered use the default canonicalizer
+func (q *URLQueue) RegisterExecution(executionID, workflowID string, cfg *models.DedupConfig) {
+	q.executions.Store(executionID, &executionDedup{
+		workflowID:    workflowID,
+		canonicalizer: NewCanonicalizer(cfg),
+	})
+}
+
+// UnregisterExecution removes the dedup configuration of a finished execution
+func (q *URLQueue) UnregisterExecution(executionID string) {
+	q.executions.Delete(executionID)
+}
+
+// Canonicalizer returns the canonicalizer used for an execution
+func (q *URLQueue) Canonicalizer(executionID string) *Canonicalizer {
+	if state, ok := q.executions.Load(executionID); ok {
+		return state.(*executionDedup).canonicalizer
+	}
+	return q.defaultCanonical
+}
+
 // Enqueue adds a URL to the queue with deduplication
+// Items that are not inserted, because they were seen by a previous execution or are already
+// queued in this one, get status skipped; an already queued item gets the existing row's ID
 func (q *URLQueue) Enqueue(ctx context.Context, item *models.URLQueueItem) error {
 	if item.ID == "" {
 		item.ID = uuid.New().String()
 	}
 
 	// Calculate URL hash for deduplication
-	item.URLHash = q.hashURL(item.URL)
+	item.URLHash = q.hashURL(item.ExecutionID, item.URL)
+
+	seen, err := q.seenBefore(ctx, item)
+	if err != nil {
+		return err
+	}
+	if seen {
+		item.Status = models.QueueItemStatusSkipped
+		return nil
+	}
 
 	// Handle empty metadata - use NULL for JSONB column
 	var metadata interface{}
@@ -50,14 +94,15 @@ func (q *URLQueue) Enqueue(ctx context.Context, item *models.URLQueueItem) error
 				WHEN url_queue.status = 'failed' THEN 'pending'
 				ELSE url_queue.status
 			END
-		RETURNING id
+		RETURNING id, (xmax = 0) AS inserted
 	` // The executor will set it appropriately based on the node
 	// if item.URLType == "" {
 	// 	item.URLType = "page"
 	// }
 
 	var returnedID string
-	err := q.db.Pool.QueryRow(ctx, query,
+	var inserted bool
+	err = q.db.Pool.QueryRow(ctx, query,
 		item.ID,
 		item.ExecutionID,
 		item.URL,
@@ -72,7 +117,7 @@ func (q *URLQueue) Enqueue(ctx context.Context, item *models.URLQueueItem) error
 		item.Marker,
 		item.PhaseID,
 		metadata,
-	).Scan(&returnedID)
+	).Scan(&returnedID, &inserted)
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -82,25 +127,38 @@ func (q *URLQueue) Enqueue(ctx context.Context, item *models.URLQueueItem) error
 		return fmt.Errorf("failed to enqueue URL: %w", err)
 	}
 
-	// If an item was returned, it means it was inserted or updated.
-	// We don't need to update item.CreatedAt here as it's not returned.
-	// The ID is returned, but item.ID is already set.
+	// An update of an existing row is a duplicate, not a new URL
+	if !inserted {
+		item.ID = returnedID
+		item.Status = models.QueueItemStatusSkipped
+	}
 	return nil
 }
 
 // EnqueueBatch adds multiple URLs to the queue efficiently
+// Items that are not inserted get status skipped, as with Enqueue
 func (q *URLQueue) EnqueueBatch(ctx context.Context, items []*models.URLQueueItem) error {
 	if len(items) == 0 {
 		return nil
 	}
 
 	batch := &pgx.Batch{}
+	var queued []*models.URLQueueItem
 
 	for _, item := range items {
 		if item.ID == "" {
 			item.ID = uuid.New().String()
 		}
-		item.URLHash = q.hashURL(item.URL)
+		item.URLHash = q.hashURL(item.ExecutionID, item.URL)
+
+		seen, err := q.seenBefore(ctx, item)
+		if err != nil {
+			return err
+		}
+		if seen {
+			item.Status = models.QueueItemStatusSkipped
+			continue
+		}
 
 		// Handle empty metadata - use NULL for JSONB column
 		var metadata interface{}
@@ -125,7 +183,7 @@ func (q *URLQueue) EnqueueBatch(ctx context.Context, items []*models.URLQueueIte
 					WHEN url_queue.status = 'failed' THEN 'pending'
 					ELSE url_queue.status
 				END
-			RETURNING id
+			RETURNING id, (xmax = 0) AS inserted
 		`
 		batch.Queue(query,
 			item.ID,
@@ -143,16 +201,26 @@ func (q *URLQueue) EnqueueBatch(ctx context.Context, items []*models.URLQueueIte
 			item.PhaseID,
 			metadata,
 		)
+		queued = append(queued, item)
+	}
+
+	if len(queued) == 0 {
+		return nil
 	}
 
 	br := q.db.Pool.SendBatch(ctx, batch)
 	defer br.Close()
 
-	for i := 0; i < len(items); i++ {
-		_, err := br.Exec()
-		if err != nil {
+	for i, item := range queued {
+		var returnedID string
+		var inserted bool
+		if err := br.QueryRow().Scan(&returnedID, &inserted); err != nil {
 			return fmt.Errorf("failed to enqueue batch at index %d: %w", i, err)
 		}
+		if !inserted {
+			item.ID = returnedID
+			item.Status = models.QueueItemStatusSkipped
+		}
 	}
 
 	return nil
@@ -330,7 +398,7 @@ func (q *URLQueue) CleanupStaleItems(ctx context.Context, timeout time.Duration)
 
 // IsDuplicate checks if a URL already exists in the queue for this execution
 func (q *URLQueue) IsDuplicate(ctx context.Context, executionID, url string) (bool, error) {
-	urlHash := q.hashURL(url)
+	urlHash := q.hashURL(executionID, url)
 
 	query := `SELECT EXISTS(SELECT 1 FROM url_queue WHERE execution_id = $1 AND url_hash = $2)`
 
@@ -343,10 +411,131 @@ func (q *URLQueue) IsDuplicate(ctx context.Context, executionID, url string) (bo
 	return exists, nil
 }
 
-// hashURL generates a SHA-256 hash of the URL for deduplication
-func (q *URLQueue) hashURL(url string) string {
-	hash := sha256.Sum256([]byte(url))
-	return fmt.Sprintf("%x", hash)
+// hashURL generates a SHA-256 hash of the canonical URL for deduplication
+func (q *URLQueue) hashURL(executionID, url string) string {
+	return q.Canonicalizer(executionID).Hash(url)
+}
+
+// seenBefore checks if a URL was processed by a previous execution of the same workflow
+// Only applies when the execution enables seen_before; start URLs are never skipped
+func (q *URLQueue) seenBefore(ctx context.Context, item *models.URLQueueItem) (bool, error) {
+	state, ok := q.executions.Load(item.ExecutionID)
+	if !ok {
+		return false, nil
+	}
+	dedup := state.(*executionDedup)
+	if !dedup.appliesSeenBefore(item) {
+		return false, nil
+	}
+
+	query := `SELECT EXISTS(SELECT 1 FROM url_seen WHERE workflow_id = $1 AND url_hash = $2)`
+
+	var exists bool
+	if err := q.db.Pool.QueryRow(ctx, query, dedup.workflowID, item.URLHash).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check seen URL: %w", err)
+	}
+
+	return exists, nil
+}
+
+// appliesSeenBefore checks if the seen-before rule covers this item
+func (d *executionDedup) appliesSeenBefore(item *models.URLQueueItem) bool {
+	seenCfg := d.canonicalizer.Config().SeenBefore
+	if seenCfg == nil || !seenCfg.Enabled || d.workflowID == "" {
+		return false
+	}
+	if item.Depth == 0 || item.URLType == "start" {
+		return false
+	}
+	if len(seenCfg.Markers) == 0 {
+		return true
+	}
+	for _, marker := range seenCfg.Markers {
+		if item.Marker == marker {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordSeen remembers a processed URL for incremental crawls of the same workflow
+// It is a no-op unless the execution enables seen_before
+func (q *URLQueue) RecordSeen(ctx context.Context, item *models.URLQueueItem) error {
+	state, ok := q.executions.Load(item.ExecutionID)
+	if !ok {
+		return nil
+	}
+	dedup := state.(*executionDedup)
+	if !dedup.appliesSeenBefore(item) {
+		return nil
+	}
+
+	query := `
+		INSERT INTO url_seen (workflow_id, url_hash, url, last_execution_id, first_seen_at, last_seen_at)
+		VALUES ($1, $2, $3, $4, NOW(), NOW())
+		ON CONFLICT (workflow_id, url_hash) DO UPDATE SET
+			last_execution_id = EXCLUDED.last_execution_id,
+			last_seen_at = NOW()
+	`
+
+	if _, err := q.db.Pool.Exec(ctx, query, dedup.workflowID, item.URLHash, item.URL, item.ExecutionID); err != nil {
+		return fmt.Errorf("failed to record seen URL: %w", err)
+	}
+
+	return nil
+}
+
+// ResolveCanonical applies the page-declared canonical URL to a queue item
+// Returns true when another item of the execution already owns that canonical URL
+func (q *URLQueue) ResolveCanonical(ctx context.Context, item *models.URLQueueItem, canonicalURL string) (bool, error) {
+	canonicalHash := q.hashURL(item.ExecutionID, canonicalURL)
+	if canonicalHash == item.URLHash {
+		return false, nil
+	}
+
+	query := `SELECT EXISTS(SELECT 1 FROM url_queue WHERE execution_id = $1 AND url_hash = $2 AND id <> $3)`
+
+	var exists bool
+	if err := q.db.Pool.QueryRow(ctx, query, item.ExecutionID, canonicalHash, item.ID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check canonical duplicate: %w", err)
+	}
+	if exists {
+		return true, nil
+	}
+
+	// Claim the canonical hash so later links to the canonical URL dedupe against this item
+	_, err := q.db.Pool.Exec(ctx, `UPDATE url_queue SET url_hash = $1 WHERE id = $2`, canonicalHash, item.ID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			// Another worker claimed it first
+			return true, nil
+		}
+		return false, fmt.Errorf("failed to update canonical hash: %w", err)
+	}
+	item.URLHash = canonicalHash
+
+	return false, nil
+}
+
+// MarkSkipped marks a URL as skipped without processing it further
+func (q *URLQueue) MarkSkipped(ctx context.Context, id string, reason string) error {
+	query := `
+		UPDATE url_queue
+		SET status = $1, error = $2, processed_at = NOW()
+		WHERE id = $3 AND locked_by = $4
+	`
+
+	result, err := q.db.Pool.Exec(ctx, query, models.QueueItemStatusSkipped, reason, id, q.workerID)
+	if err != nil {
+		return fmt.Errorf("failed to mark URL as skipped: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("URL not found or not locked by this worker: %s", id)
+	}
+
+	return nil
 }
 
 // GetPendingCount returns the number of pending items for an execution
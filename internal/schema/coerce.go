@@ -0,0 +1,265 @@
+package schema
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// currencySymbols maps common currency symbols and prefixes to ISO codes
+// Longer symbols come first so "US$" wins over "$"
+var currencySymbols = []struct {
+	symbol string
+	code   string
+}{
+	{"US$", "USD"},
+	{"CA$", "CAD"},
+	{"C$", "CAD"},
+	{"A$", "AUD"},
+	{"AU$", "AUD"},
+	{"NZ$", "NZD"},
+	{"HK$", "HKD"},
+	{"S$", "SGD"},
+	{"R$", "BRL"},
+	{"CHF", "CHF"},
+	{"zł", "PLN"},
+	{"€", "EUR"},
+	{"£", "GBP"},
+	{"¥", "JPY"},
+	{"円", "JPY"},
+	{"₹", "INR"},
+	{"₩", "KRW"},
+	{"₽", "RUB"},
+	{"₺", "TRY"},
+	{"₫", "VND"},
+	{"฿", "THB"},
+	{"₱", "PHP"},
+	{"৳", "BDT"},
+	{"$", "USD"},
+}
+
+// isoCurrencyPattern matches candidate ISO 4217 codes; letterBounded drops those inside words
+var isoCurrencyPattern = regexp.MustCompile(`[A-Z]{3}`)
+
+// kronaCurrencies are written "kr"; which one is meant takes a hint
+var kronaCurrencies = map[string]bool{"SEK": true, "NOK": true, "DKK": true, "ISK": true}
+
+// isoCurrencyCodes are the ISO codes recognised in text (avoids matching words like "NEW")
+var isoCurrencyCodes = map[string]bool{
+	"USD": true, "EUR": true, "GBP": true, "JPY": true, "CNY": true, "RMB": true, "INR": true,
+	"CAD": true, "AUD": true, "NZD": true, "CHF": true, "SEK": true, "NOK": true, "DKK": true,
+	"ISK": true, "PLN": true, "CZK": true, "HUF": true, "RUB": true, "TRY": true, "BRL": true, "MXN": true,
+	"HKD": true, "SGD": true, "KRW": true, "TWD": true, "THB": true, "VND": true, "PHP": true,
+	"IDR": true, "MYR": true, "BDT": true, "PKR": true, "AED": true, "SAR": true, "ZAR": true,
+}
+
+// numberPattern finds the first number: digit groups joined by separators,
+// where space-like grouping only applies to 3-digit groups ("1 234,56")
+var numberPattern = regexp.MustCompile(`-?\d+(?:[ \x{00A0}\x{202F}]\d{3}|[.,']\d+)*`)
+
+// toNumber converts strings like "1,234.56", "1.234,56" or "4.5 out of 5" to a float
+// decimal is the declared decimal separator, "" to infer it
+func toNumber(value interface{}, decimal string) (float64, error) {
+	switch v := value.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case bool:
+		return 0, fmt.Errorf("boolean is not a number")
+	}
+	return ParseLocaleNumber(stringify(value), decimal)
+}
+
+// ParseNumber extracts a number from free text, inferring the decimal separator
+// The last of "." or "," is the decimal separator when both appear; a single separator
+// followed by exactly three digits is treated as a thousands separator ("$1,299", "€1.234").
+// Declare the separator where a value like "3.141" is a decimal.
+func ParseNumber(s string) (float64, error) {
+	return ParseLocaleNumber(s, "")
+}
+
+// ParseLocaleNumber extracts a number using a known decimal separator ("." or ","),
+// falling back to ParseNumber's inference when decimal is empty
+// CJK magnitude suffixes (千, 万, 億) directly after the number are applied
+func ParseLocaleNumber(s, decimal string) (float64, error) {
+	loc := numberPattern.FindStringIndex(s)
+	if loc == nil {
+		return 0, fmt.Errorf("no number in '%s'", s)
+	}
+	match := s[loc[0]:loc[1]]
+
+	// Drop grouping spaces and apostrophes (1 234,56 / 1'234.56)
+	cleaned := strings.NewReplacer(" ", "", "\u00a0", "", "\u202f", "", "'", "").Replace(strings.TrimSpace(match))
+
+	switch decimal {
+	case ".":
+		cleaned = strings.ReplaceAll(cleaned, ",", "")
+	case ",":
+		cleaned = strings.ReplaceAll(cleaned, ".", "")
+		cleaned = strings.Replace(cleaned, ",", ".", 1)
+	case "":
+		cleaned = inferDecimalSeparator(cleaned)
+	default:
+		return 0, fmt.Errorf("unsupported decimal separator '%s'", decimal)
+	}
+
+	f, err := strconv.ParseFloat(cleaned, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number '%s'", s)
+	}
+
+	rest := strings.TrimLeft(s[loc[1]:], " ")
+	for _, m := range cjkMagnitudes {
+		if strings.HasPrefix(rest, m.suffix) {
+			f *= m.factor
+			break
+		}
+	}
+	return f, nil
+}
+
+// cjkMagnitudes are magnitude suffixes used in Chinese and Japanese amounts ("1.5万円")
+var cjkMagnitudes = []struct {
+	suffix string
+	factor float64
+}{
+	{"千", 1e3},
+	{"万", 1e4},
+	{"萬", 1e4},
+	{"億", 1e8},
+	{"亿", 1e8},
+}
+
+// inferDecimalSeparator normalises a number whose decimal separator is unknown
+func inferDecimalSeparator(cleaned string) string {
+	lastDot := strings.LastIndex(cleaned, ".")
+	lastComma := strings.LastIndex(cleaned, ",")
+
+	switch {
+	case lastDot >= 0 && lastComma >= 0:
+		if lastComma > lastDot {
+			cleaned = strings.ReplaceAll(cleaned, ".", "")
+			return strings.Replace(cleaned, ",", ".", 1)
+		}
+		return strings.ReplaceAll(cleaned, ",", "")
+	case lastComma >= 0:
+		return normaliseSingleSeparator(cleaned, ",")
+	case lastDot >= 0:
+		return normaliseSingleSeparator(cleaned, ".")
+	}
+	return cleaned
+}
+
+// normaliseSingleSeparator decides whether a lone separator is a decimal or grouping mark
+func normaliseSingleSeparator(s, sep string) string {
+	parts := strings.Split(s, sep)
+	if len(parts) > 2 || len(parts[len(parts)-1]) == 3 {
+		return strings.ReplaceAll(s, sep, "")
+	}
+	return strings.Replace(s, sep, ".", 1)
+}
+
+// toCurrency parses an amount and detects its currency from symbols or ISO codes
+// defaultCurrency is used when none is detected, and tells which krona "kr" is
+func toCurrency(value interface{}, defaultCurrency, decimal string) (float64, string, error) {
+	if obj, ok := value.(map[string]interface{}); ok {
+		amount, err := toNumber(obj["amount"], decimal)
+		if err != nil {
+			return 0, "", err
+		}
+		currency, _ := obj["currency"].(string)
+		if currency == "" {
+			currency = defaultCurrency
+		}
+		return amount, strings.ToUpper(currency), nil
+	}
+
+	s := stringify(value)
+	amount, err := toNumber(value, decimal)
+	if err != nil {
+		return 0, "", err
+	}
+
+	currency := DetectCurrencyWithHint(s, defaultCurrency)
+	if currency == "" {
+		currency = strings.ToUpper(defaultCurrency)
+	}
+	return amount, currency, nil
+}
+
+// DetectCurrency returns the ISO code for the first currency symbol or code in s
+// Letter symbols and codes only count as whole tokens. "kr" is shared by the Swedish,
+// Norwegian, Danish and Icelandic krona, so it detects nothing; see DetectCurrencyWithHint.
+func DetectCurrency(s string) string {
+	return DetectCurrencyWithHint(s, "")
+}
+
+// DetectCurrencyWithHint is DetectCurrency, reading "kr" as hint when hint is a krona code
+func DetectCurrencyWithHint(s, hint string) string {
+	for _, loc := range isoCurrencyPattern.FindAllStringIndex(s, -1) {
+		if code := s[loc[0]:loc[1]]; isoCurrencyCodes[code] && letterBounded(s, loc[0], loc[1]) {
+			return code
+		}
+	}
+	for _, cs := range currencySymbols {
+		if containsToken(s, cs.symbol) {
+			return cs.code
+		}
+	}
+	if hint = strings.ToUpper(hint); kronaCurrencies[hint] && containsToken(strings.ToLower(s), "kr") {
+		return hint
+	}
+	return ""
+}
+
+// containsToken reports whether token occurs in s without letters directly around it
+// Only tokens made of letters need the check; "$" may touch anything.
+func containsToken(s, token string) bool {
+	for offset := 0; ; {
+		i := strings.Index(s[offset:], token)
+		if i < 0 {
+			return false
+		}
+		start := offset + i
+		end := start + len(token)
+		if !startsWithLetter(token) || letterBounded(s, start, end) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+// letterBounded reports whether s[start:end] has no letter directly before or after it
+func letterBounded(s string, start, end int) bool {
+	before, _ := utf8.DecodeLastRuneInString(s[:start])
+	after, _ := utf8.DecodeRuneInString(s[end:])
+	return !unicode.IsLetter(before) && !unicode.IsLetter(after)
+}
+
+func startsWithLetter(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return unicode.IsLetter(r)
+}
+
+// toBool converts common truthy/falsy strings to a bool
+func toBool(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case float64:
+		return v != 0, nil
+	}
+	switch strings.ToLower(strings.TrimSpace(stringify(value))) {
+	case "true", "yes", "y", "1", "on":
+		return true, nil
+	case "false", "no", "n", "0", "off":
+		return false, nil
+	}
+	return nil, fmt.Errorf("'%v' is not a boolean", value)
+}
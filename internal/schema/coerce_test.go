@@ -0,0 +1,51 @@
+package schema
+
+import "testing"
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		input string
+		want  float64
+	}{
+		{"$1,299", 1299},
+		{"¥12,800", 12800},
+		{"€1.234", 1234},
+		{"1.234,56 €", 1234.56},
+		{"1,234.56", 1234.56},
+		{"12,5", 12.5},
+		{"19.99", 19.99},
+		{"1,234,567", 1234567},
+	}
+	for _, tt := range tests {
+		got, err := ParseNumber(tt.input)
+		if err != nil {
+			t.Errorf("ParseNumber(%q) failed: %v", tt.input, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseNumber(%q) = %v, want %v", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseLocaleNumber(t *testing.T) {
+	tests := []struct {
+		input   string
+		decimal string
+		want    float64
+	}{
+		{"3.141", ".", 3.141},
+		{"1,234", ",", 1.234},
+		{"1.234,56 €", ",", 1234.56},
+	}
+	for _, tt := range tests {
+		got, err := ParseLocaleNumber(tt.input, tt.decimal)
+		if err != nil {
+			t.Errorf("ParseLocaleNumber(%q, %q) failed: %v", tt.input, tt.decimal, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseLocaleNumber(%q, %q) = %v, want %v", tt.input, tt.decimal, got, tt.want)
+		}
+	}
+}
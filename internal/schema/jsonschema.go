@@ -0,0 +1,168 @@
+package schema
+
+import (
+	"github.com/uzzalhcse/crawlify/pkg/models"
+)
+
+// jsonSchemaDialect is the JSON Schema draft emitted by ToJSONSchema
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// ToJSONSchema exports an item schema as a JSON Schema document describing the coerced output
+func ToJSONSchema(s *models.ItemSchema) map[string]interface{} {
+	doc := objectSchema(s.Fields, !s.DropUnknown)
+	doc["$schema"] = jsonSchemaDialect
+
+	// Downloaded files are attached next to their URL field
+	properties := doc["properties"].(map[string]interface{})
+	for _, name := range sortedFieldNames(s.Fields) {
+		if field := s.Fields[name]; field.Download {
+			asset := assetSchema()
+			if field.Type == models.FieldTypeArray {
+				asset = map[string]interface{}{"type": "array", "items": asset}
+			}
+			properties[name+"_asset"] = asset
+		}
+	}
+
+	if s.Name != "" {
+		doc["title"] = s.Name
+	}
+	if s.Description != "" {
+		doc["description"] = s.Description
+	}
+	return doc
+}
+
+// assetSchema describes the metadata of a downloaded file
+func assetSchema() map[string]interface{} {
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"url":          map[string]interface{}{"type": "string", "format": "uri"},
+			"path":         map[string]interface{}{"type": "string"},
+			"location":     map[string]interface{}{"type": "string"},
+			"hash":         map[string]interface{}{"type": "string"},
+			"size":         map[string]interface{}{"type": "integer"},
+			"content_type": map[string]interface{}{"type": "string"},
+			"width":        map[string]interface{}{"type": "integer"},
+			"height":       map[string]interface{}{"type": "integer"},
+			"duplicate":    map[string]interface{}{"type": "boolean"},
+		},
+		"required": []string{"url", "path", "hash", "size", "content_type"},
+	}
+}
+
+// objectSchema builds an object schema from field definitions
+func objectSchema(fields map[string]*models.SchemaField, additional bool) map[string]interface{} {
+	properties := make(map[string]interface{}, len(fields))
+	required := []string{}
+	for _, name := range sortedFieldNames(fields) {
+		field := fields[name]
+		properties[name] = fieldSchema(field)
+		if field.Required && field.Default == nil {
+			required = append(required, name)
+		}
+	}
+
+	doc := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": additional,
+	}
+	if len(required) > 0 {
+		doc["required"] = required
+	}
+	return doc
+}
+
+// fieldSchema converts a single field definition
+func fieldSchema(field *models.SchemaField) map[string]interface{} {
+	doc := map[string]interface{}{}
+
+	switch field.Type {
+	case models.FieldTypeString:
+		doc["type"] = "string"
+		if field.Pattern != "" {
+			doc["pattern"] = field.Pattern
+		}
+		switch field.Format {
+		case "email":
+			doc["format"] = "email"
+		case "uri", "url":
+			doc["format"] = "uri"
+		}
+		setLength(doc, field, "minLength", "maxLength")
+	case models.FieldTypeInt:
+		doc["type"] = "integer"
+		setRange(doc, field)
+	case models.FieldTypeDecimal:
+		doc["type"] = "number"
+		setRange(doc, field)
+	case models.FieldTypeCurrency:
+		amount := map[string]interface{}{"type": "number"}
+		setRange(amount, field)
+		currency := map[string]interface{}{"type": "string", "pattern": "^[A-Z]{3}$"}
+		if field.Currency != "" {
+			currency["default"] = field.Currency
+		}
+		doc["type"] = "object"
+		doc["properties"] = map[string]interface{}{
+			"amount":   amount,
+			"currency": currency,
+		}
+		doc["required"] = []string{"amount"}
+	case models.FieldTypeBool:
+		doc["type"] = "boolean"
+	case models.FieldTypeURL:
+		doc["type"] = "string"
+		doc["format"] = "uri"
+	case models.FieldTypeDate:
+		doc["type"] = "string"
+		if field.Format == "date" {
+			doc["format"] = "date"
+		} else {
+			doc["format"] = "date-time"
+		}
+	case models.FieldTypeEnum:
+		doc["type"] = "string"
+		doc["enum"] = field.Enum
+	case models.FieldTypeArray:
+		doc["type"] = "array"
+		if field.Items != nil {
+			doc["items"] = fieldSchema(field.Items)
+		}
+		setLength(doc, field, "minItems", "maxItems")
+	case models.FieldTypeObject:
+		for k, v := range objectSchema(field.Properties, true) {
+			doc[k] = v
+		}
+	}
+
+	if field.Description != "" {
+		doc["description"] = field.Description
+	}
+	if field.Default != nil {
+		doc["default"] = field.Default
+	}
+	return doc
+}
+
+// setRange copies numeric bounds
+func setRange(doc map[string]interface{}, field *models.SchemaField) {
+	if field.Min != nil {
+		doc["minimum"] = *field.Min
+	}
+	if field.Max != nil {
+		doc["maximum"] = *field.Max
+	}
+}
+
+// setLength copies length bounds under the given keywords
+func setLength(doc map[string]interface{}, field *models.SchemaField, minKey, maxKey string) {
+	if field.MinLength != nil {
+		doc[minKey] = *field.MinLength
+	}
+	if field.MaxLength != nil {
+		doc[maxKey] = *field.MaxLength
+	}
+}
@@ -0,0 +1,448 @@
+package schema
+
+import (
+	"fmt"
+	"math"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uzzalhcse/crawlify/pkg/models"
+)
+
+// Validation error codes stored on extracted items
+const (
+	CodeRequired = "required"
+	CodeType     = "type"
+	CodeEnum     = "enum"
+	CodePattern  = "pattern"
+	CodeFormat   = "format"
+	CodeRange    = "range"
+	CodeLength   = "length"
+	CodeDownload = "download"
+)
+
+// patternCache caches compiled field patterns across items
+var patternCache sync.Map // map[string]*regexp.Regexp
+
+// Validate checks that a schema definition is well-formed
+func Validate(s *models.ItemSchema) error {
+	if s == nil {
+		return nil
+	}
+	if len(s.Fields) == 0 {
+		return fmt.Errorf("schema must declare at least one field")
+	}
+	for name, field := range s.Fields {
+		if err := validateField(name, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validateField checks a single field definition recursively
+func validateField(path string, field *models.SchemaField) error {
+	if field == nil {
+		return fmt.Errorf("field '%s' has no definition", path)
+	}
+
+	switch field.Type {
+	case models.FieldTypeString, models.FieldTypeInt, models.FieldTypeDecimal, models.FieldTypeCurrency,
+		models.FieldTypeBool, models.FieldTypeURL, models.FieldTypeDate:
+	case models.FieldTypeEnum:
+		if len(field.Enum) == 0 {
+			return fmt.Errorf("enum field '%s' must list its allowed values", path)
+		}
+	case models.FieldTypeArray:
+		if field.Items != nil {
+			if err := validateField(path+"[]", field.Items); err != nil {
+				return err
+			}
+		}
+	case models.FieldTypeObject:
+		for name, prop := range field.Properties {
+			if err := validateField(path+"."+name, prop); err != nil {
+				return err
+			}
+		}
+	case "":
+		return fmt.Errorf("field '%s' must have a type", path)
+	default:
+		return fmt.Errorf("field '%s' has unsupported type '%s'", path, field.Type)
+	}
+
+	if field.Pattern != "" {
+		if _, err := compilePattern(field.Pattern); err != nil {
+			return fmt.Errorf("field '%s' has invalid pattern: %w", path, err)
+		}
+	}
+	if field.DecimalSeparator != "" && field.DecimalSeparator != "." && field.DecimalSeparator != "," {
+		return fmt.Errorf("field '%s' decimal_separator must be \".\" or \",\"", path)
+	}
+	if field.Currency != "" && len(field.Currency) != 3 {
+		return fmt.Errorf("field '%s' currency must be a 3-letter ISO code", path)
+	}
+	if field.Min != nil && field.Max != nil && *field.Min > *field.Max {
+		return fmt.Errorf("field '%s' has min greater than max", path)
+	}
+	if field.Download && strings.ContainsAny(path, ".[") {
+		return fmt.Errorf("field '%s': download is only supported on top-level fields", path)
+	}
+	if field.Download && field.Type != models.FieldTypeURL &&
+		!(field.Type == models.FieldTypeArray && field.Items != nil && field.Items.Type == models.FieldTypeURL) {
+		return fmt.Errorf("field '%s' can only download url fields or arrays of url", path)
+	}
+	return nil
+}
+
+// Apply coerces extracted data to the schema and collects validation errors
+// Invalid values are kept as extracted so nothing is lost; baseURL resolves relative URLs
+func Apply(s *models.ItemSchema, data map[string]interface{}, baseURL string) (map[string]interface{}, []models.FieldValidationError) {
+	if s == nil {
+		return data, nil
+	}
+
+	c := &coercer{baseURL: baseURL}
+	result := make(map[string]interface{}, len(data))
+
+	if !s.DropUnknown {
+		for key, value := range data {
+			if _, declared := s.Fields[key]; !declared {
+				result[key] = value
+			}
+		}
+	}
+
+	for _, name := range sortedFieldNames(s.Fields) {
+		value, present := data[name]
+		if coerced, ok := c.field(name, s.Fields[name], value, present); ok {
+			result[name] = coerced
+		}
+	}
+
+	return result, c.errors
+}
+
+// coercer accumulates validation errors while coercing an item
+type coercer struct {
+	baseURL string
+	errors  []models.FieldValidationError
+}
+
+// fail records a validation error
+func (c *coercer) fail(path, code string, value interface{}, format string, args ...interface{}) {
+	c.errors = append(c.errors, models.FieldValidationError{
+		Field:   path,
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+		Value:   value,
+	})
+}
+
+// field coerces a single value; returns false if the field should be omitted
+func (c *coercer) field(path string, field *models.SchemaField, value interface{}, present bool) (interface{}, bool) {
+	if !present || isEmpty(value) {
+		if field.Default != nil {
+			return field.Default, true
+		}
+		if field.Required {
+			c.fail(path, CodeRequired, nil, "field is required")
+		}
+		if present {
+			return nil, true
+		}
+		return nil, false
+	}
+
+	coerced, err := c.coerce(path, field, value)
+	if err != nil {
+		c.fail(path, CodeType, value, "cannot convert to %s: %v", field.Type, err)
+		return value, true
+	}
+	return coerced, true
+}
+
+// coerce converts a value to the field type and checks its constraints
+func (c *coercer) coerce(path string, field *models.SchemaField, value interface{}) (interface{}, error) {
+	switch field.Type {
+	case models.FieldTypeString:
+		s := strings.TrimSpace(stringify(value))
+		c.checkString(path, field, s)
+		return s, nil
+
+	case models.FieldTypeInt:
+		f, err := toNumber(value, field.DecimalSeparator)
+		if err != nil {
+			return nil, err
+		}
+		if f != math.Trunc(f) {
+			return nil, fmt.Errorf("%v is not a whole number", f)
+		}
+		c.checkRange(path, field, f)
+		return int64(f), nil
+
+	case models.FieldTypeDecimal:
+		f, err := toNumber(value, field.DecimalSeparator)
+		if err != nil {
+			return nil, err
+		}
+		c.checkRange(path, field, f)
+		return f, nil
+
+	case models.FieldTypeCurrency:
+		amount, currency, err := toCurrency(value, field.Currency, field.DecimalSeparator)
+		if err != nil {
+			return nil, err
+		}
+		c.checkRange(path, field, amount)
+		result := map[string]interface{}{"amount": amount}
+		if currency != "" {
+			result["currency"] = currency
+		}
+		return result, nil
+
+	case models.FieldTypeBool:
+		return toBool(value)
+
+	case models.FieldTypeURL:
+		return c.toURL(stringify(value))
+
+	case models.FieldTypeDate:
+		return toDate(stringify(value), field.Format)
+
+	case models.FieldTypeEnum:
+		s := strings.TrimSpace(stringify(value))
+		for _, allowed := range field.Enum {
+			if strings.EqualFold(s, allowed) {
+				return allowed, nil
+			}
+		}
+		c.fail(path, CodeEnum, value, "value must be one of: %s", strings.Join(field.Enum, ", "))
+		return s, nil
+
+	case models.FieldTypeArray:
+		list, ok := value.([]interface{})
+		if !ok {
+			list = []interface{}{value}
+		}
+		c.checkLength(path, field, len(list))
+		if field.Items == nil {
+			return list, nil
+		}
+		result := make([]interface{}, 0, len(list))
+		for i, item := range list {
+			if coerced, ok := c.field(fmt.Sprintf("%s[%d]", path, i), field.Items, item, true); ok {
+				result = append(result, coerced)
+			}
+		}
+		return result, nil
+
+	case models.FieldTypeObject:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("expected an object, got %T", value)
+		}
+		if len(field.Properties) == 0 {
+			return obj, nil
+		}
+		result := make(map[string]interface{}, len(obj))
+		for key, v := range obj {
+			if _, declared := field.Properties[key]; !declared {
+				result[key] = v
+			}
+		}
+		for _, name := range sortedFieldNames(field.Properties) {
+			v, present := obj[name]
+			if coerced, ok := c.field(path+"."+name, field.Properties[name], v, present); ok {
+				result[name] = coerced
+			}
+		}
+		return result, nil
+	}
+
+	return value, nil
+}
+
+// checkString validates string constraints
+func (c *coercer) checkString(path string, field *models.SchemaField, s string) {
+	c.checkLength(path, field, len([]rune(s)))
+
+	if field.Pattern != "" {
+		if re, err := compilePattern(field.Pattern); err == nil && !re.MatchString(s) {
+			c.fail(path, CodePattern, s, "value does not match pattern %s", field.Pattern)
+		}
+	}
+
+	switch strings.ToLower(field.Format) {
+	case "email":
+		if _, err := mail.ParseAddress(s); err != nil {
+			c.fail(path, CodeFormat, s, "value is not a valid email address")
+		}
+	case "uri", "url":
+		if u, err := url.Parse(s); err != nil || u.Scheme == "" || u.Host == "" {
+			c.fail(path, CodeFormat, s, "value is not an absolute URL")
+		}
+	}
+}
+
+// checkRange validates numeric bounds
+func (c *coercer) checkRange(path string, field *models.SchemaField, f float64) {
+	if field.Min != nil && f < *field.Min {
+		c.fail(path, CodeRange, f, "value %v is below minimum %v", f, *field.Min)
+	}
+	if field.Max != nil && f > *field.Max {
+		c.fail(path, CodeRange, f, "value %v is above maximum %v", f, *field.Max)
+	}
+}
+
+// checkLength validates string/array length bounds
+func (c *coercer) checkLength(path string, field *models.SchemaField, length int) {
+	if field.MinLength != nil && length < *field.MinLength {
+		c.fail(path, CodeLength, length, "length %d is below minimum %d", length, *field.MinLength)
+	}
+	if field.MaxLength != nil && length > *field.MaxLength {
+		c.fail(path, CodeLength, length, "length %d is above maximum %d", length, *field.MaxLength)
+	}
+}
+
+// toURL resolves a URL against the page URL and requires an http(s) result
+func (c *coercer) toURL(raw string) (interface{}, error) {
+	raw = strings.TrimSpace(raw)
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	if !u.IsAbs() && c.baseURL != "" {
+		if base, err := url.Parse(c.baseURL); err == nil {
+			u = base.ResolveReference(u)
+		}
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("'%s' is not an http(s) URL", raw)
+	}
+	return u.String(), nil
+}
+
+// compilePattern compiles a pattern once and caches it
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if cached, ok := patternCache.Load(pattern); ok {
+		return cached.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	patternCache.Store(pattern, re)
+	return re, nil
+}
+
+// isEmpty reports missing values: nil, blank strings and empty slices
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+// stringify converts scalar values to strings
+func stringify(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+			return fmt.Sprintf("%d", int64(v))
+		}
+		return fmt.Sprintf("%v", v)
+	case []interface{}:
+		if len(v) > 0 {
+			return stringify(v[0])
+		}
+		return ""
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// sortedFieldNames returns field names in a stable order so errors are deterministic
+func sortedFieldNames(fields map[string]*models.SchemaField) []string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// dateLayouts are tried in order when a date field has no explicit format
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"2006/01/02",
+	"01/02/2006",
+	"02.01.2006",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	"2006年1月2日",
+	time.RFC1123,
+	time.RFC1123Z,
+}
+
+// toDate parses a date and returns it as RFC 3339 (or YYYY-MM-DD for date-only formats)
+func toDate(raw, format string) (interface{}, error) {
+	var layouts []string
+	dateOnly := false
+	switch strings.ToLower(format) {
+	case "":
+	case "date":
+		dateOnly = true
+	case "date-time", "datetime":
+	default:
+		layouts = []string{format}
+	}
+
+	t, err := ParseDate(raw, layouts...)
+	if err != nil {
+		return nil, err
+	}
+	if dateOnly {
+		return t.Format("2006-01-02"), nil
+	}
+	return t.Format(time.RFC3339), nil
+}
+
+// ParseDate parses a date with the given Go layouts, or the default layouts when none are given
+func ParseDate(raw string, layouts ...string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if len(layouts) == 0 {
+		layouts = dateLayouts
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised date '%s'", raw)
+}
+
+// ForPhase returns the output schema for a phase, falling back to the workflow default
+func ForPhase(config *models.WorkflowConfig, phase *models.WorkflowPhase) *models.ItemSchema {
+	if phase != nil && phase.Schema != nil {
+		return phase.Schema
+	}
+	return config.Schema
+}
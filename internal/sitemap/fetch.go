@@ -0,0 +1,51 @@
+package sitemap
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// userAgent identifies sitemap requests made outside the browser
+const userAgent = "Mozilla/5.0 (compatible; Crawlify/1.0; +sitemap)"
+
+// HTTPFetcher returns a Fetcher using a plain HTTP client, for sources read before any browser page is open
+// headers (e.g. the workflow's headers) are sent with every request
+func HTTPFetcher(headers map[string]string, timeout time.Duration) Fetcher {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	client := &http.Client{Timeout: timeout}
+
+	return func(ctx context.Context, rawURL string) ([]byte, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+		}
+		req.Header.Set("User-Agent", userAgent)
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return nil, fmt.Errorf("failed to fetch %s: HTTP %d", rawURL, resp.StatusCode)
+		}
+
+		body, err := io.ReadAll(io.LimitReader(resp.Body, DefaultMaxBytes+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", rawURL, err)
+		}
+		if len(body) > DefaultMaxBytes {
+			return nil, fmt.Errorf("%s exceeds %d bytes", rawURL, DefaultMaxBytes)
+		}
+		return body, nil
+	}
+}
@@ -0,0 +1,387 @@
+package sitemap
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+// DefaultMaxBytes caps a single sitemap or feed, after decompression (the sitemap protocol allows 50MB)
+const DefaultMaxBytes = 50 * 1024 * 1024
+
+// DefaultMaxSitemaps caps how many sitemap files one discovery reads, including nested indexes
+const DefaultMaxSitemaps = 1000
+
+// Fetcher fetches a sitemap, feed or robots.txt
+type Fetcher func(ctx context.Context, rawURL string) ([]byte, error)
+
+// Entry is a page URL listed by a sitemap or feed
+type Entry struct {
+	URL     string
+	LastMod time.Time // Zero when the source gives no date
+	Title   string    // Feed item title
+	Source  string    // Sitemap or feed that listed the URL
+}
+
+// Metadata returns the entry's queue item metadata
+func (e Entry) Metadata() map[string]interface{} {
+	metadata := map[string]interface{}{"source": e.Source}
+	if !e.LastMod.IsZero() {
+		metadata["lastmod"] = e.LastMod.UTC().Format(time.RFC3339)
+	}
+	if e.Title != "" {
+		metadata["title"] = e.Title
+	}
+	return metadata
+}
+
+// Options filter the discovered URLs
+type Options struct {
+	Include     []*regexp.Regexp // URL must match one of these, when set
+	Exclude     []*regexp.Regexp // URL must match none of these
+	Since       time.Time        // Drop URLs whose lastmod is before Since; URLs without lastmod are kept
+	MaxURLs     int              // 0 = unlimited
+	MaxSitemaps int              // Default DefaultMaxSitemaps
+}
+
+// CompilePatterns compiles include/exclude patterns
+func CompilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// Result is the outcome of a discovery
+type Result struct {
+	Entries  []Entry
+	Sitemaps int      // Sitemap, feed and robots.txt files read
+	Filtered int      // URLs dropped by lastmod or pattern
+	Errors   []string // Sources that could not be read; discovery continues without them
+}
+
+// Discover reads the sources and returns the page URLs they list
+// A source is a sitemap, sitemap index, RSS or Atom feed (optionally gzipped), a robots.txt whose
+// Sitemap: entries are followed, or a site root, which is looked up through its robots.txt and
+// falls back to /sitemap.xml. It fails only when no source could be read
+func Discover(ctx context.Context, fetch Fetcher, sources []string, opts Options) (*Result, error) {
+	maxSitemaps := opts.MaxSitemaps
+	if maxSitemaps <= 0 {
+		maxSitemaps = DefaultMaxSitemaps
+	}
+
+	result := &Result{}
+	pending := append([]string(nil), sources...)
+	visited := make(map[string]bool)
+	listed := make(map[string]bool)
+
+	for len(pending) > 0 && result.Sitemaps < maxSitemaps {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		if opts.MaxURLs > 0 && len(result.Entries) >= opts.MaxURLs {
+			break
+		}
+
+		source := pending[0]
+		pending = pending[1:]
+		if visited[source] {
+			continue
+		}
+		visited[source] = true
+
+		if isSiteRoot(source) {
+			root, _ := url.Parse(source)
+			robotsURL := root.ResolveReference(&url.URL{Path: "/robots.txt"}).String()
+			if !visited[robotsURL] {
+				sitemaps, err := readRobots(ctx, fetch, robotsURL)
+				visited[robotsURL] = true
+				result.Sitemaps++
+				if err != nil || len(sitemaps) == 0 {
+					sitemaps = []string{root.ResolveReference(&url.URL{Path: "/sitemap.xml"}).String()}
+				}
+				pending = append(pending, sitemaps...)
+			}
+			continue
+		}
+
+		result.Sitemaps++
+		if isRobots(source) {
+			sitemaps, err := readRobots(ctx, fetch, source)
+			if err != nil {
+				result.Errors = append(result.Errors, err.Error())
+			}
+			pending = append(pending, sitemaps...)
+			continue
+		}
+
+		body, err := fetch(ctx, source)
+		if err != nil {
+			result.Errors = append(result.Errors, err.Error())
+			continue
+		}
+		doc, err := parse(body)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", source, err))
+			continue
+		}
+
+		for _, child := range doc.sitemaps {
+			// An index entry older than Since cannot list newer URLs
+			if child.URL == "" || (!opts.Since.IsZero() && !child.LastMod.IsZero() && child.LastMod.Before(opts.Since)) {
+				continue
+			}
+			pending = append(pending, resolve(source, child.URL))
+		}
+
+		for _, entry := range doc.entries {
+			entry.URL = resolve(source, entry.URL)
+			if entry.URL == "" || listed[entry.URL] {
+				continue
+			}
+			listed[entry.URL] = true
+			if !opts.accepts(entry) {
+				result.Filtered++
+				continue
+			}
+			entry.Source = source
+			result.Entries = append(result.Entries, entry)
+			if opts.MaxURLs > 0 && len(result.Entries) >= opts.MaxURLs {
+				break
+			}
+		}
+	}
+
+	if len(result.Entries) == 0 && len(result.Errors) > 0 && len(result.Errors) >= result.Sitemaps {
+		return result, fmt.Errorf("no sitemap or feed could be read: %s", result.Errors[0])
+	}
+	return result, nil
+}
+
+// accepts applies the lastmod and pattern filters
+func (opts Options) accepts(entry Entry) bool {
+	if !opts.Since.IsZero() && !entry.LastMod.IsZero() && entry.LastMod.Before(opts.Since) {
+		return false
+	}
+	if len(opts.Include) > 0 {
+		included := false
+		for _, re := range opts.Include {
+			if re.MatchString(entry.URL) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false
+		}
+	}
+	for _, re := range opts.Exclude {
+		if re.MatchString(entry.URL) {
+			return false
+		}
+	}
+	return true
+}
+
+// readRobots returns the Sitemap: entries of a robots.txt
+func readRobots(ctx context.Context, fetch Fetcher, robotsURL string) ([]string, error) {
+	body, err := fetch(ctx, robotsURL)
+	if err != nil {
+		return nil, err
+	}
+	var sitemaps []string
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || !strings.EqualFold(strings.TrimSpace(name), "sitemap") {
+			continue
+		}
+		if value = strings.TrimSpace(value); value != "" {
+			sitemaps = append(sitemaps, resolve(robotsURL, value))
+		}
+	}
+	return sitemaps, nil
+}
+
+// isSiteRoot reports whether source is a bare site URL such as https://example.com/
+func isSiteRoot(source string) bool {
+	u, err := url.Parse(source)
+	return err == nil && u.Host != "" && (u.Path == "" || u.Path == "/") && u.RawQuery == ""
+}
+
+func isRobots(source string) bool {
+	u, err := url.Parse(source)
+	return err == nil && strings.EqualFold(u.Path, "/robots.txt")
+}
+
+// resolve makes ref absolute against base
+func resolve(base, ref string) string {
+	ref = strings.TrimSpace(ref)
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return ""
+	}
+	return baseURL.ResolveReference(refURL).String()
+}
+
+// document is a parsed sitemap or feed
+type document struct {
+	sitemaps []Entry // Children of a sitemap index
+	entries  []Entry
+}
+
+// xmlDocument covers sitemaps (<urlset>, <sitemapindex>), RSS 2.0 (<rss><channel><item>),
+// RSS 1.0 (<rdf:RDF><item>) and Atom (<feed><entry>); elements match by local name
+type xmlDocument struct {
+	XMLName  xml.Name
+	URLs     []xmlLocation `xml:"url"`
+	Sitemaps []xmlLocation `xml:"sitemap"`
+	Items    []rssItem     `xml:"item"`
+	Channel  struct {
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type xmlLocation struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+type rssItem struct {
+	Title   string `xml:"title"`
+	Link    string `xml:"link"`
+	GUID    string `xml:"guid"`
+	PubDate string `xml:"pubDate"`
+	Date    string `xml:"date"` // dc:date
+}
+
+type atomEntry struct {
+	Title string `xml:"title"`
+	Links []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Updated   string `xml:"updated"`
+	Published string `xml:"published"`
+}
+
+// parse decodes a (possibly gzipped) sitemap or feed
+func parse(body []byte) (*document, error) {
+	if len(body) > 2 && body[0] == 0x1f && body[1] == 0x8b {
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip: %w", err)
+		}
+		defer reader.Close()
+		// Guard against decompression bombs
+		body, err = io.ReadAll(io.LimitReader(reader, DefaultMaxBytes+1))
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip: %w", err)
+		}
+		if len(body) > DefaultMaxBytes {
+			return nil, fmt.Errorf("decompressed size exceeds %d bytes", DefaultMaxBytes)
+		}
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	decoder.CharsetReader = charset.NewReaderLabel
+	decoder.Strict = false
+	var raw xmlDocument
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("not a sitemap or feed: %w", err)
+	}
+
+	doc := &document{}
+	switch raw.XMLName.Local {
+	case "urlset":
+		for _, loc := range raw.URLs {
+			doc.entries = append(doc.entries, Entry{URL: loc.Loc, LastMod: parseDate(loc.LastMod)})
+		}
+	case "sitemapindex":
+		for _, loc := range raw.Sitemaps {
+			doc.sitemaps = append(doc.sitemaps, Entry{URL: loc.Loc, LastMod: parseDate(loc.LastMod)})
+		}
+	case "rss", "RDF":
+		for _, item := range append(raw.Channel.Items, raw.Items...) {
+			link := item.Link
+			if link == "" && strings.HasPrefix(item.GUID, "http") {
+				link = item.GUID
+			}
+			date := parseDate(item.PubDate)
+			if date.IsZero() {
+				date = parseDate(item.Date)
+			}
+			doc.entries = append(doc.entries, Entry{URL: link, LastMod: date, Title: strings.TrimSpace(item.Title)})
+		}
+	case "feed":
+		for _, entry := range raw.Entries {
+			link := ""
+			for _, l := range entry.Links {
+				if l.Rel == "" || l.Rel == "alternate" {
+					link = l.Href
+					break
+				}
+			}
+			date := parseDate(entry.Updated)
+			if date.IsZero() {
+				date = parseDate(entry.Published)
+			}
+			doc.entries = append(doc.entries, Entry{URL: link, LastMod: date, Title: strings.TrimSpace(entry.Title)})
+		}
+	default:
+		return nil, fmt.Errorf("unsupported document <%s>", raw.XMLName.Local)
+	}
+	return doc, nil
+}
+
+// dateLayouts are the W3C datetime forms used by sitemaps and the RFC 822 forms used by RSS
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04Z07:00",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"2006-01",
+	"2006",
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	time.RFC822Z,
+	time.RFC822,
+}
+
+// parseDate parses a lastmod or feed date; unparseable dates yield the zero time
+func parseDate(value string) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,209 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/uzzalhcse/crawlify/pkg/models"
+)
+
+// AIUsageRepository persists the AI call log and the AI response cache
+type AIUsageRepository struct {
+	db *PostgresDB
+}
+
+// NewAIUsageRepository creates a new AI usage repository
+func NewAIUsageRepository(db *PostgresDB) *AIUsageRepository {
+	return &AIUsageRepository{db: db}
+}
+
+// Record logs an AI call
+func (r *AIUsageRepository) Record(ctx context.Context, usage *models.AIUsage) error {
+	if usage.ID == "" {
+		usage.ID = uuid.New().String()
+	}
+	if usage.CreatedAt.IsZero() {
+		usage.CreatedAt = time.Now()
+	}
+	query := `
+		INSERT INTO ai_usage (id, provider, model, feature, workflow_id, execution_id, status,
+			prompt_tokens, response_tokens, tokens_estimated, latency_ms, cost_usd, prompt_hash, error, created_at)
+		VALUES ($1, $2, $3, $4, NULLIF($5, '')::uuid, NULLIF($6, '')::uuid, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+	`
+	_, err := r.db.Pool.Exec(ctx, query,
+		usage.ID, usage.Provider, usage.Model, usage.Feature, usage.WorkflowID, usage.ExecutionID, usage.Status,
+		usage.PromptTokens, usage.ResponseTokens, usage.TokensEstimated, usage.LatencyMs, usage.CostUSD,
+		usage.PromptHash, usage.Error, usage.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record AI usage: %w", err)
+	}
+	return nil
+}
+
+// Spend returns the cost and number of AI calls made since a time, for one workflow or, when
+// workflowID is empty, for all of them
+// Cached and blocked calls are not counted.
+func (r *AIUsageRepository) Spend(ctx context.Context, since time.Time, workflowID string) (float64, int, error) {
+	query := `
+		SELECT COALESCE(SUM(cost_usd), 0)::float8, COUNT(*)
+		FROM ai_usage
+		WHERE created_at >= $1 AND status IN ('success', 'error')
+			AND ($2 = '' OR workflow_id = NULLIF($2, '')::uuid)
+	`
+	var cost float64
+	var calls int
+	if err := r.db.Pool.QueryRow(ctx, query, since, workflowID).Scan(&cost, &calls); err != nil {
+		return 0, 0, fmt.Errorf("failed to read AI spend: %w", err)
+	}
+	return cost, calls, nil
+}
+
+// aiUsageGroups are the columns usage can be summarized by
+var aiUsageGroups = map[string]string{
+	"day":      "to_char(created_at AT TIME ZONE 'UTC', 'YYYY-MM-DD')",
+	"feature":  "feature",
+	"provider": "provider",
+	"model":    "model",
+	"workflow": "COALESCE(workflow_id::text, '')",
+	"status":   "status",
+}
+
+// Summary totals AI calls between from and to, grouped by day, feature, provider, model,
+// workflow or status
+func (r *AIUsageRepository) Summary(ctx context.Context, from, to time.Time, groupBy, workflowID string) ([]models.AIUsageSummary, error) {
+	group, ok := aiUsageGroups[groupBy]
+	if !ok {
+		return nil, fmt.Errorf("cannot group AI usage by '%s'", groupBy)
+	}
+	query := `
+		SELECT ` + group + ` AS grp,
+			COUNT(*),
+			COUNT(*) FILTER (WHERE status = 'cached'),
+			COUNT(*) FILTER (WHERE status = 'budget_blocked'),
+			COUNT(*) FILTER (WHERE status = 'error'),
+			COALESCE(SUM(prompt_tokens), 0),
+			COALESCE(SUM(response_tokens), 0),
+			COALESCE(SUM(cost_usd), 0)::float8,
+			COALESCE(AVG(latency_ms) FILTER (WHERE status IN ('success', 'error')), 0)::float8
+		FROM ai_usage
+		WHERE created_at >= $1 AND created_at < $2
+			AND ($3 = '' OR workflow_id = NULLIF($3, '')::uuid)
+		GROUP BY grp
+		ORDER BY grp
+	`
+	rows, err := r.db.Pool.Query(ctx, query, from, to, workflowID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to summarize AI usage: %w", err)
+	}
+	defer rows.Close()
+
+	summaries := []models.AIUsageSummary{}
+	for rows.Next() {
+		var s models.AIUsageSummary
+		if err := rows.Scan(&s.Group, &s.Calls, &s.CachedCalls, &s.BlockedCalls, &s.FailedCalls,
+			&s.PromptTokens, &s.ResponseTokens, &s.CostUSD, &s.AvgLatencyMs); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries, rows.Err()
+}
+
+// List lists AI calls, newest first, optionally for one workflow or feature
+func (r *AIUsageRepository) List(ctx context.Context, workflowID, feature string, limit, offset int) ([]*models.AIUsage, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	query := `
+		SELECT id, provider, model, feature, COALESCE(workflow_id::text, ''), COALESCE(execution_id::text, ''),
+			status, prompt_tokens, response_tokens, tokens_estimated, latency_ms, cost_usd::float8,
+			prompt_hash, error, created_at
+		FROM ai_usage
+		WHERE ($1 = '' OR workflow_id = NULLIF($1, '')::uuid) AND ($2 = '' OR feature = $2)
+		ORDER BY created_at DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := r.db.Pool.Query(ctx, query, workflowID, feature, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list AI usage: %w", err)
+	}
+	defer rows.Close()
+
+	usages := []*models.AIUsage{}
+	for rows.Next() {
+		var u models.AIUsage
+		var createdAt *time.Time
+		if err := rows.Scan(&u.ID, &u.Provider, &u.Model, &u.Feature, &u.WorkflowID, &u.ExecutionID,
+			&u.Status, &u.PromptTokens, &u.ResponseTokens, &u.TokensEstimated, &u.LatencyMs, &u.CostUSD,
+			&u.PromptHash, &u.Error, &createdAt); err != nil {
+			return nil, err
+		}
+		if createdAt != nil {
+			u.CreatedAt = *createdAt
+		}
+		usages = append(usages, &u)
+	}
+	return usages, rows.Err()
+}
+
+// GetCachedResponse returns the unexpired cached answer for a prompt hash and counts the hit,
+// or nil when there is none
+func (r *AIUsageRepository) GetCachedResponse(ctx context.Context, promptHash string) (*models.AICachedResponse, error) {
+	query := `
+		UPDATE ai_response_cache SET hits = hits + 1
+		WHERE prompt_hash = $1 AND (expires_at IS NULL OR expires_at > NOW())
+		RETURNING prompt_hash, provider, model, response, prompt_tokens, response_tokens, hits, created_at, expires_at
+	`
+	var c models.AICachedResponse
+	var createdAt *time.Time
+	err := r.db.Pool.QueryRow(ctx, query, promptHash).Scan(
+		&c.PromptHash, &c.Provider, &c.Model, &c.Response, &c.PromptTokens, &c.ResponseTokens,
+		&c.Hits, &createdAt, &c.ExpiresAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read AI response cache: %w", err)
+	}
+	if createdAt != nil {
+		c.CreatedAt = *createdAt
+	}
+	return &c, nil
+}
+
+// PutCachedResponse stores an answer, replacing an older one for the same prompt hash
+func (r *AIUsageRepository) PutCachedResponse(ctx context.Context, c *models.AICachedResponse) error {
+	query := `
+		INSERT INTO ai_response_cache (prompt_hash, provider, model, response, prompt_tokens, response_tokens, hits, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, 0, NOW(), $7)
+		ON CONFLICT (prompt_hash) DO UPDATE SET
+			provider = EXCLUDED.provider, model = EXCLUDED.model, response = EXCLUDED.response,
+			prompt_tokens = EXCLUDED.prompt_tokens, response_tokens = EXCLUDED.response_tokens,
+			hits = 0, created_at = NOW(), expires_at = EXCLUDED.expires_at
+	`
+	_, err := r.db.Pool.Exec(ctx, query,
+		c.PromptHash, c.Provider, c.Model, c.Response, c.PromptTokens, c.ResponseTokens, c.ExpiresAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to write AI response cache: %w", err)
+	}
+	return nil
+}
+
+// ClearCache deletes cached answers; with expiredOnly, only those past their expiry
+func (r *AIUsageRepository) ClearCache(ctx context.Context, expiredOnly bool) (int64, error) {
+	query := `DELETE FROM ai_response_cache`
+	if expiredOnly {
+		query += ` WHERE expires_at IS NOT NULL AND expires_at <= NOW()`
+	}
+	tag, err := r.db.Pool.Exec(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear AI response cache: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/uzzalhcse/crawlify/pkg/models"
 )
 
@@ -67,7 +68,8 @@ func (r *ExecutionRepository) UpdateStatus(ctx context.Context, id string, statu
 	var query string
 	var args []interface{}
 
-	if status == models.ExecutionStatusCompleted || status == models.ExecutionStatusFailed || status == models.ExecutionStatusCancelled {
+	if status == models.ExecutionStatusCompleted || status == models.ExecutionStatusFailed ||
+		status == models.ExecutionStatusCancelled || status == models.ExecutionStatusStopped {
 		query = `
 			UPDATE workflow_executions
 			SET status = $2, completed_at = NOW(), error = $3
@@ -250,6 +252,27 @@ func (r *ExecutionRepository) List(ctx context.Context, workflowID, status strin
 	return executions, nil
 }
 
+// GetLastCompletedStart returns when the workflow's most recent completed execution, other than
+// excludeID, started; ok is false when there is none
+func (r *ExecutionRepository) GetLastCompletedStart(ctx context.Context, workflowID, excludeID string) (startedAt time.Time, ok bool, err error) {
+	query := `
+		SELECT started_at
+		FROM workflow_executions
+		WHERE workflow_id = $1 AND status = $2 AND id <> $3
+		ORDER BY started_at DESC
+		LIMIT 1
+	`
+
+	err = r.db.Pool.QueryRow(ctx, query, workflowID, models.ExecutionStatusCompleted, excludeID).Scan(&startedAt)
+	if err == pgx.ErrNoRows {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("failed to get last completed execution: %w", err)
+	}
+	return startedAt, true, nil
+}
+
 func (r *ExecutionRepository) Count(ctx context.Context, workflowID, status string) (int, error) {
 	query := `
 		SELECT COUNT(*)
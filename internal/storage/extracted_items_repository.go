@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 
 	"github.com/jackc/pgx/v5"
@@ -28,18 +29,24 @@ func (r *ExtractedItemsRepository) Create(ctx context.Context, item *models.Extr
 		dataJSON = "{}"
 	}
 
+	validationJSON, err := marshalValidationErrors(item.ValidationErrors)
+	if err != nil {
+		return err
+	}
+
 	const query = `
 		INSERT INTO extracted_items (
-			id, execution_id, url_id, node_execution_id, schema_name, data, extracted_at
-		) VALUES ($1, $2, $3, $4, $5, $6, $7)
-		ON CONFLICT (execution_id, url_id, schema_name) 
-		DO UPDATE SET data = EXCLUDED.data, extracted_at = EXCLUDED.extracted_at
+			id, execution_id, url_id, node_execution_id, schema_name, item_key, data, extracted_at, validation_errors
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		ON CONFLICT (execution_id, url_id, schema_name, (COALESCE(item_key, ''))) 
+		DO UPDATE SET data = EXCLUDED.data, extracted_at = EXCLUDED.extracted_at,
+			node_execution_id = EXCLUDED.node_execution_id, validation_errors = EXCLUDED.validation_errors
 		RETURNING id
 	`
 
-	err := r.db.Pool.QueryRow(ctx, query,
+	err = r.db.Pool.QueryRow(ctx, query,
 		item.ID, item.ExecutionID, item.URLID, item.NodeExecutionID,
-		item.SchemaName, dataJSON, item.ExtractedAt,
+		item.SchemaName, item.ItemKey, dataJSON, item.ExtractedAt, validationJSON,
 	).Scan(&item.ID)
 
 	if err != nil {
@@ -65,15 +72,21 @@ func (r *ExtractedItemsRepository) CreateBatch(ctx context.Context, items []*mod
 			dataJSON = "{}"
 		}
 
+		validationJSON, err := marshalValidationErrors(item.ValidationErrors)
+		if err != nil {
+			return err
+		}
+
 		batch.Queue(`
 			INSERT INTO extracted_items (
-				id, execution_id, url_id, node_execution_id, schema_name, data, extracted_at
-			) VALUES ($1, $2, $3, $4, $5, $6, $7)
-			ON CONFLICT (execution_id, url_id, schema_name) 
-			DO UPDATE SET data = EXCLUDED.data, extracted_at = EXCLUDED.extracted_at
+				id, execution_id, url_id, node_execution_id, schema_name, item_key, data, extracted_at, validation_errors
+			) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (execution_id, url_id, schema_name, (COALESCE(item_key, ''))) 
+			DO UPDATE SET data = EXCLUDED.data, extracted_at = EXCLUDED.extracted_at,
+				node_execution_id = EXCLUDED.node_execution_id, validation_errors = EXCLUDED.validation_errors
 		`,
 			item.ID, item.ExecutionID, item.URLID, item.NodeExecutionID,
-			item.SchemaName, dataJSON, item.ExtractedAt,
+			item.SchemaName, item.ItemKey, dataJSON, item.ExtractedAt, validationJSON,
 		)
 	}
 
@@ -93,8 +106,8 @@ func (r *ExtractedItemsRepository) CreateBatch(ctx context.Context, items []*mod
 // GetByExecutionID retrieves extracted items for an execution with pagination
 func (r *ExtractedItemsRepository) GetByExecutionID(ctx context.Context, executionID string, limit, offset int) ([]*models.ExtractedItem, error) {
 	query := `
-		SELECT id, execution_id, url_id, node_execution_id, schema_name,
-			   data, extracted_at
+		SELECT id, execution_id, url_id, node_execution_id, schema_name, item_key,
+			   data, extracted_at, validation_errors
 		FROM extracted_items
 		WHERE execution_id = $1
 		ORDER BY extracted_at DESC
@@ -122,8 +135,8 @@ func (r *ExtractedItemsRepository) GetByExecutionID(ctx context.Context, executi
 // GetByURL retrieves all extracted items for a specific URL
 func (r *ExtractedItemsRepository) GetByURL(ctx context.Context, executionID, urlID string) ([]*models.ExtractedItem, error) {
 	query := `
-		SELECT id, execution_id, url_id, node_execution_id, schema_name,
-			   data, extracted_at
+		SELECT id, execution_id, url_id, node_execution_id, schema_name, item_key,
+			   data, extracted_at, validation_errors
 		FROM extracted_items
 		WHERE execution_id = $1 AND url_id = $2
 		ORDER BY extracted_at DESC
@@ -158,6 +171,43 @@ func (r *ExtractedItemsRepository) GetCount(ctx context.Context, executionID str
 	return count, nil
 }
 
+// GetValidationStats returns the number of items with schema validation errors
+// and the error count per field for an execution
+func (r *ExtractedItemsRepository) GetValidationStats(ctx context.Context, executionID string) (int, map[string]int, error) {
+	var invalid int
+	query := `SELECT COUNT(*) FROM extracted_items WHERE execution_id = $1 AND validation_errors IS NOT NULL`
+	if err := r.db.Pool.QueryRow(ctx, query, executionID).Scan(&invalid); err != nil {
+		return 0, nil, fmt.Errorf("failed to count invalid items: %w", err)
+	}
+
+	byField := make(map[string]int)
+	if invalid == 0 {
+		return 0, byField, nil
+	}
+
+	rows, err := r.db.Pool.Query(ctx, `
+		SELECT e->>'field', COUNT(*)
+		FROM extracted_items, jsonb_array_elements(validation_errors) e
+		WHERE execution_id = $1 AND validation_errors IS NOT NULL
+		GROUP BY 1
+	`, executionID)
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to aggregate validation errors: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var field string
+		var count int
+		if err := rows.Scan(&field, &count); err != nil {
+			return 0, nil, fmt.Errorf("failed to scan validation stats: %w", err)
+		}
+		byField[field] = count
+	}
+
+	return invalid, byField, nil
+}
+
 // DeleteByExecutionID deletes all extracted items for an execution
 func (r *ExtractedItemsRepository) DeleteByExecutionID(ctx context.Context, executionID string) error {
 	query := `DELETE FROM extracted_items WHERE execution_id = $1`
@@ -174,15 +224,22 @@ func (r *ExtractedItemsRepository) scanRow(rows interface {
 }) (*models.ExtractedItem, error) {
 	var item models.ExtractedItem
 	var dataJSON sql.NullString
+	var validationJSON []byte
 
 	err := rows.Scan(
-		&item.ID, &item.ExecutionID, &item.URLID, &item.NodeExecutionID, &item.SchemaName,
-		&dataJSON, &item.ExtractedAt,
+		&item.ID, &item.ExecutionID, &item.URLID, &item.NodeExecutionID, &item.SchemaName, &item.ItemKey,
+		&dataJSON, &item.ExtractedAt, &validationJSON,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan item: %w", err)
 	}
 
+	if len(validationJSON) > 0 {
+		if err := json.Unmarshal(validationJSON, &item.ValidationErrors); err != nil {
+			return nil, fmt.Errorf("failed to decode validation errors: %w", err)
+		}
+	}
+
 	if dataJSON.Valid {
 		item.Data = dataJSON.String
 	} else {
@@ -192,6 +249,18 @@ func (r *ExtractedItemsRepository) scanRow(rows interface {
 	return &item, nil
 }
 
+// marshalValidationErrors encodes validation errors, using NULL for valid items
+func marshalValidationErrors(errs []models.FieldValidationError) ([]byte, error) {
+	if len(errs) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(errs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal validation errors: %w", err)
+	}
+	return data, nil
+}
+
 // GetWithHierarchy retrieves items with their URL hierarchy
 func (r *ExtractedItemsRepository) GetWithHierarchy(ctx context.Context, executionID string) ([]*ExtractedItemWithHierarchy, error) {
 	query := `
@@ -209,8 +278,8 @@ func (r *ExtractedItemsRepository) GetWithHierarchy(ctx context.Context, executi
 			INNER JOIN url_tree ut ON uq.parent_url_id = ut.id::uuid
 		)
 		SELECT 
-			ei.id, ei.execution_id, ei.url_id, ei.schema_name,
-			ei.data, ei.extracted_at,
+			ei.id, ei.execution_id, ei.url_id, ei.node_execution_id, ei.schema_name, ei.item_key,
+			ei.data, ei.extracted_at, ei.validation_errors,
 			ut.url, ut.url_type, ut.marker, ut.level,
 			parent_uq.url as parent_url, parent_uq.url_type as parent_url_type
 		FROM extracted_items ei
@@ -230,11 +299,12 @@ func (r *ExtractedItemsRepository) GetWithHierarchy(ctx context.Context, executi
 	for rows.Next() {
 		var item ExtractedItemWithHierarchy
 		var dataJSON sql.NullString
+		var validationJSON []byte
 		var parentURL, parentURLType sql.NullString
 
 		err := rows.Scan(
-			&item.ID, &item.ExecutionID, &item.URLID, &item.SchemaName,
-			&dataJSON, &item.ExtractedAt,
+			&item.ID, &item.ExecutionID, &item.URLID, &item.NodeExecutionID, &item.SchemaName, &item.ItemKey,
+			&dataJSON, &item.ExtractedAt, &validationJSON,
 			&item.URL, &item.URLType, &item.Marker, &item.URLLevel,
 			&parentURL, &parentURLType,
 		)
@@ -248,6 +318,12 @@ func (r *ExtractedItemsRepository) GetWithHierarchy(ctx context.Context, executi
 			item.Data = "{}"
 		}
 
+		if len(validationJSON) > 0 {
+			if err := json.Unmarshal(validationJSON, &item.ValidationErrors); err != nil {
+				return nil, fmt.Errorf("failed to decode validation errors: %w", err)
+			}
+		}
+
 		if parentURL.Valid {
 			item.ParentURL = &parentURL.String
 		}
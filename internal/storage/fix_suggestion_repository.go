@@ -183,6 +183,25 @@ func (r *FixSuggestionRepository) UpdateStatus(ctx context.Context, id string, s
 	return nil
 }
 
+// UpdateVerification stores a new verification result for a suggestion
+func (r *FixSuggestionRepository) UpdateVerification(ctx context.Context, id string, result *models.VerificationResult) error {
+	verificationJSON, _ := json.Marshal(result)
+
+	query := `
+		UPDATE fix_suggestions
+		SET verification_result = $1
+		WHERE id = $2
+	`
+
+	_, err := r.db.Pool.Exec(ctx, query, verificationJSON, id)
+	if err != nil {
+		logger.Error("Failed to update fix suggestion verification", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
 // MarkAsApplied marks a suggestion as applied
 func (r *FixSuggestionRepository) MarkAsApplied(ctx context.Context, id string) error {
 	query := `
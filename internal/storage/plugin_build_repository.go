@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/uzzalhcse/crawlify/pkg/models"
+)
+
+// PluginBuildRepository persists plugin build jobs and their logs
+type PluginBuildRepository struct {
+	db *PostgresDB
+}
+
+// NewPluginBuildRepository creates a new plugin build repository
+func NewPluginBuildRepository(db *PostgresDB) *PluginBuildRepository {
+	return &PluginBuildRepository{db: db}
+}
+
+const pluginBuildColumns = `
+	id, COALESCE(plugin_id::text, '') as plugin_id, plugin_slug,
+	COALESCE(plugin_version_id::text, '') as plugin_version_id,
+	source_revision, status, tests_only, tests, test_log, log,
+	artifact, artifact_hash, artifact_size, sbom,
+	created_at, started_at, completed_at
+`
+
+// Create inserts a queued build
+func (r *PluginBuildRepository) Create(ctx context.Context, build *models.PluginBuild) error {
+	query := `
+		INSERT INTO plugin_builds (id, plugin_id, plugin_slug, plugin_version_id, source_revision, status, tests_only, created_at)
+		VALUES ($1, NULLIF($2, '')::uuid, $3, NULLIF($4, '')::uuid, $5, $6, $7, $8)
+	`
+	_, err := r.db.Pool.Exec(ctx, query,
+		build.ID, build.PluginID, build.PluginSlug, build.PluginVersionID, build.SourceRevision,
+		build.Status, build.TestsOnly, build.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create plugin build: %w", err)
+	}
+	return nil
+}
+
+// Update saves the progress of a build: status, test outcome and logs
+func (r *PluginBuildRepository) Update(ctx context.Context, build *models.PluginBuild) error {
+	query := `
+		UPDATE plugin_builds
+		SET status = $2, tests = $3, test_log = $4, log = $5, started_at = $6
+		WHERE id = $1
+	`
+	_, err := r.db.Pool.Exec(ctx, query,
+		build.ID, build.Status, build.Tests, build.TestLog, build.Log, build.StartedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to update plugin build: %w", err)
+	}
+	return nil
+}
+
+// Finish saves a finished build. When a successful build belongs to a plugin version, its
+// artifact, hash, size and SBOM are recorded on the version as the binary for goos/goarch.
+func (r *PluginBuildRepository) Finish(ctx context.Context, build *models.PluginBuild, goos, goarch string) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE plugin_builds
+		SET status = $2, tests = $3, test_log = $4, log = $5,
+			artifact = $6, artifact_hash = $7, artifact_size = $8, sbom = $9,
+			started_at = $10, completed_at = $11
+		WHERE id = $1
+	`
+	_, err = tx.Exec(ctx, query,
+		build.ID, build.Status, build.Tests, build.TestLog, build.Log,
+		build.Artifact, build.ArtifactHash, build.ArtifactSize, build.SBOM,
+		build.StartedAt, build.CompletedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to finish plugin build: %w", err)
+	}
+
+	if build.Status == models.BuildStatusSuccess && build.PluginVersionID != "" && build.Artifact != "" {
+		column, ok := binaryPathColumns[goos+"/"+goarch]
+		if !ok {
+			return fmt.Errorf("plugin versions have no binary for %s/%s", goos, goarch)
+		}
+		query := `
+			UPDATE plugin_versions
+			SET ` + column + ` = $2, binary_hash = $3, binary_size_bytes = $4, build_id = $5, sbom = $6
+			WHERE id = $1
+		`
+		_, err = tx.Exec(ctx, query,
+			build.PluginVersionID, build.Artifact, build.ArtifactHash, build.ArtifactSize, build.ID, build.SBOM,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to record build on plugin version: %w", err)
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// binaryPathColumns maps a platform to its binary column in plugin_versions
+var binaryPathColumns = map[string]string{
+	"linux/amd64":  "linux_amd64_binary_path",
+	"linux/arm64":  "linux_arm64_binary_path",
+	"darwin/amd64": "darwin_amd64_binary_path",
+	"darwin/arm64": "darwin_arm64_binary_path",
+}
+
+// GetByID retrieves a build by ID
+func (r *PluginBuildRepository) GetByID(ctx context.Context, id string) (*models.PluginBuild, error) {
+	query := `SELECT ` + pluginBuildColumns + ` FROM plugin_builds WHERE id = $1`
+	build, err := scanPluginBuild(r.db.Pool.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("plugin build not found: %s", id)
+	}
+	return build, err
+}
+
+// ListByPlugin lists the builds of a plugin, newest first
+func (r *PluginBuildRepository) ListByPlugin(ctx context.Context, pluginSlug string, limit int) ([]*models.PluginBuild, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	query := `SELECT ` + pluginBuildColumns + ` FROM plugin_builds WHERE plugin_slug = $1 ORDER BY created_at DESC LIMIT $2`
+	return r.list(ctx, query, pluginSlug, limit)
+}
+
+// ListUnfinished lists queued and running builds, oldest first
+func (r *PluginBuildRepository) ListUnfinished(ctx context.Context) ([]*models.PluginBuild, error) {
+	query := `SELECT ` + pluginBuildColumns + ` FROM plugin_builds WHERE status IN ('queued', 'building') ORDER BY created_at`
+	return r.list(ctx, query)
+}
+
+func (r *PluginBuildRepository) list(ctx context.Context, query string, args ...interface{}) ([]*models.PluginBuild, error) {
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	builds := []*models.PluginBuild{}
+	for rows.Next() {
+		build, err := scanPluginBuild(rows)
+		if err != nil {
+			return nil, err
+		}
+		builds = append(builds, build)
+	}
+	return builds, rows.Err()
+}
+
+func scanPluginBuild(row pgx.Row) (*models.PluginBuild, error) {
+	var build models.PluginBuild
+	var createdAt *time.Time
+	err := row.Scan(
+		&build.ID, &build.PluginID, &build.PluginSlug, &build.PluginVersionID,
+		&build.SourceRevision, &build.Status, &build.TestsOnly, &build.Tests, &build.TestLog, &build.Log,
+		&build.Artifact, &build.ArtifactHash, &build.ArtifactSize, &build.SBOM,
+		&createdAt, &build.StartedAt, &build.CompletedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if createdAt != nil {
+		build.CreatedAt = *createdAt
+	}
+	return &build, nil
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/uzzalhcse/crawlify/pkg/models"
+)
+
+// PluginKVRepository persists the key-value stores of plugins
+type PluginKVRepository struct {
+	db *PostgresDB
+}
+
+// NewPluginKVRepository creates a new plugin key-value repository
+func NewPluginKVRepository(db *PostgresDB) *PluginKVRepository {
+	return &PluginKVRepository{db: db}
+}
+
+// scopeCondition matches the rows of a scope; it uses $1 to $3
+const scopeCondition = `plugin_slug = $1 AND workflow_id = $2 AND COALESCE(execution_id::text, '') = $3`
+
+// GetValue returns the JSON value stored under key
+func (r *PluginKVRepository) GetValue(ctx context.Context, scope models.PluginKVScope, key string) (json.RawMessage, bool, error) {
+	query := `SELECT value FROM plugin_kv WHERE ` + scopeCondition + ` AND key = $4`
+	var value []byte
+	err := r.db.Pool.QueryRow(ctx, query, scope.PluginSlug, scope.WorkflowID, scope.ExecutionID, key).Scan(&value)
+	if err == pgx.ErrNoRows {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get plugin value: %w", err)
+	}
+	return value, true, nil
+}
+
+// SetValue stores a JSON value under key, replacing the previous one
+func (r *PluginKVRepository) SetValue(ctx context.Context, scope models.PluginKVScope, key string, value json.RawMessage) error {
+	query := `
+		INSERT INTO plugin_kv (plugin_slug, workflow_id, execution_id, key, value, updated_at)
+		VALUES ($1, $2, NULLIF($3, '')::uuid, $4, $5, CURRENT_TIMESTAMP)
+		ON CONFLICT (plugin_slug, workflow_id, (COALESCE(execution_id::text, '')), key)
+		DO UPDATE SET value = EXCLUDED.value, updated_at = EXCLUDED.updated_at
+	`
+	_, err := r.db.Pool.Exec(ctx, query, scope.PluginSlug, scope.WorkflowID, scope.ExecutionID, key, []byte(value))
+	if err != nil {
+		return fmt.Errorf("failed to set plugin value: %w", err)
+	}
+	return nil
+}
+
+// DeleteValue removes key; removing a missing key is not an error
+func (r *PluginKVRepository) DeleteValue(ctx context.Context, scope models.PluginKVScope, key string) error {
+	query := `DELETE FROM plugin_kv WHERE ` + scopeCondition + ` AND key = $4`
+	if _, err := r.db.Pool.Exec(ctx, query, scope.PluginSlug, scope.WorkflowID, scope.ExecutionID, key); err != nil {
+		return fmt.Errorf("failed to delete plugin value: %w", err)
+	}
+	return nil
+}
+
+// ListKeys returns the keys starting with prefix, sorted
+func (r *PluginKVRepository) ListKeys(ctx context.Context, scope models.PluginKVScope, prefix string) ([]string, error) {
+	query := `SELECT key FROM plugin_kv WHERE ` + scopeCondition + ` AND starts_with(key, $4) ORDER BY key`
+	rows, err := r.db.Pool.Query(ctx, query, scope.PluginSlug, scope.WorkflowID, scope.ExecutionID, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list plugin keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []string{}
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
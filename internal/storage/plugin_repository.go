@@ -186,6 +186,13 @@ func (r *PluginRepository) UpdatePlugin(ctx context.Context, plugin *models.Plug
 	return err
 }
 
+// SetVerified records whether every version of a plugin carries a signature from a trusted key
+func (r *PluginRepository) SetVerified(ctx context.Context, id string, verified bool) error {
+	query := `UPDATE plugins SET is_verified = $1 WHERE id = $2`
+	_, err := r.db.Pool.Exec(ctx, query, verified, id)
+	return err
+}
+
 // DeletePlugin deletes a plugin
 func (r *PluginRepository) DeletePlugin(ctx context.Context, id string) error {
 	query := `DELETE FROM plugins WHERE id = $1`
@@ -197,14 +204,26 @@ func (r *PluginRepository) DeletePlugin(ctx context.Context, id string) error {
 func (r *PluginRepository) PublishVersion(ctx context.Context, version *models.PluginVersion) error {
 	query := `
 		INSERT INTO plugin_versions (
-			id, plugin_id, version, changelog, is_stable,
-			linux_amd64_binary_path, config_schema
-		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+			id, plugin_id, version, changelog, is_stable, min_crawlify_version,
+			linux_amd64_binary_path, linux_arm64_binary_path,
+			darwin_amd64_binary_path, darwin_arm64_binary_path,
+			binary_hash, binary_size_bytes, config_schema,
+			runtime, capabilities, limits,
+			manifest, signature, signing_key_id
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19)
 	`
+	runtime := version.Runtime
+	if runtime == "" {
+		runtime = models.PluginRuntimeNative
+	}
 	_, err := r.db.Pool.Exec(ctx, query,
 		version.ID, version.PluginID, version.Version,
-		version.Changelog, version.IsStable,
-		version.LinuxAmd64BinaryPath, version.ConfigSchema,
+		version.Changelog, version.IsStable, version.MinCrawlifyVersion,
+		version.LinuxAmd64BinaryPath, version.LinuxArm64BinaryPath,
+		version.DarwinAmd64BinaryPath, version.DarwinArm64BinaryPath,
+		version.BinaryHash, version.BinarySizeBytes, version.ConfigSchema,
+		runtime, version.Capabilities, version.Limits,
+		version.Manifest, version.Signature, version.SigningKeyID,
 	)
 	return err
 }
@@ -225,6 +244,9 @@ func (r *PluginRepository) GetLatestVersion(ctx context.Context, pluginID string
 			COALESCE(binary_hash, '') as binary_hash,
 			COALESCE(binary_size_bytes, 0) as binary_size_bytes,
 			config_schema,
+			runtime, capabilities, limits,
+			COALESCE(build_id::text, '') as build_id, sbom,
+			manifest, signature, signing_key_id,
 			downloads,
 			published_at
 		FROM plugin_versions 
@@ -238,6 +260,9 @@ func (r *PluginRepository) GetLatestVersion(ctx context.Context, pluginID string
 		&version.LinuxAmd64BinaryPath, &version.LinuxArm64BinaryPath,
 		&version.DarwinAmd64BinaryPath, &version.DarwinArm64BinaryPath,
 		&version.BinaryHash, &version.BinarySizeBytes, &version.ConfigSchema,
+		&version.Runtime, &version.Capabilities, &version.Limits,
+		&version.BuildID, &version.SBOM,
+		&version.Manifest, &version.Signature, &version.SigningKeyID,
 		&version.Downloads, &version.PublishedAt,
 	)
 	return &version, err
@@ -259,6 +284,9 @@ func (r *PluginRepository) GetVersionByID(ctx context.Context, versionID string)
 			COALESCE(binary_hash, '') as binary_hash,
 			COALESCE(binary_size_bytes, 0) as binary_size_bytes,
 			config_schema,
+			runtime, capabilities, limits,
+			COALESCE(build_id::text, '') as build_id, sbom,
+			manifest, signature, signing_key_id,
 			downloads,
 			published_at
 		FROM plugin_versions 
@@ -270,11 +298,21 @@ func (r *PluginRepository) GetVersionByID(ctx context.Context, versionID string)
 		&version.LinuxAmd64BinaryPath, &version.LinuxArm64BinaryPath,
 		&version.DarwinAmd64BinaryPath, &version.DarwinArm64BinaryPath,
 		&version.BinaryHash, &version.BinarySizeBytes, &version.ConfigSchema,
+		&version.Runtime, &version.Capabilities, &version.Limits,
+		&version.BuildID, &version.SBOM,
+		&version.Manifest, &version.Signature, &version.SigningKeyID,
 		&version.Downloads, &version.PublishedAt,
 	)
 	return &version, err
 }
 
+// DeleteVersion deletes a plugin version
+func (r *PluginRepository) DeleteVersion(ctx context.Context, versionID string) error {
+	query := `DELETE FROM plugin_versions WHERE id = $1`
+	_, err := r.db.Pool.Exec(ctx, query, versionID)
+	return err
+}
+
 // ListVersions lists all versions
 func (r *PluginRepository) ListVersions(ctx context.Context, pluginID string) ([]*models.PluginVersion, error) {
 	query := `
@@ -290,6 +328,9 @@ func (r *PluginRepository) ListVersions(ctx context.Context, pluginID string) ([
 			COALESCE(binary_hash, '') as binary_hash,
 			COALESCE(binary_size_bytes, 0) as binary_size_bytes,
 			config_schema,
+			runtime, capabilities, limits,
+			COALESCE(build_id::text, '') as build_id, sbom,
+			manifest, signature, signing_key_id,
 			downloads,
 			published_at
 		FROM plugin_versions 
@@ -311,6 +352,9 @@ func (r *PluginRepository) ListVersions(ctx context.Context, pluginID string) ([
 			&v.LinuxAmd64BinaryPath, &v.LinuxArm64BinaryPath,
 			&v.DarwinAmd64BinaryPath, &v.DarwinArm64BinaryPath,
 			&v.BinaryHash, &v.BinarySizeBytes, &v.ConfigSchema,
+			&v.Runtime, &v.Capabilities, &v.Limits,
+			&v.BuildID, &v.SBOM,
+			&v.Manifest, &v.Signature, &v.SigningKeyID,
 			&v.Downloads, &v.PublishedAt,
 		)
 		if err != nil {
@@ -325,14 +369,19 @@ func (r *PluginRepository) ListVersions(ctx context.Context, pluginID string) ([
 // InstallPlugin records a plugin installation
 func (r *PluginRepository) InstallPlugin(ctx context.Context, installation *models.PluginInstallation) error {
 	query := `
-		INSERT INTO plugin_installations (id, plugin_id, plugin_version_id, workspace_id)
-		VALUES ($1, $2, $3, $4)
+		INSERT INTO plugin_installations (
+			id, plugin_id, plugin_version_id, workspace_id,
+			approved_capabilities, approved_limits, artifact_path
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
 		ON CONFLICT (plugin_id, workspace_id) DO UPDATE SET
-			plugin_version_id = $3, installed_at = NOW()
+			plugin_version_id = $3, approved_capabilities = $5, approved_limits = $6,
+			artifact_path = $7, installed_at = NOW()
 	`
 	_, err := r.db.Pool.Exec(ctx, query,
 		installation.ID, installation.PluginID,
 		installation.PluginVersionID, installation.WorkspaceID,
+		installation.ApprovedCapabilities, installation.ApprovedLimits,
+		installation.ArtifactPath,
 	)
 	return err
 }
@@ -347,11 +396,20 @@ func (r *PluginRepository) UninstallPlugin(ctx context.Context, pluginID, worksp
 // GetInstallation retrieves a plugin installation
 func (r *PluginRepository) GetInstallation(ctx context.Context, pluginID, workspaceID string) (*models.PluginInstallation, error) {
 	var installation models.PluginInstallation
-	query := `SELECT * FROM plugin_installations WHERE plugin_id = $1 AND workspace_id = $2`
+	query := `
+		SELECT pi.id, pi.plugin_id, pi.plugin_version_id, pi.workspace_id, pi.installed_at,
+			pi.last_used_at, pi.usage_count, pi.approved_capabilities, pi.approved_limits,
+			pi.artifact_path, pv.version
+		FROM plugin_installations pi
+		JOIN plugin_versions pv ON pv.id = pi.plugin_version_id
+		WHERE pi.plugin_id = $1 AND pi.workspace_id = $2
+	`
 	err := r.db.Pool.QueryRow(ctx, query, pluginID, workspaceID).Scan(
 		&installation.ID, &installation.PluginID, &installation.PluginVersionID,
 		&installation.WorkspaceID, &installation.InstalledAt,
 		&installation.LastUsedAt, &installation.UsageCount,
+		&installation.ApprovedCapabilities, &installation.ApprovedLimits,
+		&installation.ArtifactPath, &installation.Version,
 	)
 	return &installation, err
 }
@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/uzzalhcse/crawlify/pkg/models"
+)
+
+// PluginSourceRepository persists the revision history of plugin sources
+type PluginSourceRepository struct {
+	db *PostgresDB
+}
+
+// NewPluginSourceRepository creates a new plugin source repository
+func NewPluginSourceRepository(db *PostgresDB) *PluginSourceRepository {
+	return &PluginSourceRepository{db: db}
+}
+
+// Create saves a revision, numbering it after the plugin's latest one
+func (r *PluginSourceRepository) Create(ctx context.Context, rev *models.PluginSourceRevision) error {
+	if rev.ID == "" {
+		rev.ID = uuid.New().String()
+	}
+	rev.CreatedAt = time.Now()
+	if rev.Changes == nil {
+		rev.Changes = []models.SourceFileChange{}
+	}
+
+	query := `
+		INSERT INTO plugin_source_revisions (id, plugin_slug, revision, author, message, files, changes, diff, restored_from, created_at)
+		SELECT $1, $2, COALESCE(MAX(revision), 0) + 1, $3, $4, $5, $6, $7, $8, $9
+		FROM plugin_source_revisions WHERE plugin_slug = $2
+		RETURNING revision
+	`
+	err := r.db.Pool.QueryRow(ctx, query,
+		rev.ID, rev.PluginSlug, rev.Author, rev.Message, rev.Files, rev.Changes, rev.Diff, rev.RestoredFrom, rev.CreatedAt,
+	).Scan(&rev.Revision)
+	if err != nil {
+		return fmt.Errorf("failed to create source revision: %w", err)
+	}
+	return nil
+}
+
+// Get retrieves a revision of a plugin with its files and diff
+func (r *PluginSourceRepository) Get(ctx context.Context, pluginSlug string, revision int) (*models.PluginSourceRevision, error) {
+	query := `
+		SELECT id, plugin_slug, revision, author, message, files, changes, diff, restored_from, created_at
+		FROM plugin_source_revisions
+		WHERE plugin_slug = $1 AND revision = $2
+	`
+	rev, err := scanSourceRevision(r.db.Pool.QueryRow(ctx, query, pluginSlug, revision), true)
+	if err == pgx.ErrNoRows {
+		return nil, fmt.Errorf("source revision %d of plugin %s not found", revision, pluginSlug)
+	}
+	return rev, err
+}
+
+// Latest retrieves the newest revision of a plugin, or nil when it has no history yet
+func (r *PluginSourceRepository) Latest(ctx context.Context, pluginSlug string) (*models.PluginSourceRevision, error) {
+	query := `
+		SELECT id, plugin_slug, revision, author, message, files, changes, diff, restored_from, created_at
+		FROM plugin_source_revisions
+		WHERE plugin_slug = $1
+		ORDER BY revision DESC
+		LIMIT 1
+	`
+	rev, err := scanSourceRevision(r.db.Pool.QueryRow(ctx, query, pluginSlug), true)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	return rev, err
+}
+
+// List lists the revisions of a plugin, newest first, without their files and diffs
+func (r *PluginSourceRepository) List(ctx context.Context, pluginSlug string, limit, offset int) ([]*models.PluginSourceRevision, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	query := `
+		SELECT id, plugin_slug, revision, author, message, changes, restored_from, created_at
+		FROM plugin_source_revisions
+		WHERE plugin_slug = $1
+		ORDER BY revision DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := r.db.Pool.Query(ctx, query, pluginSlug, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []*models.PluginSourceRevision{}
+	for rows.Next() {
+		rev, err := scanSourceRevision(rows, false)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
+
+func scanSourceRevision(row pgx.Row, withContent bool) (*models.PluginSourceRevision, error) {
+	var rev models.PluginSourceRevision
+	var createdAt *time.Time
+	var err error
+	if withContent {
+		err = row.Scan(&rev.ID, &rev.PluginSlug, &rev.Revision, &rev.Author, &rev.Message,
+			&rev.Files, &rev.Changes, &rev.Diff, &rev.RestoredFrom, &createdAt)
+	} else {
+		err = row.Scan(&rev.ID, &rev.PluginSlug, &rev.Revision, &rev.Author, &rev.Message,
+			&rev.Changes, &rev.RestoredFrom, &createdAt)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if createdAt != nil {
+		rev.CreatedAt = *createdAt
+	}
+	return &rev, nil
+}
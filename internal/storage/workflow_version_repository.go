@@ -24,18 +24,27 @@ func (r *WorkflowVersionRepository) Create(ctx context.Context, version *models.
 		version.ID = uuid.New().String()
 	}
 
+	if version.Plugins == nil {
+		version.Plugins = []models.ResolvedPlugin{}
+	}
+	pluginsJSON, err := json.Marshal(version.Plugins)
+	if err != nil {
+		return fmt.Errorf("failed to marshal plugins: %w", err)
+	}
+
 	query := `
-		INSERT INTO workflow_versions (id, workflow_id, version, config, change_reason, created_at)
-		VALUES ($1, $2, $3, $4, $5, NOW())
+		INSERT INTO workflow_versions (id, workflow_id, version, config, change_reason, plugins, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
 		RETURNING created_at
 	`
 
-	err := r.db.Pool.QueryRow(ctx, query,
+	err = r.db.Pool.QueryRow(ctx, query,
 		version.ID,
 		version.WorkflowID,
 		version.Version,
 		version.Config,
 		version.ChangeReason,
+		pluginsJSON,
 	).Scan(&version.CreatedAt)
 
 	if err != nil {
@@ -48,7 +57,7 @@ func (r *WorkflowVersionRepository) Create(ctx context.Context, version *models.
 // GetLatest retrieves the latest version for a workflow
 func (r *WorkflowVersionRepository) GetLatest(ctx context.Context, workflowID string) (*models.WorkflowVersion, error) {
 	query := `
-		SELECT id, workflow_id, version, config, change_reason, created_at
+		SELECT id, workflow_id, version, config, change_reason, plugins, created_at
 		FROM workflow_versions
 		WHERE workflow_id = $1
 		ORDER BY version DESC
@@ -56,7 +65,7 @@ func (r *WorkflowVersionRepository) GetLatest(ctx context.Context, workflowID st
 	`
 
 	var version models.WorkflowVersion
-	var configJSON []byte
+	var configJSON, pluginsJSON []byte
 
 	err := r.db.Pool.QueryRow(ctx, query, workflowID).Scan(
 		&version.ID,
@@ -64,6 +73,7 @@ func (r *WorkflowVersionRepository) GetLatest(ctx context.Context, workflowID st
 		&version.Version,
 		&configJSON,
 		&version.ChangeReason,
+		&pluginsJSON,
 		&version.CreatedAt,
 	)
 
@@ -77,6 +87,9 @@ func (r *WorkflowVersionRepository) GetLatest(ctx context.Context, workflowID st
 	if err := json.Unmarshal(configJSON, &version.Config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	if err := json.Unmarshal(pluginsJSON, &version.Plugins); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal plugins: %w", err)
+	}
 
 	return &version, nil
 }
@@ -84,13 +97,13 @@ func (r *WorkflowVersionRepository) GetLatest(ctx context.Context, workflowID st
 // GetByVersion retrieves a specific version for a workflow
 func (r *WorkflowVersionRepository) GetByVersion(ctx context.Context, workflowID string, versionNum int) (*models.WorkflowVersion, error) {
 	query := `
-		SELECT id, workflow_id, version, config, change_reason, created_at
+		SELECT id, workflow_id, version, config, change_reason, plugins, created_at
 		FROM workflow_versions
 		WHERE workflow_id = $1 AND version = $2
 	`
 
 	var version models.WorkflowVersion
-	var configJSON []byte
+	var configJSON, pluginsJSON []byte
 
 	err := r.db.Pool.QueryRow(ctx, query, workflowID, versionNum).Scan(
 		&version.ID,
@@ -98,6 +111,7 @@ func (r *WorkflowVersionRepository) GetByVersion(ctx context.Context, workflowID
 		&version.Version,
 		&configJSON,
 		&version.ChangeReason,
+		&pluginsJSON,
 		&version.CreatedAt,
 	)
 
@@ -111,6 +125,9 @@ func (r *WorkflowVersionRepository) GetByVersion(ctx context.Context, workflowID
 	if err := json.Unmarshal(configJSON, &version.Config); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
+	if err := json.Unmarshal(pluginsJSON, &version.Plugins); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal plugins: %w", err)
+	}
 
 	return &version, nil
 }
@@ -118,7 +135,7 @@ func (r *WorkflowVersionRepository) GetByVersion(ctx context.Context, workflowID
 // List retrieves versions for a workflow with pagination
 func (r *WorkflowVersionRepository) List(ctx context.Context, workflowID string, limit, offset int) ([]*models.WorkflowVersion, error) {
 	query := `
-		SELECT id, workflow_id, version, config, change_reason, created_at
+		SELECT id, workflow_id, version, config, change_reason, plugins, created_at
 		FROM workflow_versions
 		WHERE workflow_id = $1
 		ORDER BY version DESC
@@ -134,7 +151,7 @@ func (r *WorkflowVersionRepository) List(ctx context.Context, workflowID string,
 	var versions []*models.WorkflowVersion
 	for rows.Next() {
 		var version models.WorkflowVersion
-		var configJSON []byte
+		var configJSON, pluginsJSON []byte
 
 		err := rows.Scan(
 			&version.ID,
@@ -142,6 +159,7 @@ func (r *WorkflowVersionRepository) List(ctx context.Context, workflowID string,
 			&version.Version,
 			&configJSON,
 			&version.ChangeReason,
+			&pluginsJSON,
 			&version.CreatedAt,
 		)
 		if err != nil {
@@ -151,6 +169,9 @@ func (r *WorkflowVersionRepository) List(ctx context.Context, workflowID string,
 		if err := json.Unmarshal(configJSON, &version.Config); err != nil {
 			return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 		}
+		if err := json.Unmarshal(pluginsJSON, &version.Plugins); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal plugins: %w", err)
+		}
 
 		versions = append(versions, &version)
 	}
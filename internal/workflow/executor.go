@@ -2,6 +2,8 @@ package workflow
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -9,22 +11,34 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"github.com/uzzalhcse/crawlify/internal/assets"
 	"github.com/uzzalhcse/crawlify/internal/browser"
+	"github.com/uzzalhcse/crawlify/internal/config"
+	"github.com/uzzalhcse/crawlify/internal/expression"
 	"github.com/uzzalhcse/crawlify/internal/extraction"
 	"github.com/uzzalhcse/crawlify/internal/logger"
 	"github.com/uzzalhcse/crawlify/internal/queue"
+	"github.com/uzzalhcse/crawlify/internal/schema"
+	"github.com/uzzalhcse/crawlify/internal/sitemap"
 	"github.com/uzzalhcse/crawlify/internal/storage"
 	"github.com/uzzalhcse/crawlify/internal/workflow/nodes"
+	"github.com/uzzalhcse/crawlify/internal/workflow/nodes/interaction"
 	"github.com/uzzalhcse/crawlify/pkg/models"
+	"github.com/uzzalhcse/crawlify/pkg/plugins"
 	"go.uber.org/zap"
 )
 
 // ErrURLRequeued is returned when a URL is requeued for later processing
 var ErrURLRequeued = errors.New("url requeued for later processing")
 
+// ErrURLDuplicate is returned when a page declares a canonical URL that is already queued
+var ErrURLDuplicate = errors.New("url is a duplicate of an already queued canonical url")
+
 type Executor struct {
 	browserPool         *browser.BrowserPool
 	urlQueue            *queue.URLQueue
@@ -36,6 +50,12 @@ type Executor struct {
 	eventBroadcaster    *EventBroadcaster
 	errorRecoverySystem interface{}                             // NEW: Error recovery system (using interface{} to avoid circular import)
 	recoveryHistoryRepo *storage.ErrorRecoveryHistoryRepository // NEW: For tracking recovery history
+	defaultMaxDepth     int                                     // Crawler-wide max depth used when a workflow sets none
+	scopes              sync.Map                                // executionID -> *ScopeEnforcer
+	assets              *assets.Downloader                      // Downloads referenced images and files; nil when not configured
+	lastRuns            sync.Map                                // executionID -> time.Time the previous completed execution started
+	httpClients         sync.Map                                // executionID -> *plugins.HTTPClient handed to plugins
+	pluginKV            plugins.KVBackend                       // Plugin key-value stores; nil when not configured
 }
 
 // ExecutionEvent represents a real-time event during workflow execution
@@ -99,13 +119,18 @@ func (eb *EventBroadcaster) Publish(event ExecutionEvent) {
 	eb.broadcast <- event
 }
 
-func NewExecutor(browserPool *browser.BrowserPool, urlQueue *queue.URLQueue, extractedItemsRepo *storage.ExtractedItemsRepository, nodeExecRepo *storage.NodeExecutionRepository, executionRepo *storage.ExecutionRepository, errorRecoverySystem interface{}, recoveryHistoryRepo *storage.ErrorRecoveryHistoryRepository) *Executor {
+func NewExecutor(browserPool *browser.BrowserPool, urlQueue *queue.URLQueue, extractedItemsRepo *storage.ExtractedItemsRepository, nodeExecRepo *storage.NodeExecutionRepository, executionRepo *storage.ExecutionRepository, errorRecoverySystem interface{}, recoveryHistoryRepo *storage.ErrorRecoveryHistoryRepository, crawlerConfig *config.CrawlerConfig, assetDownloader *assets.Downloader) *Executor {
 	// Create registry and register default nodes
 	registry := NewNodeRegistry()
 	if err := registry.RegisterDefaultNodes(); err != nil {
 		logger.Warn("Failed to register default nodes", zap.Error(err))
 	}
 
+	defaultMaxDepth := 0
+	if crawlerConfig != nil {
+		defaultMaxDepth = crawlerConfig.MaxDepth
+	}
+
 	return &Executor{
 		browserPool:         browserPool,
 		urlQueue:            urlQueue,
@@ -117,6 +142,8 @@ func NewExecutor(browserPool *browser.BrowserPool, urlQueue *queue.URLQueue, ext
 		eventBroadcaster:    NewEventBroadcaster(),
 		errorRecoverySystem: errorRecoverySystem,
 		recoveryHistoryRepo: recoveryHistoryRepo,
+		defaultMaxDepth:     defaultMaxDepth,
+		assets:              assetDownloader,
 	}
 }
 
@@ -125,6 +152,11 @@ func (e *Executor) GetEventBroadcaster() *EventBroadcaster {
 	return e.eventBroadcaster
 }
 
+// SetPluginKV sets the backend of the key-value stores given to plugins
+func (e *Executor) SetPluginKV(backend plugins.KVBackend) {
+	e.pluginKV = backend
+}
+
 // GetNodeRegistry returns the node registry instance
 func (e *Executor) GetNodeRegistry() *NodeRegistry {
 	return e.registry
@@ -169,13 +201,54 @@ func (e *Executor) ExecuteWorkflow(ctx context.Context, workflow *models.Workflo
 		LastUpdate:      time.Now(),
 	}
 
+	// Apply the workflow's URL canonicalisation and dedup settings
+	e.urlQueue.RegisterExecution(executionID, workflow.ID, workflow.Config.Dedup)
+	defer e.urlQueue.UnregisterExecution(executionID)
+
+	// Plugins resolved during the execution get OnExecutionEnd however it finishes
+	defer e.registry.EndPluginExecution(executionID)
+
+	// Enforce depth, domain, pattern and budget rules at enqueue time
+	scope, err := NewScopeEnforcer(&workflow.Config, e.defaultMaxDepth, startTime)
+	if err != nil {
+		if e.executionRepo != nil {
+			e.executionRepo.UpdateStatus(ctx, executionID, models.ExecutionStatusFailed, err.Error())
+		}
+		return fmt.Errorf("invalid workflow scope: %w", err)
+	}
+	if queueStats, err := e.urlQueue.GetStats(ctx, executionID); err == nil {
+		// Resumed executions already spent part of their URL budget
+		total := 0
+		for _, count := range queueStats {
+			total += count
+		}
+		scope.SetEnqueued(total)
+	}
+	e.scopes.Store(executionID, scope)
+	defer e.scopes.Delete(executionID)
+
+	// Plugins' HTTP requests go through the workflow's proxy with its headers and rate limit
+	httpClient, err := plugins.NewHTTPClient(plugins.HTTPPolicy{
+		Proxy:   workflow.Config.ProxyConfig,
+		Delay:   time.Duration(workflow.Config.RateLimitDelay) * time.Millisecond,
+		Headers: workflow.Config.Headers,
+	})
+	if err != nil {
+		if e.executionRepo != nil {
+			e.executionRepo.UpdateStatus(ctx, executionID, models.ExecutionStatusFailed, err.Error())
+		}
+		return fmt.Errorf("invalid workflow proxy: %w", err)
+	}
+	e.httpClients.Store(executionID, httpClient)
+	defer e.httpClients.Delete(executionID)
+
 	// Publish execution started event
 	e.PublishEvent(executionID, "execution_started", map[string]interface{}{
 		"workflow_id": workflow.ID,
 		"start_urls":  len(workflow.Config.StartURLs),
 	})
 
-	// Enqueue start URLs
+	// Enqueue start URLs; they bypass domain and pattern rules but not the URL budget
 	for _, startURL := range workflow.Config.StartURLs {
 		item := &models.URLQueueItem{
 			ExecutionID: executionID,
@@ -184,9 +257,9 @@ func (e *Executor) ExecuteWorkflow(ctx context.Context, workflow *models.Workflo
 			Priority:    100,
 			URLType:     "start", // Mark as start URL for proper phase detection
 		}
-		if err := e.urlQueue.Enqueue(ctx, item); err != nil {
+		if err := e.enqueueWithinBudget(ctx, scope, []*models.URLQueueItem{item}); err != nil {
 			logger.Error("Failed to enqueue start URL", zap.Error(err), zap.String("url", startURL))
-		} else {
+		} else if item.Status != models.QueueItemStatusSkipped {
 			stats.URLsDiscovered++
 			e.PublishEvent(executionID, "url_discovered", map[string]interface{}{
 				"url":  startURL,
@@ -195,6 +268,21 @@ func (e *Executor) ExecuteWorkflow(ctx context.Context, workflow *models.Workflo
 		}
 	}
 
+	// Incremental sitemap discovery only needs URLs modified since the previous completed run
+	if e.executionRepo != nil {
+		if lastRun, ok, err := e.executionRepo.GetLastCompletedStart(ctx, workflow.ID, executionID); err != nil {
+			logger.Warn("Failed to look up previous execution", zap.Error(err))
+		} else if ok {
+			e.lastRuns.Store(executionID, lastRun)
+			defer e.lastRuns.Delete(executionID)
+		}
+	}
+
+	// Expand sitemap and feed start sources
+	for i := range workflow.Config.StartSources {
+		stats.URLsDiscovered += e.enqueueStartSource(ctx, workflow, &workflow.Config.StartSources[i], executionID, scope)
+	}
+
 	// Update stats periodically
 	updateTicker := time.NewTicker(5 * time.Second)
 	defer updateTicker.Stop()
@@ -211,6 +299,7 @@ func (e *Executor) ExecuteWorkflow(ctx context.Context, workflow *models.Workflo
 			// Update execution stats
 			stats.Duration = time.Since(startTime).Milliseconds()
 			stats.LastUpdate = time.Now()
+			stats.URLsRejected, stats.RejectedReasons = scope.Rejections()
 
 			// Get node execution stats from database
 			if e.nodeExecRepo != nil {
@@ -227,6 +316,9 @@ func (e *Executor) ExecuteWorkflow(ctx context.Context, workflow *models.Workflo
 				if err == nil {
 					stats.ItemsExtracted = count
 				}
+				if invalid, byField, err := e.extractedItemsRepo.GetValidationStats(ctx, executionID); err == nil {
+					stats.ItemsInvalid, stats.InvalidFields = invalid, byField
+				}
 			}
 
 			if e.executionRepo != nil {
@@ -241,6 +333,13 @@ func (e *Executor) ExecuteWorkflow(ctx context.Context, workflow *models.Workflo
 			})
 
 		default:
+			// Stop picking up work once the time budget is spent; pending URLs stay resumable
+			if scope.TimeBudgetExceeded() {
+				logger.Info("Execution time budget exhausted", zap.String("execution_id", executionID))
+				e.completeExecution(ctx, executionID, &stats, startTime, scope, RejectReasonTimeBudget)
+				return nil
+			}
+
 			// Dequeue next URL
 			item, err := e.urlQueue.Dequeue(ctx, executionID)
 			if err != nil {
@@ -263,39 +362,7 @@ func (e *Executor) ExecuteWorkflow(ctx context.Context, workflow *models.Workflo
 				}
 
 				// No more URLs in queue - update final stats
-				stats.Duration = time.Since(startTime).Milliseconds()
-				stats.LastUpdate = time.Now()
-
-				// Get final node execution stats
-				if e.nodeExecRepo != nil {
-					nodeStats, err := e.nodeExecRepo.GetStatsByExecutionID(ctx, executionID)
-					if err == nil {
-						stats.NodesExecuted = nodeStats["completed"]
-						stats.NodesFailed = nodeStats["failed"]
-					}
-				}
-
-				// Get final extracted data count
-				if e.extractedItemsRepo != nil {
-					count, err := e.extractedItemsRepo.GetCount(ctx, executionID)
-					if err == nil {
-						stats.ItemsExtracted = count
-					}
-				}
-
-				if e.executionRepo != nil {
-					e.executionRepo.UpdateStats(ctx, executionID, stats)
-					e.executionRepo.UpdateStatus(ctx, executionID, models.ExecutionStatusCompleted, "")
-				}
-				logger.Info("No more URLs to process",
-					zap.Int("urls_processed", stats.URLsProcessed),
-					zap.Int("items_extracted", stats.ItemsExtracted),
-					zap.Int("nodes_executed", stats.NodesExecuted),
-				)
-
-				e.PublishEvent(executionID, "execution_completed", map[string]interface{}{
-					"stats": stats,
-				})
+				e.completeExecution(ctx, executionID, &stats, startTime, scope, "")
 				return nil
 			}
 
@@ -308,6 +375,15 @@ func (e *Executor) ExecuteWorkflow(ctx context.Context, workflow *models.Workflo
 					continue
 				}
 
+				// Canonical duplicates are skipped, not failed
+				if errors.Is(err, ErrURLDuplicate) {
+					logger.Info("Skipping duplicate canonical URL", zap.String("url", item.URL))
+					if skipErr := e.urlQueue.MarkSkipped(ctx, item.ID, err.Error()); skipErr != nil {
+						logger.Error("Failed to mark URL as skipped", zap.Error(skipErr))
+					}
+					continue
+				}
+
 				logger.Error("Failed to process URL",
 					zap.Error(err),
 					zap.String("url", item.URL),
@@ -321,11 +397,75 @@ func (e *Executor) ExecuteWorkflow(ctx context.Context, workflow *models.Workflo
 			if err := e.urlQueue.MarkCompleted(ctx, item.ID); err != nil {
 				logger.Error("Failed to mark URL as completed", zap.Error(err))
 			}
+			if err := e.urlQueue.RecordSeen(ctx, item); err != nil {
+				logger.Warn("Failed to record seen URL", zap.Error(err))
+			}
 			stats.URLsProcessed++
 		}
 	}
 }
 
+// completeExecution records final stats and marks the execution as completed, or as stopped
+// when stopReason names the budget that ran out
+func (e *Executor) completeExecution(ctx context.Context, executionID string, stats *models.ExecutionStats, startTime time.Time, scope *ScopeEnforcer, stopReason string) {
+	stats.Duration = time.Since(startTime).Milliseconds()
+	stats.LastUpdate = time.Now()
+	stats.URLsRejected, stats.RejectedReasons = scope.Rejections()
+
+	// Get final node execution stats
+	if e.nodeExecRepo != nil {
+		nodeStats, err := e.nodeExecRepo.GetStatsByExecutionID(ctx, executionID)
+		if err == nil {
+			stats.NodesExecuted = nodeStats["completed"]
+			stats.NodesFailed = nodeStats["failed"]
+		}
+	}
+
+	// Get final extracted data count
+	if e.extractedItemsRepo != nil {
+		count, err := e.extractedItemsRepo.GetCount(ctx, executionID)
+		if err == nil {
+			stats.ItemsExtracted = count
+		}
+		if invalid, byField, err := e.extractedItemsRepo.GetValidationStats(ctx, executionID); err == nil {
+			stats.ItemsInvalid, stats.InvalidFields = invalid, byField
+		}
+	}
+
+	if stopReason != "" {
+		if e.executionRepo != nil {
+			e.executionRepo.UpdateStats(ctx, executionID, *stats)
+			e.executionRepo.UpdateStatus(ctx, executionID, models.ExecutionStatusStopped, "budget exhausted: "+stopReason)
+		}
+		logger.Info("Execution stopped by its budget",
+			zap.String("reason", stopReason),
+			zap.Int("urls_processed", stats.URLsProcessed),
+			zap.Int("items_extracted", stats.ItemsExtracted),
+		)
+		e.PublishEvent(executionID, "execution_stopped", map[string]interface{}{
+			"reason": stopReason,
+			"stats":  *stats,
+		})
+		return
+	}
+
+	if e.executionRepo != nil {
+		e.executionRepo.UpdateStats(ctx, executionID, *stats)
+		e.executionRepo.UpdateStatus(ctx, executionID, models.ExecutionStatusCompleted, "")
+	}
+	logger.Info("No more URLs to process",
+		zap.Int("urls_processed", stats.URLsProcessed),
+		zap.Int("urls_rejected", stats.URLsRejected),
+		zap.Int("items_extracted", stats.ItemsExtracted),
+		zap.Int("items_invalid", stats.ItemsInvalid),
+		zap.Int("nodes_executed", stats.NodesExecuted),
+	)
+
+	e.PublishEvent(executionID, "execution_completed", map[string]interface{}{
+		"stats": *stats,
+	})
+}
+
 // processURL processes a single URL using phase-based workflow
 func (e *Executor) processURL(ctx context.Context, workflow *models.Workflow, executionID string, item *models.URLQueueItem) error {
 	logger.Info("Processing URL",
@@ -378,6 +518,11 @@ func (e *Executor) processURL(ctx context.Context, workflow *models.Workflow, ex
 		browserCtx.SetHeaders(workflow.Config.Headers)
 	}
 
+	// Intercept rules must be registered before navigation to see the page's API calls
+	if err := e.startNetworkIntercept(phaseToExecute.Nodes, browserCtx); err != nil {
+		return err
+	}
+
 	// BACKWARD COMPATIBILITY: Auto-navigate if no navigate node exists in this phase
 	// Check if phase has a navigate node
 	hasNavigateNode := false
@@ -452,6 +597,10 @@ func (e *Executor) processURL(ctx context.Context, workflow *models.Workflow, ex
 		} else {
 			logger.Info("✅ Auto-navigation completed successfully", zap.String("url", item.URL))
 		}
+
+		if err := e.checkCanonicalDuplicate(ctx, workflow, browserCtx, item); err != nil {
+			return err
+		}
 	} else {
 		logger.Debug("Navigate node found in phase, skipping auto-navigation")
 	}
@@ -466,6 +615,9 @@ func (e *Executor) processURL(ctx context.Context, workflow *models.Workflow, ex
 	execCtx.Set("_url", item.URL)
 	execCtx.Set("_depth", item.Depth)
 	execCtx.Set("_phase_id", phaseToExecute.ID)
+	if lastRun, ok := e.lastRuns.Load(item.ExecutionID); ok {
+		execCtx.Set("_last_run_at", lastRun)
+	}
 
 	// Execute phase nodes
 	logger.Info("Executing phase nodes",
@@ -481,6 +633,11 @@ func (e *Executor) processURL(ctx context.Context, workflow *models.Workflow, ex
 	})
 
 	if err := e.executeNodeGroup(ctx, workflow, phaseToExecute.Nodes, browserCtx, &execCtx, executionID, item); err != nil {
+		// A navigate node found the page to be a canonical duplicate - nothing to save
+		if errors.Is(err, ErrURLDuplicate) {
+			return err
+		}
+
 		logger.Error("Phase execution failed",
 			zap.String("phase_id", phaseToExecute.ID),
 			zap.Error(err))
@@ -511,7 +668,8 @@ func (e *Executor) processURL(ctx context.Context, workflow *models.Workflow, ex
 					nodeExecIDPtr = &nodeExecIDStr
 				}
 			}
-			if err := e.saveExtractedData(ctx, executionID, item.ID, schemaName, nodeExecIDPtr, extractedData); err != nil {
+			itemSchema := schema.ForPhase(&workflow.Config, phaseToExecute)
+			if err := e.saveExtractedData(ctx, executionID, item, browserCtx, schemaName, nil, itemSchema, nodeExecIDPtr, extractedData); err != nil {
 				logger.Error("Failed to save extracted data", zap.Error(err))
 			} else {
 				logger.Info("Saved extracted data",
@@ -519,6 +677,9 @@ func (e *Executor) processURL(ctx context.Context, workflow *models.Workflow, ex
 					zap.Int("fields", len(extractedData)))
 			}
 		}
+		if emitted := execCtx.EmittedItems(); len(emitted) > 0 {
+			e.saveEmittedItems(ctx, executionID, item, browserCtx, schema.ForPhase(&workflow.Config, phaseToExecute), phaseToExecute.ID, emitted)
+		}
 	}
 
 	// Check for phase transition
@@ -531,6 +692,33 @@ func (e *Executor) processURL(ctx context.Context, workflow *models.Workflow, ex
 	return nil
 }
 
+// checkCanonicalDuplicate honours <link rel=canonical> when the workflow enables it
+// Returns ErrURLDuplicate if the canonical URL is already owned by another queue item
+func (e *Executor) checkCanonicalDuplicate(ctx context.Context, workflow *models.Workflow, browserCtx *browser.BrowserContext, item *models.URLQueueItem) error {
+	if workflow.Config.Dedup == nil || !workflow.Config.Dedup.HonorCanonical {
+		return nil
+	}
+
+	canonicalURL, err := browserCtx.CanonicalURL()
+	if err != nil || canonicalURL == "" {
+		return nil
+	}
+
+	duplicate, err := e.urlQueue.ResolveCanonical(ctx, item, canonicalURL)
+	if err != nil {
+		logger.Warn("Failed to resolve canonical URL", zap.String("url", item.URL), zap.Error(err))
+		return nil
+	}
+	if duplicate {
+		logger.Debug("Canonical URL already queued",
+			zap.String("url", item.URL),
+			zap.String("canonical_url", canonicalURL))
+		return ErrURLDuplicate
+	}
+
+	return nil
+}
+
 // urlMatchesPhase checks if a URL should be processed in a given phase
 func (e *Executor) urlMatchesPhase(item *models.URLQueueItem, phase *models.WorkflowPhase) bool {
 	logger.Debug("Checking phase match",
@@ -593,10 +781,33 @@ func (e *Executor) urlMatchesPhase(item *models.URLQueueItem, phase *models.Work
 		}
 	}
 
+	// Check expression
+	if filter.Expression != "" {
+		matched, err := evalExpressionCondition(filter.Expression, expression.NewEnv(nil, nil, item))
+		if err != nil {
+			logger.Warn("Phase URL filter expression failed",
+				zap.String("phase_id", phase.ID),
+				zap.String("url", item.URL),
+				zap.Error(err))
+		} else if matched {
+			logger.Debug("Expression matched!", zap.String("expression", filter.Expression))
+			return true
+		}
+	}
+
 	logger.Debug("No match for phase", zap.String("phase_id", phase.ID))
 	return false
 }
 
+// evalExpressionCondition evaluates an expression with JavaScript truthiness
+func evalExpressionCondition(source string, env expression.Env) (bool, error) {
+	program, err := expression.Compile(source)
+	if err != nil {
+		return false, err
+	}
+	return program.EvalBool(env)
+}
+
 // handlePhaseTransition handles transitioning discovered URLs to the next phase
 func (e *Executor) handlePhaseTransition(ctx context.Context, workflow *models.Workflow, currentPhase *models.WorkflowPhase, item *models.URLQueueItem, executionID string, execCtx *models.ExecutionContext) error {
 	transition := currentPhase.Transition
@@ -613,6 +824,16 @@ func (e *Executor) handlePhaseTransition(ctx context.Context, workflow *models.W
 			// This would need tracking - simplified for now
 			shouldTransition = true
 		}
+	case "expression":
+		// Sandboxed expression over extracted data (vars) and the processed URL
+		source, _ := transition.Params["expression"].(string)
+		matched, err := evalExpressionCondition(source, expression.NewEnv(nil, execCtx, item))
+		if err != nil {
+			logger.Warn("Transition expression failed",
+				zap.String("phase_id", currentPhase.ID),
+				zap.Error(err))
+		}
+		shouldTransition = matched
 	default:
 		logger.Warn("Unknown transition condition", zap.String("condition", transition.Condition))
 	}
@@ -769,8 +990,11 @@ func (e *Executor) executeNode(ctx context.Context, workflow *models.Workflow, n
 					zap.String("node_type", string(node.Type)),
 				)
 
-				// Resolve template variables in params before execution
-				resolvedParams := e.resolveTemplateVariables(node.Params, item.URL, workflow.Config.StartURLs)
+				// Resolve template expressions in params before execution
+				resolvedParams, resolveErr := e.resolveTemplateVariables(node, item, execCtx, workflow.Config.StartURLs)
+
+				// Links streamed by the node while it runs are counted with its final output
+				streamedURLs := 0
 
 				// Prepare input for plugin execution
 				input := &nodes.ExecutionInput{
@@ -779,6 +1003,18 @@ func (e *Executor) executeNode(ctx context.Context, workflow *models.Workflow, n
 					Params:           resolvedParams,
 					URLItem:          item,
 					ExecutionID:      executionID,
+					NodeID:           node.ID,
+					Assets:           e.assets,
+					WorkflowID:       workflow.ID,
+					HTTP:             e.pluginHTTPClient(executionID),
+					PluginKV:         e.pluginKV,
+					EnqueueURLs: func(urls []string) (int, error) {
+						enqueued, enqErr := e.enqueueDiscoveredURLs(ctx, executionID, item, nodes.DiscoveredLinks(urls), node, nodeExecID, execCtx)
+						if enqErr == nil {
+							streamedURLs += len(urls)
+						}
+						return enqueued, enqErr
+					},
 				}
 
 				// Execute using plugin
@@ -787,7 +1023,11 @@ func (e *Executor) executeNode(ctx context.Context, workflow *models.Workflow, n
 					zap.String("node_type", string(node.Type)),
 				)
 
-				output, execErr := executor.Execute(ctx, input)
+				var output *nodes.ExecutionOutput
+				execErr := resolveErr
+				if execErr == nil {
+					output, execErr = executor.Execute(ctx, input)
+				}
 				if execErr != nil {
 					err = execErr
 					logger.Error("Node execution failed",
@@ -861,32 +1101,22 @@ func (e *Executor) executeNode(ctx context.Context, workflow *models.Workflow, n
 							logger.Debug("✅ HTTP status OK after navigate",
 								zap.String("node_id", node.ID))
 						}
+
+						if dupErr := e.checkCanonicalDuplicate(ctx, workflow, browserCtx, item); dupErr != nil {
+							return dupErr
+						}
 					}
 
 					// Handle discovered URLs
-					if len(output.DiscoveredURLs) > 0 {
-						if enqueuedIDs, enqErr := e.enqueueLinks(ctx, executionID, item, output.DiscoveredURLs, node.Params, node.ID, nodeExecID); enqErr != nil {
+					discovered := append(nodes.DiscoveredLinks(output.DiscoveredURLs), output.DiscoveredItems...)
+					if len(discovered) > 0 || streamedURLs > 0 {
+						if _, enqErr := e.enqueueDiscoveredURLs(ctx, executionID, item, discovered, node, nodeExecID, execCtx); enqErr != nil {
 							logger.Error("Failed to enqueue links", zap.Error(enqErr))
-						} else {
-							// Track discovered item IDs in context for phase transition
-							if len(enqueuedIDs) > 0 {
-								existingIDs, _ := execCtx.Get("_discovered_item_ids")
-								var ids []string
-								if existingIDs != nil {
-									if casted, ok := existingIDs.([]string); ok {
-										ids = casted
-									}
-								}
-								ids = append(ids, enqueuedIDs...)
-								execCtx.Set("_discovered_item_ids", ids)
-							}
-
-							if e.nodeExecRepo != nil && nodeExecID != "" {
-								// Update node execution with URLs discovered count
-								if nodeExec, getErr := e.nodeExecRepo.GetByID(ctx, nodeExecID); getErr == nil {
-									nodeExec.URLsDiscovered = len(output.DiscoveredURLs)
-									e.nodeExecRepo.Update(ctx, nodeExec)
-								}
+						} else if e.nodeExecRepo != nil && nodeExecID != "" {
+							// Update node execution with URLs discovered count
+							if nodeExec, getErr := e.nodeExecRepo.GetByID(ctx, nodeExecID); getErr == nil {
+								nodeExec.URLsDiscovered = streamedURLs + len(discovered)
+								e.nodeExecRepo.Update(ctx, nodeExec)
 							}
 						}
 					}
@@ -970,14 +1200,100 @@ func (e *Executor) executeNode(ctx context.Context, workflow *models.Workflow, n
 	return err
 }
 
+// enqueueStartSource reads a sitemap or feed start source and enqueues the URLs it lists as start URLs
+// Unlike configured start URLs, they are subject to the workflow scope
+func (e *Executor) enqueueStartSource(ctx context.Context, workflow *models.Workflow, source *models.StartSource, executionID string, scope *ScopeEnforcer) int {
+	include, err := sitemap.CompilePatterns(source.Include)
+	if err != nil {
+		logger.Error("Invalid start source include pattern", zap.Error(err))
+		return 0
+	}
+	exclude, err := sitemap.CompilePatterns(source.Exclude)
+	if err != nil {
+		logger.Error("Invalid start source exclude pattern", zap.Error(err))
+		return 0
+	}
+	opts := sitemap.Options{Include: include, Exclude: exclude, MaxURLs: source.MaxURLs}
+	if source.SinceLastRun {
+		if lastRun, ok := e.lastRuns.Load(executionID); ok {
+			opts.Since = lastRun.(time.Time)
+		}
+	}
+
+	result, err := sitemap.Discover(ctx, sitemap.HTTPFetcher(workflow.Config.Headers, 0), source.URLs, opts)
+	if err != nil {
+		logger.Error("Failed to read start source", zap.Strings("urls", source.URLs), zap.Error(err))
+		return 0
+	}
+	for _, sourceErr := range result.Errors {
+		logger.Warn("Start source partially unreadable", zap.String("error", sourceErr))
+	}
+
+	marker := source.Marker
+	if marker == "" {
+		marker = "start"
+	}
+
+	items := make([]*models.URLQueueItem, 0, len(result.Entries))
+	for _, entry := range result.Entries {
+		if reason := scope.Admit(entry.URL, 0); reason != "" {
+			continue
+		}
+		item := &models.URLQueueItem{
+			ExecutionID: executionID,
+			URL:         entry.URL,
+			Depth:       0,
+			Priority:    100,
+			URLType:     marker,
+			Marker:      marker,
+		}
+		if metadataJSON, err := json.Marshal(entry.Metadata()); err == nil {
+			item.Metadata = string(metadataJSON)
+		}
+		items = append(items, item)
+	}
+	if err := e.enqueueWithinBudget(ctx, scope, items); err != nil {
+		logger.Error("Failed to enqueue start source URLs", zap.Error(err))
+		return 0
+	}
+
+	enqueued := 0
+	for _, item := range items {
+		if item.Status != models.QueueItemStatusSkipped {
+			enqueued++
+		}
+	}
+
+	logger.Info("Expanded start source",
+		zap.String("type", source.Type),
+		zap.Int("sitemaps_read", result.Sitemaps),
+		zap.Int("urls_listed", len(result.Entries)),
+		zap.Int("urls_filtered", result.Filtered),
+		zap.Int("urls_enqueued", enqueued))
+	e.PublishEvent(executionID, "url_discovered", map[string]interface{}{
+		"type":   source.Type,
+		"count":  enqueued,
+		"marker": marker,
+	})
+	return enqueued
+}
+
 // enqueueLinks enqueues discovered links with hierarchy tracking
-func (e *Executor) enqueueLinks(ctx context.Context, executionID string, parentItem *models.URLQueueItem, links []string, params map[string]interface{}, nodeID, nodeExecID string) ([]string, error) {
+// It returns the IDs of enqueued links that take part in the phase transition (same-phase
+// links such as generated pagination pages stay in phaseID) and the total number enqueued
+func (e *Executor) enqueueLinks(ctx context.Context, executionID string, parentItem *models.URLQueueItem, links []nodes.DiscoveredURL, params map[string]interface{}, nodeID, nodeExecID, phaseID string) ([]string, int, error) {
 	baseURL, err := url.Parse(parentItem.URL)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	var items []*models.URLQueueItem
+	samePhase := make(map[*models.URLQueueItem]bool)
+
+	var scope *ScopeEnforcer
+	if loaded, ok := e.scopes.Load(executionID); ok {
+		scope = loaded.(*ScopeEnforcer)
+	}
 
 	// Get marker from params (for phase-based routing)
 	marker := getStringParam(params, "marker")
@@ -994,7 +1310,7 @@ func (e *Executor) enqueueLinks(ctx context.Context, executionID string, parentI
 
 	for _, link := range links {
 		// Resolve relative URLs
-		linkURL, err := url.Parse(link)
+		linkURL, err := url.Parse(link.URL)
 		if err != nil {
 			continue
 		}
@@ -1006,39 +1322,134 @@ func (e *Executor) enqueueLinks(ctx context.Context, executionID string, parentI
 			continue
 		}
 
+		// Same-phase links are siblings of the current page (e.g. page 2 of a listing)
+		depth := parentItem.Depth + 1
+		itemMarker, itemPhaseID := marker, ""
+		if link.SamePhase {
+			depth = parentItem.Depth
+			itemMarker, itemPhaseID = parentItem.Marker, phaseID
+		}
+		if link.Marker != "" {
+			itemMarker = link.Marker
+		}
+
+		// Enforce workflow scope (depth, domains, patterns, budgets)
+		if scope != nil {
+			if reason := scope.Admit(absoluteURL, depth); reason != "" {
+				logger.Debug("URL rejected by scope",
+					zap.String("url", absoluteURL),
+					zap.String("reason", reason))
+				continue
+			}
+		}
+
 		item := &models.URLQueueItem{
 			ExecutionID:           executionID,
 			URL:                   absoluteURL,
-			Depth:                 parentItem.Depth + 1,
+			Depth:                 depth,
 			Priority:              parentItem.Priority - 10,
 			ParentURLID:           &parentItem.ID,
 			DiscoveredByNode:      &nodeID,
 			ParentNodeExecutionID: &nodeExecID, // NEW: Track the node execution that discovered this URL
-			URLType:               marker,      // For backward compatibility
-			Marker:                marker,      // NEW: Set marker for phase matching
-			PhaseID:               "",          // Will be set by phase transition logic
+			URLType:               itemMarker,  // For backward compatibility
+			Marker:                itemMarker,  // NEW: Set marker for phase matching
+			PhaseID:               itemPhaseID, // Set by phase transition logic unless same-phase
+		}
+		if len(link.Metadata) > 0 {
+			if metadataJSON, err := json.Marshal(link.Metadata); err == nil {
+				item.Metadata = string(metadataJSON)
+			}
 		}
 
 		logger.Debug("Enqueued URL",
 			zap.String("url", absoluteURL),
-			zap.String("marker", marker),
-			zap.Int("depth", parentItem.Depth+1))
+			zap.String("marker", itemMarker),
+			zap.Int("depth", depth))
 
 		items = append(items, item)
+		samePhase[item] = link.SamePhase
 	}
 
 	// Enqueue batch and get IDs
-	if err := e.urlQueue.EnqueueBatch(ctx, items); err != nil {
-		return nil, err
+	if err := e.enqueueWithinBudget(ctx, scope, items); err != nil {
+		return nil, 0, err
 	}
 
-	// Collect IDs of enqueued items
-	var enqueuedIDs []string
+	// Collect IDs of enqueued items (skipped items were never inserted)
+	var transitionIDs []string
+	enqueued := 0
 	for _, item := range items {
-		enqueuedIDs = append(enqueuedIDs, item.ID)
+		if item.Status == models.QueueItemStatusSkipped {
+			continue
+		}
+		enqueued++
+		if !samePhase[item] {
+			transitionIDs = append(transitionIDs, item.ID)
+		}
 	}
 
-	return enqueuedIDs, nil
+	return transitionIDs, enqueued, nil
+}
+
+// enqueueWithinBudget enqueues items while the execution's URL budget lasts, charging it only
+// for URLs the queue actually inserted. Items left over when the budget runs out are marked
+// skipped like the ones the queue drops.
+func (e *Executor) enqueueWithinBudget(ctx context.Context, scope *ScopeEnforcer, items []*models.URLQueueItem) error {
+	if scope == nil {
+		return e.urlQueue.EnqueueBatch(ctx, items)
+	}
+
+	for pending := items; len(pending) > 0; {
+		granted := scope.Reserve(len(pending))
+		if granted == 0 {
+			scope.RejectOverBudget(len(pending))
+			for _, item := range pending {
+				item.Status = models.QueueItemStatusSkipped
+			}
+			return nil
+		}
+
+		chunk := pending[:granted]
+		pending = pending[granted:]
+		if err := e.urlQueue.EnqueueBatch(ctx, chunk); err != nil {
+			scope.Release(granted)
+			return err
+		}
+
+		unused := 0
+		for _, item := range chunk {
+			if item.Status == models.QueueItemStatusSkipped {
+				unused++
+			}
+		}
+		scope.Release(unused)
+	}
+	return nil
+}
+
+// enqueueDiscoveredURLs enqueues links found by a node and tracks their queue IDs in the
+// context for the phase transition
+func (e *Executor) enqueueDiscoveredURLs(ctx context.Context, executionID string, item *models.URLQueueItem, urls []nodes.DiscoveredURL, node *models.Node, nodeExecID string, execCtx *models.ExecutionContext) (int, error) {
+	if len(urls) == 0 {
+		return 0, nil
+	}
+	phaseID, _ := execCtx.Get("_phase_id")
+	phaseIDStr, _ := phaseID.(string)
+	enqueuedIDs, enqueued, err := e.enqueueLinks(ctx, executionID, item, urls, node.Params, node.ID, nodeExecID, phaseIDStr)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(enqueuedIDs) > 0 {
+		var ids []string
+		if existingIDs, ok := execCtx.Get("_discovered_item_ids"); ok {
+			if casted, ok := existingIDs.([]string); ok {
+				ids = casted
+			}
+		}
+		execCtx.Set("_discovered_item_ids", append(ids, enqueuedIDs...))
+	}
+	return enqueued, nil
 }
 
 // Helper functions
@@ -1105,6 +1516,14 @@ func containsString(s, substr string) bool {
 				containsString(s[1:], substr))))
 }
 
+// pluginHTTPClient returns the HTTP client of a running execution
+func (e *Executor) pluginHTTPClient(executionID string) *plugins.HTTPClient {
+	if client, ok := e.httpClients.Load(executionID); ok {
+		return client.(*plugins.HTTPClient)
+	}
+	return nil
+}
+
 // collectExtractedData collects all extracted data from execution context
 // Only data set by extract nodes should be saved - all other node outputs are metadata
 func (e *Executor) collectExtractedData(execCtx *models.ExecutionContext) map[string]interface{} {
@@ -1145,7 +1564,15 @@ func (e *Executor) collectExtractedData(execCtx *models.ExecutionContext) map[st
 }
 
 // saveExtractedData saves extracted data to storage
-func (e *Executor) saveExtractedData(ctx context.Context, executionID, urlID, schemaName string, nodeExecID *string, result interface{}) error {
+// When an output schema is set, values are coerced to it and validation errors are stored with the item
+// itemKey is nil for the page's own item and set for items emitted from it
+func (e *Executor) saveExtractedData(ctx context.Context, executionID string, urlItem *models.URLQueueItem, browserCtx *browser.BrowserContext, schemaName string, itemKey *string, itemSchema *models.ItemSchema, nodeExecID *string, result map[string]interface{}) error {
+	var validationErrors []models.FieldValidationError
+	if itemSchema != nil {
+		result, validationErrors = schema.Apply(itemSchema, result, urlItem.URL)
+		validationErrors = append(validationErrors, e.downloadSchemaAssets(ctx, browserCtx, itemSchema, result)...)
+	}
+
 	// Marshal entire result to JSON
 	dataJSON, err := json.Marshal(result)
 	if err != nil {
@@ -1153,73 +1580,144 @@ func (e *Executor) saveExtractedData(ctx context.Context, executionID, urlID, sc
 	}
 
 	item := &models.ExtractedItem{
-		ID:              uuid.New().String(),
-		ExecutionID:     executionID,
-		URLID:           urlID,
-		NodeExecutionID: nodeExecID,
-		SchemaName:      &schemaName,
-		Data:            string(dataJSON),
-		ExtractedAt:     time.Now(),
+		ID:               uuid.New().String(),
+		ExecutionID:      executionID,
+		URLID:            urlItem.ID,
+		NodeExecutionID:  nodeExecID,
+		SchemaName:       &schemaName,
+		ItemKey:          itemKey,
+		Data:             string(dataJSON),
+		ExtractedAt:      time.Now(),
+		ValidationErrors: validationErrors,
+	}
+
+	if len(validationErrors) > 0 {
+		logger.Warn("Extracted item failed schema validation",
+			zap.String("url", urlItem.URL),
+			zap.Int("errors", len(validationErrors)))
 	}
 
 	e.PublishEvent(executionID, "item_extracted", map[string]interface{}{
-		"item_id": item.ID,
-		"data":    result,
+		"item_id":           item.ID,
+		"schema_name":       schemaName,
+		"item_key":          itemKey,
+		"data":              result,
+		"validation_errors": validationErrors,
 	})
 
 	return e.extractedItemsRepo.Create(ctx, item)
 }
 
-// Helper functions for parsing extracted data
-func parsePrice(s string) (float64, error) {
-	// Remove currency symbols and whitespace
-	s = strings.TrimSpace(s)
-	s = strings.ReplaceAll(s, "$", "")
-	s = strings.ReplaceAll(s, "€", "")
-	s = strings.ReplaceAll(s, "£", "")
-	s = strings.ReplaceAll(s, "¥", "")
-	s = strings.ReplaceAll(s, ",", "")
-	s = strings.TrimSpace(s)
+// maxItemKeyLength is the length of the extracted_items.item_key column
+const maxItemKeyLength = 255
 
-	if s == "" {
-		return 0, fmt.Errorf("empty price")
+// storedItemKey returns the key an emitted item is saved under: "#<position>" when it has none,
+// and a hash of keys too long for the column
+func storedItemKey(key string, position int) string {
+	if key == "" {
+		return "#" + strconv.Itoa(position)
 	}
-
-	return strconv.ParseFloat(s, 64)
+	if utf8.RuneCountInString(key) > maxItemKeyLength {
+		sum := sha256.Sum256([]byte(key))
+		return "sha256:" + hex.EncodeToString(sum[:])
+	}
+	return key
 }
 
-func parseRating(s string) (float64, error) {
-	// Extract numeric rating from strings like "4.5 out of 5 stars"
-	s = strings.TrimSpace(s)
+// saveEmittedItems saves each item emitted while processing a page as an extracted item of its own
+// Items of the phase's schema get its output schema; keyless items are keyed by their position in their schema
+// as "#<n>", so that they never take the key of an item with an explicit one
+func (e *Executor) saveEmittedItems(ctx context.Context, executionID string, urlItem *models.URLQueueItem, browserCtx *browser.BrowserContext, phaseSchema *models.ItemSchema, phaseID string, emitted []models.EmittedItem) {
+	positions := make(map[string]int)
+	perNode := make(map[string]int)
+	saved := 0
+	for _, emittedItem := range emitted {
+		schemaName := emittedItem.SchemaName
+		if schemaName == "" {
+			schemaName = phaseID
+		}
+		positions[schemaName]++
+		itemKey := storedItemKey(emittedItem.Key, positions[schemaName])
+
+		data := make(map[string]interface{}, len(emittedItem.Data)+1)
+		for k, v := range emittedItem.Data {
+			data[k] = v
+		}
+		if _, exists := data["source_url"]; !exists {
+			data["source_url"] = urlItem.URL
+		}
+
+		var itemSchema *models.ItemSchema
+		if schemaName == phaseID {
+			itemSchema = phaseSchema
+		}
+		var nodeExecIDPtr *string
+		if emittedItem.NodeExecutionID != "" {
+			nodeExecID := emittedItem.NodeExecutionID
+			nodeExecIDPtr = &nodeExecID
+		}
 
-	// Try direct parsing first
-	if r, err := strconv.ParseFloat(s, 64); err == nil {
-		return r, nil
+		if err := e.saveExtractedData(ctx, executionID, urlItem, browserCtx, schemaName, &itemKey, itemSchema, nodeExecIDPtr, data); err != nil {
+			logger.Error("Failed to save emitted item",
+				zap.String("url", urlItem.URL),
+				zap.String("schema", schemaName),
+				zap.String("key", itemKey),
+				zap.Error(err))
+			continue
+		}
+		saved++
+		if nodeExecIDPtr != nil {
+			perNode[*nodeExecIDPtr]++
+		}
 	}
 
-	// Try to extract first number
-	re := regexp.MustCompile(`(\d+\.?\d*)`)
-	matches := re.FindStringSubmatch(s)
-	if len(matches) > 1 {
-		return strconv.ParseFloat(matches[1], 64)
+	// Credit the items to the node executions that emitted them
+	if e.nodeExecRepo != nil {
+		for nodeExecID, count := range perNode {
+			if nodeExec, err := e.nodeExecRepo.GetByID(ctx, nodeExecID); err == nil {
+				nodeExec.ItemsExtracted += count
+				e.nodeExecRepo.Update(ctx, nodeExec)
+			}
+		}
 	}
 
-	return 0, fmt.Errorf("could not parse rating")
+	logger.Info("Saved emitted items",
+		zap.String("url", urlItem.URL),
+		zap.Int("items", saved),
+		zap.Int("emitted", len(emitted)))
 }
 
-func parseInt(s string) (int, error) {
-	// Remove commas and whitespace
-	s = strings.TrimSpace(s)
-	s = strings.ReplaceAll(s, ",", "")
+// downloadSchemaAssets stores the files referenced by schema fields marked download,
+// attaching their metadata as <field>_asset; failed downloads are reported as validation errors
+func (e *Executor) downloadSchemaAssets(ctx context.Context, browserCtx *browser.BrowserContext, itemSchema *models.ItemSchema, result map[string]interface{}) []models.FieldValidationError {
+	var validationErrors []models.FieldValidationError
+	for name, field := range itemSchema.Fields {
+		value, ok := result[name]
+		if !field.Download || !ok || value == nil {
+			continue
+		}
+		if e.assets == nil {
+			validationErrors = append(validationErrors, models.FieldValidationError{
+				Field:   name,
+				Code:    schema.CodeDownload,
+				Message: "asset downloads are not configured",
+			})
+			continue
+		}
 
-	// Extract first number
-	re := regexp.MustCompile(`(\d+)`)
-	matches := re.FindStringSubmatch(s)
-	if len(matches) > 1 {
-		return strconv.Atoi(matches[1])
+		downloaded, errs := e.assets.DownloadValue(ctx, browserCtx, value, assets.Options{})
+		if downloaded != nil {
+			result[name+"_asset"] = downloaded
+		}
+		for _, err := range errs {
+			validationErrors = append(validationErrors, models.FieldValidationError{
+				Field:   name,
+				Code:    schema.CodeDownload,
+				Message: err.Error(),
+			})
+		}
 	}
-
-	return strconv.Atoi(s)
+	return validationErrors
 }
 
 // executePagination handles pagination logic - both click-based and link-based
@@ -1237,6 +1735,11 @@ func (e *Executor) executePagination(ctx context.Context, node *models.Node, bro
 		return nil, fmt.Errorf("pagination requires either next_selector or link_selector")
 	}
 
+	// Selector types: <key>_type overrides the node-wide selector_type
+	nextSelectorType := paginationSelectorType(node.Params, "next_selector")
+	linkSelectorType := paginationSelectorType(node.Params, "link_selector")
+	itemSelectorType := paginationSelectorType(node.Params, "item_selector")
+
 	// Default values
 	if maxPages == 0 {
 		maxPages = 100 // Default max pages to prevent infinite loops
@@ -1263,7 +1766,7 @@ func (e *Executor) executePagination(ctx context.Context, node *models.Node, bro
 
 		// Extract items/links from current page if item_selector is provided
 		if itemSelector != "" {
-			links, err := extractionEngine.ExtractLinks(itemSelector, 0)
+			links, err := extractionEngine.ExtractLinksWithType(itemSelector, itemSelectorType, 0)
 			if err == nil && len(links) > 0 {
 				// Resolve relative URLs
 				baseURL, _ := url.Parse(item.URL)
@@ -1291,7 +1794,7 @@ func (e *Executor) executePagination(ctx context.Context, node *models.Node, bro
 
 		// Strategy 1: Try next button/link (click-based or href-based)
 		if nextSelector != "" {
-			nextNavigated, err := e.tryNavigateNext(browserCtx, nextSelector, paginationType, waitAfterClick)
+			nextNavigated, err := e.tryNavigateNext(browserCtx, extraction.PlaywrightSelector(nextSelector, nextSelectorType), paginationType, waitAfterClick)
 			if err != nil {
 				logger.Debug("Next navigation failed", zap.Error(err))
 			} else if nextNavigated {
@@ -1302,7 +1805,7 @@ func (e *Executor) executePagination(ctx context.Context, node *models.Node, bro
 
 		// Strategy 2: Try pagination links if next button didn't work
 		if !navigated && paginationSelector != "" {
-			linkNavigated, err := e.tryNavigatePaginationLink(browserCtx, paginationSelector, currentPage+1, paginationType, waitAfterClick)
+			linkNavigated, err := e.tryNavigatePaginationLink(browserCtx, extraction.PlaywrightSelector(paginationSelector, linkSelectorType), currentPage+1, paginationType, waitAfterClick)
 			if err != nil {
 				logger.Debug("Pagination link navigation failed", zap.Error(err))
 			} else if linkNavigated {
@@ -1326,7 +1829,7 @@ func (e *Executor) executePagination(ctx context.Context, node *models.Node, bro
 
 	// Enqueue all discovered links if we have them
 	if len(allLinks) > 0 {
-		if _, err := e.enqueueLinks(ctx, executionID, item, allLinks, node.Params, node.ID, ""); err != nil {
+		if _, _, err := e.enqueueLinks(ctx, executionID, item, nodes.DiscoveredLinks(allLinks), node.Params, node.ID, "", item.PhaseID); err != nil {
 			logger.Error("Failed to enqueue paginated links", zap.Error(err))
 			return nil, err
 		}
@@ -1339,6 +1842,57 @@ func (e *Executor) executePagination(ctx context.Context, node *models.Node, bro
 	}, nil
 }
 
+// startNetworkIntercept registers the phase's intercept nodes on the page before navigation
+// Rules from several intercept nodes are merged into one interceptor
+func (e *Executor) startNetworkIntercept(phaseNodes []models.Node, browserCtx *browser.BrowserContext) error {
+	var merged browser.InterceptConfig
+	found := false
+	ruleNodes := make(map[string]string) // Rule name -> intercept node that declares it
+	for _, node := range phaseNodes {
+		if node.Type != models.NodeTypeIntercept {
+			continue
+		}
+		cfg, err := interaction.ParseInterceptConfig(node.ID, node.Params)
+		if err != nil {
+			return fmt.Errorf("intercept node '%s': %w", node.ID, err)
+		}
+		for _, rule := range cfg.Rules {
+			if other, ok := ruleNodes[rule.Name]; ok {
+				return fmt.Errorf("intercept rule name '%s' is used by nodes '%s' and '%s'", rule.Name, other, node.ID)
+			}
+			ruleNodes[rule.Name] = node.ID
+		}
+		found = true
+		merged.Rules = append(merged.Rules, cfg.Rules...)
+		merged.BlockResourceTypes = append(merged.BlockResourceTypes, cfg.BlockResourceTypes...)
+		merged.BlockDomains = append(merged.BlockDomains, cfg.BlockDomains...)
+		merged.BlockTrackers = merged.BlockTrackers || cfg.BlockTrackers
+		if cfg.MaxBodyBytes > merged.MaxBodyBytes {
+			merged.MaxBodyBytes = cfg.MaxBodyBytes
+		}
+	}
+	if !found {
+		return nil
+	}
+
+	if err := browserCtx.StartIntercept(merged); err != nil {
+		return fmt.Errorf("failed to start network interception: %w", err)
+	}
+	logger.Debug("Network interception started",
+		zap.Int("rules", len(merged.Rules)),
+		zap.Strings("blocked_types", merged.BlockResourceTypes),
+		zap.Bool("block_trackers", merged.BlockTrackers))
+	return nil
+}
+
+// paginationSelectorType returns the selector type for a pagination selector param
+func paginationSelectorType(params map[string]interface{}, key string) string {
+	if selectorType := getStringParam(params, key+"_type"); selectorType != "" {
+		return selectorType
+	}
+	return getStringParam(params, "selector_type")
+}
+
 // tryNavigateNext attempts to navigate using the "next" button/link
 func (e *Executor) tryNavigateNext(browserCtx *browser.BrowserContext, nextSelector string, paginationType string, waitAfter int) (bool, error) {
 	page := browserCtx.Page
@@ -1495,53 +2049,16 @@ func (e *Executor) tryNavigatePaginationLink(browserCtx *browser.BrowserContext,
 	return true, nil
 }
 
-// resolveTemplateVariables resolves template placeholders in node params
-// Supports {{start_url}} and {{current_url}} placeholders
-func (e *Executor) resolveTemplateVariables(params map[string]interface{}, currentURL string, startURLs []string) map[string]interface{} {
-	if params == nil {
-		return nil
-	}
-
-	// Deep copy params to avoid mutating original configuration
-	resolvedParams := make(map[string]interface{})
-	for key, value := range params {
-		resolvedParams[key] = e.resolveValue(value, currentURL, startURLs)
+// resolveTemplateVariables expands {{ expression }} templates in the node's expression and URL params
+// Templates are sandboxed expressions over url, vars, current_url and start_url; other params only
+// resolve the exact placeholders "{{current_url}}" and "{{start_url}}"
+func (e *Executor) resolveTemplateVariables(node *models.Node, item *models.URLQueueItem, execCtx *models.ExecutionContext, startURLs []string) (map[string]interface{}, error) {
+	startURL := ""
+	if len(startURLs) > 0 {
+		startURL = startURLs[0]
 	}
+	env := expression.NewEnv(nil, execCtx, item).With("current_url", item.URL).With("start_url", startURL)
 
-	return resolvedParams
-}
-
-// resolveValue recursively resolves template variables in any value type
-func (e *Executor) resolveValue(value interface{}, currentURL string, startURLs []string) interface{} {
-	switch v := value.(type) {
-	case string:
-		// Replace template variables
-		if v == "{{current_url}}" {
-			return currentURL
-		}
-		if v == "{{start_url}}" && len(startURLs) > 0 {
-			return startURLs[0]
-		}
-		return v
-
-	case map[string]interface{}:
-		// Recursively resolve nested maps
-		resolvedMap := make(map[string]interface{})
-		for k, val := range v {
-			resolvedMap[k] = e.resolveValue(val, currentURL, startURLs)
-		}
-		return resolvedMap
-
-	case []interface{}:
-		// Recursively resolve arrays
-		resolvedArray := make([]interface{}, len(v))
-		for i, val := range v {
-			resolvedArray[i] = e.resolveValue(val, currentURL, startURLs)
-		}
-		return resolvedArray
-
-	default:
-		// Return other types as-is (numbers, bools, etc.)
-		return v
-	}
+	// ExpandParams copies params, so the original configuration is never mutated
+	return expression.ExpandParams(node.Params, env, ExpressionParams(node.Type)...)
 }
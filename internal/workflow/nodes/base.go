@@ -3,8 +3,10 @@ package nodes
 import (
 	"context"
 
+	"github.com/uzzalhcse/crawlify/internal/assets"
 	"github.com/uzzalhcse/crawlify/internal/browser"
 	"github.com/uzzalhcse/crawlify/pkg/models"
+	"github.com/uzzalhcse/crawlify/pkg/plugins"
 )
 
 // NodeExecutor defines the interface all node types must implement
@@ -39,13 +41,54 @@ type ExecutionInput struct {
 	Params           map[string]interface{}
 	URLItem          *models.URLQueueItem
 	ExecutionID      string
+	NodeID           string             // ID of the node being run; "" outside workflow executions
+	Assets           *assets.Downloader // nil when asset downloads are not configured
+	// EnqueueURLs enqueues discovered links immediately so long-running discovery keeps its
+	// progress if it fails midway; it returns the number enqueued. nil outside workflow executions
+	EnqueueURLs func(urls []string) (int, error)
+
+	// Services handed to plugins; zero outside workflow executions
+	WorkflowID string
+	HTTP       *plugins.HTTPClient // Honours the workflow's proxy and rate limit
+	PluginKV   plugins.KVBackend   // nil when no database is configured
+}
+
+// PluginStores returns the workflow and execution stores of a plugin, nil when the input has no backend or workflow
+func (in *ExecutionInput) PluginStores(pluginSlug string) (plugins.KVStore, plugins.KVStore) {
+	scope := models.PluginKVScope{PluginSlug: pluginSlug, WorkflowID: in.WorkflowID}
+	workflowStore := plugins.NewKVStore(in.PluginKV, scope)
+	if workflowStore == nil || in.ExecutionID == "" {
+		return workflowStore, nil
+	}
+	scope.ExecutionID = in.ExecutionID
+	return workflowStore, plugins.NewKVStore(in.PluginKV, scope)
 }
 
 // ExecutionOutput contains the results of node execution
 type ExecutionOutput struct {
-	Result         interface{}
-	Metadata       map[string]interface{}
-	DiscoveredURLs []string
+	Result          interface{}
+	Metadata        map[string]interface{}
+	DiscoveredURLs  []string
+	DiscoveredItems []DiscoveredURL // Discovered URLs that need queue options
+}
+
+// DiscoveredURL is a discovered link with queue options
+type DiscoveredURL struct {
+	URL      string
+	Marker   string                 // Overrides the node's marker param
+	Metadata map[string]interface{} // Stored on the queue item, e.g. the page number of a generated page
+	// SamePhase queues the URL at the current depth for the phase that discovered it (generated
+	// pagination pages) instead of one level deeper for the phase transition
+	SamePhase bool
+}
+
+// DiscoveredLinks converts plain links to discovered URLs
+func DiscoveredLinks(links []string) []DiscoveredURL {
+	discovered := make([]DiscoveredURL, len(links))
+	for i, link := range links {
+		discovered[i] = DiscoveredURL{URL: link}
+	}
+	return discovered
 }
 
 // BaseNodeExecutor provides common functionality for node executors
@@ -0,0 +1,38 @@
+package nodes
+
+import (
+	"time"
+
+	"github.com/uzzalhcse/crawlify/internal/browser"
+)
+
+// CapturesContextKey is the ExecutionContext key holding captured network responses by rule name
+const CapturesContextKey = "_captures"
+
+// GetCaptures returns the captured responses for a rule ("" = all rules)
+// All captures are mirrored into the execution context so later nodes and saved items can see them
+func GetCaptures(input *ExecutionInput, rule string, timeout time.Duration) []browser.CapturedResponse {
+	if input.BrowserContext == nil || !input.BrowserContext.IsIntercepting() {
+		return nil
+	}
+
+	all := input.BrowserContext.Captures("", timeout)
+	if input.ExecutionContext != nil {
+		byRule := make(map[string][]browser.CapturedResponse)
+		for _, captured := range all {
+			byRule[captured.Rule] = append(byRule[captured.Rule], captured)
+		}
+		input.ExecutionContext.Set(CapturesContextKey, byRule)
+	}
+
+	if rule == "" {
+		return all
+	}
+	var matched []browser.CapturedResponse
+	for _, captured := range all {
+		if captured.Rule == rule {
+			matched = append(matched, captured)
+		}
+	}
+	return matched
+}
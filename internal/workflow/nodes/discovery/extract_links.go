@@ -29,16 +29,17 @@ func (e *ExtractLinksExecutor) Type() models.NodeType {
 // Validate validates the node parameters
 func (e *ExtractLinksExecutor) Validate(params map[string]interface{}) error {
 	// selector is optional, defaults to "a"
-	return nil
+	return nodes.ValidateStaticSelectorParam(params, "selector", "selector_type")
 }
 
 // Execute extracts links from the page
 func (e *ExtractLinksExecutor) Execute(ctx context.Context, input *nodes.ExecutionInput) (*nodes.ExecutionOutput, error) {
 	selector := nodes.GetStringParam(input.Params, "selector", "a")
+	selectorType := nodes.GetStringParam(input.Params, "selector_type")
 	limit := nodes.GetIntParam(input.Params, "limit", 0)
 
 	engine := extraction.NewExtractionEngine(input.BrowserContext.Page)
-	links, err := engine.ExtractLinks(selector, limit)
+	links, err := engine.ExtractLinksWithType(selector, selectorType, limit)
 	if err != nil {
 		return nil, fmt.Errorf("failed to extract links: %w", err)
 	}
@@ -65,7 +66,7 @@ func (e *ExtractLinksExecutor) ValidateForMonitoring(ctx context.Context, input
 	selector := nodes.GetStringParam(input.Params, "selector", "a")
 
 	page := input.BrowserContext.Page
-	locator := page.Locator(selector)
+	locator := page.Locator(nodes.ResolveSelector(input.Params, "selector", "selector_type", "a"))
 	count, _ := locator.Count()
 
 	// Check if elements have valid href attributes
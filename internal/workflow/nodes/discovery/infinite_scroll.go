@@ -0,0 +1,257 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+	"github.com/uzzalhcse/crawlify/internal/extraction"
+	"github.com/uzzalhcse/crawlify/internal/workflow/nodes"
+	"github.com/uzzalhcse/crawlify/pkg/models"
+)
+
+// Reasons an infinite_scroll node stopped
+const (
+	StopReasonNoNewItems  = "no_new_items"
+	StopReasonSentinel    = "sentinel"
+	StopReasonMaxItems    = "max_items"
+	StopReasonMaxTime     = "max_time"
+	StopReasonMaxRounds   = "max_rounds"
+	StopReasonNetworkIdle = "network_idle"
+	StopReasonCancelled   = "cancelled"
+)
+
+// InfiniteScrollExecutor discovers links on listings that load more items on scroll or on a "load more" click
+type InfiniteScrollExecutor struct {
+	nodes.BaseNodeExecutor
+}
+
+// NewInfiniteScrollExecutor creates a new infinite_scroll executor
+func NewInfiniteScrollExecutor() *InfiniteScrollExecutor {
+	return &InfiniteScrollExecutor{
+		BaseNodeExecutor: nodes.BaseNodeExecutor{},
+	}
+}
+
+// Type returns the node type
+func (e *InfiniteScrollExecutor) Type() models.NodeType {
+	return models.NodeTypeInfiniteScroll
+}
+
+// Validate validates the node parameters
+func (e *InfiniteScrollExecutor) Validate(params map[string]interface{}) error {
+	if nodes.GetStringParam(params, "item_selector") == "" {
+		return fmt.Errorf("item_selector is required for infinite_scroll node")
+	}
+	for _, key := range []string{"item_selector", "load_more_selector", "sentinel_selector", "scroll_container"} {
+		if err := nodes.ValidateSelectorParam(params, key, "selector_type"); err != nil {
+			return err
+		}
+	}
+	// Links are read from the page HTML
+	if err := nodes.ValidateStaticSelectorParam(params, "link_selector", "selector_type"); err != nil {
+		return err
+	}
+	for _, key := range []string{"max_items", "max_time", "max_rounds", "max_stale_rounds", "wait_after", "idle_timeout"} {
+		if nodes.GetIntParam(params, key, 0) < 0 {
+			return fmt.Errorf("%s must not be negative", key)
+		}
+	}
+	return nil
+}
+
+// infiniteScrollConfig holds the resolved node parameters
+type infiniteScrollConfig struct {
+	itemSelector     string
+	linkSelector     string
+	linkSelectorType string
+	loadMoreSelector string
+	sentinelSelector string
+	scrollContainer  string
+	maxItems         int
+	maxTime          time.Duration
+	maxRounds        int
+	maxStaleRounds   int
+	waitAfter        time.Duration
+	idleTimeout      time.Duration
+	stopOnIdle       bool
+}
+
+func parseInfiniteScrollConfig(params map[string]interface{}) infiniteScrollConfig {
+	linkSelector := nodes.GetStringParam(params, "link_selector", nodes.GetStringParam(params, "item_selector"))
+	return infiniteScrollConfig{
+		itemSelector:     nodes.ResolveSelector(params, "item_selector", "selector_type"),
+		linkSelector:     linkSelector,
+		linkSelectorType: nodes.GetStringParam(params, "selector_type"),
+		loadMoreSelector: nodes.ResolveSelector(params, "load_more_selector", "selector_type"),
+		sentinelSelector: nodes.ResolveSelector(params, "sentinel_selector", "selector_type"),
+		scrollContainer:  nodes.ResolveSelector(params, "scroll_container", "selector_type"),
+		maxItems:         nodes.GetIntParam(params, "max_items", 0),
+		maxTime:          time.Duration(nodes.GetIntParam(params, "max_time", 120000)) * time.Millisecond,
+		maxRounds:        nodes.GetIntParam(params, "max_rounds", 100),
+		maxStaleRounds:   nodes.GetIntParam(params, "max_stale_rounds", 3),
+		waitAfter:        time.Duration(nodes.GetIntParam(params, "wait_after", 1000)) * time.Millisecond,
+		idleTimeout:      time.Duration(nodes.GetIntParam(params, "idle_timeout", 5000)) * time.Millisecond,
+		stopOnIdle:       nodes.GetBoolParam(params, "stop_on_network_idle", false),
+	}
+}
+
+// Execute scrolls (or clicks load_more_selector) until the item count stops growing, the sentinel
+// appears, or a budget is hit. New links are enqueued after every round, so a page that crashes
+// midway keeps what was already discovered
+func (e *InfiniteScrollExecutor) Execute(ctx context.Context, input *nodes.ExecutionInput) (*nodes.ExecutionOutput, error) {
+	cfg := parseInfiniteScrollConfig(input.Params)
+	page := input.BrowserContext.Page
+	engine := extraction.NewExtractionEngine(page)
+
+	monitor := input.BrowserContext.MonitorNetwork()
+	defer monitor.Stop()
+
+	seen := make(map[string]bool)
+	var pending []string // links not yet enqueued; returned as DiscoveredURLs
+	enqueued := 0
+
+	// collect reads the listing's links and yields the ones not seen before
+	collect := func() {
+		links, err := engine.ExtractLinksWithType(cfg.linkSelector, cfg.linkSelectorType, 0)
+		if err != nil {
+			return
+		}
+		var fresh []string
+		for _, link := range links {
+			if cfg.maxItems > 0 && len(seen) >= cfg.maxItems {
+				break
+			}
+			if !seen[link] {
+				seen[link] = true
+				fresh = append(fresh, link)
+			}
+		}
+		if len(fresh) == 0 {
+			return
+		}
+		if input.EnqueueURLs == nil {
+			pending = append(pending, fresh...)
+			return
+		}
+		if _, err := input.EnqueueURLs(fresh); err != nil {
+			// Keep them for the node's output, which the executor enqueues again
+			pending = append(pending, fresh...)
+			return
+		}
+		enqueued += len(fresh)
+	}
+
+	start := time.Now()
+	lastCount, _ := page.Locator(cfg.itemSelector).Count()
+	collect()
+
+	rounds, staleRounds, clicks := 0, 0, 0
+	stopReason := StopReasonMaxRounds
+	for rounds < cfg.maxRounds {
+		if ctx.Err() != nil {
+			stopReason = StopReasonCancelled
+			break
+		}
+		if cfg.sentinelSelector != "" {
+			if visible, _ := page.Locator(cfg.sentinelSelector).First().IsVisible(); visible {
+				stopReason = StopReasonSentinel
+				break
+			}
+		}
+		if cfg.maxItems > 0 && (lastCount >= cfg.maxItems || len(seen) >= cfg.maxItems) {
+			stopReason = StopReasonMaxItems
+			break
+		}
+		if time.Since(start) >= cfg.maxTime {
+			stopReason = StopReasonMaxTime
+			break
+		}
+
+		rounds++
+		if e.clickLoadMore(page, cfg) {
+			clicks++
+		} else if err := e.scroll(page, cfg); err != nil {
+			return nil, fmt.Errorf("infinite scroll failed after %d rounds (%d links enqueued): %w", rounds, enqueued, err)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(cfg.waitAfter):
+		}
+		if ctx.Err() != nil {
+			stopReason = StopReasonCancelled
+			break
+		}
+		idle := monitor.WaitForIdle(500*time.Millisecond, cfg.idleTimeout)
+
+		count, err := page.Locator(cfg.itemSelector).Count()
+		if err != nil {
+			return nil, fmt.Errorf("infinite scroll failed after %d rounds (%d links enqueued): %w", rounds, enqueued, err)
+		}
+		collect()
+
+		if count > lastCount {
+			staleRounds = 0
+		} else {
+			staleRounds++
+		}
+		lastCount = count
+
+		if staleRounds > 0 && idle && cfg.stopOnIdle {
+			stopReason = StopReasonNetworkIdle
+			break
+		}
+		if staleRounds >= cfg.maxStaleRounds {
+			stopReason = StopReasonNoNewItems
+			break
+		}
+	}
+
+	return &nodes.ExecutionOutput{
+		Result: map[string]interface{}{
+			"rounds":      rounds,
+			"clicks":      clicks,
+			"items":       lastCount,
+			"links":       len(seen),
+			"enqueued":    enqueued,
+			"stop_reason": stopReason,
+			"duration_ms": time.Since(start).Milliseconds(),
+		},
+		DiscoveredURLs: pending,
+	}, nil
+}
+
+// clickLoadMore clicks the "load more" control when it is visible
+func (e *InfiniteScrollExecutor) clickLoadMore(page playwright.Page, cfg infiniteScrollConfig) bool {
+	if cfg.loadMoreSelector == "" {
+		return false
+	}
+	button := page.Locator(cfg.loadMoreSelector).First()
+	if visible, _ := button.IsVisible(); !visible {
+		return false
+	}
+	err := button.Click(playwright.LocatorClickOptions{
+		Timeout: playwright.Float(5000),
+	})
+	return err == nil
+}
+
+// scroll brings the last item into view (for IntersectionObserver loaders) and scrolls to the bottom
+func (e *InfiniteScrollExecutor) scroll(page playwright.Page, cfg infiniteScrollConfig) error {
+	page.Locator(cfg.itemSelector).Last().ScrollIntoViewIfNeeded(playwright.LocatorScrollIntoViewIfNeededOptions{
+		Timeout: playwright.Float(2000),
+	})
+
+	if cfg.scrollContainer != "" {
+		_, err := page.Locator(cfg.scrollContainer).First().Evaluate(`el => { el.scrollTop = el.scrollHeight; }`, nil)
+		return err
+	}
+
+	if _, err := page.Evaluate(`() => window.scrollTo(0, document.documentElement.scrollHeight)`); err != nil {
+		return err
+	}
+	// Some listings only react to wheel events
+	return page.Mouse().Wheel(0, 400)
+}
@@ -28,19 +28,45 @@ func (e *PaginateExecutor) Type() models.NodeType {
 }
 
 // Validate validates the node parameters
+// mode "click" (default) follows a next button; mode "url_pattern" generates page URLs
 func (e *PaginateExecutor) Validate(params map[string]interface{}) error {
+	switch mode := nodes.GetStringParam(params, "mode", "click"); mode {
+	case PaginateModeURLPattern:
+		return validateURLPagination(params)
+	case "click":
+	default:
+		return fmt.Errorf("unknown paginate mode '%s' (expected click or %s)", mode, PaginateModeURLPattern)
+	}
+
 	selector := nodes.GetStringParam(params, "selector")
 	if selector == "" {
 		return fmt.Errorf("selector is required for paginate node")
 	}
-	return nil
+	if err := nodes.ValidateSelectorParam(params, "selector", "selector_type"); err != nil {
+		return err
+	}
+	return nodes.ValidateStaticSelectorParam(params, "link_selector", linkSelectorTypeKey(params))
+}
+
+// linkSelectorTypeKey returns the param holding the link selector's type
+// link_selector_type overrides selector_type when set
+func linkSelectorTypeKey(params map[string]interface{}) string {
+	if nodes.GetStringParam(params, "link_selector_type") != "" {
+		return "link_selector_type"
+	}
+	return "selector_type"
 }
 
 // Execute performs pagination
 func (e *PaginateExecutor) Execute(ctx context.Context, input *nodes.ExecutionInput) (*nodes.ExecutionOutput, error) {
-	selector := nodes.GetStringParam(input.Params, "selector")
+	if nodes.GetStringParam(input.Params, "mode") == PaginateModeURLPattern {
+		return e.executeURLPagination(input)
+	}
+
+	selector := nodes.ResolveSelector(input.Params, "selector", "selector_type")
 	maxPages := nodes.GetIntParam(input.Params, "max_pages", 10)
 	linkSelector := nodes.GetStringParam(input.Params, "link_selector", "")
+	linkSelectorType := nodes.GetStringParam(input.Params, linkSelectorTypeKey(input.Params))
 
 	engine := extraction.NewExtractionEngine(input.BrowserContext.Page)
 	var allLinks []string
@@ -49,7 +75,7 @@ func (e *PaginateExecutor) Execute(ctx context.Context, input *nodes.ExecutionIn
 	for pagesProcessed < maxPages {
 		// Extract links from current page if link_selector is provided
 		if linkSelector != "" {
-			links, err := engine.ExtractLinks(linkSelector, 0)
+			links, err := engine.ExtractLinksWithType(linkSelector, linkSelectorType, 0)
 			if err == nil {
 				allLinks = append(allLinks, links...)
 			}
@@ -102,14 +128,22 @@ func (e *PaginateExecutor) ValidateForMonitoring(ctx context.Context, input *nod
 		Issues:   []models.ValidationIssue{},
 	}
 
+	// Generated pages are only known while crawling; there is no button to check
+	if nodes.GetStringParam(input.Params, "mode") == PaginateModeURLPattern {
+		result.Metrics["mode"] = PaginateModeURLPattern
+		return result, nil
+	}
+
 	selector := nodes.GetStringParam(input.Params, "selector")
+	resolvedSelector := nodes.ResolveSelector(input.Params, "selector", "selector_type")
 	linkSelector := nodes.GetStringParam(input.Params, "link_selector", "")
+	linkSelectorType := nodes.GetStringParam(input.Params, linkSelectorTypeKey(input.Params))
 	maxPages := input.Config.MaxPaginationPages // Use monitoring config limit
 
 	page := input.BrowserContext.Page
 
 	// Check if pagination button exists
-	locator := page.Locator(selector)
+	locator := page.Locator(resolvedSelector)
 	count, _ := locator.Count()
 
 	if count == 0 {
@@ -134,7 +168,7 @@ func (e *PaginateExecutor) ValidateForMonitoring(ctx context.Context, input *nod
 		pagesChecked := 0
 		for pagesChecked < maxPages && pagesChecked < 2 { // Max 2 pages for monitoring
 			// Extract links from current page
-			links, err := engine.ExtractLinks(linkSelector, 0)
+			links, err := engine.ExtractLinksWithType(linkSelector, linkSelectorType, 0)
 			if err == nil && len(links) > 0 {
 				allDiscoveredURLs = append(allDiscoveredURLs, links...)
 				if len(sampleUrls) < 3 {
@@ -148,7 +182,7 @@ func (e *PaginateExecutor) ValidateForMonitoring(ctx context.Context, input *nod
 
 			// Try to click next if it exists and we haven't reached max
 			if count > 0 && pagesChecked < maxPages-1 {
-				err = page.Click(selector, playwright.PageClickOptions{
+				err = page.Click(resolvedSelector, playwright.PageClickOptions{
 					Timeout: playwright.Float(10000),
 				})
 				if err != nil {
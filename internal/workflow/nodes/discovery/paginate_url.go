@@ -0,0 +1,372 @@
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/uzzalhcse/crawlify/internal/expression"
+	"github.com/uzzalhcse/crawlify/internal/extraction"
+	"github.com/uzzalhcse/crawlify/internal/workflow/nodes"
+)
+
+// PaginateModeURLPattern generates page URLs instead of clicking through pages
+const PaginateModeURLPattern = "url_pattern"
+
+// Strategies for generating further pages, recorded on generated queue items
+const (
+	pageStrategyTotal  = "total"  // Total known on the first page: every page is enqueued at once
+	pageStrategyCursor = "cursor" // Each page yields the cursor of the next
+	pageStrategyWindow = "window" // Total unknown: keep `window` pages ahead until a page is empty
+)
+
+// paginationMetadataKey is the queue item metadata key holding a generated page's state
+const paginationMetadataKey = "pagination"
+
+// urlPagination holds the resolved url_pattern params
+type urlPagination struct {
+	template         string // e.g. "/search?q=shoes&page={n}"; placeholders {n} {page} {offset} {limit} {cursor}
+	pageParam        string // Query params set on the current URL when there is no template
+	offsetParam      string
+	limitParam       string
+	cursorParam      string
+	startPage        int
+	pageSize         int
+	maxPages         int
+	window           int
+	totalExpression  string // Total number of items
+	pagesExpression  string // Total number of pages
+	cursorPath       string // Path of the next cursor in the JSON response
+	cursorLang       string
+	cursorExpression string
+	itemSelector     string // A page without matches is empty
+	emptyExpression  string // A page for which this is true is empty
+	capture          string // Intercept rule whose response is the page's JSON (default: the navigation response)
+	linkSelector     string
+	linkSelectorType string
+	pageMarker       string
+}
+
+// pageState is the pagination state of the page being processed
+type pageState struct {
+	Page      int    `json:"page"`
+	Cursor    string `json:"cursor,omitempty"`
+	Strategy  string `json:"strategy,omitempty"`
+	Generated bool   `json:"generated,omitempty"`
+}
+
+func parseURLPagination(params map[string]interface{}) urlPagination {
+	return urlPagination{
+		template:         nodes.GetStringParam(params, "url_template"),
+		pageParam:        nodes.GetStringParam(params, "page_param"),
+		offsetParam:      nodes.GetStringParam(params, "offset_param"),
+		limitParam:       nodes.GetStringParam(params, "limit_param"),
+		cursorParam:      nodes.GetStringParam(params, "cursor_param"),
+		startPage:        nodes.GetIntParam(params, "start_page", 1),
+		pageSize:         nodes.GetIntParam(params, "page_size", 20),
+		maxPages:         nodes.GetIntParam(params, "max_pages", 10),
+		window:           nodes.GetIntParam(params, "window", 5),
+		totalExpression:  nodes.GetStringParam(params, "total_expression"),
+		pagesExpression:  nodes.GetStringParam(params, "pages_expression"),
+		cursorPath:       nodes.GetStringParam(params, "cursor_path"),
+		cursorLang:       nodes.GetStringParam(params, "query_lang"),
+		cursorExpression: nodes.GetStringParam(params, "cursor_expression"),
+		itemSelector:     nodes.ResolveSelector(params, "item_selector", "selector_type"),
+		emptyExpression:  nodes.GetStringParam(params, "empty_expression"),
+		capture:          nodes.GetStringParam(params, "capture"),
+		linkSelector:     nodes.GetStringParam(params, "link_selector"),
+		linkSelectorType: nodes.GetStringParam(params, linkSelectorTypeKey(params)),
+		pageMarker:       nodes.GetStringParam(params, "page_marker"),
+	}
+}
+
+// usesCursor reports whether pages are chained by cursor
+func (p urlPagination) usesCursor() bool {
+	return p.cursorPath != "" || p.cursorExpression != ""
+}
+
+// validateURLPagination validates url_pattern params
+func validateURLPagination(params map[string]interface{}) error {
+	p := parseURLPagination(params)
+	if p.template == "" && p.pageParam == "" && p.offsetParam == "" && p.cursorParam == "" {
+		return fmt.Errorf("url_pattern pagination requires url_template or page_param, offset_param or cursor_param")
+	}
+	if p.startPage < 0 || p.pageSize <= 0 || p.maxPages <= 0 || p.window <= 0 {
+		return fmt.Errorf("start_page must not be negative and page_size, max_pages and window must be positive")
+	}
+	if p.usesCursor() && !strings.Contains(p.template, "{cursor}") && p.cursorParam == "" {
+		return fmt.Errorf("cursor pagination requires {cursor} in url_template or cursor_param")
+	}
+	if !p.usesCursor() && (strings.Contains(p.template, "{cursor}") || p.cursorParam != "") {
+		return fmt.Errorf("cursor pagination requires cursor_path or cursor_expression")
+	}
+	if p.cursorPath != "" {
+		if err := extraction.ValidateJSONQuery(p.cursorPath, p.cursorLang); err != nil {
+			return fmt.Errorf("cursor_path: %w", err)
+		}
+	}
+	for key, source := range map[string]string{
+		"total_expression":  p.totalExpression,
+		"pages_expression":  p.pagesExpression,
+		"cursor_expression": p.cursorExpression,
+		"empty_expression":  p.emptyExpression,
+	} {
+		if source == "" {
+			continue
+		}
+		if err := expression.Validate(source); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+	if err := nodes.ValidateSelectorParam(params, "item_selector", "selector_type"); err != nil {
+		return err
+	}
+	return nodes.ValidateStaticSelectorParam(params, "link_selector", linkSelectorTypeKey(params))
+}
+
+// executeURLPagination enqueues generated page URLs as queue items for the current phase so pages
+// are processed concurrently and retried individually. Each generated page runs this node again:
+// it stops when the page is empty, the cursor runs out or max_pages is reached
+func (e *PaginateExecutor) executeURLPagination(input *nodes.ExecutionInput) (*nodes.ExecutionOutput, error) {
+	p := parseURLPagination(input.Params)
+	state := currentPageState(input, p)
+
+	current, err := url.Parse(input.URLItem.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page URL: %w", err)
+	}
+
+	data := e.pageJSON(input, p)
+	env := expression.NewEnv(nil, input.ExecutionContext, input.URLItem).With("json", data).With("page", state.Page)
+
+	// Links on the current page (e.g. product links) are discovered as usual
+	var links []string
+	itemCount := -1
+	if p.linkSelector != "" {
+		links, _ = extraction.NewExtractionEngine(input.BrowserContext.Page).ExtractLinksWithType(p.linkSelector, p.linkSelectorType, 0)
+	}
+	if p.itemSelector != "" {
+		itemCount, _ = input.BrowserContext.Page.Locator(p.itemSelector).Count()
+	}
+
+	result := map[string]interface{}{
+		"page":     state.Page,
+		"strategy": state.Strategy,
+	}
+	output := &nodes.ExecutionOutput{
+		Result:         result,
+		DiscoveredURLs: links,
+	}
+
+	empty, err := p.isEmpty(env.With("items", itemCount), itemCount)
+	if err != nil {
+		return nil, err
+	}
+	if empty {
+		result["stop_reason"] = "empty"
+		return output, nil
+	}
+
+	lastPage := p.startPage + p.maxPages - 1
+	var next []pageState
+	switch {
+	case p.usesCursor():
+		cursor, err := p.nextCursor(data, env)
+		if err != nil {
+			return nil, err
+		}
+		if cursor == "" {
+			result["stop_reason"] = "no_cursor"
+		} else if state.Page < lastPage {
+			next = append(next, pageState{Page: state.Page + 1, Cursor: cursor, Strategy: pageStrategyCursor})
+		}
+
+	case state.Generated && state.Strategy == pageStrategyTotal:
+		// The first page already enqueued every page
+
+	case !state.Generated && (p.totalExpression != "" || p.pagesExpression != ""):
+		totalPages, err := p.totalPages(env)
+		if err != nil {
+			return nil, err
+		}
+		result["total_pages"] = totalPages
+		if end := p.startPage + totalPages - 1; end < lastPage {
+			lastPage = end
+		}
+		for page := state.Page + 1; page <= lastPage; page++ {
+			next = append(next, pageState{Page: page, Strategy: pageStrategyTotal})
+		}
+
+	case !state.Generated:
+		// Unknown total: open a window of pages; every generated page then extends it by one
+		for page := state.Page + 1; page <= state.Page+p.window && page <= lastPage; page++ {
+			next = append(next, pageState{Page: page, Strategy: pageStrategyWindow})
+		}
+
+	default:
+		if page := state.Page + p.window; page <= lastPage {
+			next = append(next, pageState{Page: page, Strategy: pageStrategyWindow})
+		}
+	}
+
+	if len(next) == 0 && result["stop_reason"] == nil && state.Page >= lastPage {
+		result["stop_reason"] = "max_pages"
+	}
+
+	for _, page := range next {
+		page.Generated = true
+		pageURL := p.pageURL(current, page)
+		output.DiscoveredItems = append(output.DiscoveredItems, nodes.DiscoveredURL{
+			URL:       pageURL,
+			Marker:    p.pageMarker,
+			Metadata:  map[string]interface{}{paginationMetadataKey: page},
+			SamePhase: true,
+		})
+	}
+	result["pages_enqueued"] = len(output.DiscoveredItems)
+
+	return output, nil
+}
+
+// currentPageState reads the page state from the queue item; pages not generated by this node start at start_page
+func currentPageState(input *nodes.ExecutionInput, p urlPagination) pageState {
+	state := pageState{Page: p.startPage}
+	if input.URLItem == nil || input.URLItem.Metadata == "" {
+		return state
+	}
+	var metadata map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(input.URLItem.Metadata), &metadata); err != nil {
+		return state
+	}
+	if raw, ok := metadata[paginationMetadataKey]; ok {
+		json.Unmarshal(raw, &state)
+	}
+	return state
+}
+
+// pageJSON returns the JSON behind the page: the latest capture of the configured intercept rule,
+// or the navigation response when the page is an API endpoint
+func (e *PaginateExecutor) pageJSON(input *nodes.ExecutionInput, p urlPagination) interface{} {
+	if p.capture != "" {
+		captures := nodes.GetCaptures(input, p.capture, 0)
+		if len(captures) > 0 {
+			return captures[len(captures)-1].Body
+		}
+		return nil
+	}
+	data, err := input.BrowserContext.GetResponseJSON()
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// isEmpty applies the empty-result detectors
+func (p urlPagination) isEmpty(env expression.Env, itemCount int) (bool, error) {
+	if p.itemSelector != "" && itemCount == 0 {
+		return true, nil
+	}
+	if p.emptyExpression == "" {
+		return false, nil
+	}
+	program, err := expression.Compile(p.emptyExpression)
+	if err != nil {
+		return false, err
+	}
+	empty, err := program.EvalBool(env)
+	if err != nil {
+		return false, fmt.Errorf("empty_expression failed: %w", err)
+	}
+	return empty, nil
+}
+
+// totalPages evaluates pages_expression, or total_expression divided by page_size
+func (p urlPagination) totalPages(env expression.Env) (int, error) {
+	source, perPage := p.pagesExpression, 1
+	if source == "" {
+		source, perPage = p.totalExpression, p.pageSize
+	}
+	value, err := expression.Eval(source, env)
+	if err != nil {
+		return 0, fmt.Errorf("failed to evaluate total: %w", err)
+	}
+	total, ok := toFloat(value)
+	if !ok {
+		return 0, fmt.Errorf("total expression '%s' returned %v, not a number", source, value)
+	}
+	return int(math.Ceil(total / float64(perPage))), nil
+}
+
+// nextCursor reads the next page's cursor; "" means there are no more pages
+func (p urlPagination) nextCursor(data interface{}, env expression.Env) (string, error) {
+	var value interface{}
+	var err error
+	if p.cursorExpression != "" {
+		value, err = expression.Eval(p.cursorExpression, env)
+	} else if data != nil {
+		value, err = extraction.QueryJSON(data, p.cursorPath, p.cursorLang)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read cursor: %w", err)
+	}
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case bool:
+		return "", nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// pageURL builds the URL of a page from the template, or by setting query params on the current URL
+func (p urlPagination) pageURL(current *url.URL, page pageState) string {
+	offset := (page.Page - p.startPage) * p.pageSize
+	if p.template != "" {
+		return strings.NewReplacer(
+			"{n}", strconv.Itoa(page.Page),
+			"{page}", strconv.Itoa(page.Page),
+			"{offset}", strconv.Itoa(offset),
+			"{limit}", strconv.Itoa(p.pageSize),
+			"{cursor}", url.QueryEscape(page.Cursor),
+		).Replace(p.template)
+	}
+
+	next := *current
+	query := next.Query()
+	if p.pageParam != "" {
+		query.Set(p.pageParam, strconv.Itoa(page.Page))
+	}
+	if p.offsetParam != "" {
+		query.Set(p.offsetParam, strconv.Itoa(offset))
+	}
+	if p.limitParam != "" {
+		query.Set(p.limitParam, strconv.Itoa(p.pageSize))
+	}
+	if p.cursorParam != "" {
+		query.Set(p.cursorParam, page.Cursor)
+	}
+	next.RawQuery = query.Encode()
+	return next.String()
+}
+
+// toFloat converts a numeric expression result
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, !math.IsNaN(v) && !math.IsInf(v, 0)
+	case int:
+		return float64(v), true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,165 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/uzzalhcse/crawlify/internal/sitemap"
+	"github.com/uzzalhcse/crawlify/internal/workflow/nodes"
+	"github.com/uzzalhcse/crawlify/pkg/models"
+)
+
+// SitemapExecutor discovers URLs from sitemaps, sitemap indexes, robots.txt and RSS/Atom feeds
+type SitemapExecutor struct {
+	nodes.BaseNodeExecutor
+}
+
+// NewSitemapExecutor creates a new sitemap executor
+func NewSitemapExecutor() *SitemapExecutor {
+	return &SitemapExecutor{
+		BaseNodeExecutor: nodes.BaseNodeExecutor{},
+	}
+}
+
+// Type returns the node type
+func (e *SitemapExecutor) Type() models.NodeType {
+	return models.NodeTypeSitemap
+}
+
+// Validate validates the node parameters
+func (e *SitemapExecutor) Validate(params map[string]interface{}) error {
+	for _, source := range stringList(nodes.GetArrayParam(params, "urls")) {
+		if _, err := url.Parse(source); err != nil {
+			return fmt.Errorf("invalid sitemap URL '%s': %w", source, err)
+		}
+	}
+	if _, err := sitemap.CompilePatterns(stringList(nodes.GetArrayParam(params, "include"))); err != nil {
+		return fmt.Errorf("include: %w", err)
+	}
+	if _, err := sitemap.CompilePatterns(stringList(nodes.GetArrayParam(params, "exclude"))); err != nil {
+		return fmt.Errorf("exclude: %w", err)
+	}
+	if since := nodes.GetStringParam(params, "since"); since != "" {
+		if _, err := parseSince(since); err != nil {
+			return err
+		}
+	}
+	if nodes.GetIntParam(params, "max_urls", 0) < 0 || nodes.GetIntParam(params, "max_sitemaps", 0) < 0 {
+		return fmt.Errorf("max_urls and max_sitemaps must not be negative")
+	}
+	return nil
+}
+
+// Execute reads the sitemaps or feeds and returns the URLs they list, with lastmod and title as queue metadata
+// Without urls, the current site's robots.txt (falling back to /sitemap.xml) is used.
+// Sources are fetched through the browser context, so its cookies and proxy apply
+func (e *SitemapExecutor) Execute(ctx context.Context, input *nodes.ExecutionInput) (*nodes.ExecutionOutput, error) {
+	sources := stringList(nodes.GetArrayParam(input.Params, "urls"))
+	if len(sources) == 0 {
+		if input.URLItem == nil {
+			return nil, fmt.Errorf("sitemap node requires urls when there is no current page")
+		}
+		current, err := url.Parse(input.URLItem.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid page URL: %w", err)
+		}
+		sources = []string{current.Scheme + "://" + current.Host + "/"}
+	}
+
+	include, err := sitemap.CompilePatterns(stringList(nodes.GetArrayParam(input.Params, "include")))
+	if err != nil {
+		return nil, err
+	}
+	exclude, err := sitemap.CompilePatterns(stringList(nodes.GetArrayParam(input.Params, "exclude")))
+	if err != nil {
+		return nil, err
+	}
+	opts := sitemap.Options{
+		Include:     include,
+		Exclude:     exclude,
+		MaxURLs:     nodes.GetIntParam(input.Params, "max_urls", 0),
+		MaxSitemaps: nodes.GetIntParam(input.Params, "max_sitemaps", 0),
+	}
+	if since := nodes.GetStringParam(input.Params, "since"); since != "" {
+		if opts.Since, err = parseSince(since); err != nil {
+			return nil, err
+		}
+	}
+	if nodes.GetBoolParam(input.Params, "since_last_run", false) {
+		// Set by the executor when the workflow has a previous completed execution
+		if lastRun, ok := input.ExecutionContext.Get("_last_run_at"); ok {
+			if t, ok := lastRun.(time.Time); ok && t.After(opts.Since) {
+				opts.Since = t
+			}
+		}
+	}
+
+	result, err := sitemap.Discover(ctx, e.fetcher(input), sources, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	discovered := make([]nodes.DiscoveredURL, len(result.Entries))
+	for i, entry := range result.Entries {
+		discovered[i] = nodes.DiscoveredURL{URL: entry.URL, Metadata: entry.Metadata()}
+	}
+
+	metadata := map[string]interface{}{
+		"sitemaps_read": result.Sitemaps,
+		"urls_found":    len(result.Entries),
+		"urls_filtered": result.Filtered,
+		"errors":        result.Errors,
+	}
+	if !opts.Since.IsZero() {
+		metadata["since"] = opts.Since.UTC().Format(time.RFC3339)
+	}
+
+	return &nodes.ExecutionOutput{
+		Result:          metadata,
+		Metadata:        metadata,
+		DiscoveredItems: discovered,
+	}, nil
+}
+
+// fetcher fetches through the page's browser context, or over plain HTTP when there is no page
+func (e *SitemapExecutor) fetcher(input *nodes.ExecutionInput) sitemap.Fetcher {
+	timeout := time.Duration(nodes.GetIntParam(input.Params, "timeout", 30000)) * time.Millisecond
+	if input.BrowserContext == nil || input.BrowserContext.Context == nil {
+		return sitemap.HTTPFetcher(nil, timeout)
+	}
+	return func(ctx context.Context, rawURL string) ([]byte, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		file, err := input.BrowserContext.FetchFile(rawURL, sitemap.DefaultMaxBytes, timeout)
+		if err != nil {
+			return nil, err
+		}
+		return file.Body, nil
+	}
+}
+
+// parseSince parses an RFC 3339 timestamp or a YYYY-MM-DD date
+func parseSince(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("since must be an RFC 3339 timestamp or YYYY-MM-DD date, got '%s'", value)
+	}
+	return t, nil
+}
+
+// stringList keeps the non-empty strings of an array param
+func stringList(values []interface{}) []string {
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok && s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
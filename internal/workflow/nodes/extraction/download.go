@@ -0,0 +1,157 @@
+package extraction
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/uzzalhcse/crawlify/internal/assets"
+	"github.com/uzzalhcse/crawlify/internal/workflow/nodes"
+	"github.com/uzzalhcse/crawlify/pkg/models"
+)
+
+// DownloadExecutor downloads images and files referenced by the page or by extracted fields
+// into the asset store and attaches their metadata to the extracted item
+type DownloadExecutor struct {
+	nodes.BaseNodeExecutor
+}
+
+// NewDownloadExecutor creates a new download executor
+func NewDownloadExecutor() *DownloadExecutor {
+	return &DownloadExecutor{
+		BaseNodeExecutor: nodes.BaseNodeExecutor{},
+	}
+}
+
+// Type returns the node type
+func (e *DownloadExecutor) Type() models.NodeType {
+	return models.NodeTypeDownload
+}
+
+// Validate validates the node parameters
+// Sources: fields (extracted URL fields), selector (+attribute) or trigger_selector (click that starts a download)
+func (e *DownloadExecutor) Validate(params map[string]interface{}) error {
+	fields := toStringSlice(nodes.GetArrayParam(params, "fields"))
+	selector := nodes.GetStringParam(params, "selector")
+	trigger := nodes.GetStringParam(params, "trigger_selector")
+
+	if len(fields) == 0 && selector == "" && trigger == "" {
+		return fmt.Errorf("download node requires fields, selector or trigger_selector")
+	}
+	if (selector != "" || trigger != "") && nodes.GetStringParam(params, "field") == "" {
+		return fmt.Errorf("download node requires 'field' to store assets found by selector or trigger_selector")
+	}
+	if err := nodes.ValidateSelectorParam(params, "selector", "selector_type"); err != nil {
+		return err
+	}
+	if err := nodes.ValidateSelectorParam(params, "trigger_selector", "selector_type"); err != nil {
+		return err
+	}
+	if nodes.GetIntParam(params, "max_bytes", 0) < 0 {
+		return fmt.Errorf("max_bytes must not be negative")
+	}
+	return nil
+}
+
+// Execute downloads the referenced files
+// Assets for an extracted field are stored as <field>_asset; selector and trigger downloads under 'field'
+func (e *DownloadExecutor) Execute(ctx context.Context, input *nodes.ExecutionInput) (*nodes.ExecutionOutput, error) {
+	if input.Assets == nil {
+		return nil, fmt.Errorf("asset downloads are not configured (see 'assets' in config.yaml)")
+	}
+	if input.BrowserContext == nil || input.BrowserContext.Page == nil {
+		return nil, fmt.Errorf("download node requires a page")
+	}
+
+	opts := assets.Options{
+		MaxBytes:     int64(nodes.GetIntParam(input.Params, "max_bytes", 0)),
+		AllowedTypes: toStringSlice(nodes.GetArrayParam(input.Params, "allowed_types")),
+		Timeout:      time.Duration(nodes.GetIntParam(input.Params, "timeout", 0)) * time.Millisecond,
+	}
+
+	results := make(map[string]interface{})
+	var failures []string
+	record := func(name string, value interface{}, errs []error) {
+		if value != nil {
+			results[name] = value
+		}
+		for _, err := range errs {
+			failures = append(failures, err.Error())
+		}
+	}
+
+	for _, field := range toStringSlice(nodes.GetArrayParam(input.Params, "fields")) {
+		value, ok := input.ExecutionContext.Get(field)
+		if !ok {
+			failures = append(failures, fmt.Sprintf("field '%s' was not extracted", field))
+			continue
+		}
+		downloaded, errs := input.Assets.DownloadValue(ctx, input.BrowserContext, value, opts)
+		record(field+"_asset", downloaded, errs)
+	}
+
+	field := nodes.GetStringParam(input.Params, "field")
+	if nodes.GetStringParam(input.Params, "selector") != "" {
+		urls, err := e.selectorURLs(input)
+		if err != nil {
+			return nil, err
+		}
+		var value interface{} = urls
+		if !nodes.GetBoolParam(input.Params, "multiple", false) {
+			value = nil
+			if len(urls) > 0 {
+				value = urls[0]
+			}
+		}
+		downloaded, errs := input.Assets.DownloadValue(ctx, input.BrowserContext, value, opts)
+		record(field, downloaded, errs)
+	}
+
+	if trigger := nodes.ResolveSelector(input.Params, "trigger_selector", "selector_type"); trigger != "" {
+		asset, err := input.Assets.DownloadByClick(ctx, input.BrowserContext, trigger, opts)
+		if err != nil {
+			record(field, nil, []error{err})
+		} else {
+			record(field, asset.Map(), nil)
+		}
+	}
+
+	if len(failures) > 0 && nodes.GetBoolParam(input.Params, "required", false) {
+		return nil, fmt.Errorf("download failed: %s", failures[0])
+	}
+
+	registerExtractedFields(input.ExecutionContext, results)
+
+	return &nodes.ExecutionOutput{
+		Result: results,
+		Metadata: map[string]interface{}{
+			"downloaded": len(results),
+			"errors":     failures,
+		},
+	}, nil
+}
+
+// selectorURLs reads absolute URLs from the attribute (default src, then href) of the matched elements
+func (e *DownloadExecutor) selectorURLs(input *nodes.ExecutionInput) ([]interface{}, error) {
+	selector := nodes.ResolveSelector(input.Params, "selector", "selector_type")
+	attribute := nodes.GetStringParam(input.Params, "attribute")
+
+	// Element properties (img.src, a.href) are already resolved against the page URL
+	raw, err := input.BrowserContext.Page.Locator(selector).EvaluateAll(`(elements, attribute) => elements
+		.map(el => {
+			const names = attribute ? [attribute] : ['src', 'href'];
+			for (const name of names) {
+				const prop = el[name];
+				if (typeof prop === 'string' && prop) return prop;
+				const value = el.getAttribute(name);
+				if (value) return new URL(value, document.baseURI).href;
+			}
+			return '';
+		})
+		.filter(Boolean)`, attribute)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read URLs from '%s': %w", selector, err)
+	}
+	urls, _ := raw.([]interface{})
+	return urls, nil
+}
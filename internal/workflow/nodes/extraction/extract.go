@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 
+	"github.com/uzzalhcse/crawlify/internal/expression"
 	extraction_engine "github.com/uzzalhcse/crawlify/internal/extraction"
 	"github.com/uzzalhcse/crawlify/internal/workflow/nodes"
 	"github.com/uzzalhcse/crawlify/pkg/models"
@@ -29,6 +31,11 @@ func (e *ExtractExecutor) Type() models.NodeType {
 
 // Validate validates the node parameters
 func (e *ExtractExecutor) Validate(params map[string]interface{}) error {
+	// Captured network responses are queried instead of the DOM
+	if nodes.GetStringParam(params, "capture") != "" {
+		return validateCaptureQueries(params)
+	}
+
 	// Either fields or selector must be present
 	fields := nodes.GetMapParam(params, "fields")
 	selector := nodes.GetStringParam(params, "selector")
@@ -36,11 +43,59 @@ func (e *ExtractExecutor) Validate(params map[string]interface{}) error {
 	if len(fields) == 0 && selector == "" {
 		return fmt.Errorf("either fields or selector must be provided for extract node")
 	}
+	if nodes.GetBoolParam(params, "emit_items") && (selector == "" || !nodes.GetBoolParam(params, "multiple")) {
+		return fmt.Errorf("emit_items needs a selector with multiple set")
+	}
+	return validateFieldConfig(params, "")
+}
+
+// validateFieldConfig checks selectors and transforms for a config, its fields and extractions
+func validateFieldConfig(params map[string]interface{}, path string) error {
+	if err := nodes.ValidateSelectorParam(params, "selector", "selector_type"); err != nil {
+		return fmt.Errorf("%s%w", path, err)
+	}
+	if err := extraction_engine.ValidateTransforms(params["transform"]); err != nil {
+		return fmt.Errorf("%s%w", path, err)
+	}
+	for _, key := range []string{"expression", "filter"} {
+		if source := nodes.GetStringParam(params, key); source != "" {
+			if err := expression.Validate(source); err != nil {
+				return fmt.Errorf("%s%s: %w", path, key, err)
+			}
+		}
+	}
+
+	for i, item := range nodes.GetArrayParam(params, "extractions") {
+		pair, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, key := range []string{"key_selector", "value_selector"} {
+			if err := nodes.ValidateSelectorParam(pair, key, "selector_type"); err != nil {
+				return fmt.Errorf("%sextractions[%d].%w", path, i, err)
+			}
+		}
+		if err := extraction_engine.ValidateTransforms(pair["transform"]); err != nil {
+			return fmt.Errorf("%sextractions[%d].%w", path, i, err)
+		}
+	}
+
+	for fieldName, fieldConfig := range nodes.GetMapParam(params, "fields") {
+		if fieldParams, ok := fieldConfig.(map[string]interface{}); ok {
+			if err := validateFieldConfig(fieldParams, path+"fields."+fieldName+"."); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
 // Execute performs data extraction
 func (e *ExtractExecutor) Execute(ctx context.Context, input *nodes.ExecutionInput) (*nodes.ExecutionOutput, error) {
+	if nodes.GetStringParam(input.Params, "capture") != "" {
+		return extractFromCapture(input)
+	}
+
 	engine := extraction_engine.NewExtractionEngine(input.BrowserContext.Page)
 
 	// Convert params to ExtractConfig
@@ -58,7 +113,14 @@ func (e *ExtractExecutor) Execute(ctx context.Context, input *nodes.ExecutionInp
 		// For field-based extraction, we extract individual fields and combine results
 		fieldResults := make(map[string]interface{})
 
+		computedFields := make(map[string]string)
 		for fieldName, fieldConfigRaw := range config.Fields {
+			// Computed fields are evaluated once the extracted fields are known
+			if source := computedFieldExpression(fieldConfigRaw); source != "" {
+				computedFields[fieldName] = source
+				continue
+			}
+
 			var fieldConfig extraction_engine.ExtractConfig
 
 			// Convert field config to ExtractConfig
@@ -94,6 +156,26 @@ func (e *ExtractExecutor) Execute(ctx context.Context, input *nodes.ExecutionInp
 			}
 		}
 
+		metadata := make(map[string]interface{})
+		if fieldErrors := computeFields(input, config.Fields, computedFields, fieldResults); len(fieldErrors) > 0 {
+			metadata["field_errors"] = fieldErrors
+		}
+
+		// Items rejected by the filter are returned but not saved
+		if source := nodes.GetStringParam(input.Params, "filter"); source != "" {
+			keep, err := evalFilter(source, itemEnv(input, config.Fields, fieldResults))
+			if err != nil {
+				return nil, err
+			}
+			if !keep {
+				metadata["filtered"] = true
+				return &nodes.ExecutionOutput{
+					Result:   fieldResults,
+					Metadata: metadata,
+				}, nil
+			}
+		}
+
 		// Store in execution context
 		extractedFieldNames := make([]string, 0, len(fieldResults))
 		for fieldName, fieldValue := range fieldResults {
@@ -104,7 +186,8 @@ func (e *ExtractExecutor) Execute(ctx context.Context, input *nodes.ExecutionInp
 		input.ExecutionContext.Set("__extracted_fields__", extractedFieldNames)
 
 		return &nodes.ExecutionOutput{
-			Result: fieldResults,
+			Result:   fieldResults,
+			Metadata: metadata,
 		}, nil
 	}
 
@@ -114,11 +197,57 @@ func (e *ExtractExecutor) Execute(ctx context.Context, input *nodes.ExecutionInp
 		return nil, fmt.Errorf("extraction failed: %w", err)
 	}
 
+	if source := nodes.GetStringParam(input.Params, "filter"); source != "" {
+		if result, err = filterResults(input, source, result); err != nil {
+			return nil, err
+		}
+	}
+
+	if nodes.GetBoolParam(input.Params, "emit_items") {
+		emitted := emitItems(input, result)
+		return &nodes.ExecutionOutput{
+			Result:   result,
+			Metadata: map[string]interface{}{"items_emitted": emitted},
+		}, nil
+	}
+
 	return &nodes.ExecutionOutput{
 		Result: result,
 	}, nil
 }
 
+// emitItems saves every element of a multiple extraction as an item of its own
+// item_key names the field that identifies an element, schema_name the schema of the items
+func emitItems(input *nodes.ExecutionInput, result interface{}) int {
+	list, ok := result.([]interface{})
+	if !ok {
+		return 0
+	}
+	schemaName := nodes.GetStringParam(input.Params, "schema_name")
+	keyField := nodes.GetStringParam(input.Params, "item_key")
+
+	emitted := 0
+	for _, element := range list {
+		data, ok := element.(map[string]interface{})
+		if !ok {
+			data = map[string]interface{}{"value": element}
+		}
+		var key string
+		if keyField != "" {
+			if value, exists := data[keyField]; exists && value != nil {
+				key = strings.TrimSpace(fmt.Sprint(value))
+			}
+		}
+		input.ExecutionContext.EmitItem(models.EmittedItem{
+			SchemaName: schemaName,
+			Key:        key,
+			Data:       data,
+		})
+		emitted++
+	}
+	return emitted
+}
+
 // getOutputFormat extracts output_format from field config
 func getOutputFormat(fieldConfigRaw interface{}) string {
 	if configMap, ok := fieldConfigRaw.(map[string]interface{}); ok {
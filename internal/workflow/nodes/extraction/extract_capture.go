@@ -0,0 +1,152 @@
+package extraction
+
+import (
+	"fmt"
+	"time"
+
+	extraction_engine "github.com/uzzalhcse/crawlify/internal/extraction"
+	"github.com/uzzalhcse/crawlify/internal/workflow/nodes"
+	"github.com/uzzalhcse/crawlify/pkg/models"
+)
+
+// validateCaptureQueries validates the query expressions of a capture-backed extract node
+func validateCaptureQueries(params map[string]interface{}) error {
+	defaultLang := nodes.GetStringParam(params, "query_lang")
+
+	fields := nodes.GetMapParam(params, "fields")
+	path := nodes.GetStringParam(params, "path")
+	if len(fields) == 0 && path == "" {
+		return fmt.Errorf("either fields or path must be provided when extracting from a capture")
+	}
+	if path != "" {
+		if err := extraction_engine.ValidateJSONQuery(path, defaultLang); err != nil {
+			return err
+		}
+	}
+
+	for fieldName, fieldConfig := range fields {
+		expr, lang, _, _ := captureFieldQuery(fieldConfig, defaultLang)
+		if expr == "" {
+			return fmt.Errorf("field '%s' requires a path", fieldName)
+		}
+		if err := extraction_engine.ValidateJSONQuery(expr, lang); err != nil {
+			return fmt.Errorf("field '%s': %w", fieldName, err)
+		}
+	}
+	return nil
+}
+
+// extractFromCapture queries captured network responses with JSONPath or JMESPath
+func extractFromCapture(input *nodes.ExecutionInput) (*nodes.ExecutionOutput, error) {
+	rule := nodes.GetStringParam(input.Params, "capture")
+	timeout := time.Duration(nodes.GetIntParam(input.Params, "timeout", 5000)) * time.Millisecond
+	defaultLang := nodes.GetStringParam(input.Params, "query_lang")
+
+	if input.BrowserContext == nil || !input.BrowserContext.IsIntercepting() {
+		return nil, fmt.Errorf("capture '%s' requested but no intercept node is active", rule)
+	}
+	input.BrowserContext.WaitForCapture(rule, timeout)
+
+	captures := nodes.GetCaptures(input, rule, timeout)
+	if len(captures) == 0 {
+		return nil, fmt.Errorf("no responses captured for '%s'", rule)
+	}
+
+	// Pick the bodies to query: the latest capture by default, a specific index, or all of them
+	var bodies []interface{}
+	if nodes.GetBoolParam(input.Params, "capture_all", false) {
+		for _, c := range captures {
+			bodies = append(bodies, c.Body)
+		}
+	} else {
+		index := nodes.GetIntParam(input.Params, "capture_index", -1)
+		if index < 0 {
+			index = len(captures) + index
+		}
+		if index < 0 || index >= len(captures) {
+			return nil, fmt.Errorf("capture_index out of range: %d captures for '%s'", len(captures), rule)
+		}
+		bodies = []interface{}{captures[index].Body}
+	}
+
+	query := func(expr, lang string) (interface{}, error) {
+		if len(bodies) == 1 {
+			return extraction_engine.QueryJSON(bodies[0], expr, lang)
+		}
+		var results []interface{}
+		for _, body := range bodies {
+			value, err := extraction_engine.QueryJSON(body, expr, lang)
+			if err != nil {
+				return nil, err
+			}
+			if list, ok := value.([]interface{}); ok {
+				results = append(results, list...)
+			} else if value != nil {
+				results = append(results, value)
+			}
+		}
+		return results, nil
+	}
+
+	fields := nodes.GetMapParam(input.Params, "fields")
+	if len(fields) == 0 {
+		result, err := query(nodes.GetStringParam(input.Params, "path"), defaultLang)
+		if err != nil {
+			return nil, fmt.Errorf("capture query failed: %w", err)
+		}
+		return &nodes.ExecutionOutput{Result: result}, nil
+	}
+
+	fieldResults := make(map[string]interface{})
+	for fieldName, fieldConfig := range fields {
+		expr, lang, defaultValue, hasDefault := captureFieldQuery(fieldConfig, defaultLang)
+		value, err := query(expr, lang)
+		if err != nil || value == nil {
+			if hasDefault {
+				fieldResults[fieldName] = defaultValue
+			}
+			continue
+		}
+		fieldResults[fieldName] = value
+	}
+
+	registerExtractedFields(input.ExecutionContext, fieldResults)
+
+	return &nodes.ExecutionOutput{
+		Result: fieldResults,
+	}, nil
+}
+
+// captureFieldQuery reads a field's query: either a bare expression or {path, query_lang, default}
+func captureFieldQuery(fieldConfig interface{}, defaultLang string) (string, string, interface{}, bool) {
+	switch cfg := fieldConfig.(type) {
+	case string:
+		return cfg, defaultLang, nil, false
+	case map[string]interface{}:
+		defaultValue, hasDefault := cfg["default"]
+		return nodes.GetStringParam(cfg, "path"), nodes.GetStringParam(cfg, "query_lang", defaultLang), defaultValue, hasDefault
+	}
+	return "", defaultLang, nil, false
+}
+
+// registerExtractedFields stores field values in the context and marks them for saving,
+// keeping fields registered by earlier extraction nodes
+func registerExtractedFields(execCtx *models.ExecutionContext, fieldResults map[string]interface{}) {
+	extractedFieldNames := make([]string, 0, len(fieldResults))
+	if existing, ok := execCtx.Get("__extracted_fields__"); ok {
+		if names, ok := existing.([]string); ok {
+			extractedFieldNames = append(extractedFieldNames, names...)
+		}
+	}
+	seen := make(map[string]bool, len(extractedFieldNames))
+	for _, name := range extractedFieldNames {
+		seen[name] = true
+	}
+	for fieldName, fieldValue := range fieldResults {
+		execCtx.Set(fieldName, fieldValue)
+		if !seen[fieldName] {
+			extractedFieldNames = append(extractedFieldNames, fieldName)
+		}
+	}
+	execCtx.Set("__extracted_fields__", extractedFieldNames)
+}
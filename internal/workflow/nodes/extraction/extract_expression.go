@@ -0,0 +1,99 @@
+package extraction
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/uzzalhcse/crawlify/internal/expression"
+	"github.com/uzzalhcse/crawlify/internal/workflow/nodes"
+)
+
+// computedFieldExpression returns the expression of a computed field ({"expression": "..."} without a selector)
+func computedFieldExpression(fieldConfig interface{}) string {
+	cfg, ok := fieldConfig.(map[string]interface{})
+	if !ok || nodes.GetStringParam(cfg, "selector") != "" {
+		return ""
+	}
+	return nodes.GetStringParam(cfg, "expression")
+}
+
+// computeFields evaluates computed fields in name order so later fields can use earlier ones
+// Failed expressions fall back to the field's default and are reported in the returned map
+func computeFields(input *nodes.ExecutionInput, declared map[string]interface{}, computed map[string]string, fieldResults map[string]interface{}) map[string]string {
+	names := make([]string, 0, len(computed))
+	for name := range computed {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fieldErrors := make(map[string]string)
+	for _, name := range names {
+		value, err := expression.Eval(computed[name], itemEnv(input, declared, fieldResults))
+		if err != nil {
+			fieldErrors[name] = err.Error()
+			if cfg, ok := declared[name].(map[string]interface{}); ok {
+				if defaultValue, ok := cfg["default"]; ok {
+					fieldResults[name] = defaultValue
+				}
+			}
+			continue
+		}
+		if value != nil {
+			fieldResults[name] = value
+		}
+	}
+	return fieldErrors
+}
+
+// itemEnv builds the expression environment for an item; declared fields that were not
+// extracted are bound to null so expressions can test them without reference errors
+func itemEnv(input *nodes.ExecutionInput, declared map[string]interface{}, fieldResults map[string]interface{}) expression.Env {
+	item := make(map[string]interface{}, len(declared))
+	for name := range declared {
+		item[name] = nil
+	}
+	for name, value := range fieldResults {
+		item[name] = value
+	}
+	return expression.NewEnv(item, input.ExecutionContext, input.URLItem)
+}
+
+// evalFilter evaluates a filter expression, reporting whether the item is kept
+func evalFilter(source string, env expression.Env) (bool, error) {
+	program, err := expression.Compile(source)
+	if err != nil {
+		return false, err
+	}
+	keep, err := program.EvalBool(env)
+	if err != nil {
+		return false, fmt.Errorf("filter failed: %w", err)
+	}
+	return keep, nil
+}
+
+// filterResults keeps the elements of a multiple extraction for which the filter is true
+// Object elements are bound as item (and their fields); other elements as value
+func filterResults(input *nodes.ExecutionInput, source string, result interface{}) (interface{}, error) {
+	list, ok := result.([]interface{})
+	if !ok {
+		return result, nil
+	}
+
+	kept := make([]interface{}, 0, len(list))
+	for _, element := range list {
+		var env expression.Env
+		if item, ok := element.(map[string]interface{}); ok {
+			env = expression.NewEnv(item, input.ExecutionContext, input.URLItem)
+		} else {
+			env = expression.NewEnv(nil, input.ExecutionContext, input.URLItem).With("value", element)
+		}
+		keep, err := evalFilter(source, env)
+		if err != nil {
+			return nil, err
+		}
+		if keep {
+			kept = append(kept, element)
+		}
+	}
+	return kept, nil
+}
@@ -0,0 +1,178 @@
+package extraction
+
+import (
+	"context"
+	"fmt"
+
+	extraction_engine "github.com/uzzalhcse/crawlify/internal/extraction"
+	"github.com/uzzalhcse/crawlify/internal/workflow/nodes"
+	"github.com/uzzalhcse/crawlify/pkg/models"
+)
+
+// ExtractStructuredExecutor handles JSON-LD, microdata, RDFa, OpenGraph and embedded state extraction
+type ExtractStructuredExecutor struct {
+	nodes.BaseNodeExecutor
+}
+
+// NewExtractStructuredExecutor creates a new extract_structured executor
+func NewExtractStructuredExecutor() *ExtractStructuredExecutor {
+	return &ExtractStructuredExecutor{
+		BaseNodeExecutor: nodes.BaseNodeExecutor{},
+	}
+}
+
+// Type returns the node type
+func (e *ExtractStructuredExecutor) Type() models.NodeType {
+	return models.NodeTypeExtractStructured
+}
+
+// Validate validates the node parameters
+func (e *ExtractStructuredExecutor) Validate(params map[string]interface{}) error {
+	for _, source := range toStringSlice(nodes.GetArrayParam(params, "sources")) {
+		if !isStructuredSource(source) {
+			return fmt.Errorf("unsupported structured data source '%s'", source)
+		}
+	}
+
+	for fieldName, fieldConfig := range nodes.GetMapParam(params, "fields") {
+		switch cfg := fieldConfig.(type) {
+		case string:
+			if cfg == "" {
+				return fmt.Errorf("field '%s' has an empty path", fieldName)
+			}
+		case map[string]interface{}:
+			if nodes.GetStringParam(cfg, "path") == "" && nodes.GetStringParam(cfg, "schema_type") == "" {
+				return fmt.Errorf("field '%s' requires a path or schema_type", fieldName)
+			}
+		default:
+			return fmt.Errorf("field '%s' must be a path string or an object", fieldName)
+		}
+	}
+	return nil
+}
+
+// Execute collects structured data from the page (or static HTML) and resolves fields
+func (e *ExtractStructuredExecutor) Execute(ctx context.Context, input *nodes.ExecutionInput) (*nodes.ExecutionOutput, error) {
+	opts := extraction_engine.StructuredOptions{
+		Sources:         toStringSlice(nodes.GetArrayParam(input.Params, "sources")),
+		EmbeddedGlobals: toStringSlice(nodes.GetArrayParam(input.Params, "embedded_globals")),
+	}
+
+	var data *extraction_engine.StructuredData
+	var err error
+	if html := nodes.GetStringParam(input.Params, "html"); html != "" {
+		data, err = extraction_engine.ParseStructuredData(html, opts)
+	} else {
+		if input.BrowserContext == nil || input.BrowserContext.Page == nil {
+			return nil, fmt.Errorf("extract_structured requires a page or an html param")
+		}
+		data, err = extraction_engine.NewExtractionEngine(input.BrowserContext.Page).ExtractStructured(opts)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract structured data: %w", err)
+	}
+
+	schemaTypes := toStringSlice(nodes.GetArrayParam(input.Params, "schema_types"))
+	fields := nodes.GetMapParam(input.Params, "fields")
+
+	// Without fields, return the whole (optionally type-filtered) tree
+	if len(fields) == 0 {
+		result := data.ToMap()
+		items := data.FindByType(schemaTypes...)
+		result["items"] = toItemSlice(items)
+		registerExtractedFields(input.ExecutionContext, result)
+		return &nodes.ExecutionOutput{
+			Result: result,
+			Metadata: map[string]interface{}{
+				"items_found": len(items),
+			},
+		}, nil
+	}
+
+	fieldResults := make(map[string]interface{})
+	for fieldName, fieldConfig := range fields {
+		if value, ok := resolveStructuredField(data, fieldConfig, schemaTypes); ok {
+			fieldResults[fieldName] = value
+		}
+	}
+
+	registerExtractedFields(input.ExecutionContext, fieldResults)
+
+	return &nodes.ExecutionOutput{
+		Result: fieldResults,
+	}, nil
+}
+
+// resolveStructuredField resolves a field config against the structured data
+// A string config is a path; object configs support path, schema_type, all and default
+func resolveStructuredField(data *extraction_engine.StructuredData, fieldConfig interface{}, schemaTypes []string) (interface{}, bool) {
+	var path, schemaType string
+	var all bool
+	var defaultValue interface{}
+	hasDefault := false
+
+	switch cfg := fieldConfig.(type) {
+	case string:
+		path = cfg
+	case map[string]interface{}:
+		path = nodes.GetStringParam(cfg, "path")
+		schemaType = nodes.GetStringParam(cfg, "schema_type")
+		all = nodes.GetBoolParam(cfg, "all")
+		defaultValue, hasDefault = cfg["default"]
+	default:
+		return nil, false
+	}
+
+	// Paths resolve against matching items when a schema type applies,
+	// otherwise against the full tree (e.g. "opengraph.title")
+	var matches []interface{}
+	types := schemaTypes
+	if schemaType != "" {
+		types = []string{schemaType}
+	}
+	if len(types) > 0 {
+		for _, item := range data.FindByType(types...) {
+			matches = append(matches, extraction_engine.LookupPath(item, path)...)
+		}
+	} else {
+		matches = extraction_engine.LookupPath(data.ToMap(), path)
+	}
+
+	if len(matches) == 0 {
+		return defaultValue, hasDefault
+	}
+	if all {
+		return matches, true
+	}
+	return matches[0], true
+}
+
+// isStructuredSource checks if a source name is supported
+func isStructuredSource(source string) bool {
+	for _, s := range extraction_engine.AllStructuredSources {
+		if s == source {
+			return true
+		}
+	}
+	return false
+}
+
+// toStringSlice converts an array param to strings, skipping non-string entries
+func toStringSlice(values []interface{}) []string {
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if s, ok := v.(string); ok && s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
+
+// toItemSlice converts structured items to a generic slice
+func toItemSlice(items []map[string]interface{}) []interface{} {
+	result := make([]interface{}, len(items))
+	for i, item := range items {
+		result[i] = item
+	}
+	return result
+}
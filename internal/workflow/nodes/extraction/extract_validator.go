@@ -47,7 +47,7 @@ func (e *ExtractExecutor) ValidateForMonitoring(ctx context.Context, input *node
 			continue
 		}
 
-		locator := page.Locator(selector)
+		locator := page.Locator(nodes.ResolveSelector(fieldConfig, "selector", "selector_type"))
 		count, _ := locator.Count()
 
 		if count == 0 {
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/uzzalhcse/crawlify/internal/expression"
 	"github.com/uzzalhcse/crawlify/internal/workflow/nodes"
 	"github.com/uzzalhcse/crawlify/pkg/models"
 )
@@ -30,8 +31,19 @@ func (e *ConditionalExecutor) Type() models.NodeType {
 // Validate validates the conditional configuration
 func (e *ConditionalExecutor) Validate(params map[string]interface{}) error {
 	condition := nodes.GetStringParam(params, "condition")
-	if condition == "" {
-		return fmt.Errorf("condition is required")
+	source := nodes.GetStringParam(params, "expression")
+	switch {
+	case source != "":
+		if condition != "" && condition != "expression" {
+			return fmt.Errorf("use either a condition type or an expression, not both")
+		}
+		if err := expression.Validate(source); err != nil {
+			return err
+		}
+	case condition == "":
+		return fmt.Errorf("condition or expression is required")
+	case condition == "expression":
+		return fmt.Errorf("expression is required for expression condition")
 	}
 
 	// Must have either 'then' or both 'then' and 'else'
@@ -46,6 +58,9 @@ func (e *ConditionalExecutor) Validate(params map[string]interface{}) error {
 // Execute evaluates condition and executes appropriate branch
 func (e *ConditionalExecutor) Execute(ctx context.Context, input *nodes.ExecutionInput) (*nodes.ExecutionOutput, error) {
 	condition := nodes.GetStringParam(input.Params, "condition")
+	if condition == "" && nodes.GetStringParam(input.Params, "expression") != "" {
+		condition = "expression"
+	}
 
 	// Evaluate condition
 	conditionMet, err := e.evaluateCondition(condition, input)
@@ -93,6 +108,8 @@ func (e *ConditionalExecutor) Execute(ctx context.Context, input *nodes.Executio
 		Params:           branchParams,
 		URLItem:          input.URLItem,
 		ExecutionID:      input.ExecutionID,
+		Assets:           input.Assets,
+		EnqueueURLs:      input.EnqueueURLs,
 	}
 
 	output, err := executor.Execute(ctx, branchInput)
@@ -156,11 +173,32 @@ func (e *ConditionalExecutor) evaluateCondition(condition string, input *nodes.E
 		actualValue, _ := input.ExecutionContext.Get(key)
 		return actualValue == expectedValue, nil
 
+	case condition == "expression":
+		// Sandboxed JavaScript over extracted fields, context variables and URL metadata
+		program, err := expression.Compile(nodes.GetStringParam(input.Params, "expression"))
+		if err != nil {
+			return false, err
+		}
+		return program.EvalBool(conditionEnv(input))
+
 	default:
 		return false, fmt.Errorf("unknown condition type: %s", condition)
 	}
 }
 
+// conditionEnv exposes the fields extracted so far as the current item
+func conditionEnv(input *nodes.ExecutionInput) expression.Env {
+	item := make(map[string]interface{})
+	if names, ok := input.ExecutionContext.Get("__extracted_fields__"); ok {
+		if fieldNames, ok := names.([]string); ok {
+			for _, name := range fieldNames {
+				item[name], _ = input.ExecutionContext.Get(name)
+			}
+		}
+	}
+	return expression.NewEnv(item, input.ExecutionContext, input.URLItem)
+}
+
 func containsString(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(substr) == 0 ||
 		(len(s) > 0 && len(substr) > 0 && findSubstring(s, substr)))
@@ -0,0 +1,157 @@
+package interaction
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/uzzalhcse/crawlify/internal/browser"
+	"github.com/uzzalhcse/crawlify/internal/workflow/nodes"
+	"github.com/uzzalhcse/crawlify/pkg/models"
+)
+
+// InterceptExecutor registers network response capture and request blocking
+type InterceptExecutor struct {
+	nodes.BaseNodeExecutor
+}
+
+// NewInterceptExecutor creates a new intercept executor
+func NewInterceptExecutor() *InterceptExecutor {
+	return &InterceptExecutor{
+		BaseNodeExecutor: nodes.BaseNodeExecutor{},
+	}
+}
+
+// Type returns the node type
+func (e *InterceptExecutor) Type() models.NodeType {
+	return models.NodeTypeIntercept
+}
+
+// Validate validates the node parameters
+func (e *InterceptExecutor) Validate(params map[string]interface{}) error {
+	cfg, err := ParseInterceptConfig("", params)
+	if err != nil {
+		return err
+	}
+	if len(cfg.Rules) == 0 && len(cfg.BlockResourceTypes) == 0 && len(cfg.BlockDomains) == 0 && !cfg.BlockTrackers {
+		return fmt.Errorf("intercept node requires rules, url_pattern or a blocking option")
+	}
+	for _, rule := range cfg.Rules {
+		if _, err := browser.CompileURLPattern(rule.URLPattern); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Execute starts interception (if the executor did not already start it before navigation)
+// and optionally waits for the first matching responses
+func (e *InterceptExecutor) Execute(ctx context.Context, input *nodes.ExecutionInput) (*nodes.ExecutionOutput, error) {
+	cfg, err := ParseInterceptConfig(input.NodeID, input.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	if !input.BrowserContext.IsIntercepting() {
+		if err := input.BrowserContext.StartIntercept(cfg); err != nil {
+			return nil, fmt.Errorf("failed to start network interception: %w", err)
+		}
+	}
+
+	timeout := time.Duration(nodes.GetIntParam(input.Params, "timeout", 10000)) * time.Millisecond
+	missing := []string{}
+	if nodes.GetBoolParam(input.Params, "wait_for_capture", false) {
+		for _, rule := range cfg.Rules {
+			if !input.BrowserContext.WaitForCapture(rule.Name, timeout) {
+				missing = append(missing, rule.Name)
+			}
+		}
+		if len(missing) > 0 && nodes.GetBoolParam(input.Params, "required", false) {
+			return nil, fmt.Errorf("no responses captured for rules: %v", missing)
+		}
+	}
+
+	captured := make(map[string]interface{})
+	for _, c := range nodes.GetCaptures(input, "", timeout) {
+		count, _ := captured[c.Rule].(int)
+		captured[c.Rule] = count + 1
+	}
+
+	return &nodes.ExecutionOutput{
+		Result: map[string]interface{}{
+			"_rules":    len(cfg.Rules),
+			"_captured": captured,
+			"_blocked":  input.BrowserContext.BlockedRequests(),
+			"_missing":  missing,
+		},
+	}, nil
+}
+
+// ParseInterceptConfig builds an intercept config from the params of node nodeID
+// A single rule may be given inline with name/url_pattern/methods/resource_types. Unnamed rules
+// are named <node ID>_capture_<n> so that they do not collide with another intercept node's.
+func ParseInterceptConfig(nodeID string, params map[string]interface{}) (browser.InterceptConfig, error) {
+	cfg := browser.InterceptConfig{
+		BlockResourceTypes: stringSliceParam(params, "block_resource_types"),
+		BlockDomains:       stringSliceParam(params, "block_domains"),
+		BlockTrackers:      nodes.GetBoolParam(params, "block_trackers", false),
+		MaxBodyBytes:       nodes.GetIntParam(params, "max_body_bytes", 0),
+	}
+
+	if pattern := nodes.GetStringParam(params, "url_pattern"); pattern != "" {
+		cfg.Rules = append(cfg.Rules, parseInterceptRule(params))
+	}
+	for i, raw := range nodes.GetArrayParam(params, "rules") {
+		ruleParams, ok := raw.(map[string]interface{})
+		if !ok {
+			return cfg, fmt.Errorf("rules[%d] must be an object", i)
+		}
+		rule := parseInterceptRule(ruleParams)
+		if rule.URLPattern == "" && len(rule.Methods) == 0 && len(rule.ResourceTypes) == 0 {
+			return cfg, fmt.Errorf("rules[%d] needs url_pattern, methods or resource_types", i)
+		}
+		cfg.Rules = append(cfg.Rules, rule)
+	}
+
+	seen := make(map[string]bool)
+	for i := range cfg.Rules {
+		if cfg.Rules[i].Name == "" {
+			cfg.Rules[i].Name = fmt.Sprintf("capture_%d", i)
+			if nodeID != "" {
+				cfg.Rules[i].Name = nodeID + "_" + cfg.Rules[i].Name
+			}
+		}
+		if seen[cfg.Rules[i].Name] {
+			return cfg, fmt.Errorf("duplicate intercept rule name '%s'", cfg.Rules[i].Name)
+		}
+		seen[cfg.Rules[i].Name] = true
+	}
+
+	return cfg, nil
+}
+
+// parseInterceptRule reads a single rule from params
+func parseInterceptRule(params map[string]interface{}) browser.InterceptRule {
+	methods := stringSliceParam(params, "methods")
+	if method := nodes.GetStringParam(params, "method"); method != "" {
+		methods = append(methods, method)
+	}
+	return browser.InterceptRule{
+		Name:          nodes.GetStringParam(params, "name"),
+		URLPattern:    nodes.GetStringParam(params, "url_pattern"),
+		Methods:       methods,
+		ResourceTypes: stringSliceParam(params, "resource_types"),
+		MaxCaptures:   nodes.GetIntParam(params, "max_captures", 0),
+	}
+}
+
+// stringSliceParam reads a string array param
+func stringSliceParam(params map[string]interface{}, key string) []string {
+	var result []string
+	for _, v := range nodes.GetArrayParam(params, key) {
+		if s, ok := v.(string); ok && s != "" {
+			result = append(result, s)
+		}
+	}
+	return result
+}
@@ -87,6 +87,8 @@ func (e *SequenceExecutor) Execute(ctx context.Context, input *nodes.ExecutionIn
 			Params:           stepParams,
 			URLItem:          input.URLItem,
 			ExecutionID:      input.ExecutionID,
+			Assets:           input.Assets,
+			EnqueueURLs:      input.EnqueueURLs,
 		}
 
 		// Execute step
@@ -3,25 +3,40 @@ package nodes
 import (
 	"context"
 	"fmt"
-	"plugin"
+	"time"
 
+	"github.com/Masterminds/semver/v3"
 	"github.com/uzzalhcse/crawlify/pkg/models"
 	"github.com/uzzalhcse/crawlify/pkg/plugins"
 	"go.uber.org/zap"
 )
 
+// PluginResolver returns the instance of the plugin installed under a slug for an execution and node config
+// It is implemented by the plugin loader, which this package cannot import
+type PluginResolver interface {
+	ResolvePlugin(ctx context.Context, executionID, slug, constraint string, config map[string]interface{}) (PluginRun, error)
+}
+
+// PluginRun is a resolved plugin instance
+type PluginRun interface {
+	Instance() interface{} // DiscoveryPlugin or ExtractionPlugin
+	Record(start time.Time, discovered, extracted int, err error)
+}
+
 // PluginNodeExecutor executes plugin-based nodes
 type PluginNodeExecutor struct {
-	logger *zap.Logger
+	logger   *zap.Logger
+	resolver PluginResolver
 }
 
 // NewPluginNodeExecutor creates a new plugin node executor
-func NewPluginNodeExecutor(logger *zap.Logger) *PluginNodeExecutor {
+func NewPluginNodeExecutor(logger *zap.Logger, resolver PluginResolver) *PluginNodeExecutor {
 	if logger == nil {
 		logger = zap.NewNop()
 	}
 	return &PluginNodeExecutor{
-		logger: logger,
+		logger:   logger,
+		resolver: resolver,
 	}
 }
 
@@ -39,6 +54,16 @@ func (e *PluginNodeExecutor) Validate(params map[string]interface{}) error {
 	if slug, ok := params["plugin_slug"].(string); !ok || slug == "" {
 		return fmt.Errorf("plugin_slug must be a non-empty string")
 	}
+	// plugin_version is an optional semver constraint such as "1.4.2", "^1.4" or ">=1.2, <2"
+	if raw, ok := params["plugin_version"]; ok {
+		constraint, ok := raw.(string)
+		if !ok {
+			return fmt.Errorf("plugin_version must be a string")
+		}
+		if _, err := semver.NewConstraint(constraint); err != nil {
+			return fmt.Errorf("invalid plugin_version '%s': %w", constraint, err)
+		}
+	}
 	return nil
 }
 
@@ -61,148 +86,177 @@ func (e *PluginNodeExecutor) Execute(ctx context.Context, input *ExecutionInput)
 		zap.String("url", input.URLItem.URL),
 	)
 
-	// Load plugin by slug - construct the path from the slug
-	pluginPath := fmt.Sprintf("./plugins/%s.so", pluginSlug)
-
-	// Load the shared object
-	plug, err := plugin.Open(pluginPath)
+	if e.resolver == nil {
+		return nil, fmt.Errorf("plugin %s cannot be loaded: no plugin resolver configured", pluginSlug)
+	}
+	constraint, _ := input.Params["plugin_version"].(string)
+	run, err := e.resolver.ResolvePlugin(ctx, input.ExecutionID, pluginSlug, constraint, pluginConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load plugin: %w", err)
+		return nil, err
 	}
 
-	// Look for the plugin constructor functions
-	// Try extraction plugin first
-	if newExtractionFunc, err := plug.Lookup("NewExtractionPlugin"); err == nil {
-		// Call the constructor
-		constructor, ok := newExtractionFunc.(func(*zap.Logger) (plugins.ExtractionPlugin, error))
-		if !ok {
-			return nil, fmt.Errorf("NewExtractionPlugin has invalid signature")
+	start := time.Now()
+	switch p := run.Instance().(type) {
+	case plugins.ExtractionPlugin:
+		output, extracted, err := e.executeExtraction(ctx, input, pluginSlug, p, pluginConfig)
+		run.Record(start, 0, extracted, err)
+		return output, err
+	case plugins.DiscoveryPlugin:
+		output, err := e.executeDiscovery(ctx, input, pluginSlug, p, pluginConfig)
+		discovered := 0
+		if err == nil {
+			discovered = len(output.DiscoveredURLs)
 		}
+		run.Record(start, discovered, 0, err)
+		return output, err
+	default:
+		return nil, fmt.Errorf("plugin %s implements neither DiscoveryPlugin nor ExtractionPlugin", pluginSlug)
+	}
+}
 
-		extractionPlugin, err := constructor(e.logger)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create extraction plugin: %w", err)
-		}
-		// Create extraction input
-		extractionInput := &plugins.ExtractionInput{
-			BrowserContext:   input.BrowserContext,
-			URL:              input.URLItem.URL,
-			URLItem:          input.URLItem,
-			ExecutionContext: input.ExecutionContext,
-			Config:           pluginConfig,
-			ExecutionID:      input.ExecutionID,
-		}
+// executeExtraction runs an extraction plugin and stores its data for collectExtractedData
+// It returns the number of items extracted: the page's own and the emitted ones
+func (e *PluginNodeExecutor) executeExtraction(ctx context.Context, input *ExecutionInput, pluginSlug string, extractionPlugin plugins.ExtractionPlugin, pluginConfig map[string]interface{}) (*ExecutionOutput, int, error) {
+	// Create extraction input
+	extractionInput := &plugins.ExtractionInput{
+		BrowserContext:   input.BrowserContext,
+		Browser:          plugins.NewLocalBrowser(input.BrowserContext),
+		URL:              input.URLItem.URL,
+		URLItem:          input.URLItem,
+		ExecutionContext: input.ExecutionContext,
+		Config:           pluginConfig,
+		ExecutionID:      input.ExecutionID,
+		HTTP:             input.HTTP,
+	}
+	extractionInput.WorkflowStore, extractionInput.ExecutionStore = input.PluginStores(pluginSlug)
 
-		// Execute extraction
-		e.logger.Debug("Calling plugin Extract method",
+	// Execute extraction
+	e.logger.Debug("Calling plugin Extract method",
+		zap.String("plugin_slug", pluginSlug),
+		zap.String("url", input.URLItem.URL),
+	)
+
+	output, err := extractionPlugin.Extract(ctx, extractionInput)
+	if err != nil {
+		e.logger.Error("Plugin extraction failed",
 			zap.String("plugin_slug", pluginSlug),
-			zap.String("url", input.URLItem.URL),
+			zap.Error(err),
 		)
+		return nil, 0, fmt.Errorf("plugin extraction failed: %w", err)
+	}
 
-		output, err := extractionPlugin.Extract(ctx, extractionInput)
-		if err != nil {
-			e.logger.Error("Plugin extraction failed",
-				zap.String("plugin_slug", pluginSlug),
-				zap.Error(err),
-			)
-			return nil, fmt.Errorf("plugin extraction failed: %w", err)
-		}
+	e.logger.Info("Plugin extraction completed",
+		zap.String("plugin_slug", pluginSlug),
+		zap.String("schema", output.SchemaName),
+		zap.Int("data_fields", len(output.Data)),
+		zap.Any("data_keys", func() []string {
+			keys := make([]string, 0, len(output.Data))
+			for k := range output.Data {
+				keys = append(keys, k)
+			}
+			return keys
+		}()),
+	)
 
-		e.logger.Info("Plugin extraction completed",
-			zap.String("plugin_slug", pluginSlug),
-			zap.String("schema", output.SchemaName),
-			zap.Int("data_fields", len(output.Data)),
-			zap.Any("data_keys", func() []string {
-				keys := make([]string, 0, len(output.Data))
-				for k := range output.Data {
-					keys = append(keys, k)
-				}
-				return keys
-			}()),
+	// Store extracted data in execution context and set marker
+	// This is critical - the collectExtractedData function needs this marker
+	extractedFieldNames := make([]string, 0, len(output.Data))
+	for schemaKey, data := range output.Data {
+		e.logger.Debug("Storing plugin data in context",
+			zap.String("schema_key", schemaKey),
+			zap.String("data_type", fmt.Sprintf("%T", data)),
 		)
+		// Store the data under the schema key
+		input.ExecutionContext.Set(schemaKey, data)
+		extractedFieldNames = append(extractedFieldNames, schemaKey)
+	}
 
-		// Store extracted data in execution context and set marker
-		// This is critical - the collectExtractedData function needs this marker
-		extractedFieldNames := make([]string, 0, len(output.Data))
-		for schemaKey, data := range output.Data {
-			e.logger.Debug("Storing plugin data in context",
-				zap.String("schema_key", schemaKey),
-				zap.String("data_type", fmt.Sprintf("%T", data)),
-			)
-			// Store the data under the schema key
-			input.ExecutionContext.Set(schemaKey, data)
-			extractedFieldNames = append(extractedFieldNames, schemaKey)
+	// Emitted items are saved as rows of their own, linked to this node execution
+	for _, item := range output.Items {
+		schemaName := item.SchemaName
+		if schemaName == "" {
+			schemaName = output.SchemaName
 		}
+		input.ExecutionContext.EmitItem(models.EmittedItem{
+			SchemaName: schemaName,
+			Key:        item.Key,
+			Data:       item.Data,
+		})
+	}
 
-		// CRITICAL: Set the marker that tells collectExtractedData which fields to save
+	// CRITICAL: Set the marker that tells collectExtractedData which fields to save
+	// A plugin that only emits items has no page item to save
+	if len(extractedFieldNames) > 0 {
 		input.ExecutionContext.Set("__extracted_fields__", extractedFieldNames)
+	}
 
-		e.logger.Debug("Set extracted fields marker",
-			zap.Strings("field_names", extractedFieldNames),
-		)
+	e.logger.Debug("Set extracted fields marker",
+		zap.Strings("field_names", extractedFieldNames),
+	)
 
-		// Return result for node execution tracking
-		var resultData interface{}
-		if len(output.Data) > 0 {
-			for _, data := range output.Data {
-				resultData = data
-				break
-			}
-		} else {
-			e.logger.Warn("Plugin returned no data",
-				zap.String("plugin_slug", pluginSlug),
-			)
+	// Return result for node execution tracking
+	var resultData interface{}
+	if len(output.Data) > 0 {
+		for _, data := range output.Data {
+			resultData = data
+			break
 		}
-
-		e.logger.Debug("Returning plugin execution output",
+	} else if len(output.Items) > 0 {
+		items := make([]interface{}, 0, len(output.Items))
+		for _, item := range output.Items {
+			items = append(items, item.Data)
+		}
+		resultData = items
+	} else {
+		e.logger.Warn("Plugin returned no data",
 			zap.String("plugin_slug", pluginSlug),
-			zap.Bool("has_result", resultData != nil),
-			zap.Int("discovered_urls", len(output.DiscoveredURLs)),
 		)
-
-		return &ExecutionOutput{
-			Result:         resultData,
-			DiscoveredURLs: output.DiscoveredURLs,
-		}, nil
 	}
 
-	// Try discovery plugin
-	if newDiscoveryFunc, err := plug.Lookup("NewDiscoveryPlugin"); err == nil {
-		constructor, ok := newDiscoveryFunc.(func(*zap.Logger) (plugins.DiscoveryPlugin, error))
-		if !ok {
-			return nil, fmt.Errorf("NewDiscoveryPlugin has invalid signature")
-		}
-
-		discoveryPlugin, err := constructor(e.logger)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create discovery plugin: %w", err)
-		}
-		// Create discovery input
-		discoveryInput := &plugins.DiscoveryInput{
-			BrowserContext:   input.BrowserContext,
-			URL:              input.URLItem.URL,
-			URLItem:          input.URLItem,
-			ExecutionContext: input.ExecutionContext,
-			Config:           pluginConfig,
-			ExecutionID:      input.ExecutionID,
-		}
+	e.logger.Debug("Returning plugin execution output",
+		zap.String("plugin_slug", pluginSlug),
+		zap.Bool("has_result", resultData != nil),
+		zap.Int("discovered_urls", len(output.DiscoveredURLs)),
+	)
 
-		// Execute discovery
-		output, err := discoveryPlugin.Discover(ctx, discoveryInput)
-		if err != nil {
-			return nil, fmt.Errorf("plugin discovery failed: %w", err)
-		}
+	extracted := len(output.Items)
+	if len(output.Data) > 0 {
+		extracted++
+	}
+	return &ExecutionOutput{
+		Result:         resultData,
+		DiscoveredURLs: output.DiscoveredURLs,
+	}, extracted, nil
+}
 
-		e.logger.Info("Plugin discovery completed",
-			zap.String("plugin_slug", pluginSlug),
-			zap.Int("urls_discovered", len(output.DiscoveredURLs)),
-		)
+// executeDiscovery runs a discovery plugin
+func (e *PluginNodeExecutor) executeDiscovery(ctx context.Context, input *ExecutionInput, pluginSlug string, discoveryPlugin plugins.DiscoveryPlugin, pluginConfig map[string]interface{}) (*ExecutionOutput, error) {
+	// Create discovery input
+	discoveryInput := &plugins.DiscoveryInput{
+		BrowserContext:   input.BrowserContext,
+		Browser:          plugins.NewLocalBrowser(input.BrowserContext),
+		URL:              input.URLItem.URL,
+		URLItem:          input.URLItem,
+		ExecutionContext: input.ExecutionContext,
+		Config:           pluginConfig,
+		ExecutionID:      input.ExecutionID,
+		HTTP:             input.HTTP,
+	}
+	discoveryInput.WorkflowStore, discoveryInput.ExecutionStore = input.PluginStores(pluginSlug)
 
-		return &ExecutionOutput{
-			Result:         output.Metadata,
-			DiscoveredURLs: output.DiscoveredURLs,
-		}, nil
+	// Execute discovery
+	output, err := discoveryPlugin.Discover(ctx, discoveryInput)
+	if err != nil {
+		return nil, fmt.Errorf("plugin discovery failed: %w", err)
 	}
 
-	return nil, fmt.Errorf("plugin does not export NewDiscoveryPlugin or NewExtractionPlugin function")
+	e.logger.Info("Plugin discovery completed",
+		zap.String("plugin_slug", pluginSlug),
+		zap.Int("urls_discovered", len(output.DiscoveredURLs)),
+	)
+
+	return &ExecutionOutput{
+		Result:         output.Metadata,
+		DiscoveredURLs: output.DiscoveredURLs,
+	}, nil
 }
@@ -0,0 +1,44 @@
+package nodes
+
+import (
+	"fmt"
+
+	"github.com/uzzalhcse/crawlify/internal/extraction"
+)
+
+// ValidateSelectorParam validates a selector param against its selector type param
+// Missing selectors are not an error; callers decide whether a selector is required
+func ValidateSelectorParam(params map[string]interface{}, selectorKey, typeKey string) error {
+	selector := GetStringParam(params, selectorKey)
+	if selector == "" {
+		if _, err := extraction.NormalizeSelectorType(GetStringParam(params, typeKey)); err != nil {
+			return fmt.Errorf("%s: %w", typeKey, err)
+		}
+		return nil
+	}
+
+	if err := extraction.ValidateSelector(selector, GetStringParam(params, typeKey)); err != nil {
+		return fmt.Errorf("%s: %w", selectorKey, err)
+	}
+	return nil
+}
+
+// ValidateStaticSelectorParam is ValidateSelectorParam for selectors evaluated on the page
+// HTML rather than in the browser, such as link selectors, where CSS must be standard CSS
+func ValidateStaticSelectorParam(params map[string]interface{}, selectorKey, typeKey string) error {
+	selector := GetStringParam(params, selectorKey)
+	if selector == "" {
+		return ValidateSelectorParam(params, selectorKey, typeKey)
+	}
+
+	if err := extraction.ValidateStaticSelector(selector, GetStringParam(params, typeKey)); err != nil {
+		return fmt.Errorf("%s: %w", selectorKey, err)
+	}
+	return nil
+}
+
+// ResolveSelector returns the Playwright selector for a selector param and its type param
+func ResolveSelector(params map[string]interface{}, selectorKey, typeKey string, defaultSelector ...string) string {
+	selector := GetStringParam(params, selectorKey, defaultSelector...)
+	return extraction.PlaywrightSelector(selector, GetStringParam(params, typeKey))
+}
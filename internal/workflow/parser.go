@@ -3,12 +3,30 @@ package workflow
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"os"
+	"time"
 
+	"github.com/uzzalhcse/crawlify/internal/expression"
+	"github.com/uzzalhcse/crawlify/internal/schema"
+	"github.com/uzzalhcse/crawlify/internal/sitemap"
 	"github.com/uzzalhcse/crawlify/pkg/models"
 	"gopkg.in/yaml.v3"
 )
 
+// expressionParams are the params each node type evaluates as expressions
+var expressionParams = map[models.NodeType][]string{
+	models.NodeTypeExtract:     {"expression", "filter"},
+	models.NodeTypeConditional: {"expression"},
+	models.NodeTypePaginate:    {"total_expression", "pages_expression", "cursor_expression", "empty_expression"},
+}
+
+// ExpressionParams returns the params a node type evaluates as expressions
+// They and URL params are the only params whose {{ }} templates are expanded.
+func ExpressionParams(nodeType models.NodeType) []string {
+	return expressionParams[nodeType]
+}
+
 type Parser struct{}
 
 func NewParser() *Parser {
@@ -44,8 +62,14 @@ func (p *Parser) ParseFromBytes(data []byte, filename string) (*models.WorkflowC
 
 // Validate validates a workflow configuration
 func (p *Parser) Validate(config *models.WorkflowConfig) error {
-	if len(config.StartURLs) == 0 {
-		return fmt.Errorf("workflow must have at least one start URL")
+	if len(config.StartURLs) == 0 && len(config.StartSources) == 0 {
+		return fmt.Errorf("workflow must have at least one start URL or start source")
+	}
+
+	for i, source := range config.StartSources {
+		if err := validateStartSource(source); err != nil {
+			return fmt.Errorf("start source %d: %w", i, err)
+		}
 	}
 
 	if len(config.Phases) == 0 {
@@ -65,6 +89,39 @@ func (p *Parser) Validate(config *models.WorkflowConfig) error {
 		if err := p.validateNodes(phase.Nodes); err != nil {
 			return fmt.Errorf("phase '%s' validation failed: %w", phase.ID, err)
 		}
+
+		if err := schema.Validate(phase.Schema); err != nil {
+			return fmt.Errorf("phase '%s' schema: %w", phase.ID, err)
+		}
+
+		if phase.URLFilter != nil && phase.URLFilter.Expression != "" {
+			if err := expression.Validate(phase.URLFilter.Expression); err != nil {
+				return fmt.Errorf("phase '%s' url_filter: %w", phase.ID, err)
+			}
+		}
+		if phase.Transition != nil && phase.Transition.Condition == "expression" {
+			source, _ := phase.Transition.Params["expression"].(string)
+			if err := expression.Validate(source); err != nil {
+				return fmt.Errorf("phase '%s' transition: %w", phase.ID, err)
+			}
+		}
+	}
+
+	if err := schema.Validate(config.Schema); err != nil {
+		return fmt.Errorf("workflow schema: %w", err)
+	}
+
+	if config.Dedup != nil {
+		if err := validateDedup(config.Dedup); err != nil {
+			return fmt.Errorf("dedup: %w", err)
+		}
+	}
+
+	// Validate scope patterns up front so a bad regex fails at save time
+	if config.Scope != nil {
+		if _, err := NewScopeEnforcer(config, 0, time.Now()); err != nil {
+			return err
+		}
 	}
 
 	// Check for circular dependencies across all phases
@@ -75,6 +132,45 @@ func (p *Parser) Validate(config *models.WorkflowConfig) error {
 	return nil
 }
 
+// validateStartSource validates a sitemap or feed start source
+func validateStartSource(source models.StartSource) error {
+	if source.Type != models.StartSourceSitemap && source.Type != models.StartSourceFeed {
+		return fmt.Errorf("unknown type '%s' (expected %s or %s)", source.Type, models.StartSourceSitemap, models.StartSourceFeed)
+	}
+	if len(source.URLs) == 0 {
+		return fmt.Errorf("at least one URL is required")
+	}
+	for _, rawURL := range source.URLs {
+		if u, err := url.Parse(rawURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("'%s' is not an http(s) URL", rawURL)
+		}
+	}
+	if _, err := sitemap.CompilePatterns(source.Include); err != nil {
+		return fmt.Errorf("include: %w", err)
+	}
+	if _, err := sitemap.CompilePatterns(source.Exclude); err != nil {
+		return fmt.Errorf("exclude: %w", err)
+	}
+	if source.MaxURLs < 0 {
+		return fmt.Errorf("max_urls must not be negative")
+	}
+	return nil
+}
+
+// validateDedup rejects canonicalisation options on a workflow that has not opted into canonical dedup
+func validateDedup(dedup *models.DedupConfig) error {
+	switch dedup.Strategy {
+	case "", models.DedupStrategyExact:
+		if len(dedup.StripParams) > 0 || dedup.KeepDefaultParams || dedup.KeepFragment || dedup.StripTrailingSlash {
+			return fmt.Errorf("strip_params, keep_default_params, keep_fragment and strip_trailing_slash require strategy '%s'", models.DedupStrategyCanonical)
+		}
+	case models.DedupStrategyCanonical:
+	default:
+		return fmt.Errorf("unknown strategy '%s' (expected %s or %s)", dedup.Strategy, models.DedupStrategyExact, models.DedupStrategyCanonical)
+	}
+	return nil
+}
+
 // validateNodes validates a list of nodes
 func (p *Parser) validateNodes(nodes []models.Node) error {
 	nodeIDs := make(map[string]bool)
@@ -98,6 +194,11 @@ func (p *Parser) validateNodes(nodes []models.Node) error {
 		if !p.isValidNodeType(node.Type) {
 			return fmt.Errorf("node '%s' has invalid type: %s", node.ID, node.Type)
 		}
+
+		// Compile expressions and templates so syntax errors surface before the workflow runs
+		if err := expression.ValidateParams(node.Params, ExpressionParams(node.Type)...); err != nil {
+			return fmt.Errorf("node '%s': %w", node.ID, err)
+		}
 	}
 
 	// Validate dependencies exist
@@ -121,6 +222,12 @@ func (p *Parser) isValidNodeType(nodeType models.NodeType) bool {
 		models.NodeTypeWait,
 		models.NodeTypeExtractLinks,
 		models.NodeTypeExtract,
+		models.NodeTypeExtractStructured,
+		models.NodeTypeDownload,
+		models.NodeTypeIntercept,
+		models.NodeTypeInfiniteScroll,
+		models.NodeTypeSitemap,
+		models.NodeTypeConditional,
 		models.NodeTypeInput,
 		models.NodeTypePlugin, // NEW: Plugin node support
 	}
@@ -95,11 +95,23 @@ func (r *NodeRegistry) RegisterDefaultNodes() error {
 	if err := r.Register(discovery.NewPaginateExecutor()); err != nil {
 		return err
 	}
+	if err := r.Register(discovery.NewInfiniteScrollExecutor()); err != nil {
+		return err
+	}
+	if err := r.Register(discovery.NewSitemapExecutor()); err != nil {
+		return err
+	}
 
 	// Extraction nodes
 	if err := r.Register(extraction.NewExtractExecutor()); err != nil {
 		return err
 	}
+	if err := r.Register(extraction.NewExtractStructuredExecutor()); err != nil {
+		return err
+	}
+	if err := r.Register(extraction.NewDownloadExecutor()); err != nil {
+		return err
+	}
 
 	// Interaction nodes
 	if err := r.Register(interaction.NewClickExecutor()); err != nil {
@@ -108,15 +120,31 @@ func (r *NodeRegistry) RegisterDefaultNodes() error {
 	if err := r.Register(interaction.NewWaitExecutor()); err != nil {
 		return err
 	}
+	if err := r.Register(interaction.NewInterceptExecutor()); err != nil {
+		return err
+	}
+	if err := r.Register(interaction.NewConditionalExecutor(r)); err != nil {
+		return err
+	}
 
 	// Plugin executor
-	if err := r.Register(nodes.NewPluginNodeExecutor(r.logger)); err != nil {
+	if err := r.Register(nodes.NewPluginNodeExecutor(r.logger, r.pluginLoader)); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// PluginLoader returns the loader that resolves plugins for plugin nodes
+func (r *NodeRegistry) PluginLoader() *plugins.PluginLoader {
+	return r.pluginLoader
+}
+
+// EndPluginExecution releases the plugin instances an execution used, calling their OnExecutionEnd hooks
+func (r *NodeRegistry) EndPluginExecution(executionID string) {
+	r.pluginLoader.EndExecution(executionID)
+}
+
 // RegisterPlugin registers a compiled plugin from file
 func (r *NodeRegistry) RegisterPlugin(pluginPath string) error {
 	r.mu.Lock()
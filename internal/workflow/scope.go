@@ -0,0 +1,237 @@
+package workflow
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uzzalhcse/crawlify/pkg/models"
+)
+
+// Scope rejection reasons recorded in ExecutionStats.RejectedReasons
+const (
+	RejectReasonMaxDepth         = "max_depth"
+	RejectReasonInvalidURL       = "invalid_url"
+	RejectReasonDomainNotAllowed = "domain_not_allowed"
+	RejectReasonDomainBlocked    = "domain_blocked"
+	RejectReasonNotIncluded      = "pattern_not_included"
+	RejectReasonExcluded         = "pattern_excluded"
+	RejectReasonURLBudget        = "url_budget"
+	RejectReasonTimeBudget       = "time_budget"
+)
+
+// ScopeEnforcer decides which discovered URLs may be enqueued for an execution
+type ScopeEnforcer struct {
+	maxDepth        int
+	allowedDomains  []string
+	blockedDomains  []string
+	allowSubdomains bool
+	blockSubdomains bool
+	includePatterns []*regexp.Regexp
+	excludePatterns []*regexp.Regexp
+	maxURLs         int
+	deadline        time.Time
+
+	mu       sync.Mutex
+	enqueued int
+	rejected map[string]int
+}
+
+// NewScopeEnforcer builds a scope enforcer from the workflow config
+// defaultMaxDepth applies when the workflow does not set its own max_depth (0 = unlimited)
+func NewScopeEnforcer(config *models.WorkflowConfig, defaultMaxDepth int, startedAt time.Time) (*ScopeEnforcer, error) {
+	s := &ScopeEnforcer{
+		maxDepth: config.MaxDepth,
+		rejected: make(map[string]int),
+	}
+	if s.maxDepth <= 0 {
+		s.maxDepth = defaultMaxDepth
+	}
+
+	scope := config.Scope
+	if scope == nil {
+		return s, nil
+	}
+
+	s.allowSubdomains = scope.AllowSubdomains
+	s.blockSubdomains = scope.BlockSubdomains
+	s.maxURLs = scope.MaxURLs
+	if scope.MaxDurationSeconds > 0 {
+		s.deadline = startedAt.Add(time.Duration(scope.MaxDurationSeconds) * time.Second)
+	}
+
+	for _, domain := range scope.AllowedDomains {
+		s.allowedDomains = append(s.allowedDomains, normalizeDomain(domain))
+	}
+	if len(s.allowedDomains) == 0 && scope.SameDomainOnly {
+		startURLs := config.StartURLs
+		for _, source := range config.StartSources {
+			startURLs = append(startURLs, source.URLs...)
+		}
+		for _, startURL := range startURLs {
+			if u, err := url.Parse(startURL); err == nil && u.Hostname() != "" {
+				s.allowedDomains = append(s.allowedDomains, normalizeDomain(u.Hostname()))
+			}
+		}
+	}
+	for _, domain := range scope.BlockedDomains {
+		s.blockedDomains = append(s.blockedDomains, normalizeDomain(domain))
+	}
+
+	for _, pattern := range scope.IncludePatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid include pattern '%s': %w", pattern, err)
+		}
+		s.includePatterns = append(s.includePatterns, re)
+	}
+	for _, pattern := range scope.ExcludePatterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid exclude pattern '%s': %w", pattern, err)
+		}
+		s.excludePatterns = append(s.excludePatterns, re)
+	}
+
+	return s, nil
+}
+
+// SetEnqueued seeds the URL budget counter (used when resuming an execution)
+func (s *ScopeEnforcer) SetEnqueued(count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enqueued = count
+}
+
+// Admit checks a discovered URL against the scope rules and whether any URL budget is left
+// Returns the rejection reason, or "" if the URL may be enqueued. The budget is charged by
+// Reserve when the URL is enqueued, so duplicates the queue drops do not use it up.
+func (s *ScopeEnforcer) Admit(rawURL string, depth int) string {
+	reason := s.check(rawURL, depth)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if reason == "" && s.maxURLs > 0 && s.enqueued >= s.maxURLs {
+		reason = RejectReasonURLBudget
+	}
+	if reason != "" {
+		s.rejected[reason]++
+	}
+	return reason
+}
+
+// Reserve takes up to n slots of the URL budget and returns how many were granted
+// Slots the queue did not use for a new URL are handed back with Release.
+func (s *ScopeEnforcer) Reserve(n int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.maxURLs > 0 && s.enqueued+n > s.maxURLs {
+		n = max(s.maxURLs-s.enqueued, 0)
+	}
+	s.enqueued += n
+	return n
+}
+
+// Release hands back n reserved budget slots
+func (s *ScopeEnforcer) Release(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.enqueued = max(s.enqueued-n, 0)
+}
+
+// RejectOverBudget counts n admitted URLs that found no budget left when enqueued
+func (s *ScopeEnforcer) RejectOverBudget(n int) {
+	if n <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rejected[RejectReasonURLBudget] += n
+}
+
+// TimeBudgetExceeded checks if the execution ran past its time budget
+func (s *ScopeEnforcer) TimeBudgetExceeded() bool {
+	return !s.deadline.IsZero() && time.Now().After(s.deadline)
+}
+
+// Rejections returns the total rejected count and a copy of the per-reason counts
+func (s *ScopeEnforcer) Rejections() (int, map[string]int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := 0
+	reasons := make(map[string]int, len(s.rejected))
+	for reason, count := range s.rejected {
+		reasons[reason] = count
+		total += count
+	}
+	return total, reasons
+}
+
+// check applies the stateless scope rules
+func (s *ScopeEnforcer) check(rawURL string, depth int) string {
+	if s.TimeBudgetExceeded() {
+		return RejectReasonTimeBudget
+	}
+	if s.maxDepth > 0 && depth > s.maxDepth {
+		return RejectReasonMaxDepth
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return RejectReasonInvalidURL
+	}
+	host := normalizeDomain(u.Hostname())
+
+	if domainMatches(host, s.blockedDomains, s.blockSubdomains) {
+		return RejectReasonDomainBlocked
+	}
+	if len(s.allowedDomains) > 0 && !domainMatches(host, s.allowedDomains, s.allowSubdomains) {
+		return RejectReasonDomainNotAllowed
+	}
+
+	for _, re := range s.excludePatterns {
+		if re.MatchString(rawURL) {
+			return RejectReasonExcluded
+		}
+	}
+	if len(s.includePatterns) > 0 {
+		included := false
+		for _, re := range s.includePatterns {
+			if re.MatchString(rawURL) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return RejectReasonNotIncluded
+		}
+	}
+
+	return ""
+}
+
+// normalizeDomain lower-cases a domain and strips a leading "www."
+func normalizeDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	domain = strings.TrimSuffix(domain, ".")
+	return strings.TrimPrefix(domain, "www.")
+}
+
+// domainMatches checks if host equals one of the domains, or is a subdomain when allowed
+func domainMatches(host string, domains []string, includeSubdomains bool) bool {
+	for _, domain := range domains {
+		if host == domain {
+			return true
+		}
+		if includeSubdomains && strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,3 @@
+-- Remove cross-execution seen URL tracking
+DROP INDEX IF EXISTS idx_url_seen_last_seen;
+DROP TABLE IF EXISTS url_seen;
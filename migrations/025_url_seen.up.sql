@@ -0,0 +1,16 @@
+-- URLs processed by previous executions of a workflow (incremental crawl support)
+CREATE TABLE IF NOT EXISTS url_seen (
+    workflow_id UUID NOT NULL REFERENCES workflows(id) ON DELETE CASCADE,
+    url_hash VARCHAR(64) NOT NULL,
+    url TEXT NOT NULL,
+    last_execution_id UUID REFERENCES workflow_executions(id) ON DELETE SET NULL,
+    first_seen_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (workflow_id, url_hash)
+);
+
+CREATE INDEX idx_url_seen_last_seen ON url_seen(workflow_id, last_seen_at);
+
+COMMENT ON TABLE url_seen IS 'Canonical URL hashes processed by previous executions, used by dedup.seen_before';
+COMMENT ON COLUMN url_seen.url_hash IS 'SHA-256 of the canonicalised URL';
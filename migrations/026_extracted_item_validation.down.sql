@@ -0,0 +1,3 @@
+-- Remove schema validation errors from extracted items
+DROP INDEX IF EXISTS idx_extracted_items_invalid;
+ALTER TABLE extracted_items DROP COLUMN IF EXISTS validation_errors;
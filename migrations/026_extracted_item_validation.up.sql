@@ -0,0 +1,6 @@
+-- Schema validation errors stored beside each extracted item
+ALTER TABLE extracted_items ADD COLUMN IF NOT EXISTS validation_errors JSONB;
+
+CREATE INDEX IF NOT EXISTS idx_extracted_items_invalid ON extracted_items(execution_id) WHERE validation_errors IS NOT NULL;
+
+COMMENT ON COLUMN extracted_items.validation_errors IS 'Output schema coercion/validation errors (NULL when the item is valid or has no schema)';
@@ -0,0 +1,7 @@
+-- Remove plugin sandbox manifests and approvals
+ALTER TABLE plugin_installations DROP COLUMN IF EXISTS approved_limits;
+ALTER TABLE plugin_installations DROP COLUMN IF EXISTS approved_capabilities;
+
+ALTER TABLE plugin_versions DROP COLUMN IF EXISTS limits;
+ALTER TABLE plugin_versions DROP COLUMN IF EXISTS capabilities;
+ALTER TABLE plugin_versions DROP COLUMN IF EXISTS runtime;
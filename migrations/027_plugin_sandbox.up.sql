@@ -0,0 +1,13 @@
+-- Sandbox manifest of plugin versions and the capabilities approved at install time
+ALTER TABLE plugin_versions ADD COLUMN IF NOT EXISTS runtime VARCHAR(20) NOT NULL DEFAULT 'native';
+ALTER TABLE plugin_versions ADD COLUMN IF NOT EXISTS capabilities JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE plugin_versions ADD COLUMN IF NOT EXISTS limits JSONB NOT NULL DEFAULT '{}';
+
+ALTER TABLE plugin_installations ADD COLUMN IF NOT EXISTS approved_capabilities JSONB NOT NULL DEFAULT '{}';
+ALTER TABLE plugin_installations ADD COLUMN IF NOT EXISTS approved_limits JSONB NOT NULL DEFAULT '{}';
+
+COMMENT ON COLUMN plugin_versions.runtime IS 'native (.so), rpc (executable) or wasm (sandboxed module)';
+COMMENT ON COLUMN plugin_versions.capabilities IS 'Capabilities a WASM plugin requests: network_hosts, browser_evaluate, file_access';
+COMMENT ON COLUMN plugin_versions.limits IS 'Per-call limits a WASM plugin needs: memory_mb, timeout_ms';
+COMMENT ON COLUMN plugin_installations.approved_capabilities IS 'Capabilities an admin approved when installing the plugin';
+COMMENT ON COLUMN plugin_installations.approved_limits IS 'Per-call limits an admin approved when installing the plugin';
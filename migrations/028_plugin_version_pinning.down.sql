@@ -0,0 +1,3 @@
+-- Remove versioned plugin artifacts and plugin version snapshots
+ALTER TABLE workflow_versions DROP COLUMN IF EXISTS plugins;
+ALTER TABLE plugin_installations DROP COLUMN IF EXISTS artifact_path;
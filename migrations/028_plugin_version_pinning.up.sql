@@ -0,0 +1,6 @@
+-- Versioned plugin artifacts and the plugin versions recorded in workflow version snapshots
+ALTER TABLE plugin_installations ADD COLUMN IF NOT EXISTS artifact_path VARCHAR(500) NOT NULL DEFAULT '';
+ALTER TABLE workflow_versions ADD COLUMN IF NOT EXISTS plugins JSONB NOT NULL DEFAULT '[]';
+
+COMMENT ON COLUMN plugin_installations.artifact_path IS 'Binary of the locked version in the versioned plugin store: plugins/<slug>/<version>/';
+COMMENT ON COLUMN workflow_versions.plugins IS 'Plugin version each plugin node resolved to when the workflow version was saved';
@@ -0,0 +1,7 @@
+-- Remove persistent plugin builds
+ALTER TABLE plugin_versions DROP COLUMN IF EXISTS sbom;
+ALTER TABLE plugin_versions DROP COLUMN IF EXISTS build_id;
+
+DROP INDEX IF EXISTS idx_plugin_builds_status;
+DROP INDEX IF EXISTS idx_plugin_builds_plugin;
+DROP TABLE IF EXISTS plugin_builds;
@@ -0,0 +1,30 @@
+-- Persistent plugin build jobs, and the build and SBOM of plugin version binaries
+CREATE TABLE IF NOT EXISTS plugin_builds (
+    id UUID PRIMARY KEY,
+    plugin_id UUID REFERENCES plugins(id) ON DELETE CASCADE,
+    plugin_slug VARCHAR(255) NOT NULL,
+    plugin_version_id UUID REFERENCES plugin_versions(id) ON DELETE SET NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'queued',
+    tests_only BOOLEAN NOT NULL DEFAULT false,
+    tests VARCHAR(20) NOT NULL DEFAULT '',
+    test_log TEXT NOT NULL DEFAULT '',
+    log TEXT NOT NULL DEFAULT '',
+    artifact VARCHAR(500) NOT NULL DEFAULT '',
+    artifact_hash VARCHAR(64) NOT NULL DEFAULT '',
+    artifact_size BIGINT NOT NULL DEFAULT 0,
+    sbom JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMP WITH TIME ZONE,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_plugin_builds_plugin ON plugin_builds(plugin_slug, created_at DESC);
+CREATE INDEX idx_plugin_builds_status ON plugin_builds(status) WHERE status IN ('queued', 'building');
+
+ALTER TABLE plugin_versions ADD COLUMN IF NOT EXISTS build_id UUID REFERENCES plugin_builds(id) ON DELETE SET NULL;
+ALTER TABLE plugin_versions ADD COLUMN IF NOT EXISTS sbom JSONB NOT NULL DEFAULT '{}';
+
+COMMENT ON TABLE plugin_builds IS 'Plugin build jobs: bundled tests, then go build -buildmode=plugin in a copy of the source';
+COMMENT ON COLUMN plugin_builds.plugin_version_id IS 'Version the artifact, hash and SBOM are recorded on when the build succeeds';
+COMMENT ON COLUMN plugin_builds.artifact_hash IS 'SHA-256 of the built binary';
+COMMENT ON COLUMN plugin_versions.sbom IS 'Go version, modules and build settings embedded in the binary';
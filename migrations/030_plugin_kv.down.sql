@@ -0,0 +1,3 @@
+-- Remove the plugin key-value store
+DROP INDEX IF EXISTS idx_plugin_kv_key;
+DROP TABLE IF EXISTS plugin_kv;
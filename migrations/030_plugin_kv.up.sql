@@ -0,0 +1,14 @@
+-- Key-value store of plugins, scoped to a workflow or to one of its executions
+CREATE TABLE IF NOT EXISTS plugin_kv (
+    plugin_slug VARCHAR(255) NOT NULL,
+    workflow_id UUID NOT NULL REFERENCES workflows(id) ON DELETE CASCADE,
+    execution_id UUID REFERENCES workflow_executions(id) ON DELETE CASCADE,
+    key VARCHAR(512) NOT NULL,
+    value JSONB NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_plugin_kv_key ON plugin_kv(plugin_slug, workflow_id, (COALESCE(execution_id::text, '')), key);
+
+COMMENT ON TABLE plugin_kv IS 'Values plugins keep between calls through input.WorkflowStore and input.ExecutionStore';
+COMMENT ON COLUMN plugin_kv.execution_id IS 'NULL for values shared by every execution of the workflow';
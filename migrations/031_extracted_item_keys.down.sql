@@ -0,0 +1,5 @@
+-- Back to one item per URL and schema; emitted items cannot be kept
+DELETE FROM extracted_items WHERE item_key IS NOT NULL;
+DROP INDEX IF EXISTS idx_extracted_items_key;
+ALTER TABLE extracted_items ADD CONSTRAINT unique_extracted_item_per_url UNIQUE(execution_id, url_id, schema_name);
+ALTER TABLE extracted_items DROP COLUMN IF EXISTS item_key;
@@ -0,0 +1,8 @@
+-- Several items per page: each emitted item is a row of its own, identified by its key within its schema
+ALTER TABLE extracted_items ADD COLUMN IF NOT EXISTS item_key VARCHAR(255);
+
+ALTER TABLE extracted_items DROP CONSTRAINT IF EXISTS unique_extracted_item_per_url;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_extracted_items_key
+    ON extracted_items(execution_id, url_id, schema_name, (COALESCE(item_key, '')));
+
+COMMENT ON COLUMN extracted_items.item_key IS 'Key of an item emitted with others from the same page (NULL for the page''s own item)';
@@ -0,0 +1,4 @@
+-- Remove signed plugin bundles
+ALTER TABLE plugin_versions DROP COLUMN IF EXISTS signing_key_id;
+ALTER TABLE plugin_versions DROP COLUMN IF EXISTS signature;
+ALTER TABLE plugin_versions DROP COLUMN IF EXISTS manifest;
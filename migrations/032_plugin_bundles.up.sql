@@ -0,0 +1,8 @@
+-- Signed plugin bundles: the manifest and signature a plugin version was imported with
+ALTER TABLE plugin_versions ADD COLUMN IF NOT EXISTS manifest TEXT NOT NULL DEFAULT '';
+ALTER TABLE plugin_versions ADD COLUMN IF NOT EXISTS signature TEXT NOT NULL DEFAULT '';
+ALTER TABLE plugin_versions ADD COLUMN IF NOT EXISTS signing_key_id VARCHAR(64) NOT NULL DEFAULT '';
+
+COMMENT ON COLUMN plugin_versions.manifest IS 'manifest.json of the bundle the version was imported from, as signed';
+COMMENT ON COLUMN plugin_versions.signature IS 'Base64 ed25519 signature of manifest; plugins.is_verified is set when every version verifies against a trusted key';
+COMMENT ON COLUMN plugin_versions.signing_key_id IS 'First 8 bytes of the SHA-256 of the signing public key, in hex';
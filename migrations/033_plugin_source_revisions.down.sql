@@ -0,0 +1,3 @@
+-- Remove plugin source history
+ALTER TABLE plugin_builds DROP COLUMN IF EXISTS source_revision;
+DROP TABLE IF EXISTS plugin_source_revisions;
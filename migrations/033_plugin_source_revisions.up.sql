@@ -0,0 +1,22 @@
+-- Plugin source history: every save from the editor is a revision that can be diffed, rolled back to and built
+CREATE TABLE IF NOT EXISTS plugin_source_revisions (
+    id UUID PRIMARY KEY,
+    plugin_slug VARCHAR(255) NOT NULL,
+    revision INTEGER NOT NULL,
+    author VARCHAR(255) NOT NULL DEFAULT '',
+    message TEXT NOT NULL DEFAULT '',
+    files JSONB NOT NULL DEFAULT '{}',
+    changes JSONB NOT NULL DEFAULT '[]',
+    diff TEXT NOT NULL DEFAULT '',
+    restored_from INTEGER,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(plugin_slug, revision)
+);
+
+ALTER TABLE plugin_builds ADD COLUMN IF NOT EXISTS source_revision INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON TABLE plugin_source_revisions IS 'Snapshots of plugin source trees, one per save';
+COMMENT ON COLUMN plugin_source_revisions.files IS 'Every editable file of the plugin at this revision, path to content';
+COMMENT ON COLUMN plugin_source_revisions.diff IS 'Unified diff against the previous revision';
+COMMENT ON COLUMN plugin_source_revisions.restored_from IS 'Revision this one rolled back to, if it is a rollback';
+COMMENT ON COLUMN plugin_builds.source_revision IS 'Source revision the build was made from; 0 for the working tree';
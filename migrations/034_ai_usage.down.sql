@@ -0,0 +1,3 @@
+-- Remove AI usage accounting
+DROP TABLE IF EXISTS ai_response_cache;
+DROP TABLE IF EXISTS ai_usage;
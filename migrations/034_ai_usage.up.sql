@@ -0,0 +1,40 @@
+-- AI usage accounting: one row per AI call, and a cache of answers keyed by prompt hash
+CREATE TABLE IF NOT EXISTS ai_usage (
+    id UUID PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL,
+    model VARCHAR(255) NOT NULL DEFAULT '',
+    feature VARCHAR(50) NOT NULL DEFAULT '',
+    workflow_id UUID,
+    execution_id UUID,
+    status VARCHAR(20) NOT NULL,
+    prompt_tokens INTEGER NOT NULL DEFAULT 0,
+    response_tokens INTEGER NOT NULL DEFAULT 0,
+    tokens_estimated BOOLEAN NOT NULL DEFAULT false,
+    latency_ms BIGINT NOT NULL DEFAULT 0,
+    cost_usd NUMERIC(12, 6) NOT NULL DEFAULT 0,
+    prompt_hash VARCHAR(64) NOT NULL DEFAULT '',
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_ai_usage_created ON ai_usage(created_at);
+CREATE INDEX idx_ai_usage_workflow ON ai_usage(workflow_id, created_at) WHERE workflow_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS ai_response_cache (
+    prompt_hash VARCHAR(64) PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL,
+    model VARCHAR(255) NOT NULL DEFAULT '',
+    response TEXT NOT NULL,
+    prompt_tokens INTEGER NOT NULL DEFAULT 0,
+    response_tokens INTEGER NOT NULL DEFAULT 0,
+    hits INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_ai_response_cache_expires ON ai_response_cache(expires_at) WHERE expires_at IS NOT NULL;
+
+COMMENT ON TABLE ai_usage IS 'Every AI call: provider, model, feature, tokens, latency and estimated cost';
+COMMENT ON COLUMN ai_usage.status IS 'success, error, cached (answered from ai_response_cache) or budget_blocked (not made)';
+COMMENT ON COLUMN ai_usage.cost_usd IS 'Estimated from ai.pricing; 0 for cached, blocked and self-hosted calls';
+COMMENT ON TABLE ai_response_cache IS 'AI answers keyed by the SHA-256 of provider, model, prompt and image';
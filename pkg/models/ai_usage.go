@@ -0,0 +1,70 @@
+package models
+
+import "time"
+
+// AIUsageStatus is the outcome of an AI call
+type AIUsageStatus string
+
+const (
+	AIUsageSuccess AIUsageStatus = "success"
+	AIUsageError   AIUsageStatus = "error"
+	AIUsageCached  AIUsageStatus = "cached"         // Answered from the response cache, at no cost
+	AIUsageBudget  AIUsageStatus = "budget_blocked" // Not made because a budget was spent
+)
+
+// AIUsage is one AI call with its token counts, latency and estimated cost
+type AIUsage struct {
+	ID              string        `json:"id" db:"id"`
+	Provider        string        `json:"provider" db:"provider"`
+	Model           string        `json:"model" db:"model"`
+	Feature         string        `json:"feature" db:"feature"` // autofix, recovery, generation
+	WorkflowID      string        `json:"workflow_id,omitempty" db:"workflow_id"`
+	ExecutionID     string        `json:"execution_id,omitempty" db:"execution_id"`
+	Status          AIUsageStatus `json:"status" db:"status"`
+	PromptTokens    int           `json:"prompt_tokens" db:"prompt_tokens"`
+	ResponseTokens  int           `json:"response_tokens" db:"response_tokens"`
+	TokensEstimated bool          `json:"tokens_estimated,omitempty" db:"tokens_estimated"` // The provider reported no counts
+	LatencyMs       int64         `json:"latency_ms" db:"latency_ms"`
+	CostUSD         float64       `json:"cost_usd" db:"cost_usd"`
+	PromptHash      string        `json:"prompt_hash" db:"prompt_hash"`
+	Error           string        `json:"error,omitempty" db:"error"`
+	CreatedAt       time.Time     `json:"created_at" db:"created_at"`
+}
+
+// AIUsageSummary totals AI calls for one group: a day, feature, provider, model or workflow
+type AIUsageSummary struct {
+	Group          string  `json:"group"`
+	Calls          int     `json:"calls"`
+	CachedCalls    int     `json:"cached_calls"`
+	BlockedCalls   int     `json:"blocked_calls"`
+	FailedCalls    int     `json:"failed_calls"`
+	PromptTokens   int64   `json:"prompt_tokens"`
+	ResponseTokens int64   `json:"response_tokens"`
+	CostUSD        float64 `json:"cost_usd"`
+	AvgLatencyMs   float64 `json:"avg_latency_ms"`
+}
+
+// AIBudgetStatus is the spend of the current UTC day against the configured limits; a limit of
+// 0 is unlimited
+type AIBudgetStatus struct {
+	Day          string  `json:"day"`
+	WorkflowID   string  `json:"workflow_id,omitempty"` // Set when the status is for one workflow
+	CostUSD      float64 `json:"cost_usd"`
+	Calls        int     `json:"calls"`
+	CostLimitUSD float64 `json:"cost_limit_usd"`
+	CallLimit    int     `json:"call_limit"`
+	Exceeded     bool    `json:"exceeded"`
+}
+
+// AICachedResponse is a stored answer to a prompt
+type AICachedResponse struct {
+	PromptHash     string     `json:"prompt_hash" db:"prompt_hash"`
+	Provider       string     `json:"provider" db:"provider"`
+	Model          string     `json:"model" db:"model"`
+	Response       string     `json:"response" db:"response"`
+	PromptTokens   int        `json:"prompt_tokens" db:"prompt_tokens"`
+	ResponseTokens int        `json:"response_tokens" db:"response_tokens"`
+	Hits           int        `json:"hits" db:"hits"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	ExpiresAt      *time.Time `json:"expires_at" db:"expires_at"`
+}
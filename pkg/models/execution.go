@@ -29,19 +29,24 @@ const (
 	ExecutionStatusCompleted ExecutionStatus = "completed"
 	ExecutionStatusFailed    ExecutionStatus = "failed"
 	ExecutionStatusCancelled ExecutionStatus = "cancelled"
+	ExecutionStatusStopped   ExecutionStatus = "stopped" // A budget ran out; pending URLs can be resumed
 )
 
 // ExecutionStats contains statistics about the execution
 type ExecutionStats struct {
-	URLsDiscovered  int       `json:"urls_discovered"`
-	URLsProcessed   int       `json:"urls_processed"`
-	URLsFailed      int       `json:"urls_failed"`
-	ItemsExtracted  int       `json:"items_extracted"`
-	BytesDownloaded int64     `json:"bytes_downloaded"`
-	Duration        int64     `json:"duration"` // milliseconds
-	NodesExecuted   int       `json:"nodes_executed"`
-	NodesFailed     int       `json:"nodes_failed"`
-	LastUpdate      time.Time `json:"last_update"`
+	URLsDiscovered  int            `json:"urls_discovered"`
+	URLsProcessed   int            `json:"urls_processed"`
+	URLsFailed      int            `json:"urls_failed"`
+	ItemsExtracted  int            `json:"items_extracted"`
+	BytesDownloaded int64          `json:"bytes_downloaded"`
+	Duration        int64          `json:"duration"` // milliseconds
+	NodesExecuted   int            `json:"nodes_executed"`
+	NodesFailed     int            `json:"nodes_failed"`
+	URLsRejected    int            `json:"urls_rejected"`
+	ItemsInvalid    int            `json:"items_invalid"`
+	InvalidFields   map[string]int `json:"invalid_fields,omitempty"`   // Validation error count per schema field
+	RejectedReasons map[string]int `json:"rejected_reasons,omitempty"` // Rejection count per scope rule
+	LastUpdate      time.Time      `json:"last_update"`
 }
 
 // ExecutionContext stores runtime context data passed between nodes
@@ -131,6 +136,21 @@ func (ec *ExecutionContext) GetVariable(key string) (string, bool) {
 	return val, ok
 }
 
+// EmitItem records an item to be saved on its own, linked to the node execution running now
+func (ec *ExecutionContext) EmitItem(item EmittedItem) {
+	if item.NodeExecutionID == "" {
+		item.NodeExecutionID, _ = ec.Data["_node_exec_id"].(string)
+	}
+	items, _ := ec.Data[EmittedItemsKey].([]EmittedItem)
+	ec.Data[EmittedItemsKey] = append(items, item)
+}
+
+// EmittedItems returns the items emitted so far
+func (ec *ExecutionContext) EmittedItems() []EmittedItem {
+	items, _ := ec.Data[EmittedItemsKey].([]EmittedItem)
+	return items
+}
+
 // GetAll returns all data from the context
 func (ec *ExecutionContext) GetAll() map[string]interface{} {
 	return ec.Data
@@ -196,4 +196,20 @@ type VerificationResult struct {
 	ElementsFound int      `json:"elements_found"`
 	DataPreview   []string `json:"data_preview"`
 	ErrorMessage  string   `json:"error_message,omitempty"`
+
+	// Set when the node was replayed with the suggested params in a browser
+	Method             string      `json:"method,omitempty"`              // node_replay
+	Source             string      `json:"source,omitempty"`              // live, mhtml, dom
+	Field              string      `json:"field,omitempty"`               // Extract field the fix targets; "" for the node's own selector
+	ExtractedValue     interface{} `json:"extracted_value,omitempty"`     // What the replayed node extracted
+	ExpectedType       string      `json:"expected_type,omitempty"`       // From the output schema, or inferred from the baseline
+	TypeMatches        bool        `json:"type_matches,omitempty"`        // The value coerces to the expected type
+	BaselineSimilarity *float64    `json:"baseline_similarity,omitempty"` // 0-1 against the baseline preview; nil without one
+	Issues             []string    `json:"issues,omitempty"`
+	VerifiedAt         *time.Time  `json:"verified_at,omitempty"`
 }
+
+// Verification methods
+const (
+	VerificationMethodNodeReplay = "node_replay"
+)
@@ -8,6 +8,7 @@ type WorkflowPhase struct {
 	Nodes      []Node           `json:"nodes" yaml:"nodes"`
 	URLFilter  *URLFilter       `json:"url_filter,omitempty" yaml:"url_filter,omitempty"`
 	Transition *PhaseTransition `json:"transition,omitempty" yaml:"transition,omitempty"`
+	Schema     *ItemSchema      `json:"schema,omitempty" yaml:"schema,omitempty"` // Output schema for items extracted in this phase
 }
 
 // PhaseType defines the type of workflow phase
@@ -25,11 +26,13 @@ type URLFilter struct {
 	Markers  []string `json:"markers,omitempty" yaml:"markers,omitempty"`   // URLs with these markers
 	Patterns []string `json:"patterns,omitempty" yaml:"patterns,omitempty"` // Regex patterns
 	Depth    *int     `json:"depth,omitempty" yaml:"depth,omitempty"`       // Specific depth
+	// Expression is a sandboxed JavaScript filter over url metadata, e.g. url.depth > 1 && url.path.startsWith("/p/")
+	Expression string `json:"expression,omitempty" yaml:"expression,omitempty"`
 }
 
 // PhaseTransition defines when and how to move to the next phase
 type PhaseTransition struct {
-	Condition string                 `json:"condition" yaml:"condition"`                       // all_nodes_complete, url_count, custom_condition
+	Condition string                 `json:"condition" yaml:"condition"`                       // all_nodes_complete, url_count, expression
 	NextPhase string                 `json:"next_phase,omitempty" yaml:"next_phase,omitempty"` // ID of next phase (empty = end)
 	Params    map[string]interface{} `json:"params,omitempty" yaml:"params,omitempty"`         // Parameters for condition
 }
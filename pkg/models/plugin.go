@@ -3,6 +3,8 @@ package models
 import (
 	"database/sql/driver"
 	"encoding/json"
+	"net/url"
+	"strings"
 	"time"
 )
 
@@ -38,6 +40,15 @@ const (
 	PluginTypePrivate   PluginType = "private"   // Organization-specific plugins
 )
 
+// PluginRuntime defines how a plugin binary is executed
+type PluginRuntime string
+
+const (
+	PluginRuntimeNative PluginRuntime = "native" // Go plugin (.so) loaded in-process
+	PluginRuntimeRPC    PluginRuntime = "rpc"    // Executable speaking the RPC plugin protocol
+	PluginRuntimeWASM   PluginRuntime = "wasm"   // WebAssembly module run in a sandbox with approved capabilities
+)
+
 // PluginVersion represents a specific version of a plugin
 type PluginVersion struct {
 	ID                 string `json:"id" db:"id"`
@@ -57,13 +68,60 @@ type PluginVersion struct {
 	BinaryHash      string `json:"binary_hash" db:"binary_hash"` // SHA-256 hash
 	BinarySizeBytes int64  `json:"binary_size_bytes" db:"binary_size_bytes"`
 
+	// BuildID and SBOM are set when the binary was produced by the build service
+	BuildID string     `json:"build_id,omitempty" db:"build_id"`
+	SBOM    PluginSBOM `json:"sbom" db:"sbom"`
+
+	// Manifest is the bundle manifest a version was imported with and Signature its base64 ed25519
+	// signature by SigningKeyID; all are empty for versions published on this instance
+	Manifest     string `json:"-" db:"manifest"`
+	Signature    string `json:"signature,omitempty" db:"signature"`
+	SigningKeyID string `json:"signing_key_id,omitempty" db:"signing_key_id"`
+
 	// Configuration schema (JSON Schema for plugin config)
 	ConfigSchema JSONObject `json:"config_schema" db:"config_schema"`
 
+	// Sandbox manifest (WASM plugins): what the plugin asks for and how much it may use per call
+	Runtime      PluginRuntime      `json:"runtime" db:"runtime"`
+	Capabilities PluginCapabilities `json:"capabilities" db:"capabilities"`
+	Limits       PluginLimits       `json:"limits" db:"limits"`
+
 	Downloads   int       `json:"downloads" db:"downloads"`
 	PublishedAt time.Time `json:"published_at" db:"published_at"`
 }
 
+// BinaryPath returns the binary published for a platform, or "" when there is none
+func (v *PluginVersion) BinaryPath(goos, goarch string) string {
+	switch goos + "/" + goarch {
+	case "linux/amd64":
+		return v.LinuxAmd64BinaryPath
+	case "linux/arm64":
+		return v.LinuxArm64BinaryPath
+	case "darwin/amd64":
+		return v.DarwinAmd64BinaryPath
+	case "darwin/arm64":
+		return v.DarwinArm64BinaryPath
+	}
+	return ""
+}
+
+// SetBinaryPath records the binary published for a platform; it reports false for platforms without one
+func (v *PluginVersion) SetBinaryPath(goos, goarch, path string) bool {
+	switch goos + "/" + goarch {
+	case "linux/amd64":
+		v.LinuxAmd64BinaryPath = path
+	case "linux/arm64":
+		v.LinuxArm64BinaryPath = path
+	case "darwin/amd64":
+		v.DarwinAmd64BinaryPath = path
+	case "darwin/arm64":
+		v.DarwinArm64BinaryPath = path
+	default:
+		return false
+	}
+	return true
+}
+
 // PluginInstallation tracks plugin installations
 type PluginInstallation struct {
 	ID              string     `json:"id" db:"id"`
@@ -73,6 +131,126 @@ type PluginInstallation struct {
 	InstalledAt     time.Time  `json:"installed_at" db:"installed_at"`
 	LastUsedAt      *time.Time `json:"last_used_at" db:"last_used_at"`
 	UsageCount      int        `json:"usage_count" db:"usage_count"`
+
+	// Capabilities and limits an admin approved at install time (WASM plugins)
+	ApprovedCapabilities PluginCapabilities `json:"approved_capabilities" db:"approved_capabilities"`
+	ApprovedLimits       PluginLimits       `json:"approved_limits" db:"approved_limits"`
+
+	// Version is the locked plugin version; ArtifactPath is its binary in the versioned plugin store
+	Version      string `json:"version" db:"-"`
+	ArtifactPath string `json:"artifact_path" db:"artifact_path"`
+}
+
+// PluginCapabilities lists what a sandboxed plugin may do beyond reading the current page
+type PluginCapabilities struct {
+	NetworkHosts    []string `json:"network_hosts,omitempty"`    // Hosts the plugin may fetch or navigate to; "*.example.com" matches subdomains
+	BrowserEvaluate bool     `json:"browser_evaluate,omitempty"` // Run JavaScript in the page
+	FileAccess      bool     `json:"file_access,omitempty"`      // Read and write the plugin's own data directory
+}
+
+// Missing returns the capabilities in requested that c does not grant, as readable strings
+func (c PluginCapabilities) Missing(requested PluginCapabilities) []string {
+	var missing []string
+	for _, host := range requested.NetworkHosts {
+		if !c.AllowsHost(host) {
+			missing = append(missing, "network:"+host)
+		}
+	}
+	if requested.BrowserEvaluate && !c.BrowserEvaluate {
+		missing = append(missing, "browser_evaluate")
+	}
+	if requested.FileAccess && !c.FileAccess {
+		missing = append(missing, "file_access")
+	}
+	return missing
+}
+
+// Intersect returns the capabilities granted by both c and other
+func (c PluginCapabilities) Intersect(other PluginCapabilities) PluginCapabilities {
+	result := PluginCapabilities{
+		BrowserEvaluate: c.BrowserEvaluate && other.BrowserEvaluate,
+		FileAccess:      c.FileAccess && other.FileAccess,
+	}
+	for _, host := range c.NetworkHosts {
+		if other.AllowsHost(host) {
+			result.NetworkHosts = append(result.NetworkHosts, host)
+		}
+	}
+	return result
+}
+
+// AllowsHost reports whether host (a host name, host pattern or URL) is covered by NetworkHosts
+func (c PluginCapabilities) AllowsHost(host string) bool {
+	if u, err := url.Parse(host); err == nil && u.Host != "" {
+		host = u.Hostname()
+	}
+	host = strings.ToLower(host)
+	for _, allowed := range c.NetworkHosts {
+		allowed = strings.ToLower(allowed)
+		if allowed == "*" || allowed == host {
+			return true
+		}
+		if suffix, ok := strings.CutPrefix(allowed, "*."); ok && strings.HasSuffix(host, "."+suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Scan implements sql.Scanner for PluginCapabilities
+func (c *PluginCapabilities) Scan(value interface{}) error {
+	return scanJSON(value, c)
+}
+
+// Value implements driver.Valuer for PluginCapabilities
+func (c PluginCapabilities) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+// PluginLimits bounds the resources of one sandboxed plugin call
+// The runtime cannot count instructions, so fuel is a per-call execution deadline
+type PluginLimits struct {
+	MemoryMB  int `json:"memory_mb,omitempty"`
+	TimeoutMs int `json:"timeout_ms,omitempty"`
+}
+
+// Clamp returns l with every set limit no larger than ceiling's (zero means unset)
+func (l PluginLimits) Clamp(ceiling PluginLimits) PluginLimits {
+	if ceiling.MemoryMB > 0 && l.MemoryMB > ceiling.MemoryMB {
+		l.MemoryMB = ceiling.MemoryMB
+	}
+	if ceiling.TimeoutMs > 0 && l.TimeoutMs > ceiling.TimeoutMs {
+		l.TimeoutMs = ceiling.TimeoutMs
+	}
+	return l
+}
+
+// Scan implements sql.Scanner for PluginLimits
+func (l *PluginLimits) Scan(value interface{}) error {
+	return scanJSON(value, l)
+}
+
+// Value implements driver.Valuer for PluginLimits
+func (l PluginLimits) Value() (driver.Value, error) {
+	return json.Marshal(l)
+}
+
+// scanJSON decodes a JSONB column into v, leaving v unchanged for NULL
+func scanJSON(value interface{}, v interface{}) error {
+	switch data := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(data, v)
+	case string:
+		return json.Unmarshal([]byte(data), v)
+	default:
+		b, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(b, v)
+	}
 }
 
 // PluginReview represents a user review for a plugin
@@ -195,3 +373,11 @@ func (pv *PluginVersion) GetBinaryPath(platform PlatformInfo) string {
 		return ""
 	}
 }
+
+// PluginKVScope identifies the key-value store of a plugin in a workflow
+// An empty ExecutionID is the store shared by every execution of the workflow
+type PluginKVScope struct {
+	PluginSlug  string `json:"plugin_slug"`
+	WorkflowID  string `json:"workflow_id"`
+	ExecutionID string `json:"execution_id,omitempty"`
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+)
+
+// BuildStatus represents the status of a plugin build
+type BuildStatus string
+
+const (
+	BuildStatusQueued   BuildStatus = "queued"
+	BuildStatusBuilding BuildStatus = "building"
+	BuildStatusSuccess  BuildStatus = "success"
+	BuildStatusFailed   BuildStatus = "failed"
+)
+
+// Finished reports whether a build in this status will not change anymore
+func (s BuildStatus) Finished() bool {
+	return s == BuildStatusSuccess || s == BuildStatusFailed
+}
+
+// TestStatus is the outcome of running a plugin's bundled tests
+type TestStatus string
+
+const (
+	TestStatusPassed  TestStatus = "passed"
+	TestStatusFailed  TestStatus = "failed"
+	TestStatusSkipped TestStatus = "skipped" // The plugin has no _test.go files
+)
+
+// PluginBuild is a queued, running or finished build of a plugin's source
+type PluginBuild struct {
+	ID              string      `json:"id" db:"id"`
+	PluginID        string      `json:"plugin_id" db:"plugin_id"`
+	PluginSlug      string      `json:"plugin_slug" db:"plugin_slug"`
+	PluginVersionID string      `json:"plugin_version_id,omitempty" db:"plugin_version_id"` // Version the artifact is recorded on
+	Status          BuildStatus `json:"status" db:"status"`
+	SourceRevision  int         `json:"source_revision,omitempty" db:"source_revision"` // 0 builds the working tree
+	TestsOnly       bool        `json:"tests_only,omitempty" db:"tests_only"`
+	Tests           TestStatus  `json:"tests,omitempty" db:"tests"`
+	TestLog         string      `json:"test_log,omitempty" db:"test_log"`
+	Log             string      `json:"log" db:"log"`
+
+	// Artifact is the built binary; it is kept for the version even when the development build changes
+	Artifact     string     `json:"artifact" db:"artifact"`
+	ArtifactHash string     `json:"artifact_hash,omitempty" db:"artifact_hash"` // SHA-256
+	ArtifactSize int64      `json:"artifact_size,omitempty" db:"artifact_size"`
+	SBOM         PluginSBOM `json:"sbom" db:"sbom"`
+
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	StartedAt   *time.Time `json:"started_at" db:"started_at"`
+	CompletedAt *time.Time `json:"completed_at" db:"completed_at"`
+}
+
+// PluginSBOM lists what a plugin binary was built from, read from the build info Go embeds in it
+type PluginSBOM struct {
+	GoVersion string            `json:"go_version,omitempty"`
+	Module    *SBOMModule       `json:"module,omitempty"`
+	Deps      []SBOMModule      `json:"deps,omitempty"`
+	Settings  map[string]string `json:"settings,omitempty"` // Build flags, GOOS, GOARCH, CGO_ENABLED, ...
+}
+
+// SBOMModule is a Go module compiled into a plugin binary
+type SBOMModule struct {
+	Path    string      `json:"path"`
+	Version string      `json:"version"`
+	Sum     string      `json:"sum,omitempty"`
+	Replace *SBOMModule `json:"replace,omitempty"`
+}
+
+// Scan implements sql.Scanner for PluginSBOM
+func (s *PluginSBOM) Scan(value interface{}) error {
+	return scanJSON(value, s)
+}
+
+// Value implements driver.Valuer for PluginSBOM
+func (s PluginSBOM) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// PluginSourceRevision is a saved state of a plugin's source tree
+type PluginSourceRevision struct {
+	ID           string             `json:"id" db:"id"`
+	PluginSlug   string             `json:"plugin_slug" db:"plugin_slug"`
+	Revision     int                `json:"revision" db:"revision"` // 1, 2, ... per plugin
+	Author       string             `json:"author" db:"author"`
+	Message      string             `json:"message" db:"message"`
+	Files        map[string]string  `json:"files,omitempty" db:"files"` // Every editable file at this revision
+	Changes      []SourceFileChange `json:"changes" db:"changes"`
+	Diff         string             `json:"diff,omitempty" db:"diff"` // Unified diff against the previous revision
+	RestoredFrom *int               `json:"restored_from,omitempty" db:"restored_from"`
+	CreatedAt    time.Time          `json:"created_at" db:"created_at"`
+}
+
+// SourceChangeStatus is how a file changed between two revisions
+type SourceChangeStatus string
+
+const (
+	SourceFileAdded    SourceChangeStatus = "added"
+	SourceFileModified SourceChangeStatus = "modified"
+	SourceFileDeleted  SourceChangeStatus = "deleted"
+)
+
+// SourceFileChange summarizes the change of one file between two revisions
+type SourceFileChange struct {
+	Path      string             `json:"path"`
+	Status    SourceChangeStatus `json:"status"`
+	Additions int                `json:"additions"`
+	Deletions int                `json:"deletions"`
+}
@@ -84,6 +84,21 @@ type ExtractedItem struct {
 	URLID           string    `json:"url_id" db:"url_id"`
 	NodeExecutionID *string   `json:"node_execution_id,omitempty" db:"node_execution_id"`
 	SchemaName      *string   `json:"schema_name,omitempty" db:"schema_name"`
-	Data            string    `json:"data" db:"data"` // JSONB stored as string
+	ItemKey         *string   `json:"item_key,omitempty" db:"item_key"` // Set for items emitted with others from the same page
+	Data            string    `json:"data" db:"data"`                   // JSONB stored as string
 	ExtractedAt     time.Time `json:"extracted_at" db:"extracted_at"`
+
+	ValidationErrors []FieldValidationError `json:"validation_errors,omitempty" db:"validation_errors"`
+}
+
+// EmittedItemsKey is the execution context key holding the items emitted while processing a page
+const EmittedItemsKey = "__extracted_items__"
+
+// EmittedItem is one of several items extracted from a page, e.g. a product of a listing
+// Each is saved as an extracted item of its own, linked to the page's URL and the emitting node execution
+type EmittedItem struct {
+	SchemaName      string                 `json:"schema_name,omitempty"` // Defaults to the phase ID
+	Key             string                 `json:"key,omitempty"`         // Identifies the item within its schema; defaults to "#" and its position
+	NodeExecutionID string                 `json:"node_execution_id,omitempty"`
+	Data            map[string]interface{} `json:"data"`
 }
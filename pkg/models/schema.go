@@ -0,0 +1,54 @@
+package models
+
+// FieldType defines the declared type of an output schema field
+type FieldType string
+
+const (
+	FieldTypeString   FieldType = "string"
+	FieldTypeInt      FieldType = "int"
+	FieldTypeDecimal  FieldType = "decimal"
+	FieldTypeCurrency FieldType = "currency" // Currency amount: {"amount": 12.5, "currency": "USD"}
+	FieldTypeBool     FieldType = "bool"
+	FieldTypeURL      FieldType = "url"
+	FieldTypeDate     FieldType = "date"
+	FieldTypeEnum     FieldType = "enum"
+	FieldTypeArray    FieldType = "array"
+	FieldTypeObject   FieldType = "object"
+)
+
+// ItemSchema declares the typed output of a phase's extracted items
+type ItemSchema struct {
+	Name        string                  `json:"name,omitempty" yaml:"name,omitempty"`
+	Description string                  `json:"description,omitempty" yaml:"description,omitempty"`
+	Fields      map[string]*SchemaField `json:"fields" yaml:"fields"`
+	// DropUnknown removes extracted fields that are not declared in the schema
+	DropUnknown bool `json:"drop_unknown,omitempty" yaml:"drop_unknown,omitempty"`
+}
+
+// SchemaField declares the type and constraints of a single field
+type SchemaField struct {
+	Type             FieldType               `json:"type" yaml:"type"`
+	Description      string                  `json:"description,omitempty" yaml:"description,omitempty"`
+	Required         bool                    `json:"required,omitempty" yaml:"required,omitempty"`
+	Format           string                  `json:"format,omitempty" yaml:"format,omitempty"`                       // date: Go layout or "date"/"date-time"; string: "email", "uri"
+	Pattern          string                  `json:"pattern,omitempty" yaml:"pattern,omitempty"`                     // string: regex the value must match
+	Enum             []string                `json:"enum,omitempty" yaml:"enum,omitempty"`                           // enum: allowed values
+	Currency         string                  `json:"currency,omitempty" yaml:"currency,omitempty"`                   // currency: ISO code used when none is detected; also tells which krona "kr" is
+	DecimalSeparator string                  `json:"decimal_separator,omitempty" yaml:"decimal_separator,omitempty"` // int/decimal/currency: "." or ","; inferred when empty
+	Min              *float64                `json:"min,omitempty" yaml:"min,omitempty"`                             // int/decimal/currency: minimum value
+	Max              *float64                `json:"max,omitempty" yaml:"max,omitempty"`                             // int/decimal/currency: maximum value
+	MinLength        *int                    `json:"min_length,omitempty" yaml:"min_length,omitempty"`               // string/array: minimum length
+	MaxLength        *int                    `json:"max_length,omitempty" yaml:"max_length,omitempty"`               // string/array: maximum length
+	Default          interface{}             `json:"default,omitempty" yaml:"default,omitempty"`                     // Used when the value is missing
+	Items            *SchemaField            `json:"items,omitempty" yaml:"items,omitempty"`                         // array: element schema
+	Properties       map[string]*SchemaField `json:"properties,omitempty" yaml:"properties,omitempty"`               // object: property schemas
+	Download         bool                    `json:"download,omitempty" yaml:"download,omitempty"`                   // url (or array of url): store the referenced file, attached as <field>_asset
+}
+
+// FieldValidationError describes a value that failed coercion or validation
+type FieldValidationError struct {
+	Field   string      `json:"field"`
+	Code    string      `json:"code"` // required, type, enum, pattern, format, range, length, download
+	Message string      `json:"message"`
+	Value   interface{} `json:"value,omitempty"`
+}
@@ -21,15 +21,36 @@ type Workflow struct {
 // WorkflowConfig contains the workflow execution configuration
 type WorkflowConfig struct {
 	StartURLs      []string          `json:"start_urls" yaml:"start_urls"`
-	Phases         []WorkflowPhase   `json:"phases" yaml:"phases"` // NEW: Phase-based workflow
+	StartSources   []StartSource     `json:"start_sources,omitempty" yaml:"start_sources,omitempty"` // Sitemaps and feeds expanded into start URLs
+	Phases         []WorkflowPhase   `json:"phases" yaml:"phases"`                                   // NEW: Phase-based workflow
 	MaxDepth       int               `json:"max_depth" yaml:"max_depth"`
 	RateLimitDelay int               `json:"rate_limit_delay" yaml:"rate_limit_delay"`
 	Headers        map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
 	Cookies        []Cookie          `json:"cookies,omitempty" yaml:"cookies,omitempty"`
 	Authentication *AuthConfig       `json:"authentication,omitempty" yaml:"authentication,omitempty"`
 	ProxyConfig    *ProxyConfig      `json:"proxy_config,omitempty" yaml:"proxy_config,omitempty"`
+	Dedup          *DedupConfig      `json:"dedup,omitempty" yaml:"dedup,omitempty"`
+	Scope          *ScopeConfig      `json:"scope,omitempty" yaml:"scope,omitempty"`
+	Schema         *ItemSchema       `json:"schema,omitempty" yaml:"schema,omitempty"` // Default output schema for phases without their own
 }
 
+// StartSource seeds the queue with the page URLs listed by sitemaps, robots.txt or RSS/Atom feeds
+type StartSource struct {
+	Type         string   `json:"type" yaml:"type"`                                         // sitemap, feed
+	URLs         []string `json:"urls" yaml:"urls"`                                         // Sitemaps, sitemap indexes, feeds, robots.txt or site roots
+	Marker       string   `json:"marker,omitempty" yaml:"marker,omitempty"`                 // Marker for URLFilter.Markers (default "start")
+	Include      []string `json:"include,omitempty" yaml:"include,omitempty"`               // Regex; URL must match at least one
+	Exclude      []string `json:"exclude,omitempty" yaml:"exclude,omitempty"`               // Regex; URL must match none
+	SinceLastRun bool     `json:"since_last_run,omitempty" yaml:"since_last_run,omitempty"` // Skip URLs not modified since the last completed execution
+	MaxURLs      int      `json:"max_urls,omitempty" yaml:"max_urls,omitempty"`             // 0 = unlimited
+}
+
+// Start source types
+const (
+	StartSourceSitemap = "sitemap"
+	StartSourceFeed    = "feed"
+)
+
 // Node represents a single workflow node (atomic task)
 type Node struct {
 	ID           string                 `json:"id" yaml:"id"`
@@ -46,12 +67,15 @@ type NodeType string
 
 const (
 	// URL Discovery nodes
-	NodeTypeFetch        NodeType = "fetch"
-	NodeTypeExtractLinks NodeType = "extract_links"
-	NodeTypeFilterURLs   NodeType = "filter_urls"
-	NodeTypeNavigate     NodeType = "navigate"
-	NodeTypePaginate     NodeType = "paginate"
-	NodeTypePlugin       NodeType = "plugin" // NEW: Plugin execution
+	NodeTypeFetch          NodeType = "fetch"
+	NodeTypeExtractLinks   NodeType = "extract_links"
+	NodeTypeFilterURLs     NodeType = "filter_urls"
+	NodeTypeNavigate       NodeType = "navigate"
+	NodeTypePaginate       NodeType = "paginate"
+	NodeTypePlugin         NodeType = "plugin"          // NEW: Plugin execution
+	NodeTypeIntercept      NodeType = "intercept"       // Network response capture and request blocking
+	NodeTypeInfiniteScroll NodeType = "infinite_scroll" // Scroll or click "load more" until the listing is exhausted
+	NodeTypeSitemap        NodeType = "sitemap"         // Discover URLs from sitemaps, robots.txt and RSS/Atom feeds
 
 	// Interaction nodes
 	NodeTypeClick      NodeType = "click"
@@ -64,10 +88,12 @@ const (
 	NodeTypeInput      NodeType = "input" // Added based on instruction's snippet
 
 	// Extraction nodes
-	NodeTypeExtract     NodeType = "extract"
-	NodeTypeExtractText NodeType = "extract_text"
-	NodeTypeExtractAttr NodeType = "extract_attr"
-	NodeTypeExtractJSON NodeType = "extract_json"
+	NodeTypeExtract           NodeType = "extract"
+	NodeTypeExtractText       NodeType = "extract_text"
+	NodeTypeExtractAttr       NodeType = "extract_attr"
+	NodeTypeExtractJSON       NodeType = "extract_json"
+	NodeTypeExtractStructured NodeType = "extract_structured" // JSON-LD, microdata, RDFa, OpenGraph, embedded state
+	NodeTypeDownload          NodeType = "download"           // Download images and files into the asset store
 
 	// Transformation nodes
 	NodeTypeTransform NodeType = "transform"
@@ -119,6 +145,44 @@ type ProxyConfig struct {
 	Password string `json:"password,omitempty" yaml:"password,omitempty"`
 }
 
+// DedupStrategy defines how URLs are compared when deduplicating the queue
+type DedupStrategy string
+
+const (
+	DedupStrategyExact     DedupStrategy = "exact"     // Hash the raw URL string (default)
+	DedupStrategyCanonical DedupStrategy = "canonical" // Hash the canonicalised URL
+)
+
+// DedupConfig controls URL canonicalisation and deduplication for a workflow
+type DedupConfig struct {
+	Strategy           DedupStrategy     `json:"strategy,omitempty" yaml:"strategy,omitempty"`
+	StripParams        []string          `json:"strip_params,omitempty" yaml:"strip_params,omitempty"` // Query params to drop, "utm_*" style prefixes allowed; canonical only
+	KeepDefaultParams  bool              `json:"keep_default_params,omitempty" yaml:"keep_default_params,omitempty"`
+	KeepFragment       bool              `json:"keep_fragment,omitempty" yaml:"keep_fragment,omitempty"`
+	StripTrailingSlash bool              `json:"strip_trailing_slash,omitempty" yaml:"strip_trailing_slash,omitempty"`
+	HonorCanonical     bool              `json:"honor_canonical,omitempty" yaml:"honor_canonical,omitempty"` // Use <link rel=canonical> when present
+	SeenBefore         *SeenBeforeConfig `json:"seen_before,omitempty" yaml:"seen_before,omitempty"`
+}
+
+// SeenBeforeConfig skips URLs already processed by a previous execution of the same workflow
+type SeenBeforeConfig struct {
+	Enabled bool     `json:"enabled" yaml:"enabled"`
+	Markers []string `json:"markers,omitempty" yaml:"markers,omitempty"` // Only skip URLs with these markers (empty = all discovered URLs)
+}
+
+// ScopeConfig restricts which discovered URLs may be enqueued during an execution
+type ScopeConfig struct {
+	AllowedDomains     []string `json:"allowed_domains,omitempty" yaml:"allowed_domains,omitempty"`
+	BlockedDomains     []string `json:"blocked_domains,omitempty" yaml:"blocked_domains,omitempty"`
+	SameDomainOnly     bool     `json:"same_domain_only,omitempty" yaml:"same_domain_only,omitempty"`         // Allow only start URL and start source hosts when allowed_domains is empty
+	AllowSubdomains    bool     `json:"allow_subdomains,omitempty" yaml:"allow_subdomains,omitempty"`         // Allowed domains also match their subdomains
+	BlockSubdomains    bool     `json:"block_subdomains,omitempty" yaml:"block_subdomains,omitempty"`         // Blocked domains also match their subdomains
+	IncludePatterns    []string `json:"include_patterns,omitempty" yaml:"include_patterns,omitempty"`         // Regex; URL must match at least one
+	ExcludePatterns    []string `json:"exclude_patterns,omitempty" yaml:"exclude_patterns,omitempty"`         // Regex; URL must match none
+	MaxURLs            int      `json:"max_urls,omitempty" yaml:"max_urls,omitempty"`                         // Per-execution URL budget (0 = unlimited)
+	MaxDurationSeconds int      `json:"max_duration_seconds,omitempty" yaml:"max_duration_seconds,omitempty"` // Per-execution time budget (0 = unlimited)
+}
+
 // RetryConfig defines retry behavior for a node
 type RetryConfig struct {
 	MaxRetries int `json:"max_retries" yaml:"max_retries"`
@@ -157,3 +221,44 @@ func (wc *WorkflowConfig) Scan(value interface{}) error {
 func (wc WorkflowConfig) Value() (interface{}, error) {
 	return json.Marshal(wc)
 }
+
+// PluginRef is a plugin node of a workflow
+type PluginRef struct {
+	PhaseID    string `json:"phase_id"`
+	NodeID     string `json:"node_id"`
+	Slug       string `json:"slug"`
+	Constraint string `json:"constraint,omitempty"` // plugin_version param; empty means the installed version
+}
+
+// PluginRefs returns the plugin nodes of the workflow, including the branches of conditional nodes
+func (wc *WorkflowConfig) PluginRefs() []PluginRef {
+	var refs []PluginRef
+	for _, phase := range wc.Phases {
+		for _, node := range phase.Nodes {
+			refs = appendPluginRefs(refs, phase.ID, node.ID, string(node.Type), node.Params)
+		}
+	}
+	return refs
+}
+
+// appendPluginRefs adds the node if it is a plugin node, then any nodes nested in its params
+func appendPluginRefs(refs []PluginRef, phaseID, nodeID, nodeType string, params map[string]interface{}) []PluginRef {
+	if NodeType(nodeType) == NodeTypePlugin {
+		slug, _ := params["plugin_slug"].(string)
+		constraint, _ := params["plugin_version"].(string)
+		if slug != "" {
+			refs = append(refs, PluginRef{PhaseID: phaseID, NodeID: nodeID, Slug: slug, Constraint: constraint})
+		}
+	}
+	for _, value := range params {
+		nested, ok := value.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if nestedType, ok := nested["type"].(string); ok {
+			nestedParams, _ := nested["params"].(map[string]interface{})
+			refs = appendPluginRefs(refs, phaseID, nodeID, nestedType, nestedParams)
+		}
+	}
+	return refs
+}
@@ -13,6 +13,15 @@ type WorkflowVersion struct {
 	Config       WorkflowConfig `json:"config" db:"config"`
 	ChangeReason string         `json:"change_reason" db:"change_reason"`
 	CreatedAt    time.Time      `json:"created_at" db:"created_at"`
+
+	// Plugins records the version each plugin node resolved to when the version was saved
+	Plugins []ResolvedPlugin `json:"plugins" db:"plugins"`
+}
+
+// ResolvedPlugin is a plugin node and the plugin version it resolved to
+type ResolvedPlugin struct {
+	PluginRef
+	Version string `json:"version"` // Empty when no installed version satisfied the constraint
 }
 
 // Scan implements sql.Scanner for WorkflowVersion
@@ -0,0 +1,104 @@
+package plugins
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/uzzalhcse/crawlify/internal/browser"
+)
+
+// Browser is the page API available to every plugin runtime
+// In-process plugins get it backed by the page directly; RPC plugins get a proxy whose
+// calls are executed by the host, since the browser lives in the crawler process
+type Browser interface {
+	// URL returns the current page URL
+	URL() (string, error)
+	// Navigate loads url in the page
+	Navigate(url string) error
+	// Content returns the page HTML
+	Content() (string, error)
+	// Evaluate runs a JavaScript expression or function in the page and returns its JSON result
+	Evaluate(expression string, arg interface{}) (interface{}, error)
+	// Count returns the number of elements matching selector
+	Count(selector string) (int, error)
+	// Texts returns the trimmed text content of every element matching selector
+	Texts(selector string) ([]string, error)
+	// Attributes returns the attribute of every element matching selector that has it
+	Attributes(selector, attribute string) ([]string, error)
+	// Click clicks the first element matching selector
+	Click(selector string) error
+	// WaitForSelector waits until an element matching selector is visible
+	WaitForSelector(selector string, timeout time.Duration) error
+}
+
+// localBrowser implements Browser on a page in this process
+type localBrowser struct {
+	ctx *browser.BrowserContext
+}
+
+// NewLocalBrowser returns a Browser backed by a browser context
+func NewLocalBrowser(ctx *browser.BrowserContext) Browser {
+	if ctx == nil || ctx.Page == nil {
+		return nil
+	}
+	return &localBrowser{ctx: ctx}
+}
+
+func (b *localBrowser) URL() (string, error) {
+	return b.ctx.Page.URL(), nil
+}
+
+func (b *localBrowser) Navigate(url string) error {
+	_, err := b.ctx.Navigate(url)
+	return err
+}
+
+func (b *localBrowser) Content() (string, error) {
+	return b.ctx.Content()
+}
+
+func (b *localBrowser) Evaluate(expression string, arg interface{}) (interface{}, error) {
+	if arg == nil {
+		return b.ctx.Page.Evaluate(expression)
+	}
+	return b.ctx.Page.Evaluate(expression, arg)
+}
+
+func (b *localBrowser) Count(selector string) (int, error) {
+	return b.ctx.Page.Locator(selector).Count()
+}
+
+func (b *localBrowser) Texts(selector string) ([]string, error) {
+	texts, err := b.ctx.Page.Locator(selector).AllTextContents()
+	if err != nil {
+		return nil, err
+	}
+	for i, text := range texts {
+		texts[i] = strings.TrimSpace(text)
+	}
+	return texts, nil
+}
+
+func (b *localBrowser) Attributes(selector, attribute string) ([]string, error) {
+	raw, err := b.ctx.Page.Locator(selector).EvaluateAll(`(elements, name) => elements
+		.map(el => el.getAttribute(name))
+		.filter(value => value !== null)`, attribute)
+	if err != nil {
+		return nil, err
+	}
+	list, _ := raw.([]interface{})
+	values := make([]string, 0, len(list))
+	for _, v := range list {
+		values = append(values, fmt.Sprint(v))
+	}
+	return values, nil
+}
+
+func (b *localBrowser) Click(selector string) error {
+	return b.ctx.Page.Locator(selector).First().Click()
+}
+
+func (b *localBrowser) WaitForSelector(selector string, timeout time.Duration) error {
+	return b.ctx.WaitForSelector(selector, timeout)
+}
@@ -0,0 +1,78 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/uzzalhcse/crawlify/internal/browser"
+)
+
+// CapturedResponse is a network response captured from the page
+type CapturedResponse = browser.CapturedResponse
+
+// ResponseCapture records the page's responses matching a pattern until it is stopped
+type ResponseCapture = browser.ResponseCapture
+
+// NetworkHelpers captures the responses a page receives, e.g. the JSON API behind a listing
+// It needs the page itself, so it is only available to in-process plugins (input.BrowserContext)
+type NetworkHelpers struct {
+	ctx *browser.BrowserContext
+}
+
+// NewNetworkHelpers creates network capture utilities
+func (s *SDK) NewNetworkHelpers(ctx *browser.BrowserContext) *NetworkHelpers {
+	return &NetworkHelpers{ctx: ctx}
+}
+
+// StartCapture starts capturing responses whose URL matches pattern: "re:<regex>", a glob with "*", or a substring
+// Responses already received are not captured; start it before the action that triggers the requests
+func (nh *NetworkHelpers) StartCapture(pattern string) (*ResponseCapture, error) {
+	if nh.ctx == nil || nh.ctx.Page == nil {
+		return nil, fmt.Errorf("network capture needs the page; it is not available out of process")
+	}
+	return nh.ctx.CaptureResponses(browser.InterceptRule{Name: "capture", URLPattern: pattern}, 0)
+}
+
+// CaptureJSON runs action (a click, scroll or navigation) and returns the JSON bodies of the matching
+// responses it triggered, waiting up to timeout for the first one. Responses that are not JSON are skipped
+func (nh *NetworkHelpers) CaptureJSON(ctx context.Context, pattern string, timeout time.Duration, action func() error) ([]interface{}, error) {
+	capture, err := nh.StartCapture(pattern)
+	if err != nil {
+		return nil, err
+	}
+	defer capture.Stop()
+
+	if action != nil {
+		if err := action(); err != nil {
+			return nil, err
+		}
+	}
+
+	done := make(chan []CapturedResponse, 1)
+	go func() { done <- capture.Wait(1, timeout) }()
+	var captured []CapturedResponse
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case captured = <-done:
+	}
+
+	bodies := make([]interface{}, 0, len(captured))
+	for _, resp := range captured {
+		if _, isText := resp.Body.(string); !isText && resp.Body != nil {
+			bodies = append(bodies, resp.Body)
+		}
+	}
+	return bodies, nil
+}
+
+// Decode converts a captured JSON body into v, e.g. a struct describing the API response
+func (nh *NetworkHelpers) Decode(body interface{}, v interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
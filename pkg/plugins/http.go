@@ -0,0 +1,328 @@
+package plugins
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uzzalhcse/crawlify/pkg/models"
+)
+
+// DefaultUserAgent is sent by HTTPClient when neither the policy nor the request sets one
+const DefaultUserAgent = "Crawlify/1.0"
+
+// MaxFetchBytes caps the response bodies http.fetch returns to RPC plugins (10MB)
+const MaxFetchBytes = 10 * 1024 * 1024
+
+// maxRetryAfter caps how long a Retry-After header can make a request wait
+const maxRetryAfter = time.Minute
+
+// HTTPPolicy configures an HTTPClient
+type HTTPPolicy struct {
+	Proxy      *models.ProxyConfig // Used when enabled (the workflow's proxy_config)
+	Delay      time.Duration       // Minimum time between requests to the same host (the workflow's rate_limit_delay)
+	Headers    map[string]string   // Sent unless the request sets them (the workflow's headers)
+	MaxRetries int                 // Retries of network errors, 429 and 5xx responses; 0 = 3, negative = none
+	Timeout    time.Duration       // Per attempt; 0 = 30s
+	Transport  http.RoundTripper   // Replaces the network, e.g. to forward requests to the host
+}
+
+// HTTPClient is a polite HTTP client for API calls made by plugins: it goes through the execution's
+// proxy, spaces requests to each host by the workflow's rate limit and backs off on 429 and 5xx,
+// honouring Retry-After. It is safe for concurrent use
+type HTTPClient struct {
+	client     *http.Client
+	headers    map[string]string
+	delay      time.Duration
+	maxRetries int
+	remote     bool // Requests are sent by the host, which sets the headers
+
+	mu   sync.Mutex
+	next map[string]time.Time // host -> earliest start of its next request
+}
+
+// NewHTTPClient creates a client following policy
+func NewHTTPClient(policy HTTPPolicy) (*HTTPClient, error) {
+	transport := policy.Transport
+	if transport == nil {
+		base := http.DefaultTransport.(*http.Transport).Clone()
+		if policy.Proxy != nil && policy.Proxy.Enabled && policy.Proxy.Server != "" {
+			proxyURL, err := parseProxy(policy.Proxy)
+			if err != nil {
+				return nil, err
+			}
+			base.Proxy = http.ProxyURL(proxyURL)
+		}
+		transport = base
+	}
+
+	timeout := policy.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	maxRetries := policy.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = 3
+	} else if maxRetries < 0 {
+		maxRetries = 0
+	}
+
+	return &HTTPClient{
+		client:     &http.Client{Transport: transport, Timeout: timeout},
+		headers:    policy.Headers,
+		delay:      policy.Delay,
+		maxRetries: maxRetries,
+		next:       make(map[string]time.Time),
+	}, nil
+}
+
+// parseProxy turns a proxy config into a proxy URL with its credentials
+func parseProxy(proxy *models.ProxyConfig) (*url.URL, error) {
+	server := proxy.Server
+	if !strings.Contains(server, "://") {
+		server = "http://" + server
+	}
+	proxyURL, err := url.Parse(server)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy server '%s': %w", proxy.Server, err)
+	}
+	if proxy.Username != "" {
+		proxyURL.User = url.UserPassword(proxy.Username, proxy.Password)
+	}
+	return proxyURL, nil
+}
+
+// Do sends a request, waiting for the host's rate limit and retrying transient failures
+// Requests with a body are only retried when it can be replayed (see http.Request.GetBody)
+func (c *HTTPClient) Do(req *http.Request) (*http.Response, error) {
+	for name, value := range c.headers {
+		if req.Header.Get(name) == "" {
+			req.Header.Set(name, value)
+		}
+	}
+	if req.Header.Get("User-Agent") == "" && !c.remote {
+		req.Header.Set("User-Agent", DefaultUserAgent)
+	}
+
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		if err := c.wait(ctx, req.URL.Host); err != nil {
+			return nil, err
+		}
+		resp, err := c.client.Do(req)
+
+		retryable := err != nil || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		if !retryable || attempt >= c.maxRetries || (req.Body != nil && req.GetBody == nil) {
+			return resp, err
+		}
+
+		backoff := time.Duration(1<<attempt) * time.Second
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+				backoff = retryAfter
+			}
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(backoff):
+		}
+
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+	}
+}
+
+// Get sends a GET request
+func (c *HTTPClient) Get(ctx context.Context, rawURL string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return c.Do(req)
+}
+
+// GetJSON sends a GET request and decodes the JSON response into v; non-2xx responses are errors
+func (c *HTTPClient) GetJSON(ctx context.Context, rawURL string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("GET %s: %s", rawURL, resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", rawURL, err)
+	}
+	return nil
+}
+
+// wait blocks until a request to host may start and reserves the slot
+func (c *HTTPClient) wait(ctx context.Context, host string) error {
+	if c.delay <= 0 {
+		return nil
+	}
+	c.mu.Lock()
+	now := time.Now()
+	start := c.next[host]
+	if start.Before(now) {
+		start = now
+	}
+	c.next[host] = start.Add(c.delay)
+	c.mu.Unlock()
+
+	if wait := time.Until(start); wait > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+	return nil
+}
+
+// parseRetryAfter reads a Retry-After header in seconds or as an HTTP date
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	var wait time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		wait = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(value); err == nil {
+		wait = time.Until(at)
+	} else {
+		return 0, false
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	if wait > maxRetryAfter {
+		wait = maxRetryAfter
+	}
+	return wait, true
+}
+
+// NewRemoteHTTPClient returns an HTTPClient whose requests are sent by the host's client for a session,
+// so they share the execution's proxy and rate limit
+func NewRemoteHTTPClient(host Caller, session string) *HTTPClient {
+	client, _ := NewHTTPClient(HTTPPolicy{
+		MaxRetries: -1, // The host waits and retries
+		Timeout:    5 * time.Minute,
+		Transport:  &fetchTransport{host: host, session: session},
+	})
+	client.remote = true
+	return client
+}
+
+// fetchTransport sends requests with http.fetch
+type fetchTransport struct {
+	host    Caller
+	session string
+}
+
+func (t *fetchTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	params := HTTPFetchParams{
+		Session: t.session,
+		Method:  req.Method,
+		URL:     req.URL.String(),
+		Headers: make(map[string]string, len(req.Header)),
+	}
+	for name := range req.Header {
+		params.Headers[name] = req.Header.Get(name)
+	}
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		params.Body = string(body)
+	}
+
+	var result HTTPFetchResult
+	if err := t.host.Call(req.Context(), MethodHTTPFetch, params, &result); err != nil {
+		return nil, err
+	}
+	resp := &http.Response{
+		Status:        fmt.Sprintf("%d %s", result.Status, http.StatusText(result.Status)),
+		StatusCode:    result.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header, len(result.Headers)),
+		Body:          io.NopCloser(strings.NewReader(result.Body)),
+		ContentLength: int64(len(result.Body)),
+		Request:       req,
+	}
+	for name, value := range result.Headers {
+		resp.Header.Set(name, value)
+	}
+	return resp, nil
+}
+
+// ServeHTTPFetch executes an RPC plugin's http.fetch with the HTTP client of its call
+func ServeHTTPFetch(ctx context.Context, client *HTTPClient, p HTTPFetchParams) (*HTTPFetchResult, error) {
+	if client == nil {
+		return nil, fmt.Errorf("no HTTP client is available for this call")
+	}
+	target, err := url.Parse(p.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") {
+		return nil, &RPCError{Code: RPCCodeInvalidParams, Message: fmt.Sprintf("invalid URL '%s'", p.URL)}
+	}
+	method := p.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body io.Reader
+	if p.Body != "" {
+		body = bytes.NewReader([]byte(p.Body))
+	}
+	req, err := http.NewRequestWithContext(ctx, method, p.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range p.Headers {
+		req.Header.Set(name, value)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxFetchBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxFetchBytes {
+		return nil, fmt.Errorf("response exceeds %d bytes", MaxFetchBytes)
+	}
+	headers := make(map[string]string, len(resp.Header))
+	for name := range resp.Header {
+		headers[name] = resp.Header.Get(name)
+	}
+	return &HTTPFetchResult{Status: resp.StatusCode, Headers: headers, Body: string(data)}, nil
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/uzzalhcse/crawlify/internal/browser"
+	"github.com/uzzalhcse/crawlify/internal/extraction"
 	"github.com/uzzalhcse/crawlify/pkg/models"
 )
 
@@ -23,9 +24,12 @@ type PluginInfo struct {
 
 // DiscoveryInput contains all data needed for discovery phase execution
 type DiscoveryInput struct {
-	// Browser context for web interactions
+	// Browser context for web interactions; nil for plugins running out of process
 	BrowserContext *browser.BrowserContext
 
+	// Browser gives access to the page in every plugin runtime
+	Browser Browser
+
 	// Current URL being processed
 	URL string
 
@@ -40,6 +44,14 @@ type DiscoveryInput struct {
 
 	// Execution ID for tracking
 	ExecutionID string
+
+	// HTTP sends API requests through the execution's proxy and rate limit; nil outside workflow executions
+	HTTP *HTTPClient
+
+	// WorkflowStore keeps values across the workflow's executions, ExecutionStore for this execution only
+	// Both belong to the plugin and are nil outside workflow executions
+	WorkflowStore  KVStore
+	ExecutionStore KVStore
 }
 
 // DiscoveryOutput contains results from discovery phase
@@ -59,9 +71,12 @@ type DiscoveryOutput struct {
 
 // ExtractionInput contains all data needed for extraction phase execution
 type ExtractionInput struct {
-	// Browser context for web interactions
+	// Browser context for web interactions; nil for plugins running out of process
 	BrowserContext *browser.BrowserContext
 
+	// Browser gives access to the page in every plugin runtime
+	Browser Browser
+
 	// Current URL being processed
 	URL string
 
@@ -76,6 +91,14 @@ type ExtractionInput struct {
 
 	// Execution ID for tracking
 	ExecutionID string
+
+	// HTTP sends API requests through the execution's proxy and rate limit; nil outside workflow executions
+	HTTP *HTTPClient
+
+	// WorkflowStore keeps values across the workflow's executions, ExecutionStore for this execution only
+	// Both belong to the plugin and are nil outside workflow executions
+	WorkflowStore  KVStore
+	ExecutionStore KVStore
 }
 
 // ExtractionOutput contains results from extraction phase
@@ -91,6 +114,16 @@ type ExtractionOutput struct {
 
 	// Optional: Additional URLs discovered during extraction
 	DiscoveredURLs []string
+
+	// Optional: Records extracted from the page, e.g. every product of a listing (see ItemEmitter)
+	Items []Item
+}
+
+// Item is one record extracted from a page
+type Item struct {
+	SchemaName string                 // Defaults to the output's SchemaName
+	Key        string                 // Identifies the item within its schema, e.g. a SKU; empty when unknown
+	Data       map[string]interface{} // Field values
 }
 
 // DiscoveryPlugin interface for discovery phase plugins
@@ -99,6 +132,7 @@ type DiscoveryPlugin interface {
 	Info() PluginInfo
 
 	// Discover executes discovery logic and returns discovered URLs
+	// ctx is cancelled when the execution is stopped, paused or fails
 	Discover(ctx context.Context, input *DiscoveryInput) (*DiscoveryOutput, error)
 
 	// Validate checks if plugin configuration is valid
@@ -114,6 +148,7 @@ type ExtractionPlugin interface {
 	Info() PluginInfo
 
 	// Extract executes extraction logic and returns structured data
+	// ctx is cancelled when the execution is stopped, paused or fails
 	Extract(ctx context.Context, input *ExtractionInput) (*ExtractionOutput, error)
 
 	// Validate checks if plugin configuration is valid
@@ -123,22 +158,56 @@ type ExtractionPlugin interface {
 	ConfigSchema() map[string]interface{}
 }
 
+// Transform is a custom field transform contributed by a plugin
+type Transform = extraction.Transform
+
+// TransformParam declares a parameter accepted by a custom transform
+type TransformParam = extraction.TransformParam
+
+// TransformContext carries page information available to transforms
+type TransformContext = extraction.TransformContext
+
+// TransformProvider is implemented by plugins that register custom transforms
+// Transforms become available to extract fields while the plugin is loaded
+type TransformProvider interface {
+	Transforms() []Transform
+}
+
+// Initializer is implemented by plugins that set up resources (connections, caches) once per instance
+// Init is called with the node's config before the instance's first Discover or Extract
+type Initializer interface {
+	Init(config map[string]interface{}) error
+}
+
+// ExecutionHooks is implemented by plugins that act at the boundaries of each workflow execution using them
+// OnExecutionStart is called before the execution's first call; an error fails its calls to the plugin.
+// OnExecutionEnd is called when the execution finishes, fails or is paused
+type ExecutionHooks interface {
+	OnExecutionStart(ctx context.Context, executionID string) error
+	OnExecutionEnd(ctx context.Context, executionID string) error
+}
+
+// Closer is implemented by plugins that release resources when their instance is discarded
+type Closer interface {
+	Close() error
+}
+
 // PluginMetrics tracks plugin execution metrics
 type PluginMetrics struct {
-	ExecutionCount  int64
-	TotalDuration   time.Duration
-	AverageDuration time.Duration
-	SuccessCount    int64
-	FailureCount    int64
-	LastExecutedAt  time.Time
-	URLsDiscovered  int64
-	ItemsExtracted  int64
+	ExecutionCount  int64         `json:"execution_count"`
+	TotalDuration   time.Duration `json:"total_duration"`
+	AverageDuration time.Duration `json:"average_duration"`
+	SuccessCount    int64         `json:"success_count"`
+	FailureCount    int64         `json:"failure_count"`
+	LastExecutedAt  time.Time     `json:"last_executed_at"`
+	URLsDiscovered  int64         `json:"urls_discovered"`
+	ItemsExtracted  int64         `json:"items_extracted"`
 }
 
 // PluginHealth represents the health status of a plugin
 type PluginHealth struct {
-	IsHealthy        bool
-	LastError        string
-	LastErrorAt      time.Time
-	ConsecutiveFails int
+	IsHealthy        bool      `json:"is_healthy"`
+	LastError        string    `json:"last_error,omitempty"`
+	LastErrorAt      time.Time `json:"last_error_at"`
+	ConsecutiveFails int       `json:"consecutive_fails"`
 }
@@ -0,0 +1,53 @@
+package plugins
+
+import "sync"
+
+// ItemEmitter collects the items an extraction plugin finds on a page for ExtractionOutput.Items
+// Emitting a key twice within a schema replaces the first item. It is safe for concurrent use
+type ItemEmitter struct {
+	schemaName string
+
+	mu    sync.Mutex
+	items []Item
+	index map[string]int // schema + key -> position in items
+}
+
+// NewItemEmitter creates an emitter whose items default to schemaName
+func (s *SDK) NewItemEmitter(schemaName string) *ItemEmitter {
+	return &ItemEmitter{schemaName: schemaName, index: make(map[string]int)}
+}
+
+// Emit adds an item of the emitter's schema
+func (e *ItemEmitter) Emit(key string, data map[string]interface{}) {
+	e.EmitSchema(e.schemaName, key, data)
+}
+
+// EmitSchema adds an item of another schema, e.g. the reviews found next to a product
+func (e *ItemEmitter) EmitSchema(schemaName, key string, data map[string]interface{}) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	item := Item{SchemaName: schemaName, Key: key, Data: data}
+	if key != "" {
+		id := schemaName + "\x00" + key
+		if i, ok := e.index[id]; ok {
+			e.items[i] = item
+			return
+		}
+		e.index[id] = len(e.items)
+	}
+	e.items = append(e.items, item)
+}
+
+// Items returns the emitted items in emission order
+func (e *ItemEmitter) Items() []Item {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Item(nil), e.items...)
+}
+
+// Len returns the number of items emitted
+func (e *ItemEmitter) Len() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.items)
+}
@@ -0,0 +1,231 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/uzzalhcse/crawlify/pkg/models"
+)
+
+// KVStore keeps values between plugin calls, e.g. a session token, a cursor or the IDs already seen
+// Values are stored as JSON, so they survive restarts and are shared by every runtime
+type KVStore interface {
+	// Get decodes the value stored under key into value and reports whether it was found
+	Get(ctx context.Context, key string, value interface{}) (bool, error)
+	// Set stores value under key, replacing the previous one
+	Set(ctx context.Context, key string, value interface{}) error
+	// Delete removes key
+	Delete(ctx context.Context, key string) error
+	// Keys returns the keys starting with prefix, sorted
+	Keys(ctx context.Context, prefix string) ([]string, error)
+}
+
+// KVBackend stores the values of every plugin's stores; the crawler uses Postgres
+type KVBackend interface {
+	GetValue(ctx context.Context, scope models.PluginKVScope, key string) (json.RawMessage, bool, error)
+	SetValue(ctx context.Context, scope models.PluginKVScope, key string, value json.RawMessage) error
+	DeleteValue(ctx context.Context, scope models.PluginKVScope, key string) error
+	ListKeys(ctx context.Context, scope models.PluginKVScope, prefix string) ([]string, error)
+}
+
+// MaxKVValueBytes caps the encoded size of a stored value (1MB)
+const MaxKVValueBytes = 1 << 20
+
+// NewKVStore returns the store of a scope; nil when backend is nil or the scope has no workflow
+func NewKVStore(backend KVBackend, scope models.PluginKVScope) KVStore {
+	if backend == nil || scope.WorkflowID == "" {
+		return nil
+	}
+	return &scopedKV{backend: backend, scope: scope}
+}
+
+// scopedKV is a KVStore over one scope of a backend
+type scopedKV struct {
+	backend KVBackend
+	scope   models.PluginKVScope
+}
+
+func (s *scopedKV) Get(ctx context.Context, key string, value interface{}) (bool, error) {
+	raw, ok, err := s.backend.GetValue(ctx, s.scope, key)
+	if err != nil || !ok {
+		return false, err
+	}
+	if err := json.Unmarshal(raw, value); err != nil {
+		return false, fmt.Errorf("failed to decode value of '%s': %w", key, err)
+	}
+	return true, nil
+}
+
+func (s *scopedKV) Set(ctx context.Context, key string, value interface{}) error {
+	raw, err := encodeKVValue(key, value)
+	if err != nil {
+		return err
+	}
+	return s.backend.SetValue(ctx, s.scope, key, raw)
+}
+
+func (s *scopedKV) Delete(ctx context.Context, key string) error {
+	if key == "" {
+		return fmt.Errorf("key is required")
+	}
+	return s.backend.DeleteValue(ctx, s.scope, key)
+}
+
+func (s *scopedKV) Keys(ctx context.Context, prefix string) ([]string, error) {
+	return s.backend.ListKeys(ctx, s.scope, prefix)
+}
+
+func encodeKVValue(key string, value interface{}) (json.RawMessage, error) {
+	if key == "" {
+		return nil, fmt.Errorf("key is required")
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode value of '%s': %w", key, err)
+	}
+	if len(raw) > MaxKVValueBytes {
+		return nil, fmt.Errorf("value of '%s' exceeds %d bytes", key, MaxKVValueBytes)
+	}
+	return raw, nil
+}
+
+// MemoryKV is a KVBackend kept in memory, for tests and runs without a database
+type MemoryKV struct {
+	mu     sync.RWMutex
+	values map[models.PluginKVScope]map[string]json.RawMessage
+}
+
+// NewMemoryKV creates an empty in-memory backend
+func NewMemoryKV() *MemoryKV {
+	return &MemoryKV{values: make(map[models.PluginKVScope]map[string]json.RawMessage)}
+}
+
+// GetValue returns the value stored under key
+func (m *MemoryKV) GetValue(ctx context.Context, scope models.PluginKVScope, key string) (json.RawMessage, bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	value, ok := m.values[scope][key]
+	return value, ok, nil
+}
+
+// SetValue stores value under key
+func (m *MemoryKV) SetValue(ctx context.Context, scope models.PluginKVScope, key string, value json.RawMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.values[scope] == nil {
+		m.values[scope] = make(map[string]json.RawMessage)
+	}
+	m.values[scope][key] = append(json.RawMessage(nil), value...)
+	return nil
+}
+
+// DeleteValue removes key
+func (m *MemoryKV) DeleteValue(ctx context.Context, scope models.PluginKVScope, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.values[scope], key)
+	return nil
+}
+
+// ListKeys returns the keys starting with prefix, sorted
+func (m *MemoryKV) ListKeys(ctx context.Context, scope models.PluginKVScope, prefix string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	keys := []string{}
+	for key := range m.values[scope] {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// remoteKV is a KVStore whose calls are executed by the host
+type remoteKV struct {
+	host    Caller
+	session string
+	scope   string
+}
+
+// NewRemoteKVStore returns a KVStore that forwards calls for a session to host
+// scope is KVScopeWorkflow or KVScopeExecution
+func NewRemoteKVStore(host Caller, session, scope string) KVStore {
+	return &remoteKV{host: host, session: session, scope: scope}
+}
+
+func (s *remoteKV) Get(ctx context.Context, key string, value interface{}) (bool, error) {
+	var result KVResult
+	if err := s.host.Call(ctx, MethodKVGet, KVParams{Session: s.session, Scope: s.scope, Key: key}, &result); err != nil {
+		return false, err
+	}
+	if !result.Found {
+		return false, nil
+	}
+	if err := json.Unmarshal(result.Value, value); err != nil {
+		return false, fmt.Errorf("failed to decode value of '%s': %w", key, err)
+	}
+	return true, nil
+}
+
+func (s *remoteKV) Set(ctx context.Context, key string, value interface{}) error {
+	raw, err := encodeKVValue(key, value)
+	if err != nil {
+		return err
+	}
+	return s.host.Call(ctx, MethodKVSet, KVParams{Session: s.session, Scope: s.scope, Key: key, Value: raw}, nil)
+}
+
+func (s *remoteKV) Delete(ctx context.Context, key string) error {
+	return s.host.Call(ctx, MethodKVDelete, KVParams{Session: s.session, Scope: s.scope, Key: key}, nil)
+}
+
+func (s *remoteKV) Keys(ctx context.Context, prefix string) ([]string, error) {
+	var result KVResult
+	if err := s.host.Call(ctx, MethodKVKeys, KVParams{Session: s.session, Scope: s.scope, Prefix: prefix}, &result); err != nil {
+		return nil, err
+	}
+	return result.Keys, nil
+}
+
+// ServeKV executes a kv.* request of an out-of-process plugin against the stores of its call
+func ServeKV(ctx context.Context, workflowStore, executionStore KVStore, method string, p KVParams) (interface{}, error) {
+	store := workflowStore
+	if p.Scope == KVScopeExecution {
+		store = executionStore
+	} else if p.Scope != KVScopeWorkflow {
+		return nil, &RPCError{Code: RPCCodeInvalidParams, Message: fmt.Sprintf("unknown store scope '%s'", p.Scope)}
+	}
+	if store == nil {
+		return nil, fmt.Errorf("no %s store is available for this call", p.Scope)
+	}
+
+	switch method {
+	case MethodKVGet:
+		var value json.RawMessage
+		found, err := store.Get(ctx, p.Key, &value)
+		if err != nil {
+			return nil, err
+		}
+		return &KVResult{Found: found, Value: value}, nil
+	case MethodKVSet:
+		if len(p.Value) == 0 {
+			return nil, &RPCError{Code: RPCCodeInvalidParams, Message: "value is required"}
+		}
+		return nil, store.Set(ctx, p.Key, p.Value)
+	case MethodKVDelete:
+		return nil, store.Delete(ctx, p.Key)
+	case MethodKVKeys:
+		keys, err := store.Keys(ctx, p.Prefix)
+		if err != nil {
+			return nil, err
+		}
+		return &KVResult{Keys: keys}, nil
+	default:
+		return nil, &RPCError{Code: RPCCodeMethodNotFound, Message: "method not found: " + method}
+	}
+}
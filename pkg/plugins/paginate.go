@@ -0,0 +1,164 @@
+package plugins
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultMaxPages bounds a Paginator without MaxPages
+const defaultMaxPages = 50
+
+// PaginatorOptions configures how a Paginator moves from page to page
+// Set NextSelector to click a "next" control, or URLPattern to load numbered pages
+type PaginatorOptions struct {
+	NextSelector string // Clicked to reach the next page; pagination ends when it is missing
+	URLPattern   string // URL of page N with "{page}" in place of the number, e.g. "https://shop.example.com/list?page={page}"
+	StartPage    int    // Number of the page already loaded; default 1
+	MaxPages     int    // Pages visited at most, the current one included; default 50
+
+	// WaitSelector is waited for after each move; when it does not appear the page is
+	// considered empty and pagination ends
+	WaitSelector string
+	WaitTimeout  time.Duration // Default 10s
+	Delay        time.Duration // Pause after each move, e.g. for lazy content
+}
+
+// Paginator walks the pages of a listing in the page given to the plugin
+type Paginator struct {
+	browser Browser
+	opts    PaginatorOptions
+}
+
+// NewPaginator creates a paginator over browser
+func (s *SDK) NewPaginator(browser Browser, opts PaginatorOptions) *Paginator {
+	if opts.StartPage <= 0 {
+		opts.StartPage = 1
+	}
+	if opts.MaxPages <= 0 {
+		opts.MaxPages = defaultMaxPages
+	}
+	if opts.WaitTimeout <= 0 {
+		opts.WaitTimeout = 10 * time.Second
+	}
+	return &Paginator{browser: browser, opts: opts}
+}
+
+// Each calls fn with the number of every page, starting with the loaded one, until fn returns false,
+// there is no next page, MaxPages is reached or ctx is cancelled. It returns the number of pages visited
+func (p *Paginator) Each(ctx context.Context, fn func(page int) (bool, error)) (int, error) {
+	if p.browser == nil {
+		return 0, fmt.Errorf("paginator has no browser")
+	}
+	if p.opts.NextSelector == "" && p.opts.URLPattern == "" {
+		return 0, fmt.Errorf("paginator needs a next selector or a URL pattern")
+	}
+
+	page := p.opts.StartPage
+	for visited := 1; ; visited++ {
+		if err := ctx.Err(); err != nil {
+			return visited - 1, err
+		}
+		more, err := fn(page)
+		if err != nil || !more || visited >= p.opts.MaxPages {
+			return visited, err
+		}
+
+		page++
+		moved, err := p.next(ctx, page)
+		if err != nil || !moved {
+			return visited, err
+		}
+	}
+}
+
+// next moves to page and reports whether it has content
+func (p *Paginator) next(ctx context.Context, page int) (bool, error) {
+	if p.opts.URLPattern != "" {
+		target := strings.ReplaceAll(p.opts.URLPattern, "{page}", strconv.Itoa(page))
+		if err := p.browser.Navigate(target); err != nil {
+			return false, fmt.Errorf("failed to load page %d: %w", page, err)
+		}
+	} else {
+		count, err := p.browser.Count(p.opts.NextSelector)
+		if err != nil || count == 0 {
+			return false, err
+		}
+		before, err := p.fingerprint()
+		if err != nil {
+			return false, err
+		}
+		if err := p.browser.Click(p.opts.NextSelector); err != nil {
+			return false, fmt.Errorf("failed to click next page: %w", err)
+		}
+		// A disabled "next" control leaves the page as it was
+		changed, err := p.waitChange(ctx, before)
+		if err != nil || !changed {
+			return false, err
+		}
+	}
+
+	if err := p.settle(ctx); err != nil {
+		return false, err
+	}
+	return p.waitContent()
+}
+
+// waitChange polls the page until its fingerprint differs from before or WaitTimeout passes
+func (p *Paginator) waitChange(ctx context.Context, before string) (bool, error) {
+	deadline := time.Now().Add(p.opts.WaitTimeout)
+	for {
+		after, err := p.fingerprint()
+		if err != nil {
+			return false, err
+		}
+		if after != before {
+			return true, nil
+		}
+		if time.Now().After(deadline) {
+			return false, nil
+		}
+		select {
+		case <-ctx.Done():
+			return false, ctx.Err()
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
+
+// settle waits for the configured delay
+func (p *Paginator) settle(ctx context.Context) error {
+	if p.opts.Delay <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(p.opts.Delay):
+		return nil
+	}
+}
+
+// waitContent waits for WaitSelector, reporting false when it does not appear
+func (p *Paginator) waitContent() (bool, error) {
+	if p.opts.WaitSelector == "" {
+		return true, nil
+	}
+	return p.browser.WaitForSelector(p.opts.WaitSelector, p.opts.WaitTimeout) == nil, nil
+}
+
+// fingerprint identifies the page's URL and content
+func (p *Paginator) fingerprint() (string, error) {
+	pageURL, err := p.browser.URL()
+	if err != nil {
+		return "", err
+	}
+	content, err := p.browser.Content()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s#%x", pageURL, sha256.Sum256([]byte(content))), nil
+}
@@ -0,0 +1,84 @@
+package plugintest
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Golden compares got, as indented JSON, with the golden file at path
+// With -update the file is written instead. A missing golden file fails the test.
+func Golden(t testing.TB, path string, got interface{}) {
+	t.Helper()
+	actual, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Fatalf("plugintest: failed to encode output: %v", err)
+	}
+	actual = append(actual, '\n')
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("plugintest: %v", err)
+		}
+		if err := os.WriteFile(path, actual, 0644); err != nil {
+			t.Fatalf("plugintest: %v", err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("plugintest: golden file %s does not exist; run go test -update to create it", path)
+	}
+	if err != nil {
+		t.Fatalf("plugintest: %v", err)
+	}
+	if bytes.Equal(normalizeJSON(expected), normalizeJSON(actual)) {
+		return
+	}
+	t.Errorf("plugintest: output differs from %s (run go test -update to accept it):\n%s", path, diffLines(string(expected), string(actual)))
+}
+
+// normalizeJSON re-indents JSON so hand-edited golden files compare by content
+func normalizeJSON(data []byte) []byte {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return data
+	}
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return data
+	}
+	return out
+}
+
+// diffLines lists the lines that differ between want and got
+func diffLines(want, got string) string {
+	wantLines := strings.Split(want, "\n")
+	gotLines := strings.Split(got, "\n")
+	var b strings.Builder
+	for i := 0; i < len(wantLines) || i < len(gotLines); i++ {
+		var w, g string
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if w == g {
+			continue
+		}
+		if i < len(wantLines) {
+			fmt.Fprintf(&b, "%4d - %s\n", i+1, w)
+		}
+		if i < len(gotLines) {
+			fmt.Fprintf(&b, "%4d + %s\n", i+1, g)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,91 @@
+package plugintest
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// harFile is the subset of the HAR 1.2 format needed to replay responses
+type harFile struct {
+	Log struct {
+		Entries []struct {
+			Request struct {
+				Method string `json:"method"`
+				URL    string `json:"url"`
+			} `json:"request"`
+			Response struct {
+				Status  int `json:"status"`
+				Headers []struct {
+					Name  string `json:"name"`
+					Value string `json:"value"`
+				} `json:"headers"`
+				Content struct {
+					MimeType string `json:"mimeType"`
+					Text     string `json:"text"`
+					Encoding string `json:"encoding"`
+				} `json:"content"`
+			} `json:"response"`
+		} `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	method   string
+	url      string
+	response *Response
+}
+
+// Headers that describe the recorded transfer rather than the decoded body served back
+var skippedHARHeaders = map[string]bool{
+	"content-encoding":  true,
+	"content-length":    true,
+	"transfer-encoding": true,
+	"connection":        true,
+}
+
+func readHAR(path string) ([]harEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var har harFile
+	if err := json.Unmarshal(data, &har); err != nil {
+		return nil, fmt.Errorf("invalid HAR file %s: %w", path, err)
+	}
+
+	entries := make([]harEntry, 0, len(har.Log.Entries))
+	for _, e := range har.Log.Entries {
+		// Status 0 marks a request that never got a response
+		if e.Response.Status == 0 {
+			continue
+		}
+		body := []byte(e.Response.Content.Text)
+		if e.Response.Content.Encoding == "base64" {
+			if body, err = base64.StdEncoding.DecodeString(e.Response.Content.Text); err != nil {
+				return nil, fmt.Errorf("invalid HAR file %s: body of %s: %w", path, e.Request.URL, err)
+			}
+		}
+		headers := make(map[string]string)
+		for _, header := range e.Response.Headers {
+			if !skippedHARHeaders[strings.ToLower(header.Name)] {
+				headers[header.Name] = header.Value
+			}
+		}
+		if e.Response.Content.MimeType != "" {
+			headers["Content-Type"] = e.Response.Content.MimeType
+		}
+		method := e.Request.Method
+		if method == "" {
+			method = "GET"
+		}
+		entries = append(entries, harEntry{
+			method:   method,
+			url:      e.Request.URL,
+			response: &Response{Status: e.Response.Status, Headers: headers, Body: body},
+		})
+	}
+	return entries, nil
+}
@@ -0,0 +1,369 @@
+// Package plugintest runs plugins against recorded pages in a real browser and compares their
+// output with golden files, so extractors can be tested without the live site.
+//
+//	func TestExtract(t *testing.T) {
+//		h := plugintest.New(t)
+//		page := h.LoadHTML("testdata/product.html", "https://shop.example.com/p/1")
+//		out := plugintest.RunExtraction(t, &MyPlugin{}, page.ExtractionInput(nil))
+//		plugintest.Golden(t, "testdata/product.golden.json", out.Data)
+//	}
+//
+// Run `go test -update` to write the golden files from the current output.
+package plugintest
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/playwright-community/playwright-go"
+	"github.com/uzzalhcse/crawlify/internal/browser"
+	"github.com/uzzalhcse/crawlify/pkg/models"
+	"github.com/uzzalhcse/crawlify/pkg/plugins"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files with the current plugin output")
+
+// RunTimeout bounds a single Discover or Extract call
+var RunTimeout = time.Minute
+
+// Response is a recorded response served to the browser
+type Response struct {
+	Status  int
+	Headers map[string]string
+	Body    []byte
+}
+
+// Harness is a headless browser whose network is served by a local httptest server from
+// recorded responses. Pages keep their original URLs; requests without a recording fail.
+type Harness struct {
+	t       testing.TB
+	pw      *playwright.Playwright
+	browser playwright.Browser
+	server  *httptest.Server
+	client  *http.Client
+
+	mu        sync.RWMutex
+	responses map[string]*Response // keyed by method and URL without fragment
+
+	http *plugins.HTTPClient // Serves input.HTTP from the recordings too
+	kv   *plugins.MemoryKV   // Backs the inputs' stores; shared by the test's pages
+}
+
+// New starts a headless Chromium for the test; it is closed when the test ends
+func New(t testing.TB) *Harness {
+	t.Helper()
+	pw, err := playwright.Run()
+	if err != nil {
+		if err := playwright.Install(&playwright.RunOptions{Browsers: []string{"chromium"}, Verbose: false}); err != nil {
+			t.Fatalf("plugintest: failed to install playwright: %v", err)
+		}
+		if pw, err = playwright.Run(); err != nil {
+			t.Fatalf("plugintest: failed to start playwright: %v", err)
+		}
+	}
+	b, err := pw.Chromium.Launch(playwright.BrowserTypeLaunchOptions{Headless: playwright.Bool(true)})
+	if err != nil {
+		pw.Stop()
+		t.Fatalf("plugintest: failed to launch browser: %v", err)
+	}
+
+	h := &Harness{t: t, pw: pw, browser: b, responses: make(map[string]*Response), kv: plugins.NewMemoryKV()}
+	h.http, _ = plugins.NewHTTPClient(plugins.HTTPPolicy{MaxRetries: -1, Transport: recordedTransport{h}})
+	h.server = httptest.NewServer(http.HandlerFunc(h.serve))
+	// Recorded redirects go back to the browser, which requests the target through route again
+	h.client = &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	t.Cleanup(func() {
+		h.browser.Close()
+		h.pw.Stop()
+		h.server.Close()
+	})
+	return h
+}
+
+// Serve records the response returned for GET requests to rawURL
+func (h *Harness) Serve(rawURL string, resp *Response) {
+	h.ServeMethod(http.MethodGet, rawURL, resp)
+}
+
+// ServeMethod records the response returned for requests with the given method to rawURL
+func (h *Harness) ServeMethod(method, rawURL string, resp *Response) {
+	if resp.Status == 0 {
+		resp.Status = http.StatusOK
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.responses[responseKey(method, rawURL)] = resp
+}
+
+// LoadHTML serves an HTML fixture at pageURL and opens it in a new page
+func (h *Harness) LoadHTML(path, pageURL string) *Page {
+	h.t.Helper()
+	body, err := os.ReadFile(path)
+	if err != nil {
+		h.t.Fatalf("plugintest: %v", err)
+	}
+	h.Serve(pageURL, &Response{
+		Headers: map[string]string{"Content-Type": "text/html; charset=utf-8"},
+		Body:    body,
+	})
+	return h.Open(pageURL)
+}
+
+// LoadHAR serves every response recorded in a HAR file and opens pageURL in a new page
+// When a URL was recorded more than once, the first response is served.
+func (h *Harness) LoadHAR(path, pageURL string) *Page {
+	h.t.Helper()
+	entries, err := readHAR(path)
+	if err != nil {
+		h.t.Fatalf("plugintest: %v", err)
+	}
+	h.mu.Lock()
+	for _, entry := range entries {
+		key := responseKey(entry.method, entry.url)
+		if _, ok := h.responses[key]; !ok {
+			h.responses[key] = entry.response
+		}
+	}
+	h.mu.Unlock()
+	return h.Open(pageURL)
+}
+
+// Open navigates a new page, in its own browser context, to pageURL
+func (h *Harness) Open(pageURL string) *Page {
+	h.t.Helper()
+	bctx, err := h.browser.NewContext(playwright.BrowserNewContextOptions{
+		UserAgent:         playwright.String("Crawlify/1.0"),
+		IgnoreHttpsErrors: playwright.Bool(true),
+		JavaScriptEnabled: playwright.Bool(true),
+		Viewport:          &playwright.Size{Width: 1920, Height: 1080},
+	})
+	if err != nil {
+		h.t.Fatalf("plugintest: failed to create browser context: %v", err)
+	}
+	h.t.Cleanup(func() { bctx.Close() })
+
+	if err := bctx.Route("**/*", h.route); err != nil {
+		h.t.Fatalf("plugintest: failed to route requests: %v", err)
+	}
+	page, err := bctx.NewPage()
+	if err != nil {
+		h.t.Fatalf("plugintest: failed to open page: %v", err)
+	}
+
+	ctx := &browser.BrowserContext{Context: bctx, Page: page}
+	if _, err := ctx.Navigate(pageURL); err != nil {
+		h.t.Fatalf("plugintest: failed to load %s: %v", pageURL, err)
+	}
+	return &Page{URL: pageURL, Context: ctx, Browser: plugins.NewLocalBrowser(ctx), h: h}
+}
+
+// route forwards a browser request to the local server, which answers from the recordings
+func (h *Harness) route(route playwright.Route) {
+	req := route.Request()
+	local := h.server.URL + "/?url=" + url.QueryEscape(req.URL())
+	httpReq, err := http.NewRequest(req.Method(), local, nil)
+	if err != nil {
+		route.Abort()
+		return
+	}
+	resp, err := h.client.Do(httpReq)
+	if err != nil {
+		route.Abort()
+		return
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		route.Abort()
+		return
+	}
+	if resp.StatusCode == http.StatusNotFound && resp.Header.Get("X-Plugintest-Miss") != "" {
+		h.t.Logf("plugintest: no recorded response for %s %s", req.Method(), req.URL())
+	}
+
+	headers := make(map[string]string, len(resp.Header))
+	for name := range resp.Header {
+		headers[name] = resp.Header.Get(name)
+	}
+	delete(headers, "X-Plugintest-Miss")
+	status := resp.StatusCode
+	route.Fulfill(playwright.RouteFulfillOptions{Status: &status, Headers: headers, Body: body})
+}
+
+// recordedTransport answers input.HTTP requests from the recordings
+type recordedTransport struct {
+	h *Harness
+}
+
+func (rt recordedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.h.mu.RLock()
+	recorded, ok := rt.h.responses[responseKey(req.Method, req.URL.String())]
+	rt.h.mu.RUnlock()
+	if !ok {
+		rt.h.t.Logf("plugintest: no recorded response for %s %s", req.Method, req.URL)
+		recorded = &Response{Status: http.StatusNotFound}
+	}
+	resp := &http.Response{
+		Status:     fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode: recorded.Status,
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     make(http.Header),
+		Body:       io.NopCloser(bytes.NewReader(recorded.Body)),
+		Request:    req,
+	}
+	for name, value := range recorded.Headers {
+		resp.Header.Set(name, value)
+	}
+	return resp, nil
+}
+
+// Store returns the workflow store the inputs get, to seed or inspect a plugin's values
+func (h *Harness) Store() plugins.KVStore {
+	return plugins.NewKVStore(h.kv, models.PluginKVScope{PluginSlug: "plugintest", WorkflowID: "plugintest"})
+}
+
+func (h *Harness) serve(w http.ResponseWriter, r *http.Request) {
+	h.mu.RLock()
+	resp, ok := h.responses[responseKey(r.Method, r.URL.Query().Get("url"))]
+	h.mu.RUnlock()
+	if !ok {
+		w.Header().Set("X-Plugintest-Miss", "1")
+		http.NotFound(w, r)
+		return
+	}
+	for name, value := range resp.Headers {
+		w.Header().Set(name, value)
+	}
+	w.WriteHeader(resp.Status)
+	w.Write(resp.Body)
+}
+
+func responseKey(method, rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		u.Fragment = ""
+		rawURL = u.String()
+	}
+	return method + " " + rawURL
+}
+
+// Page is a loaded page ready to be handed to a plugin
+// Its inputs' HTTP client is served from the recordings and their stores are kept in memory
+type Page struct {
+	URL     string
+	Context *browser.BrowserContext
+	Browser plugins.Browser
+
+	h *Harness
+}
+
+// DiscoveryInput builds the input a discovery plugin receives for this page
+func (p *Page) DiscoveryInput(config map[string]interface{}) *plugins.DiscoveryInput {
+	return &plugins.DiscoveryInput{
+		BrowserContext:   p.Context,
+		Browser:          p.Browser,
+		URL:              p.URL,
+		URLItem:          p.urlItem(),
+		ExecutionContext: p.executionContext(),
+		Config:           orEmpty(config),
+		ExecutionID:      "plugintest",
+		HTTP:             p.h.http,
+		WorkflowStore:    p.h.Store(),
+		ExecutionStore:   plugins.NewKVStore(p.h.kv, executionScope),
+	}
+}
+
+// ExtractionInput builds the input an extraction plugin receives for this page
+func (p *Page) ExtractionInput(config map[string]interface{}) *plugins.ExtractionInput {
+	return &plugins.ExtractionInput{
+		BrowserContext:   p.Context,
+		Browser:          p.Browser,
+		URL:              p.URL,
+		URLItem:          p.urlItem(),
+		ExecutionContext: p.executionContext(),
+		Config:           orEmpty(config),
+		ExecutionID:      "plugintest",
+		HTTP:             p.h.http,
+		WorkflowStore:    p.h.Store(),
+		ExecutionStore:   plugins.NewKVStore(p.h.kv, executionScope),
+	}
+}
+
+// executionScope is the scope of the inputs' execution store
+var executionScope = models.PluginKVScope{PluginSlug: "plugintest", WorkflowID: "plugintest", ExecutionID: "plugintest"}
+
+func (p *Page) urlItem() *models.URLQueueItem {
+	return &models.URLQueueItem{ID: "plugintest", ExecutionID: "plugintest", URL: p.URL, Status: models.QueueItemStatusProcessing}
+}
+
+func (p *Page) executionContext() *models.ExecutionContext {
+	ctx := models.NewExecutionContext()
+	return &ctx
+}
+
+func orEmpty(config map[string]interface{}) map[string]interface{} {
+	if config == nil {
+		return map[string]interface{}{}
+	}
+	return config
+}
+
+// RunDiscovery validates the input's config and runs a discovery plugin, failing the test on error
+func RunDiscovery(t testing.TB, plugin plugins.DiscoveryPlugin, input *plugins.DiscoveryInput) *plugins.DiscoveryOutput {
+	t.Helper()
+	if err := prepare(plugin, plugin.Validate, input.Config); err != nil {
+		t.Fatalf("plugintest: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), RunTimeout)
+	defer cancel()
+	out, err := plugin.Discover(ctx, input)
+	if err != nil {
+		t.Fatalf("plugintest: Discover failed: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("plugintest: Discover returned no output")
+	}
+	return out
+}
+
+// RunExtraction validates the input's config and runs an extraction plugin, failing the test on error
+func RunExtraction(t testing.TB, plugin plugins.ExtractionPlugin, input *plugins.ExtractionInput) *plugins.ExtractionOutput {
+	t.Helper()
+	if err := prepare(plugin, plugin.Validate, input.Config); err != nil {
+		t.Fatalf("plugintest: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), RunTimeout)
+	defer cancel()
+	out, err := plugin.Extract(ctx, input)
+	if err != nil {
+		t.Fatalf("plugintest: Extract failed: %v", err)
+	}
+	if out == nil {
+		t.Fatalf("plugintest: Extract returned no output")
+	}
+	return out
+}
+
+// prepare validates config and initialises the plugin the way the crawler does before its first call
+func prepare(plugin interface{}, validate func(map[string]interface{}) error, config map[string]interface{}) error {
+	if err := validate(config); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	if initializer, ok := plugin.(plugins.Initializer); ok {
+		if err := initializer.Init(config); err != nil {
+			return fmt.Errorf("plugin Init failed: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,415 @@
+package plugins
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/uzzalhcse/crawlify/pkg/models"
+)
+
+// ProtocolVersion is the RPC plugin protocol version; the host rejects plugins speaking another one
+const ProtocolVersion = 1
+
+// Methods the host calls on an RPC plugin
+const (
+	MethodHandshake = "plugin.handshake"
+	MethodValidate  = "plugin.validate"
+	MethodDiscover  = "plugin.discover"
+	MethodExtract   = "plugin.extract"
+	MethodShutdown  = "plugin.shutdown"
+
+	// Lifecycle hooks, forwarded to plugins implementing Initializer or ExecutionHooks
+	MethodInit           = "plugin.init"
+	MethodExecutionStart = "plugin.execution_start"
+	MethodExecutionEnd   = "plugin.execution_end"
+
+	// Notification cancelling the plugin.discover or plugin.extract call of a session, sent when
+	// the execution is stopped, paused or fails while the call runs
+	MethodCancel = "plugin.cancel"
+)
+
+// Methods an RPC plugin calls on the host while it handles plugin.discover or plugin.extract
+const (
+	MethodBrowserURL             = "browser.url"
+	MethodBrowserNavigate        = "browser.navigate"
+	MethodBrowserContent         = "browser.content"
+	MethodBrowserEvaluate        = "browser.evaluate"
+	MethodBrowserCount           = "browser.count"
+	MethodBrowserTexts           = "browser.texts"
+	MethodBrowserAttributes      = "browser.attributes"
+	MethodBrowserClick           = "browser.click"
+	MethodBrowserWaitForSelector = "browser.wait_for_selector"
+
+	// Sends a request through the execution's HTTP client; WASM plugins are limited to their approved hosts
+	MethodHTTPFetch = "http.fetch"
+
+	// Key-value stores of the call, see KVStore
+	MethodKVGet    = "kv.get"
+	MethodKVSet    = "kv.set"
+	MethodKVDelete = "kv.delete"
+	MethodKVKeys   = "kv.keys"
+)
+
+// Store scopes of kv.* requests
+const (
+	KVScopeWorkflow  = "workflow"
+	KVScopeExecution = "execution"
+)
+
+// Plugin kinds reported in the handshake
+const (
+	PluginKindDiscovery  = "discovery"
+	PluginKindExtraction = "extraction"
+)
+
+// JSON-RPC error codes
+const (
+	RPCCodeMethodNotFound = -32601
+	RPCCodeInvalidParams  = -32602
+	RPCCodeInternal       = -32603
+	RPCCodePluginError    = 1 // The plugin's Discover, Extract or Validate returned an error
+	RPCCodeDenied         = 2 // A sandboxed plugin called something its approved capabilities do not allow
+)
+
+// ErrConnClosed is returned for calls on a connection whose peer went away (e.g. the plugin process exited)
+var ErrConnClosed = errors.New("plugin connection closed")
+
+// HandshakeParams is sent by the host when an RPC plugin starts
+type HandshakeParams struct {
+	ProtocolVersion int `json:"protocol_version"`
+}
+
+// HandshakeResult describes the plugin to the host
+type HandshakeResult struct {
+	ProtocolVersion int                    `json:"protocol_version"`
+	Kind            string                 `json:"kind"` // discovery or extraction
+	Info            PluginInfo             `json:"info"`
+	ConfigSchema    map[string]interface{} `json:"config_schema"`
+
+	// Set by sandboxed (WASM) plugins: the capabilities to request at install time and the limits they need
+	Capabilities *models.PluginCapabilities `json:"capabilities,omitempty"`
+	Limits       *models.PluginLimits       `json:"limits,omitempty"`
+}
+
+// ValidateParams carries a plugin configuration to validate
+type ValidateParams struct {
+	Config map[string]interface{} `json:"config"`
+}
+
+// InitParams carries the config of plugin.init
+type InitParams struct {
+	Config map[string]interface{} `json:"config"`
+}
+
+// ExecutionParams carries the execution of plugin.execution_start and plugin.execution_end
+type ExecutionParams struct {
+	ExecutionID string `json:"execution_id"`
+}
+
+// CallParams carries the input of plugin.discover and plugin.extract
+// Session identifies the call in the plugin's browser requests
+type CallParams struct {
+	Session          string                   `json:"session"`
+	URL              string                   `json:"url"`
+	URLItem          *models.URLQueueItem     `json:"url_item,omitempty"`
+	ExecutionContext *models.ExecutionContext `json:"execution_context,omitempty"`
+	Config           map[string]interface{}   `json:"config"`
+	ExecutionID      string                   `json:"execution_id"`
+
+	// Whether the host serves http.fetch and kv.* for the session (input.HTTP and the stores)
+	HTTP   bool `json:"http,omitempty"`
+	Stores bool `json:"stores,omitempty"`
+}
+
+// DiscoverResult is the result of plugin.discover
+// ExecutionContext is the context after the call; the host applies the plugin's changes
+type DiscoverResult struct {
+	Output           *DiscoveryOutput         `json:"output"`
+	ExecutionContext *models.ExecutionContext `json:"execution_context,omitempty"`
+}
+
+// ExtractResult is the result of plugin.extract
+type ExtractResult struct {
+	Output           *ExtractionOutput        `json:"output"`
+	ExecutionContext *models.ExecutionContext `json:"execution_context,omitempty"`
+}
+
+// BrowserParams carries the arguments of browser.* calls
+type BrowserParams struct {
+	Session    string      `json:"session"`
+	URL        string      `json:"url,omitempty"`
+	Expression string      `json:"expression,omitempty"`
+	Arg        interface{} `json:"arg,omitempty"`
+	Selector   string      `json:"selector,omitempty"`
+	Attribute  string      `json:"attribute,omitempty"`
+	TimeoutMs  int64       `json:"timeout_ms,omitempty"`
+}
+
+// CancelParams carries the session of plugin.cancel
+type CancelParams struct {
+	Session string `json:"session"`
+}
+
+// HTTPFetchParams carries the arguments of http.fetch
+type HTTPFetchParams struct {
+	Session string            `json:"session,omitempty"` // Set by RPC plugins
+	Method  string            `json:"method,omitempty"`  // Defaults to GET
+	URL     string            `json:"url"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    string            `json:"body,omitempty"`
+}
+
+// HTTPFetchResult is the response of http.fetch
+type HTTPFetchResult struct {
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+}
+
+// KVParams carries the arguments of kv.* requests
+type KVParams struct {
+	Session string          `json:"session"`
+	Scope   string          `json:"scope"` // workflow or execution
+	Key     string          `json:"key,omitempty"`
+	Value   json.RawMessage `json:"value,omitempty"`
+	Prefix  string          `json:"prefix,omitempty"`
+}
+
+// KVResult is the result of kv.get and kv.keys
+type KVResult struct {
+	Found bool            `json:"found,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+	Keys  []string        `json:"keys,omitempty"`
+}
+
+// RPCError is a JSON-RPC error
+type RPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *RPCError) Error() string {
+	return e.Message
+}
+
+// Caller sends a request to the other side and decodes its result; Conn implements it
+type Caller interface {
+	Call(ctx context.Context, method string, params, result interface{}) error
+}
+
+// RPCHandler handles a request from the other side of a connection
+type RPCHandler func(ctx context.Context, method string, params json.RawMessage) (interface{}, error)
+
+// rpcMessage is a JSON-RPC 2.0 request or response
+type rpcMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *uint64         `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// Conn is a bidirectional JSON-RPC 2.0 connection carrying one message per line
+// Both sides may call each other; requests are handled concurrently
+type Conn struct {
+	reader  *bufio.Reader
+	writer  io.Writer
+	writeMu sync.Mutex
+	handler RPCHandler
+
+	nextID  atomic.Uint64
+	mu      sync.Mutex
+	pending map[uint64]chan *rpcMessage
+	done    chan struct{}
+}
+
+// NewConn creates a connection; call Serve to start reading
+func NewConn(r io.Reader, w io.Writer, handler RPCHandler) *Conn {
+	return &Conn{
+		reader:  bufio.NewReader(r),
+		writer:  w,
+		handler: handler,
+		pending: make(map[uint64]chan *rpcMessage),
+		done:    make(chan struct{}),
+	}
+}
+
+// Serve reads messages until the peer closes the connection, then fails the pending calls
+func (c *Conn) Serve() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var err error
+	for {
+		var line []byte
+		line, err = c.reader.ReadBytes('\n')
+		if len(line) > 0 {
+			c.dispatch(ctx, line)
+		}
+		if err != nil {
+			break
+		}
+	}
+	if err == io.EOF {
+		err = ErrConnClosed
+	}
+
+	c.mu.Lock()
+	for id, ch := range c.pending {
+		close(ch)
+		delete(c.pending, id)
+	}
+	close(c.done)
+	c.mu.Unlock()
+	return err
+}
+
+// Done is closed when the connection stops
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Notify sends a request that gets no response
+func (c *Conn) Notify(method string, params interface{}) error {
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s params: %w", method, err)
+	}
+	return c.write(&rpcMessage{Method: method, Params: rawParams})
+}
+
+// Call sends a request and decodes the response into result (which may be nil)
+func (c *Conn) Call(ctx context.Context, method string, params, result interface{}) error {
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s params: %w", method, err)
+	}
+
+	id := c.nextID.Add(1)
+	ch := make(chan *rpcMessage, 1)
+	c.mu.Lock()
+	select {
+	case <-c.done:
+		c.mu.Unlock()
+		return ErrConnClosed
+	default:
+	}
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	if err := c.write(&rpcMessage{ID: &id, Method: method, Params: rawParams}); err != nil {
+		c.forget(id)
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		c.forget(id)
+		return ctx.Err()
+	case resp, ok := <-ch:
+		if !ok {
+			return ErrConnClosed
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result == nil || len(resp.Result) == 0 {
+			return nil
+		}
+		if err := json.Unmarshal(resp.Result, result); err != nil {
+			return fmt.Errorf("failed to decode %s result: %w", method, err)
+		}
+		return nil
+	}
+}
+
+// dispatch routes a response to its caller or runs the handler for a request
+func (c *Conn) dispatch(ctx context.Context, line []byte) {
+	var msg rpcMessage
+	if err := json.Unmarshal(line, &msg); err != nil {
+		return
+	}
+
+	if msg.Method == "" {
+		if msg.ID == nil {
+			return
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[*msg.ID]
+		delete(c.pending, *msg.ID)
+		c.mu.Unlock()
+		if ok {
+			ch <- &msg
+		}
+		return
+	}
+
+	go func() {
+		result, err := c.handle(ctx, msg.Method, msg.Params)
+		if msg.ID == nil {
+			return // Notification
+		}
+		resp := &rpcMessage{ID: msg.ID}
+		if err != nil {
+			var rpcErr *RPCError
+			if !errors.As(err, &rpcErr) {
+				rpcErr = &RPCError{Code: RPCCodeInternal, Message: err.Error()}
+			}
+			resp.Error = rpcErr
+		} else if resp.Result, err = json.Marshal(result); err != nil {
+			resp.Result = nil
+			resp.Error = &RPCError{Code: RPCCodeInternal, Message: fmt.Sprintf("failed to encode result: %v", err)}
+		}
+		c.write(resp)
+	}()
+}
+
+// handle runs the handler, turning panics into errors so one bad request cannot kill the connection
+func (c *Conn) handle(ctx context.Context, method string, params json.RawMessage) (result interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &RPCError{Code: RPCCodeInternal, Message: fmt.Sprintf("panic in %s: %v", method, r)}
+		}
+	}()
+	if c.handler == nil {
+		return nil, &RPCError{Code: RPCCodeMethodNotFound, Message: "method not found: " + method}
+	}
+	return c.handler(ctx, method, params)
+}
+
+func (c *Conn) write(msg *rpcMessage) error {
+	msg.JSONRPC = "2.0"
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if _, err := c.writer.Write(data); err != nil {
+		return fmt.Errorf("%w: %v", ErrConnClosed, err)
+	}
+	return nil
+}
+
+func (c *Conn) forget(id uint64) {
+	c.mu.Lock()
+	delete(c.pending, id)
+	c.mu.Unlock()
+}
+
+// decodeParams decodes request params, reporting failures as invalid params
+func decodeParams(raw json.RawMessage, v interface{}) error {
+	if err := json.Unmarshal(raw, v); err != nil {
+		return &RPCError{Code: RPCCodeInvalidParams, Message: err.Error()}
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package plugins
 
 import (
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"strings"
@@ -203,6 +204,59 @@ func (dh *DataHelpers) ExtractNumbers(text string) []string {
 	return re.FindAllString(text, -1)
 }
 
+// JSONLD returns the JSON-LD objects embedded in an HTML document, with @graph lists flattened
+// Scripts that are not valid JSON are skipped, as browsers do
+func (dh *DataHelpers) JSONLD(html string) ([]map[string]interface{}, error) {
+	doc, err := dh.ParseHTML(html)
+	if err != nil {
+		return nil, err
+	}
+	var objects []map[string]interface{}
+	doc.Find(`script[type="application/ld+json"]`).Each(func(i int, s *goquery.Selection) {
+		var value interface{}
+		if json.Unmarshal([]byte(s.Text()), &value) == nil {
+			objects = appendJSONLD(objects, value)
+		}
+	})
+	return objects, nil
+}
+
+func appendJSONLD(objects []map[string]interface{}, value interface{}) []map[string]interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		for _, element := range v {
+			objects = appendJSONLD(objects, element)
+		}
+	case map[string]interface{}:
+		if graph, ok := v["@graph"]; ok {
+			return appendJSONLD(objects, graph)
+		}
+		objects = append(objects, v)
+	}
+	return objects
+}
+
+// JSONLDOfType returns the objects whose @type is typeName or lists it, e.g. "Product"
+func (dh *DataHelpers) JSONLDOfType(objects []map[string]interface{}, typeName string) []map[string]interface{} {
+	var matches []map[string]interface{}
+	for _, object := range objects {
+		switch t := object["@type"].(type) {
+		case string:
+			if t == typeName {
+				matches = append(matches, object)
+			}
+		case []interface{}:
+			for _, name := range t {
+				if name == typeName {
+					matches = append(matches, object)
+					break
+				}
+			}
+		}
+	}
+	return matches
+}
+
 // Logger provides structured logging for plugins
 type Logger struct {
 	logger *zap.Logger
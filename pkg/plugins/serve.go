@@ -0,0 +1,369 @@
+package plugins
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/uzzalhcse/crawlify/pkg/models"
+)
+
+// Serve runs a DiscoveryPlugin or ExtractionPlugin as an out-of-process RPC plugin on stdin/stdout
+// Build the plugin as an ordinary executable whose main calls Serve:
+//
+//	func main() {
+//		if err := plugins.Serve(NewMyPlugin()); err != nil {
+//			log.Fatal(err)
+//		}
+//	}
+//
+// The plugin reaches the page through input.Browser; input.BrowserContext is nil out of process.
+// Anything the plugin prints to stdout is redirected to stderr, which the host logs
+func Serve(plugin interface{}) error {
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	return ServeConn(plugin, os.Stdin, stdout)
+}
+
+// ServeConn runs a plugin over an arbitrary reader and writer (used by Serve and in tests)
+func ServeConn(plugin interface{}, r io.Reader, w io.Writer) error {
+	server, err := newPluginServer(plugin)
+	if err != nil {
+		return err
+	}
+	conn := NewConn(r, w, server.handle)
+	server.host = conn
+	err = conn.Serve()
+	if err == ErrConnClosed {
+		return nil
+	}
+	return err
+}
+
+// NewPluginHandler returns a handler answering the host's plugin.* requests for plugin,
+// whose input.Browser calls are sent to host. Plugin runtimes other than stdio use it
+func NewPluginHandler(plugin interface{}, host Caller) (RPCHandler, error) {
+	server, err := newPluginServer(plugin)
+	if err != nil {
+		return nil, err
+	}
+	server.host = host
+	return server.handle, nil
+}
+
+// SandboxedPlugin is implemented by plugins built for the WASM runtime that need more than
+// read access to the current page; the capabilities are approved by an admin at install time
+type SandboxedPlugin interface {
+	Capabilities() models.PluginCapabilities
+	Limits() models.PluginLimits
+}
+
+// pluginServer answers the host's requests for one plugin
+type pluginServer struct {
+	discovery  DiscoveryPlugin
+	extraction ExtractionPlugin
+	host       Caller
+	cancels    sync.Map // session -> context.CancelFunc of its running call
+}
+
+func newPluginServer(plugin interface{}) (*pluginServer, error) {
+	switch p := plugin.(type) {
+	case DiscoveryPlugin:
+		return &pluginServer{discovery: p}, nil
+	case ExtractionPlugin:
+		return &pluginServer{extraction: p}, nil
+	default:
+		return nil, fmt.Errorf("plugin %T implements neither DiscoveryPlugin nor ExtractionPlugin", plugin)
+	}
+}
+
+func (s *pluginServer) handle(ctx context.Context, method string, params json.RawMessage) (interface{}, error) {
+	switch method {
+	case MethodHandshake:
+		var p HandshakeParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return s.handshake(), nil
+
+	case MethodValidate:
+		var p ValidateParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		var err error
+		if s.discovery != nil {
+			err = s.discovery.Validate(p.Config)
+		} else {
+			err = s.extraction.Validate(p.Config)
+		}
+		if err != nil {
+			return nil, &RPCError{Code: RPCCodePluginError, Message: err.Error()}
+		}
+		return nil, nil
+
+	case MethodDiscover:
+		if s.discovery == nil {
+			return nil, &RPCError{Code: RPCCodeMethodNotFound, Message: "not a discovery plugin"}
+		}
+		var p CallParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		input := &DiscoveryInput{
+			Browser:          NewRemoteBrowser(s.host, p.Session),
+			URL:              p.URL,
+			URLItem:          p.URLItem,
+			ExecutionContext: callContext(p.ExecutionContext),
+			Config:           p.Config,
+			ExecutionID:      p.ExecutionID,
+		}
+		input.HTTP, input.WorkflowStore, input.ExecutionStore = s.services(&p)
+		ctx, done := s.cancellable(ctx, p.Session)
+		defer done()
+		output, err := s.discovery.Discover(ctx, input)
+		if err != nil {
+			return nil, &RPCError{Code: RPCCodePluginError, Message: err.Error()}
+		}
+		return &DiscoverResult{Output: output, ExecutionContext: input.ExecutionContext}, nil
+
+	case MethodExtract:
+		if s.extraction == nil {
+			return nil, &RPCError{Code: RPCCodeMethodNotFound, Message: "not an extraction plugin"}
+		}
+		var p CallParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		input := &ExtractionInput{
+			Browser:          NewRemoteBrowser(s.host, p.Session),
+			URL:              p.URL,
+			URLItem:          p.URLItem,
+			ExecutionContext: callContext(p.ExecutionContext),
+			Config:           p.Config,
+			ExecutionID:      p.ExecutionID,
+		}
+		input.HTTP, input.WorkflowStore, input.ExecutionStore = s.services(&p)
+		ctx, done := s.cancellable(ctx, p.Session)
+		defer done()
+		output, err := s.extraction.Extract(ctx, input)
+		if err != nil {
+			return nil, &RPCError{Code: RPCCodePluginError, Message: err.Error()}
+		}
+		return &ExtractResult{Output: output, ExecutionContext: input.ExecutionContext}, nil
+
+	case MethodCancel:
+		var p CancelParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		if cancel, ok := s.cancels.Load(p.Session); ok {
+			cancel.(context.CancelFunc)()
+		}
+		return nil, nil
+
+	case MethodInit:
+		var p InitParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		if initializer, ok := s.instance().(Initializer); ok {
+			if err := initializer.Init(p.Config); err != nil {
+				return nil, &RPCError{Code: RPCCodePluginError, Message: err.Error()}
+			}
+		}
+		return nil, nil
+
+	case MethodExecutionStart, MethodExecutionEnd:
+		var p ExecutionParams
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		hooks, ok := s.instance().(ExecutionHooks)
+		if !ok {
+			return nil, nil
+		}
+		var err error
+		if method == MethodExecutionStart {
+			err = hooks.OnExecutionStart(ctx, p.ExecutionID)
+		} else {
+			err = hooks.OnExecutionEnd(ctx, p.ExecutionID)
+		}
+		if err != nil {
+			return nil, &RPCError{Code: RPCCodePluginError, Message: err.Error()}
+		}
+		return nil, nil
+
+	case MethodShutdown:
+		if closer, ok := s.instance().(Closer); ok {
+			return nil, closer.Close()
+		}
+		return nil, nil
+
+	default:
+		return nil, &RPCError{Code: RPCCodeMethodNotFound, Message: "method not found: " + method}
+	}
+}
+
+// services returns the host services a call's input gets
+func (s *pluginServer) services(p *CallParams) (*HTTPClient, KVStore, KVStore) {
+	var client *HTTPClient
+	if p.HTTP {
+		client = NewRemoteHTTPClient(s.host, p.Session)
+	}
+	if !p.Stores {
+		return client, nil, nil
+	}
+	return client, NewRemoteKVStore(s.host, p.Session, KVScopeWorkflow), NewRemoteKVStore(s.host, p.Session, KVScopeExecution)
+}
+
+// cancellable returns the context of a session's call, cancelled by plugin.cancel
+func (s *pluginServer) cancellable(ctx context.Context, session string) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	s.cancels.Store(session, cancel)
+	return ctx, func() {
+		s.cancels.Delete(session)
+		cancel()
+	}
+}
+
+func (s *pluginServer) handshake() *HandshakeResult {
+	result := &HandshakeResult{ProtocolVersion: ProtocolVersion}
+	if s.discovery != nil {
+		result.Kind = PluginKindDiscovery
+		result.Info = s.discovery.Info()
+		result.ConfigSchema = s.discovery.ConfigSchema()
+	} else {
+		result.Kind = PluginKindExtraction
+		result.Info = s.extraction.Info()
+		result.ConfigSchema = s.extraction.ConfigSchema()
+	}
+	if sandboxed, ok := s.instance().(SandboxedPlugin); ok {
+		capabilities, limits := sandboxed.Capabilities(), sandboxed.Limits()
+		result.Capabilities = &capabilities
+		result.Limits = &limits
+	}
+	return result
+}
+
+func (s *pluginServer) instance() interface{} {
+	if s.discovery != nil {
+		return s.discovery
+	}
+	return s.extraction
+}
+
+// callContext returns the execution context sent by the host, or an empty one
+func callContext(execCtx *models.ExecutionContext) *models.ExecutionContext {
+	if execCtx == nil {
+		empty := models.NewExecutionContext()
+		return &empty
+	}
+	if execCtx.Data == nil {
+		execCtx.Data = make(map[string]interface{})
+	}
+	if execCtx.Variables == nil {
+		execCtx.Variables = make(map[string]string)
+	}
+	if execCtx.Metadata == nil {
+		execCtx.Metadata = make(map[string]interface{})
+	}
+	return execCtx
+}
+
+// remoteBrowser implements Browser by calling back into the host, which owns the page
+type remoteBrowser struct {
+	host    Caller
+	session string
+}
+
+// NewRemoteBrowser returns a Browser whose calls are executed by the host for the given call session
+func NewRemoteBrowser(host Caller, session string) Browser {
+	return &remoteBrowser{host: host, session: session}
+}
+
+func (b *remoteBrowser) call(method string, params BrowserParams, result interface{}) error {
+	params.Session = b.session
+	return b.host.Call(context.Background(), method, params, result)
+}
+
+func (b *remoteBrowser) URL() (string, error) {
+	var url string
+	err := b.call(MethodBrowserURL, BrowserParams{}, &url)
+	return url, err
+}
+
+func (b *remoteBrowser) Navigate(url string) error {
+	return b.call(MethodBrowserNavigate, BrowserParams{URL: url}, nil)
+}
+
+func (b *remoteBrowser) Content() (string, error) {
+	var content string
+	err := b.call(MethodBrowserContent, BrowserParams{}, &content)
+	return content, err
+}
+
+func (b *remoteBrowser) Evaluate(expression string, arg interface{}) (interface{}, error) {
+	var result interface{}
+	err := b.call(MethodBrowserEvaluate, BrowserParams{Expression: expression, Arg: arg}, &result)
+	return result, err
+}
+
+func (b *remoteBrowser) Count(selector string) (int, error) {
+	var count int
+	err := b.call(MethodBrowserCount, BrowserParams{Selector: selector}, &count)
+	return count, err
+}
+
+func (b *remoteBrowser) Texts(selector string) ([]string, error) {
+	var texts []string
+	err := b.call(MethodBrowserTexts, BrowserParams{Selector: selector}, &texts)
+	return texts, err
+}
+
+func (b *remoteBrowser) Attributes(selector, attribute string) ([]string, error) {
+	var values []string
+	err := b.call(MethodBrowserAttributes, BrowserParams{Selector: selector, Attribute: attribute}, &values)
+	return values, err
+}
+
+func (b *remoteBrowser) Click(selector string) error {
+	return b.call(MethodBrowserClick, BrowserParams{Selector: selector}, nil)
+}
+
+func (b *remoteBrowser) WaitForSelector(selector string, timeout time.Duration) error {
+	return b.call(MethodBrowserWaitForSelector, BrowserParams{Selector: selector, TimeoutMs: timeout.Milliseconds()}, nil)
+}
+
+// ServeBrowser answers a plugin's browser.* request against browser; hosts use it in their RPC handler
+func ServeBrowser(browser Browser, method string, p BrowserParams) (interface{}, error) {
+	if browser == nil {
+		return nil, fmt.Errorf("no page is available to the plugin")
+	}
+	switch method {
+	case MethodBrowserURL:
+		return browser.URL()
+	case MethodBrowserNavigate:
+		return nil, browser.Navigate(p.URL)
+	case MethodBrowserContent:
+		return browser.Content()
+	case MethodBrowserEvaluate:
+		return browser.Evaluate(p.Expression, p.Arg)
+	case MethodBrowserCount:
+		return browser.Count(p.Selector)
+	case MethodBrowserTexts:
+		return browser.Texts(p.Selector)
+	case MethodBrowserAttributes:
+		return browser.Attributes(p.Selector, p.Attribute)
+	case MethodBrowserClick:
+		return nil, browser.Click(p.Selector)
+	case MethodBrowserWaitForSelector:
+		return nil, browser.WaitForSelector(p.Selector, time.Duration(p.TimeoutMs)*time.Millisecond)
+	default:
+		return nil, &RPCError{Code: RPCCodeMethodNotFound, Message: "method not found: " + method}
+	}
+}
@@ -0,0 +1,43 @@
+package plugins
+
+import "encoding/json"
+
+// WASM plugin ABI
+//
+// A WASM plugin is a WASI reactor module (for Go: GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared)
+// that exports memory and the two functions below. Every call gets a fresh instance of the module.
+//
+//	crawlify_malloc(size u32) -> ptr u32        allocate size bytes the host can write the request into
+//	crawlify_call(ptr u32, len u32) -> u64      handle the WASMRequest at ptr; returns ptr<<32 | len of a WASMResponse
+//
+// The requests are the RPC plugin methods (plugin.handshake, plugin.validate, plugin.discover,
+// plugin.extract) with the same params and results. The host provides the module "crawlify":
+//
+//	host_call(ptr u32, len u32) -> len u32      run the WASMRequest at ptr (browser.*, http.fetch); returns the response length
+//	host_result(ptr u32)                        copy the response of the last host_call to ptr
+//	log(ptr u32, len u32)                       write a line to the crawler log
+//
+// pkg/plugins/wasmguest implements the guest side for Go plugins.
+const (
+	WASMExportMalloc = "crawlify_malloc"
+	WASMExportCall   = "crawlify_call"
+
+	WASMHostModule     = "crawlify"
+	WASMHostCall       = "host_call"
+	WASMHostResult     = "host_result"
+	WASMHostLog        = "log"
+	WASMStartFunction  = "_initialize"
+	WASMDataMountPoint = "/data" // Where the plugin's data directory is mounted when it has file_access
+)
+
+// WASMRequest is a request across the WASM boundary, in either direction
+type WASMRequest struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// WASMResponse is the response to a WASMRequest
+type WASMResponse struct {
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *RPCError       `json:"error,omitempty"`
+}
@@ -0,0 +1,15 @@
+// Package wasmguest runs a DiscoveryPlugin or ExtractionPlugin inside the WASM plugin sandbox.
+//
+// Register the plugin from an init function and build a WASI reactor module:
+//
+//	func init() {
+//		wasmguest.Register(NewMyPlugin())
+//	}
+//
+//	func main() {}
+//
+//	GOOS=wasip1 GOARCH=wasm go build -buildmode=c-shared -o my-plugin.wasm .
+//
+// The plugin reaches the page through input.Browser and the network through Fetch, within the
+// capabilities an admin approved at install time.
+package wasmguest
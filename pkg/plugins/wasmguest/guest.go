@@ -0,0 +1,152 @@
+//go:build wasip1
+
+package wasmguest
+
+import (
+	"context"
+	"encoding/json"
+	"unsafe"
+
+	"github.com/uzzalhcse/crawlify/pkg/plugins"
+)
+
+//go:wasmimport crawlify host_call
+func hostCall(ptr, size uint32) uint32
+
+//go:wasmimport crawlify host_result
+func hostResult(ptr uint32)
+
+//go:wasmimport crawlify log
+func hostLog(ptr, size uint32)
+
+var (
+	handler plugins.RPCHandler
+	pinned  [][]byte // Buffers handed to the host, kept alive until the next call
+)
+
+// Register makes plugin the one served by this module
+func Register(plugin interface{}) {
+	h, err := plugins.NewPluginHandler(plugin, host{})
+	if err != nil {
+		panic(err)
+	}
+	handler = h
+}
+
+// Fetch performs an HTTP request through the host; the URL's host must be an approved network host
+func Fetch(ctx context.Context, params plugins.HTTPFetchParams) (*plugins.HTTPFetchResult, error) {
+	var result plugins.HTTPFetchResult
+	if err := (host{}).Call(ctx, plugins.MethodHTTPFetch, params, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Log writes a line to the crawler log
+func Log(message string) {
+	if message == "" {
+		return
+	}
+	data := []byte(message)
+	hostLog(pointer(data), uint32(len(data)))
+}
+
+//go:wasmexport crawlify_malloc
+func malloc(size uint32) uint32 {
+	buf := make([]byte, max(size, 1))
+	pinned = append(pinned, buf)
+	return pointer(buf)
+}
+
+//go:wasmexport crawlify_call
+func call(ptr unsafe.Pointer, size uint32) uint64 {
+	request := unsafe.Slice((*byte)(ptr), size)
+	response := handle(request)
+	pinned = [][]byte{response}
+	return uint64(pointer(response))<<32 | uint64(len(response))
+}
+
+// handle runs a host request, turning panics into errors
+func handle(data []byte) (out []byte) {
+	defer func() {
+		if r := recover(); r != nil {
+			out = encodeResponse(nil, &plugins.RPCError{Code: plugins.RPCCodeInternal, Message: "plugin panicked: " + toString(r)})
+		}
+	}()
+
+	var request plugins.WASMRequest
+	if err := json.Unmarshal(data, &request); err != nil {
+		return encodeResponse(nil, &plugins.RPCError{Code: plugins.RPCCodeInvalidParams, Message: err.Error()})
+	}
+	if handler == nil {
+		return encodeResponse(nil, &plugins.RPCError{Code: plugins.RPCCodeInternal, Message: "no plugin registered; call wasmguest.Register in init"})
+	}
+	result, err := handler(context.Background(), request.Method, request.Params)
+	if err != nil {
+		rpcErr, ok := err.(*plugins.RPCError)
+		if !ok {
+			rpcErr = &plugins.RPCError{Code: plugins.RPCCodeInternal, Message: err.Error()}
+		}
+		return encodeResponse(nil, rpcErr)
+	}
+	return encodeResponse(result, nil)
+}
+
+func encodeResponse(result interface{}, rpcErr *plugins.RPCError) []byte {
+	response := plugins.WASMResponse{Error: rpcErr}
+	if rpcErr == nil {
+		raw, err := json.Marshal(result)
+		if err != nil {
+			response.Error = &plugins.RPCError{Code: plugins.RPCCodeInternal, Message: "failed to encode result: " + err.Error()}
+		} else {
+			response.Result = raw
+		}
+	}
+	data, _ := json.Marshal(response)
+	return data
+}
+
+// host implements plugins.Caller through the crawlify host functions
+type host struct{}
+
+func (host) Call(ctx context.Context, method string, params, result interface{}) error {
+	rawParams, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	request, err := json.Marshal(plugins.WASMRequest{Method: method, Params: rawParams})
+	if err != nil {
+		return err
+	}
+
+	size := hostCall(pointer(request), uint32(len(request)))
+	data := make([]byte, max(size, 1))
+	hostResult(pointer(data))
+
+	var response plugins.WASMResponse
+	if err := json.Unmarshal(data[:size], &response); err != nil {
+		return err
+	}
+	if response.Error != nil {
+		return response.Error
+	}
+	if result == nil || len(response.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(response.Result, result)
+}
+
+func pointer(data []byte) uint32 {
+	return uint32(uintptr(unsafe.Pointer(unsafe.SliceData(data))))
+}
+
+func toString(v interface{}) string {
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	data, _ := json.Marshal(v)
+	return string(data)
+}
@@ -0,0 +1,6 @@
+// Package version holds the Crawlify release version
+package version
+
+// Version is the Crawlify release version, checked against the min_crawlify_version of plugins
+// Release builds set it with -ldflags "-X github.com/uzzalhcse/crawlify/pkg/version.Version=x.y.z"
+var Version = "1.0.0"